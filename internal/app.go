@@ -15,6 +15,8 @@ import (
 	"github.com/dushixiang/pika/internal/migrate"
 	"github.com/dushixiang/pika/internal/models"
 	"github.com/dushixiang/pika/internal/scheduler"
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/dushixiang/pika/internal/utils"
 	"github.com/dushixiang/pika/pkg/replace"
 	"github.com/dushixiang/pika/pkg/version"
 	"github.com/dushixiang/pika/web"
@@ -42,6 +44,9 @@ func setup(app *orz.App) error {
 	if err := autoMigrate(app.GetDatabase()); err != nil {
 		return err
 	}
+	if err := backfillNullColumns(app.GetDatabase()); err != nil {
+		app.Logger().Warn("回填新增字段的默认值失败", zap.Error(err))
+	}
 
 	// 读取应用配置
 	var appConfig config.AppConfig
@@ -85,6 +90,11 @@ func setup(app *orz.App) error {
 		// 不返回错误，继续启动
 	}
 
+	// 加载已吊销的分享令牌
+	if err := components.ShareTokenService.LoadRevoked(ctx); err != nil {
+		app.Logger().Error("加载已吊销的分享令牌失败", zap.Error(err))
+	}
+
 	// 启动WebSocket管理器
 	go components.WSManager.Run(ctx)
 
@@ -104,6 +114,18 @@ func setup(app *orz.App) error {
 	go components.DDNSService.Run(ctx)
 	// 启动公网 IP 采集定时任务
 	go components.PublicIPService.Run(ctx)
+	// 启动告警记录归档定时任务
+	go components.ArchiveService.Run(ctx)
+	// 启动长期离线探针自动清理定时任务（默认关闭）
+	go components.AgentCleanupService.Run(ctx)
+	// 启动未确认告警升级检查任务
+	go components.AlertService.RunAckEscalation(ctx)
+	// 启动入库降采样平均值窗口的定时写入任务
+	go components.MetricService.RunIngestDownsampling(ctx)
+	// 启动接收延迟和采集耗时的定时写入任务
+	go components.MetricService.RunIngestionSamples(ctx)
+	// 核对 VictoriaMetrics 的降采样参数与查询档位是否一致
+	go components.MetricService.CheckDownsampling(ctx)
 
 	// 设置API
 	setupApi(app, components)
@@ -187,22 +209,43 @@ func setupApi(app *orz.App, components *AppComponents) {
 
 		// Agent 版本和下载（完全公开，无需任何认证）
 		publicApi.GET("/agent/version", components.AgentHandler.GetAgentVersion)
+		// 构建信息（供部署工具做升级后检查）
+		publicApi.GET("/version", func(c echo.Context) error {
+			return orz.Ok(c, version.GetBuildInfo())
+		})
 		publicApi.GET("/agent/downloads/:filename", components.AgentHandler.DownloadAgent)
 		publicApi.GET("/agent/install.sh", components.AgentHandler.GetInstallScript)
+
+		// 时间序列注释上报（API 密钥认证，供探针或 CI 使用）
+		publicApi.POST("/annotations", components.AnnotationHandler.Ingest)
+
+		// node_exporter 指标导入（API 密钥认证，供无法安装探针的主机使用）
+		publicApi.POST("/import/node-exporter", components.NodeExporterHandler.Import)
 	}
 
 	// 公开接口（支持可选认证）- 已登录返回全部数据，未登录只返回公开数据
 	publicApiWithOptionalAuth := e.Group("/api")
 	publicApiWithOptionalAuth.Use(OptionalJWTAuthMiddleware(components.AccountHandler))
+	publicApiWithOptionalAuth.Use(ShareTokenMiddleware(components.ShareTokenService))
 	{
 		// 探针信息（公开访问，支持可选认证）- 用于公共展示页面
 		publicApiWithOptionalAuth.GET("/agents", components.AgentHandler.GetAgents)
+		publicApiWithOptionalAuth.GET("/agents/paged", components.AgentHandler.GetAgentsPaged)
 		publicApiWithOptionalAuth.GET("/agents/tags", components.AgentHandler.GetTags)
 		publicApiWithOptionalAuth.GET("/agents/:id", components.AgentHandler.Get)
 		publicApiWithOptionalAuth.GET("/agents/:id/metrics", components.AgentHandler.GetMetrics)
+		publicApiWithOptionalAuth.GET("/metrics/derived", components.AgentHandler.ListDerivedMetrics)
 		publicApiWithOptionalAuth.GET("/agents/:id/metrics/latest", components.AgentHandler.GetLatestMetrics)
+		publicApiWithOptionalAuth.GET("/agents/:id/metrics/recent", components.AgentHandler.GetRecentRawMetrics)
+		publicApiWithOptionalAuth.GET("/agents/:id/metrics/as-of", components.AgentHandler.GetMetricAsOf)
+		publicApiWithOptionalAuth.GET("/agents/:id/metrics/network-by-interface", components.AgentHandler.GetNetworkMetricsByInterface)
 		publicApiWithOptionalAuth.GET("/agents/:id/network-interfaces", components.AgentHandler.GetAvailableNetworkInterfaces)
 
+		// Grafana SimpleJSON 数据源（公开访问，支持可选认证）- 可在数据源中配置 Authorization 或 X-Share-Token 请求头
+		publicApiWithOptionalAuth.GET("/grafana", components.AgentHandler.GrafanaTestConnection)
+		publicApiWithOptionalAuth.POST("/grafana/search", components.AgentHandler.GrafanaSearch)
+		publicApiWithOptionalAuth.POST("/grafana/query", components.AgentHandler.GrafanaQuery)
+
 		// 监控统计数据（公开访问，支持可选认证）- 用于公共展示页面
 		publicApiWithOptionalAuth.GET("/monitors", components.MonitorHandler.GetMonitors)
 		publicApiWithOptionalAuth.GET("/monitors/:id/stats", components.MonitorHandler.GetStatsByID)
@@ -211,6 +254,8 @@ func setupApi(app *orz.App, components *AppComponents) {
 
 		// Logo（公开访问）- 用于公共页面只获取 Logo
 		publicApiWithOptionalAuth.GET("/logo", components.PropertyHandler.GetLogo)
+		// 品牌配置（公开访问）- 公共页面按分组获取名称、备案号和自定义样式
+		publicApiWithOptionalAuth.GET("/branding", components.PropertyHandler.GetBranding)
 	}
 
 	// WebSocket 路由（探针连接）
@@ -219,6 +264,7 @@ func setupApi(app *orz.App, components *AppComponents) {
 	// 管理员 API 路由（需要认证）
 	adminApi := e.Group("/api/admin")
 	adminApi.Use(JWTAuthMiddleware(components.AccountHandler))
+	adminApi.Use(ViewerReadOnlyMiddleware())
 	{
 		adminApi.GET("/version", func(c echo.Context) error {
 			return c.JSON(http.StatusOK, orz.Map{
@@ -229,6 +275,8 @@ func setupApi(app *orz.App, components *AppComponents) {
 		// 账户相关
 		adminApi.GET("/account/info", components.AccountHandler.GetCurrentUser)
 		adminApi.POST("/logout", components.AccountHandler.Logout)
+		adminApi.PUT("/account/password", components.AccountHandler.ChangePassword)
+		adminApi.GET("/login-audit-logs", components.AccountHandler.ListLoginAuditLogs)
 
 		// API密钥管理
 		adminApi.GET("/api-keys", components.ApiKeyHandler.Paging)
@@ -239,18 +287,56 @@ func setupApi(app *orz.App, components *AppComponents) {
 		adminApi.POST("/api-keys/:id/enable", components.ApiKeyHandler.Enable)
 		adminApi.POST("/api-keys/:id/disable", components.ApiKeyHandler.Disable)
 
+		// 只读分享令牌管理
+		adminApi.GET("/share-tokens", components.ShareTokenHandler.List)
+		adminApi.POST("/share-tokens", components.ShareTokenHandler.Create)
+		adminApi.DELETE("/share-tokens/:id", components.ShareTokenHandler.Revoke)
+
+		// 时间序列注释
+		adminApi.GET("/annotations", components.AnnotationHandler.List)
+		adminApi.POST("/annotations", components.AnnotationHandler.Create)
+		adminApi.PUT("/annotations/:id", components.AnnotationHandler.Update)
+		adminApi.DELETE("/annotations/:id", components.AnnotationHandler.Delete)
+
 		// 探针管理（管理员功能）
 		adminApi.POST("/server-url", components.AgentHandler.GetServerUrl)
 		adminApi.GET("/agents", components.AgentHandler.Paging)
+		adminApi.GET("/agents/paged", components.AgentHandler.PagingAgents)
 		adminApi.GET("/agents/statistics", components.AgentHandler.GetStatistics)
+		adminApi.GET("/agents/connection-stats", components.AgentHandler.GetConnectionStats)
+		adminApi.GET("/agents/ingestion-stats", components.AgentHandler.GetIngestionStats)
+		adminApi.GET("/agents/fleet-summary", components.AgentHandler.GetFleetSummary)
+		adminApi.GET("/agents/collisions", components.AgentHandler.ListCollisions)
+		adminApi.GET("/agent-keys", components.AgentHandler.ListAgentKeys)
+		adminApi.POST("/agent-keys/:id/approve", components.AgentHandler.ApproveAgentKey)
+		adminApi.POST("/agent-keys/:id/reject", components.AgentHandler.RejectAgentKey)
+		adminApi.DELETE("/agent-keys/:id", components.AgentHandler.DeleteAgentKey)
+		adminApi.GET("/storage/stats", components.AgentHandler.GetStorageStats)
+		adminApi.POST("/agents/:id/split", components.AgentHandler.SplitAgent)
 		adminApi.GET("/agents/tags", components.AgentHandler.GetTags)
+		adminApi.GET("/agents/attributes", components.AgentHandler.GetAttributeValues)
+		adminApi.GET("/agents/cleanup-candidates", components.AgentCleanupHandler.ListCandidates)
+		adminApi.POST("/agents/install-command", components.AgentHandler.GenerateInstallCommand)
+		adminApi.POST("/agents/import", components.AgentHandler.ImportAgent)
 		adminApi.GET("/agents/:id", components.AgentHandler.GetForAdmin)
 		adminApi.GET("/agents/:id/metrics/latest", components.AgentHandler.GetAdminLatestMetrics)
+		adminApi.GET("/agents/:id/metrics/coverage", components.AgentHandler.GetMetricCoverage)
+		adminApi.GET("/agents/:id/metrics/export", components.AgentHandler.ExportMetrics)
+		adminApi.GET("/agents/:id/export", components.AgentHandler.ExportAgent)
+		adminApi.GET("/agents/:id/connection-history", components.AgentHandler.GetConnectionHistory)
+		adminApi.GET("/agents/:id/disk-forecast", components.AgentHandler.GetDiskForecast)
+		adminApi.GET("/agents/:id/ip-history", components.AgentHandler.GetIPHistory)
+		adminApi.GET("/agents/:id/annotations", components.AnnotationHandler.List)
+		adminApi.GET("/agents/:id/metric-policy", components.AgentHandler.GetMetricPolicy)
+		adminApi.PUT("/agents/:id/metric-policy", components.AgentHandler.UpdateMetricPolicy)
+		adminApi.POST("/agents/:id/reload-config", components.AgentHandler.ReloadConfig)
 		adminApi.PUT("/agents/:id", components.AgentHandler.UpdateInfo)
 		adminApi.POST("/agents/batch/tags", components.AgentHandler.BatchUpdateTags)
 		adminApi.POST("/agents/batch/visibility", components.AgentHandler.BatchUpdateVisibility)
 		adminApi.DELETE("/agents/:id", components.AgentHandler.Delete)
 		adminApi.POST("/agents/:id/command", components.AgentHandler.SendCommand)
+		adminApi.POST("/agents/:id/log-tail", components.AgentHandler.StreamLogTail)
+		adminApi.DELETE("/agents/:id/log-tail/:sessionId", components.AgentHandler.StopLogTail)
 
 		// 流量管理（管理员访问）
 		adminApi.GET("/agents/:id/traffic", components.AgentHandler.GetTrafficStats)
@@ -260,6 +346,8 @@ func setupApi(app *orz.App, components *AppComponents) {
 		// VPS审计结果（管理员访问）
 		adminApi.GET("/agents/:id/audit/result", components.AgentHandler.GetAuditResult)
 		adminApi.GET("/agents/:id/audit/results", components.AgentHandler.ListAuditResults)
+		adminApi.GET("/agents/:id/audit/results/:auditId/analysis", components.AgentHandler.GetAuditAnalysis)
+		adminApi.GET("/agents/:id/audit/diff", components.AgentHandler.DiffAuditResults)
 
 		// 防篡改管理（管理员功能）
 		adminApi.GET("/agents/:id/tamper/config", components.TamperHandler.GetConfig)
@@ -276,13 +364,24 @@ func setupApi(app *orz.App, components *AppComponents) {
 		// 通用属性管理
 		adminApi.GET("/properties/:id", components.PropertyHandler.GetProperty)
 		adminApi.PUT("/properties/:id", components.PropertyHandler.SetProperty)
+		adminApi.GET("/config-audit-logs", components.PropertyHandler.ListConfigAuditLogs)
+		adminApi.POST("/alert-templates/from-config", components.PropertyHandler.CreateAlertTemplateFromConfig)
+		adminApi.POST("/alert-templates/:id/clone", components.PropertyHandler.CloneAlertTemplate)
+		adminApi.POST("/alert-templates/:id/instantiate", components.PropertyHandler.InstantiateAlertTemplate)
 
 		// 通知渠道测试（从数据库读取配置测试）
 		adminApi.POST("/notification-channels/:type/test", components.PropertyHandler.TestNotificationChannel)
+		adminApi.GET("/notification-deliveries", components.PropertyHandler.ListNotificationDeliveries)
 
 		// 告警记录查询
 		adminApi.GET("/alert-records", components.AlertHandler.ListAlertRecords)
 		adminApi.DELETE("/alert-records", components.AlertHandler.ClearAlertRecords)
+		adminApi.POST("/alert-records/ack", components.AlertHandler.AckAlertRecords)
+		adminApi.POST("/alert-records/resolve", components.AlertHandler.ResolveAlertRecords)
+		adminApi.GET("/alert-records/:id/comments", components.AlertHandler.ListAlertComments)
+		adminApi.POST("/alert-records/:id/comments", components.AlertHandler.AddAlertComment)
+		adminApi.POST("/alert-states/reset", components.AlertHandler.ResetAlertStates)
+		adminApi.GET("/agents/:id/effective-alert-config", components.AlertHandler.GetEffectiveAlertConfig)
 
 		// 服务监控配置
 		adminApi.GET("/monitors", components.MonitorHandler.List)
@@ -291,6 +390,10 @@ func setupApi(app *orz.App, components *AppComponents) {
 		adminApi.PUT("/monitors/:id", components.MonitorHandler.Update)
 		adminApi.DELETE("/monitors/:id", components.MonitorHandler.Delete)
 
+		// 自定义检查管理
+		adminApi.GET("/custom-checks", components.CustomCheckHandler.List)
+		adminApi.PUT("/custom-checks", components.CustomCheckHandler.Save)
+
 		// DNS Provider 管理
 		adminApi.GET("/dns-providers", components.DNSProviderHandler.GetAll)
 		adminApi.POST("/dns-providers", components.DNSProviderHandler.Upsert)
@@ -315,20 +418,56 @@ func setupApi(app *orz.App, components *AppComponents) {
 	publicApi.POST("/auth/github/callback", components.AccountHandler.GitHubLogin)
 }
 
+// nullColumnDefaults 升级时新增的字段，AutoMigrate 添加字段时已有记录的值为 NULL，按 0 值查询时无法匹配
+var nullColumnDefaults = []struct {
+	table  string
+	column string
+	value  any
+}{
+	{"alert_records", "archived_at", 0},
+	{"alert_records", "acked_at", 0},
+	{"alert_records", "suppressed", false},
+	{"alert_records", "escalated_at", 0},
+	{"alert_records", "escalation_count", 0},
+}
+
+// backfillNullColumns 将升级前已有记录中新增字段的 NULL 回填为默认值，已回填时不会更新任何记录
+func backfillNullColumns(database *gorm.DB) error {
+	for _, item := range nullColumnDefaults {
+		if err := database.Table(item.table).
+			Where(item.column+" IS NULL").
+			UpdateColumn(item.column, item.value).Error; err != nil {
+			return fmt.Errorf("回填 %s.%s 失败: %w", item.table, item.column, err)
+		}
+	}
+	return nil
+}
+
 func autoMigrate(database *gorm.DB) error {
 	// 自动迁移数据库表
 	return database.AutoMigrate(
-		&models.Agent{},         // 探针
-		&models.ApiKey{},        // ApiKey
-		&models.AuditResult{},   // 审计历史
-		&models.Property{},      // 系统属性
-		&models.AlertRecord{},   // 告警记录
-		&models.AlertState{},    // 告警状态
-		&models.MonitorTask{},   // 服务监控
-		&models.TamperEvent{},   // 防篡改事件
-		&models.DDNSConfig{},    // DDNS 配置
-		&models.DDNSRecord{},    // DDNS 记录
-		&models.SSHLoginEvent{}, // SSH 登录事件
+		&models.Agent{},                // 探针
+		&models.AgentCollision{},       // 探针ID冲突记录
+		&models.AgentKey{},             // 探针注册公钥
+		&models.AgentConnectionEvent{}, // 探针连接事件
+		&models.AgentIPHistory{},       // 探针公网IP变更历史
+		&models.ApiKey{},               // ApiKey
+		&models.ShareToken{},           // 只读分享令牌
+		&models.AuditResult{},          // 审计历史
+		&models.Property{},             // 系统属性
+		&models.AlertRecord{},          // 告警记录
+		&models.AlertState{},           // 告警状态
+		&models.AlertComment{},         // 告警备注
+		&models.Annotation{},           // 时间序列注释
+		&models.ConfigAuditLog{},       // 配置审计日志
+		&models.LoginAuditLog{},        // 登录审计日志
+		&models.MonitorTask{},          // 服务监控
+		&models.TamperEvent{},          // 防篡改事件
+		&models.DDNSConfig{},           // DDNS 配置
+		&models.DDNSRecord{},           // DDNS 记录
+		&models.DiskForecast{},         // 磁盘将满预测
+		&models.SSHLoginEvent{},        // SSH 登录事件
+		&models.UserCredential{},       // 用户密码（运行时修改）
 	)
 }
 
@@ -342,27 +481,40 @@ func ErrorHandler(logger *zap.Logger) func(next echo.HandlerFunc) echo.HandlerFu
 	var a = func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			if err := next(c); err != nil {
+				// 所有错误统一输出 code（状态码）、errorCode（机器可读错误码）和 message
+				var ae *handler.APIError
+				if errors.As(err, &ae) {
+					return c.JSON(ae.Status, orz.Map{
+						"code":      ae.Status,
+						"errorCode": ae.ErrorCode,
+						"message":   ae.Message,
+					})
+				}
+
 				var he *echo.HTTPError
 				if errors.As(err, &he) {
 					return c.JSON(he.Code, orz.Map{
-						"code":    he.Code,
-						"message": err.Error(),
+						"code":      he.Code,
+						"errorCode": handler.ErrorCodeFromStatus(he.Code),
+						"message":   err.Error(),
 					})
 				}
 
 				var oe *orz.Error
 				if errors.As(err, &oe) {
 					return c.JSON(400, orz.Map{
-						"code":    oe.Code,
-						"message": err.Error(),
+						"code":      oe.Code,
+						"errorCode": handler.ErrorCodeFromStatus(int(oe.Code)),
+						"message":   err.Error(),
 					})
 				}
 
 				logger.Sugar().Errorf("[ERROR] %s", err.Error())
 
 				return c.JSON(500, orz.Map{
-					"code":    500,
-					"message": "Internal Server Error",
+					"code":      500,
+					"errorCode": handler.ErrInternal,
+					"message":   "Internal Server Error",
 				})
 			}
 			return nil
@@ -384,6 +536,9 @@ func startMetricsMonitoring(ctx context.Context, components *AppComponents, logg
 			logger.Info("指标监控任务已停止")
 			return
 		case <-ticker.C:
+			// 导入的探针没有长连接，按最后导入时间判断离线
+			components.NodeExporterService.MarkStaleAgentsOffline(ctx)
+
 			// 检查所有在线探针的最新指标
 			agents, err := components.AgentService.ListOnlineAgents(ctx)
 			if err != nil {
@@ -403,35 +558,24 @@ func startMetricsMonitoring(ctx context.Context, components *AppComponents, logg
 					continue
 				}
 
-				// 提取 CPU、内存、磁盘使用率、网速
-				var cpuUsage, memoryUsage, diskUsage, networkSpeed float64
+				// 提取 CPU、内存、磁盘使用率、网速等告警判断数值
+				values := service.NewAlertMetricValues(latest)
 
-				if latest.CPU != nil {
-					cpuUsage = latest.CPU.UsagePercent
+				// 检查告警规则，沿用最近一次指标上报的追踪ID
+				traceID := latest.TraceID
+				if traceID == "" {
+					traceID = utils.NewTraceID()
 				}
-
-				if latest.Memory != nil {
-					memoryUsage = latest.Memory.UsagePercent
-				}
-
-				if latest.Disk != nil {
-					diskUsage = latest.Disk.UsagePercent
-				}
-
-				if latest.Network != nil {
-					// 网速 = (发送速率 + 接收速率) / 1024 / 1024 (转换为 MB/s)
-					networkSpeed = float64(latest.Network.TotalBytesSentRate+latest.Network.TotalBytesRecvRate) / 1024 / 1024
-				}
-
-				// 检查告警规则
-				if err := components.AlertService.CheckMetrics(ctx, agent.ID, cpuUsage, memoryUsage, diskUsage, networkSpeed); err != nil {
-					logger.Error("检查告警规则失败", zap.String("agentId", agent.ID), zap.Error(err))
+				checkCtx := utils.WithTraceID(ctx, traceID)
+				if err := components.AlertService.CheckMetrics(checkCtx, agent.ID, values.CPU, values.Memory, values.Disk, values.NetworkSpeed, values.MemoryFree, values.DiskFree, values.Connections, values.Load, values.Cores); err != nil {
+					logger.Error("检查告警规则失败", zap.String("agentId", agent.ID), zap.Error(err), utils.TraceField(checkCtx))
 				}
 			}
 
-			// 检查监控相关告警（证书和服务下线）
-			if err := components.AlertService.CheckMonitorAlerts(ctx); err != nil {
-				logger.Error("检查监控告警失败", zap.Error(err))
+			// 检查监控相关告警（证书和服务下线），每轮检查生成新的追踪ID
+			monitorCtx := utils.WithTraceID(ctx, utils.NewTraceID())
+			if err := components.AlertService.CheckMonitorAlerts(monitorCtx); err != nil {
+				logger.Error("检查监控告警失败", zap.Error(err), utils.TraceField(monitorCtx))
 			}
 		}
 	}
@@ -464,13 +608,13 @@ func JWTAuthMiddleware(accountHandler *handler.AccountHandler) echo.MiddlewareFu
 			// 从 Authorization header 获取 token
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "未提供认证令牌")
+				return handler.NewAPIError(http.StatusUnauthorized, handler.ErrTokenMissing, "未提供认证令牌")
 			}
 
 			// 检查 Bearer 前缀
 			const bearerPrefix = "Bearer "
 			if len(authHeader) < len(bearerPrefix) || authHeader[:len(bearerPrefix)] != bearerPrefix {
-				return echo.NewHTTPError(http.StatusUnauthorized, "认证令牌格式错误")
+				return handler.NewAPIError(http.StatusUnauthorized, handler.ErrTokenInvalid, "认证令牌格式错误")
 			}
 
 			tokenString := authHeader[len(bearerPrefix):]
@@ -478,19 +622,66 @@ func JWTAuthMiddleware(accountHandler *handler.AccountHandler) echo.MiddlewareFu
 			// 验证 token
 			claims, err := accountHandler.ValidateToken(tokenString)
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "认证令牌无效: "+err.Error())
+				return handler.NewAPIError(http.StatusUnauthorized, handler.ErrTokenInvalid, "认证令牌无效: "+err.Error())
 			}
 
 			// 将用户信息存入 context
 			c.Set("userID", claims.UserID)
 			c.Set("username", claims.Username)
+			c.Set("role", claims.EffectiveRole())
 			c.Set("authenticated", true)
+			// 记录操作用户，用于配置变更审计
+			c.SetRequest(c.Request().WithContext(service.WithOperator(c.Request().Context(), claims.Username)))
 
 			return next(c)
 		}
 	}
 }
 
+// viewerDeniedPaths 返回密钥等敏感信息的查询接口，只读用户不可访问
+var viewerDeniedPaths = map[string]struct{}{
+	"/api/admin/api-keys":          {},
+	"/api/admin/api-keys/:id":      {},
+	"/api/admin/agent-keys":        {},
+	"/api/admin/agents/:id/export": {},
+	"/api/admin/config-audit-logs": {},
+}
+
+// viewerDeniedProperties 包含密钥的系统属性，只读用户不可读取
+var viewerDeniedProperties = map[string]struct{}{
+	service.PropertyIDNotificationChannels: {},
+	service.PropertyIDDNSProviders:         {},
+	service.PropertyIDArchiveConfig:        {},
+}
+
+// ViewerReadOnlyMiddleware 只读角色仅允许查询类请求，且不能查询包含密钥的接口（需放在 JWTAuthMiddleware 之后）
+func ViewerReadOnlyMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if role, _ := c.Get("role").(string); role != service.RoleViewer {
+				return next(c)
+			}
+			if _, denied := viewerDeniedPaths[c.Path()]; denied {
+				return handler.NewAPIError(http.StatusForbidden, handler.ErrReadOnly, "只读用户无权查看此内容")
+			}
+			if c.Path() == "/api/admin/properties/:id" {
+				if _, denied := viewerDeniedProperties[c.Param("id")]; denied {
+					return handler.NewAPIError(http.StatusForbidden, handler.ErrReadOnly, "只读用户无权查看此内容")
+				}
+			}
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			// 登出不修改任何数据，只读用户也允许
+			if c.Path() == "/api/admin/logout" {
+				return next(c)
+			}
+			return handler.NewAPIError(http.StatusForbidden, handler.ErrReadOnly, "只读用户无权执行此操作")
+		}
+	}
+}
+
 // OptionalJWTAuthMiddleware 可选 JWT 认证中间件（尝试解析 token，但不强制要求）
 func OptionalJWTAuthMiddleware(accountHandler *handler.AccountHandler) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -509,6 +700,7 @@ func OptionalJWTAuthMiddleware(accountHandler *handler.AccountHandler) echo.Midd
 						// token 有效，将用户信息存入 context
 						c.Set("userID", claims.UserID)
 						c.Set("username", claims.Username)
+						c.Set("role", claims.EffectiveRole())
 						c.Set("authenticated", true)
 					}
 				}
@@ -520,4 +712,32 @@ func OptionalJWTAuthMiddleware(accountHandler *handler.AccountHandler) echo.Midd
 	}
 }
 
+// ShareTokenMiddleware 分享令牌中间件（需放在 OptionalJWTAuthMiddleware 之后）
+// 未登录但携带分享令牌（查询参数 share_token 或请求头 X-Share-Token）时，将令牌授予的探针范围存入 context
+func ShareTokenMiddleware(shareTokenService *service.ShareTokenService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if utils.IsAuthenticated(c) {
+				return next(c)
+			}
+
+			tokenString := c.QueryParam("share_token")
+			if tokenString == "" {
+				tokenString = c.Request().Header.Get("X-Share-Token")
+			}
+			if tokenString == "" {
+				return next(c)
+			}
+
+			scope, err := shareTokenService.Validate(tokenString)
+			if err != nil {
+				return handler.NewAPIError(http.StatusUnauthorized, handler.ErrShareTokenInvalid, "分享令牌无效: "+err.Error())
+			}
+			c.Set("shareScope", scope)
+
+			return next(c)
+		}
+	}
+}
+
 // APIKeyAuthMiddleware 使用 API Key 进行认证
@@ -2,12 +2,70 @@ package config
 
 // AppConfig 应用配置
 type AppConfig struct {
-	JWT             JWTConfig          `json:"JWT"`
-	Users           map[string]string  `json:"Users"`           // 用户名 -> bcrypt加密的密码
-	OIDC            *OIDCConfig        `json:"OIDC"`            // OIDC配置（可选）
-	GitHub          *GitHubOAuthConfig `json:"GitHub"`          // GitHub OAuth配置（可选）
-	GeoIP           *GeoIPConfig       `json:"GeoIP"`           // GeoIP配置（可选）
-	VictoriaMetrics *VMConfig          `json:"VictoriaMetrics"` // VictoriaMetrics配置（可选）
+	JWT             JWTConfig            `json:"JWT"`
+	Users           map[string]string    `json:"Users"`           // 用户名 -> 密码哈希（bcrypt 或 argon2id）
+	OIDC            *OIDCConfig          `json:"OIDC"`            // OIDC配置（可选）
+	GitHub          *GitHubOAuthConfig   `json:"GitHub"`          // GitHub OAuth配置（可选）
+	GeoIP           *GeoIPConfig         `json:"GeoIP"`           // GeoIP配置（可选）
+	VictoriaMetrics *VMConfig            `json:"VictoriaMetrics"` // VictoriaMetrics配置（可选）
+	Agent           *AgentConfig         `json:"Agent"`           // 探针注册配置（可选）
+	WebSocket       *WebSocketConfig     `json:"WebSocket"`       // 探针连接配置（可选）
+	MetricForward   *MetricForwardConfig `json:"MetricForward"`   // 指标转发到外部 TSDB 配置（可选）
+	LoginRegion     *LoginRegionConfig   `json:"LoginRegion"`     // 登录地区限制配置（可选）
+	PasswordHash    *PasswordHashConfig  `json:"PasswordHash"`    // 密码哈希配置（可选）
+}
+
+// PasswordHashConfig 密码哈希配置
+type PasswordHashConfig struct {
+	Algorithm         string `json:"Algorithm"`         // 新密码使用的算法: argon2id（默认）、bcrypt，两种哈希都可以校验
+	BcryptCost        int    `json:"BcryptCost"`        // bcrypt 计算成本（4-31），默认 10
+	Argon2Memory      int    `json:"Argon2Memory"`      // argon2id 内存（KiB），默认 65536
+	Argon2Iterations  int    `json:"Argon2Iterations"`  // argon2id 迭代次数，默认 1
+	Argon2Parallelism int    `json:"Argon2Parallelism"` // argon2id 并行度（1-255），默认 2
+}
+
+// LoginRegionConfig 基于 GeoIP 的登录地区限制配置（依赖 GeoIP 数据库）
+type LoginRegionConfig struct {
+	Enabled        bool     `json:"Enabled"`        // 是否启用登录地区限制
+	AllowCountries []string `json:"AllowCountries"` // 允许登录的国家代码（ISO 3166-1，如 CN、US），为空则不限制
+	DenyCountries  []string `json:"DenyCountries"`  // 禁止登录的国家代码，优先于允许列表
+	BypassCIDRs    []string `json:"BypassCIDRs"`    // 不受限制的内网网段，如 10.0.0.0/8
+}
+
+// MetricForwardConfig 指标转发到外部 TSDB（InfluxDB Line Protocol）配置
+type MetricForwardConfig struct {
+	Enabled   bool   `json:"Enabled"`   // 是否启用转发
+	DualWrite bool   `json:"DualWrite"` // 是否同时写入 VictoriaMetrics（迁移期间使用），关闭时仅写入外部 TSDB，服务端不从外部 TSDB 读取，历史查询无数据
+	URL       string `json:"URL"`       // 写入地址，如 http://influxdb:8086/api/v2/write
+	Token     string `json:"Token"`     // 认证令牌（可选）
+	Org       string `json:"Org"`       // InfluxDB 组织（可选）
+	Bucket    string `json:"Bucket"`    // InfluxDB Bucket（可选）
+	Timeout   int    `json:"Timeout"`   // 写入超时（秒）
+}
+
+// WebSocketConfig 探针 WebSocket 连接配置
+type WebSocketConfig struct {
+	MaxConnections int    `json:"MaxConnections"` // 最大连接数，0 表示不限制
+	SendBufferSize int    `json:"SendBufferSize"` // 每个连接的发送缓冲区大小，默认 256
+	OverflowPolicy string `json:"OverflowPolicy"` // 发送缓冲区溢出策略: drop-oldest（默认）, disconnect
+	PingInterval   int    `json:"PingInterval"`   // 心跳 Ping 间隔（秒），默认 30
+	PongTimeout    int    `json:"PongTimeout"`    // 等待 Pong 的超时时间（秒），超时断开连接，默认 60
+	IdleTimeout    int    `json:"IdleTimeout"`    // 未收到任何消息的空闲超时（秒），默认 120
+}
+
+// AgentConfig 探针注册配置
+type AgentConfig struct {
+	RejectIDCollision      bool `json:"RejectIDCollision"`      // 检测到探针ID冲突时是否拒绝注册
+	CollisionWindowSeconds int  `json:"CollisionWindowSeconds"` // ID冲突检测窗口（秒），默认 120
+	ClockSkewTolerance     int  `json:"ClockSkewTolerance"`     // 探针上报时间与服务端接收时间允许的最大偏差（秒），超过时标记为时钟偏差，默认 30
+	CorrectClockSkew       bool `json:"CorrectClockSkew"`       // 是否将时钟偏差超限的探针上报的时间戳校正为服务端时间
+	MinSampleInterval      int  `json:"MinSampleInterval"`      // 同一探针同一指标类型两次上报的最小间隔（毫秒），采集时间间隔更短的数据会被丢弃，默认 0 表示不限制
+
+	ImportIdentityHeader string `json:"ImportIdentityHeader"` // 导入 node_exporter 指标时携带探针ID的请求头，默认 X-Pika-Agent-ID
+
+	// RequireSignedRegistration 是否要求探针使用密钥对签名注册，未签名或公钥未审批的探针无法注册
+	// 关闭时（默认）仍只校验 API 密钥，但探针提交签名时会登记公钥，审批后同样强制校验
+	RequireSignedRegistration bool `json:"RequireSignedRegistration"`
 }
 
 // JWTConfig JWT配置
@@ -23,6 +81,11 @@ type OIDCConfig struct {
 	ClientID     string `json:"ClientID"`     // Client ID
 	ClientSecret string `json:"ClientSecret"` // Client Secret
 	RedirectURL  string `json:"RedirectURL"`  // 回调URL
+
+	GroupsClaim   string   `json:"GroupsClaim"`   // ID Token 中用户组的 claim 名称，支持 a.b 形式的嵌套路径（默认 groups）
+	AllowedGroups []string `json:"AllowedGroups"` // 允许登录的用户组白名单（为空则允许所有用户）
+	AdminGroups   []string `json:"AdminGroups"`   // 映射为管理员角色的用户组（为空则所有允许登录的用户均为管理员）
+	ViewerGroups  []string `json:"ViewerGroups"`  // 映射为只读角色的用户组
 }
 
 // GitHubOAuthConfig GitHub OAuth认证配置
@@ -43,9 +106,23 @@ type GeoIPConfig struct {
 
 // VMConfig VictoriaMetrics配置
 type VMConfig struct {
-	Enabled       bool   `json:"Enabled"`       // 是否启用VictoriaMetrics
-	URL           string `json:"URL"`           // VictoriaMetrics地址
-	RetentionDays int    `json:"RetentionDays"` // 数据保留天数（用于文档说明）
-	WriteTimeout  int    `json:"WriteTimeout"`  // 写入超时（秒）
-	QueryTimeout  int    `json:"QueryTimeout"`  // 查询超时（秒）
+	Enabled               bool   `json:"Enabled"`               // 是否启用VictoriaMetrics
+	URL                   string `json:"URL"`                   // VictoriaMetrics地址
+	RetentionDays         int    `json:"RetentionDays"`         // 数据保留天数，未登录的查询会被限制在该范围内
+	ExtendedRetentionDays int    `json:"ExtendedRetentionDays"` // 管理员使用 full=true 查询时允许的最长天数，0 表示不限制
+	WriteTimeout          int    `json:"WriteTimeout"`          // 写入超时（秒）
+	QueryTimeout          int    `json:"QueryTimeout"`          // 查询超时（秒）
+	Precision             *int   `json:"Precision"`             // 查询结果保留的小数位数，未配置时默认 2，0 表示取整，负数表示不取整
+	AllowedIntervals      []int  `json:"AllowedIntervals"`      // 允许的查询步长（秒），未配置时使用内置档位
+	// Aggregations 按指标类型和系列指定降采样时使用的聚合函数（avg/max/last），未配置时不做聚合
+	Aggregations map[string]map[string]string `json:"Aggregations"`
+	// Downsampling 降采样查询档位，需与 VictoriaMetrics 的 -downsampling.period 一致，查询较早的数据时步长不小于对应档位
+	// 只影响查询步长，Pika 不会重新聚合已存储的数据
+	Downsampling []DownsamplingTier `json:"Downsampling"`
+}
+
+// DownsamplingTier 降采样档位：早于 AfterDays 天的数据每 Interval 秒只保留一个点
+type DownsamplingTier struct {
+	AfterDays int `json:"AfterDays"` // 数据超过的天数
+	Interval  int `json:"Interval"`  // 降采样后的间隔（秒）
 }
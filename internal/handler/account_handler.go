@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/dushixiang/pika/internal/service"
@@ -35,9 +36,12 @@ func (r AccountHandler) Login(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
-	loginResp, err := r.accountService.Login(ctx, req.Username, req.Password)
+	loginResp, err := r.accountService.Login(ctx, req.Username, req.Password, c.RealIP())
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "用户名或密码错误")
+		if errors.Is(err, service.ErrLoginRegionDenied) {
+			return NewAPIError(http.StatusForbidden, ErrLoginRegionDenied, "当前地区不允许登录")
+		}
+		return NewAPIError(http.StatusBadRequest, ErrInvalidCredentials, "用户名或密码错误")
 	}
 
 	return orz.Ok(c, loginResp)
@@ -60,9 +64,15 @@ func (r AccountHandler) OIDCLogin(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
-	loginResp, err := r.accountService.LoginWithOIDC(ctx, req.Code, req.State)
+	loginResp, err := r.accountService.LoginWithOIDC(ctx, req.Code, req.State, c.RealIP())
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "OIDC 认证失败: "+err.Error())
+		if errors.Is(err, service.ErrLoginRegionDenied) {
+			return NewAPIError(http.StatusForbidden, ErrLoginRegionDenied, "当前地区不允许登录")
+		}
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			return NewAPIError(http.StatusBadRequest, ErrOIDCDisabled, err.Error())
+		}
+		return NewAPIError(http.StatusBadRequest, ErrOIDCFailed, "OIDC 认证失败: "+err.Error())
 	}
 
 	return orz.Ok(c, loginResp)
@@ -78,7 +88,10 @@ func (r AccountHandler) GetAuthConfig(c echo.Context) error {
 func (r AccountHandler) GetOIDCAuthURL(c echo.Context) error {
 	authURL, err := r.accountService.GetOIDCAuthURL()
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			return NewAPIError(http.StatusBadRequest, ErrOIDCDisabled, err.Error())
+		}
+		return NewAPIError(http.StatusBadRequest, ErrOIDCFailed, err.Error())
 	}
 	return orz.Ok(c, authURL)
 }
@@ -87,7 +100,10 @@ func (r AccountHandler) GetOIDCAuthURL(c echo.Context) error {
 func (r AccountHandler) GetGitHubAuthURL(c echo.Context) error {
 	authURL, err := r.accountService.GetGitHubAuthURL()
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrGitHubDisabled) {
+			return NewAPIError(http.StatusBadRequest, ErrGitHubDisabled, err.Error())
+		}
+		return NewAPIError(http.StatusBadRequest, ErrGitHubFailed, err.Error())
 	}
 	return orz.Ok(c, authURL)
 }
@@ -109,19 +125,76 @@ func (r AccountHandler) GitHubLogin(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
-	loginResp, err := r.accountService.LoginWithGitHub(ctx, req.Code, req.State)
+	loginResp, err := r.accountService.LoginWithGitHub(ctx, req.Code, req.State, c.RealIP())
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "GitHub 认证失败: "+err.Error())
+		if errors.Is(err, service.ErrLoginRegionDenied) {
+			return NewAPIError(http.StatusForbidden, ErrLoginRegionDenied, "当前地区不允许登录")
+		}
+		if errors.Is(err, service.ErrGitHubDisabled) {
+			return NewAPIError(http.StatusBadRequest, ErrGitHubDisabled, err.Error())
+		}
+		return NewAPIError(http.StatusBadRequest, ErrGitHubFailed, "GitHub 认证失败: "+err.Error())
 	}
 
 	return orz.Ok(c, loginResp)
 }
 
+// ListLoginAuditLogs 分页查询被拒绝的登录审计日志
+func (r AccountHandler) ListLoginAuditLogs(c echo.Context) error {
+	pageReq := orz.GetPageRequest(c, "createdAt")
+	builder := orz.NewPageBuilder(r.accountService.LoginAuditLogRepo.Repository).
+		PageRequest(pageReq).
+		Equal("username", c.QueryParam("username")).
+		Equal("country", c.QueryParam("country"))
+
+	page, err := builder.Execute(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return orz.Ok(c, page)
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+// ChangePassword 修改当前登录用户的密码
+func (r AccountHandler) ChangePassword(c echo.Context) error {
+	username, ok := c.Get("username").(string)
+	if !ok || username == "" {
+		return NewAPIError(http.StatusUnauthorized, ErrUnauthorized, "未登录")
+	}
+
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if err := r.accountService.ChangePassword(ctx, username, req.OldPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			return NewAPIError(http.StatusBadRequest, ErrInvalidCredentials, "原密码错误")
+		case errors.Is(err, service.ErrPasswordTooShort):
+			return NewAPIError(http.StatusBadRequest, ErrPasswordTooShort, err.Error())
+		}
+		return err
+	}
+
+	return orz.Ok(c, orz.Map{})
+}
+
 // Logout 用户登出
 func (r AccountHandler) Logout(c echo.Context) error {
 	userID := c.Get("userID")
 	if userID == nil {
-		return orz.NewError(401, "未登录")
+		return NewAPIError(http.StatusUnauthorized, ErrUnauthorized, "未登录")
 	}
 
 	ctx := c.Request().Context()
@@ -144,11 +217,14 @@ func (r AccountHandler) GetCurrentUser(c echo.Context) error {
 	username := c.Get("username")
 
 	if userID == nil || username == nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, "未登录")
+		return NewAPIError(http.StatusUnauthorized, ErrUnauthorized, "未登录")
 	}
 
+	role, _ := c.Get("role").(string)
+
 	return orz.Ok(c, orz.Map{
 		"userId":   userID.(string),
 		"username": username.(string),
+		"role":     role,
 	})
 }
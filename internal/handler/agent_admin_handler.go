@@ -2,16 +2,23 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dushixiang/pika/internal/models"
 	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/service"
+	ws "github.com/dushixiang/pika/internal/websocket"
 	"github.com/go-orz/orz"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 func SortAgents(agents []models.Agent) {
@@ -34,11 +41,18 @@ func SortAgents(agents []models.Agent) {
 func (h *AgentHandler) Paging(c echo.Context) error {
 	ctx := c.Request().Context()
 
+	// 按属性筛选，可传多个 attr=key=value，需同时满足
+	selector, err := service.ParseAttributeSelector(c.QueryParams()["attr"])
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+
 	// 获取所有探针（管理员接口）
 	agents, err := h.agentService.AgentRepo.FindAll(ctx)
 	if err != nil {
 		return err
 	}
+	agents = service.FilterAgentsByAttributes(agents, selector)
 
 	// 排序
 	SortAgents(agents)
@@ -46,6 +60,104 @@ func (h *AgentHandler) Paging(c echo.Context) error {
 	return orz.Ok(c, agents)
 }
 
+// PagingAgents 探针分页查询，支持按状态、标签、分组、关键词（名称、主机名、IP）筛选，按权重、名称或最后上线时间排序
+func (h *AgentHandler) PagingAgents(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status != "" && status != "online" && status != "offline" {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的状态，支持: online, offline")
+	}
+
+	pageReq := getPageRequest(c, "weight", "name", "lastSeenAt")
+	page, err := h.agentService.ListAgentsPaged(c.Request().Context(), pageReq, service.AgentPageQuery{
+		Status:  status,
+		Tags:    c.QueryParam("tag"),
+		Group:   c.QueryParam("group"),
+		Keyword: c.QueryParam("keyword"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return orz.Ok(c, newPageResponse(pageReq, page))
+}
+
+// GetAttributeValues 获取探针使用中的属性名及取值
+func (h *AgentHandler) GetAttributeValues(c echo.Context) error {
+	values, err := h.agentService.GetAttributeValues(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, values)
+}
+
+// GetConnectionStats 获取探针连接统计（连接数、丢弃消息数、消息压缩率）
+func (h *AgentHandler) GetConnectionStats(c echo.Context) error {
+	return orz.Ok(c, orz.Map{
+		"connections": h.wsManager.ConnectionStats(),
+		"compression": h.wsManager.CompressionStats(),
+		"pings":       h.wsManager.PingStats(),
+	})
+}
+
+// GetIngestionStats 获取各探针的采集耗时、接收延迟及时钟偏差标记
+func (h *AgentHandler) GetIngestionStats(c echo.Context) error {
+	return orz.Ok(c, h.metricService.GetIngestionStats())
+}
+
+// GetFleetSummary 获取所有在线探针的汇总统计（核心数、内存、磁盘、网速及超过告警阈值的探针数）
+func (h *AgentHandler) GetFleetSummary(c echo.Context) error {
+	summary, err := h.metricService.GetFleetSummary(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, summary)
+}
+
+// ListCollisions 分页查询疑似探针ID冲突记录
+func (h *AgentHandler) ListCollisions(c echo.Context) error {
+	pageReq := orz.GetPageRequest(c, "createdAt")
+	builder := orz.NewPageBuilder(h.agentService.AgentCollisionRepo.Repository).
+		PageRequest(pageReq).
+		Equal("agentId", c.QueryParam("agentId"))
+
+	page, err := builder.Execute(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return orz.Ok(c, page)
+}
+
+// SplitAgent 将冲突的探针拆分为新ID，并通知当前连接的探针使用新ID重新注册
+func (h *AgentHandler) SplitAgent(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	newAgent, err := h.agentService.SplitAgent(ctx, agentID)
+	if err != nil {
+		return err
+	}
+
+	msgData, err := json.Marshal(protocol.OutboundMessage{
+		Type: protocol.MessageTypeReassignID,
+		Data: protocol.ReassignIDData{AgentID: newAgent.ID},
+	})
+	if err != nil {
+		return err
+	}
+
+	notified := true
+	if err := h.wsManager.SendToClient(agentID, msgData); err != nil {
+		notified = false
+		h.logger.Warn("通知探针使用新ID失败", zap.String("agentID", agentID), zap.Error(err))
+	}
+
+	return orz.Ok(c, orz.Map{
+		"agent":    newAgent,
+		"notified": notified,
+	})
+}
+
 // GetForAdmin 获取探针详情（管理员接口，显示完整信息）
 func (h *AgentHandler) GetForAdmin(c echo.Context) error {
 	id := c.Param("id")
@@ -67,9 +179,137 @@ func (h *AgentHandler) GetAdminLatestMetrics(c echo.Context) error {
 	if !ok {
 		return orz.NewError(404, "探针最新指标不存在")
 	}
+	if checkLatestMetricsNotModified(c, metrics, "full") {
+		return c.NoContent(http.StatusNotModified)
+	}
 	return orz.Ok(c, metrics)
 }
 
+// GetMetricCoverage 获取探针各指标类型的上报覆盖情况
+func (h *AgentHandler) GetMetricCoverage(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	coverages, err := h.metricService.GetMetricCoverage(ctx, id)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, coverages)
+}
+
+// GetStorageStats 获取数据表行数、占用空间及各指标类型的时间范围，用于容量规划
+func (h *AgentHandler) GetStorageStats(c echo.Context) error {
+	stats, err := h.metricService.GetStorageStats(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, stats)
+}
+
+// GetDiskForecast 获取探针各挂载点的磁盘将满预测
+func (h *AgentHandler) GetDiskForecast(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	forecasts, err := h.metricService.GetDiskForecasts(ctx, id)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, forecasts)
+}
+
+// GetIPHistory 获取探针公网 IPv4/IPv6 变更历史
+func (h *AgentHandler) GetIPHistory(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	histories, err := h.agentService.GetIPHistory(ctx, id, limit)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, histories)
+}
+
+// GetMetricPolicy 获取探针的指标采集策略
+func (h *AgentHandler) GetMetricPolicy(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	policy, err := h.metricService.GetMetricPolicy(ctx, id)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, policy)
+}
+
+// UpdateMetricPolicy 更新探针的指标采集策略，并下发给在线探针停止采集被禁止的指标
+func (h *AgentHandler) UpdateMetricPolicy(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	var policy protocol.MetricPolicyData
+	if err := c.Bind(&policy); err != nil {
+		return orz.NewError(400, "请求参数错误")
+	}
+
+	if err := h.metricService.SetMetricPolicy(ctx, id, &policy); err != nil {
+		return orz.NewError(400, err.Error())
+	}
+
+	msgData, err := json.Marshal(protocol.OutboundMessage{
+		Type: protocol.MessageTypeMetricPolicy,
+		Data: policy,
+	})
+	if err != nil {
+		return err
+	}
+	if err := h.wsManager.SendToClient(id, msgData); err != nil {
+		h.logger.Debug("探针不在线，采集策略将在下次连接时下发", zap.String("agentId", id), zap.Error(err))
+	}
+
+	return orz.Ok(c, orz.Map{})
+}
+
+// ReloadConfig 向在线探针重新下发完整配置，探针无需重连即可应用，返回下发的配置
+func (h *AgentHandler) ReloadConfig(c echo.Context) error {
+	id := c.Param("id")
+
+	config, err := h.agentConfigs.ReloadConfig(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return NewAPIError(http.StatusNotFound, ErrNotFound, "探针不存在")
+		case errors.Is(err, service.ErrAgentOffline), errors.Is(err, ws.ErrClientNotFound):
+			return NewAPIError(http.StatusConflict, ErrBadRequest, service.ErrAgentOffline.Error())
+		}
+		h.logger.Error("重新下发探针配置失败", zap.String("agentId", id), zap.Error(err))
+		return err
+	}
+	return orz.Ok(c, config)
+}
+
+// GetConnectionHistory 获取探针连接/断开历史（默认最近 7 天）
+func (h *AgentHandler) GetConnectionHistory(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	rangeParam := c.QueryParam("range")
+	if rangeParam == "" {
+		rangeParam = "7d"
+	}
+	start, end, err := parseTimeRangeOrStartEnd(rangeParam, c.QueryParam("start"), c.QueryParam("end"))
+	if err != nil {
+		return orz.NewError(400, err.Error())
+	}
+
+	history, err := h.agentService.GetConnectionHistory(ctx, id, start, end)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, history)
+}
+
 // SendCommand 向探针发送指令
 func (h *AgentHandler) SendCommand(c echo.Context) error {
 	agentID := c.Param("id")
@@ -128,23 +368,58 @@ func (h *AgentHandler) GetAuditResult(c echo.Context) error {
 	return orz.Ok(c, result)
 }
 
-// ListAuditResults 获取审计结果列表
+// GetAuditAnalysis 获取指定审计结果的服务端安全分析
+func (h *AgentHandler) GetAuditAnalysis(c echo.Context) error {
+	agentID := c.Param("id")
+	auditID, err := strconv.ParseInt(c.Param("auditId"), 10, 64)
+	if err != nil {
+		return orz.NewError(400, "无效的审计ID")
+	}
+
+	analysis, err := h.agentService.GetAuditAnalysis(c.Request().Context(), agentID, auditID)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, analysis)
+}
+
+// DiffAuditResults 对比两次审计结果
+func (h *AgentHandler) DiffAuditResults(c echo.Context) error {
+	agentID := c.Param("id")
+	auditIDA, err := strconv.ParseInt(c.QueryParam("a"), 10, 64)
+	if err != nil {
+		return orz.NewError(400, "无效的审计ID: a")
+	}
+	auditIDB, err := strconv.ParseInt(c.QueryParam("b"), 10, 64)
+	if err != nil {
+		return orz.NewError(400, "无效的审计ID: b")
+	}
+
+	diff, err := h.agentService.DiffAuditResults(c.Request().Context(), agentID, auditIDA, auditIDB)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, diff)
+}
+
+// ListAuditResults 分页获取审计结果列表，未传分页参数时返回最近 50 条
 func (h *AgentHandler) ListAuditResults(c echo.Context) error {
 	agentID := c.Param("id")
 	ctx := c.Request().Context()
 
-	results, err := h.agentService.ListAuditResults(ctx, agentID)
+	pr := getPageRequest(c, "createdAt")
+	if !hasPageParams(c) {
+		pr.PageSize = 50
+	}
+	results, total, err := h.agentService.ListAuditResults(ctx, agentID, pr)
 	if err != nil {
 		return err
 	}
 
-	return orz.Ok(c, orz.Map{
-		"items": results,
-		"total": len(results),
-	})
+	return orz.Ok(c, newPageResponse(pr, orz.NewPageResult(results, total)))
 }
 
-// UpdateInfo 更新探针信息（名称、标签、到期时间、可见性、权重、备注）
+// UpdateInfo 更新探针信息（名称、标签、到期时间、可见性、权重、备注、主磁盘、主网卡）
 func (h *AgentHandler) UpdateInfo(c echo.Context) error {
 	agentID := c.Param("id")
 
@@ -155,10 +430,24 @@ func (h *AgentHandler) UpdateInfo(c echo.Context) error {
 		Visibility string   `json:"visibility"`
 		Weight     int      `json:"weight"`
 		Remark     string   `json:"remark"`
+		// 运维设置的属性，未传时保持不变
+		Attributes *map[string]string `json:"attributes"`
+		// 主磁盘挂载点和主网卡，未传时保持不变，为空表示自动识别
+		PrimaryMountPoint *string `json:"primaryMountPoint"`
+		PrimaryInterface  *string `json:"primaryInterface"`
+		// 分组，未传时保持不变
+		Group *string `json:"group"`
 	}
 	if err := c.Bind(&req); err != nil {
 		return orz.NewError(400, "请求参数错误")
 	}
+	var attributes map[string]string
+	if req.Attributes != nil {
+		var err error
+		if attributes, err = service.NormalizeAttributes(*req.Attributes, true); err != nil {
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+		}
+	}
 
 	ctx := c.Request().Context()
 	agent, err := h.agentService.AgentRepo.FindById(ctx, agentID)
@@ -172,11 +461,24 @@ func (h *AgentHandler) UpdateInfo(c echo.Context) error {
 	agent.Visibility = req.Visibility
 	agent.Weight = req.Weight
 	agent.Remark = req.Remark
+	if attributes != nil {
+		agent.Attributes = datatypes.NewJSONType(attributes)
+	}
+	if req.PrimaryMountPoint != nil {
+		agent.PrimaryMountPoint = strings.TrimSpace(*req.PrimaryMountPoint)
+	}
+	if req.PrimaryInterface != nil {
+		agent.PrimaryInterface = strings.TrimSpace(*req.PrimaryInterface)
+	}
+	if req.Group != nil {
+		agent.Group = strings.TrimSpace(*req.Group)
+	}
 	agent.UpdatedAt = time.Now().UnixMilli()
 
 	if err := h.agentService.AgentRepo.Save(ctx, &agent); err != nil {
 		return err
 	}
+	h.metricService.InvalidatePrimaryDevices(agentID)
 
 	return orz.Ok(c, orz.Map{})
 }
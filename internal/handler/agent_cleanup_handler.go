@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type AgentCleanupHandler struct {
+	logger              *zap.Logger
+	agentCleanupService *service.AgentCleanupService
+}
+
+func NewAgentCleanupHandler(logger *zap.Logger, agentCleanupService *service.AgentCleanupService) *AgentCleanupHandler {
+	return &AgentCleanupHandler{
+		logger:              logger,
+		agentCleanupService: agentCleanupService,
+	}
+}
+
+// ListCandidates 列出满足自动清理条件的探针及预计删除时间
+func (h *AgentCleanupHandler) ListCandidates(c echo.Context) error {
+	candidates, err := h.agentCleanupService.ListCandidates(c.Request().Context())
+	if err != nil {
+		h.logger.Error("获取待清理探针失败", zap.Error(err))
+		return err
+	}
+	return orz.Ok(c, candidates)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ExportContinueTokenHeader 导出未完成时返回的续传令牌响应头，客户端将其作为 cursor 参数继续导出
+const ExportContinueTokenHeader = "X-Continue-Token"
+
+// abortResponse 中断已发送响应头的输出，由 net/http 直接关闭连接（Recover 中间件会继续抛出该错误），
+// 客户端收到传输错误，不会把截断的内容当作完整的响应
+func abortResponse() {
+	panic(http.ErrAbortHandler)
+}
+
+// ExportMetrics 按时间顺序以 CSV 流式导出探针指标（管理员接口）
+// 单次请求最多导出固定步数，未导出完时在响应头中返回续传令牌；
+// 断线后也可将最后收到的完整时间戳作为 cursor 继续导出
+func (h *AgentHandler) ExportMetrics(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	if _, err := h.agentService.AgentRepo.FindById(ctx, agentID); err != nil {
+		return NewAPIError(http.StatusNotFound, ErrNotFound, "探针不存在")
+	}
+
+	metricType := c.QueryParam("type")
+	if err := validateMetricType(metricType); err != nil {
+		return err
+	}
+	interfaceName := normalizeInterfaceName(c.QueryParam("interface"))
+	fields := parseFieldsParam(c.QueryParam("fields"))
+	interval, err := parseIntervalParam(c.QueryParam("interval"))
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+	start, end, err := parseTimeRangeOrStartEnd(c.QueryParam("range"), c.QueryParam("start"), c.QueryParam("end"))
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, err.Error())
+	}
+	start, end = h.metricService.ClampTimeRange(start, end, true)
+
+	var cursor int64
+	if cursorParam := c.QueryParam("cursor"); cursorParam != "" {
+		if cursor, err = strconv.ParseInt(cursorParam, 10, 64); err != nil || cursor < 0 {
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的 cursor 参数")
+		}
+	}
+
+	page := h.metricService.PlanExportPage(start, end, cursor, interval)
+
+	// 续传令牌在开始输出前即可确定，写入响应头
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s-%s-%d.csv", agentID, metricType, page.After+1))
+	header.Set("X-Export-Interval", strconv.FormatInt(int64(page.Step.Seconds()), 10))
+	if page.Next > 0 {
+		header.Set(ExportContinueTokenHeader, strconv.FormatInt(page.Next, 10))
+	}
+	header.Add(echo.HeaderAccessControlExposeHeaders, ExportContinueTokenHeader)
+	c.Response().WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(c.Response())
+	if err := writer.Write([]string{"timestamp", "time", "series", "labels", "value"}); err != nil {
+		h.logger.Error("导出指标中断", zap.String("agentId", agentID), zap.String("type", metricType), zap.Error(err))
+		abortResponse()
+	}
+
+	err = h.metricService.ExportMetrics(ctx, agentID, metricType, interfaceName, fields, page, func(rows []service.ExportRow) error {
+		for _, row := range rows {
+			if err := writer.Write([]string{
+				strconv.FormatInt(row.Timestamp, 10),
+				time.UnixMilli(row.Timestamp).UTC().Format(time.RFC3339),
+				row.Series,
+				row.Labels,
+				strconv.FormatFloat(row.Value, 'f', -1, 64),
+			}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		c.Response().Flush()
+		return writer.Error()
+	})
+	if err == nil {
+		writer.Flush()
+		err = writer.Error()
+	}
+	if err != nil {
+		// 响应头已发送，中断连接，客户端收到传输错误而不是看似完整的 CSV，可按最后收到的完整时间戳续传
+		h.logger.Error("导出指标中断", zap.String("agentId", agentID), zap.String("type", metricType), zap.Error(err))
+		abortResponse()
+	}
+	return nil
+}
+
+// ExportAgent 导出单个探针的完整数据包（zip），包含探针记录、关联数据和最近 metricDays 天的原始指标（管理员接口）
+func (h *AgentHandler) ExportAgent(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	if _, err := h.agentService.AgentRepo.FindById(ctx, agentID); err != nil {
+		return NewAPIError(http.StatusNotFound, ErrNotFound, "探针不存在")
+	}
+
+	metricDays := service.DefaultAgentBundleMetricDays
+	if value := c.QueryParam("metricDays"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 || days > service.MaxAgentBundleMetricDays {
+			return NewAPIErrorf(http.StatusBadRequest, ErrInvalidParam, "metricDays 取值范围为 0-%d", service.MaxAgentBundleMetricDays)
+		}
+		metricDays = days
+	}
+
+	// 先查询探针记录和关联数据，出错时还能返回错误状态码
+	data, err := h.agentService.PrepareAgentExport(ctx, agentID)
+	if err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "application/zip")
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=agent-%s-%s.zip", agentID, time.Now().Format("20060102150405")))
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := h.agentService.ExportAgent(ctx, data, c.Response(), metricDays); err != nil {
+		// 响应头已发送，中断连接，客户端收到下载失败而不是看似完整的 zip
+		h.logger.Error("导出探针中断", zap.String("agentId", agentID), zap.Error(err))
+		abortResponse()
+	}
+	return nil
+}
+
+// ImportAgent 从导出包导入探针，表单字段 file 为导出包，newId 为新的探针ID（为空时沿用原ID）（管理员接口）
+func (h *AgentHandler) ImportAgent(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请上传探针导出包")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := h.agentService.ImportAgent(c.Request().Context(), file, fileHeader.Size, c.FormValue("newId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAgentIDExists):
+			return NewAPIError(http.StatusConflict, ErrInvalidParam, err.Error())
+		case errors.Is(err, service.ErrAgentBundleInvalid),
+			errors.Is(err, service.ErrAgentBundleVersion),
+			errors.Is(err, service.ErrInvalidAgentID):
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+		}
+		return err
+	}
+	username, _ := c.Get("username").(string)
+	h.logger.Info("已导入探针",
+		zap.String("agentId", result.Agent.ID),
+		zap.String("sourceAgentId", result.SourceAgentID),
+		zap.String("operator", username))
+	return orz.Ok(c, result)
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// grafanaMaxTargets 单次查询最多的 target 数，每个 target 对应一次 VictoriaMetrics 查询
+	grafanaMaxTargets = 20
+	// grafanaMaxRange 单次查询的最大时间范围
+	grafanaMaxRange = 31 * 24 * time.Hour
+)
+
+// grafanaMetricTypes Grafana 数据源可查询的指标类型（按展示顺序）
+var grafanaMetricTypes = []string{"cpu", "memory", "disk", "disk_io", "network", "network_connection", "gpu", "temperature", "monitor", "load"}
+
+// GrafanaSearchRequest Grafana SimpleJSON /search 请求
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaTarget Grafana 可选指标，value 格式为 探针ID/指标类型[/系列名称]
+type GrafanaTarget struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// GrafanaQueryRequest Grafana SimpleJSON /query 请求
+type GrafanaQueryRequest struct {
+	Range struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"range"`
+	IntervalMs int64 `json:"intervalMs"`
+	Targets    []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+// GrafanaTimeSeries Grafana 时序响应，datapoints 为 [value, 毫秒时间戳] 数组
+type GrafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaTestConnection Grafana 数据源连接测试
+func (h *AgentHandler) GrafanaTestConnection(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+// GrafanaSearch 列出可查询的指标（Grafana SimpleJSON /search），target 不为空时按名称过滤
+func (h *AgentHandler) GrafanaSearch(c echo.Context) error {
+	var req GrafanaSearchRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+	keyword := strings.ToLower(strings.TrimSpace(req.Target))
+
+	agents, err := h.listAgentsByRequest(c)
+	if err != nil {
+		return err
+	}
+	SortAgents(agents)
+
+	targets := make([]GrafanaTarget, 0)
+	for _, agent := range agents {
+		for _, metricType := range grafanaMetricTypes {
+			candidates := []GrafanaTarget{{
+				Text:  fmt.Sprintf("%s / %s", agent.Name, metricType),
+				Value: fmt.Sprintf("%s/%s", agent.ID, metricType),
+			}}
+			for _, name := range h.metricService.SeriesNames(metricType) {
+				candidates = append(candidates, GrafanaTarget{
+					Text:  fmt.Sprintf("%s / %s / %s", agent.Name, metricType, name),
+					Value: fmt.Sprintf("%s/%s/%s", agent.ID, metricType, name),
+				})
+			}
+			for _, target := range candidates {
+				if keyword == "" || strings.Contains(strings.ToLower(target.Text), keyword) {
+					targets = append(targets, target)
+				}
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, targets)
+}
+
+// GrafanaQuery 按 Grafana 的时间范围查询指标（Grafana SimpleJSON /query），返回 [value, timestamp] 格式的时序数据
+func (h *AgentHandler) GrafanaQuery(c echo.Context) error {
+	var req GrafanaQueryRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	from, err := time.Parse(time.RFC3339Nano, req.Range.From)
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "无效的开始时间")
+	}
+	to, err := time.Parse(time.RFC3339Nano, req.Range.To)
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "无效的结束时间")
+	}
+	if !from.Before(to) {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "开始时间必须早于结束时间")
+	}
+	if to.Sub(from) > grafanaMaxRange {
+		return NewAPIErrorf(http.StatusBadRequest, ErrInvalidTimeRange, "时间范围不能超过 %d 天", int(grafanaMaxRange.Hours()/24))
+	}
+	visible := 0
+	for _, target := range req.Targets {
+		if !target.Hide && target.Target != "" {
+			visible++
+		}
+	}
+	if visible > grafanaMaxTargets {
+		return NewAPIErrorf(http.StatusBadRequest, ErrInvalidParam, "单次最多查询 %d 个 target", grafanaMaxTargets)
+	}
+	start, end := h.metricService.ClampTimeRange(from.UnixMilli(), to.UnixMilli(), false)
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+
+	ctx := c.Request().Context()
+	result := make([]GrafanaTimeSeries, 0)
+	for _, target := range req.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+
+		agentID, metricType, field, err := parseGrafanaTarget(target.Target)
+		if err != nil {
+			return err
+		}
+		agent, err := h.getAgentByRequest(c, agentID)
+		if err != nil {
+			return err
+		}
+
+		var fields []string
+		if field != "" {
+			fields = []string{field}
+		}
+		metrics, err := h.metricService.GetMetrics(ctx, agentID, metricType, start, end, "", "", false, fields, interval, false, "", 0)
+		if err != nil {
+			return err
+		}
+		for _, series := range metrics.Series {
+			if field != "" && series.Name != field {
+				continue
+			}
+			result = append(result, GrafanaTimeSeries{
+				Target:     grafanaSeriesName(agent.Name, metricType, series),
+				Datapoints: grafanaDatapoints(series.Data),
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// parseGrafanaTarget 解析 探针ID/指标类型[/系列名称] 格式的 target
+func parseGrafanaTarget(target string) (agentID, metricType, field string, err error) {
+	parts := strings.SplitN(strings.TrimSpace(target), "/", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", "", "", NewAPIErrorf(http.StatusBadRequest, ErrInvalidParam, "无效的 target: %s", target)
+	}
+	if err := validateMetricType(parts[1]); err != nil {
+		return "", "", "", err
+	}
+	if len(parts) == 3 {
+		field = parts[2]
+	}
+	return parts[0], parts[1], field, nil
+}
+
+// grafanaSeriesName 生成 Grafana 中显示的系列名称，按标签拆分的系列附带标签
+func grafanaSeriesName(agentName, metricType string, series metric.Series) string {
+	name := fmt.Sprintf("%s / %s / %s", agentName, metricType, series.Name)
+	if len(series.Labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(series.Labels))
+	for k := range series.Labels {
+		if k == "agent_id" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return name
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, series.Labels[k]))
+	}
+	return fmt.Sprintf("%s {%s}", name, strings.Join(pairs, ", "))
+}
+
+// grafanaDatapoints 转换为 Grafana 的 [value, timestamp] 数组
+func grafanaDatapoints(points []metric.DataPoint) [][2]float64 {
+	datapoints := make([][2]float64, 0, len(points))
+	for _, p := range points {
+		datapoints = append(datapoints, [2]float64{p.Value, float64(p.Timestamp)})
+	}
+	return datapoints
+}
@@ -18,6 +18,9 @@ type AgentHandler struct {
 	sshLoginService *service.SSHLoginService
 	apiKeyService   *service.ApiKeyService
 	propertyService *service.PropertyService
+	customChecks    *service.CustomCheckService
+	logTailService  *service.LogTailService
+	agentConfigs    *service.AgentConfigService
 	wsManager       *ws.Manager
 	upgrader        websocket.Upgrader
 }
@@ -25,7 +28,8 @@ type AgentHandler struct {
 func NewAgentHandler(logger *zap.Logger, agentService *service.AgentService, trafficService *service.TrafficService,
 	metricService *service.MetricService, monitorService *service.MonitorService, tamperService *service.TamperService,
 	ddnsService *service.DDNSService, sshLoginService *service.SSHLoginService, apiKeyService *service.ApiKeyService,
-	propertyService *service.PropertyService, wsManager *ws.Manager) *AgentHandler {
+	propertyService *service.PropertyService, customCheckService *service.CustomCheckService, logTailService *service.LogTailService,
+	agentConfigService *service.AgentConfigService, wsManager *ws.Manager) *AgentHandler {
 
 	h := &AgentHandler{
 		logger:          logger,
@@ -38,6 +42,9 @@ func NewAgentHandler(logger *zap.Logger, agentService *service.AgentService, tra
 		sshLoginService: sshLoginService,
 		apiKeyService:   apiKeyService,
 		propertyService: propertyService,
+		customChecks:    customCheckService,
+		logTailService:  logTailService,
+		agentConfigs:    agentConfigService,
 		wsManager:       wsManager,
 	}
 
@@ -49,6 +56,8 @@ func NewAgentHandler(logger *zap.Logger, agentService *service.AgentService, tra
 
 	// 设置WebSocket消息处理器
 	wsManager.SetMessageHandler(h.handleWebSocketMessage)
+	// 记录探针连接/断开事件
+	wsManager.SetConnectionHandler(agentService.RecordConnectionEvent)
 
 	return h
 }
@@ -3,7 +3,9 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/dushixiang/pika"
 	"github.com/dushixiang/pika/pkg/version"
@@ -15,7 +17,8 @@ import (
 // GetAgentVersion 获取 Agent 版本信息
 func (h *AgentHandler) GetAgentVersion(c echo.Context) error {
 	return orz.Ok(c, orz.Map{
-		"version": version.GetAgentVersion(),
+		"version":       version.GetAgentVersion(),
+		"serverVersion": version.GetVersion(),
 	})
 }
 
@@ -82,6 +85,82 @@ func (h *AgentHandler) GetServerUrl(c echo.Context) error {
 	})
 }
 
+// InstallCommandRequest 生成安装命令请求
+type InstallCommandRequest struct {
+	Name string   `json:"name"` // 探针名称（可选）
+	Tags []string `json:"tags"` // 预分配的标签（可选）
+}
+
+// GenerateInstallCommand 生成一键安装命令（同时创建专用的 API 密钥）
+func (h *AgentHandler) GenerateInstallCommand(c echo.Context) error {
+	var req InstallCommandRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	serverUrl := strings.TrimRight(strings.TrimSpace(h.getServerURL(c)), "/")
+	if serverUrl == "" {
+		return orz.NewError(400, "请先配置服务端地址")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	keyName := "安装命令 " + time.Now().Format("2006-01-02 15:04:05")
+	if name != "" {
+		keyName = "安装命令 " + name
+	}
+	userID, _ := c.Get("userID").(string)
+
+	apiKey, err := h.apiKeyService.GenerateScopedApiKey(c.Request().Context(), keyName, userID, tags)
+	if err != nil {
+		h.logger.Error("生成安装密钥失败", zap.Error(err))
+		return err
+	}
+
+	return orz.Ok(c, orz.Map{
+		"apiKeyId":   apiKey.ID,
+		"tags":       tags,
+		"bash":       buildBashInstallCommand(serverUrl, apiKey.Key, name),
+		"powershell": buildPowerShellInstallCommand(serverUrl, apiKey.Key, name),
+	})
+}
+
+// buildBashInstallCommand 构建 Linux/macOS 一键安装命令
+func buildBashInstallCommand(serverUrl, token, name string) string {
+	params := url.Values{}
+	params.Set("token", token)
+	if name != "" {
+		params.Set("name", name)
+	}
+	scriptURL := serverUrl + "/api/agent/install.sh?" + params.Encode()
+	return "curl -fsSL " + bashSingleQuote(scriptURL) + " | sudo bash"
+}
+
+// buildPowerShellInstallCommand 构建 Windows 一键安装命令（需管理员权限）
+func buildPowerShellInstallCommand(serverUrl, token, name string) string {
+	nameArg := ""
+	if name != "" {
+		nameArg = " --name " + powerShellSingleQuote(name)
+	}
+	return "$arch = if ($env:PROCESSOR_ARCHITECTURE -eq 'ARM64') { 'arm64' } else { 'amd64' }; " +
+		"$dir = Join-Path $env:ProgramFiles 'pika'; " +
+		"New-Item -ItemType Directory -Force -Path $dir | Out-Null; " +
+		"$exe = Join-Path $dir 'pika-agent.exe'; " +
+		"Invoke-WebRequest -UseBasicParsing -Uri (" + powerShellSingleQuote(serverUrl+"/api/agent/downloads/agent-windows-") +
+		" + $arch + " + powerShellSingleQuote(".exe?key="+url.QueryEscape(token)) + ") -OutFile $exe; " +
+		"& $exe register --endpoint " + powerShellSingleQuote(serverUrl) + " --token " + powerShellSingleQuote(token) + nameArg + " --yes"
+}
+
+func powerShellSingleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
 // GetInstallScript 生成自动安装脚本
 func (h *AgentHandler) GetInstallScript(c echo.Context) error {
 	token := c.QueryParam("token")
@@ -96,6 +175,7 @@ func (h *AgentHandler) GetInstallScript(c echo.Context) error {
 	}
 	customName := strings.TrimSpace(c.QueryParam("name"))
 	customNameLiteral := bashSingleQuote(customName)
+	serverVersion := version.GetVersion()
 
 	script := `#!/bin/bash
 set -e
@@ -224,6 +304,7 @@ register_agent() {
 # 主流程
 main() {
     echo_info "开始安装 Pika Agent..."
+    echo_info "服务端版本: ` + serverVersion + `"
     echo ""
 
     detect_platform
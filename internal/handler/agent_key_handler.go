@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ListAgentKeys 列出探针注册公钥，可通过 status 过滤（pending/approved/rejected）
+func (h *AgentHandler) ListAgentKeys(c echo.Context) error {
+	keys, err := h.agentService.ListAgentKeys(c.Request().Context(), c.QueryParam("status"))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, keys)
+}
+
+// ApproveAgentKey 审批通过探针公钥，之后该探针ID只能使用此公钥签名注册
+func (h *AgentHandler) ApproveAgentKey(c echo.Context) error {
+	return h.reviewAgentKey(c, true)
+}
+
+// RejectAgentKey 拒绝探针公钥
+func (h *AgentHandler) RejectAgentKey(c echo.Context) error {
+	return h.reviewAgentKey(c, false)
+}
+
+func (h *AgentHandler) reviewAgentKey(c echo.Context, approve bool) error {
+	username, _ := c.Get("username").(string)
+	key, err := h.agentService.ReviewAgentKey(c.Request().Context(), c.Param("id"), approve, username)
+	if err != nil {
+		return h.agentKeyError(err)
+	}
+	return orz.Ok(c, key)
+}
+
+// DeleteAgentKey 解除探针ID与公钥的绑定
+func (h *AgentHandler) DeleteAgentKey(c echo.Context) error {
+	username, _ := c.Get("username").(string)
+	if err := h.agentService.DeleteAgentKey(c.Request().Context(), c.Param("id"), username); err != nil {
+		return h.agentKeyError(err)
+	}
+	return orz.Ok(c, nil)
+}
+
+// agentKeyError 将探针公钥相关错误转换为接口错误
+func (h *AgentHandler) agentKeyError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewAPIError(http.StatusNotFound, ErrNotFound, "探针公钥不存在")
+	case errors.Is(err, service.ErrAgentKeyInvalidStatus):
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+	h.logger.Error("处理探针公钥失败", zap.Error(err))
+	return err
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	// logTailKeepAliveInterval SSE 心跳间隔，同时用于检查探针是否仍在线
+	logTailKeepAliveInterval = 15 * time.Second
+	// logTailGracePeriod 探针到期后自行结束跟踪的宽限时间，超过后由服务端结束
+	logTailGracePeriod = 10 * time.Second
+)
+
+// logTailRequest 日志跟踪请求参数
+type logTailRequest struct {
+	Path            string `json:"path"`
+	DurationSeconds int    `json:"durationSeconds"`
+	LinesPerSecond  int    `json:"linesPerSecond"`
+}
+
+// logTailEnd 跟踪结束事件
+type logTailEnd struct {
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"`
+}
+
+// StreamLogTail 跟踪探针上的日志文件，以 SSE 流式返回新增的日志行（管理员接口）
+// 事件依次为 session（会话信息）、lines（日志行）、end（结束原因），客户端断开连接即停止跟踪
+func (h *AgentHandler) StreamLogTail(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	var req logTailRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	username, _ := c.Get("username").(string)
+	session, err := h.logTailService.Start(agentID, req.Path, time.Duration(req.DurationSeconds)*time.Second, req.LinesPerSecond, username)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidLogTail), errors.Is(err, service.ErrLogTailAgentOffline):
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+		case errors.Is(err, service.ErrLogTailTooMany):
+			return NewAPIError(http.StatusTooManyRequests, ErrBadRequest, err.Error())
+		}
+		h.logger.Error("failed to start log tail", zap.String("agentID", agentID), zap.Error(err))
+		return err
+	}
+	defer h.logTailService.Stop(session.ID)
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/event-stream")
+	header.Set(echo.HeaderCacheControl, "no-cache")
+	header.Set(echo.HeaderConnection, "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := writeSSEEvent(c, "session", orz.Map{
+		"sessionId":       session.ID,
+		"path":            session.Path,
+		"durationSeconds": int(session.Duration.Seconds()),
+	}); err != nil {
+		return nil
+	}
+
+	deadline := time.NewTimer(session.Duration + logTailGracePeriod)
+	defer deadline.Stop()
+	keepAlive := time.NewTicker(logTailKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-deadline.C:
+			_ = writeSSEEvent(c, "end", logTailEnd{Reason: "timeout"})
+			return nil
+
+		case <-keepAlive.C:
+			if _, ok := h.wsManager.GetClient(agentID); !ok {
+				_ = writeSSEEvent(c, "end", logTailEnd{Reason: "agent_offline"})
+				return nil
+			}
+			if _, err := fmt.Fprint(c.Response(), ": keepalive\n\n"); err != nil {
+				return nil
+			}
+			c.Response().Flush()
+
+		case data, ok := <-session.Data:
+			if !ok {
+				_ = writeSSEEvent(c, "end", logTailEnd{Reason: "stopped"})
+				return nil
+			}
+			if len(data.Lines) > 0 || data.Dropped > 0 {
+				if err := writeSSEEvent(c, "lines", orz.Map{
+					"lines":   data.Lines,
+					"dropped": data.Dropped,
+				}); err != nil {
+					return nil
+				}
+			}
+			if data.Done {
+				end := logTailEnd{Reason: "finished", Error: data.Error}
+				if data.Error != "" {
+					end.Reason = "error"
+				}
+				_ = writeSSEEvent(c, "end", end)
+				return nil
+			}
+		}
+	}
+}
+
+// StopLogTail 停止日志跟踪（管理员接口），正在读取的 SSE 流会收到 end 事件
+func (h *AgentHandler) StopLogTail(c echo.Context) error {
+	session, ok := h.logTailService.Get(c.Param("sessionId"))
+	if !ok || session.AgentID != c.Param("id") {
+		return NewAPIError(http.StatusNotFound, ErrNotFound, "日志跟踪会话不存在")
+	}
+	h.logTailService.Stop(session.ID)
+	return orz.Ok(c, nil)
+}
+
+// writeSSEEvent 写入一条 SSE 事件并立即刷新
+func writeSSEEvent(c echo.Context, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Response(), "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	c.Response().Flush()
+	return nil
+}
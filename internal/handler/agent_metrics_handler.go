@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dushixiang/pika/internal/service"
 	"github.com/dushixiang/pika/internal/utils"
 	"github.com/go-orz/orz"
 	"github.com/labstack/echo/v4"
@@ -12,9 +16,12 @@ import (
 
 var validMetricTypes = map[string]struct{}{
 	"cpu": {}, "memory": {}, "disk": {}, "network": {}, "network_connection": {},
-	"disk_io": {}, "gpu": {}, "temperature": {}, "monitor": {},
+	"disk_io": {}, "gpu": {}, "temperature": {}, "monitor": {}, "load": {},
 }
 
+// derivedMetricType 派生指标的查询类型，配合 name 参数使用
+const derivedMetricType = "derived"
+
 var timeRangeMilliseconds = map[string]int64{
 	"1m":  int64(time.Minute / time.Millisecond),
 	"5m":  int64(5 * time.Minute / time.Millisecond),
@@ -48,20 +55,71 @@ func parseTimeRange(rangeParam string) (start, end int64, err error) {
 	return start, end, nil
 }
 
+// relativeTimeUnits 相对时间表达式支持的单位
+var relativeTimeUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// parseTimeParam 解析毫秒时间戳或相对时间表达式（now、now-24h、now-7d，单位支持 s/m/h/d/w）
+// 相对时间统一以 now 计算，start 和 end 使用同一时刻，不依赖客户端时钟
+func parseTimeParam(param string, now int64) (int64, error) {
+	if ts, err := strconv.ParseInt(param, 10, 64); err == nil {
+		return ts, nil
+	}
+	expr, ok := strings.CutPrefix(strings.TrimSpace(param), "now")
+	if !ok {
+		return 0, fmt.Errorf("必须是毫秒时间戳或相对时间（如 now-24h）")
+	}
+	if expr == "" {
+		return now, nil
+	}
+
+	sign := int64(1)
+	switch expr[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, fmt.Errorf("相对时间格式为 now-<数量><单位>，如 now-24h")
+	}
+	if len(expr) < 3 {
+		return 0, fmt.Errorf("相对时间格式为 now-<数量><单位>，如 now-24h")
+	}
+	unit, ok := relativeTimeUnits[expr[len(expr)-1]]
+	if !ok {
+		return 0, fmt.Errorf("相对时间单位支持 s, m, h, d, w")
+	}
+	amount, err := strconv.ParseInt(expr[1:len(expr)-1], 10, 64)
+	if err != nil || amount < 0 || amount > int64(10*365*24*time.Hour/unit) {
+		return 0, fmt.Errorf("相对时间的数量无效")
+	}
+	return now + sign*amount*unit.Milliseconds(), nil
+}
+
+// parseTimeRangeOrStartEnd 解析 start/end 或 range 参数，start/end 可为毫秒时间戳或相对时间表达式
+// start 为相对时间且未提供 end 时，end 默认为 now
 func parseTimeRangeOrStartEnd(rangeParam, startParam, endParam string) (start, end int64, err error) {
 	if startParam != "" || endParam != "" {
+		if endParam == "" && strings.HasPrefix(strings.TrimSpace(startParam), "now") {
+			endParam = "now"
+		}
 		if startParam == "" || endParam == "" {
 			return 0, 0, fmt.Errorf("start 和 end 必须同时提供")
 		}
 
-		start, err = strconv.ParseInt(startParam, 10, 64)
+		now := time.Now().UnixMilli()
+		start, err = parseTimeParam(startParam, now)
 		if err != nil {
-			return 0, 0, fmt.Errorf("无效的 start 时间戳")
+			return 0, 0, fmt.Errorf("无效的 start: %w", err)
 		}
 
-		end, err = strconv.ParseInt(endParam, 10, 64)
+		end, err = parseTimeParam(endParam, now)
 		if err != nil {
-			return 0, 0, fmt.Errorf("无效的 end 时间戳")
+			return 0, 0, fmt.Errorf("无效的 end: %w", err)
 		}
 
 		if start >= end {
@@ -81,12 +139,41 @@ func normalizeInterfaceName(name string) string {
 	return name
 }
 
+// parseFieldsParam 解析逗号分隔的 fields 参数，指定只返回的系列名称
+func parseFieldsParam(param string) []string {
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// parseIntervalParam 解析步长参数，支持 Go 时长格式（如 20s、5m）或秒数，为空时返回 0 表示自动选择
+func parseIntervalParam(param string) (time.Duration, error) {
+	if param == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(param); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("无效的 interval 参数")
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(param)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("无效的 interval 参数")
+	}
+	return d, nil
+}
+
 func validateMetricType(metricType string) error {
 	if metricType == "" {
-		return orz.NewError(400, "指标类型不能为空")
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "指标类型不能为空")
 	}
 	if _, ok := validMetricTypes[metricType]; !ok {
-		return orz.NewError(400, "无效的指标类型")
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的指标类型")
 	}
 	return nil
 }
@@ -97,7 +184,8 @@ func (h *AgentHandler) GetMetrics(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// 验证探针访问权限
-	if _, err := h.agentService.GetAgentByAuth(ctx, agentID, utils.IsAuthenticated(c)); err != nil {
+	isAuthenticated := utils.IsAuthenticated(c)
+	if _, err := h.getAgentByRequest(c, agentID); err != nil {
 		return err
 	}
 
@@ -107,35 +195,183 @@ func (h *AgentHandler) GetMetrics(c echo.Context) error {
 	endParam := c.QueryParam("end")
 	interfaceName := normalizeInterfaceName(c.QueryParam("interface"))
 	aggregation := normalizeAggregation(c.QueryParam("aggregation"))
+	smooth, _ := strconv.ParseBool(c.QueryParam("smooth"))
+	fill, _ := strconv.ParseBool(c.QueryParam("fill"))
+	fields := parseFieldsParam(c.QueryParam("fields"))
+	decimation := normalizeDecimation(c.QueryParam("decimation"))
 
-	if err := validateMetricType(metricType); err != nil {
-		return err
+	// 派生指标通过 name 参数指定，不在固定的指标类型中
+	if metricType != derivedMetricType {
+		if err := validateMetricType(metricType); err != nil {
+			return err
+		}
+	}
+	interval, err := parseIntervalParam(c.QueryParam("interval"))
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+	var points int
+	if param := c.QueryParam("points"); param != "" {
+		if points, err = strconv.Atoi(param); err != nil || points < 2 {
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "points 必须是不小于 2 的整数")
+		}
 	}
 
 	// 解析时间范围
 	start, end, err := parseTimeRangeOrStartEnd(rangeParam, startParam, endParam)
 	if err != nil {
-		return orz.NewError(400, err.Error())
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, err.Error())
+	}
+
+	// 未登录请求始终限制在数据保留范围内，已登录请求可通过 full=true 使用扩展保留范围
+	full, _ := strconv.ParseBool(c.QueryParam("full"))
+	start, end = h.metricService.ClampTimeRange(start, end, isAuthenticated && full)
+
+	if metricType == derivedMetricType {
+		metrics, err := h.metricService.GetDerivedMetrics(ctx, agentID, c.QueryParam("name"), start, end, aggregation, smooth, interval, fill)
+		if err != nil {
+			if errors.Is(err, service.ErrDerivedMetricNotFound) {
+				return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的派生指标名称")
+			}
+			return err
+		}
+		return orz.Ok(c, metrics)
 	}
 
-	// GetMetrics 内部会自动计算最优聚合间隔
-	metrics, err := h.metricService.GetMetrics(ctx, agentID, metricType, start, end, interfaceName, aggregation)
+	// 未指定 interval 时 GetMetrics 内部会自动计算最优聚合间隔，并对齐到允许的步长
+	metrics, err := h.metricService.GetMetrics(ctx, agentID, metricType, start, end, interfaceName, aggregation, smooth, fields, interval, fill, decimation, points)
 	if err != nil {
 		return err
 	}
+	// 查询调试信息只返回给管理员
+	role, _ := c.Get("role").(string)
+	if debug, _ := strconv.ParseBool(c.QueryParam("debug")); !debug || !isAuthenticated || role != service.RoleAdmin {
+		metrics.Debug = nil
+	}
 
 	// 直接返回 GetMetricsResponse，避免额外嵌套
 	return orz.Ok(c, metrics)
 }
 
+// ListDerivedMetrics 获取可查询的派生指标（内置和自定义）
+func (h *AgentHandler) ListDerivedMetrics(c echo.Context) error {
+	metrics, err := h.metricService.ListDerivedMetrics(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, metrics)
+}
+
+// GetMetricAsOf 获取探针在指定时间点（含）之前的最新指标值（公开接口，已登录返回全部，未登录返回公开可见）
+// ts 为毫秒时间戳，为空时使用当前时间，可传入告警记录的触发时间查看告警时的指标
+func (h *AgentHandler) GetMetricAsOf(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	isAuthenticated := utils.IsAuthenticated(c)
+	if _, err := h.getAgentByRequest(c, agentID); err != nil {
+		return err
+	}
+
+	metricType := c.QueryParam("type")
+	if err := validateMetricType(metricType); err != nil {
+		return err
+	}
+
+	var ts int64
+	if tsParam := c.QueryParam("ts"); tsParam != "" {
+		var err error
+		if ts, err = strconv.ParseInt(tsParam, 10, 64); err != nil || ts < 0 {
+			return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "无效的 ts 时间戳")
+		}
+	}
+
+	// 超出数据保留范围的时间点不查询
+	full, _ := strconv.ParseBool(c.QueryParam("full"))
+	if ts > 0 {
+		if earliest, _ := h.metricService.ClampTimeRange(ts, time.Now().UnixMilli(), isAuthenticated && full); earliest > ts {
+			return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "时间点超出数据保留范围")
+		}
+	}
+
+	result, err := h.metricService.GetMetricAsOf(ctx, agentID, metricType, ts)
+	if err != nil {
+		if errors.Is(err, service.ErrNoMetricBefore) {
+			return NewAPIError(http.StatusNotFound, ErrNotFound, err.Error())
+		}
+		return err
+	}
+	return orz.Ok(c, result)
+}
+
+// GetNetworkMetricsByInterface 按网卡获取网络速率历史（公开接口，已登录返回全部，未登录返回公开可见）
+// 长时间范围降采样后仍按网卡分别返回每个时间桶的平均、最小、最大速率
+func (h *AgentHandler) GetNetworkMetricsByInterface(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	isAuthenticated := utils.IsAuthenticated(c)
+	if _, err := h.getAgentByRequest(c, agentID); err != nil {
+		return err
+	}
+
+	interval, err := parseIntervalParam(c.QueryParam("interval"))
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+	start, end, err := parseTimeRangeOrStartEnd(c.QueryParam("range"), c.QueryParam("start"), c.QueryParam("end"))
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, err.Error())
+	}
+	full, _ := strconv.ParseBool(c.QueryParam("full"))
+	start, end = h.metricService.ClampTimeRange(start, end, isAuthenticated && full)
+
+	metrics, err := h.metricService.GetNetworkMetricsByInterface(ctx, agentID, start, end, interval)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, metrics)
+}
+
+// GetRecentRawMetrics 获取探针最近 N 个原始数据点（公开接口，已登录返回全部，未登录返回公开可见）
+func (h *AgentHandler) GetRecentRawMetrics(c echo.Context) error {
+	agentID := c.Param("id")
+	ctx := c.Request().Context()
+
+	// 验证探针访问权限
+	if _, err := h.getAgentByRequest(c, agentID); err != nil {
+		return err
+	}
+
+	metricType := c.QueryParam("type")
+	if err := validateMetricType(metricType); err != nil {
+		return err
+	}
+	interfaceName := normalizeInterfaceName(c.QueryParam("interface"))
+
+	limit := 0
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitParam); err != nil {
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的 limit 参数")
+		}
+	}
+
+	metrics, err := h.metricService.GetRecentRawMetrics(ctx, agentID, metricType, interfaceName, limit)
+	if err != nil {
+		return err
+	}
+
+	return orz.Ok(c, metrics)
+}
+
 // GetLatestMetrics 获取探针最新指标（公开接口，已登录返回全部，未登录返回公开可见）
 func (h *AgentHandler) GetLatestMetrics(c echo.Context) error {
 	id := c.Param("id")
-	ctx := c.Request().Context()
 
 	// 验证探针访问权限
 	isAuthenticated := utils.IsAuthenticated(c)
-	if _, err := h.agentService.GetAgentByAuth(ctx, id, isAuthenticated); err != nil {
+	if _, err := h.getAgentByRequest(c, id); err != nil {
 		return err
 	}
 
@@ -143,6 +379,14 @@ func (h *AgentHandler) GetLatestMetrics(c echo.Context) error {
 	if !ok {
 		return orz.NewError(404, "探针最新指标不存在")
 	}
+	// 公开访问与登录访问的响应内容不同，使用不同的 ETag
+	variant := "public"
+	if isAuthenticated {
+		variant = "full"
+	}
+	if checkLatestMetricsNotModified(c, metrics, variant) {
+		return c.NoContent(http.StatusNotModified)
+	}
 	if !isAuthenticated {
 		sanitized := *metrics
 		sanitized.NetworkInterfaces = nil
@@ -158,7 +402,7 @@ func (h *AgentHandler) GetAvailableNetworkInterfaces(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// 验证探针访问权限
-	if _, err := h.agentService.GetAgentByAuth(ctx, id, utils.IsAuthenticated(c)); err != nil {
+	if _, err := h.getAgentByRequest(c, id); err != nil {
 		return err
 	}
 
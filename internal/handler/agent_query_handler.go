@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"net/http"
+
 	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/service"
 	"github.com/dushixiang/pika/internal/utils"
 	"github.com/go-orz/orz"
 	"github.com/labstack/echo/v4"
@@ -11,11 +14,10 @@ import (
 // Get 获取探针详情（公开接口，已登录返回全部，未登录返回公开可见）
 func (h *AgentHandler) Get(c echo.Context) error {
 	id := c.Param("id")
-	ctx := c.Request().Context()
 
-	// 根据认证状态返回相应的探针
+	// 根据认证状态返回相应的探针，分享令牌按公开访问处理敏感信息
 	isAuthenticated := utils.IsAuthenticated(c)
-	agent, err := h.agentService.GetAgentByAuth(ctx, id, isAuthenticated)
+	agent, err := h.getAgentByRequest(c, id)
 	if err != nil {
 		return err
 	}
@@ -29,6 +31,9 @@ func (h *AgentHandler) Get(c echo.Context) error {
 		agent.IPv4 = ""
 		agent.IPv6 = ""
 		agent.Hostname = ""
+		agent.Attributes = datatypes.JSONType[map[string]string]{}
+		agent.ReportedAttributes = datatypes.JSONType[map[string]string]{}
+		agent.Health = datatypes.JSONType[models.AgentHealthData]{}
 	}
 
 	return orz.Ok(c, agent)
@@ -36,14 +41,39 @@ func (h *AgentHandler) Get(c echo.Context) error {
 
 // GetAgents 获取探针列表（公开接口，已登录返回全部，未登录返回公开可见）
 func (h *AgentHandler) GetAgents(c echo.Context) error {
-	ctx := c.Request().Context()
+	result, err := h.buildAgentList(c)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, result)
+}
 
-	// 根据认证状态返回相应的探针列表
-	isAuthenticated := utils.IsAuthenticated(c)
-	agents, err := h.agentService.ListByAuth(ctx, isAuthenticated)
+// GetAgentsPaged 分页获取探针列表，可见范围和排序与 GetAgents 一致
+func (h *AgentHandler) GetAgentsPaged(c echo.Context) error {
+	result, err := h.buildAgentList(c)
 	if err != nil {
 		return err
 	}
+	return orz.Ok(c, paginateSlice(result, getPageRequest(c)))
+}
+
+// buildAgentList 按认证状态构建排序后的探针列表
+func (h *AgentHandler) buildAgentList(c echo.Context) ([]map[string]interface{}, error) {
+	// 根据认证状态返回相应的探针列表，分享令牌只返回范围内的探针
+	isAuthenticated := utils.IsAuthenticated(c)
+	agents, err := h.listAgentsByRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已登录时支持按属性筛选（attr=key=value）
+	if isAuthenticated {
+		selector, err := service.ParseAttributeSelector(c.QueryParams()["attr"])
+		if err != nil {
+			return nil, NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+		}
+		agents = service.FilterAgentsByAttributes(agents, selector)
+	}
 
 	// 排序
 	SortAgents(agents)
@@ -52,8 +82,7 @@ func (h *AgentHandler) GetAgents(c echo.Context) error {
 	for _, agent := range agents {
 		result = append(result, h.buildAgentListItem(agent, isAuthenticated))
 	}
-
-	return orz.Ok(c, result)
+	return result, nil
 }
 
 func (h *AgentHandler) buildAgentListItem(agent models.Agent, isAuthenticated bool) map[string]interface{} {
@@ -71,6 +100,10 @@ func (h *AgentHandler) buildAgentListItem(agent models.Agent, isAuthenticated bo
 		"weight":     agent.Weight,
 	}
 
+	if isAuthenticated {
+		item["attributes"] = agent.EffectiveAttributes()
+	}
+
 	trafficStats := agent.TrafficStats.Data()
 	if trafficStats.Enabled {
 		item["trafficStats"] = trafficStats
@@ -107,3 +140,27 @@ func (h *AgentHandler) GetTags(c echo.Context) error {
 		"tags": tags,
 	})
 }
+
+// shareScope 获取请求携带的分享令牌访问范围，未使用分享令牌时返回 nil
+func shareScope(c echo.Context) *service.ShareScope {
+	scope, _ := c.Get("shareScope").(*service.ShareScope)
+	return scope
+}
+
+// getAgentByRequest 按请求的认证方式获取探针：已登录返回全部，分享令牌返回范围内的探针，否则只返回公开可见
+func (h *AgentHandler) getAgentByRequest(c echo.Context, id string) (*models.Agent, error) {
+	ctx := c.Request().Context()
+	if scope := shareScope(c); scope != nil {
+		return h.agentService.GetAgentByShareScope(ctx, id, scope)
+	}
+	return h.agentService.GetAgentByAuth(ctx, id, utils.IsAuthenticated(c))
+}
+
+// listAgentsByRequest 按请求的认证方式列出探针：已登录返回全部，分享令牌返回范围内的探针，否则只返回公开可见
+func (h *AgentHandler) listAgentsByRequest(c echo.Context) ([]models.Agent, error) {
+	ctx := c.Request().Context()
+	if scope := shareScope(c); scope != nil {
+		return h.agentService.ListByShareScope(ctx, scope)
+	}
+	return h.agentService.ListByAuth(ctx, utils.IsAuthenticated(c))
+}
@@ -3,10 +3,13 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/dushixiang/pika/internal/models"
 	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/service"
 	ws "github.com/dushixiang/pika/internal/websocket"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
@@ -21,14 +24,31 @@ func (h *AgentHandler) HandleWebSocket(c echo.Context) error {
 		return err
 	}
 
-	registerReq, err := h.readRegisterRequest(conn)
+	registerReq, challenge, err := h.readRegisterRequest(conn)
 	if err != nil {
 		conn.Close()
 		return err
 	}
 
+	// 超过最大连接数时拒绝连接，使用 1013 (Try Again Later) 让探针退避重连
+	// 通过检查后预留名额，直到注册到管理器或注册失败，避免并发注册超过最大连接数
+	reservedID := registerReq.AgentInfo.ID
+	if !h.wsManager.Reserve(reservedID) {
+		h.logger.Warn("too many connections, rejecting agent", zap.String("agentID", reservedID))
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too many connections")
+		_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		return nil
+	}
+	registered := false
+	defer func() {
+		if !registered {
+			h.wsManager.Release(reservedID)
+		}
+	}()
+
 	// 注册探针 - 使用独立的context,不依赖HTTP请求的context
-	agent, err := h.agentService.RegisterAgent(context.Background(), c.RealIP(), &registerReq.AgentInfo, registerReq.ApiKey)
+	agent, err := h.agentService.RegisterAgent(context.Background(), c.RealIP(), &registerReq.AgentInfo, registerReq.ApiKey, registerReq.Signature, challenge)
 	if err != nil {
 		// 发送注册失败响应
 		h.sendRegisterError(conn, err.Error())
@@ -40,8 +60,11 @@ func (h *AgentHandler) HandleWebSocket(c echo.Context) error {
 		h.markAgentOffline(agent.ID)
 	}()
 
+	// 协商消息压缩算法
+	compression := ws.NegotiateCompression(append(registerReq.Compressions, registerReq.Compression)...)
+
 	// 发送注册成功响应
-	if err := h.sendRegisterSuccess(conn, agent.ID); err != nil {
+	if err := h.sendRegisterSuccess(conn, agent.ID, compression); err != nil {
 		h.logger.Error("failed to send register ack", zap.Error(err))
 		conn.Close()
 		return err
@@ -52,21 +75,16 @@ func (h *AgentHandler) HandleWebSocket(c echo.Context) error {
 		h.logger.Error("failed to send tamper config", zap.Error(err))
 		// 配置下发失败不中断连接，只记录日志
 	}
-	// 下发SSH登录监控配置
-	if err := h.sendSSHLoginConfig(conn, agent.ID); err != nil {
-		h.logger.Error("failed to send ssh login config", zap.Error(err))
-		// 配置下发失败不中断连接，只记录日志
-	}
-	// 下发公网 IP 采集配置
-	if err := h.sendPublicIPConfig(conn, agent.ID); err != nil {
-		h.logger.Error("failed to send public ip config", zap.Error(err))
-		// 配置下发失败不中断连接，只记录日志
-	}
+	// 下发SSH登录监控、公网 IP 采集、指标采集策略和自定义检查配置
+	h.sendAgentConfig(conn, agent)
 
 	// 创建客户端并注册到管理器
 	client := h.newClient(agent.ID, conn)
+	client.Compression = compression
+	client.RemoteAddr = c.RealIP()
 
-	h.wsManager.Register(client)
+	h.wsManager.Register(client, reservedID)
+	registered = true
 
 	// 启动读写协程
 	go client.WritePump()
@@ -78,7 +96,7 @@ func (h *AgentHandler) HandleWebSocket(c echo.Context) error {
 func (h *AgentHandler) handleWebSocketMessage(ctx context.Context, agentID string, messageType string, data json.RawMessage) error {
 	switch protocol.MessageType(messageType) {
 	case protocol.MessageTypeHeartbeat:
-		return h.handleHeartbeatMessage(ctx, agentID)
+		return h.handleHeartbeatMessage(ctx, agentID, data)
 
 	case protocol.MessageTypeMetrics:
 		return h.handleMetricsMessage(ctx, agentID, data)
@@ -104,13 +122,58 @@ func (h *AgentHandler) handleWebSocketMessage(ctx context.Context, agentID strin
 	case protocol.MessageTypeTamperProtect:
 		return h.handleTamperProtectMessage(ctx, agentID, data)
 
+	case protocol.MessageTypeLogTailData:
+		return h.handleLogTailDataMessage(agentID, data)
+
 	default:
 		h.logger.Warn("unknown message type", zap.String("type", messageType))
 		return nil
 	}
 }
 
-func (h *AgentHandler) readRegisterRequest(conn *websocket.Conn) (*protocol.RegisterRequest, error) {
+// readRegisterRequest 读取注册请求，返回请求和本次连接下发的签名挑战随机数（未请求挑战时为空）
+func (h *AgentHandler) readRegisterRequest(conn *websocket.Conn) (*protocol.RegisterRequest, string, error) {
+	msg, err := h.readRegisterMessage(conn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 签名注册的探针先请求挑战，使用服务端下发的随机数签名，防止注册消息被重放
+	var challenge string
+	if msg.Type == protocol.MessageTypeRegisterChallenge {
+		challenge, err = service.NewRegisterChallenge()
+		if err != nil {
+			h.logger.Error("failed to generate register challenge", zap.Error(err))
+			return nil, "", err
+		}
+		if err := conn.WriteJSON(protocol.OutboundMessage{
+			Type: protocol.MessageTypeRegisterChallenge,
+			Data: protocol.RegisterChallenge{Nonce: challenge},
+		}); err != nil {
+			h.logger.Error("failed to send register challenge", zap.Error(err))
+			return nil, "", err
+		}
+		if msg, err = h.readRegisterMessage(conn); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if msg.Type != protocol.MessageTypeRegister {
+		h.logger.Error("first message must be register", zap.String("type", string(msg.Type)))
+		return nil, "", echo.NewHTTPError(http.StatusBadRequest, "首条消息必须是注册消息")
+	}
+
+	var registerReq protocol.RegisterRequest
+	if err := json.Unmarshal(msg.Data, &registerReq); err != nil {
+		h.logger.Error("failed to parse register request", zap.Error(err))
+		return nil, "", err
+	}
+
+	return &registerReq, challenge, nil
+}
+
+// readRegisterMessage 读取注册阶段的一条消息，超过 10 秒未收到时断开
+func (h *AgentHandler) readRegisterMessage(conn *websocket.Conn) (*protocol.InputMessage, error) {
 	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 	_, message, err := conn.ReadMessage()
 	if err != nil {
@@ -125,19 +188,7 @@ func (h *AgentHandler) readRegisterRequest(conn *websocket.Conn) (*protocol.Regi
 		h.logger.Error("failed to parse register message", zap.Error(err))
 		return nil, err
 	}
-
-	if msg.Type != protocol.MessageTypeRegister {
-		h.logger.Error("first message must be register", zap.String("type", string(msg.Type)))
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "首条消息必须是注册消息")
-	}
-
-	var registerReq protocol.RegisterRequest
-	if err := json.Unmarshal(msg.Data, &registerReq); err != nil {
-		h.logger.Error("failed to parse register request", zap.Error(err))
-		return nil, err
-	}
-
-	return &registerReq, nil
+	return &msg, nil
 }
 
 func (h *AgentHandler) markAgentOffline(agentID string) {
@@ -148,26 +199,58 @@ func (h *AgentHandler) newClient(agentID string, conn *websocket.Conn) *ws.Clien
 	return &ws.Client{
 		ID:         agentID,
 		Conn:       conn,
-		Send:       make(chan []byte, 256),
+		Send:       h.wsManager.NewSendChannel(),
 		Manager:    h.wsManager,
 		LastActive: time.Now(),
 	}
 }
 
-func (h *AgentHandler) handleHeartbeatMessage(ctx context.Context, agentID string) error {
-	return h.agentService.UpdateAgentStatus(ctx, agentID, 1)
+func (h *AgentHandler) handleHeartbeatMessage(ctx context.Context, agentID string, data json.RawMessage) error {
+	// 旧版本探针的心跳不携带运行状况，解析失败时也只更新在线状态
+	var heartbeat protocol.HeartbeatData
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &heartbeat); err != nil {
+			h.logger.Warn("failed to parse heartbeat", zap.String("agentId", agentID), zap.Error(err))
+		}
+	}
+	if heartbeat.Health == nil {
+		return h.agentService.UpdateAgentStatus(ctx, agentID, 1)
+	}
+	return h.agentService.UpdateAgentHealth(ctx, agentID, *heartbeat.Health)
 }
 
 func (h *AgentHandler) handleMetricsMessage(ctx context.Context, agentID string, data json.RawMessage) error {
-	var metricsWrapper protocol.MetricsPayload
+	receivedAt := time.Now().UnixMilli()
+	// 指标数据保留原始 JSON，只在服务层按指标类型解析一次
+	var metricsWrapper protocol.InputMetricsPayload
 	if err := json.Unmarshal(data, &metricsWrapper); err != nil {
 		return err
 	}
-	metricsData, err := json.Marshal(metricsWrapper.Data)
+	h.metricService.RecordIngestion(ctx, agentID, string(metricsWrapper.Type), metricsWrapper.Timestamp, metricsWrapper.CollectDuration, receivedAt)
+	err := h.metricService.HandleMetricData(ctx, agentID, string(metricsWrapper.Type), metricsWrapper.Data, metricsWrapper.Timestamp)
+
+	// 部分条目处理失败时告知探针具体失败的条目，服务层已记录日志
+	var partialErr *service.MetricPartialError
+	if errors.As(err, &partialErr) {
+		h.sendMetricsResult(agentID, partialErr.Result())
+		return nil
+	}
+	return err
+}
+
+// sendMetricsResult 回传指标处理结果
+func (h *AgentHandler) sendMetricsResult(agentID string, result protocol.MetricsResult) {
+	msgData, err := json.Marshal(protocol.OutboundMessage{
+		Type: protocol.MessageTypeMetricsResult,
+		Data: result,
+	})
 	if err != nil {
-		return err
+		h.logger.Error("failed to marshal metrics result", zap.Error(err))
+		return
+	}
+	if err := h.wsManager.SendToClient(agentID, msgData); err != nil {
+		h.logger.Warn("failed to send metrics result", zap.String("agentID", agentID), zap.Error(err))
 	}
-	return h.metricService.HandleMetricData(ctx, agentID, string(metricsWrapper.Type), metricsData, metricsWrapper.Timestamp)
 }
 
 func (h *AgentHandler) handleCommandResponseMessage(ctx context.Context, agentID string, data json.RawMessage) error {
@@ -232,11 +315,22 @@ func (h *AgentHandler) handleTamperProtectMessage(ctx context.Context, agentID s
 	return h.tamperService.HandleConfigResult(ctx, agentID, protectResp)
 }
 
+func (h *AgentHandler) handleLogTailDataMessage(agentID string, data json.RawMessage) error {
+	var tailData protocol.LogTailData
+	if err := json.Unmarshal(data, &tailData); err != nil {
+		h.logger.Error("failed to unmarshal log tail data", zap.Error(err))
+		return err
+	}
+	h.logTailService.HandleData(agentID, tailData)
+	return nil
+}
+
 // sendRegisterSuccess 发送注册成功响应
-func (h *AgentHandler) sendRegisterSuccess(conn *websocket.Conn, agentID string) error {
+func (h *AgentHandler) sendRegisterSuccess(conn *websocket.Conn, agentID, compression string) error {
 	resp := protocol.RegisterResponse{
-		AgentID: agentID,
-		Status:  "success",
+		AgentID:     agentID,
+		Status:      "success",
+		Compression: compression,
 	}
 	return conn.WriteJSON(protocol.OutboundMessage{
 		Type: protocol.MessageTypeRegisterAck,
@@ -282,52 +376,36 @@ func (h *AgentHandler) sendTamperConfig(conn *websocket.Conn, agentID string) er
 	return conn.WriteMessage(websocket.TextMessage, msgData)
 }
 
-func (h *AgentHandler) sendSSHLoginConfig(conn *websocket.Conn, agentID string) error {
-	config, err := h.sshLoginService.GetConfig(context.Background(), agentID)
-	if err != nil {
-		return err
-	}
-	msgData, err := json.Marshal(protocol.OutboundMessage{
-		Type: protocol.MessageTypeSSHLoginConfig,
-		Data: protocol.SSHLoginConfig{
-			Enabled: config.Enabled,
-		},
-	})
+// sendAgentConfig 连接时逐项下发探针配置，使用各自的消息类型以兼容不支持 reload_config 的旧版探针
+// 某一项获取或发送失败不影响其他项，也不中断连接
+func (h *AgentHandler) sendAgentConfig(conn *websocket.Conn, agent *models.Agent) {
+	config, err := h.agentConfigs.BuildConfig(context.Background(), agent)
 	if err != nil {
-		return err
+		h.logger.Error("failed to build agent config", zap.String("agentID", agent.ID), zap.Error(err))
 	}
-	return conn.WriteMessage(websocket.TextMessage, msgData)
-}
 
-func (h *AgentHandler) sendPublicIPConfig(conn *websocket.Conn, agentID string) error {
-	config, err := h.propertyService.GetPublicIPConfig(context.Background())
-	if err != nil {
-		return err
+	messages := make([]protocol.OutboundMessage, 0, 4)
+	if config.SSHLogin != nil {
+		messages = append(messages, protocol.OutboundMessage{Type: protocol.MessageTypeSSHLoginConfig, Data: config.SSHLogin})
 	}
-
-	if !config.Enabled || (!config.IPv4Enabled && !config.IPv6Enabled) {
-		return nil
+	if config.PublicIP != nil {
+		messages = append(messages, protocol.OutboundMessage{Type: protocol.MessageTypePublicIPConfig, Data: config.PublicIP})
 	}
-
-	ipv4Enabled := config.IsIPv4Target(agentID)
-	ipv6Enabled := config.IsIPv6Target(agentID)
-	if !ipv4Enabled && !ipv6Enabled {
-		return nil
+	if config.MetricPolicy != nil {
+		messages = append(messages, protocol.OutboundMessage{Type: protocol.MessageTypeMetricPolicy, Data: config.MetricPolicy})
+	}
+	if config.CustomChecks != nil {
+		messages = append(messages, protocol.OutboundMessage{Type: protocol.MessageTypeCustomCheckConfig, Data: config.CustomChecks})
 	}
 
-	msgData, err := json.Marshal(protocol.OutboundMessage{
-		Type: protocol.MessageTypePublicIPConfig,
-		Data: protocol.PublicIPConfigData{
-			Enabled:         config.Enabled,
-			IntervalSeconds: config.IntervalSeconds,
-			IPv4Enabled:     ipv4Enabled,
-			IPv6Enabled:     ipv6Enabled,
-			IPv4APIs:        config.IPv4APIs,
-			IPv6APIs:        config.IPv6APIs,
-		},
-	})
-	if err != nil {
-		return err
+	for _, msg := range messages {
+		msgData, err := json.Marshal(msg)
+		if err != nil {
+			h.logger.Error("failed to marshal agent config", zap.String("type", string(msg.Type)), zap.Error(err))
+			continue
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, msgData); err != nil {
+			h.logger.Error("failed to send agent config", zap.String("type", string(msg.Type)), zap.Error(err))
+		}
 	}
-	return conn.WriteMessage(websocket.TextMessage, msgData)
 }
@@ -1,13 +1,25 @@
 package handler
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/dushixiang/pika/internal/service"
+)
 
 func normalizeAggregation(raw string) string {
 	value := strings.ToLower(strings.TrimSpace(raw))
 	switch value {
-	case "avg", "max":
+	case "avg", "max", "last":
 		return value
 	default:
 		return ""
 	}
 }
+
+// normalizeDecimation 规范化数据点缩减算法，不支持的值按步长聚合处理
+func normalizeDecimation(raw string) string {
+	if strings.EqualFold(strings.TrimSpace(raw), service.DecimationLTTB) {
+		return service.DecimationLTTB
+	}
+	return ""
+}
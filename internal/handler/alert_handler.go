@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/dushixiang/pika/internal/repo"
 	"github.com/dushixiang/pika/internal/service"
 	"github.com/go-orz/orz"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type AlertHandler struct {
@@ -23,25 +28,96 @@ func NewAlertHandler(logger *zap.Logger, alertService *service.AlertService) *Al
 
 // ListAlertRecords 列出告警记录
 func (h *AlertHandler) ListAlertRecords(c echo.Context) error {
-	agentID := c.QueryParam("agentId")
-
-	pr := orz.GetPageRequest(c, "createdAt", "firedAt")
-
-	builder := orz.NewPageBuilder(h.alertService.AlertRecordRepo.Repository).
-		PageRequest(pr)
+	query := repo.AlertRecordQuery{
+		AgentID:   c.QueryParam("agentId"),
+		AlertType: c.QueryParam("alertType"),
+		Level:     c.QueryParam("level"),
+		Status:    c.QueryParam("status"),
+		Keyword:   strings.TrimSpace(c.QueryParam("keyword")),
+	}
 
-	if agentID != "" {
-		builder.Equal("agent_id", agentID)
+	var err error
+	if query.StartTime, err = parseTimestampParam(c, "start"); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "开始时间格式错误")
+	}
+	if query.EndTime, err = parseTimestampParam(c, "end"); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "结束时间格式错误")
 	}
+	if query.StartTime > 0 && query.EndTime > 0 && query.StartTime > query.EndTime {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, "开始时间不能晚于结束时间")
+	}
+
+	pr := getPageRequest(c, "createdAt", "firedAt")
 
 	ctx := c.Request().Context()
-	page, err := builder.Execute(ctx)
+	page, err := h.alertService.AlertRecordRepo.FindAlertRecordPage(ctx, query, pr)
 	if err != nil {
 		h.logger.Error("获取告警记录失败", zap.Error(err))
 		return err
 	}
+	if err := h.alertService.FillCommentCounts(ctx, page.Items); err != nil {
+		h.logger.Error("统计告警备注数量失败", zap.Error(err))
+		return err
+	}
 
-	return orz.Ok(c, page)
+	return orz.Ok(c, newPageResponse(pr, page))
+}
+
+// parseTimestampParam 解析毫秒时间戳查询参数，未传时返回 0
+func parseTimestampParam(c echo.Context, name string) (int64, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// alertBatchRequest 批量操作请求，ids 为空时按 agentId + alertType 筛选告警中的记录
+type alertBatchRequest struct {
+	IDs       []int64 `json:"ids"`
+	AgentID   string  `json:"agentId"`
+	AlertType string  `json:"alertType"`
+}
+
+// resolveBatchIDs 解析批量操作的目标告警ID
+func (h *AlertHandler) resolveBatchIDs(c echo.Context) ([]int64, error) {
+	var req alertBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+	if len(req.IDs) > 0 {
+		return req.IDs, nil
+	}
+	if req.AgentID == "" && req.AlertType == "" {
+		return nil, NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请指定告警ID列表或筛选条件")
+	}
+	return h.alertService.FindFiringAlertIDs(c.Request().Context(), req.AgentID, req.AlertType)
+}
+
+// AckAlertRecords 批量确认告警
+func (h *AlertHandler) AckAlertRecords(c echo.Context) error {
+	ids, err := h.resolveBatchIDs(c)
+	if err != nil {
+		return err
+	}
+	username, _ := c.Get("username").(string)
+	results := h.alertService.AckAlerts(c.Request().Context(), ids, username)
+	return orz.Ok(c, orz.Map{
+		"items": results,
+	})
+}
+
+// ResolveAlertRecords 批量手动恢复告警
+func (h *AlertHandler) ResolveAlertRecords(c echo.Context) error {
+	ids, err := h.resolveBatchIDs(c)
+	if err != nil {
+		return err
+	}
+	username, _ := c.Get("username").(string)
+	results := h.alertService.ResolveAlerts(c.Request().Context(), ids, username)
+	return orz.Ok(c, orz.Map{
+		"items": results,
+	})
 }
 
 // ClearAlertRecords 清空告警记录
@@ -55,3 +131,88 @@ func (h *AlertHandler) ClearAlertRecords(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, echo.Map{})
 }
+
+// resetAlertStatesRequest 重置告警状态请求，字段为空时不按该条件过滤
+type resetAlertStatesRequest struct {
+	AgentID   string `json:"agentId"`
+	AlertType string `json:"alertType"`
+}
+
+// ResetAlertStates 清除告警状态，下一轮检查时重新评估
+func (h *AlertHandler) ResetAlertStates(c echo.Context) error {
+	var req resetAlertStatesRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	username, _ := c.Get("username").(string)
+	count, err := h.alertService.ResetAlertStates(c.Request().Context(), strings.TrimSpace(req.AgentID), strings.TrimSpace(req.AlertType), username)
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, orz.Map{
+		"count": count,
+	})
+}
+
+// alertCommentRequest 添加告警备注请求
+type alertCommentRequest struct {
+	Text string `json:"text"`
+}
+
+// ListAlertComments 列出告警记录的备注
+func (h *AlertHandler) ListAlertComments(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的告警ID")
+	}
+
+	comments, err := h.alertService.ListComments(c.Request().Context(), id)
+	if err != nil {
+		return h.alertCommentError(err)
+	}
+	return orz.Ok(c, comments)
+}
+
+// AddAlertComment 为告警记录添加备注
+func (h *AlertHandler) AddAlertComment(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "无效的告警ID")
+	}
+	var req alertCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	username, _ := c.Get("username").(string)
+	comment, err := h.alertService.AddComment(c.Request().Context(), id, username, req.Text)
+	if err != nil {
+		return h.alertCommentError(err)
+	}
+	return orz.Ok(c, comment)
+}
+
+// alertCommentError 将告警备注相关错误转换为接口错误
+func (h *AlertHandler) alertCommentError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewAPIError(http.StatusNotFound, ErrNotFound, "告警记录不存在")
+	case errors.Is(err, service.ErrAlertCommentEmpty), errors.Is(err, service.ErrAlertCommentTooLong):
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+	h.logger.Error("处理告警备注失败", zap.Error(err))
+	return err
+}
+
+// GetEffectiveAlertConfig 预览探针合并后实际生效的告警配置及各值的来源
+func (h *AlertHandler) GetEffectiveAlertConfig(c echo.Context) error {
+	config, err := h.alertService.GetEffectiveAlertConfig(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewAPIError(http.StatusNotFound, ErrNotFound, "探针不存在")
+		}
+		return err
+	}
+	return orz.Ok(c, config)
+}
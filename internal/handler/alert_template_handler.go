@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+// alertTemplateRequest 创建或复制告警规则模板请求
+type alertTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateAlertTemplateFromConfig 以当前全局告警规则创建模板
+func (h *PropertyHandler) CreateAlertTemplateFromConfig(c echo.Context) error {
+	var req alertTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "模板名称不能为空")
+	}
+
+	template, err := h.service.CreateAlertRuleTemplateFromConfig(c.Request().Context(), name, strings.TrimSpace(req.Description))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, template)
+}
+
+// CloneAlertTemplate 复制告警规则模板，名称为空时使用原名称加“副本”
+func (h *PropertyHandler) CloneAlertTemplate(c echo.Context) error {
+	var req alertTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	template, err := h.service.CloneAlertRuleTemplate(c.Request().Context(), c.Param("id"), strings.TrimSpace(req.Name))
+	if err != nil {
+		if errors.Is(err, service.ErrAlertTemplateNotFound) {
+			return NewAPIError(http.StatusNotFound, ErrNotFound, err.Error())
+		}
+		return err
+	}
+	return orz.Ok(c, template)
+}
+
+// alertTemplateInstantiateRequest 将模板应用到一组探针的请求
+type alertTemplateInstantiateRequest struct {
+	Tags            []string          `json:"tags"`
+	AgentAttributes map[string]string `json:"agentAttributes"`
+}
+
+// InstantiateAlertTemplate 将模板应用到带有指定标签或属性的探针，返回更新后的全部模板绑定
+func (h *PropertyHandler) InstantiateAlertTemplate(c echo.Context) error {
+	var req alertTemplateInstantiateRequest
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+	if len(req.Tags) == 0 && len(req.AgentAttributes) == 0 {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "必须指定标签或属性")
+	}
+
+	bindings, err := h.service.InstantiateAlertRuleTemplate(c.Request().Context(), c.Param("id"), models.AlertTemplateBinding{
+		Tags:            req.Tags,
+		AgentAttributes: req.AgentAttributes,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrAlertTemplateNotFound) {
+			return NewAPIError(http.StatusNotFound, ErrNotFound, err.Error())
+		}
+		return err
+	}
+	return orz.Ok(c, bindings)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AnnotationAPIKeyHeader 外部系统上报注释时携带 API 密钥的请求头
+const AnnotationAPIKeyHeader = "X-API-Key"
+
+type AnnotationHandler struct {
+	logger            *zap.Logger
+	annotationService *service.AnnotationService
+	apiKeyService     *service.ApiKeyService
+}
+
+func NewAnnotationHandler(logger *zap.Logger, annotationService *service.AnnotationService, apiKeyService *service.ApiKeyService) *AnnotationHandler {
+	return &AnnotationHandler{
+		logger:            logger,
+		annotationService: annotationService,
+		apiKeyService:     apiKeyService,
+	}
+}
+
+// List 查询与时间窗口有重叠的注释
+// 路由参数 id 存在时返回该探针的注释和全局注释，否则可通过 agentId 查询参数过滤
+func (h *AnnotationHandler) List(c echo.Context) error {
+	agentID := c.Param("id")
+	if agentID == "" {
+		agentID = c.QueryParam("agentId")
+	}
+	start, end, err := parseTimeRangeOrStartEnd(c.QueryParam("range"), c.QueryParam("start"), c.QueryParam("end"))
+	if err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidTimeRange, err.Error())
+	}
+
+	annotations, err := h.annotationService.ListInRange(c.Request().Context(), agentID, start, end, strings.TrimSpace(c.QueryParam("tag")))
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, annotations)
+}
+
+// Create 创建注释（管理员）
+func (h *AnnotationHandler) Create(c echo.Context) error {
+	var req service.AnnotationInput
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	username, _ := c.Get("username").(string)
+	annotation, err := h.annotationService.Create(c.Request().Context(), req, models.AnnotationSourceManual, username)
+	if err != nil {
+		return h.annotationError(err)
+	}
+	return orz.Ok(c, annotation)
+}
+
+// Update 修改注释
+func (h *AnnotationHandler) Update(c echo.Context) error {
+	var req service.AnnotationInput
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	annotation, err := h.annotationService.Update(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return h.annotationError(err)
+	}
+	return orz.Ok(c, annotation)
+}
+
+// Delete 删除注释
+func (h *AnnotationHandler) Delete(c echo.Context) error {
+	if err := h.annotationService.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return h.annotationError(err)
+	}
+	return orz.Ok(c, nil)
+}
+
+// Ingest 供探针或 CI 等外部系统上报注释，使用 API 密钥认证
+func (h *AnnotationHandler) Ingest(c echo.Context) error {
+	apiKey, err := h.apiKeyService.ValidateApiKey(c.Request().Context(), c.Request().Header.Get(AnnotationAPIKeyHeader))
+	if err != nil {
+		return orz.NewError(401, "无效的 API 密钥")
+	}
+
+	var req service.AnnotationInput
+	if err := c.Bind(&req); err != nil {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "请求参数错误")
+	}
+
+	annotation, err := h.annotationService.Create(c.Request().Context(), req, models.AnnotationSourceAPI, apiKey.Name)
+	if err != nil {
+		return h.annotationError(err)
+	}
+	return orz.Ok(c, annotation)
+}
+
+// annotationError 将注释相关错误转换为接口错误
+func (h *AnnotationHandler) annotationError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewAPIError(http.StatusNotFound, ErrNotFound, "注释不存在")
+	case errors.Is(err, service.ErrInvalidAnnotation):
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+	}
+	h.logger.Error("处理注释失败", zap.Error(err))
+	return err
+}
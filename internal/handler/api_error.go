@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ErrorCode 机器可读的错误码，前端据此做本地化和分支处理，取值保持稳定
+type ErrorCode string
+
+const (
+	ErrBadRequest   ErrorCode = "ERR_BAD_REQUEST"
+	ErrUnauthorized ErrorCode = "ERR_UNAUTHORIZED"
+	ErrForbidden    ErrorCode = "ERR_FORBIDDEN"
+	ErrNotFound     ErrorCode = "ERR_NOT_FOUND"
+	ErrInternal     ErrorCode = "ERR_INTERNAL"
+
+	// 认证相关
+	ErrInvalidCredentials ErrorCode = "ERR_INVALID_CREDENTIALS"
+	ErrLoginRegionDenied  ErrorCode = "ERR_LOGIN_REGION_DENIED"
+	ErrOIDCDisabled       ErrorCode = "ERR_OIDC_DISABLED"
+	ErrOIDCFailed         ErrorCode = "ERR_OIDC_FAILED"
+	ErrGitHubDisabled     ErrorCode = "ERR_GITHUB_DISABLED"
+	ErrGitHubFailed       ErrorCode = "ERR_GITHUB_FAILED"
+	ErrPasswordTooShort   ErrorCode = "ERR_PASSWORD_TOO_SHORT"
+	ErrTokenMissing       ErrorCode = "ERR_TOKEN_MISSING"
+	ErrTokenInvalid       ErrorCode = "ERR_TOKEN_INVALID"
+	ErrShareTokenInvalid  ErrorCode = "ERR_SHARE_TOKEN_INVALID"
+	ErrReadOnly           ErrorCode = "ERR_READ_ONLY"
+
+	// 参数相关
+	ErrInvalidParam     ErrorCode = "ERR_INVALID_PARAM"
+	ErrInvalidTimeRange ErrorCode = "ERR_INVALID_TIME_RANGE"
+)
+
+// APIError 带 HTTP 状态码和错误码的接口错误，统一输出为 {"code": 状态码, "errorCode": 错误码, "message": 描述}
+type APIError struct {
+	Status    int
+	ErrorCode ErrorCode
+	Message   string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// NewAPIError 创建接口错误
+func NewAPIError(status int, code ErrorCode, message string) *APIError {
+	return &APIError{
+		Status:    status,
+		ErrorCode: code,
+		Message:   message,
+	}
+}
+
+// NewAPIErrorf 创建接口错误，message 支持格式化
+func NewAPIErrorf(status int, code ErrorCode, format string, args ...any) *APIError {
+	return NewAPIError(status, code, fmt.Sprintf(format, args...))
+}
+
+// ErrorCodeFromStatus 未指定错误码的错误按 HTTP 状态码给出通用错误码
+func ErrorCodeFromStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusInternalServerError:
+		return ErrInternal
+	}
+	if status >= 500 {
+		return ErrInternal
+	}
+	return ErrBadRequest
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type CustomCheckHandler struct {
+	logger             *zap.Logger
+	customCheckService *service.CustomCheckService
+}
+
+func NewCustomCheckHandler(logger *zap.Logger, customCheckService *service.CustomCheckService) *CustomCheckHandler {
+	return &CustomCheckHandler{
+		logger:             logger,
+		customCheckService: customCheckService,
+	}
+}
+
+// List 获取所有自定义检查
+func (h *CustomCheckHandler) List(c echo.Context) error {
+	checks, err := h.customCheckService.ListChecks(c.Request().Context())
+	if err != nil {
+		h.logger.Error("获取自定义检查失败", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "获取自定义检查失败")
+	}
+	return c.JSON(http.StatusOK, checks)
+}
+
+// Save 整体保存自定义检查列表，保存后立即下发给在线探针
+func (h *CustomCheckHandler) Save(c echo.Context) error {
+	var checks []models.CustomCheck
+	if err := c.Bind(&checks); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "请求参数错误")
+	}
+
+	saved, err := h.customCheckService.SaveChecks(c.Request().Context(), checks)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, saved)
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/labstack/echo/v4"
+)
+
+// checkLatestMetricsNotModified 写入 ETag/Last-Modified 响应头，客户端缓存仍然有效时返回 true
+func checkLatestMetricsNotModified(c echo.Context, metrics *metric.LatestMetrics, variant string) bool {
+	etag := metrics.ETag(variant)
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "no-cache")
+	if metrics.UpdatedAt > 0 {
+		header.Set("Last-Modified", time.UnixMilli(metrics.UpdatedAt).UTC().Format(http.TimeFormat))
+	}
+
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag || "W/"+candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -134,7 +134,7 @@ func (h *MonitorHandler) Delete(c echo.Context) error {
 // GetMonitors 获取所有监控统计数据
 func (h *MonitorHandler) GetMonitors(c echo.Context) error {
 	ctx := c.Request().Context()
-	stats, err := h.monitorService.ListByAuth(ctx, utils.IsAuthenticated(c))
+	stats, err := h.monitorService.ListByAuth(ctx, utils.IsAuthenticated(c), c.QueryParam("group"))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxNodeExporterBodySize 单次导入的数据上限，node_exporter 完整抓取结果通常在 1MB 以内
+const maxNodeExporterBodySize = 8 << 20
+
+type NodeExporterHandler struct {
+	logger              *zap.Logger
+	nodeExporterService *service.NodeExporterService
+	apiKeyService       *service.ApiKeyService
+}
+
+func NewNodeExporterHandler(logger *zap.Logger, nodeExporterService *service.NodeExporterService, apiKeyService *service.ApiKeyService) *NodeExporterHandler {
+	return &NodeExporterHandler{
+		logger:              logger,
+		nodeExporterService: nodeExporterService,
+		apiKeyService:       apiKeyService,
+	}
+}
+
+// Import 导入 node_exporter 抓取结果或 textfile 文件（Prometheus 文本格式），使用 API 密钥认证
+// 探针ID由配置的请求头携带，探针不存在时自动创建，已被 WebSocket 探针使用时返回 409
+func (h *NodeExporterHandler) Import(c echo.Context) error {
+	ctx := c.Request().Context()
+	apiKey, err := h.apiKeyService.ValidateApiKey(ctx, c.Request().Header.Get(AnnotationAPIKeyHeader))
+	if err != nil {
+		return orz.NewError(401, "无效的 API 密钥")
+	}
+
+	header := h.nodeExporterService.IdentityHeader()
+	agentID := strings.TrimSpace(c.Request().Header.Get(header))
+	if agentID == "" {
+		return NewAPIError(http.StatusBadRequest, ErrInvalidParam, "缺少请求头 "+header)
+	}
+
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxNodeExporterBodySize)
+	defer io.Copy(io.Discard, body)
+
+	result, err := h.nodeExporterService.Import(ctx, agentID, c.RealIP(), apiKey, body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return NewAPIError(http.StatusRequestEntityTooLarge, ErrInvalidParam, "导入数据过大")
+		case errors.Is(err, service.ErrInvalidImportAgentID), errors.Is(err, service.ErrNoImportableMetrics), errors.Is(err, service.ErrInvalidPromText):
+			return NewAPIError(http.StatusBadRequest, ErrInvalidParam, err.Error())
+		case errors.Is(err, service.ErrImportAgentIDInUse):
+			return NewAPIError(http.StatusConflict, ErrInvalidParam, err.Error())
+		}
+		h.logger.Error("导入 node_exporter 指标失败", zap.String("agentId", agentID), zap.Error(err))
+		return err
+	}
+	return orz.Ok(c, result)
+}
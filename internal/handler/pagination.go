@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+)
+
+// maxPageSize 单页最大数量，避免一次返回过多数据
+const maxPageSize = 1000
+
+// PageResponse 统一的分页响应
+type PageResponse[T any] struct {
+	Items    []T   `json:"items"`    // 当前页数据
+	Total    int64 `json:"total"`    // 总记录数
+	Page     int   `json:"page"`     // 当前页码，从 1 开始
+	PageSize int   `json:"pageSize"` // 每页数量
+}
+
+// getPageRequest 解析分页参数
+// 支持 page、pageSize、sort、order，同时兼容 orz 的 pageIndex、sortField、sortOrder；
+// sort 只能使用 allowedFields 中的字段，未传时使用第一个字段，order 默认 desc
+func getPageRequest(c echo.Context, allowedFields ...string) *orz.PageRequest {
+	pr := orz.GetPageRequest(c, allowedFields...)
+	if c.QueryParam("pageIndex") == "" {
+		if page, err := strconv.Atoi(c.QueryParam("page")); err == nil && page > 0 {
+			pr.PageIndex = page
+		}
+	}
+	if sort := c.QueryParam("sort"); sort != "" && c.QueryParam("sortField") == "" {
+		pr.SortField = sort
+	}
+	if order := c.QueryParam("order"); order != "" && c.QueryParam("sortOrder") == "" {
+		switch strings.ToLower(order) {
+		case "asc", "ascend":
+			pr.SortOrder = orz.ASC
+		default:
+			pr.SortOrder = orz.DESC
+		}
+	}
+	pr.PageSize = min(pr.PageSize, maxPageSize)
+	return pr
+}
+
+// hasPageParams 请求是否携带了页码或每页数量，用于兼容原先返回全部数据的接口
+func hasPageParams(c echo.Context) bool {
+	return c.QueryParam("page") != "" || c.QueryParam("pageIndex") != "" || c.QueryParam("pageSize") != ""
+}
+
+// newPageResponse 将 orz 分页结果转换为统一的分页响应
+func newPageResponse[T any](pr *orz.PageRequest, page *orz.PageResult[T]) *PageResponse[T] {
+	items := page.Items
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return &PageResponse[T]{
+		Items:    items,
+		Total:    page.Total,
+		Page:     pr.PageIndex,
+		PageSize: pr.PageSize,
+	}
+}
+
+// paginateSlice 对内存中已排序的列表分页
+func paginateSlice[T any](items []T, pr *orz.PageRequest) *PageResponse[T] {
+	total := len(items)
+	start := min((pr.PageIndex-1)*pr.PageSize, total)
+	end := min(start+pr.PageSize, total)
+	return &PageResponse[T]{
+		Items:    append(make([]T, 0, end-start), items[start:end]...),
+		Total:    int64(total),
+		Page:     pr.PageIndex,
+		PageSize: pr.PageSize,
+	}
+}
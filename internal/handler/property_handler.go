@@ -89,6 +89,137 @@ func (h *PropertyHandler) SetProperty(c echo.Context) error {
 					"message": "系统名称（中文）和系统名称（英文）不能同时为空",
 				})
 			}
+
+			// 指标卡片只允许已知的指标类型
+			if rawCards, ok := valMap["metricCards"].([]interface{}); ok {
+				cards := make([]string, 0, len(rawCards))
+				for _, raw := range rawCards {
+					card, _ := raw.(string)
+					cards = append(cards, card)
+				}
+				if err := service.ValidateMetricCards(cards); err != nil {
+					return c.JSON(http.StatusBadRequest, map[string]string{
+						"message": err.Error(),
+					})
+				}
+			}
+		}
+	}
+
+	// 特殊校验：通知渠道的超时和代理配置
+	if id == service.PropertyIDNotificationChannels {
+		var channels []models.NotificationChannelConfig
+		raw, _ := json.Marshal(req.Value)
+		if err := json.Unmarshal(raw, &channels); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "无效的通知渠道配置",
+			})
+		}
+		if err := service.ValidateNotificationChannels(channels); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+	}
+
+	// 特殊校验：告警升级策略
+	if id == service.PropertyIDAlertConfig {
+		var config models.AlertConfig
+		raw, _ := json.Marshal(req.Value)
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "无效的告警配置",
+			})
+		}
+		if err := service.ValidateAlertEscalation(&config.Escalation); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+		if len(config.TemplateBindings) > 0 {
+			templates, err := h.service.GetAlertRuleTemplates(c.Request().Context())
+			if err != nil {
+				h.logger.Error("获取告警规则模板失败", zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"message": "获取告警规则模板失败",
+				})
+			}
+			if err := service.ValidateAlertTemplateBindings(config.TemplateBindings, templates); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"message": err.Error(),
+				})
+			}
+		}
+	}
+
+	// 特殊校验：告警规则模板，被告警配置引用的模板不能删除
+	if id == service.PropertyIDAlertRuleTemplates {
+		var templates []models.AlertRuleTemplate
+		raw, _ := json.Marshal(req.Value)
+		if err := json.Unmarshal(raw, &templates); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "无效的告警规则模板",
+			})
+		}
+		alertConfig, err := h.service.GetAlertConfig(c.Request().Context())
+		if err != nil {
+			h.logger.Error("获取告警配置失败", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "获取告警配置失败",
+			})
+		}
+		if err := service.ValidateAlertRuleTemplates(templates, alertConfig.TemplateBindings); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+	}
+
+	// 特殊校验：公网 IP 采集配置
+	if id == service.PropertyIDPublicIPConfig {
+		var config models.PublicIPConfig
+		raw, _ := json.Marshal(req.Value)
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "无效的公网 IP 采集配置",
+			})
+		}
+		if err := service.ValidatePublicIPConfig(&config); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+	}
+
+	// 特殊校验：派生指标只允许白名单字段和四则运算
+	if id == service.PropertyIDDerivedMetrics {
+		var metrics []models.DerivedMetric
+		raw, _ := json.Marshal(req.Value)
+		if err := json.Unmarshal(raw, &metrics); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "无效的派生指标配置",
+			})
+		}
+		if err := service.ValidateDerivedMetrics(metrics); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+	}
+
+	// 特殊校验：入库降采样规则
+	if id == service.PropertyIDIngestDownsampling {
+		var config models.IngestDownsamplingConfig
+		raw, _ := json.Marshal(req.Value)
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "无效的入库降采样配置",
+			})
+		}
+		if err := service.ValidateIngestDownsamplingConfig(&config); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
 		}
 	}
 
@@ -102,9 +233,37 @@ func (h *PropertyHandler) SetProperty(c echo.Context) error {
 	return c.JSON(http.StatusOK, orz.Map{})
 }
 
-// GetLogo 获取系统 Logo（公开访问，返回图片文件流）
+// ListConfigAuditLogs 分页查询配置变更审计日志
+func (h *PropertyHandler) ListConfigAuditLogs(c echo.Context) error {
+	pageReq := orz.GetPageRequest(c, "createdAt")
+	builder := orz.NewPageBuilder(h.service.ConfigAuditLogRepo.Repository).
+		PageRequest(pageReq).
+		Equal("propertyId", c.QueryParam("propertyId")).
+		Equal("username", c.QueryParam("username"))
+
+	page, err := builder.Execute(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return orz.Ok(c, page)
+}
+
+// GetBranding 获取公开页面的品牌配置（公开访问，支持按分组覆盖）
+func (h *PropertyHandler) GetBranding(c echo.Context) error {
+	sysConfig, err := h.service.ResolveSystemConfig(c.Request().Context(), c.QueryParam("group"))
+	if err != nil {
+		return err
+	}
+
+	// Logo 通过 /api/logo 获取，避免重复传输
+	sysConfig.LogoBase64 = ""
+	return orz.Ok(c, sysConfig)
+}
+
+// GetLogo 获取系统 Logo（公开访问，返回图片文件流，支持按分组覆盖）
 func (h *PropertyHandler) GetLogo(c echo.Context) error {
-	sysConfig, err := h.service.GetSystemConfig(c.Request().Context())
+	sysConfig, err := h.service.ResolveSystemConfig(c.Request().Context(), c.QueryParam("group"))
 	if err != nil {
 		// 如果配置不存在，返回 404
 		return echo.NewHTTPError(http.StatusNotFound, "Logo 不存在")
@@ -170,6 +329,11 @@ func (h *PropertyHandler) GetLogo(c echo.Context) error {
 	return c.Blob(http.StatusOK, contentType, imageData)
 }
 
+// ListNotificationDeliveries 获取最近的通知投递结果（包含请求/响应字节数和最终地址）
+func (h *PropertyHandler) ListNotificationDeliveries(c echo.Context) error {
+	return orz.Ok(c, h.notifier.RecentDeliveries(c.QueryParam("type")))
+}
+
 // TestNotificationChannel 测试通知渠道（从数据库读取配置）
 func (h *PropertyHandler) TestNotificationChannel(c echo.Context) error {
 	channelType := c.Param("type")
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/dushixiang/pika/internal/service"
+	"github.com/go-orz/orz"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type ShareTokenHandler struct {
+	logger            *zap.Logger
+	shareTokenService *service.ShareTokenService
+}
+
+func NewShareTokenHandler(logger *zap.Logger, shareTokenService *service.ShareTokenService) *ShareTokenHandler {
+	return &ShareTokenHandler{
+		logger:            logger,
+		shareTokenService: shareTokenService,
+	}
+}
+
+// CreateShareTokenRequest 创建分享令牌请求
+type CreateShareTokenRequest struct {
+	Name         string   `json:"name" validate:"required"`
+	AgentIDs     []string `json:"agentIds"`
+	Tags         []string `json:"tags"`
+	ExpiresHours int      `json:"expiresHours" validate:"required,min=1,max=8760"`
+}
+
+// List 列出分享令牌
+func (r ShareTokenHandler) List(c echo.Context) error {
+	tokens, err := r.shareTokenService.List(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return orz.Ok(c, tokens)
+}
+
+// Create 创建分享令牌，令牌字符串只在此时返回
+func (r ShareTokenHandler) Create(c echo.Context) error {
+	var req CreateShareTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	username, _ := c.Get("username").(string)
+	ctx := c.Request().Context()
+	result, err := r.shareTokenService.Create(ctx, req.Name, username, req.AgentIDs, req.Tags, time.Duration(req.ExpiresHours)*time.Hour)
+	if err != nil {
+		r.logger.Error("failed to create share token", zap.Error(err))
+		return err
+	}
+
+	return orz.Ok(c, result)
+}
+
+// Revoke 吊销分享令牌
+func (r ShareTokenHandler) Revoke(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+
+	if err := r.shareTokenService.Revoke(ctx, id); err != nil {
+		r.logger.Error("failed to revoke share token", zap.Error(err))
+		return err
+	}
+
+	return orz.Ok(c, orz.Map{})
+}
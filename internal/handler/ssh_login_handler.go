@@ -73,7 +73,7 @@ func (h *SSHLoginHandler) ListEvents(c echo.Context) error {
 	agentID := c.Param("id")
 
 	// 获取分页参数
-	pageReq := orz.GetPageRequest(c, "createdAt")
+	pageReq := getPageRequest(c, "createdAt")
 	builder := orz.NewPageBuilder(h.service.SSHLoginEventRepo.Repository).
 		PageRequest(pageReq).
 		Equal("agentId", agentID).
@@ -87,7 +87,7 @@ func (h *SSHLoginHandler) ListEvents(c echo.Context) error {
 		return err
 	}
 
-	return orz.Ok(c, page)
+	return orz.Ok(c, newPageResponse(pageReq, page))
 }
 
 // GetEvent 获取单个SSH登录事件
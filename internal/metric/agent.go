@@ -1,6 +1,10 @@
 package metric
 
-import "github.com/dushixiang/pika/internal/protocol"
+import (
+	"fmt"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
 
 // DiskSummary 磁盘汇总数据
 type DiskSummary struct {
@@ -9,6 +13,14 @@ type DiskSummary struct {
 	Total        uint64  `json:"total"`        // 总容量(字节)
 	Used         uint64  `json:"used"`         // 已使用(字节)
 	Free         uint64  `json:"free"`         // 空闲(字节)
+
+	// 主磁盘（运维指定或自动识别），用于概览展示，没有磁盘数据时为空
+	PrimaryMountPoint   string  `json:"primaryMountPoint,omitempty"`
+	PrimarySource       string  `json:"primarySource,omitempty"` // 主磁盘来源: configured-运维指定, auto-自动识别
+	PrimaryUsagePercent float64 `json:"primaryUsagePercent"`
+	PrimaryTotal        uint64  `json:"primaryTotal"`
+	PrimaryUsed         uint64  `json:"primaryUsed"`
+	PrimaryFree         uint64  `json:"primaryFree"`
 }
 
 // NetworkSummary 网络汇总数据
@@ -18,6 +30,12 @@ type NetworkSummary struct {
 	TotalBytesSentTotal uint64 `json:"totalBytesSentTotal"` // 累计总发送流量
 	TotalBytesRecvTotal uint64 `json:"totalBytesRecvTotal"` // 累计总接收流量
 	TotalInterfaces     int    `json:"totalInterfaces"`     // 网卡数量
+
+	// 主网卡（运维指定或自动识别），用于概览展示，没有网卡数据时为空
+	PrimaryInterface     string `json:"primaryInterface,omitempty"`
+	PrimarySource        string `json:"primarySource,omitempty"` // 主网卡来源: configured-运维指定, auto-自动识别
+	PrimaryBytesSentRate uint64 `json:"primaryBytesSentRate"`
+	PrimaryBytesRecvRate uint64 `json:"primaryBytesRecvRate"`
 }
 
 // LatestMetrics 最新指标数据（用于API响应）
@@ -32,4 +50,42 @@ type LatestMetrics struct {
 	GPU               []protocol.GPUData              `json:"gpu,omitempty"`
 	Temp              []protocol.TemperatureData      `json:"temperature,omitempty"`
 	Monitors          []protocol.MonitorData          `json:"monitors,omitempty"`
+	Custom            []protocol.CustomMetricData     `json:"custom,omitempty"`
+	Load              *protocol.LoadData              `json:"load,omitempty"`
+	LoadFromHost      bool                            `json:"-"` // 负载取自主机信息（旧版探针不单独上报负载）
+	TraceID           string                          `json:"-"` // 最近一次上报的追踪ID，用于关联后续的告警判定
+	UpdatedAt         int64                           `json:"-"` // 最近一次上报的采样时间（毫秒），用于生成 ETag/Last-Modified
+	Revision          uint64                          `json:"-"` // 每次上报递增，区分同一采样时间内的多次更新
+}
+
+// MetricCoverage 单个指标类型的上报覆盖情况
+type MetricCoverage struct {
+	Type             string `json:"type"`             // 指标类型
+	Reported         bool   `json:"reported"`         // 回溯窗口内是否上报过
+	LastSeenAt       int64  `json:"lastSeenAt"`       // 最后一次上报时间（毫秒）
+	ExpectedInterval int64  `json:"expectedInterval"` // 期望上报间隔（秒）
+	Stale            bool   `json:"stale"`            // 是否已超过期望间隔未上报
+}
+
+// IngestionStats 探针指标上报的采集耗时与接收延迟统计
+type IngestionStats struct {
+	AgentID             string           `json:"agentId"`                 // 探针ID
+	LastCollectDuration int64            `json:"lastCollectDuration"`     // 最近一次采集耗时（毫秒）
+	MaxCollectDuration  int64            `json:"maxCollectDuration"`      // 最大采集耗时（毫秒）
+	MaxCollectType      string           `json:"maxCollectType"`          // 最大采集耗时对应的指标类型
+	LastLatency         int64            `json:"lastLatency"`             // 最近一次接收延迟：服务端接收时间 - 探针上报时间（毫秒），为负说明探针时钟偏快
+	AvgLatency          int64            `json:"avgLatency"`              // 接收延迟的指数移动平均（毫秒）
+	ClockSkewed         bool             `json:"clockSkewed"`             // 平均接收延迟超过容忍范围，疑似时钟偏差
+	Samples             int64            `json:"samples"`                 // 统计的上报次数
+	UpdatedAt           int64            `json:"updatedAt"`               // 最近一次接收时间（毫秒）
+	DroppedSamples      int64            `json:"droppedSamples"`          // 因上报过于频繁被丢弃的次数
+	DroppedByType       map[string]int64 `json:"droppedByType,omitempty"` // 各指标类型被丢弃的次数
+	LastDroppedAt       int64            `json:"lastDroppedAt,omitempty"` // 最近一次丢弃的时间（毫秒）
+	DownsampledSamples  int64            `json:"downsampledSamples"`      // 因入库降采样未单独写入的次数
+	PersistedSkew       int64            `json:"-"`                       // 最近一次持久化到数据库的时钟偏差（毫秒）
+}
+
+// ETag 根据最新采样时间和更新次数生成 ETag，无需序列化响应体
+func (m *LatestMetrics) ETag(variant string) string {
+	return fmt.Sprintf(`W/"%d-%d-%s"`, m.UpdatedAt, m.Revision, variant)
 }
@@ -0,0 +1,32 @@
+package metric
+
+// FleetSummary 所有在线探针最新指标的汇总，用于总览大盘
+type FleetSummary struct {
+	TotalAgents     int `json:"totalAgents"`     // 探针总数
+	OnlineAgents    int `json:"onlineAgents"`    // 在线探针数
+	ReportingAgents int `json:"reportingAgents"` // 有最新指标的在线探针数，以下汇总只统计这些探针
+
+	TotalCores      int     `json:"totalCores"`      // 逻辑核心总数
+	CoresInUse      float64 `json:"coresInUse"`      // 按各探针 CPU 使用率折算的在用核心数
+	CPUUsagePercent float64 `json:"cpuUsagePercent"` // 在用核心数占核心总数的比例
+
+	MemoryTotal uint64 `json:"memoryTotal"` // 内存总量(字节)
+	MemoryUsed  uint64 `json:"memoryUsed"`  // 已用内存(字节)
+
+	DiskTotal uint64 `json:"diskTotal"` // 磁盘总容量(字节)
+	DiskUsed  uint64 `json:"diskUsed"`  // 磁盘已使用(字节)
+	DiskFree  uint64 `json:"diskFree"`  // 磁盘空闲(字节)
+
+	NetworkSentRate uint64 `json:"networkSentRate"` // 总发送速率(字节/秒)
+	NetworkRecvRate uint64 `json:"networkRecvRate"` // 总接收速率(字节/秒)
+
+	Breaches    []FleetThresholdBreach `json:"breaches"`    // 各项已启用的告警规则及当前超过阈值的探针数
+	GeneratedAt int64                  `json:"generatedAt"` // 汇总生成时间（毫秒）
+}
+
+// FleetThresholdBreach 当前超过某项告警阈值的探针数
+type FleetThresholdBreach struct {
+	AlertType string  `json:"alertType"` // 告警类型: cpu, memory, memory_free, disk, disk_free, network, load
+	Threshold float64 `json:"threshold"` // 告警阈值
+	Agents    int     `json:"agents"`    // 最新指标超过阈值的探针数（不考虑持续时间）
+}
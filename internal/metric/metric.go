@@ -1,29 +1,132 @@
 package metric
 
+import "encoding/json"
+
 // DataPoint 统一的指标数据点结构
 type DataPoint struct {
 	Timestamp int64   `json:"timestamp"` // 毫秒时间戳
 	Value     float64 `json:"value"`
+	Gap       bool    `json:"-"` // 补齐的空数据点，序列化时 value 为 null
+}
+
+// MarshalJSON 空数据点的 value 输出为 null，便于前端图表断开连线
+func (p DataPoint) MarshalJSON() ([]byte, error) {
+	if p.Gap {
+		return json.Marshal(struct {
+			Timestamp int64    `json:"timestamp"`
+			Value     *float64 `json:"value"`
+		}{Timestamp: p.Timestamp})
+	}
+	return json.Marshal(struct {
+		Timestamp int64   `json:"timestamp"`
+		Value     float64 `json:"value"`
+	}{Timestamp: p.Timestamp, Value: p.Value})
 }
 
 // Series 指标系列（支持多系列，如多网卡、多传感器）
 type Series struct {
-	Name   string            `json:"name"`             // 系列名称
-	Labels map[string]string `json:"labels,omitempty"` // 额外标签
-	Data   []DataPoint       `json:"data"`             // 数据点列表
+	Name        string            `json:"name"`                  // 系列名称
+	Labels      map[string]string `json:"labels,omitempty"`      // 额外标签
+	Aggregation string            `json:"aggregation,omitempty"` // 降采样使用的聚合函数: avg, max, last
+	Data        []DataPoint       `json:"data"`                  // 数据点列表
 }
 
 // GetMetricsResponse 统一的查询响应格式
 type GetMetricsResponse struct {
-	AgentID string   `json:"agentId"`
-	Type    string   `json:"type"`
-	Range   string   `json:"range"`
-	Series  []Series `json:"series"`
+	AgentID  string `json:"agentId"`
+	Type     string `json:"type"`
+	Range    string `json:"range"`
+	Interval int64  `json:"interval,omitempty"` // 实际使用的步长（秒），LTTB 缩减时为点的平均间隔
+	// Decimation 数据点缩减算法: 为空表示按步长聚合, lttb 表示从原始精度数据中按 LTTB 挑选
+	Decimation string   `json:"decimation,omitempty"`
+	Series     []Series `json:"series"`
+
+	Debug *QueryDebug `json:"debug,omitempty"` // 查询调试信息，仅管理员指定 debug=true 时返回
+}
+
+// QueryDebug 指标查询的调试信息，用于排查慢查询
+type QueryDebug struct {
+	Source               string            `json:"source"`                         // 数据来源: raw-原始样本, aggregate-降采样数据
+	Step                 int64             `json:"step"`                           // 查询步长（秒）
+	DownsamplingInterval int64             `json:"downsamplingInterval,omitempty"` // 命中的降采样档位间隔（秒），原始样本时为空
+	Aggregation          string            `json:"aggregation,omitempty"`          // 查询时在步长内使用的聚合函数
+	SeriesFetched        int64             `json:"seriesFetched"`                  // VictoriaMetrics 扫描的时间序列数
+	SeriesReturned       int               `json:"seriesReturned"`                 // 返回的系列数
+	PointsReturned       int               `json:"pointsReturned"`                 // 查询返回的数据点数，不含补齐的空数据点
+	DurationMs           int64             `json:"durationMs"`                     // 总耗时（毫秒），包含步长选择、平滑、补点等处理
+	Queries              []QueryDebugEntry `json:"queries"`                        // 各 PromQL 查询的明细
+}
+
+// QueryDebugEntry 单个 PromQL 查询的调试信息
+type QueryDebugEntry struct {
+	Name            string `json:"name"`
+	Query           string `json:"query"`
+	SeriesFetched   int64  `json:"seriesFetched"`   // 扫描的时间序列数，VictoriaMetrics 未返回统计时为 0
+	SeriesReturned  int    `json:"seriesReturned"`  // 返回的系列数
+	PointsReturned  int    `json:"pointsReturned"`  // 返回的数据点数
+	ExecutionTimeMs int64  `json:"executionTimeMs"` // VictoriaMetrics 服务端耗时（毫秒）
+	DurationMs      int64  `json:"durationMs"`      // 请求往返耗时（毫秒）
+	Error           string `json:"error,omitempty"` // 查询失败的原因
+}
+
+// 时间点查询的数据来源
+const (
+	AsOfSourceRaw       = "raw"       // 原始样本
+	AsOfSourceAggregate = "aggregate" // 降采样数据
+)
+
+// AsOfResponse 指定时间点之前的最新指标值
+type AsOfResponse struct {
+	AgentID  string   `json:"agentId"`
+	Type     string   `json:"type"`
+	At       int64    `json:"at"`                 // 查询的时间点（毫秒）
+	Source   string   `json:"source"`             // 数据来源: raw, aggregate
+	Interval int64    `json:"interval,omitempty"` // 降采样步长（秒），原始样本时为空
+	Series   []Series `json:"series"`             // 每个系列只包含一个数据点
 }
 
 // QueryDefinition 查询定义（用于构建多个查询）
 type QueryDefinition struct {
-	Name   string            // 系列名称
-	Query  string            // PromQL 查询语句
-	Labels map[string]string // 额外标签
+	Name        string            // 系列名称
+	Query       string            // PromQL 查询语句
+	Labels      map[string]string // 额外标签
+	Aggregation string            // 降采样使用的聚合函数
+}
+
+// NetworkInterfaceMetric 单个网卡在一个时间桶内的速率（字节/秒）
+type NetworkInterfaceMetric struct {
+	Timestamp   int64   `json:"timestamp"` // 时间桶起点（毫秒）
+	Interface   string  `json:"interface"`
+	AvgSentRate float64 `json:"avgSentRate"` // 由累计发送字节数重新计算的平均速率
+	MinSentRate float64 `json:"minSentRate"`
+	MaxSentRate float64 `json:"maxSentRate"`
+	AvgRecvRate float64 `json:"avgRecvRate"` // 由累计接收字节数重新计算的平均速率
+	MinRecvRate float64 `json:"minRecvRate"`
+	MaxRecvRate float64 `json:"maxRecvRate"`
+}
+
+// NetworkInterfaceSummary 单个网卡在整个查询范围内的速率统计（字节/秒）
+type NetworkInterfaceSummary struct {
+	Interface   string  `json:"interface"`
+	MacAddress  string  `json:"macAddress,omitempty"` // 当前上报的 MAC 地址，网卡已不存在时为空
+	FirstSeen   int64   `json:"firstSeen"`            // 范围内第一个有数据的时间桶（毫秒）
+	LastSeen    int64   `json:"lastSeen"`             // 范围内最后一个有数据的时间桶（毫秒）
+	AvgSentRate float64 `json:"avgSentRate"`
+	MinSentRate float64 `json:"minSentRate"`
+	MaxSentRate float64 `json:"maxSentRate"`
+	AvgRecvRate float64 `json:"avgRecvRate"`
+	MinRecvRate float64 `json:"minRecvRate"`
+	MaxRecvRate float64 `json:"maxRecvRate"`
+}
+
+// NetworkByInterfaceResponse 按网卡查询网络速率的响应
+type NetworkByInterfaceResponse struct {
+	AgentID    string                    `json:"agentId"`
+	Type       string                    `json:"type"`
+	Range      string                    `json:"range"`
+	Start      int64                     `json:"start"`
+	End        int64                     `json:"end"`
+	Interval   int64                     `json:"interval"` // 实际使用的步长（秒）
+	Metrics    []NetworkInterfaceMetric  `json:"metrics"`
+	Interfaces []NetworkInterfaceSummary `json:"interfaces"`
 }
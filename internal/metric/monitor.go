@@ -14,7 +14,7 @@ type LatestMonitorMetrics struct {
 
 // MonitorStatsResult 监控统计结果（所有探针的聚合数据）
 type MonitorStatsResult struct {
-	Status          string `json:"status"`                   // 聚合状态（up/down/unknown）
+	Status          string `json:"status"`                   // 聚合状态（up/degraded/down/unknown）
 	ResponseTime    int64  `json:"responseTime"`             // 当前平均响应时间(ms)
 	ResponseTimeMin int64  `json:"responseTimeMin"`          // 最快响应时间(ms)
 	ResponseTimeMax int64  `json:"responseTimeMax"`          // 最慢响应时间(ms)
@@ -37,10 +37,11 @@ type PublicMonitorOverview struct {
 	Target           string `json:"target"`
 	ShowTargetPublic bool   `json:"showTargetPublic"` // 在公开页面是否显示目标地址
 	Description      string `json:"description"`
+	Group            string `json:"group,omitempty"` // 分组
 	Enabled          bool   `json:"enabled"`
 	Interval         int    `json:"interval"`
 	AgentCount       int    `json:"agentCount"`
-	Status           string `json:"status"`                   // up/down/unknown
+	Status           string `json:"status"`                   // up/degraded/down/unknown
 	ResponseTime     int64  `json:"responseTime"`             // 当前平均响应时间(ms)
 	ResponseTimeMin  int64  `json:"responseTimeMin"`          // 最快响应时间(ms)
 	ResponseTimeMax  int64  `json:"responseTimeMax"`          // 最慢响应时间(ms)
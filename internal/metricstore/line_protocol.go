@@ -0,0 +1,144 @@
+package metricstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/vmclient"
+)
+
+// LineProtocolStore 以 InfluxDB Line Protocol 转发指标到外部 TSDB
+// （InfluxDB v2 的 /api/v2/write，或兼容该协议的 VictoriaMetrics /write 等）
+type LineProtocolStore struct {
+	writeURL   string
+	token      string
+	httpClient *http.Client
+}
+
+// LineProtocolOptions 外部 TSDB 配置
+type LineProtocolOptions struct {
+	URL     string        // 写入地址，如 http://influxdb:8086/api/v2/write
+	Token   string        // 认证令牌（可选）
+	Org     string        // InfluxDB 组织（可选）
+	Bucket  string        // InfluxDB Bucket（可选）
+	Timeout time.Duration // 写入超时
+}
+
+// NewLineProtocolStore 创建外部 TSDB 写入后端
+func NewLineProtocolStore(options LineProtocolOptions) (*LineProtocolStore, error) {
+	u, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tsdb url: %w", err)
+	}
+	query := u.Query()
+	query.Set("precision", "ms")
+	if options.Org != "" {
+		query.Set("org", options.Org)
+	}
+	if options.Bucket != "" {
+		query.Set("bucket", options.Bucket)
+	}
+	u.RawQuery = query.Encode()
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &LineProtocolStore{
+		writeURL:   u.String(),
+		token:      options.Token,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *LineProtocolStore) Name() string {
+	return "line-protocol"
+}
+
+func (s *LineProtocolStore) Write(ctx context.Context, metrics []vmclient.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, metric := range metrics {
+		encodeLineProtocol(&buf, metric)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.writeURL, &buf)
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("write metrics failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("write metrics failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// encodeLineProtocol 将指标编码为 Line Protocol：measurement,tag=v value=1.0 timestamp
+func encodeLineProtocol(buf *bytes.Buffer, metric vmclient.Metric) {
+	name := metric.Metric["__name__"]
+	if name == "" {
+		return
+	}
+
+	keys := make([]string, 0, len(metric.Metric))
+	for k, v := range metric.Metric {
+		if k == "__name__" || v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var prefix strings.Builder
+	prefix.WriteString(measurementEscaper.Replace(name))
+	for _, k := range keys {
+		prefix.WriteByte(',')
+		prefix.WriteString(tagEscaper.Replace(k))
+		prefix.WriteByte('=')
+		prefix.WriteString(tagEscaper.Replace(metric.Metric[k]))
+	}
+
+	for i, value := range metric.Values {
+		if i >= len(metric.Timestamps) {
+			break
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		buf.WriteString(prefix.String())
+		buf.WriteString(" value=")
+		buf.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(metric.Timestamps[i], 10))
+		buf.WriteByte('\n')
+	}
+}
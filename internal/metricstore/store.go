@@ -0,0 +1,93 @@
+package metricstore
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+// MetricStore 指标写入后端
+type MetricStore interface {
+	// Name 后端名称，用于日志
+	Name() string
+	// Write 写入一批指标
+	Write(ctx context.Context, metrics []vmclient.Metric) error
+}
+
+// VMStore 写入 VictoriaMetrics（默认后端）
+type VMStore struct {
+	client *vmclient.VMClient
+}
+
+// NewVMStore 创建 VictoriaMetrics 写入后端
+func NewVMStore(client *vmclient.VMClient) *VMStore {
+	return &VMStore{client: client}
+}
+
+func (s *VMStore) Name() string {
+	return "victoriametrics"
+}
+
+func (s *VMStore) Write(ctx context.Context, metrics []vmclient.Metric) error {
+	return s.client.Write(ctx, metrics)
+}
+
+// multiStoreQueueSize 每个转发后端的待写入批次上限，队列满时丢弃新批次
+const multiStoreQueueSize = 256
+
+// MultiStore 双写：同步写入主后端，再异步转发到其他后端（迁移期间使用）。
+// 只有主后端的写入错误会返回；转发后端变慢或不可用时不会阻塞上报，队列满时丢弃批次并记录日志。
+type MultiStore struct {
+	logger      *zap.Logger
+	primary     MetricStore
+	secondaries []*secondaryStore
+}
+
+// secondaryStore 转发后端及其待写入队列
+type secondaryStore struct {
+	store MetricStore
+	queue chan []vmclient.Metric
+}
+
+// NewMultiStore 创建双写后端，每个转发后端由一个后台协程按顺序写入
+func NewMultiStore(logger *zap.Logger, primary MetricStore, secondaries ...MetricStore) *MultiStore {
+	s := &MultiStore{
+		logger:  logger,
+		primary: primary,
+	}
+	for _, store := range secondaries {
+		secondary := &secondaryStore{
+			store: store,
+			queue: make(chan []vmclient.Metric, multiStoreQueueSize),
+		}
+		s.secondaries = append(s.secondaries, secondary)
+		go s.forward(secondary)
+	}
+	return s
+}
+
+func (s *MultiStore) Name() string {
+	return s.primary.Name()
+}
+
+func (s *MultiStore) Write(ctx context.Context, metrics []vmclient.Metric) error {
+	err := s.primary.Write(ctx, metrics)
+	for _, secondary := range s.secondaries {
+		select {
+		case secondary.queue <- metrics:
+		default:
+			s.logger.Warn("双写队列已满，丢弃指标", zap.String("store", secondary.store.Name()), zap.Int("count", len(metrics)))
+		}
+	}
+	return err
+}
+
+// forward 依次写入转发后端，写入超时由后端自身控制
+func (s *MultiStore) forward(secondary *secondaryStore) {
+	for metrics := range secondary.queue {
+		if err := secondary.store.Write(context.Background(), metrics); err != nil {
+			s.logger.Warn("双写指标失败", zap.String("store", secondary.store.Name()), zap.Error(err))
+		}
+	}
+}
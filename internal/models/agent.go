@@ -4,29 +4,38 @@ import (
 	"net"
 	"strings"
 
+	"github.com/dushixiang/pika/internal/protocol"
 	"gorm.io/datatypes"
 )
 
 // Agent 探针信息
 type Agent struct {
-	ID         string                      `gorm:"primaryKey" json:"id"`                  // 探针ID (UUID)
-	Name       string                      `gorm:"index" json:"name"`                     // 探针名称
-	Hostname   string                      `gorm:"index" json:"hostname,omitempty"`       // 主机名
-	IP         string                      `gorm:"index" json:"ip,omitempty"`             // 连接 IP 地址
-	IPv4       string                      `gorm:"index" json:"ipv4,omitempty"`           // 公网 IPv4 地址
-	IPv6       string                      `gorm:"index" json:"ipv6,omitempty"`           // 公网 IPv6 地址
-	OS         string                      `json:"os"`                                    // 操作系统
-	Arch       string                      `json:"arch"`                                  // 架构
-	Version    string                      `json:"version"`                               // 探针版本
-	Tags       datatypes.JSONSlice[string] `json:"tags"`                                  // 标签
-	ExpireTime int64                       `json:"expireTime"`                            // 到期时间（时间戳毫秒）
-	Status     int                         `json:"status"`                                // 状态: 0-离线, 1-在线
-	Visibility string                      `gorm:"default:public" json:"visibility"`      // 可见性: public-匿名可见, private-登录可见
-	Weight     int                         `gorm:"default:0;index" json:"weight"`         // 权重排序（数字越大越靠前）
-	Remark     string                      `json:"remark"`                                // 备注信息
-	LastSeenAt int64                       `gorm:"index" json:"lastSeenAt"`               // 最后上线时间（时间戳毫秒）
-	CreatedAt  int64                       `json:"createdAt"`                             // 创建时间（时间戳毫秒）
-	UpdatedAt  int64                       `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+	ID                 string                                `gorm:"primaryKey" json:"id"`                  // 探针ID (UUID)
+	Name               string                                `gorm:"index" json:"name"`                     // 探针名称
+	Hostname           string                                `gorm:"index" json:"hostname,omitempty"`       // 主机名
+	MAC                string                                `json:"mac,omitempty"`                         // 主网卡 MAC 地址
+	IP                 string                                `gorm:"index" json:"ip,omitempty"`             // 连接 IP 地址
+	IPv4               string                                `gorm:"index" json:"ipv4,omitempty"`           // 公网 IPv4 地址
+	IPv6               string                                `gorm:"index" json:"ipv6,omitempty"`           // 公网 IPv6 地址
+	OS                 string                                `json:"os"`                                    // 操作系统
+	Arch               string                                `json:"arch"`                                  // 架构
+	Version            string                                `json:"version"`                               // 探针版本
+	Tags               datatypes.JSONSlice[string]           `json:"tags"`                                  // 标签
+	Group              string                                `gorm:"column:agent_group;index" json:"group"` // 分组，group 为 SQL 保留字，列名使用 agent_group
+	Attributes         datatypes.JSONType[map[string]string] `json:"attributes"`                            // 运维设置的属性，覆盖探针上报的同名属性，值为空表示删除该属性
+	ReportedAttributes datatypes.JSONType[map[string]string] `json:"reportedAttributes"`                    // 探针上报的属性（探针配置 agent.attributes）
+	ExpireTime         int64                                 `json:"expireTime"`                            // 到期时间（时间戳毫秒）
+	Status             int                                   `json:"status"`                                // 状态: 0-离线, 1-在线
+	Visibility         string                                `gorm:"default:public" json:"visibility"`      // 可见性: public-匿名可见, private-登录可见
+	Weight             int                                   `gorm:"default:0;index" json:"weight"`         // 权重排序（数字越大越靠前）
+	Remark             string                                `json:"remark"`                                // 备注信息
+	PrimaryMountPoint  string                                `json:"primaryMountPoint"`                     // 运维指定的主磁盘挂载点，为空时自动识别
+	PrimaryInterface   string                                `json:"primaryInterface"`                      // 运维指定的主网卡，为空时自动识别
+	LastSeenAt         int64                                 `gorm:"index" json:"lastSeenAt"`               // 最后上线时间（时间戳毫秒）
+	ClockSkew          int64                                 `json:"clockSkew"`                             // 测得的时钟偏差（毫秒）：服务端接收时间 - 探针上报时间
+	ClockSkewed        bool                                  `json:"clockSkewed"`                           // 时钟偏差是否超过容忍范围
+	CreatedAt          int64                                 `json:"createdAt"`                             // 创建时间（时间戳毫秒）
+	UpdatedAt          int64                                 `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
 
 	// 流量统计相关字段
 	TrafficStats datatypes.JSONType[TrafficStatsData] `json:"trafficStats,omitempty"` // 流量统计
@@ -36,6 +45,15 @@ type Agent struct {
 
 	// SSH登录监控配置
 	SSHLoginConfig datatypes.JSONType[SSHLoginConfigData] `json:"sshLoginConfig,omitempty"` // SSH登录监控配置
+
+	// 探针自报的运行状况
+	Health datatypes.JSONType[AgentHealthData] `json:"health,omitempty"` // 随心跳上报的运行状况
+}
+
+// AgentHealthData 探针随心跳上报的运行状况
+type AgentHealthData struct {
+	protocol.AgentHealth
+	ReportedAt int64 `json:"reportedAt"` // 服务端收到的时间（时间戳毫秒），0 表示探针未上报过（旧版本探针）
 }
 
 // TrafficStatsData 流量统计数据
@@ -96,3 +114,33 @@ func (r SSHLoginConfigData) IsIPWhitelisted(ip string) bool {
 func (Agent) TableName() string {
 	return "agents"
 }
+
+// EffectiveAttributes 合并探针上报的属性和运维设置的属性，运维设置优先，值为空的属性被删除
+func (r Agent) EffectiveAttributes() map[string]string {
+	result := make(map[string]string)
+	for k, v := range r.ReportedAttributes.Data() {
+		result[k] = v
+	}
+	for k, v := range r.Attributes.Data() {
+		if v == "" {
+			delete(result, k)
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// MatchAttributes 判断探针是否满足全部属性条件，条件为空时视为满足
+func (r Agent) MatchAttributes(selector map[string]string) bool {
+	if len(selector) == 0 {
+		return true
+	}
+	attributes := r.EffectiveAttributes()
+	for k, v := range selector {
+		if attributes[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package models
+
+// AgentCollision 探针ID冲突记录（如从同一镜像克隆的机器使用了相同的探针ID）
+type AgentCollision struct {
+	ID               string `gorm:"primaryKey" json:"id"`   // 记录ID (UUID)
+	AgentID          string `gorm:"index" json:"agentId"`   // 冲突的探针ID
+	ExistingHostname string `json:"existingHostname"`       // 已注册的主机名
+	ExistingMAC      string `json:"existingMac,omitempty"`  // 已注册的 MAC 地址
+	ExistingIP       string `json:"existingIp,omitempty"`   // 已注册的连接 IP
+	IncomingHostname string `json:"incomingHostname"`       // 新注册的主机名
+	IncomingMAC      string `json:"incomingMac,omitempty"`  // 新注册的 MAC 地址
+	IncomingIP       string `json:"incomingIp,omitempty"`   // 新注册的连接 IP
+	Rejected         bool   `json:"rejected"`               // 是否拒绝了本次注册
+	NewAgentID       string `json:"newAgentId,omitempty"`   // 拆分后分配的新探针ID
+	CreatedAt        int64  `gorm:"index" json:"createdAt"` // 检测时间（毫秒）
+}
+
+func (AgentCollision) TableName() string {
+	return "agent_collisions"
+}
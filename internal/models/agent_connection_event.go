@@ -0,0 +1,20 @@
+package models
+
+// 探针连接事件类型
+const (
+	AgentConnectionConnect    = "connect"
+	AgentConnectionDisconnect = "disconnect"
+)
+
+// AgentConnectionEvent 探针连接/断开事件
+type AgentConnectionEvent struct {
+	ID         string `gorm:"primaryKey" json:"id"`   // 事件ID (UUID)
+	AgentID    string `gorm:"index" json:"agentId"`   // 探针ID
+	Event      string `json:"event"`                  // 事件类型: connect, disconnect
+	RemoteAddr string `json:"remoteAddr"`             // 连接来源地址
+	Timestamp  int64  `gorm:"index" json:"timestamp"` // 事件时间（毫秒）
+}
+
+func (AgentConnectionEvent) TableName() string {
+	return "agent_connection_events"
+}
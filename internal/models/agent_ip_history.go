@@ -0,0 +1,21 @@
+package models
+
+// 公网 IP 地址族
+const (
+	IPFamilyV4 = "ipv4"
+	IPFamilyV6 = "ipv6"
+)
+
+// AgentIPHistory 探针公网 IP 变更历史
+type AgentIPHistory struct {
+	ID        string `gorm:"primaryKey" json:"id"`   // 记录ID (UUID)
+	AgentID   string `gorm:"index" json:"agentId"`   // 探针ID
+	Family    string `json:"family"`                 // 地址族: ipv4, ipv6
+	IP        string `json:"ip"`                     // 新的公网 IP
+	PrevIP    string `json:"prevIp,omitempty"`       // 变更前的公网 IP
+	ChangedAt int64  `gorm:"index" json:"changedAt"` // 变更时间（毫秒）
+}
+
+func (AgentIPHistory) TableName() string {
+	return "agent_ip_histories"
+}
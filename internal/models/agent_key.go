@@ -0,0 +1,27 @@
+package models
+
+// 探针公钥状态
+const (
+	AgentKeyPending  = "pending"  // 首次使用时登记，等待管理员审批
+	AgentKeyApproved = "approved" // 已审批，该探针ID只能使用此公钥注册
+	AgentKeyRejected = "rejected" // 已拒绝，使用此公钥的注册被拒绝
+)
+
+// AgentKey 探针ID绑定的注册公钥（Ed25519）
+type AgentKey struct {
+	AgentID     string `gorm:"primaryKey" json:"agentId"`             // 探针ID
+	PublicKey   string `json:"publicKey"`                             // 公钥（base64）
+	Fingerprint string `json:"fingerprint"`                           // 公钥指纹（SHA-256 前 16 字节的十六进制）
+	Status      string `gorm:"index" json:"status"`                   // 状态: pending, approved, rejected
+	Hostname    string `json:"hostname"`                              // 登记时上报的主机名
+	IP          string `json:"ip"`                                    // 登记时的连接 IP
+	ReviewedBy  string `json:"reviewedBy,omitempty"`                  // 审批人
+	ReviewedAt  int64  `json:"reviewedAt,omitempty"`                  // 审批时间（时间戳毫秒）
+	LastUsedAt  int64  `json:"lastUsedAt,omitempty"`                  // 最近一次验证通过的时间（时间戳毫秒）
+	CreatedAt   int64  `gorm:"index" json:"createdAt"`                // 登记时间（时间戳毫秒）
+	UpdatedAt   int64  `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+}
+
+func (AgentKey) TableName() string {
+	return "agent_keys"
+}
@@ -1,26 +1,67 @@
 package models
 
+import "gorm.io/datatypes"
+
 // AlertRecord 告警记录
 type AlertRecord struct {
-	ID          int64   `gorm:"primaryKey;autoIncrement" json:"id"`    // 记录ID
-	AgentID     string  `gorm:"index" json:"agentId"`                  // 探针ID
-	AgentName   string  `json:"agentName"`                             // 探针名称
-	AlertType   string  `json:"alertType"`                             // 告警类型: cpu, memory, disk, network
-	Message     string  `json:"message"`                               // 告警消息
-	Threshold   float64 `json:"threshold"`                             // 告警阈值
-	ActualValue float64 `json:"actualValue"`                           // 实际值
-	Level       string  `json:"level"`                                 // 告警级别: info, warning, critical
-	Status      string  `json:"status"`                                // 状态: firing（告警中）, resolved（已恢复）
-	FiredAt     int64   `gorm:"index" json:"firedAt"`                  // 触发时间（时间戳毫秒）
-	ResolvedAt  int64   `json:"resolvedAt,omitempty"`                  // 恢复时间（时间戳毫秒）
-	CreatedAt   int64   `json:"createdAt"`                             // 创建时间（时间戳毫秒）
-	UpdatedAt   int64   `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+	ID              int64   `gorm:"primaryKey;autoIncrement" json:"id"`          // 记录ID
+	AgentID         string  `gorm:"index" json:"agentId"`                        // 探针ID
+	AgentName       string  `json:"agentName"`                                   // 探针名称
+	AlertType       string  `json:"alertType"`                                   // 告警类型: cpu, memory, disk, network
+	Message         string  `json:"message"`                                     // 告警消息
+	Threshold       float64 `json:"threshold"`                                   // 告警阈值
+	ActualValue     float64 `json:"actualValue"`                                 // 实际值
+	Level           string  `json:"level"`                                       // 告警级别: info, warning, critical
+	Status          string  `json:"status"`                                      // 状态: firing（告警中）, resolved（已恢复）
+	FiredAt         int64   `gorm:"index" json:"firedAt"`                        // 触发时间（时间戳毫秒）
+	ResolvedAt      int64   `json:"resolvedAt,omitempty"`                        // 恢复时间（时间戳毫秒）
+	Suppressed      bool    `gorm:"default:false" json:"suppressed"`             // 是否被抑制（依赖的探针离线告警触发中，不发送通知）
+	DependsOn       int64   `json:"dependsOn,omitempty"`                         // 依赖的父告警记录ID（探针离线告警）
+	TraceID         string  `json:"traceId,omitempty"`                           // 最近一次触发、升级或恢复时的追踪ID
+	AckedAt         int64   `gorm:"default:0" json:"ackedAt,omitempty"`          // 确认时间（时间戳毫秒）
+	AckedBy         string  `json:"ackedBy,omitempty"`                           // 确认人
+	ResolvedBy      string  `json:"resolvedBy,omitempty"`                        // 手动恢复操作人，为空表示自动恢复
+	EscalatedAt     int64   `gorm:"default:0" json:"escalatedAt,omitempty"`      // 最近一次因未确认而升级通知的时间（时间戳毫秒）
+	EscalationCount int     `gorm:"default:0" json:"escalationCount,omitempty"`  // 因未确认而升级通知的次数
+	ArchivedAt      int64   `gorm:"index;default:0" json:"archivedAt,omitempty"` // 归档到对象存储的时间（时间戳毫秒），0 表示未归档
+	CreatedAt       int64   `json:"createdAt"`                                   // 创建时间（时间戳毫秒）
+	UpdatedAt       int64   `json:"updatedAt" gorm:"autoUpdateTime:milli"`       // 更新时间（时间戳毫秒）
+
+	CommentCount int64                `gorm:"-" json:"commentCount"` // 备注数量，仅列表查询时填充
+	Monitor      *MonitorAlertContext `gorm:"-" json:"-"`            // 监控项告警上下文（服务下线、证书告警），仅用于通知渲染，不持久化
 }
 
 func (AlertRecord) TableName() string {
 	return "alert_records"
 }
 
+// AlertComment 告警备注，处理告警时记录的排查过程和结论
+type AlertComment struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement" json:"id"` // 备注ID
+	AlertRecordID int64  `gorm:"index" json:"alertRecordId"`         // 告警记录ID
+	Username      string `json:"username"`                           // 备注人
+	Text          string `gorm:"type:text" json:"text"`              // 备注内容
+	CreatedAt     int64  `json:"createdAt"`                          // 创建时间（时间戳毫秒）
+}
+
+func (AlertComment) TableName() string {
+	return "alert_comments"
+}
+
+// MonitorAlertContext 监控项状态变化时的上下文信息
+type MonitorAlertContext struct {
+	ID           string // 监控项ID
+	Name         string // 监控项名称
+	Type         string // 监控类型: http, tcp, icmp
+	Target       string // 监控目标
+	Status       string // 最近一次检测状态: up, down
+	StatusCode   int    // HTTP 状态码
+	ResponseTime int64  // 响应时间（毫秒）
+	Error        string // 错误信息
+	Downtime     int64  // 持续离线时间（秒）
+	CertDaysLeft int    // 证书剩余天数
+}
+
 // AlertState 告警状态（持久化到数据库，用于判断是否持续超过阈值）
 type AlertState struct {
 	ID            string  `gorm:"primaryKey" json:"id"`                  // 状态ID（格式：agentId:configId:alertType）
@@ -33,8 +74,17 @@ type AlertState struct {
 	LastCheckTime int64   `json:"lastCheckTime"`                         // 上次检查时间
 	IsFiring      bool    `json:"isFiring"`                              // 是否正在告警
 	LastRecordID  int64   `json:"lastRecordId"`                          // 最后一条告警记录ID
+	Level         string  `json:"level"`                                 // 当前告警级别
 	CreatedAt     int64   `json:"createdAt"`                             // 创建时间（时间戳毫秒）
 	UpdatedAt     int64   `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+
+	Samples datatypes.JSONSlice[AlertSample] `json:"-"` // windowed 模式下最近一个窗口内的采样，continuous 模式下为空
+}
+
+// AlertSample 告警检查采样，只记录是否超过阈值
+type AlertSample struct {
+	Time     int64 `json:"t"` // 检查时间（时间戳毫秒）
+	Breached bool  `json:"b"` // 是否超过阈值
 }
 
 func (AlertState) TableName() string {
@@ -0,0 +1,27 @@
+package models
+
+import "gorm.io/datatypes"
+
+// 注释来源
+const (
+	AnnotationSourceManual = "manual" // 管理员手动添加
+	AnnotationSourceAPI    = "api"    // 探针或 CI 通过 API 密钥上报
+)
+
+// Annotation 时间序列注释，用于在图表上标记部署、变更等事件
+type Annotation struct {
+	ID        string                      `gorm:"primaryKey" json:"id"`                  // 注释ID (UUID)
+	AgentID   string                      `gorm:"index" json:"agentId"`                  // 探针ID，为空表示全局注释（显示在所有探针的图表上）
+	Time      int64                       `gorm:"index" json:"time"`                     // 事件时间（时间戳毫秒）
+	EndTime   int64                       `gorm:"index" json:"endTime,omitempty"`        // 结束时间（时间戳毫秒），0 表示时间点事件
+	Text      string                      `json:"text"`                                  // 注释内容
+	Tags      datatypes.JSONSlice[string] `json:"tags"`                                  // 标签，如 deploy、v2.3
+	Source    string                      `json:"source"`                                // 来源: manual, api
+	CreatedBy string                      `json:"createdBy"`                             // 创建人（用户名或 API 密钥名称）
+	CreatedAt int64                       `json:"createdAt"`                             // 创建时间（时间戳毫秒）
+	UpdatedAt int64                       `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+}
+
+func (Annotation) TableName() string {
+	return "annotations"
+}
@@ -1,14 +1,17 @@
 package models
 
+import "gorm.io/datatypes"
+
 // ApiKey API密钥信息
 type ApiKey struct {
-	ID        string `gorm:"primaryKey" json:"id"`                  // 密钥ID (UUID)
-	Name      string `gorm:"index" json:"name"`                     // 密钥名称/备注
-	Key       string `gorm:"uniqueIndex" json:"key"`                // API密钥
-	Enabled   bool   `gorm:"index;default:true" json:"enabled"`     // 是否启用
-	CreatedBy string `gorm:"index" json:"createdBy"`                // 创建人ID
-	CreatedAt int64  `json:"createdAt"`                             // 创建时间（时间戳毫秒）
-	UpdatedAt int64  `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+	ID        string                      `gorm:"primaryKey" json:"id"`                  // 密钥ID (UUID)
+	Name      string                      `gorm:"index" json:"name"`                     // 密钥名称/备注
+	Key       string                      `gorm:"uniqueIndex" json:"key"`                // API密钥
+	Enabled   bool                        `gorm:"index;default:true" json:"enabled"`     // 是否启用
+	Tags      datatypes.JSONSlice[string] `json:"tags"`                                  // 使用该密钥注册的新探针默认标签
+	CreatedBy string                      `gorm:"index" json:"createdBy"`                // 创建人ID
+	CreatedAt int64                       `json:"createdAt"`                             // 创建时间（时间戳毫秒）
+	UpdatedAt int64                       `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
 }
 
 func (ApiKey) TableName() string {
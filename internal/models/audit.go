@@ -6,6 +6,8 @@ type AuditResult struct {
 	AgentID   string `gorm:"type:varchar(64);not null;index" json:"agentId"`
 	Type      string `gorm:"type:varchar(32);not null" json:"type"` // vps_audit
 	Result    string `gorm:"type:text;not null" json:"result"`      // JSON格式的审计结果
+	Analysis  string `gorm:"type:text" json:"-"`                    // JSON格式的服务端安全分析结果
+	RiskScore int    `json:"riskScore"`                             // 风险评分(0-100)
 	StartTime int64  `gorm:"not null" json:"startTime"`
 	EndTime   int64  `gorm:"not null" json:"endTime"`
 	CreatedAt int64  `gorm:"not null" json:"createdAt"`
@@ -15,3 +17,20 @@ type AuditResult struct {
 func (AuditResult) TableName() string {
 	return "audit_results"
 }
+
+// AuditDiff 两次审计结果的差异
+type AuditDiff struct {
+	AgentID  string           `json:"agentId"`
+	AuditIDA int64            `json:"auditIdA"` // 基准审计
+	AuditIDB int64            `json:"auditIdB"` // 对比审计
+	Changes  []AuditDiffEntry `json:"changes"`
+}
+
+// AuditDiffEntry 审计差异项
+type AuditDiffEntry struct {
+	Section string      `json:"section"`          // 所属部分: systemInfo/statistics
+	Path    string      `json:"path"`             // 字段路径，如 networkStats.publicListeningPorts
+	Kind    string      `json:"kind"`             // 差异类型: added/removed/changed
+	Before  interface{} `json:"before,omitempty"` // 基准审计中的值
+	After   interface{} `json:"after,omitempty"`  // 对比审计中的值
+}
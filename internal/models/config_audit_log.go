@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/datatypes"
+
+// ConfigAuditLog 配置变更审计日志
+type ConfigAuditLog struct {
+	ID           string                            `gorm:"primaryKey" json:"id"`    // 日志ID (UUID)
+	PropertyID   string                            `gorm:"index" json:"propertyId"` // 属性ID
+	PropertyName string                            `json:"propertyName"`            // 属性名称
+	Username     string                            `gorm:"index" json:"username"`   // 操作用户（来自 JWT）
+	Changes      datatypes.JSONSlice[ConfigChange] `json:"changes"`                 // 变更字段列表
+	CreatedAt    int64                             `gorm:"index" json:"createdAt"`  // 变更时间（毫秒）
+}
+
+func (ConfigAuditLog) TableName() string {
+	return "config_audit_logs"
+}
+
+// ConfigChange 单个字段的变更
+type ConfigChange struct {
+	Field  string      `json:"field"`            // 字段路径，如 rules.cpuThreshold
+	Before interface{} `json:"before,omitempty"` // 变更前的值（敏感字段已脱敏）
+	After  interface{} `json:"after,omitempty"`  // 变更后的值（敏感字段已脱敏）
+}
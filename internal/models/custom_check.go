@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+const (
+	// CustomCheckMinInterval 自定义检查的最小执行间隔（秒）
+	CustomCheckMinInterval = 10
+	// CustomCheckDefaultInterval 自定义检查的默认执行间隔（秒）
+	CustomCheckDefaultInterval = 60
+	// CustomCheckDefaultTimeout 自定义检查的默认执行超时（秒）
+	CustomCheckDefaultTimeout = 10
+)
+
+// CustomCheck 自定义检查，在服务端统一定义，下发到目标探针执行并以自定义指标回传
+type CustomCheck struct {
+	ID              string   `json:"id"`                // 检查ID
+	Name            string   `json:"name"`              // 检查名称
+	Enabled         bool     `json:"enabled"`           // 是否启用
+	Command         string   `json:"command"`           // 执行的 shell 命令
+	IntervalSeconds int      `json:"intervalSeconds"`   // 执行间隔（秒）
+	TimeoutSeconds  int      `json:"timeoutSeconds"`    // 执行超时（秒）
+	Parser          string   `json:"parser"`            // 解析方式: number, regex, exit_code
+	Pattern         string   `json:"pattern,omitempty"` // 正则表达式（parser 为 regex 时使用）
+	Scope           string   `json:"scope"`             // 目标范围: all/custom
+	AgentIDs        []string `json:"agentIds"`          // 目标探针列表（scope 为 custom 时使用）
+	Tags            []string `json:"tags"`              // 目标标签，探针包含任一标签即匹配（scope 为 custom 时使用）
+}
+
+// Normalize 填充默认值
+func (c *CustomCheck) Normalize() {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Command = strings.TrimSpace(c.Command)
+	if c.Parser == "" {
+		c.Parser = protocol.CustomCheckParserNumber
+	}
+	if c.IntervalSeconds <= 0 {
+		c.IntervalSeconds = CustomCheckDefaultInterval
+	}
+	if c.TimeoutSeconds <= 0 {
+		c.TimeoutSeconds = CustomCheckDefaultTimeout
+	}
+	if c.Scope != "custom" {
+		c.Scope = "all"
+	}
+}
+
+// Validate 校验检查定义
+func (c *CustomCheck) Validate() error {
+	if c.Name == "" {
+		return errors.New("检查名称不能为空")
+	}
+	if c.Command == "" {
+		return errors.New("检查命令不能为空")
+	}
+	if c.IntervalSeconds < CustomCheckMinInterval {
+		return errors.New("执行间隔不能小于10秒")
+	}
+	if c.TimeoutSeconds > c.IntervalSeconds {
+		return errors.New("执行超时不能大于执行间隔")
+	}
+	switch c.Parser {
+	case protocol.CustomCheckParserNumber, protocol.CustomCheckParserExitCode:
+	case protocol.CustomCheckParserRegex:
+		if c.Pattern == "" {
+			return errors.New("正则解析方式需要填写正则表达式")
+		}
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return errors.New("正则表达式无效: " + err.Error())
+		}
+	default:
+		return errors.New("不支持的解析方式: " + c.Parser)
+	}
+	return nil
+}
+
+// IsTarget 判断探针是否需要执行该检查
+func (c *CustomCheck) IsTarget(agent *Agent) bool {
+	if c == nil || !c.Enabled || agent == nil {
+		return false
+	}
+	if c.Scope != "custom" {
+		return true
+	}
+	if slices.Contains(c.AgentIDs, agent.ID) {
+		return true
+	}
+	for _, tag := range agent.Tags {
+		if slices.Contains(c.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToItem 转换为下发给探针的检查项
+func (c *CustomCheck) ToItem() protocol.CustomCheckItem {
+	return protocol.CustomCheckItem{
+		ID:              c.ID,
+		Name:            c.Name,
+		Command:         c.Command,
+		IntervalSeconds: c.IntervalSeconds,
+		TimeoutSeconds:  c.TimeoutSeconds,
+		Parser:          c.Parser,
+		Pattern:         c.Pattern,
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+// DiskForecast 磁盘将满预测（按挂载点线性拟合最近的使用率趋势）
+type DiskForecast struct {
+	ID              string  `gorm:"primaryKey" json:"id"`                  // 预测ID（格式：agentId:mountPoint）
+	AgentID         string  `gorm:"index" json:"agentId"`                  // 探针ID
+	MountPoint      string  `json:"mountPoint"`                            // 挂载点
+	UsagePercent    float64 `json:"usagePercent"`                          // 最新使用率(0-100)
+	Slope           float64 `json:"slope"`                                 // 使用率增长速度（百分点/小时）
+	Samples         int     `json:"samples"`                               // 参与拟合的样本数
+	EstimatedFullAt int64   `json:"estimatedFullAt"`                       // 预计写满时间（毫秒），0 表示趋势不增长或数据不足
+	UpdatedAt       int64   `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+}
+
+func (DiskForecast) TableName() string {
+	return "disk_forecasts"
+}
@@ -0,0 +1,16 @@
+package models
+
+// LoginAuditLog 登录审计日志（记录被拒绝的登录）
+type LoginAuditLog struct {
+	ID        string `gorm:"primaryKey" json:"id"`   // 日志ID (UUID)
+	Username  string `gorm:"index" json:"username"`  // 登录用户名
+	Method    string `json:"method"`                 // 登录方式: password, oidc, github
+	IP        string `json:"ip"`                     // 客户端IP
+	Country   string `json:"country"`                // 国家代码（ISO 3166-1）
+	Reason    string `json:"reason"`                 // 拒绝原因
+	CreatedAt int64  `gorm:"index" json:"createdAt"` // 登录时间（毫秒）
+}
+
+func (LoginAuditLog) TableName() string {
+	return "login_audit_logs"
+}
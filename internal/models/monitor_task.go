@@ -7,24 +7,37 @@ import (
 
 // MonitorTask 描述一个服务监控任务
 type MonitorTask struct {
-	ID               string                                         `gorm:"primaryKey" json:"id"`                  // 任务 ID
-	Name             string                                         `gorm:"uniqueIndex" json:"name"`               // 任务名称
-	Type             string                                         `gorm:"index" json:"type"`                     // 监控类型 http/tcp
-	Target           string                                         `json:"target"`                                // 目标地址
-	Description      string                                         `json:"description"`                           // 描述信息
-	Enabled          bool                                           `json:"enabled"`                               // 是否启用
-	ShowTargetPublic bool                                           `json:"showTargetPublic"`                      // 在公开页面是否显示目标地址
-	Visibility       string                                         `gorm:"default:public" json:"visibility"`      // 可见性: public-匿名可见, private-登录可见
-	Interval         int                                            `json:"interval"`                              // 检测频率（秒），默认 60
-	AgentIds         datatypes.JSONSlice[string]                    `json:"agentIds"`                              // 指定的探针 ID 列表（JSON 数组）
-	AgentNames       []string                                       `gorm:"-" json:"agentNames"`                   // 指定的探针名称列表
-	HTTPConfig       datatypes.JSONType[protocol.HTTPMonitorConfig] `json:"httpConfig"`                            // HTTP 监控配置
-	TCPConfig        datatypes.JSONType[protocol.TCPMonitorConfig]  `json:"tcpConfig"`                             // TCP 监控配置
-	ICMPConfig       datatypes.JSONType[protocol.ICMPMonitorConfig] `json:"icmpConfig"`                            // ICMP 监控配置
-	CreatedAt        int64                                          `gorm:"autoCreateTime:milli" json:"createdAt"` // 创建时间
-	UpdatedAt        int64                                          `gorm:"autoUpdateTime:milli" json:"updatedAt"` // 更新时间
+	ID                   string                                         `gorm:"primaryKey" json:"id"`                  // 任务 ID
+	Name                 string                                         `gorm:"uniqueIndex" json:"name"`               // 任务名称
+	Type                 string                                         `gorm:"index" json:"type"`                     // 监控类型 http/tcp
+	Target               string                                         `json:"target"`                                // 目标地址
+	Description          string                                         `json:"description"`                           // 描述信息
+	Enabled              bool                                           `json:"enabled"`                               // 是否启用
+	ShowTargetPublic     bool                                           `json:"showTargetPublic"`                      // 在公开页面是否显示目标地址
+	Visibility           string                                         `gorm:"default:public" json:"visibility"`      // 可见性: public-匿名可见, private-登录可见
+	Group                string                                         `gorm:"index" json:"group"`                    // 分组（公开页面按分组展示品牌）
+	Interval             int                                            `json:"interval"`                              // 检测频率（秒），默认 60
+	DegradedThreshold    int                                            `json:"degradedThreshold"`                     // 判定为 degraded 的异常探针比例上限（%），达到该比例判定为 down，默认 50
+	RunOnServer          bool                                           `json:"runOnServer"`                           // 是否由服务端执行检测，结果归属于保留探针 ServerAgentID
+	AgentIds             datatypes.JSONSlice[string]                    `json:"agentIds"`                              // 指定的探针 ID 列表（JSON 数组）
+	AgentNames           []string                                       `gorm:"-" json:"agentNames"`                   // 指定的探针名称列表
+	Tags                 datatypes.JSONSlice[string]                    `json:"tags"`                                  // 指定的探针标签（JSON 数组），带有任一标签的探针参与检测
+	AgentWeights         datatypes.JSONType[map[string]float64]         `json:"agentWeights"`                          // 探针权重（探针 ID -> 权重），未配置的探针权重为 1
+	HTTPConfig           datatypes.JSONType[protocol.HTTPMonitorConfig] `json:"httpConfig"`                            // HTTP 监控配置
+	TCPConfig            datatypes.JSONType[protocol.TCPMonitorConfig]  `json:"tcpConfig"`                             // TCP 监控配置
+	ICMPConfig           datatypes.JSONType[protocol.ICMPMonitorConfig] `json:"icmpConfig"`                            // ICMP 监控配置
+	NotificationChannels datatypes.JSONSlice[string]                    `json:"notificationChannels"`                  // 接收状态变化通知的渠道 ID（未设置 ID 的渠道为类型），为空时发送到所有已启用的渠道
+	CreatedAt            int64                                          `gorm:"autoCreateTime:milli" json:"createdAt"` // 创建时间
+	UpdatedAt            int64                                          `gorm:"autoUpdateTime:milli" json:"updatedAt"` // 更新时间
 }
 
+const (
+	// ServerAgentID 服务端执行监控检测时使用的保留探针ID
+	ServerAgentID = "server"
+	// ServerAgentName 服务端执行监控检测时显示的探针名称
+	ServerAgentName = "服务端"
+)
+
 func (MonitorTask) TableName() string {
 	return "monitor_tasks"
 }
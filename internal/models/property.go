@@ -1,5 +1,7 @@
 package models
 
+import "slices"
+
 // Property 通用属性配置表
 type Property struct {
 	ID        string `gorm:"primaryKey" json:"id"`                  // 属性ID (如: notification_channels)
@@ -15,20 +17,37 @@ func (Property) TableName() string {
 
 // NotificationChannelConfig 通知渠道配置（存储在 Property 中）
 type NotificationChannelConfig struct {
-	Type    string                 `json:"type"`    // 类型: dingtalk, wecom, feishu, webhook
-	Enabled bool                   `json:"enabled"` // 是否启用
-	Config  map[string]interface{} `json:"config"`  // 配置对象
+	ID              string                 `json:"id,omitempty"`              // 渠道ID，监控项按 ID 指定通知渠道，为空时使用类型（兼容旧配置）
+	Type            string                 `json:"type"`                      // 类型: dingtalk, wecom, feishu, discord, mattermost, webhook
+	Enabled         bool                   `json:"enabled"`                   // 是否启用
+	MinLevel        string                 `json:"minLevel,omitempty"`        // 最低告警级别: info, warning, critical，为空时接收所有级别
+	NotifyOnResolve *bool                  `json:"notifyOnResolve,omitempty"` // 是否发送告警恢复通知，为空时使用全局告警配置
+	Config          map[string]interface{} `json:"config"`                    // 配置对象
+}
+
+// ChannelID 渠道的唯一标识，未设置 ID 时使用类型
+func (c NotificationChannelConfig) ChannelID() string {
+	if c.ID != "" {
+		return c.ID
+	}
+	return c.Type
 }
 
 // 配置格式说明：
 // dingtalk: { "secretKey": "xxx", "signSecret": "xxx" }
-// wecom:    { "secretKey": "xxx" }
+// wecom:    { "secretKey": "xxx" }  // 群机器人
+//           或 { "corpId": "xxx", "corpSecret": "xxx", "agentId": 1000002, "userIds": ["zhangsan"], "departmentIds": ["2"], "url": "https://..." }  // 企业应用，发送文本卡片
 // feishu:   { "secretKey": "xxx", "signSecret": "xxx" }
+// discord:  { "webhookUrl": "https://discord.com/api/webhooks/..." }
+// mattermost: { "webhookUrl": "https://mattermost.example.com/hooks/...", "channel": "town-square" }  // channel 可选，覆盖 Webhook 默认频道
+// 所有渠道均可额外配置 "debugBody": true，以 debug 级别记录完整请求体，便于排查接收端截断或拒绝大消息的问题
+// HTTP 类渠道还可配置 "timeoutSeconds": 10（单次请求超时，默认 10 秒，最长 120 秒）和 "proxy": "http://127.0.0.1:7890"（支持 http/https/socks5）
 // webhook:  {
 //   "url": "https://...",
 //   "method": "POST",  // 可选：GET, POST, PUT, PATCH, DELETE，默认 POST
 //   "headers": {"key": "value"},  // 可选：自定义请求头
-//   "customBody": ""  // 自定义请求体模板，支持变量替换
+//   "customBody": "",  // 自定义请求体模板，支持变量替换，为空时发送默认请求体
+//   "schemaVersion": 1  // 可选：固定请求体结构版本，默认使用当前版本
 // }
 
 // DNSProviderConfig DNS 服务商配置（存储在 Property 中）
@@ -53,16 +72,115 @@ type WebhookConfig struct {
 }
 
 type SystemConfig struct {
-	SystemNameZh string `json:"systemNameZh"` // 系统名称（中文）
-	SystemNameEn string `json:"systemNameEn"` // 系统名称（英文）
-	LogoBase64   string `json:"logoBase64"`   // 系统logo（base64编码）
-	ICPCode      string `json:"icpCode"`      // ICP备案号
-	DefaultView  string `json:"defaultView"`  // 默认视图 grid | list
-	CustomCSS    string `json:"customCSS"`    // 自定义 CSS
-	CustomJS     string `json:"customJS"`     // 自定义 JS
-	Version      string `json:"-"`            // 系统版本
+	SystemNameZh string   `json:"systemNameZh"`          // 系统名称（中文）
+	SystemNameEn string   `json:"systemNameEn"`          // 系统名称（英文）
+	LogoBase64   string   `json:"logoBase64"`            // 系统logo（base64编码）
+	ICPCode      string   `json:"icpCode"`               // ICP备案号
+	DefaultView  string   `json:"defaultView"`           // 默认视图 grid | list
+	MetricCards  []string `json:"metricCards,omitempty"` // 探针详情页展示的指标卡片及顺序，为空时使用 DefaultMetricCards
+	CustomCSS    string   `json:"customCSS"`             // 自定义 CSS
+	CustomJS     string   `json:"customJS"`              // 自定义 JS
+	Version      string   `json:"-"`                     // 系统版本
+}
+
+// DerivedMetric 派生指标：由已存储的字段通过四则运算计算得到，查询时计算，不单独存储
+type DerivedMetric struct {
+	Name        string `json:"name"`                  // 名称，查询时作为 name 参数
+	Expression  string `json:"expression"`            // 表达式，如 (memory.used+memory.swapUsed)/(memory.total+memory.swapTotal)*100
+	Unit        string `json:"unit,omitempty"`        // 单位，仅用于展示
+	Description string `json:"description,omitempty"` // 说明
+}
+
+// DefaultMetricCards 默认展示的指标卡片及顺序（与原有详情页布局一致）
+var DefaultMetricCards = []string{"cpu", "memory", "network", "disk_io", "network_connection", "gpu", "temperature", "monitor"}
+
+// BrandingOverride 分组品牌覆盖配置，空字段回退到全局系统配置
+type BrandingOverride struct {
+	SystemNameZh string `json:"systemNameZh,omitempty"` // 系统名称（中文）
+	SystemNameEn string `json:"systemNameEn,omitempty"` // 系统名称（英文）
+	LogoBase64   string `json:"logoBase64,omitempty"`   // 系统logo（base64编码）
+	ICPCode      string `json:"icpCode,omitempty"`      // ICP备案号
+	CustomCSS    string `json:"customCSS,omitempty"`    // 自定义 CSS
+	CustomJS     string `json:"customJS,omitempty"`     // 自定义 JS
+}
+
+// Apply 将覆盖配置合并到系统配置
+func (b BrandingOverride) Apply(config *SystemConfig) {
+	if b.SystemNameZh != "" {
+		config.SystemNameZh = b.SystemNameZh
+	}
+	if b.SystemNameEn != "" {
+		config.SystemNameEn = b.SystemNameEn
+	}
+	if b.LogoBase64 != "" {
+		config.LogoBase64 = b.LogoBase64
+	}
+	if b.ICPCode != "" {
+		config.ICPCode = b.ICPCode
+	}
+	if b.CustomCSS != "" {
+		config.CustomCSS = b.CustomCSS
+	}
+	if b.CustomJS != "" {
+		config.CustomJS = b.CustomJS
+	}
 }
 
+// AgentCleanupConfig 长期离线探针自动清理配置
+type AgentCleanupConfig struct {
+	Enabled         bool     `json:"enabled"`         // 是否启用自动清理（默认关闭）
+	OfflineDays     int      `json:"offlineDays"`     // 离线超过该天数的探针进入待清理状态，默认 30
+	GraceHours      int      `json:"graceHours"`      // 发出清理预告后等待的小时数，期间探针重新上线则取消清理，默认 24
+	ExcludeAgentIDs []string `json:"excludeAgentIds"` // 不自动清理的探针ID
+	ExcludeTags     []string `json:"excludeTags"`     // 带有任一标签的探针不自动清理
+}
+
+// 入库降采样方式
+const (
+	IngestDownsamplingModeFirst = "first" // 保留窗口内的第一个样本
+	IngestDownsamplingModeAvg   = "avg"   // 写入窗口内样本的平均值，时间戳为窗口起点
+)
+
+// IngestDownsamplingConfig 入库降采样配置，匹配规则的探针在每个时间窗口内只写入一个样本（默认关闭）
+type IngestDownsamplingConfig struct {
+	Enabled bool                     `json:"enabled"` // 是否启用
+	Rules   []IngestDownsamplingRule `json:"rules"`   // 按顺序匹配，第一个匹配的规则生效
+}
+
+// IngestDownsamplingRule 入库降采样规则
+type IngestDownsamplingRule struct {
+	AgentIDs []string `json:"agentIds"` // 匹配的探针ID
+	Tags     []string `json:"tags"`     // 匹配带有任一标签的探针
+	Interval int      `json:"interval"` // 时间窗口（秒）
+	Mode     string   `json:"mode"`     // 降采样方式: first（默认）、avg
+}
+
+// ArchiveConfig 告警记录长期归档配置（S3 兼容对象存储）
+type ArchiveConfig struct {
+	Enabled            bool          `json:"enabled"`            // 是否启用归档
+	Endpoint           string        `json:"endpoint"`           // 对象存储地址，如 https://s3.amazonaws.com、http://minio:9000
+	Region             string        `json:"region"`             // 区域，默认 us-east-1
+	Bucket             string        `json:"bucket"`             // 存储桶
+	AccessKeyID        string        `json:"accessKeyId"`        // Access Key
+	SecretAccessKey    string        `json:"secretAccessKey"`    // Secret Key
+	Prefix             string        `json:"prefix"`             // 对象键前缀
+	UsePathStyle       bool          `json:"usePathStyle"`       // 使用路径风格访问（MinIO 等通常需要开启）
+	Format             ArchiveFormat `json:"format"`             // 归档格式: json, ndjson（均为 gzip 压缩）
+	ArchiveAfterDays   int           `json:"archiveAfterDays"`   // 恢复超过该天数的告警记录才归档
+	DeleteAfterArchive bool          `json:"deleteAfterArchive"` // 归档成功后是否从数据库删除
+	IntervalMinutes    int           `json:"intervalMinutes"`    // 归档检查间隔（分钟）
+	ArchiveMetrics     bool          `json:"archiveMetrics"`     // 是否同时按天归档聚合后的探针指标
+	MetricsInterval    int           `json:"metricsInterval"`    // 指标归档的聚合步长（秒），默认 3600
+}
+
+// ArchiveFormat 归档文件格式
+type ArchiveFormat string
+
+const (
+	ArchiveFormatJSON   ArchiveFormat = "json"   // gzip 压缩的 JSON 数组
+	ArchiveFormatNDJSON ArchiveFormat = "ndjson" // gzip 压缩的 JSON Lines，每行一条记录
+)
+
 // PublicIPConfig 公网 IP 采集配置
 type PublicIPConfig struct {
 	Enabled         bool     `json:"enabled"`         // 是否启用采集
@@ -75,6 +193,16 @@ type PublicIPConfig struct {
 	IPv6Enabled     bool     `json:"ipv6Enabled"`     // 是否采集 IPv6
 	IPv4APIs        []string `json:"ipv4Apis"`        // IPv4 API 列表
 	IPv6APIs        []string `json:"ipv6Apis"`        // IPv6 API 列表
+	// 带优先级和超时的 API 列表，非空时优先于 IPv4APIs/IPv6APIs
+	IPv4APIEntries []PublicIPAPI `json:"ipv4ApiEntries,omitempty"`
+	IPv6APIEntries []PublicIPAPI `json:"ipv6ApiEntries,omitempty"`
+}
+
+// PublicIPAPI 公网 IP 查询 API
+type PublicIPAPI struct {
+	URL            string `json:"url"`            // API 地址
+	Priority       int    `json:"priority"`       // 优先级，数值越小越先尝试
+	TimeoutSeconds int    `json:"timeoutSeconds"` // 单次请求超时（秒），必须为正数
 }
 
 func (c *PublicIPConfig) IsIPv4Target(agentID string) bool {
@@ -109,33 +237,138 @@ func (c *PublicIPConfig) IsIPv6Target(agentID string) bool {
 
 // AlertConfig 全局告警配置
 type AlertConfig struct {
-	Enabled       bool               `json:"enabled"`       // 是否启用全局告警
-	MaskIP        bool               `json:"maskIP"`        // 是否在通知中打码 IP 地址
-	Rules         AlertRules         `json:"rules"`         // 告警规则
-	Notifications AlertNotifications `json:"notifications"` // 通知开关
+	Enabled       bool                 `json:"enabled"`       // 是否启用全局告警
+	MaskIP        bool                 `json:"maskIP"`        // 是否在通知中打码 IP 地址（旧配置，未设置 MaskIPMode 时生效）
+	MaskIPMode    IPMaskMode           `json:"maskIPMode"`    // IP 打码粒度：none/partial/full
+	Rules         AlertRules           `json:"rules"`         // 告警规则
+	Notifications AlertNotifications   `json:"notifications"` // 通知开关
+	Throttle      NotificationThrottle `json:"throttle"`      // 单个探针的通知限流
+	Escalation    AlertEscalation      `json:"escalation"`    // 未确认告警的升级策略
+	// NotifyOnResolve 是否发送告警恢复通知，为空时发送；关闭后告警恢复仍会记录，只是不发送通知
+	NotifyOnResolve *bool `json:"notifyOnResolve,omitempty"`
+	// TemplateBindings 按顺序匹配，探针匹配的第一个绑定引用的模板替换全局的资源类告警规则
+	TemplateBindings []AlertTemplateBinding `json:"templateBindings,omitempty"`
+}
+
+// AlertRuleTemplate 命名的告警规则模板，保存在 alert_rule_templates 属性中
+type AlertRuleTemplate struct {
+	ID          string     `json:"id"`                    // 模板ID
+	Name        string     `json:"name"`                  // 模板名称
+	Description string     `json:"description,omitempty"` // 说明
+	Rules       AlertRules `json:"rules"`                 // 告警规则，只使用其中的资源类告警规则和持续时间语义
+	CreatedAt   int64      `json:"createdAt"`             // 创建时间（时间戳毫秒）
+}
+
+// AlertTemplateBinding 将告警规则模板应用到一组探针，标签和属性条件同时指定时需同时满足
+type AlertTemplateBinding struct {
+	TemplateID      string            `json:"templateId"`                // 引用的模板ID
+	Tags            []string          `json:"tags,omitempty"`            // 带有任一标签的探针
+	AgentAttributes map[string]string `json:"agentAttributes,omitempty"` // 属性全部匹配的探针
+}
+
+// Matches 判断探针是否属于该绑定
+func (b AlertTemplateBinding) Matches(agent *Agent) bool {
+	if len(b.Tags) == 0 && len(b.AgentAttributes) == 0 {
+		return false
+	}
+	if len(b.Tags) > 0 && !slices.ContainsFunc(b.Tags, func(tag string) bool { return slices.Contains(agent.Tags, tag) }) {
+		return false
+	}
+	return agent.MatchAttributes(b.AgentAttributes)
+}
+
+// ShouldNotifyOnResolve 渠道是否发送告警恢复通知，渠道未配置时使用全局配置，默认发送
+func (c AlertConfig) ShouldNotifyOnResolve(channel NotificationChannelConfig) bool {
+	if channel.NotifyOnResolve != nil {
+		return *channel.NotifyOnResolve
+	}
+	if c.NotifyOnResolve != nil {
+		return *c.NotifyOnResolve
+	}
+	return true
+}
+
+// AlertEscalation 未确认告警的升级策略：告警触发后超过指定时间仍未确认时通知升级渠道，确认或恢复后停止
+type AlertEscalation struct {
+	Enabled       bool     `json:"enabled"`       // 是否启用升级
+	MinLevel      string   `json:"minLevel"`      // 参与升级的最低告警级别，默认 critical
+	AfterMinutes  int      `json:"afterMinutes"`  // 告警触发后未确认的分钟数，默认 15
+	RepeatMinutes int      `json:"repeatMinutes"` // 升级后仍未确认时重复通知的间隔（分钟），0 表示只通知一次
+	Channels      []string `json:"channels"`      // 升级通知发送的渠道 ID（渠道配置中的 id，未设置时为渠道类型，如 ["telegram"]），不受渠道最低告警级别和通知限流限制
+}
+
+// NotificationThrottle 单个探针在时间窗口内的通知预算，超出部分只记录告警不发送通知
+type NotificationThrottle struct {
+	MaxNotifications int `json:"maxNotifications"` // 时间窗口内单个探针最多发送的通知数，0 表示不限制
+	WindowMinutes    int `json:"windowMinutes"`    // 时间窗口（分钟），默认 60
+}
+
+// IPMaskMode 通知中 IP 地址的打码粒度
+type IPMaskMode string
+
+const (
+	IPMaskNone    IPMaskMode = "none"    // 不打码
+	IPMaskPartial IPMaskMode = "partial" // 部分打码：IPv4 隐藏最后一段，IPv6 隐藏接口标识（后 64 位）
+	IPMaskFull    IPMaskMode = "full"    // 完全打码
+)
+
+// ResolveMaskIPMode 获取实际生效的打码粒度，未配置 MaskIPMode 时兼容旧的 MaskIP 开关
+// 旧版本开启 MaskIP 时只隐藏 IP 的后半部分，对应 partial
+func (c AlertConfig) ResolveMaskIPMode() IPMaskMode {
+	switch c.MaskIPMode {
+	case IPMaskNone, IPMaskPartial, IPMaskFull:
+		return c.MaskIPMode
+	}
+	if c.MaskIP {
+		return IPMaskPartial
+	}
+	return IPMaskNone
 }
 
 // AlertRules 告警规则
 type AlertRules struct {
 	// CPU 告警配置
-	CPUEnabled   bool    `json:"cpuEnabled"`   // 是否启用CPU告警
-	CPUThreshold float64 `json:"cpuThreshold"` // CPU使用率阈值(0-100)
-	CPUDuration  int     `json:"cpuDuration"`  // 持续时间（秒）
+	CPUEnabled   bool           `json:"cpuEnabled"`         // 是否启用CPU告警
+	CPUThreshold float64        `json:"cpuThreshold"`       // CPU使用率阈值(0-100)
+	CPUDuration  int            `json:"cpuDuration"`        // 持续时间（秒）
+	CPUTiers     []SeverityTier `json:"cpuTiers,omitempty"` // 分级阈值，超过更高级别阈值时升级告警
 
 	// 内存告警配置
-	MemoryEnabled   bool    `json:"memoryEnabled"`   // 是否启用内存告警
-	MemoryThreshold float64 `json:"memoryThreshold"` // 内存使用率阈值(0-100)
-	MemoryDuration  int     `json:"memoryDuration"`  // 持续时间（秒）
+	MemoryEnabled       bool           `json:"memoryEnabled"`                 // 是否启用内存告警
+	MemoryThreshold     float64        `json:"memoryThreshold"`               // 内存使用率阈值(0-100)
+	MemoryDuration      int            `json:"memoryDuration"`                // 持续时间（秒）
+	MemoryTiers         []SeverityTier `json:"memoryTiers,omitempty"`         // 分级阈值，超过更高级别阈值时升级告警
+	MemoryThresholdMode string         `json:"memoryThresholdMode,omitempty"` // 阈值模式: percent 按使用率（默认）, free 按剩余内存
+	MemoryFreeThreshold float64        `json:"memoryFreeThreshold,omitempty"` // 剩余内存阈值(GB)，free 模式下剩余内存低于该值时告警
 
 	// 磁盘告警配置
-	DiskEnabled   bool    `json:"diskEnabled"`   // 是否启用磁盘告警
-	DiskThreshold float64 `json:"diskThreshold"` // 磁盘使用率阈值(0-100)
-	DiskDuration  int     `json:"diskDuration"`  // 持续时间（秒）
+	DiskEnabled       bool           `json:"diskEnabled"`                 // 是否启用磁盘告警
+	DiskThreshold     float64        `json:"diskThreshold"`               // 磁盘使用率阈值(0-100)
+	DiskDuration      int            `json:"diskDuration"`                // 持续时间（秒）
+	DiskTiers         []SeverityTier `json:"diskTiers,omitempty"`         // 分级阈值，超过更高级别阈值时升级告警
+	DiskThresholdMode string         `json:"diskThresholdMode,omitempty"` // 阈值模式: percent 按使用率（默认）, free 按剩余空间
+	DiskFreeThreshold float64        `json:"diskFreeThreshold,omitempty"` // 磁盘剩余空间阈值(GB)，free 模式下剩余空间低于该值时告警
+
+	// 磁盘将满预测告警配置
+	DiskPredictEnabled bool `json:"diskPredictEnabled"` // 是否启用磁盘将满预测告警
+	DiskPredictHorizon int  `json:"diskPredictHorizon"` // 预计写满时间小于该值时告警（小时）
 
 	// 网络告警配置
-	NetworkEnabled   bool    `json:"networkEnabled"`   // 是否启用网络告警
-	NetworkThreshold float64 `json:"networkThreshold"` // 网速阈值(MB/s)
-	NetworkDuration  int     `json:"networkDuration"`  // 持续时间（秒）
+	NetworkEnabled   bool           `json:"networkEnabled"`         // 是否启用网络告警
+	NetworkThreshold float64        `json:"networkThreshold"`       // 网速阈值(MB/s)
+	NetworkDuration  int            `json:"networkDuration"`        // 持续时间（秒）
+	NetworkTiers     []SeverityTier `json:"networkTiers,omitempty"` // 分级阈值，超过更高级别阈值时升级告警
+
+	// 系统负载告警配置，按每核 1 分钟平均负载判断
+	LoadEnabled   bool    `json:"loadEnabled"`   // 是否启用负载告警
+	LoadThreshold float64 `json:"loadThreshold"` // 每核负载阈值，如 1.5 表示 1 分钟平均负载超过核心数的 1.5 倍
+	LoadDuration  int     `json:"loadDuration"`  // 持续时间（秒）
+
+	// 网络连接数告警配置
+	ConnectionEnabled   bool                  `json:"connectionEnabled"`          // 是否启用连接数告警
+	ConnectionThreshold float64               `json:"connectionThreshold"`        // 总连接数阈值（0 表示不检查总连接数）
+	ConnectionDuration  int                   `json:"connectionDuration"`         // 持续时间（秒）
+	ConnectionStates    []ConnectionStateRule `json:"connectionStates,omitempty"` // 按连接状态的阈值，如 CLOSE_WAIT、TIME_WAIT
 
 	// HTTPS 证书告警配置
 	CertEnabled   bool    `json:"certEnabled"`   // 是否启用证书告警
@@ -148,6 +381,48 @@ type AlertRules struct {
 	// 探针离线告警配置
 	AgentOfflineEnabled  bool `json:"agentOfflineEnabled"`  // 是否启用探针离线告警
 	AgentOfflineDuration int  `json:"agentOfflineDuration"` // 持续时间（秒）
+
+	// 探针采集异常告警配置，按探针心跳上报的运行状况判断
+	CollectorErrorEnabled  bool `json:"collectorErrorEnabled"`  // 是否启用探针采集异常告警
+	CollectorErrorDuration int  `json:"collectorErrorDuration"` // 持续时间（秒）
+
+	// 作用范围：资源类告警仅对属性全部匹配的探针生效，为空时对所有探针生效
+	AgentAttributes map[string]string `json:"agentAttributes,omitempty"`
+
+	// 持续时间语义，对资源类告警（CPU、内存、磁盘、网速、负载、连接数）生效
+	DurationMode string  `json:"durationMode,omitempty"` // continuous（默认）: 持续超过阈值才触发，任一采样恢复即重新计时; windowed: 按窗口内超过阈值的采样占比判断
+	WindowRatio  float64 `json:"windowRatio,omitempty"`  // windowed 模式下触发所需的超阈值采样占比(0-100)，默认 80
+}
+
+// 告警持续时间语义
+const (
+	DurationModeContinuous = "continuous" // 连续：超过阈值的时间需连续达到持续时间
+	DurationModeWindowed   = "windowed"   // 窗口：最近一个持续时间窗口内超过阈值的采样占比达到 WindowRatio
+)
+
+// 阈值模式
+const (
+	ThresholdModePercent = "percent" // 按使用率
+	ThresholdModeFree    = "free"    // 按剩余量
+)
+
+// 告警级别
+const (
+	AlertLevelInfo     = "info"
+	AlertLevelWarning  = "warning"
+	AlertLevelCritical = "critical"
+)
+
+// SeverityTier 告警分级阈值
+type SeverityTier struct {
+	Level     string  `json:"level"`     // 告警级别: info, warning, critical
+	Threshold float64 `json:"threshold"` // 达到该值时使用的告警级别
+}
+
+// ConnectionStateRule 连接状态告警阈值
+type ConnectionStateRule struct {
+	State     string  `json:"state"`     // 连接状态，取值同 NetworkConnectionData 字段，如 closeWait、timeWait
+	Threshold float64 `json:"threshold"` // 该状态连接数阈值
 }
 
 // AlertNotifications 告警通知开关
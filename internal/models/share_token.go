@@ -0,0 +1,20 @@
+package models
+
+import "gorm.io/datatypes"
+
+// ShareToken 只读分享令牌（令牌本身为签名的 JWT，此处仅保存元数据用于列表展示和吊销）
+type ShareToken struct {
+	ID        string                      `gorm:"primaryKey" json:"id"`                  // 令牌ID（JWT jti）
+	Name      string                      `gorm:"index" json:"name"`                     // 名称/备注
+	AgentIDs  datatypes.JSONSlice[string] `json:"agentIds"`                              // 允许访问的探针ID
+	Tags      datatypes.JSONSlice[string] `json:"tags"`                                  // 允许访问的探针标签（分组）
+	ExpiresAt int64                       `gorm:"index" json:"expiresAt"`                // 过期时间（时间戳毫秒）
+	RevokedAt int64                       `gorm:"index" json:"revokedAt,omitempty"`      // 吊销时间（时间戳毫秒），0 表示未吊销
+	CreatedBy string                      `gorm:"index" json:"createdBy"`                // 创建人
+	CreatedAt int64                       `json:"createdAt"`                             // 创建时间（时间戳毫秒）
+	UpdatedAt int64                       `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+}
+
+func (ShareToken) TableName() string {
+	return "share_tokens"
+}
@@ -0,0 +1,30 @@
+package models
+
+// StorageStats 存储容量统计，用于容量规划
+type StorageStats struct {
+	Dialect       string            `json:"dialect"`       // 数据库类型：sqlite/postgres
+	DatabaseBytes int64             `json:"databaseBytes"` // 数据库总占用空间（字节），-1 表示无法获取
+	Tables        []TableStats      `json:"tables"`        // 各数据表统计
+	Metrics       []MetricTypeStats `json:"metrics"`       // 各指标类型统计（存储于 VictoriaMetrics）
+}
+
+// TableStats 数据表统计
+type TableStats struct {
+	Table      string `json:"table"`                // 表名
+	Rows       int64  `json:"rows"`                 // 行数
+	SizeBytes  int64  `json:"sizeBytes"`            // 占用空间（字节），-1 表示驱动不支持
+	TimeColumn string `json:"timeColumn,omitempty"` // 用于统计最早/最新时间的字段
+	OldestAt   int64  `json:"oldestAt,omitempty"`   // 最早记录时间（毫秒）
+	NewestAt   int64  `json:"newestAt,omitempty"`   // 最新记录时间（毫秒）
+	Error      string `json:"error,omitempty"`      // 统计失败的原因，其他表照常返回
+}
+
+// MetricTypeStats 指标类型统计
+type MetricTypeStats struct {
+	Type     string `json:"type"`               // 指标类型
+	Metric   string `json:"metric"`             // 代表性指标名称
+	Series   int64  `json:"series"`             // 时间序列数量
+	OldestAt int64  `json:"oldestAt,omitempty"` // 最早样本时间（毫秒）
+	NewestAt int64  `json:"newestAt,omitempty"` // 最新样本时间（毫秒）
+	Error    string `json:"error,omitempty"`    // 统计失败的原因，其他指标类型照常返回
+}
@@ -0,0 +1,14 @@
+package models
+
+// UserCredential 运行时修改或升级算法后的用户密码（优先于配置文件中的密码）
+type UserCredential struct {
+	Username     string `gorm:"primaryKey" json:"username"`            // 用户名
+	PasswordHash string `json:"-"`                                     // 密码哈希
+	Algorithm    string `json:"algorithm"`                             // 密码哈希算法: argon2id, bcrypt
+	RehashedFrom string `json:"-"`                                     // 登录时由配置文件中的哪个哈希升级而来，为空表示通过接口修改的密码
+	UpdatedAt    int64  `json:"updatedAt" gorm:"autoUpdateTime:milli"` // 更新时间（时间戳毫秒）
+}
+
+func (UserCredential) TableName() string {
+	return "user_credentials"
+}
@@ -0,0 +1,33 @@
+package protocol
+
+// 自定义检查结果解析方式
+const (
+	CustomCheckParserNumber   = "number"    // 取输出中的第一个数字
+	CustomCheckParserRegex    = "regex"     // 取正则第一个捕获组中的数字
+	CustomCheckParserExitCode = "exit_code" // 取命令退出码
+)
+
+// CustomCheckItem 单个自定义检查（服务端下发给客户端）
+type CustomCheckItem struct {
+	ID              string `json:"id"`                // 检查ID
+	Name            string `json:"name"`              // 检查名称
+	Command         string `json:"command"`           // 执行的 shell 命令
+	IntervalSeconds int    `json:"intervalSeconds"`   // 执行间隔（秒）
+	TimeoutSeconds  int    `json:"timeoutSeconds"`    // 执行超时（秒）
+	Parser          string `json:"parser"`            // 解析方式: number, regex, exit_code
+	Pattern         string `json:"pattern,omitempty"` // 正则表达式（parser 为 regex 时使用）
+}
+
+// CustomCheckConfigData 自定义检查配置（服务端下发给客户端，整体替换客户端已有的检查）
+type CustomCheckConfigData struct {
+	Checks []CustomCheckItem `json:"checks"`
+}
+
+// CustomMetricData 自定义检查结果（客户端上报）
+type CustomMetricData struct {
+	CheckID string  `json:"checkId"`         // 检查ID
+	Name    string  `json:"name"`            // 检查名称
+	Value   float64 `json:"value"`           // 解析得到的数值
+	Success bool    `json:"success"`         // 是否执行并解析成功
+	Error   string  `json:"error,omitempty"` // 失败原因
+}
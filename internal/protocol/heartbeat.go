@@ -0,0 +1,19 @@
+package protocol
+
+// HeartbeatData 心跳数据，旧版本探针发送空对象
+type HeartbeatData struct {
+	Health *AgentHealth `json:"health,omitempty"` // 探针自身运行状况
+}
+
+// AgentHealth 探针自报的运行状况
+type AgentHealth struct {
+	CollectErrors     int64    `json:"collectErrors"`               // 启动以来指标采集失败次数
+	FailingCollectors []string `json:"failingCollectors,omitempty"` // 最近一轮采集失败的指标类型，为空表示最近一轮全部成功
+	LastError         string   `json:"lastError,omitempty"`         // 最近一次采集错误
+	LastErrorAt       int64    `json:"lastErrorAt,omitempty"`       // 最近一次采集错误时间（时间戳毫秒）
+	DroppedSamples    int64    `json:"droppedSamples"`              // 启动以来因缓存失败或过期丢弃的消息数
+	CPUPercent        float64  `json:"cpuPercent"`                  // 探针进程 CPU 使用率（相对单核，多核时可能超过 100）
+	MemoryRSS         uint64   `json:"memoryRss"`                   // 探针进程常驻内存（字节）
+	Goroutines        int      `json:"goroutines"`                  // 探针协程数
+	UptimeSeconds     int64    `json:"uptimeSeconds"`               // 探针进程运行时长（秒）
+}
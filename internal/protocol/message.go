@@ -1,6 +1,11 @@
 package protocol
 
-import "encoding/json"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
 
 // InputMessage WebSocket消息结构（主要用于接收）
 type InputMessage struct {
@@ -16,33 +21,96 @@ type OutboundMessage struct {
 
 // RegisterRequest 注册请求
 type RegisterRequest struct {
-	AgentInfo AgentInfo `json:"agentInfo"`
-	ApiKey    string    `json:"apiKey"`
+	AgentInfo    AgentInfo          `json:"agentInfo"`
+	ApiKey       string             `json:"apiKey"`
+	Compression  string             `json:"compression,omitempty"`  // 期望的消息压缩算法: gzip（旧版本探针），新版本使用 Compressions
+	Compressions []string           `json:"compressions,omitempty"` // 探针支持的压缩算法，按偏好排序: zstd, gzip
+	Signature    *RegisterSignature `json:"signature,omitempty"`    // 探针密钥对注册信息的签名，未启用签名注册时为空
+}
+
+// RegisterSignature 探针使用 Ed25519 私钥对注册信息的签名
+type RegisterSignature struct {
+	PublicKey string `json:"publicKey"` // 公钥（base64）
+	Timestamp int64  `json:"timestamp"` // 签名时间（时间戳毫秒）
+	Nonce     string `json:"nonce"`     // 服务端下发的挑战随机数，每个连接只能使用一次，防止重放
+	Signature string `json:"signature"` // 对 RegisterSigningPayload 的签名（base64）
+}
+
+// RegisterChallenge 签名注册的挑战，探针发送 register_challenge 后服务端返回本次连接的随机数
+type RegisterChallenge struct {
+	Nonce string `json:"nonce"`
+}
+
+// RegisterKeyFingerprint 注册公钥指纹（SHA-256 前 16 字节的十六进制），便于核对探针日志与待审批的公钥
+func RegisterKeyFingerprint(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:16])
+}
+
+// RegisterSigningPayload 注册签名的内容，探针和服务端使用相同的格式
+func RegisterSigningPayload(agentID string, timestamp int64, nonce string) []byte {
+	return []byte(fmt.Sprintf("pika-register\n%s\n%d\n%s", agentID, timestamp, nonce))
 }
 
 // RegisterResponse 注册响应
 type RegisterResponse struct {
-	AgentID string `json:"agentId"`
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	AgentID     string `json:"agentId"`
+	Status      string `json:"status"`
+	Message     string `json:"message,omitempty"`
+	Compression string `json:"compression,omitempty"` // 协商后的压缩算法，为空表示不压缩
 }
 
-// AgentInfo 探针信息
-type AgentInfo struct {
-	ID       string `json:"id"`       // 探针唯一标识（持久化）
-	Name     string `json:"name"`     // 探针名称
-	Hostname string `json:"hostname"` // 主机名
-	OS       string `json:"os"`       // 操作系统
-	Arch     string `json:"arch"`     // 架构
-	Version  string `json:"version"`  // 版本号
+// ReassignIDData 重新分配探针ID
+type ReassignIDData struct {
+	AgentID string `json:"agentId"` // 新的探针ID
 }
 
-// MetricsPayload 指标数据包装，发送端/接收端统一使用
+// AgentInfo 探针信息
+type AgentInfo struct {
+	ID       string `json:"id"`            // 探针唯一标识（持久化）
+	Name     string `json:"name"`          // 探针名称
+	Hostname string `json:"hostname"`      // 主机名
+	OS       string `json:"os"`            // 操作系统
+	Arch     string `json:"arch"`          // 架构
+	Version  string `json:"version"`       // 版本号
+	MAC      string `json:"mac,omitempty"` // 主网卡 MAC 地址
+	// Attributes 探针配置的自定义属性，如 datacenter=us-east、role=db
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// MetricsPayload 指标数据包装（主要用于发送）
 type MetricsPayload struct {
 	Type      MetricType  `json:"type"`
 	Data      interface{} `json:"data"`
 	Timestamp int64       `json:"timestamp,omitempty"` // 客户端采集时间(毫秒)
+	// CollectDuration 采集耗时(毫秒)，旧版本探针不上报
+	CollectDuration int64 `json:"collectDuration,omitempty"`
 }
+
+// InputMetricsPayload 指标数据包装（主要用于接收），Data 保留原始 JSON，由服务端按指标类型直接解析为对应结构
+type InputMetricsPayload struct {
+	Type            MetricType      `json:"type"`
+	Data            json.RawMessage `json:"data"`
+	Timestamp       int64           `json:"timestamp,omitempty"`
+	CollectDuration int64           `json:"collectDuration,omitempty"`
+}
+
+// MetricsResult 指标处理结果，数组类指标有条目处理失败时由服务端回传给探针
+type MetricsResult struct {
+	Type        MetricType        `json:"type"`
+	Timestamp   int64             `json:"timestamp,omitempty"` // 对应指标的采集时间(毫秒)
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	FailedItems []MetricItemError `json:"failedItems,omitempty"`
+	Resend      bool              `json:"resend"` // 写入存储失败，建议探针重新上报
+}
+
+// MetricItemError 数组类指标中单个条目的处理错误
+type MetricItemError struct {
+	Index int    `json:"index"` // 条目在上报数组中的下标，-1 表示整批失败
+	Error string `json:"error"`
+}
+
 type MessageType string
 
 // 控制消息
@@ -50,10 +118,13 @@ const (
 	MessageTypeRegister    MessageType = "register"
 	MessageTypeRegisterAck MessageType = "register_ack"
 	MessageTypeRegisterErr MessageType = "register_error"
-	MessageTypeHeartbeat   MessageType = "heartbeat"
-	MessageTypeCommand     MessageType = "command"
-	MessageTypeCommandResp MessageType = "command_response"
-	MessageTypeUninstall   MessageType = "uninstall"
+	// 签名注册挑战：探针请求，服务端返回随机数
+	MessageTypeRegisterChallenge MessageType = "register_challenge"
+	MessageTypeHeartbeat         MessageType = "heartbeat"
+	MessageTypeCommand           MessageType = "command"
+	MessageTypeCommandResp       MessageType = "command_response"
+	MessageTypeUninstall         MessageType = "uninstall"
+	MessageTypeReassignID        MessageType = "reassign_id" // 服务端为冲突的探针分配新ID
 	// 指标消息
 	MessageTypeMetrics       MessageType = "metrics"
 	MessageTypeMonitorConfig MessageType = "monitor_config"
@@ -70,6 +141,18 @@ const (
 	MessageTypeSSHLoginConfig       MessageType = "ssh_login_config"
 	MessageTypeSSHLoginConfigResult MessageType = "ssh_login_config_result" // Agent 反馈配置应用结果
 	MessageTypeSSHLoginEvent        MessageType = "ssh_login_event"
+	// 自定义检查消息
+	MessageTypeCustomCheckConfig MessageType = "custom_check_config"
+	// 指标采集策略消息
+	MessageTypeMetricPolicy MessageType = "metric_policy"
+	// 完整配置重新下发消息
+	MessageTypeReloadConfig MessageType = "reload_config"
+
+	// 指标部分处理失败时服务端回传的结果
+	MessageTypeMetricsResult MessageType = "metrics_result"
+	// 日志跟踪消息
+	MessageTypeLogTail     MessageType = "log_tail"      // 服务端请求开始/停止跟踪日志文件
+	MessageTypeLogTailData MessageType = "log_tail_data" // Agent 回传新增的日志行
 )
 
 type MetricType string
@@ -86,14 +169,16 @@ const (
 	MetricTypeGPU               MetricType = "gpu"
 	MetricTypeTemperature       MetricType = "temperature"
 	MetricTypeMonitor           MetricType = "monitor"
+	MetricTypeCustom            MetricType = "custom"
+	MetricTypeLoad              MetricType = "load"
 )
 
 // CPUData CPU数据
 type CPUData struct {
-	// 静态信息(不常变化,但每次都发送)
-	LogicalCores  int    `json:"logicalCores"`
-	PhysicalCores int    `json:"physicalCores"`
-	ModelName     string `json:"modelName"`
+	// 静态信息(不常变化,仅在首次上报、发生变化或定期刷新时发送,其余时候为空,由服务端合并)
+	LogicalCores  int    `json:"logicalCores,omitempty"`
+	PhysicalCores int    `json:"physicalCores,omitempty"`
+	ModelName     string `json:"modelName,omitempty"`
 	// 动态信息
 	UsagePercent float64   `json:"usagePercent"`
 	PerCore      []float64 `json:"perCore,omitempty"`
@@ -166,7 +251,7 @@ type NetworkConnectionData struct {
 	Total       uint32 `json:"total"`       // 总连接数
 }
 
-// LoadData 系统负载数据
+// LoadData 系统负载数据（1/5/15 分钟平均负载）
 type LoadData struct {
 	Load1  float64 `json:"load1"`
 	Load5  float64 `json:"load5"`
@@ -174,28 +259,30 @@ type LoadData struct {
 }
 
 // HostInfoData 主机信息
+// 主机名、启动时间、系统、内核、虚拟化等静态信息仅在首次上报、发生变化或定期刷新时发送，其余时候为空，由服务端合并
 type HostInfoData struct {
-	Hostname             string  `json:"hostname"`
+	Hostname             string  `json:"hostname,omitempty"`
 	Uptime               uint64  `json:"uptime"`
-	BootTime             uint64  `json:"bootTime"`
+	BootTime             uint64  `json:"bootTime,omitempty"`
 	Procs                uint64  `json:"procs"`
 	Load1                float64 `json:"load1"`
 	Load5                float64 `json:"load5"`
 	Load15               float64 `json:"load15"`
-	OS                   string  `json:"os"`
-	Platform             string  `json:"platform"`
-	PlatformFamily       string  `json:"platformFamily"`
-	PlatformVersion      string  `json:"platformVersion"`
-	KernelVersion        string  `json:"kernelVersion"`
-	KernelArch           string  `json:"kernelArch"`
+	OS                   string  `json:"os,omitempty"`
+	Platform             string  `json:"platform,omitempty"`
+	PlatformFamily       string  `json:"platformFamily,omitempty"`
+	PlatformVersion      string  `json:"platformVersion,omitempty"`
+	KernelVersion        string  `json:"kernelVersion,omitempty"`
+	KernelArch           string  `json:"kernelArch,omitempty"`
 	VirtualizationSystem string  `json:"virtualizationSystem,omitempty"`
 	VirtualizationRole   string  `json:"virtualizationRole,omitempty"`
 }
 
 // GPUData GPU数据
+// 名称、UUID、显存总量等静态信息仅在首次上报、发生变化或定期刷新时发送，由服务端按 Index 合并
 type GPUData struct {
 	Index       int     `json:"index"`
-	Name        string  `json:"name"`
+	Name        string  `json:"name,omitempty"`
 	UUID        string  `json:"uuid,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
 	Utilization float64 `json:"utilization,omitempty"`
@@ -220,6 +307,30 @@ type CommandRequest struct {
 	Args string `json:"args,omitempty"`
 }
 
+// 日志跟踪动作
+const (
+	LogTailActionStart = "start"
+	LogTailActionStop  = "stop"
+)
+
+// LogTailRequest 日志跟踪请求，Agent 只允许跟踪本地配置的白名单路径
+type LogTailRequest struct {
+	SessionID          string `json:"sessionId"`
+	Action             string `json:"action"`                       // start/stop
+	Path               string `json:"path,omitempty"`               // 日志文件绝对路径
+	MaxDurationSeconds int    `json:"maxDurationSeconds,omitempty"` // 最长跟踪时长，到期后 Agent 自动停止
+	MaxLinesPerSecond  int    `json:"maxLinesPerSecond,omitempty"`  // 每秒最多回传的行数，超出部分丢弃
+}
+
+// LogTailData Agent 回传的日志行
+type LogTailData struct {
+	SessionID string   `json:"sessionId"`
+	Lines     []string `json:"lines,omitempty"`
+	Dropped   int      `json:"dropped,omitempty"` // 因超出速率限制被丢弃的行数
+	Done      bool     `json:"done,omitempty"`    // 跟踪已结束
+	Error     string   `json:"error,omitempty"`   // 结束原因（出错时）
+}
+
 // CommandResponse 指令响应
 type CommandResponse struct {
 	ID     string `json:"id"`               // 指令ID
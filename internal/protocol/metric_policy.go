@@ -0,0 +1,32 @@
+package protocol
+
+import "slices"
+
+// MetricPolicyData 指标采集策略（服务端下发给客户端）
+type MetricPolicyData struct {
+	Allow []MetricType `json:"allow,omitempty"` // 允许的指标类型，为空表示不限制
+	Deny  []MetricType `json:"deny,omitempty"`  // 禁止的指标类型，优先于 Allow
+}
+
+// Allowed 判断指标类型是否允许上报
+func (p *MetricPolicyData) Allowed(metricType MetricType) bool {
+	if p == nil {
+		return true
+	}
+	if slices.Contains(p.Deny, metricType) {
+		return false
+	}
+	return len(p.Allow) == 0 || slices.Contains(p.Allow, metricType)
+}
+
+// IsKnownMetricType 判断是否为已知的指标类型
+func IsKnownMetricType(metricType MetricType) bool {
+	switch metricType {
+	case MetricTypeCPU, MetricTypeMemory, MetricTypeDisk, MetricTypeDiskIO, MetricTypeNetwork,
+		MetricTypeNetworkConnection, MetricTypeHost, MetricTypeGPU, MetricTypeTemperature,
+		MetricTypeMonitor, MetricTypeCustom, MetricTypeLoad:
+		return true
+	default:
+		return false
+	}
+}
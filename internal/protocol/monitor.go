@@ -1,5 +1,13 @@
 package protocol
 
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
 // MonitorConfigPayload 监控配置 payload
 type MonitorConfigPayload struct {
 	Interval int           `json:"interval"`
@@ -18,12 +26,179 @@ type MonitorItem struct {
 
 // HTTPMonitorConfig HTTP 监控配置
 type HTTPMonitorConfig struct {
-	Method             string            `json:"method"`
-	ExpectedStatusCode int               `json:"expectedStatusCode"`
-	ExpectedContent    string            `json:"expectedContent,omitempty"`
-	Timeout            int               `json:"timeout"`
-	Headers            map[string]string `json:"headers,omitempty"`
-	Body               string            `json:"body,omitempty"`
+	Method             string `json:"method"`
+	ExpectedStatusCode int    `json:"expectedStatusCode"`
+	// ExpectedStatusCodes 期望的状态码列表或范围，如 "200-299,301"，配置后优先于 ExpectedStatusCode
+	ExpectedStatusCodes string            `json:"expectedStatusCodes,omitempty"`
+	ExpectedContent     string            `json:"expectedContent,omitempty"`  // 响应中必须包含的内容
+	ForbiddenContent    string            `json:"forbiddenContent,omitempty"` // 响应中不允许出现的内容
+	ContentRegex        bool              `json:"contentRegex,omitempty"`     // 是否将内容断言作为正则表达式
+	Timeout             int               `json:"timeout"`
+	Headers             map[string]string `json:"headers,omitempty"`
+	Body                string            `json:"body,omitempty"`
+	// FollowRedirects 是否跟随重定向（最多 10 次），未设置时跟随；不跟随时以重定向响应的状态码判断
+	FollowRedirects *bool `json:"followRedirects,omitempty"`
+	// VerifyTLS 是否校验 HTTPS 证书，默认不校验以支持自签名证书
+	VerifyTLS bool `json:"verifyTLS,omitempty"`
+}
+
+const (
+	// DefaultHTTPMonitorTimeout HTTP 监控默认超时（秒）
+	DefaultHTTPMonitorTimeout = 60
+	// MaxHTTPMonitorBodySize HTTP 监控请求体的最大长度
+	MaxHTTPMonitorBodySize = 64 * 1024
+)
+
+// httpMonitorMethods HTTP 监控允许的请求方法
+var httpMonitorMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// Normalize 填充请求默认值（GET、超时、跟随重定向），保存后的配置即为实际发出的请求
+func (c *HTTPMonitorConfig) Normalize() {
+	c.Method = strings.ToUpper(strings.TrimSpace(c.Method))
+	if c.Method == "" {
+		c.Method = "GET"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultHTTPMonitorTimeout
+	}
+	if c.FollowRedirects == nil {
+		follow := true
+		c.FollowRedirects = &follow
+	}
+	if len(c.Headers) > 0 {
+		headers := make(map[string]string, len(c.Headers))
+		for key, value := range c.Headers {
+			if key = strings.TrimSpace(key); key != "" {
+				headers[key] = value
+			}
+		}
+		c.Headers = headers
+	}
+}
+
+// ShouldFollowRedirects 是否跟随重定向，未设置时跟随
+func (c *HTTPMonitorConfig) ShouldFollowRedirects() bool {
+	return c.FollowRedirects == nil || *c.FollowRedirects
+}
+
+// ValidateRequest 校验请求方法、请求头和请求体
+func (c *HTTPMonitorConfig) ValidateRequest() error {
+	method := strings.ToUpper(strings.TrimSpace(c.Method))
+	if method != "" && !slices.Contains(httpMonitorMethods, method) {
+		return fmt.Errorf("unsupported http method: %s", c.Method)
+	}
+	for key, value := range c.Headers {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if strings.ContainsAny(key, " \t\r\n:") {
+			return fmt.Errorf("invalid header name: %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s contains line breaks", key)
+		}
+	}
+	if len(c.Body) > MaxHTTPMonitorBodySize {
+		return fmt.Errorf("request body exceeds %d bytes", MaxHTTPMonitorBodySize)
+	}
+	return nil
+}
+
+// HasContentAssertion 是否配置了响应内容断言
+func (c *HTTPMonitorConfig) HasContentAssertion() bool {
+	return c.ExpectedContent != "" || c.ForbiddenContent != ""
+}
+
+// MatchStatusCode 检查状态码是否符合期望
+func (c *HTTPMonitorConfig) MatchStatusCode(code int) bool {
+	if strings.TrimSpace(c.ExpectedStatusCodes) == "" {
+		expected := c.ExpectedStatusCode
+		if expected == 0 {
+			expected = 200
+		}
+		return code == expected
+	}
+
+	for _, part := range strings.Split(c.ExpectedStatusCodes, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if lo, hi, ok := strings.Cut(part, "-"); ok {
+			min, err1 := strconv.Atoi(strings.TrimSpace(lo))
+			max, err2 := strconv.Atoi(strings.TrimSpace(hi))
+			if err1 == nil && err2 == nil && code >= min && code <= max {
+				return true
+			}
+			continue
+		}
+		if expected, err := strconv.Atoi(part); err == nil && code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedStatusText 期望状态码的描述
+func (c *HTTPMonitorConfig) ExpectedStatusText() string {
+	if strings.TrimSpace(c.ExpectedStatusCodes) != "" {
+		return c.ExpectedStatusCodes
+	}
+	if c.ExpectedStatusCode == 0 {
+		return "200"
+	}
+	return strconv.Itoa(c.ExpectedStatusCode)
+}
+
+// CheckContent 检查响应内容是否满足断言，不满足时返回原因
+func (c *HTTPMonitorConfig) CheckContent(body string) error {
+	if c.ExpectedContent != "" {
+		matched, err := c.matchContent(c.ExpectedContent, body)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("content does not contain expected string: %s", c.ExpectedContent)
+		}
+	}
+	if c.ForbiddenContent != "" {
+		matched, err := c.matchContent(c.ForbiddenContent, body)
+		if err != nil {
+			return err
+		}
+		if matched {
+			return fmt.Errorf("content contains forbidden string: %s", c.ForbiddenContent)
+		}
+	}
+	return nil
+}
+
+// ValidateAssertions 校验断言配置（正则表达式是否合法）
+func (c *HTTPMonitorConfig) ValidateAssertions() error {
+	if !c.ContentRegex {
+		return nil
+	}
+	for _, pattern := range []string{c.ExpectedContent, c.ForbiddenContent} {
+		if pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid content regex %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (c *HTTPMonitorConfig) matchContent(pattern, body string) (bool, error) {
+	if !c.ContentRegex {
+		return strings.Contains(body, pattern), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid content regex %q: %w", pattern, err)
+	}
+	return re.MatchString(body), nil
 }
 
 // TCPMonitorConfig TCP 监控配置
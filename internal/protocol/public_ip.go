@@ -6,8 +6,17 @@ type PublicIPConfigData struct {
 	IntervalSeconds int      `json:"intervalSeconds"` // 采集间隔（秒）
 	IPv4Enabled     bool     `json:"ipv4Enabled"`     // 是否采集 IPv4
 	IPv6Enabled     bool     `json:"ipv6Enabled"`     // 是否采集 IPv6
-	IPv4APIs        []string `json:"ipv4Apis"`        // IPv4 API 列表
-	IPv6APIs        []string `json:"ipv6Apis"`        // IPv6 API 列表
+	IPv4APIs        []string `json:"ipv4Apis"`        // IPv4 API 列表（兼容旧版客户端）
+	IPv6APIs        []string `json:"ipv6Apis"`        // IPv6 API 列表（兼容旧版客户端）
+	// 按尝试顺序排列、带超时的 API 列表，新版客户端优先使用
+	IPv4APIEntries []PublicIPAPIEntry `json:"ipv4ApiEntries,omitempty"`
+	IPv6APIEntries []PublicIPAPIEntry `json:"ipv6ApiEntries,omitempty"`
+}
+
+// PublicIPAPIEntry 公网 IP 查询 API 及其超时
+type PublicIPAPIEntry struct {
+	URL            string `json:"url"`            // API 地址
+	TimeoutSeconds int    `json:"timeoutSeconds"` // 单次请求超时（秒）
 }
 
 // PublicIPReportData 公网 IP 采集结果（客户端上报）
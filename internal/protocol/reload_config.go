@@ -0,0 +1,16 @@
+package protocol
+
+// ReloadConfigData 服务端主动下发的完整配置（服务端下发给客户端），探针无需重连即可按各项配置重新应用
+// 字段为空表示该项未下发，探针保持当前配置
+type ReloadConfigData struct {
+	PublicIP     *PublicIPConfigData    `json:"publicIp,omitempty"`     // 公网 IP 采集配置（含采集间隔），未启用或探针不在采集范围内时 enabled 为 false
+	SSHLogin     *SSHLoginConfig        `json:"sshLogin,omitempty"`     // SSH 登录监控配置
+	MetricPolicy *MetricPolicyData      `json:"metricPolicy,omitempty"` // 指标采集策略（允许/禁止列表）
+	CustomChecks *CustomCheckConfigData `json:"customChecks,omitempty"` // 自定义检查配置，整体替换探针已有的检查
+	Tamper       *TamperReloadConfig    `json:"tamper,omitempty"`       // 防篡改保护目录，整体替换探针当前保护的目录
+}
+
+// TamperReloadConfig 完整的防篡改保护目录列表，未启用时为空列表，探针停止保护所有目录
+type TamperReloadConfig struct {
+	Paths []string `json:"paths"`
+}
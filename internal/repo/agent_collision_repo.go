@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// AgentCollisionRepo 探针ID冲突记录数据访问层
+type AgentCollisionRepo struct {
+	orz.Repository[models.AgentCollision, string]
+}
+
+// NewAgentCollisionRepo 创建仓库
+func NewAgentCollisionRepo(db *gorm.DB) *AgentCollisionRepo {
+	return &AgentCollisionRepo{
+		Repository: orz.NewRepository[models.AgentCollision, string](db),
+	}
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// AgentConnectionEventRepo 探针连接事件数据访问层
+type AgentConnectionEventRepo struct {
+	orz.Repository[models.AgentConnectionEvent, string]
+}
+
+// NewAgentConnectionEventRepo 创建仓库
+func NewAgentConnectionEventRepo(db *gorm.DB) *AgentConnectionEventRepo {
+	return &AgentConnectionEventRepo{
+		Repository: orz.NewRepository[models.AgentConnectionEvent, string](db),
+	}
+}
+
+// FindByAgentIDAndTimeRange 按时间升序查询探针在时间范围内的连接事件
+func (r *AgentConnectionEventRepo) FindByAgentIDAndTimeRange(ctx context.Context, agentID string, start, end int64, limit int) ([]models.AgentConnectionEvent, error) {
+	var events []models.AgentConnectionEvent
+	err := r.GetDB(ctx).
+		Where("agent_id = ? AND timestamp >= ? AND timestamp <= ?", agentID, start, end).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	// 取最近的 limit 条后按时间升序返回
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+// FindLatestBefore 查询时间点之前的最后一个事件
+func (r *AgentConnectionEventRepo) FindLatestBefore(ctx context.Context, agentID string, before int64) (*models.AgentConnectionEvent, error) {
+	var event models.AgentConnectionEvent
+	err := r.GetDB(ctx).
+		Where("agent_id = ? AND timestamp < ?", agentID, before).
+		Order("timestamp DESC").
+		First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindByAgentID 按时间倒序查询探针最近的连接事件
+func (r *AgentConnectionEventRepo) FindByAgentID(ctx context.Context, agentID string, limit int) ([]models.AgentConnectionEvent, error) {
+	var events []models.AgentConnectionEvent
+	err := r.GetDB(ctx).
+		Where("agent_id = ?", agentID).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// DeleteEventsByAgentID 删除探针的所有连接事件
+func (r *AgentConnectionEventRepo) DeleteEventsByAgentID(ctx context.Context, agentID string) error {
+	return r.GetDB(ctx).Where("agent_id = ?", agentID).Delete(&models.AgentConnectionEvent{}).Error
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// AgentIPHistoryRepo 探针公网 IP 变更历史数据访问层
+type AgentIPHistoryRepo struct {
+	orz.Repository[models.AgentIPHistory, string]
+}
+
+// NewAgentIPHistoryRepo 创建仓库
+func NewAgentIPHistoryRepo(db *gorm.DB) *AgentIPHistoryRepo {
+	return &AgentIPHistoryRepo{
+		Repository: orz.NewRepository[models.AgentIPHistory, string](db),
+	}
+}
+
+// FindByAgentID 按时间倒序查询探针的 IP 变更历史
+func (r *AgentIPHistoryRepo) FindByAgentID(ctx context.Context, agentID string, limit int) ([]models.AgentIPHistory, error) {
+	var histories []models.AgentIPHistory
+	err := r.GetDB(ctx).
+		Where("agent_id = ?", agentID).
+		Order("changed_at DESC").
+		Limit(limit).
+		Find(&histories).Error
+	return histories, err
+}
+
+// DeleteByAgentID 删除探针的 IP 变更历史
+func (r *AgentIPHistoryRepo) DeleteByAgentID(ctx context.Context, agentID string) error {
+	return r.GetDB(ctx).Where("agent_id = ?", agentID).Delete(&models.AgentIPHistory{}).Error
+}
@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// AgentKeyRepo 探针注册公钥数据访问层
+type AgentKeyRepo struct {
+	orz.Repository[models.AgentKey, string]
+	db *gorm.DB
+}
+
+// NewAgentKeyRepo 创建仓库
+func NewAgentKeyRepo(db *gorm.DB) *AgentKeyRepo {
+	return &AgentKeyRepo{
+		Repository: orz.NewRepository[models.AgentKey, string](db),
+		db:         db,
+	}
+}
+
+// ListByStatus 按状态列出公钥，状态为空时列出全部
+func (r *AgentKeyRepo) ListByStatus(ctx context.Context, status string) ([]models.AgentKey, error) {
+	var keys []models.AgentKey
+	db := r.db.WithContext(ctx).Order("created_at desc")
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Find(&keys).Error
+	return keys, err
+}
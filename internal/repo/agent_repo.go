@@ -5,6 +5,7 @@ import (
 
 	"github.com/dushixiang/pika/internal/models"
 	"github.com/go-orz/orz"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -35,6 +36,29 @@ func (r *AgentRepo) UpdateStatus(ctx context.Context, agentID string, status int
 		Updates(m).Error
 }
 
+// UpdateHealth 更新探针状态和心跳上报的运行状况
+func (r *AgentRepo) UpdateHealth(ctx context.Context, agentID string, status int, lastSeenAt int64, health models.AgentHealthData) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Agent{}).
+		Where("id = ?", agentID).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"last_seen_at": lastSeenAt,
+			"health":       datatypes.NewJSONType(health),
+		}).Error
+}
+
+// UpdateClockSkew 更新探针测得的时钟偏差
+func (r *AgentRepo) UpdateClockSkew(ctx context.Context, agentID string, clockSkew int64, skewed bool) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Agent{}).
+		Where("id = ?", agentID).
+		Updates(map[string]interface{}{
+			"clock_skew":   clockSkew,
+			"clock_skewed": skewed,
+		}).Error
+}
+
 // FindOnlineAgents 查找所有在线探针
 func (r *AgentRepo) FindOnlineAgents(ctx context.Context) ([]models.Agent, error) {
 	var agents []models.Agent
@@ -111,17 +135,69 @@ func (r *AgentRepo) GetLatestAuditResultByType(ctx context.Context, agentID stri
 	return &audit, nil
 }
 
-// ListAuditResults 获取审计结果列表
-func (r *AgentRepo) ListAuditResults(ctx context.Context, agentID string) ([]models.AuditResult, error) {
-	var audits []models.AuditResult
+// FindAuditResult 获取探针的指定审计结果
+func (r *AgentRepo) FindAuditResult(ctx context.Context, agentID string, auditID int64) (*models.AuditResult, error) {
+	var audit models.AuditResult
 	err := r.db.WithContext(ctx).
+		Where("id = ? AND agent_id = ?", auditID, agentID).
+		First(&audit).Error
+	if err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// UpdateAuditAnalysis 更新审计结果的安全分析
+func (r *AgentRepo) UpdateAuditAnalysis(ctx context.Context, auditID int64, analysis string, riskScore int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.AuditResult{}).
+		Where("id = ?", auditID).
+		Updates(map[string]interface{}{
+			"analysis":   analysis,
+			"risk_score": riskScore,
+		}).Error
+}
+
+// ListAuditResults 分页获取审计结果列表，按创建时间排序
+func (r *AgentRepo) ListAuditResults(ctx context.Context, agentID string, pr *orz.PageRequest) ([]models.AuditResult, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AuditResult{}).Where("agent_id = ?", agentID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "created_at DESC"
+	if pr.SortOrder == orz.ASC {
+		order = "created_at ASC"
+	}
+	var audits []models.AuditResult
+	err := db.Order(order).
+		Offset((pr.PageIndex - 1) * pr.PageSize).
+		Limit(pr.PageSize).
+		Find(&audits).Error
+	return audits, total, err
+}
+
+// FindAuditResultsByAgentID 按创建时间倒序查询探针最近的审计结果
+func (r *AgentRepo) FindAuditResultsByAgentID(ctx context.Context, agentID string, limit int) ([]models.AuditResult, error) {
+	var audits []models.AuditResult
+	err := r.GetDB(ctx).
 		Where("agent_id = ?", agentID).
 		Order("created_at DESC").
-		Limit(50).
+		Limit(limit).
 		Find(&audits).Error
 	return audits, err
 }
 
+// CreateAuditResults 批量保存审计结果
+func (r *AgentRepo) CreateAuditResults(ctx context.Context, audits []models.AuditResult) error {
+	if len(audits) == 0 {
+		return nil
+	}
+	return r.GetDB(ctx).CreateInBatches(audits, 100).Error
+}
+
 // GetStatistics 获取探针统计数据
 func (r *AgentRepo) GetStatistics(ctx context.Context) (total int64, online int64, err error) {
 	// 获取总数
@@ -230,3 +306,22 @@ func (r *AgentRepo) FindAgentsWithTrafficReset(ctx context.Context) ([]models.Ag
 	}
 	return agents, nil
 }
+
+// MarkStaleOffline 将指定版本标识且最后上线时间早于 before 的在线探针标记为离线，返回更新的数量
+func (r *AgentRepo) MarkStaleOffline(ctx context.Context, version string, before int64) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Agent{}).
+		Where("version = ? AND status = ? AND last_seen_at < ?", version, 1, before).
+		Update("status", 0)
+	return result.RowsAffected, result.Error
+}
+
+// FindOfflineBefore 查找离线且最后上线时间早于 before 的探针
+func (r *AgentRepo) FindOfflineBefore(ctx context.Context, before int64) ([]models.Agent, error) {
+	var agents []models.Agent
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND last_seen_at < ? AND created_at < ?", 0, before, before).
+		Order("last_seen_at").
+		Find(&agents).Error
+	return agents, err
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type AlertCommentRepo struct {
+	orz.Repository[models.AlertComment, int64]
+	db *gorm.DB
+}
+
+func NewAlertCommentRepo(db *gorm.DB) *AlertCommentRepo {
+	return &AlertCommentRepo{
+		Repository: orz.NewRepository[models.AlertComment, int64](db),
+		db:         db,
+	}
+}
+
+// ListByAlertRecordID 按时间顺序列出告警记录的备注
+func (r *AlertCommentRepo) ListByAlertRecordID(ctx context.Context, alertRecordID int64) ([]models.AlertComment, error) {
+	var comments []models.AlertComment
+	err := r.db.WithContext(ctx).
+		Where("alert_record_id = ?", alertRecordID).
+		Order("id").
+		Find(&comments).Error
+	return comments, err
+}
+
+// ListByAlertRecordIDs 按时间顺序列出多条告警记录的备注
+func (r *AlertCommentRepo) ListByAlertRecordIDs(ctx context.Context, alertRecordIDs []int64) ([]models.AlertComment, error) {
+	var comments []models.AlertComment
+	if len(alertRecordIDs) == 0 {
+		return comments, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("alert_record_id IN ?", alertRecordIDs).
+		Order("id").
+		Find(&comments).Error
+	return comments, err
+}
+
+// CountByAlertRecordIDs 统计每条告警记录的备注数量，没有备注的记录不在结果中
+func (r *AlertCommentRepo) CountByAlertRecordIDs(ctx context.Context, alertRecordIDs []int64) (map[int64]int64, error) {
+	counts := make(map[int64]int64)
+	if len(alertRecordIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		AlertRecordID int64
+		Count         int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.AlertComment{}).
+		Select("alert_record_id, COUNT(*) AS count").
+		Where("alert_record_id IN ?", alertRecordIDs).
+		Group("alert_record_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.AlertRecordID] = row.Count
+	}
+	return counts, nil
+}
+
+// DeleteByAlertRecordIDs 删除告警记录的备注
+func (r *AlertCommentRepo) DeleteByAlertRecordIDs(ctx context.Context, alertRecordIDs []int64) error {
+	return r.db.WithContext(ctx).
+		Where("alert_record_id IN ?", alertRecordIDs).
+		Delete(&models.AlertComment{}).Error
+}
+
+func (r *AlertCommentRepo) Clear(ctx context.Context) error {
+	return r.GetDB(ctx).Where("1=1").Delete(&models.AlertComment{}).Error
+}
@@ -2,6 +2,8 @@ package repo
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/dushixiang/pika/internal/models"
 	"github.com/go-orz/orz"
@@ -55,6 +57,152 @@ func (r *AlertRecordRepo) GetLatestAlertRecord(ctx context.Context, configID str
 	return &record, nil
 }
 
+// FindFiringIDs 按探针和告警类型查询告警中的记录ID，空值表示不过滤
+func (r *AlertRecordRepo) FindFiringIDs(ctx context.Context, agentID, alertType string) ([]int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AlertRecord{}).Where("status = ?", "firing")
+	if agentID != "" {
+		db = db.Where("agent_id = ?", agentID)
+	}
+	if alertType != "" {
+		db = db.Where("alert_type = ?", alertType)
+	}
+	var ids []int64
+	err := db.Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ResolveFiring 将指定的告警中记录标记为已恢复，返回更新的数量
+func (r *AlertRecordRepo) ResolveFiring(ctx context.Context, ids []int64, resolvedAt int64, resolvedBy string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.GetDB(ctx).Model(&models.AlertRecord{}).
+		Where("id IN ? AND status = ?", ids, "firing").
+		UpdateColumns(map[string]interface{}{
+			"status":      "resolved",
+			"resolved_at": resolvedAt,
+			"resolved_by": resolvedBy,
+			"updated_at":  resolvedAt,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// FindUnacked 查询触发时间不晚于 firedBefore、未确认且未被抑制的告警中记录
+func (r *AlertRecordRepo) FindUnacked(ctx context.Context, firedBefore int64) ([]models.AlertRecord, error) {
+	var records []models.AlertRecord
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND COALESCE(acked_at, 0) = 0 AND COALESCE(suppressed, ?) = ? AND fired_at <= ?", "firing", false, false, firedBefore).
+		Order("id").
+		Find(&records).Error
+	return records, err
+}
+
+// MarkEscalated 记录未确认告警的升级通知，告警已确认或已恢复时不更新，返回是否更新成功
+func (r *AlertRecordRepo) MarkEscalated(ctx context.Context, id int64, escalatedAt int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.AlertRecord{}).
+		Where("id = ? AND status = ? AND COALESCE(acked_at, 0) = 0", id, "firing").
+		UpdateColumns(map[string]interface{}{
+			"escalated_at":     escalatedAt,
+			"escalation_count": gorm.Expr("COALESCE(escalation_count, 0) + 1"),
+			"updated_at":       escalatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindByAgentID 按触发时间倒序查询探针最近的告警记录
+func (r *AlertRecordRepo) FindByAgentID(ctx context.Context, agentID string, limit int) ([]models.AlertRecord, error) {
+	var records []models.AlertRecord
+	err := r.GetDB(ctx).
+		Where("agent_id = ?", agentID).
+		Order("fired_at DESC").
+		Limit(limit).
+		Find(&records).Error
+	return records, err
+}
+
+// FindArchivable 查询恢复时间早于 before 且尚未归档的告警记录
+func (r *AlertRecordRepo) FindArchivable(ctx context.Context, before int64, limit int) ([]models.AlertRecord, error) {
+	var records []models.AlertRecord
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND resolved_at > 0 AND resolved_at < ? AND COALESCE(archived_at, 0) = 0", "resolved", before).
+		Order("id").
+		Limit(limit).
+		Find(&records).Error
+	return records, err
+}
+
+// MarkArchived 标记告警记录已归档
+func (r *AlertRecordRepo) MarkArchived(ctx context.Context, ids []int64, archivedAt int64) error {
+	return r.db.WithContext(ctx).Model(&models.AlertRecord{}).
+		Where("id IN ?", ids).
+		Update("archived_at", archivedAt).Error
+}
+
+// DeleteArchived 删除已归档的告警记录
+func (r *AlertRecordRepo) DeleteArchived(ctx context.Context, ids []int64) error {
+	return r.db.WithContext(ctx).
+		Where("id IN ? AND archived_at > 0", ids).
+		Delete(&models.AlertRecord{}).Error
+}
+
+// AlertRecordQuery 告警记录查询条件，空值表示不过滤
+type AlertRecordQuery struct {
+	AgentID   string
+	AlertType string
+	Level     string
+	Status    string
+	Keyword   string // 告警消息关键字（不区分大小写）
+	StartTime int64  // 触发时间起始（时间戳毫秒，包含）
+	EndTime   int64  // 触发时间结束（时间戳毫秒，包含）
+}
+
+// FindAlertRecordPage 按条件分页查询告警记录
+func (r *AlertRecordRepo) FindAlertRecordPage(ctx context.Context, query AlertRecordQuery, pr *orz.PageRequest) (*orz.PageResult[models.AlertRecord], error) {
+	sort := orz.NewSort(pr.SortOrder, pr.SortField, pr.SortAllowedFields...)
+	if err := sort.Validate(); err != nil {
+		return nil, fmt.Errorf("sort validation failed: %w", err)
+	}
+
+	db := r.GetDB(ctx).Model(&models.AlertRecord{})
+	if query.AgentID != "" {
+		db = db.Where("agent_id = ?", query.AgentID)
+	}
+	if query.AlertType != "" {
+		db = db.Where("alert_type = ?", query.AlertType)
+	}
+	if query.Level != "" {
+		db = db.Where("level = ?", query.Level)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Keyword != "" {
+		db = db.Where("LOWER(message) LIKE ?", "%"+strings.ToLower(query.Keyword)+"%")
+	}
+	if query.StartTime > 0 {
+		db = db.Where("fired_at >= ?", query.StartTime)
+	}
+	if query.EndTime > 0 {
+		db = db.Where("fired_at <= ?", query.EndTime)
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if !sort.IsEmpty() {
+		db = db.Order(fmt.Sprintf("%s %s", sort.Field, sort.Order))
+	}
+
+	var items []models.AlertRecord
+	if err := db.Offset((pr.PageIndex - 1) * pr.PageSize).Limit(pr.PageSize).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return orz.NewPageResult(items, total), nil
+}
+
 func (r *AlertRecordRepo) Clear(ctx context.Context) error {
 	return r.db.WithContext(ctx).Where("1=1").Delete(&models.AlertRecord{}).Error
 }
@@ -5,16 +5,19 @@ import (
 	"time"
 
 	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
 	"gorm.io/gorm"
 )
 
 type AlertStateRepo struct {
+	orz.Repository[models.AlertState, string]
 	db *gorm.DB
 }
 
 func NewAlertStateRepo(db *gorm.DB) *AlertStateRepo {
 	return &AlertStateRepo{
-		db: db,
+		Repository: orz.NewRepository[models.AlertState, string](db),
+		db:         db,
 	}
 }
 
@@ -36,6 +39,16 @@ func (r *AlertStateRepo) SaveAlertState(ctx context.Context, state *models.Alert
 	return r.db.WithContext(ctx).Save(state).Error
 }
 
+// FindByLastRecordID 根据最后一条告警记录ID获取告警状态
+func (r *AlertStateRepo) FindByLastRecordID(ctx context.Context, recordID int64) (*models.AlertState, error) {
+	var state models.AlertState
+	err := r.db.WithContext(ctx).Where("last_record_id = ?", recordID).First(&state).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
 // DeleteAlertState 删除告警状态
 func (r *AlertStateRepo) DeleteAlertState(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&models.AlertState{}, "id = ?", id).Error
@@ -61,6 +74,38 @@ func (r *AlertStateRepo) CleanupOldStates(ctx context.Context) error {
 		Delete(&models.AlertState{}).Error
 }
 
+// filterStates 按探针和告警类型过滤告警状态，条件为空时不过滤
+func (r *AlertStateRepo) filterStates(ctx context.Context, agentID, alertType string) *gorm.DB {
+	db := r.GetDB(ctx).Model(&models.AlertState{}).Where("1=1")
+	if agentID != "" {
+		db = db.Where("agent_id = ?", agentID)
+	}
+	if alertType != "" {
+		db = db.Where("alert_type = ?", alertType)
+	}
+	return db
+}
+
+// FindLinkedRecordIDs 按探针和告警类型查询告警状态关联的告警记录ID，条件为空时不过滤
+func (r *AlertStateRepo) FindLinkedRecordIDs(ctx context.Context, agentID, alertType string) ([]int64, error) {
+	var ids []int64
+	err := r.filterStates(ctx, agentID, alertType).Where("last_record_id > 0").Pluck("last_record_id", &ids).Error
+	return ids, err
+}
+
+// DeleteStates 按探针和告警类型删除告警状态，条件为空时不过滤，返回删除的数量
+func (r *AlertStateRepo) DeleteStates(ctx context.Context, agentID, alertType string) (int64, error) {
+	result := r.filterStates(ctx, agentID, alertType).Delete(&models.AlertState{})
+	return result.RowsAffected, result.Error
+}
+
 func (r *AlertStateRepo) Clear(ctx context.Context) error {
 	return r.db.WithContext(ctx).Where("1=1").Delete(&models.AlertState{}).Error
 }
+
+// FindByAgentID 获取探针的所有告警状态
+func (r *AlertStateRepo) FindByAgentID(ctx context.Context, agentID string) ([]models.AlertState, error) {
+	var states []models.AlertState
+	err := r.db.WithContext(ctx).Where("agent_id = ?", agentID).Order("id").Find(&states).Error
+	return states, err
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type AnnotationRepo struct {
+	orz.Repository[models.Annotation, string]
+	db *gorm.DB
+}
+
+func NewAnnotationRepo(db *gorm.DB) *AnnotationRepo {
+	return &AnnotationRepo{
+		Repository: orz.NewRepository[models.Annotation, string](db),
+		db:         db,
+	}
+}
+
+// FindOverlapping 查询与时间窗口有重叠的注释，按时间升序
+// agentID 不为空时返回该探针的注释和全局注释，为空时返回所有注释
+func (r *AnnotationRepo) FindOverlapping(ctx context.Context, agentID string, start, end int64) ([]models.Annotation, error) {
+	var annotations []models.Annotation
+	query := r.db.WithContext(ctx).
+		Where("time <= ?", end).
+		Where("(end_time = 0 AND time >= ?) OR end_time >= ?", start, start)
+	if agentID != "" {
+		query = query.Where("agent_id = ? OR agent_id = ''", agentID)
+	}
+	err := query.Order("time ASC").Find(&annotations).Error
+	return annotations, err
+}
+
+// FindByAgentID 查询探针自身的注释（不含全局注释），按时间升序
+func (r *AnnotationRepo) FindByAgentID(ctx context.Context, agentID string) ([]models.Annotation, error) {
+	var annotations []models.Annotation
+	err := r.GetDB(ctx).Where("agent_id = ?", agentID).Order("time ASC").Find(&annotations).Error
+	return annotations, err
+}
+
+// DeleteByAgentID 删除探针的注释
+func (r *AnnotationRepo) DeleteByAgentID(ctx context.Context, agentID string) error {
+	return r.db.WithContext(ctx).Where("agent_id = ?", agentID).Delete(&models.Annotation{}).Error
+}
@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// ConfigAuditLogRepo 配置审计日志数据访问层
+type ConfigAuditLogRepo struct {
+	orz.Repository[models.ConfigAuditLog, string]
+}
+
+// NewConfigAuditLogRepo 创建仓库
+func NewConfigAuditLogRepo(db *gorm.DB) *ConfigAuditLogRepo {
+	return &ConfigAuditLogRepo{
+		Repository: orz.NewRepository[models.ConfigAuditLog, string](db),
+	}
+}
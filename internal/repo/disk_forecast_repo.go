@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// DiskForecastRepo 磁盘将满预测数据访问层
+type DiskForecastRepo struct {
+	orz.Repository[models.DiskForecast, string]
+}
+
+// NewDiskForecastRepo 创建仓库
+func NewDiskForecastRepo(db *gorm.DB) *DiskForecastRepo {
+	return &DiskForecastRepo{
+		Repository: orz.NewRepository[models.DiskForecast, string](db),
+	}
+}
+
+// SaveForecast 保存预测结果（存在则更新）
+func (r *DiskForecastRepo) SaveForecast(ctx context.Context, forecast *models.DiskForecast) error {
+	return r.GetDB(ctx).Save(forecast).Error
+}
+
+// FindByAgentID 获取探针所有挂载点的预测结果
+func (r *DiskForecastRepo) FindByAgentID(ctx context.Context, agentID string) ([]models.DiskForecast, error) {
+	var forecasts []models.DiskForecast
+	err := r.GetDB(ctx).
+		Where("agent_id = ?", agentID).
+		Order("mount_point ASC").
+		Find(&forecasts).Error
+	return forecasts, err
+}
+
+// DeleteByAgentID 删除探针的预测结果
+func (r *DiskForecastRepo) DeleteByAgentID(ctx context.Context, agentID string) error {
+	return r.GetDB(ctx).Where("agent_id = ?", agentID).Delete(&models.DiskForecast{}).Error
+}
@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// LoginAuditLogRepo 登录审计日志数据访问层
+type LoginAuditLogRepo struct {
+	orz.Repository[models.LoginAuditLog, string]
+}
+
+// NewLoginAuditLogRepo 创建仓库
+func NewLoginAuditLogRepo(db *gorm.DB) *LoginAuditLogRepo {
+	return &LoginAuditLogRepo{
+		Repository: orz.NewRepository[models.LoginAuditLog, string](db),
+	}
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+type ShareTokenRepo struct {
+	orz.Repository[models.ShareToken, string]
+	db *gorm.DB
+}
+
+func NewShareTokenRepo(db *gorm.DB) *ShareTokenRepo {
+	return &ShareTokenRepo{
+		Repository: orz.NewRepository[models.ShareToken, string](db),
+		db:         db,
+	}
+}
+
+// ListAll 列出所有分享令牌，按创建时间倒序
+func (r *ShareTokenRepo) ListAll(ctx context.Context) ([]models.ShareToken, error) {
+	var tokens []models.ShareToken
+	err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// FindRevokedUnexpired 查找已吊销但尚未过期的令牌（用于加载吊销列表）
+func (r *ShareTokenRepo) FindRevokedUnexpired(ctx context.Context, now int64) ([]models.ShareToken, error) {
+	var tokens []models.ShareToken
+	err := r.db.WithContext(ctx).
+		Where("revoked_at > 0 AND expires_at > ?", now).
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// UpdateRevokedAt 更新令牌的吊销时间
+func (r *ShareTokenRepo) UpdateRevokedAt(ctx context.Context, id string, revokedAt int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ShareToken{}).
+		Where("id = ?", id).
+		Update("revoked_at", revokedAt).Error
+}
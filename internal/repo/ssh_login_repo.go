@@ -24,3 +24,14 @@ func NewSSHLoginEventRepo(db *gorm.DB) *SSHLoginEventRepo {
 func (r *SSHLoginEventRepo) DeleteEventsByAgentID(ctx context.Context, agentID string) error {
 	return r.GetDB(ctx).Where("agent_id = ?", agentID).Delete(&models.SSHLoginEvent{}).Error
 }
+
+// FindByAgentID 按时间倒序查询探针最近的登录事件
+func (r *SSHLoginEventRepo) FindByAgentID(ctx context.Context, agentID string, limit int) ([]models.SSHLoginEvent, error) {
+	var events []models.SSHLoginEvent
+	err := r.GetDB(ctx).
+		Where("agent_id = ?", agentID).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// StorageStatsRepo 数据库容量统计数据访问层，按数据库类型选择统计方式
+type StorageStatsRepo struct {
+	db *gorm.DB
+}
+
+// NewStorageStatsRepo 创建仓库
+func NewStorageStatsRepo(db *gorm.DB) *StorageStatsRepo {
+	return &StorageStatsRepo{db: db}
+}
+
+// Dialect 获取数据库类型
+func (r *StorageStatsRepo) Dialect() string {
+	return r.db.Dialector.Name()
+}
+
+// Tables 列出数据库中的全部数据表（按名称排序，不含 SQLite 内部表）
+func (r *StorageStatsRepo) Tables(ctx context.Context) ([]string, error) {
+	tables, err := r.db.WithContext(ctx).Migrator().GetTables()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+		result = append(result, table)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// HasColumn 判断表中是否存在指定字段
+func (r *StorageStatsRepo) HasColumn(ctx context.Context, table, column string) bool {
+	return r.db.WithContext(ctx).Migrator().HasColumn(table, column)
+}
+
+// CountRows 统计表行数
+func (r *StorageStatsRepo) CountRows(ctx context.Context, table string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table(table).Count(&count).Error
+	return count, err
+}
+
+// TimeRange 统计表中时间字段的最早和最新值
+func (r *StorageStatsRepo) TimeRange(ctx context.Context, table, column string) (int64, int64, error) {
+	var result struct {
+		Oldest sql.NullInt64
+		Newest sql.NullInt64
+	}
+	err := r.db.WithContext(ctx).Table(table).
+		Select("MIN(" + column + ") AS oldest, MAX(" + column + ") AS newest").
+		Scan(&result).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.Oldest.Int64, result.Newest.Int64, nil
+}
+
+// TableSize 获取表占用空间（字节），驱动不支持时返回 -1
+func (r *StorageStatsRepo) TableSize(ctx context.Context, table string) (int64, error) {
+	var size sql.NullInt64
+	switch r.Dialect() {
+	case "postgres":
+		// 包含索引和 TOAST 数据
+		if err := r.db.WithContext(ctx).Raw("SELECT pg_total_relation_size(?::regclass)", table).Scan(&size).Error; err != nil {
+			return -1, err
+		}
+	case "sqlite":
+		// dbstat 虚拟表需要编译时启用，未启用时视为不支持
+		if err := r.db.WithContext(ctx).Raw("SELECT SUM(pgsize) FROM dbstat WHERE name = ?", table).Scan(&size).Error; err != nil {
+			return -1, nil
+		}
+	default:
+		return -1, nil
+	}
+	return size.Int64, nil
+}
+
+// DatabaseSize 获取数据库总占用空间（字节），驱动不支持时返回 -1
+func (r *StorageStatsRepo) DatabaseSize(ctx context.Context) (int64, error) {
+	db := r.db.WithContext(ctx)
+	switch r.Dialect() {
+	case "postgres":
+		var size int64
+		if err := db.Raw("SELECT pg_database_size(current_database())").Scan(&size).Error; err != nil {
+			return -1, err
+		}
+		return size, nil
+	case "sqlite":
+		var pageCount, pageSize int64
+		if err := db.Raw("PRAGMA page_count").Scan(&pageCount).Error; err != nil {
+			return -1, err
+		}
+		if err := db.Raw("PRAGMA page_size").Scan(&pageSize).Error; err != nil {
+			return -1, err
+		}
+		return pageCount * pageSize, nil
+	default:
+		return -1, nil
+	}
+}
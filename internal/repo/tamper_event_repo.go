@@ -22,3 +22,14 @@ func NewTamperEventRepo(db *gorm.DB) *TamperEventRepo {
 func (r *TamperEventRepo) DeleteEventsByAgentID(ctx context.Context, agentID string) error {
 	return r.GetDB(ctx).Where("agent_id = ?", agentID).Delete(&models.TamperEvent{}).Error
 }
+
+// FindByAgentID 按时间倒序查询探针最近的防篡改事件
+func (r *TamperEventRepo) FindByAgentID(ctx context.Context, agentID string, limit int) ([]models.TamperEvent, error) {
+	var events []models.TamperEvent
+	err := r.GetDB(ctx).
+		Where("agent_id = ?", agentID).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// UserCredentialRepo 用户密码数据访问层
+type UserCredentialRepo struct {
+	orz.Repository[models.UserCredential, string]
+}
+
+// NewUserCredentialRepo 创建仓库
+func NewUserCredentialRepo(db *gorm.DB) *UserCredentialRepo {
+	return &UserCredentialRepo{
+		Repository: orz.NewRepository[models.UserCredential, string](db),
+	}
+}
+
+// FindByUsername 根据用户名查找，不存在时返回 nil
+func (r *UserCredentialRepo) FindByUsername(ctx context.Context, username string) (*models.UserCredential, error) {
+	var credential models.UserCredential
+	err := r.GetDB(ctx).Where("username = ?", username).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// SaveCredential 保存用户密码（存在则更新）
+func (r *UserCredentialRepo) SaveCredential(ctx context.Context, credential *models.UserCredential) error {
+	return r.GetDB(ctx).Save(credential).Error
+}
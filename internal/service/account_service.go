@@ -5,12 +5,14 @@ import (
 	"time"
 
 	"github.com/dushixiang/pika/internal/config"
+	"github.com/dushixiang/pika/internal/repo"
 	"github.com/go-errors/errors"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-func NewAccountService(logger *zap.Logger, userService *UserService, oidcService *OIDCService, githubService *GitHubOAuthService, appConfig *config.AppConfig) *AccountService {
+func NewAccountService(logger *zap.Logger, db *gorm.DB, userService *UserService, oidcService *OIDCService, githubService *GitHubOAuthService, geoIPService *GeoIPService, appConfig *config.AppConfig) *AccountService {
 	jwtSecret := appConfig.JWT.Secret
 	tokenExpireHours := appConfig.JWT.ExpiresHours
 
@@ -29,8 +31,12 @@ func NewAccountService(logger *zap.Logger, userService *UserService, oidcService
 		userService:      userService,
 		oidcService:      oidcService,
 		githubService:    githubService,
+		geoIPService:     geoIPService,
 		jwtSecret:        jwtSecret,
 		tokenExpireHours: tokenExpireHours,
+		loginRegion:      newLoginRegionPolicy(logger, appConfig.LoginRegion, geoIPService),
+
+		LoginAuditLogRepo: repo.NewLoginAuditLogRepo(db),
 	}
 	return service
 }
@@ -40,20 +46,45 @@ type AccountService struct {
 	userService      *UserService
 	oidcService      *OIDCService
 	githubService    *GitHubOAuthService
+	geoIPService     *GeoIPService
 	jwtSecret        string
 	tokenExpireHours int
+	loginRegion      *loginRegionPolicy
+
+	LoginAuditLogRepo *repo.LoginAuditLogRepo
 }
 
+var (
+	ErrOIDCDisabled   = errors.New("OIDC 未启用")
+	ErrGitHubDisabled = errors.New("GitHub OAuth 未启用")
+)
+
+// 用户角色
+const (
+	RoleAdmin  = "admin"  // 管理员，可读写
+	RoleViewer = "viewer" // 只读
+)
+
 // JWTClaims JWT 声明
 type JWTClaims struct {
 	UserID   string `json:"userId"`
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// EffectiveRole 获取有效角色，旧 token 未携带角色时视为管理员
+func (c *JWTClaims) EffectiveRole() string {
+	if c.Role == "" {
+		return RoleAdmin
+	}
+	return c.Role
+}
+
 // UserInfo 用户信息（简化版）
 type UserInfo struct {
 	Username string `json:"username"`
+	Role     string `json:"role"`
 }
 
 // LoginResponse 登录响应
@@ -64,14 +95,19 @@ type LoginResponse struct {
 }
 
 // Login 用户登录（Basic Auth）
-func (s *AccountService) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+func (s *AccountService) Login(ctx context.Context, username, password, clientIP string) (*LoginResponse, error) {
 	// 使用 Basic Auth 验证
 	if err := s.userService.ValidateCredentials(ctx, username, password); err != nil {
 		return nil, err
 	}
 
+	// 检查登录地区
+	if err := s.checkLoginRegion(ctx, username, LoginMethodPassword, clientIP); err != nil {
+		return nil, err
+	}
+
 	// 生成 JWT token
-	token, expiresAt, err := s.generateToken(username, username)
+	token, expiresAt, err := s.generateToken(username, username, RoleAdmin)
 	if err != nil {
 		return nil, err
 	}
@@ -83,41 +119,50 @@ func (s *AccountService) Login(ctx context.Context, username, password string) (
 		ExpiresAt: expiresAt,
 		User: &UserInfo{
 			Username: username,
+			Role:     RoleAdmin,
 		},
 	}, nil
 }
 
 // LoginWithOIDC OIDC 登录
-func (s *AccountService) LoginWithOIDC(ctx context.Context, code, state string) (*LoginResponse, error) {
+func (s *AccountService) LoginWithOIDC(ctx context.Context, code, state, clientIP string) (*LoginResponse, error) {
 	// 使用 OIDC 验证
-	username, nickname, err := s.oidcService.ExchangeCode(ctx, code, state)
+	identity, err := s.oidcService.ExchangeCode(ctx, code, state)
 	if err != nil {
 		return nil, err
 	}
+	username := identity.Username
+
+	// 检查登录地区
+	if err := s.checkLoginRegion(ctx, username, LoginMethodOIDC, clientIP); err != nil {
+		return nil, err
+	}
 
 	// 生成 JWT token
-	token, expiresAt, err := s.generateToken(username, nickname)
+	token, expiresAt, err := s.generateToken(username, identity.Nickname, identity.Role)
 	if err != nil {
 		return nil, err
 	}
 
-	s.logger.Info("OIDC 登录成功", zap.String("username", username))
+	s.logger.Info("OIDC 登录成功", zap.String("username", username), zap.String("role", identity.Role))
 
 	return &LoginResponse{
 		Token:     token,
 		ExpiresAt: expiresAt,
 		User: &UserInfo{
 			Username: username,
+			Role:     identity.Role,
 		},
 	}, nil
 }
 
 // generateToken 生成 JWT token
-func (s *AccountService) generateToken(username, nickname string) (string, int64, error) {
+func (s *AccountService) generateToken(username, nickname, role string) (string, int64, error) {
 	expiresAt := time.Now().Add(time.Duration(s.tokenExpireHours) * time.Hour)
 	claims := &JWTClaims{
 		UserID:   username, // 使用 username 作为 userID
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -144,6 +189,11 @@ func (s *AccountService) Logout(ctx context.Context, userID string) error {
 	return nil
 }
 
+// ChangePassword 修改当前用户密码（仅限用户名密码登录的用户）
+func (s *AccountService) ChangePassword(ctx context.Context, username, oldPassword, newPassword string) error {
+	return s.userService.ChangePassword(ctx, username, oldPassword, newPassword)
+}
+
 // ValidateToken 验证 JWT token
 func (s *AccountService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
@@ -190,7 +240,7 @@ type OIDCAuthURL struct {
 // GetOIDCAuthURL 获取 OIDC 认证 URL
 func (s *AccountService) GetOIDCAuthURL() (*OIDCAuthURL, error) {
 	if !s.oidcService.IsEnabled() {
-		return nil, errors.New("OIDC 未启用")
+		return nil, ErrOIDCDisabled
 	}
 
 	authURL, state, err := s.oidcService.GenerateAuthURL()
@@ -213,7 +263,7 @@ type GitHubAuthURL struct {
 // GetGitHubAuthURL 获取 GitHub 认证 URL
 func (s *AccountService) GetGitHubAuthURL() (*GitHubAuthURL, error) {
 	if !s.githubService.IsEnabled() {
-		return nil, errors.New("GitHub OAuth 未启用")
+		return nil, ErrGitHubDisabled
 	}
 
 	authURL, state, err := s.githubService.GenerateAuthURL()
@@ -228,15 +278,20 @@ func (s *AccountService) GetGitHubAuthURL() (*GitHubAuthURL, error) {
 }
 
 // LoginWithGitHub GitHub 登录
-func (s *AccountService) LoginWithGitHub(ctx context.Context, code, state string) (*LoginResponse, error) {
+func (s *AccountService) LoginWithGitHub(ctx context.Context, code, state, clientIP string) (*LoginResponse, error) {
 	// 使用 GitHub OAuth 验证
 	username, nickname, err := s.githubService.ExchangeCode(ctx, code, state)
 	if err != nil {
 		return nil, err
 	}
 
+	// 检查登录地区
+	if err := s.checkLoginRegion(ctx, username, LoginMethodGitHub, clientIP); err != nil {
+		return nil, err
+	}
+
 	// 生成 JWT token
-	token, expiresAt, err := s.generateToken(username, nickname)
+	token, expiresAt, err := s.generateToken(username, nickname, RoleAdmin)
 	if err != nil {
 		return nil, err
 	}
@@ -248,6 +303,7 @@ func (s *AccountService) LoginWithGitHub(ctx context.Context, code, state string
 		ExpiresAt: expiresAt,
 		User: &UserInfo{
 			Username: username,
+			Role:     RoleAdmin,
 		},
 	}, nil
 }
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+const (
+	// maxAgentAttributes 单个探针最多的属性数量
+	maxAgentAttributes = 32
+	// maxAttributeKeyLength 属性名最大长度
+	maxAttributeKeyLength = 64
+	// maxAttributeValueLength 属性值最大长度
+	maxAttributeValueLength = 256
+)
+
+// NormalizeAttributes 规范化属性：去除首尾空白，校验数量和长度
+// keepEmpty 为 true 时保留值为空的属性（运维设置中表示删除探针上报的同名属性）
+func NormalizeAttributes(attributes map[string]string, keepEmpty bool) (map[string]string, error) {
+	result := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		k = strings.TrimSpace(k)
+		v = strings.TrimSpace(v)
+		if k == "" || (v == "" && !keepEmpty) {
+			continue
+		}
+		if len(k) > maxAttributeKeyLength {
+			return nil, fmt.Errorf("属性名 %s 超过 %d 个字符", k, maxAttributeKeyLength)
+		}
+		if len(v) > maxAttributeValueLength {
+			return nil, fmt.Errorf("属性 %s 的值超过 %d 个字符", k, maxAttributeValueLength)
+		}
+		result[k] = v
+	}
+	if len(result) > maxAgentAttributes {
+		return nil, fmt.Errorf("属性数量不能超过 %d 个", maxAgentAttributes)
+	}
+	return result, nil
+}
+
+// ParseAttributeSelector 解析 key=value 格式的属性条件，多个条件需同时满足
+func ParseAttributeSelector(values []string) (map[string]string, error) {
+	selector := make(map[string]string, len(values))
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(item, "=")
+			k = strings.TrimSpace(k)
+			if !ok || k == "" {
+				return nil, fmt.Errorf("无效的属性条件: %s，格式应为 key=value", item)
+			}
+			selector[k] = strings.TrimSpace(v)
+		}
+	}
+	return selector, nil
+}
+
+// FilterAgentsByAttributes 按属性条件筛选探针
+func FilterAgentsByAttributes(agents []models.Agent, selector map[string]string) []models.Agent {
+	if len(selector) == 0 {
+		return agents
+	}
+	result := make([]models.Agent, 0, len(agents))
+	for _, agent := range agents {
+		if agent.MatchAttributes(selector) {
+			result = append(result, agent)
+		}
+	}
+	return result
+}
+
+// GetAttributeValues 获取所有探针使用中的属性名及取值（用于筛选下拉框）
+func (s *AgentService) GetAttributeValues(ctx context.Context) (map[string][]string, error) {
+	agents, err := s.AgentRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]map[string]struct{})
+	for _, agent := range agents {
+		for k, v := range agent.EffectiveAttributes() {
+			if values[k] == nil {
+				values[k] = make(map[string]struct{})
+			}
+			values[k][v] = struct{}{}
+		}
+	}
+	result := make(map[string][]string, len(values))
+	for k, set := range values {
+		list := make([]string, 0, len(set))
+		for v := range set {
+			list = append(list, v)
+		}
+		sort.Strings(list)
+		result[k] = list
+	}
+	return result, nil
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// AgentBundleVersion 探针导出包格式版本
+	AgentBundleVersion = 1
+	// DefaultAgentBundleMetricDays 默认导出最近几天的原始指标
+	DefaultAgentBundleMetricDays = 7
+	// MaxAgentBundleMetricDays 最多导出最近几天的原始指标
+	MaxAgentBundleMetricDays = 30
+
+	// agentBundleMaxRows 每类关联数据最多导出的条数（取最近的记录）
+	agentBundleMaxRows = 10000
+	// agentBundleMetricBatch 导入指标时每批写入的序列数
+	agentBundleMetricBatch = 100
+
+	agentBundleManifestFile = "manifest.json"
+	agentBundleDataFile     = "data.json"
+	agentBundleMetricsFile  = "metrics.jsonl"
+)
+
+var (
+	ErrAgentBundleInvalid = errors.New("探针导出包格式错误")
+	ErrAgentBundleVersion = errors.New("不支持的探针导出包版本")
+	ErrAgentIDExists      = errors.New("探针ID已存在，请指定新的探针ID")
+	ErrInvalidAgentID     = errors.New("无效的探针ID")
+)
+
+// AgentBundleManifest 导出包的描述信息
+type AgentBundleManifest struct {
+	Version      int    `json:"version"`
+	AgentID      string `json:"agentId"`
+	AgentName    string `json:"agentName"`
+	ExportedAt   int64  `json:"exportedAt"`   // 导出时间（时间戳毫秒）
+	MetricsStart int64  `json:"metricsStart"` // 导出指标的开始时间（时间戳毫秒）
+	MetricsEnd   int64  `json:"metricsEnd"`   // 导出指标的结束时间（时间戳毫秒）
+}
+
+// AgentBundleData 导出包中的探针记录（含标签、属性和各项配置）及关联数据
+// 与 DeleteAgent 删除的数据对应；磁盘预测结果可重新计算，注册公钥与原主机绑定，均不导出
+type AgentBundleData struct {
+	Agent            models.Agent                  `json:"agent"`
+	MetricPolicy     *protocol.MetricPolicyData    `json:"metricPolicy,omitempty"`
+	AuditResults     []models.AuditResult          `json:"auditResults"`
+	AlertRecords     []models.AlertRecord          `json:"alertRecords"`
+	TamperEvents     []models.TamperEvent          `json:"tamperEvents"`
+	SSHLoginEvents   []models.SSHLoginEvent        `json:"sshLoginEvents"`
+	ConnectionEvents []models.AgentConnectionEvent `json:"connectionEvents"`
+	IPHistory        []models.AgentIPHistory       `json:"ipHistory"`
+	Annotations      []models.Annotation           `json:"annotations"`
+}
+
+// AgentImportResult 导入结果
+type AgentImportResult struct {
+	Agent          *models.Agent `json:"agent"`
+	SourceAgentID  string        `json:"sourceAgentId"`
+	MetricSeries   int           `json:"metricSeries"`   // 导入的指标序列数
+	MetricsSkipped bool          `json:"metricsSkipped"` // 导入指标失败时为 true，探针和关联数据已导入
+}
+
+// PrepareAgentExport 查询导出包中的探针记录及关联数据，在写入响应之前调用，出错时仍可返回错误状态码
+func (s *AgentService) PrepareAgentExport(ctx context.Context, agentID string) (*AgentBundleData, error) {
+	return s.collectAgentBundle(ctx, agentID)
+}
+
+// ExportAgent 将探针的记录、关联数据和最近 metricDays 天的原始指标写入 zip 导出包
+// 导出包包含 manifest.json、data.json 和 metrics.jsonl（VictoriaMetrics JSON Line Format）
+// 返回错误时 zip 未写完（缺少目录区），调用方需要中断输出，不能当作完整的导出包发送
+func (s *AgentService) ExportAgent(ctx context.Context, data *AgentBundleData, w io.Writer, metricDays int) error {
+	agentID := data.Agent.ID
+	now := time.Now()
+	metricDays = min(max(metricDays, 0), MaxAgentBundleMetricDays)
+	metricsStart := now.AddDate(0, 0, -metricDays)
+	manifest := AgentBundleManifest{
+		Version:      AgentBundleVersion,
+		AgentID:      data.Agent.ID,
+		AgentName:    data.Agent.Name,
+		ExportedAt:   now.UnixMilli(),
+		MetricsStart: metricsStart.UnixMilli(),
+		MetricsEnd:   now.UnixMilli(),
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeZipJSON(zw, agentBundleManifestFile, manifest); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, agentBundleDataFile, data); err != nil {
+		return err
+	}
+
+	metricsWriter, err := zw.Create(agentBundleMetricsFile)
+	if err != nil {
+		return err
+	}
+	if metricDays > 0 {
+		encoder := json.NewEncoder(metricsWriter)
+		match := fmt.Sprintf(`{agent_id=%q}`, agentID)
+		err := s.metricService.vmClient.Export(ctx, match, metricsStart, now, func(metric vmclient.Metric) error {
+			return encoder.Encode(metric)
+		})
+		if err != nil {
+			s.logger.Error("导出探针指标失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	s.logger.Info("探针导出完成",
+		zap.String("agentId", agentID),
+		zap.Int("metricDays", metricDays),
+		zap.Int("auditResults", len(data.AuditResults)),
+		zap.Int("alertRecords", len(data.AlertRecords)))
+	return nil
+}
+
+// collectAgentBundle 查询探针记录及关联数据
+func (s *AgentService) collectAgentBundle(ctx context.Context, agentID string) (*AgentBundleData, error) {
+	agent, err := s.AgentRepo.FindById(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	data := &AgentBundleData{Agent: agent}
+
+	policy, err := s.metricService.GetMetricPolicy(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(policy.Allow) > 0 || len(policy.Deny) > 0 {
+		data.MetricPolicy = policy
+	}
+	if data.AuditResults, err = s.AgentRepo.FindAuditResultsByAgentID(ctx, agentID, agentBundleMaxRows); err != nil {
+		return nil, err
+	}
+	if data.AlertRecords, err = s.AlertRecordRepo.FindByAgentID(ctx, agentID, agentBundleMaxRows); err != nil {
+		return nil, err
+	}
+	if data.TamperEvents, err = s.TamperEventRepo.FindByAgentID(ctx, agentID, agentBundleMaxRows); err != nil {
+		return nil, err
+	}
+	if data.SSHLoginEvents, err = s.SSHLoginEventRepo.FindByAgentID(ctx, agentID, agentBundleMaxRows); err != nil {
+		return nil, err
+	}
+	if data.ConnectionEvents, err = s.AgentConnectionEventRepo.FindByAgentID(ctx, agentID, agentBundleMaxRows); err != nil {
+		return nil, err
+	}
+	if data.IPHistory, err = s.AgentIPHistoryRepo.FindByAgentID(ctx, agentID, agentBundleMaxRows); err != nil {
+		return nil, err
+	}
+	if data.Annotations, err = s.AnnotationRepo.FindByAgentID(ctx, agentID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ImportAgent 从导出包重建探针及关联数据，newID 为空时沿用原探针ID
+// 关联数据重新生成主键并指向新的探针ID；导入的探针为离线状态，需要探针使用该ID重新注册后上线
+func (s *AgentService) ImportAgent(ctx context.Context, r io.ReaderAt, size int64, newID string) (*AgentImportResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrAgentBundleInvalid
+	}
+	var manifest AgentBundleManifest
+	if err := readZipJSON(zr, agentBundleManifestFile, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != AgentBundleVersion {
+		return nil, ErrAgentBundleVersion
+	}
+	var data AgentBundleData
+	if err := readZipJSON(zr, agentBundleDataFile, &data); err != nil {
+		return nil, err
+	}
+	if data.Agent.ID == "" {
+		return nil, ErrAgentBundleInvalid
+	}
+
+	sourceID := data.Agent.ID
+	agentID := strings.TrimSpace(newID)
+	if agentID == "" {
+		agentID = sourceID
+	}
+	if !importAgentIDPattern.MatchString(agentID) {
+		return nil, ErrInvalidAgentID
+	}
+	exists, err := s.AgentRepo.ExistsById(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAgentIDExists
+	}
+
+	operator, _ := OperatorFromContext(ctx)
+	rebindAgentBundle(&data, agentID, time.Now().UnixMilli(), operator)
+	err = s.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.AgentRepo.Create(ctx, &data.Agent); err != nil {
+			return err
+		}
+		if err := s.AgentRepo.CreateAuditResults(ctx, data.AuditResults); err != nil {
+			return err
+		}
+		if err := createInBatches(ctx, s.AlertRecordRepo.CreateInBatches, data.AlertRecords); err != nil {
+			return err
+		}
+		if err := createInBatches(ctx, s.TamperEventRepo.CreateInBatches, data.TamperEvents); err != nil {
+			return err
+		}
+		if err := createInBatches(ctx, s.SSHLoginEventRepo.CreateInBatches, data.SSHLoginEvents); err != nil {
+			return err
+		}
+		if err := createInBatches(ctx, s.AgentConnectionEventRepo.CreateInBatches, data.ConnectionEvents); err != nil {
+			return err
+		}
+		if err := createInBatches(ctx, s.AgentIPHistoryRepo.CreateInBatches, data.IPHistory); err != nil {
+			return err
+		}
+		return createInBatches(ctx, s.AnnotationRepo.CreateInBatches, data.Annotations)
+	})
+	if err != nil {
+		s.logger.Error("导入探针失败", zap.String("agentId", agentID), zap.String("sourceAgentId", sourceID), zap.Error(err))
+		return nil, err
+	}
+
+	result := &AgentImportResult{Agent: &data.Agent, SourceAgentID: sourceID}
+	if data.MetricPolicy != nil {
+		if err := s.metricService.SetMetricPolicy(ctx, agentID, data.MetricPolicy); err != nil {
+			s.logger.Warn("导入探针指标采集策略失败", zap.String("agentId", agentID), zap.Error(err))
+		}
+	}
+	if result.MetricSeries, err = s.importAgentBundleMetrics(ctx, zr, agentID); err != nil {
+		s.logger.Error("导入探针指标失败", zap.String("agentId", agentID), zap.Error(err))
+		result.MetricsSkipped = true
+	}
+
+	s.logger.Info("探针导入完成",
+		zap.String("agentId", agentID),
+		zap.String("sourceAgentId", sourceID),
+		zap.Int("metricSeries", result.MetricSeries))
+	return result, nil
+}
+
+// rebindAgentBundle 将探针及关联数据指向新的探针ID，并重新生成关联数据的主键
+// 告警状态不随导出包迁移，导出时仍在告警中的记录在导入时标记为已恢复，否则会一直停留在告警中
+func rebindAgentBundle(data *AgentBundleData, agentID string, importedAt int64, operator string) {
+	data.Agent.ID = agentID
+	data.Agent.Status = 0
+	for i := range data.AuditResults {
+		data.AuditResults[i].ID = 0
+		data.AuditResults[i].AgentID = agentID
+	}
+	for i := range data.AlertRecords {
+		data.AlertRecords[i].ID = 0
+		data.AlertRecords[i].AgentID = agentID
+		if data.AlertRecords[i].Status == "firing" {
+			data.AlertRecords[i].Status = "resolved"
+			data.AlertRecords[i].ResolvedAt = importedAt
+			data.AlertRecords[i].ResolvedBy = operator
+		}
+	}
+	for i := range data.TamperEvents {
+		data.TamperEvents[i].ID = uuid.NewString()
+		data.TamperEvents[i].AgentID = agentID
+	}
+	for i := range data.SSHLoginEvents {
+		data.SSHLoginEvents[i].ID = uuid.NewString()
+		data.SSHLoginEvents[i].AgentID = agentID
+	}
+	for i := range data.ConnectionEvents {
+		data.ConnectionEvents[i].ID = uuid.NewString()
+		data.ConnectionEvents[i].AgentID = agentID
+	}
+	for i := range data.IPHistory {
+		data.IPHistory[i].ID = uuid.NewString()
+		data.IPHistory[i].AgentID = agentID
+	}
+	for i := range data.Annotations {
+		data.Annotations[i].ID = uuid.NewString()
+		data.Annotations[i].AgentID = agentID
+	}
+}
+
+// importAgentBundleMetrics 将导出包中的原始指标改写探针ID后分批写入，返回写入的序列数
+func (s *AgentService) importAgentBundleMetrics(ctx context.Context, zr *zip.Reader, agentID string) (int, error) {
+	file, err := zr.Open(agentBundleMetricsFile)
+	if err != nil {
+		// 导出时未包含指标
+		return 0, nil
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	batch := make([]vmclient.Metric, 0, agentBundleMetricBatch)
+	imported := 0
+	for {
+		var metric vmclient.Metric
+		if err := decoder.Decode(&metric); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return imported, ErrAgentBundleInvalid
+		}
+		if len(metric.Metric) == 0 || len(metric.Values) != len(metric.Timestamps) {
+			continue
+		}
+		metric.Metric["agent_id"] = agentID
+		batch = append(batch, metric)
+		if len(batch) == agentBundleMetricBatch {
+			if err := s.metricService.metricStore.Write(ctx, batch); err != nil {
+				return imported, err
+			}
+			imported += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := s.metricService.metricStore.Write(ctx, batch); err != nil {
+		return imported, err
+	}
+	return imported + len(batch), nil
+}
+
+// createInBatches 批量创建记录，列表为空时跳过
+func createInBatches[T any](ctx context.Context, create func(ctx context.Context, entities []T, batchSize int) error, entities []T) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	return create(ctx, entities, 100)
+}
+
+// writeZipJSON 以 JSON 写入 zip 中的一个文件
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// readZipJSON 读取 zip 中的 JSON 文件
+func readZipJSON(zr *zip.Reader, name string, v any) error {
+	file, err := zr.Open(name)
+	if err != nil {
+		return ErrAgentBundleInvalid
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return ErrAgentBundleInvalid
+	}
+	return nil
+}
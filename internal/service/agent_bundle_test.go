@@ -0,0 +1,72 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestRebindAgentBundle(t *testing.T) {
+	data := &AgentBundleData{
+		Agent:        models.Agent{ID: "old", Status: 1, Tags: []string{"prod"}},
+		AuditResults: []models.AuditResult{{ID: 7, AgentID: "old"}},
+		AlertRecords: []models.AlertRecord{
+			{ID: 9, AgentID: "old", Status: "resolved", ResolvedAt: 100},
+			{ID: 10, AgentID: "old", Status: "firing"},
+		},
+		SSHLoginEvents: []models.SSHLoginEvent{{ID: "e1", AgentID: "old"}},
+		Annotations:    []models.Annotation{{ID: "a1", AgentID: "old"}},
+	}
+	rebindAgentBundle(data, "new", 2000, "admin")
+
+	if data.Agent.ID != "new" || data.Agent.Status != 0 || data.Agent.Tags[0] != "prod" {
+		t.Fatalf("unexpected agent: %+v", data.Agent)
+	}
+	if data.AuditResults[0].ID != 0 || data.AuditResults[0].AgentID != "new" {
+		t.Fatalf("audit result not rebound: %+v", data.AuditResults[0])
+	}
+	if data.AlertRecords[0].ID != 0 || data.AlertRecords[0].AgentID != "new" {
+		t.Fatalf("alert record not rebound: %+v", data.AlertRecords[0])
+	}
+	if data.AlertRecords[0].ResolvedAt != 100 || data.AlertRecords[0].ResolvedBy != "" {
+		t.Fatalf("resolved record should be kept as is: %+v", data.AlertRecords[0])
+	}
+	// 告警状态不随导出包迁移，告警中的记录导入时标记为已恢复
+	if r := data.AlertRecords[1]; r.Status != "resolved" || r.ResolvedAt != 2000 || r.ResolvedBy != "admin" {
+		t.Fatalf("firing record not resolved: %+v", r)
+	}
+	if data.SSHLoginEvents[0].ID == "e1" || data.SSHLoginEvents[0].AgentID != "new" {
+		t.Fatalf("ssh event not rebound: %+v", data.SSHLoginEvents[0])
+	}
+	if data.Annotations[0].ID == "a1" || data.Annotations[0].AgentID != "new" {
+		t.Fatalf("annotation not rebound: %+v", data.Annotations[0])
+	}
+}
+
+func TestAgentBundleZipJSON(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	manifest := AgentBundleManifest{Version: AgentBundleVersion, AgentID: "a1"}
+	if err := writeZipJSON(zw, agentBundleManifestFile, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got AgentBundleManifest
+	if err := readZipJSON(zr, agentBundleManifestFile, &got); err != nil || got != manifest {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	var data AgentBundleData
+	if err := readZipJSON(zr, agentBundleDataFile, &data); !errors.Is(err, ErrAgentBundleInvalid) {
+		t.Fatalf("missing file should be invalid bundle, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// agentCleanupInterval 自动清理检查间隔
+	agentCleanupInterval = time.Hour
+	// NotificationTypeAgentCleanup 探针自动清理预告通知
+	NotificationTypeAgentCleanup = "agent_cleanup"
+)
+
+// AgentCleanupCandidate 待清理的探针
+type AgentCleanupCandidate struct {
+	AgentID    string `json:"agentId"`
+	Name       string `json:"name"`
+	LastSeenAt int64  `json:"lastSeenAt"` // 最后上线时间（时间戳毫秒）
+	WarnedAt   int64  `json:"warnedAt"`   // 发出清理预告的时间（时间戳毫秒），0 表示尚未预告
+	DeleteAt   int64  `json:"deleteAt"`   // 预计删除时间（时间戳毫秒），尚未预告时为下一次检查后的宽限期结束时间
+}
+
+// AgentCleanupService 按配置自动删除长期离线的探针
+// 删除前先发送预告通知，宽限期内探针重新上线或被排除时取消删除
+type AgentCleanupService struct {
+	logger              *zap.Logger
+	agentService        *AgentService
+	propertyService     *PropertyService
+	notificationService *NotificationService
+
+	mu     sync.Mutex
+	warned map[string]int64 // 探针ID -> 发出清理预告的时间，重启后重新预告并等待宽限期
+}
+
+func NewAgentCleanupService(logger *zap.Logger, agentService *AgentService, propertyService *PropertyService, notificationService *NotificationService) *AgentCleanupService {
+	return &AgentCleanupService{
+		logger:              logger,
+		agentService:        agentService,
+		propertyService:     propertyService,
+		notificationService: notificationService,
+		warned:              make(map[string]int64),
+	}
+}
+
+// Run 启动自动清理调度
+func (s *AgentCleanupService) Run(ctx context.Context) {
+	s.logger.Info("探针自动清理定时任务已启动")
+
+	ticker := time.NewTicker(agentCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("探针自动清理定时任务已停止")
+			return
+		case <-ticker.C:
+			config, err := s.propertyService.GetAgentCleanupConfig(ctx)
+			if err != nil {
+				s.logger.Error("获取探针自动清理配置失败", zap.Error(err))
+				continue
+			}
+			if !config.Enabled {
+				s.reset()
+				continue
+			}
+			if _, err := s.Cleanup(ctx, config, time.Now()); err != nil {
+				s.logger.Error("自动清理离线探针失败", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Cleanup 执行一轮清理：首次超过离线天数的探针发送预告，预告后超过宽限期的探针删除，返回删除的数量
+func (s *AgentCleanupService) Cleanup(ctx context.Context, config *models.AgentCleanupConfig, now time.Time) (int, error) {
+	candidates, err := s.findCandidates(ctx, config, now)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	// 不再满足条件的探针（重新上线、被排除或已手动删除）取消预告
+	current := make(map[string]int64, len(candidates))
+	for _, agent := range candidates {
+		current[agent.ID] = s.warned[agent.ID]
+	}
+	s.warned = current
+	s.mu.Unlock()
+
+	grace := time.Duration(config.GraceHours) * time.Hour
+	deleted := 0
+	for _, agent := range candidates {
+		warnedAt := current[agent.ID]
+		if warnedAt == 0 {
+			s.warn(ctx, &agent, config, now)
+			continue
+		}
+		if now.UnixMilli()-warnedAt < grace.Milliseconds() {
+			continue
+		}
+
+		if err := s.agentService.DeleteAgent(ctx, agent.ID); err != nil {
+			s.logger.Error("自动清理离线探针失败", zap.String("agentId", agent.ID), zap.Error(err))
+			continue
+		}
+		s.mu.Lock()
+		delete(s.warned, agent.ID)
+		s.mu.Unlock()
+		deleted++
+		s.logger.Info("已自动清理长期离线的探针",
+			zap.String("agentId", agent.ID),
+			zap.String("name", agent.Name),
+			zap.Int64("lastSeenAt", agent.LastSeenAt))
+	}
+	return deleted, nil
+}
+
+// ListCandidates 列出当前满足清理条件的探针，用于确认排除配置是否生效
+func (s *AgentCleanupService) ListCandidates(ctx context.Context) ([]AgentCleanupCandidate, error) {
+	config, err := s.propertyService.GetAgentCleanupConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	agents, err := s.findCandidates(ctx, config, now)
+	if err != nil {
+		return nil, err
+	}
+
+	grace := time.Duration(config.GraceHours) * time.Hour
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]AgentCleanupCandidate, 0, len(agents))
+	for _, agent := range agents {
+		candidate := AgentCleanupCandidate{
+			AgentID:    agent.ID,
+			Name:       agent.Name,
+			LastSeenAt: agent.LastSeenAt,
+			WarnedAt:   s.warned[agent.ID],
+		}
+		if candidate.WarnedAt > 0 {
+			candidate.DeleteAt = candidate.WarnedAt + grace.Milliseconds()
+		} else {
+			candidate.DeleteAt = now.Add(agentCleanupInterval + grace).UnixMilli()
+		}
+		result = append(result, candidate)
+	}
+	return result, nil
+}
+
+// findCandidates 查找离线超过配置天数且未被排除的探针
+func (s *AgentCleanupService) findCandidates(ctx context.Context, config *models.AgentCleanupConfig, now time.Time) ([]models.Agent, error) {
+	before := now.AddDate(0, 0, -config.OfflineDays).UnixMilli()
+	agents, err := s.agentService.AgentRepo.FindOfflineBefore(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	candidates := agents[:0]
+	for _, agent := range agents {
+		if !isCleanupExcluded(config, &agent) {
+			candidates = append(candidates, agent)
+		}
+	}
+	return candidates, nil
+}
+
+// isCleanupExcluded 判断探针是否被排除在自动清理之外
+func isCleanupExcluded(config *models.AgentCleanupConfig, agent *models.Agent) bool {
+	if slices.Contains(config.ExcludeAgentIDs, agent.ID) {
+		return true
+	}
+	for _, tag := range agent.Tags {
+		if slices.Contains(config.ExcludeTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// warn 记录预告时间并发送清理预告通知
+func (s *AgentCleanupService) warn(ctx context.Context, agent *models.Agent, config *models.AgentCleanupConfig, now time.Time) {
+	s.mu.Lock()
+	s.warned[agent.ID] = now.UnixMilli()
+	s.mu.Unlock()
+
+	offlineDays := int(now.Sub(time.UnixMilli(agent.LastSeenAt)).Hours() / 24)
+	s.logger.Warn("探针长期离线，将被自动清理",
+		zap.String("agentId", agent.ID),
+		zap.String("name", agent.Name),
+		zap.Int("offlineDays", offlineDays),
+		zap.Int("graceHours", config.GraceHours))
+
+	record := &models.AlertRecord{
+		AgentID:   agent.ID,
+		AgentName: agent.Name,
+		AlertType: NotificationTypeAgentCleanup,
+		Message: fmt.Sprintf("探针 %s 已离线 %d 天，将在 %d 小时后自动删除；如需保留，请在自动清理配置中排除该探针或使其重新上线",
+			agent.Name, offlineDays, config.GraceHours),
+		Level:   models.AlertLevelWarning,
+		Status:  "firing",
+		FiredAt: now.UnixMilli(),
+	}
+	if err := s.notificationService.SendAlertNotification(ctx, NotificationTypeAgentCleanup, record, agent); err != nil {
+		s.logger.Error("发送探针清理预告通知失败", zap.String("agentId", agent.ID), zap.Error(err))
+	}
+}
+
+// reset 关闭自动清理时清空预告记录，重新开启后需要重新预告
+func (s *AgentCleanupService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.warned)
+}
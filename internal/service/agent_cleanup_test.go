@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestIsCleanupExcluded(t *testing.T) {
+	config := &models.AgentCleanupConfig{
+		ExcludeAgentIDs: []string{"keep-me"},
+		ExcludeTags:     []string{"pinned"},
+	}
+
+	cases := []struct {
+		agent models.Agent
+		want  bool
+	}{
+		{models.Agent{ID: "keep-me"}, true},
+		{models.Agent{ID: "a1", Tags: []string{"prod", "pinned"}}, true},
+		{models.Agent{ID: "a2", Tags: []string{"prod"}}, false},
+		{models.Agent{ID: "a3"}, false},
+	}
+	for _, tc := range cases {
+		if got := isCleanupExcluded(config, &tc.agent); got != tc.want {
+			t.Errorf("isCleanupExcluded(%s) = %v, want %v", tc.agent.ID, got, tc.want)
+		}
+	}
+}
+
+func TestAgentCleanupConfigDefaults(t *testing.T) {
+	config := &models.AgentCleanupConfig{Enabled: true}
+	applyAgentCleanupConfigDefaults(config)
+	if config.OfflineDays != 30 || config.GraceHours != 24 {
+		t.Fatalf("defaults = %+v", config)
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 默认的探针ID冲突检测窗口
+const defaultCollisionWindow = 2 * time.Minute
+
+// ErrAgentIDCollision 探针ID冲突
+var ErrAgentIDCollision = fmt.Errorf("探针ID冲突：该ID正被另一台主机使用")
+
+// collisionWindow 获取冲突检测窗口
+func (s *AgentService) collisionWindow() time.Duration {
+	if s.agentConfig != nil && s.agentConfig.CollisionWindowSeconds > 0 {
+		return time.Duration(s.agentConfig.CollisionWindowSeconds) * time.Second
+	}
+	return defaultCollisionWindow
+}
+
+// isCollision 判断注册信息是否与在线的已有探针冲突（短时间内主机名或 MAC 不一致）
+func (s *AgentService) isCollision(existing *models.Agent, info *protocol.AgentInfo, now int64) bool {
+	if existing.Status != 1 {
+		return false
+	}
+	if now-existing.LastSeenAt > s.collisionWindow().Milliseconds() {
+		return false
+	}
+	if existing.Hostname != "" && info.Hostname != "" && existing.Hostname != info.Hostname {
+		return true
+	}
+	return existing.MAC != "" && info.MAC != "" && !strings.EqualFold(existing.MAC, info.MAC)
+}
+
+// checkCollision 检测探针ID冲突，记录冲突并在配置要求时拒绝注册
+func (s *AgentService) checkCollision(ctx context.Context, existing *models.Agent, ip string, info *protocol.AgentInfo) error {
+	now := time.Now().UnixMilli()
+	if !s.isCollision(existing, info, now) {
+		return nil
+	}
+
+	reject := s.agentConfig != nil && s.agentConfig.RejectIDCollision
+	s.logger.Warn("检测到探针ID冲突",
+		zap.String("agentID", existing.ID),
+		zap.String("existingHostname", existing.Hostname),
+		zap.String("existingIP", existing.IP),
+		zap.String("incomingHostname", info.Hostname),
+		zap.String("incomingIP", ip),
+		zap.Bool("rejected", reject),
+	)
+
+	collision := &models.AgentCollision{
+		ID:               uuid.NewString(),
+		AgentID:          existing.ID,
+		ExistingHostname: existing.Hostname,
+		ExistingMAC:      existing.MAC,
+		ExistingIP:       existing.IP,
+		IncomingHostname: info.Hostname,
+		IncomingMAC:      info.MAC,
+		IncomingIP:       ip,
+		Rejected:         reject,
+		CreatedAt:        now,
+	}
+	if err := s.AgentCollisionRepo.Create(ctx, collision); err != nil {
+		s.logger.Error("保存探针ID冲突记录失败", zap.Error(err))
+	}
+
+	if reject {
+		return ErrAgentIDCollision
+	}
+	return nil
+}
+
+// SplitAgent 将冲突的探针拆分为新ID：以当前注册信息创建新探针，并返回新探针。
+// 调用方负责通知当前连接的探针使用新ID重新注册。
+func (s *AgentService) SplitAgent(ctx context.Context, agentID string) (*models.Agent, error) {
+	agent, err := s.AgentRepo.FindById(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	newAgent := &models.Agent{
+		ID:         uuid.NewString(),
+		Name:       agent.Hostname,
+		Hostname:   agent.Hostname,
+		MAC:        agent.MAC,
+		IP:         agent.IP,
+		OS:         agent.OS,
+		Arch:       agent.Arch,
+		Version:    agent.Version,
+		Tags:       agent.Tags,
+		Visibility: agent.Visibility,
+		Status:     0,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if newAgent.Name == "" {
+		newAgent.Name = agent.Name
+	}
+
+	err = s.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.AgentRepo.Create(ctx, newAgent); err != nil {
+			return err
+		}
+		// 将该探针未处理的冲突记录关联到新ID
+		return s.AgentCollisionRepo.GetDB(ctx).
+			Model(&models.AgentCollision{}).
+			Where("agent_id = ? AND new_agent_id = ?", agentID, "").
+			Update("new_agent_id", newAgent.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("探针ID已拆分",
+		zap.String("agentID", agentID),
+		zap.String("newAgentID", newAgent.ID),
+		zap.String("hostname", newAgent.Hostname))
+	return newAgent, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/repo"
+	"github.com/dushixiang/pika/internal/websocket"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrAgentOffline 探针未连接
+var ErrAgentOffline = errors.New("探针未连接")
+
+// AgentConfigService 汇总探针的各项采集配置，在连接时或管理员要求时下发给探针
+type AgentConfigService struct {
+	logger             *zap.Logger
+	agentRepo          *repo.AgentRepo
+	propertyService    *PropertyService
+	metricService      *MetricService
+	sshLoginService    *SSHLoginService
+	customCheckService *CustomCheckService
+	tamperService      *TamperService
+	wsManager          *websocket.Manager
+}
+
+// AgentConfigReloadResult 重新下发的配置，Warnings 为获取失败而未下发的项，探针保持这些项的当前配置
+type AgentConfigReloadResult struct {
+	*protocol.ReloadConfigData
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func NewAgentConfigService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, metricService *MetricService,
+	sshLoginService *SSHLoginService, customCheckService *CustomCheckService, tamperService *TamperService, wsManager *websocket.Manager) *AgentConfigService {
+	return &AgentConfigService{
+		logger:             logger,
+		agentRepo:          repo.NewAgentRepo(db),
+		propertyService:    propertyService,
+		metricService:      metricService,
+		sshLoginService:    sshLoginService,
+		customCheckService: customCheckService,
+		tamperService:      tamperService,
+		wsManager:          wsManager,
+	}
+}
+
+// BuildConfig 构建探针当前的完整配置
+// 某一项获取失败时该项为空，其余项照常返回，错误合并后返回
+func (s *AgentConfigService) BuildConfig(ctx context.Context, agent *models.Agent) (*protocol.ReloadConfigData, error) {
+	var (
+		data protocol.ReloadConfigData
+		errs []error
+	)
+
+	if config, err := s.propertyService.GetPublicIPConfig(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("获取公网 IP 采集配置失败: %w", err))
+	} else {
+		data.PublicIP = buildAgentPublicIPConfig(config, agent.ID)
+	}
+
+	if config, err := s.sshLoginService.GetConfig(ctx, agent.ID); err != nil {
+		errs = append(errs, fmt.Errorf("获取 SSH 登录监控配置失败: %w", err))
+	} else {
+		data.SSHLogin = &protocol.SSHLoginConfig{Enabled: config.Enabled}
+	}
+
+	if policy, err := s.metricService.GetMetricPolicy(ctx, agent.ID); err != nil {
+		errs = append(errs, fmt.Errorf("获取指标采集策略失败: %w", err))
+	} else {
+		data.MetricPolicy = policy
+	}
+
+	// 即使没有匹配的检查也下发空列表，停止已被删除的检查
+	if config, err := s.customCheckService.BuildConfig(ctx, agent); err != nil {
+		errs = append(errs, fmt.Errorf("获取自定义检查配置失败: %w", err))
+	} else {
+		data.CustomChecks = config
+	}
+
+	if paths, _, err := s.tamperService.BuildInitialConfig(ctx, agent.ID); err != nil {
+		errs = append(errs, fmt.Errorf("获取防篡改配置失败: %w", err))
+	} else {
+		data.Tamper = &protocol.TamperReloadConfig{Paths: paths}
+	}
+
+	return &data, errors.Join(errs...)
+}
+
+// buildAgentPublicIPConfig 构建探针的公网 IP 采集配置，未启用或探针不在采集范围内时返回 enabled 为 false 的配置，
+// 探针据此停止采集，而不是保持之前下发的配置
+func buildAgentPublicIPConfig(config *models.PublicIPConfig, agentID string) *protocol.PublicIPConfigData {
+	if !config.Enabled || (!config.IPv4Enabled && !config.IPv6Enabled) {
+		return &protocol.PublicIPConfigData{Enabled: false}
+	}
+	ipv4Enabled := config.IsIPv4Target(agentID)
+	ipv6Enabled := config.IsIPv6Target(agentID)
+	if !ipv4Enabled && !ipv6Enabled {
+		return &protocol.PublicIPConfigData{Enabled: false}
+	}
+	data := BuildPublicIPConfigData(config, ipv4Enabled, ipv6Enabled)
+	return &data
+}
+
+// ReloadConfig 向在线探针下发完整配置，探针无需重连即可应用
+// 某一项获取失败时只下发其余项（探针保持该项的当前配置），失败的项在 Warnings 中返回
+func (s *AgentConfigService) ReloadConfig(ctx context.Context, agentID string) (*AgentConfigReloadResult, error) {
+	agent, err := s.agentRepo.FindById(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.wsManager.GetClient(agentID); !ok {
+		return nil, ErrAgentOffline
+	}
+
+	result := &AgentConfigReloadResult{}
+	data, buildErr := s.BuildConfig(ctx, &agent)
+	if buildErr != nil {
+		s.logger.Warn("部分探针配置获取失败，只下发其余配置", zap.String("agentId", agentID), zap.Error(buildErr))
+		if joined, ok := buildErr.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				result.Warnings = append(result.Warnings, err.Error())
+			}
+		} else {
+			result.Warnings = append(result.Warnings, buildErr.Error())
+		}
+	}
+	result.ReloadConfigData = data
+
+	msgData, err := json.Marshal(protocol.OutboundMessage{
+		Type: protocol.MessageTypeReloadConfig,
+		Data: data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.wsManager.SendToClient(agentID, msgData); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("已向探针重新下发配置", zap.String("agentId", agentID))
+	return result, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestBuildAgentPublicIPConfig(t *testing.T) {
+	config := &models.PublicIPConfig{
+		Enabled:         true,
+		IntervalSeconds: 300,
+		IPv4Enabled:     true,
+		IPv4Scope:       "custom",
+		IPv4AgentIDs:    []string{"a1"},
+		IPv6Enabled:     true,
+		IPv6Scope:       "all",
+	}
+
+	data := buildAgentPublicIPConfig(config, "a1")
+	if data == nil || !data.IPv4Enabled || !data.IPv6Enabled || data.IntervalSeconds != 300 {
+		t.Fatalf("a1 应同时采集 IPv4 和 IPv6，得到 %+v", data)
+	}
+
+	data = buildAgentPublicIPConfig(config, "a2")
+	if data == nil || data.IPv4Enabled || !data.IPv6Enabled {
+		t.Fatalf("a2 只应采集 IPv6，得到 %+v", data)
+	}
+
+	config.IPv6Enabled = false
+	if data := buildAgentPublicIPConfig(config, "a2"); data == nil || data.Enabled {
+		t.Fatalf("a2 不在采集范围内，应下发 enabled=false，得到 %+v", data)
+	}
+
+	config.Enabled = false
+	if data := buildAgentPublicIPConfig(config, "a1"); data == nil || data.Enabled {
+		t.Fatalf("未启用时应下发 enabled=false，得到 %+v", data)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	ws "github.com/dushixiang/pika/internal/websocket"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxConnectionHistory 单次查询返回的最大连接事件数
+const maxConnectionHistory = 1000
+
+// ConnectionHistoryItem 连接历史条目
+type ConnectionHistoryItem struct {
+	models.AgentConnectionEvent
+	OfflineDuration int64 `json:"offlineDuration,omitempty"` // 重连前的离线时长（毫秒），仅 connect 事件
+}
+
+// ConnectionHistory 探针连接历史
+type ConnectionHistory struct {
+	Events             []ConnectionHistoryItem `json:"events"`
+	Disconnects        int                     `json:"disconnects"`        // 断开次数
+	TotalOfflineTime   int64                   `json:"totalOfflineTime"`   // 时间范围内累计离线时长（毫秒）
+	LongestOfflineTime int64                   `json:"longestOfflineTime"` // 最长单次离线时长（毫秒）
+}
+
+// RecordConnectionEvent 记录探针连接事件（由 WebSocket 管理器回调）
+func (s *AgentService) RecordConnectionEvent(event ws.ConnectionEvent) {
+	record := &models.AgentConnectionEvent{
+		ID:         uuid.NewString(),
+		AgentID:    event.AgentID,
+		Event:      event.Event,
+		RemoteAddr: event.RemoteAddr,
+		Timestamp:  event.Timestamp,
+	}
+	if err := s.AgentConnectionEventRepo.Create(context.Background(), record); err != nil {
+		s.logger.Error("记录探针连接事件失败",
+			zap.String("agentId", event.AgentID),
+			zap.String("event", event.Event),
+			zap.Error(err))
+	}
+}
+
+// GetConnectionHistory 获取探针在时间范围内的连接历史，并根据相邻事件计算离线时长
+func (s *AgentService) GetConnectionHistory(ctx context.Context, agentID string, start, end int64) (*ConnectionHistory, error) {
+	events, err := s.AgentConnectionEventRepo.FindByAgentIDAndTimeRange(ctx, agentID, start, end, maxConnectionHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	// 时间范围开始前的最后一个事件，用于计算跨越开始时间的离线时长
+	var lastDisconnect int64
+	if len(events) > 0 {
+		prev, err := s.AgentConnectionEventRepo.FindLatestBefore(ctx, agentID, events[0].Timestamp)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if prev != nil && prev.Event == models.AgentConnectionDisconnect {
+			lastDisconnect = max(prev.Timestamp, start)
+		}
+	}
+
+	history := &ConnectionHistory{
+		Events: make([]ConnectionHistoryItem, 0, len(events)),
+	}
+	for _, event := range events {
+		item := ConnectionHistoryItem{AgentConnectionEvent: event}
+		switch event.Event {
+		case models.AgentConnectionDisconnect:
+			history.Disconnects++
+			lastDisconnect = event.Timestamp
+		case models.AgentConnectionConnect:
+			if lastDisconnect > 0 {
+				item.OfflineDuration = event.Timestamp - lastDisconnect
+				history.TotalOfflineTime += item.OfflineDuration
+				history.LongestOfflineTime = max(history.LongestOfflineTime, item.OfflineDuration)
+			}
+			lastDisconnect = 0
+		}
+		history.Events = append(history.Events, item)
+	}
+
+	// 当前仍处于离线状态
+	if lastDisconnect > 0 {
+		offline := min(end, time.Now().UnixMilli()) - lastDisconnect
+		history.TotalOfflineTime += offline
+		history.LongestOfflineTime = max(history.LongestOfflineTime, offline)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// registerSignatureMaxSkew 注册签名时间与服务端时间允许的最大偏差
+const registerSignatureMaxSkew = 5 * time.Minute
+
+var (
+	ErrSignatureRequired     = errors.New("服务端要求签名注册，请在探针配置中开启 agent.signed_registration")
+	ErrInvalidSignature      = errors.New("注册签名无效")
+	ErrSignatureExpired      = errors.New("注册签名已过期，请检查探针时钟")
+	ErrChallengeMismatch     = errors.New("注册签名未使用服务端下发的随机数，请升级探针")
+	ErrAgentKeyPending       = errors.New("探针公钥等待管理员审批")
+	ErrAgentKeyRejected      = errors.New("探针公钥已被拒绝")
+	ErrAgentKeyMismatch      = errors.New("探针公钥与该探针ID绑定的公钥不一致")
+	ErrAgentKeyInvalidStatus = errors.New("只能审批或拒绝等待审批的公钥")
+)
+
+// requireSignedRegistration 是否要求所有探针签名注册
+func (s *AgentService) requireSignedRegistration() bool {
+	return s.agentConfig != nil && s.agentConfig.RequireSignedRegistration
+}
+
+// NewRegisterChallenge 生成签名注册的挑战随机数，每个连接生成一次
+func NewRegisterChallenge() (string, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// checkRegisterChallenge 签名必须使用本次连接下发的随机数，截获的注册消息无法在其他连接上重放
+func checkRegisterChallenge(sig *protocol.RegisterSignature, challenge string) error {
+	if challenge == "" || sig.Nonce != challenge {
+		return ErrChallengeMismatch
+	}
+	return nil
+}
+
+// verifyRegistration 校验注册签名并将公钥绑定到探针ID
+// challenge 为本次连接下发的随机数，探针未请求挑战时为空
+//
+// 探针ID已绑定审批通过的公钥时必须使用该公钥签名；首次提交公钥时登记为待审批（TOFU），
+// 要求签名注册时待审批期间拒绝注册，否则仍按 API 密钥注册，审批后开始强制校验
+func (s *AgentService) verifyRegistration(ctx context.Context, ip string, info *protocol.AgentInfo, sig *protocol.RegisterSignature, challenge string) error {
+	bound, err := s.AgentKeyRepo.FindById(ctx, info.ID)
+	hasKey := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if sig == nil {
+		if hasKey && bound.Status == models.AgentKeyApproved {
+			s.logger.Warn("agent registration rejected: signature required by bound key", zap.String("agentID", info.ID), zap.String("ip", ip))
+			return ErrSignatureRequired
+		}
+		if s.requireSignedRegistration() {
+			return ErrSignatureRequired
+		}
+		return nil
+	}
+
+	if err := checkRegisterChallenge(sig, challenge); err != nil {
+		s.logger.Warn("agent registration rejected: challenge mismatch", zap.String("agentID", info.ID), zap.String("ip", ip))
+		return err
+	}
+	publicKey, err := verifyRegisterSignature(info.ID, sig, time.Now())
+	if err != nil {
+		s.logger.Warn("agent registration rejected: invalid signature", zap.String("agentID", info.ID), zap.String("ip", ip), zap.Error(err))
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	if !hasKey {
+		key := &models.AgentKey{
+			AgentID:     info.ID,
+			PublicKey:   sig.PublicKey,
+			Fingerprint: protocol.RegisterKeyFingerprint(publicKey),
+			Status:      models.AgentKeyPending,
+			Hostname:    info.Hostname,
+			IP:          ip,
+			CreatedAt:   now,
+		}
+		if err := s.AgentKeyRepo.Create(ctx, key); err != nil {
+			return err
+		}
+		s.logger.Info("agent public key enrolled, waiting for approval",
+			zap.String("agentID", info.ID),
+			zap.String("fingerprint", key.Fingerprint),
+			zap.String("ip", ip))
+		return s.pendingKeyResult()
+	}
+
+	if bound.PublicKey != sig.PublicKey {
+		s.logger.Warn("agent registration rejected: public key mismatch",
+			zap.String("agentID", info.ID),
+			zap.String("boundFingerprint", bound.Fingerprint),
+			zap.String("incomingFingerprint", protocol.RegisterKeyFingerprint(publicKey)),
+			zap.String("ip", ip))
+		// 未审批的公钥可能来自抢先登记的攻击者，不允许其阻止后续注册，仍按模式处理
+		if bound.Status != models.AgentKeyApproved && !s.requireSignedRegistration() {
+			return nil
+		}
+		return ErrAgentKeyMismatch
+	}
+
+	switch bound.Status {
+	case models.AgentKeyApproved:
+		bound.LastUsedAt = now
+		if err := s.AgentKeyRepo.UpdateById(ctx, &bound); err != nil {
+			s.logger.Error("更新探针公钥使用时间失败", zap.String("agentId", info.ID), zap.Error(err))
+		}
+		return nil
+	case models.AgentKeyRejected:
+		return ErrAgentKeyRejected
+	default:
+		return s.pendingKeyResult()
+	}
+}
+
+// pendingKeyResult 公钥待审批时的注册结果，只有要求签名注册时才拒绝
+func (s *AgentService) pendingKeyResult() error {
+	if s.requireSignedRegistration() {
+		return ErrAgentKeyPending
+	}
+	return nil
+}
+
+// verifyRegisterSignature 校验签名时间和签名，返回解析后的公钥
+func verifyRegisterSignature(agentID string, sig *protocol.RegisterSignature, now time.Time) (ed25519.PublicKey, error) {
+	publicKey, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, ErrInvalidSignature
+	}
+	if len(sig.Nonce) < 16 || len(sig.Nonce) > 128 {
+		return nil, ErrInvalidSignature
+	}
+
+	skew := now.Sub(time.UnixMilli(sig.Timestamp))
+	if skew > registerSignatureMaxSkew || skew < -registerSignatureMaxSkew {
+		return nil, ErrSignatureExpired
+	}
+	if !ed25519.Verify(publicKey, protocol.RegisterSigningPayload(agentID, sig.Timestamp, sig.Nonce), signature) {
+		return nil, ErrInvalidSignature
+	}
+	return publicKey, nil
+}
+
+// ListAgentKeys 按状态列出探针公钥，状态为空时列出全部
+func (s *AgentService) ListAgentKeys(ctx context.Context, status string) ([]models.AgentKey, error) {
+	return s.AgentKeyRepo.ListByStatus(ctx, status)
+}
+
+// ReviewAgentKey 审批或拒绝待审批的探针公钥
+func (s *AgentService) ReviewAgentKey(ctx context.Context, agentID string, approve bool, operator string) (*models.AgentKey, error) {
+	key, err := s.AgentKeyRepo.FindById(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if key.Status != models.AgentKeyPending {
+		return nil, ErrAgentKeyInvalidStatus
+	}
+
+	key.Status = models.AgentKeyRejected
+	if approve {
+		key.Status = models.AgentKeyApproved
+	}
+	key.ReviewedBy = operator
+	key.ReviewedAt = time.Now().UnixMilli()
+	if err := s.AgentKeyRepo.UpdateById(ctx, &key); err != nil {
+		return nil, err
+	}
+	s.logger.Info("探针公钥审批完成",
+		zap.String("agentId", agentID),
+		zap.String("fingerprint", key.Fingerprint),
+		zap.String("status", key.Status),
+		zap.String("operator", operator))
+	return &key, nil
+}
+
+// DeleteAgentKey 解除探针ID与公钥的绑定，探针下次签名注册时重新登记（如重装系统后更换了密钥）
+func (s *AgentService) DeleteAgentKey(ctx context.Context, agentID, operator string) error {
+	if _, err := s.AgentKeyRepo.FindById(ctx, agentID); err != nil {
+		return err
+	}
+	if err := s.AgentKeyRepo.DeleteById(ctx, agentID); err != nil {
+		return err
+	}
+	s.logger.Info("已解除探针公钥绑定", zap.String("agentId", agentID), zap.String("operator", operator))
+	return nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+func signTestRegistration(t *testing.T, privateKey ed25519.PrivateKey, agentID string, ts int64, nonce string) *protocol.RegisterSignature {
+	t.Helper()
+	signature := ed25519.Sign(privateKey, protocol.RegisterSigningPayload(agentID, ts, nonce))
+	return &protocol.RegisterSignature{
+		PublicKey: base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		Timestamp: ts,
+		Nonce:     nonce,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}
+}
+
+func TestVerifyRegisterSignature(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	nonce := "0123456789abcdef0123"
+
+	sig := signTestRegistration(t, privateKey, "agent-1", now.UnixMilli(), nonce)
+	publicKey, err := verifyRegisterSignature("agent-1", sig, now)
+	if err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if !publicKey.Equal(privateKey.Public()) {
+		t.Fatalf("unexpected public key")
+	}
+
+	// 签名绑定探针ID，不能用于其他探针
+	if _, err := verifyRegisterSignature("agent-2", sig, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("signature for another agent: got %v", err)
+	}
+
+	tampered := *sig
+	tampered.Nonce = "fedcba9876543210fedc"
+	if _, err := verifyRegisterSignature("agent-1", &tampered, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("tampered nonce: got %v", err)
+	}
+
+	expired := signTestRegistration(t, privateKey, "agent-1", now.Add(-10*time.Minute).UnixMilli(), nonce)
+	if _, err := verifyRegisterSignature("agent-1", expired, now); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expired signature: got %v", err)
+	}
+
+	shortNonce := signTestRegistration(t, privateKey, "agent-1", now.UnixMilli(), "short")
+	if _, err := verifyRegisterSignature("agent-1", shortNonce, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("short nonce: got %v", err)
+	}
+
+	badKey := *sig
+	badKey.PublicKey = base64.StdEncoding.EncodeToString([]byte("not a key"))
+	if _, err := verifyRegisterSignature("agent-1", &badKey, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("bad key size: got %v", err)
+	}
+}
+
+func TestCheckRegisterChallenge(t *testing.T) {
+	challenge, err := NewRegisterChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkRegisterChallenge(&protocol.RegisterSignature{Nonce: challenge}, challenge); err != nil {
+		t.Fatalf("matching challenge rejected: %v", err)
+	}
+	// 探针自选的随机数或其他连接的挑战都不能通过
+	if err := checkRegisterChallenge(&protocol.RegisterSignature{Nonce: "0123456789abcdef0123"}, challenge); !errors.Is(err, ErrChallengeMismatch) {
+		t.Fatalf("client chosen nonce: got %v", err)
+	}
+	if err := checkRegisterChallenge(&protocol.RegisterSignature{Nonce: challenge}, ""); !errors.Is(err, ErrChallengeMismatch) {
+		t.Fatalf("missing challenge: got %v", err)
+	}
+	if other, _ := NewRegisterChallenge(); other == challenge {
+		t.Fatal("challenges should be unique")
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultIPHistoryLimit 默认返回的 IP 变更历史条数
+const defaultIPHistoryLimit = 100
+
+// UpdatePublicIP 更新探针的公网 IPv4/IPv6，地址变化时记录历史
+// 只覆盖本次采集到的地址族，避免某一地址族采集失败时清空已有地址
+func (s *AgentService) UpdatePublicIP(ctx context.Context, agentID string, ipv4 string, ipv6 string) error {
+	agent, err := s.AgentRepo.FindById(ctx, agentID)
+	if err != nil {
+		return err
+	}
+
+	// 按实际地址族归类，防止上报端把地址放错字段
+	var newIPv4, newIPv6 string
+	for _, raw := range []string{ipv4, ipv6} {
+		switch family, ip := classifyIP(raw); family {
+		case models.IPFamilyV4:
+			newIPv4 = ip
+		case models.IPFamilyV6:
+			newIPv6 = ip
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	updates := map[string]interface{}{
+		"updated_at": now,
+	}
+	if newIPv4 != "" && newIPv4 != agent.IPv4 {
+		updates["ipv4"] = newIPv4
+		s.recordIPChange(ctx, agentID, models.IPFamilyV4, newIPv4, agent.IPv4, now)
+	}
+	if newIPv6 != "" && newIPv6 != agent.IPv6 {
+		updates["ipv6"] = newIPv6
+		s.recordIPChange(ctx, agentID, models.IPFamilyV6, newIPv6, agent.IPv6, now)
+	}
+
+	// 兼容旧字段：连接 IP 为空时使用公网 IP 填充，优先 IPv4
+	if agent.IP == "" {
+		if newIPv4 != "" {
+			updates["ip"] = newIPv4
+		} else if newIPv6 != "" {
+			updates["ip"] = newIPv6
+		}
+	}
+
+	return s.AgentRepo.UpdateColumnsById(ctx, agentID, updates)
+}
+
+// recordIPChange 记录公网 IP 变更历史
+func (s *AgentService) recordIPChange(ctx context.Context, agentID, family, ip, prevIP string, changedAt int64) {
+	history := &models.AgentIPHistory{
+		ID:        uuid.NewString(),
+		AgentID:   agentID,
+		Family:    family,
+		IP:        ip,
+		PrevIP:    prevIP,
+		ChangedAt: changedAt,
+	}
+	if err := s.AgentIPHistoryRepo.Create(ctx, history); err != nil {
+		s.logger.Error("记录公网IP变更历史失败",
+			zap.String("agentId", agentID),
+			zap.String("family", family),
+			zap.Error(err))
+		return
+	}
+
+	s.logger.Info("探针公网IP变更",
+		zap.String("agentId", agentID),
+		zap.String("family", family),
+		zap.String("prevIp", prevIP),
+		zap.String("ip", ip))
+}
+
+// GetIPHistory 获取探针公网 IP 变更历史（按时间倒序）
+func (s *AgentService) GetIPHistory(ctx context.Context, agentID string, limit int) ([]models.AgentIPHistory, error) {
+	if limit <= 0 {
+		limit = defaultIPHistoryLimit
+	}
+	return s.AgentIPHistoryRepo.FindByAgentID(ctx, agentID, limit)
+}
+
+// classifyIP 解析 IP 并返回地址族，无效地址返回空
+func classifyIP(raw string) (string, string) {
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if ip == nil {
+		return "", ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return models.IPFamilyV4, v4.String()
+	}
+	return models.IPFamilyV6, ip.String()
+}
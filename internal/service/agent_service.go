@@ -6,44 +6,66 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dushixiang/pika/internal/config"
 	"github.com/dushixiang/pika/internal/models"
 	"github.com/dushixiang/pika/internal/protocol"
 	"github.com/dushixiang/pika/internal/repo"
 	"github.com/go-orz/orz"
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 type AgentService struct {
 	logger *zap.Logger
 	*orz.Service
-	AgentRepo         *repo.AgentRepo
-	TamperEventRepo   *repo.TamperEventRepo
-	SSHLoginEventRepo *repo.SSHLoginEventRepo
-	apiKeyService     *ApiKeyService
-	metricService     *MetricService
-	geoipService      *GeoIPService
+	AgentRepo                *repo.AgentRepo
+	TamperEventRepo          *repo.TamperEventRepo
+	SSHLoginEventRepo        *repo.SSHLoginEventRepo
+	AgentCollisionRepo       *repo.AgentCollisionRepo
+	AgentConnectionEventRepo *repo.AgentConnectionEventRepo
+	AgentIPHistoryRepo       *repo.AgentIPHistoryRepo
+	AnnotationRepo           *repo.AnnotationRepo
+	AgentKeyRepo             *repo.AgentKeyRepo
+	AlertRecordRepo          *repo.AlertRecordRepo
+	apiKeyService            *ApiKeyService
+	metricService            *MetricService
+	notificationService      *NotificationService
+	geoipService             *GeoIPService
+	agentConfig              *config.AgentConfig
 }
 
-func NewAgentService(logger *zap.Logger, db *gorm.DB, apiKeyService *ApiKeyService, metricService *MetricService, geoipService *GeoIPService) *AgentService {
+func NewAgentService(logger *zap.Logger, db *gorm.DB, apiKeyService *ApiKeyService, metricService *MetricService, notificationService *NotificationService, geoipService *GeoIPService, appConfig *config.AppConfig) *AgentService {
 	return &AgentService{
-		logger:            logger,
-		Service:           orz.NewService(db),
-		AgentRepo:         repo.NewAgentRepo(db),
-		TamperEventRepo:   repo.NewTamperEventRepo(db),
-		SSHLoginEventRepo: repo.NewSSHLoginEventRepo(db),
-		apiKeyService:     apiKeyService,
-		metricService:     metricService,
-		geoipService:      geoipService,
+		logger:                   logger,
+		Service:                  orz.NewService(db),
+		AgentRepo:                repo.NewAgentRepo(db),
+		TamperEventRepo:          repo.NewTamperEventRepo(db),
+		SSHLoginEventRepo:        repo.NewSSHLoginEventRepo(db),
+		AgentCollisionRepo:       repo.NewAgentCollisionRepo(db),
+		AgentConnectionEventRepo: repo.NewAgentConnectionEventRepo(db),
+		AgentIPHistoryRepo:       repo.NewAgentIPHistoryRepo(db),
+		AnnotationRepo:           repo.NewAnnotationRepo(db),
+		AgentKeyRepo:             repo.NewAgentKeyRepo(db),
+		AlertRecordRepo:          repo.NewAlertRecordRepo(db),
+		apiKeyService:            apiKeyService,
+		metricService:            metricService,
+		notificationService:      notificationService,
+		geoipService:             geoipService,
+		agentConfig:              appConfig.Agent,
 	}
 }
 
 // RegisterAgent 注册探针
-func (s *AgentService) RegisterAgent(ctx context.Context, ip string, info *protocol.AgentInfo, apiKey string) (*models.Agent, error) {
+// sig 为探针密钥对注册信息的签名，未启用签名注册的探针为 nil；challenge 为本次连接下发的签名挑战随机数
+func (s *AgentService) RegisterAgent(ctx context.Context, ip string, info *protocol.AgentInfo, apiKey string, sig *protocol.RegisterSignature, challenge string) (*models.Agent, error) {
 	// 验证API密钥
-	if _, err := s.apiKeyService.ValidateApiKey(ctx, apiKey); err != nil {
+	key, err := s.apiKeyService.ValidateApiKey(ctx, apiKey)
+	if err != nil {
 		s.logger.Warn("agent registration failed: invalid api key",
 			zap.String("agentID", info.ID),
 			zap.String("hostname", info.Hostname),
@@ -56,17 +78,29 @@ func (s *AgentService) RegisterAgent(ctx context.Context, ip string, info *proto
 		return nil, fmt.Errorf("agent ID 不能为空")
 	}
 
+	// 校验注册签名，防止泄露的 API 密钥被用于冒充已绑定公钥的探针
+	if err := s.verifyRegistration(ctx, ip, info, sig, challenge); err != nil {
+		return nil, err
+	}
+
 	// 使用探针的持久化 ID 来识别同一个探针
 	// 这样即使主机名变化，也能正确识别
 	existingAgent, err := s.AgentRepo.FindById(ctx, info.ID)
 	if err == nil {
+		// 检测克隆机器导致的ID冲突
+		if err := s.checkCollision(ctx, &existingAgent, ip, info); err != nil {
+			return nil, err
+		}
+
 		// 更新现有探针信息（允许主机名、名称等变化）
 		now := time.Now().UnixMilli()
 		existingAgent.Hostname = info.Hostname
+		existingAgent.MAC = info.MAC
 		existingAgent.IP = ip
 		existingAgent.OS = info.OS
 		existingAgent.Arch = info.Arch
 		existingAgent.Version = info.Version
+		existingAgent.ReportedAttributes = datatypes.NewJSONType(s.reportedAttributes(info))
 		existingAgent.Status = 1
 		existingAgent.LastSeenAt = now
 		existingAgent.UpdatedAt = now
@@ -86,17 +120,20 @@ func (s *AgentService) RegisterAgent(ctx context.Context, ip string, info *proto
 	// 创建新探针（使用客户端提供的持久化 ID）
 	now := time.Now().UnixMilli()
 	agent := &models.Agent{
-		ID:         info.ID, // 使用客户端持久化的 ID
-		Name:       info.Name,
-		Hostname:   info.Hostname,
-		IP:         ip,
-		OS:         info.OS,
-		Arch:       info.Arch,
-		Version:    info.Version,
-		Status:     1,
-		LastSeenAt: now,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		ID:                 info.ID, // 使用客户端持久化的 ID
+		Name:               info.Name,
+		Hostname:           info.Hostname,
+		MAC:                info.MAC,
+		IP:                 ip,
+		OS:                 info.OS,
+		Arch:               info.Arch,
+		Version:            info.Version,
+		Tags:               key.Tags, // 预分配安装密钥上的标签
+		ReportedAttributes: datatypes.NewJSONType(s.reportedAttributes(info)),
+		Status:             1,
+		LastSeenAt:         now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
 	}
 
 	if err := s.AgentRepo.Create(ctx, agent); err != nil {
@@ -112,19 +149,30 @@ func (s *AgentService) RegisterAgent(ctx context.Context, ip string, info *proto
 	return agent, nil
 }
 
+// reportedAttributes 规范化探针上报的属性，不合法时忽略并记录日志
+func (s *AgentService) reportedAttributes(info *protocol.AgentInfo) map[string]string {
+	attributes, err := NormalizeAttributes(info.Attributes, false)
+	if err != nil {
+		s.logger.Warn("ignore invalid agent attributes",
+			zap.String("agentID", info.ID),
+			zap.Error(err))
+		return map[string]string{}
+	}
+	return attributes
+}
+
 // UpdateAgentStatus 更新探针状态
 func (s *AgentService) UpdateAgentStatus(ctx context.Context, agentID string, status int) error {
 	return s.AgentRepo.UpdateStatus(ctx, agentID, status, time.Now().UnixMilli())
 }
 
-// UpdatePublicIP 更新探针的公网 IP 信息
-func (s *AgentService) UpdatePublicIP(ctx context.Context, agentID string, ipv4 string, ipv6 string) error {
-	updates := map[string]interface{}{
-		"updated_at": time.Now().UnixMilli(),
-		"ipv4":       ipv4,
-		"ipv6":       ipv6,
-	}
-	return s.AgentRepo.UpdateColumnsById(ctx, agentID, updates)
+// UpdateAgentHealth 处理携带运行状况的心跳，在更新在线状态的同时保存运行状况
+func (s *AgentService) UpdateAgentHealth(ctx context.Context, agentID string, health protocol.AgentHealth) error {
+	now := time.Now().UnixMilli()
+	return s.AgentRepo.UpdateHealth(ctx, agentID, 1, now, models.AgentHealthData{
+		AgentHealth: health,
+		ReportedAt:  now,
+	})
 }
 
 // GetAgent 获取探针信息
@@ -141,6 +189,35 @@ func (s *AgentService) ListAgents(ctx context.Context) ([]models.Agent, error) {
 	return s.AgentRepo.FindAll(ctx)
 }
 
+// AgentPageQuery 探针分页查询条件，字段为空时不过滤
+type AgentPageQuery struct {
+	Status  string // 状态: online 在线, offline 离线
+	Tags    string // 标签，多个以逗号分隔，需同时包含
+	Group   string // 分组
+	Keyword string // 名称、主机名或 IP 的子串
+}
+
+// ListAgentsPaged 分页查询探针，需要全部探针的内部调用使用 ListAgents
+func (s *AgentService) ListAgentsPaged(ctx context.Context, pr *orz.PageRequest, query AgentPageQuery) (*orz.PageResult[models.Agent], error) {
+	builder := orz.NewPageBuilder(s.AgentRepo.Repository).
+		PageRequest(pr).
+		Tags("tags", strings.TrimSpace(query.Tags)).
+		Keyword([]string{"name", "hostname", "ip", "ipv4", "ipv6"}, strings.TrimSpace(query.Keyword))
+
+	if group := strings.TrimSpace(query.Group); group != "" {
+		builder = builder.Equal("agent_group", group)
+	}
+
+	switch query.Status {
+	case "online":
+		builder = builder.Equal("status", 1)
+	case "offline":
+		builder = builder.Equal("status", 0)
+	}
+
+	return builder.Execute(ctx)
+}
+
 // ListOnlineAgents 列出所有在线探针
 func (s *AgentService) ListOnlineAgents(ctx context.Context) ([]models.Agent, error) {
 	return s.AgentRepo.FindOnlineAgents(ctx)
@@ -216,10 +293,19 @@ func (s *AgentService) SaveAuditResult(ctx context.Context, agentID string, resu
 		return err
 	}
 
+	// 服务端安全分析，与原始结果一同存储
+	analysis := analyzeVPSAudit(result)
+	analysisJSON, err := json.Marshal(analysis)
+	if err != nil {
+		return err
+	}
+
 	auditRecord := &models.AuditResult{
 		AgentID:   agentID,
 		Type:      "vps_audit",
 		Result:    string(resultJSON),
+		Analysis:  string(analysisJSON),
+		RiskScore: analysis.RiskScore,
 		StartTime: result.StartTime,
 		EndTime:   result.EndTime,
 		CreatedAt: time.Now().UnixMilli(),
@@ -233,6 +319,7 @@ func (s *AgentService) SaveAuditResult(ctx context.Context, agentID string, resu
 	s.logger.Info("审计结果保存成功",
 		zap.String("agentId", agentID),
 		zap.Int64("auditId", auditRecord.ID),
+		zap.Int("riskScore", analysis.RiskScore),
 	)
 
 	return nil
@@ -299,11 +386,11 @@ func (s *AgentService) GetAuditResult(ctx context.Context, agentID string) (*pro
 	return &result, nil
 }
 
-// ListAuditResults 获取审计结果列表
-func (s *AgentService) ListAuditResults(ctx context.Context, agentID string) ([]map[string]interface{}, error) {
-	records, err := s.AgentRepo.ListAuditResults(ctx, agentID)
+// ListAuditResults 分页获取审计结果列表，返回当前页的审计摘要和总数
+func (s *AgentService) ListAuditResults(ctx context.Context, agentID string, pr *orz.PageRequest) ([]map[string]interface{}, int64, error) {
+	records, total, err := s.AgentRepo.ListAuditResults(ctx, agentID, pr)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	results := make([]map[string]interface{}, 0, len(records))
@@ -314,23 +401,72 @@ func (s *AgentService) ListAuditResults(ctx context.Context, agentID string) ([]
 			continue
 		}
 
-		// TODO: 统计安全检查结果应该来自 Server 端分析后的 VPSAuditAnalysis
-		// Agent 端已经不再产生 SecurityChecks,需要实现 Server 端分析逻辑
+		analysis, err := s.loadAuditAnalysis(ctx, &record, &auditResult)
+		if err != nil {
+			s.logger.Error("加载审计分析结果失败", zap.Int64("auditId", record.ID), zap.Error(err))
+			continue
+		}
+		passCount, failCount, warnCount, totalCount := countAuditChecks(analysis)
 
 		results = append(results, map[string]interface{}{
-			"id":          record.ID,
-			"agentId":     record.AgentID,
-			"type":        record.Type,
-			"startTime":   record.StartTime,
-			"endTime":     record.EndTime,
-			"createdAt":   record.CreatedAt,
-			"systemInfo":  auditResult.SystemInfo,
-			"statistics":  auditResult.Statistics,
-			"collectTime": auditResult.EndTime - auditResult.StartTime,
+			"id":            record.ID,
+			"agentId":       record.AgentID,
+			"type":          record.Type,
+			"startTime":     record.StartTime,
+			"endTime":       record.EndTime,
+			"createdAt":     record.CreatedAt,
+			"systemInfo":    auditResult.SystemInfo,
+			"statistics":    auditResult.Statistics,
+			"collectTime":   auditResult.EndTime - auditResult.StartTime,
+			"passCount":     passCount,
+			"failCount":     failCount,
+			"warnCount":     warnCount,
+			"totalCount":    totalCount,
+			"riskScore":     analysis.RiskScore,
+			"securityScore": 100 - analysis.RiskScore,
+			"threatLevel":   analysis.ThreatLevel,
 		})
 	}
 
-	return results, nil
+	return results, total, nil
+}
+
+// GetAuditAnalysis 获取指定审计结果的服务端安全分析
+func (s *AgentService) GetAuditAnalysis(ctx context.Context, agentID string, auditID int64) (*protocol.VPSAuditAnalysis, error) {
+	record, err := s.AgentRepo.FindAuditResult(ctx, agentID, auditID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, orz.NewError(404, "审计结果不存在")
+		}
+		return nil, err
+	}
+
+	var result protocol.VPSAuditResult
+	if err := json.Unmarshal([]byte(record.Result), &result); err != nil {
+		return nil, err
+	}
+	return s.loadAuditAnalysis(ctx, record, &result)
+}
+
+// loadAuditAnalysis 读取已存储的安全分析，历史记录没有分析结果时现场分析并回填
+func (s *AgentService) loadAuditAnalysis(ctx context.Context, record *models.AuditResult, result *protocol.VPSAuditResult) (*protocol.VPSAuditAnalysis, error) {
+	var analysis *protocol.VPSAuditAnalysis
+	if record.Analysis != "" {
+		if err := json.Unmarshal([]byte(record.Analysis), &analysis); err != nil {
+			return nil, err
+		}
+	} else {
+		analysis = analyzeVPSAudit(result)
+		analysisJSON, err := json.Marshal(analysis)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.AgentRepo.UpdateAuditAnalysis(ctx, record.ID, string(analysisJSON), analysis.RiskScore); err != nil {
+			s.logger.Warn("回填审计分析结果失败", zap.Int64("auditId", record.ID), zap.Error(err))
+		}
+	}
+	analysis.AuditID = strconv.FormatInt(record.ID, 10)
+	return analysis, nil
 }
 
 // GetStatistics 获取探针统计数据
@@ -357,7 +493,7 @@ func (s *AgentService) GetStatistics(ctx context.Context) (map[string]interface{
 // DeleteAgent 删除探针及其所有相关数据
 func (s *AgentService) DeleteAgent(ctx context.Context, agentID string) error {
 	// 在事务中执行所有删除操作
-	return s.Transaction(ctx, func(ctx context.Context) error {
+	err := s.Transaction(ctx, func(ctx context.Context) error {
 		// 1. 删除探针的审计结果
 		if err := s.AgentRepo.DeleteAuditResults(ctx, agentID); err != nil {
 			s.logger.Error("删除探针审计结果失败", zap.String("agentId", agentID), zap.Error(err))
@@ -376,7 +512,43 @@ func (s *AgentService) DeleteAgent(ctx context.Context, agentID string) error {
 			return err
 		}
 
-		// 4. 最后删除探针本身
+		// 4. 删除探针的连接事件
+		if err := s.AgentConnectionEventRepo.DeleteEventsByAgentID(ctx, agentID); err != nil {
+			s.logger.Error("删除探针连接事件失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+
+		// 5. 删除探针的磁盘预测结果
+		if err := s.metricService.DiskForecastRepo.DeleteByAgentID(ctx, agentID); err != nil {
+			s.logger.Error("删除探针磁盘预测结果失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+
+		// 6. 删除探针的公网 IP 变更历史
+		if err := s.AgentIPHistoryRepo.DeleteByAgentID(ctx, agentID); err != nil {
+			s.logger.Error("删除探针公网IP历史失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+
+		// 7. 删除探针的指标采集策略
+		if err := s.metricService.DeleteMetricPolicy(ctx, agentID); err != nil {
+			s.logger.Error("删除探针指标采集策略失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+
+		// 8. 删除探针的时间序列注释（全局注释保留）
+		if err := s.AnnotationRepo.DeleteByAgentID(ctx, agentID); err != nil {
+			s.logger.Error("删除探针注释失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+
+		// 9. 删除探针绑定的注册公钥
+		if err := s.AgentKeyRepo.DeleteById(ctx, agentID); err != nil {
+			s.logger.Error("删除探针注册公钥失败", zap.String("agentId", agentID), zap.Error(err))
+			return err
+		}
+
+		// 10. 最后删除探针本身
 		if err := s.AgentRepo.DeleteById(ctx, agentID); err != nil {
 			s.logger.Error("删除探针失败", zap.String("agentId", agentID), zap.Error(err))
 			return err
@@ -385,6 +557,14 @@ func (s *AgentService) DeleteAgent(ctx context.Context, agentID string) error {
 		s.logger.Info("探针删除成功", zap.String("agentId", agentID))
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// 清理内存中按探针记录的统计，避免删除的探针长期占用
+	s.metricService.ForgetAgent(agentID)
+	s.notificationService.ForgetAgent(agentID)
+	return nil
 }
 
 // ListByAuth 根据认证状态列出探针（已登录返回全部，未登录返回公开可见）
@@ -407,6 +587,33 @@ func (s *AgentService) GetAgentByAuth(ctx context.Context, id string, isAuthenti
 	return s.AgentRepo.FindPublicAgentByID(ctx, id)
 }
 
+// ListByShareScope 列出分享令牌范围内的探针（不区分可见性）
+func (s *AgentService) ListByShareScope(ctx context.Context, scope *ShareScope) ([]models.Agent, error) {
+	agents, err := s.AgentRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Agent, 0, len(agents))
+	for _, agent := range agents {
+		if scope.Allows(&agent) {
+			result = append(result, agent)
+		}
+	}
+	return result, nil
+}
+
+// GetAgentByShareScope 获取分享令牌范围内的探针，不在范围内时视为不存在
+func (s *AgentService) GetAgentByShareScope(ctx context.Context, id string, scope *ShareScope) (*models.Agent, error) {
+	agent, err := s.AgentRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Allows(&agent) {
+		return nil, orz.NewError(404, "探针不存在")
+	}
+	return &agent, nil
+}
+
 // GetAllTags 获取所有探针的标签
 func (s *AgentService) GetAllTags(ctx context.Context) ([]string, error) {
 	tags, err := s.AgentRepo.GetAllTags(ctx)
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// ackEscalationInterval 未确认告警升级的检查间隔
+	ackEscalationInterval = time.Minute
+
+	defaultEscalationAfterMinutes = 15
+)
+
+// applyAlertEscalationDefaults 填充升级策略的默认值
+func applyAlertEscalationDefaults(escalation *models.AlertEscalation) {
+	if escalation.MinLevel == "" {
+		escalation.MinLevel = models.AlertLevelCritical
+	}
+	if escalation.AfterMinutes <= 0 {
+		escalation.AfterMinutes = defaultEscalationAfterMinutes
+	}
+}
+
+// ValidateAlertEscalation 校验升级策略，启用时必须指定升级渠道
+func ValidateAlertEscalation(escalation *models.AlertEscalation) error {
+	if escalation.MinLevel != "" && levelRank(escalation.MinLevel) == 0 {
+		return fmt.Errorf("无效的升级告警级别: %s", escalation.MinLevel)
+	}
+	if escalation.AfterMinutes < 0 || escalation.RepeatMinutes < 0 {
+		return errors.New("升级时间不能为负数")
+	}
+	if escalation.Enabled && len(escalation.Channels) == 0 {
+		return errors.New("启用告警升级时必须指定升级通知渠道")
+	}
+	return nil
+}
+
+// escalationChannels 返回已启用且 ID 在升级渠道列表中的通知渠道，ID 未设置时为渠道类型
+func escalationChannels(channelConfigs []models.NotificationChannelConfig, ids []string) []models.NotificationChannelConfig {
+	var channels []models.NotificationChannelConfig
+	for _, channel := range channelConfigs {
+		if channel.Enabled && slices.Contains(ids, channel.ChannelID()) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// shouldEscalate 判断未确认的告警记录是否需要升级通知
+func shouldEscalate(escalation *models.AlertEscalation, record *models.AlertRecord, now int64) bool {
+	if levelRank(record.Level) < levelRank(escalation.MinLevel) {
+		return false
+	}
+	after := time.Duration(escalation.AfterMinutes) * time.Minute
+	if now-record.FiredAt < after.Milliseconds() {
+		return false
+	}
+	if record.EscalatedAt == 0 {
+		return true
+	}
+	repeat := time.Duration(escalation.RepeatMinutes) * time.Minute
+	return repeat > 0 && now-record.EscalatedAt >= repeat.Milliseconds()
+}
+
+// RunAckEscalation 启动未确认告警的升级检查
+func (s *AlertService) RunAckEscalation(ctx context.Context) {
+	ticker := time.NewTicker(ackEscalationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.EscalateUnackedAlerts(ctx, time.Now()); err != nil {
+				s.logger.Error("检查未确认告警升级失败", zap.Error(err))
+			}
+		}
+	}
+}
+
+// EscalateUnackedAlerts 对超过指定时间仍未确认的告警发送升级通知，返回升级的告警数量
+func (s *AlertService) EscalateUnackedAlerts(ctx context.Context, now time.Time) (int, error) {
+	config, err := s.propertyService.GetAlertConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	escalation := config.Escalation
+	if !config.Enabled || !escalation.Enabled || len(escalation.Channels) == 0 {
+		return 0, nil
+	}
+
+	nowMilli := now.UnixMilli()
+	firedBefore := now.Add(-time.Duration(escalation.AfterMinutes) * time.Minute).UnixMilli()
+	records, err := s.AlertRecordRepo.FindUnacked(ctx, firedBefore)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	channelConfigs, err := s.propertyService.GetNotificationChannelConfigs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	channels := escalationChannels(channelConfigs, escalation.Channels)
+	if len(channels) == 0 {
+		s.logger.Warn("告警升级渠道未配置或未启用", zap.Strings("channels", escalation.Channels))
+		return 0, nil
+	}
+
+	escalated := 0
+	for i := range records {
+		record := &records[i]
+		if !shouldEscalate(&escalation, record, nowMilli) {
+			continue
+		}
+		// 先按条件标记，告警在此期间被确认或恢复时不再通知
+		ok, err := s.AlertRecordRepo.MarkEscalated(ctx, record.ID, nowMilli)
+		if err != nil {
+			s.logger.Error("更新告警升级状态失败", zap.Int64("recordId", record.ID), zap.Error(err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		escalated++
+
+		agent, err := s.agentRepo.FindById(ctx, record.AgentID)
+		if err != nil {
+			agent = models.Agent{ID: record.AgentID, Name: record.AgentName}
+		}
+		unackedMinutes := (nowMilli - record.FiredAt) / time.Minute.Milliseconds()
+		s.logger.Warn("告警长时间未确认，发送升级通知",
+			zap.Int64("recordId", record.ID),
+			zap.String("agentId", record.AgentID),
+			zap.String("alertType", record.AlertType),
+			zap.Int64("unackedMinutes", unackedMinutes),
+			zap.Strings("channels", escalation.Channels))
+
+		notice := *record
+		notice.Message = fmt.Sprintf("【升级】告警已触发 %d 分钟未确认：%s", unackedMinutes, record.Message)
+		notifyCtx := utils.WithTraceID(ctx, record.TraceID)
+		if err := s.notifier.SendNotificationByConfigs(notifyCtx, channels, &notice, &agent, config.ResolveMaskIPMode()); err != nil {
+			s.logger.Error("发送告警升级通知失败", zap.Int64("recordId", record.ID), zap.Error(err), utils.TraceField(notifyCtx))
+		}
+	}
+	return escalated, nil
+}
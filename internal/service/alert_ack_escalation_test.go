@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestShouldEscalate(t *testing.T) {
+	escalation := models.AlertEscalation{Enabled: true, Channels: []string{"telegram"}}
+	applyAlertEscalationDefaults(&escalation)
+	if escalation.MinLevel != models.AlertLevelCritical || escalation.AfterMinutes != defaultEscalationAfterMinutes {
+		t.Fatalf("unexpected defaults: %+v", escalation)
+	}
+
+	now := time.Now().UnixMilli()
+	minute := time.Minute.Milliseconds()
+	record := models.AlertRecord{Level: models.AlertLevelCritical, FiredAt: now - 20*minute}
+	if !shouldEscalate(&escalation, &record, now) {
+		t.Fatalf("critical alert unacked for 20 minutes should escalate")
+	}
+
+	recent := models.AlertRecord{Level: models.AlertLevelCritical, FiredAt: now - 5*minute}
+	if shouldEscalate(&escalation, &recent, now) {
+		t.Fatalf("alert fired 5 minutes ago should not escalate yet")
+	}
+
+	warning := models.AlertRecord{Level: models.AlertLevelWarning, FiredAt: now - 20*minute}
+	if shouldEscalate(&escalation, &warning, now) {
+		t.Fatalf("warning alert should not escalate with minLevel critical")
+	}
+
+	// 已升级过的告警默认只通知一次
+	record.EscalatedAt = now - 30*minute
+	if shouldEscalate(&escalation, &record, now) {
+		t.Fatalf("escalated alert should not repeat without repeatMinutes")
+	}
+	escalation.RepeatMinutes = 30
+	if !shouldEscalate(&escalation, &record, now) {
+		t.Fatalf("escalated alert should repeat after repeatMinutes")
+	}
+	record.EscalatedAt = now - 10*minute
+	if shouldEscalate(&escalation, &record, now) {
+		t.Fatalf("escalated alert should wait for repeatMinutes")
+	}
+}
+
+func TestValidateAlertEscalation(t *testing.T) {
+	if err := ValidateAlertEscalation(&models.AlertEscalation{Enabled: true}); err == nil {
+		t.Fatalf("expected error when enabled without channels")
+	}
+	if err := ValidateAlertEscalation(&models.AlertEscalation{MinLevel: "urgent"}); err == nil {
+		t.Fatalf("expected error for invalid level")
+	}
+	if err := ValidateAlertEscalation(&models.AlertEscalation{AfterMinutes: -1}); err == nil {
+		t.Fatalf("expected error for negative minutes")
+	}
+	if err := ValidateAlertEscalation(&models.AlertEscalation{Enabled: true, MinLevel: "warning", Channels: []string{"email"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEscalationChannels(t *testing.T) {
+	channels := []models.NotificationChannelConfig{
+		{Type: "telegram", Enabled: true},
+		{ID: "oncall", Type: "telegram", Enabled: true},
+		{ID: "disabled", Type: "telegram"},
+	}
+
+	// 同类型的多个渠道按 ID 只选择值班渠道
+	got := escalationChannels(channels, []string{"oncall", "disabled"})
+	if len(got) != 1 || got[0].ID != "oncall" {
+		t.Fatalf("unexpected channels: %+v", got)
+	}
+	// 未设置 ID 的渠道按类型匹配
+	got = escalationChannels(channels, []string{"telegram"})
+	if len(got) != 1 || got[0].ID != "" {
+		t.Fatalf("unexpected channels: %+v", got)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dushixiang/pika/internal/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AlertBatchResult 批量操作中单条告警的处理结果
+type AlertBatchResult struct {
+	ID      int64  `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// FindFiringAlertIDs 按探针和告警类型筛选告警中的记录ID
+func (s *AlertService) FindFiringAlertIDs(ctx context.Context, agentID, alertType string) ([]int64, error) {
+	return s.AlertRecordRepo.FindFiringIDs(ctx, agentID, alertType)
+}
+
+// AckAlerts 批量确认告警，已确认的告警重复确认视为成功
+func (s *AlertService) AckAlerts(ctx context.Context, ids []int64, user string) []AlertBatchResult {
+	results := make([]AlertBatchResult, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, s.batchResult(id, s.ackAlert(ctx, id, user)))
+	}
+	return results
+}
+
+func (s *AlertService) ackAlert(ctx context.Context, id int64, user string) error {
+	record, err := s.AlertRecordRepo.GetAlertRecordByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if record.Status != "firing" {
+		return errAlertNotFiring
+	}
+	if record.AckedAt > 0 {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	record.AckedAt = now
+	record.AckedBy = user
+	record.UpdatedAt = now
+	return s.AlertRecordRepo.UpdateAlertRecord(ctx, record)
+}
+
+// ResolveAlerts 批量手动恢复告警，user 为操作人
+// 对应的告警状态保持触发中但解除与记录的关联，指标恢复正常前不会重复触发，恢复正常后再次超过阈值才会产生新告警
+func (s *AlertService) ResolveAlerts(ctx context.Context, ids []int64, user string) []AlertBatchResult {
+	results := make([]AlertBatchResult, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, s.batchResult(id, s.resolveAlertManually(ctx, id, user)))
+	}
+	return results
+}
+
+func (s *AlertService) resolveAlertManually(ctx context.Context, id int64, user string) error {
+	record, err := s.AlertRecordRepo.GetAlertRecordByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if record.Status != "firing" {
+		return errAlertNotFiring
+	}
+
+	now := time.Now().UnixMilli()
+	record.Status = "resolved"
+	record.ResolvedAt = now
+	record.ResolvedBy = user
+	record.TraceID = utils.TraceIDFromContext(ctx)
+	record.UpdatedAt = now
+	if err := s.AlertRecordRepo.UpdateAlertRecord(ctx, record); err != nil {
+		return err
+	}
+
+	s.logger.Info("手动恢复告警",
+		zap.Int64("recordId", id),
+		zap.String("agentId", record.AgentID),
+		zap.String("alertType", record.AlertType),
+		zap.String("user", user),
+	)
+
+	state, err := s.AlertStateRepo.FindByLastRecordID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	state.LastRecordID = 0
+	return s.AlertStateRepo.SaveAlertState(ctx, state)
+}
+
+var errAlertNotFiring = errors.New("告警不在告警中状态")
+
+// batchResult 将单条处理错误转换为批量结果
+func (s *AlertService) batchResult(id int64, err error) AlertBatchResult {
+	if err == nil {
+		return AlertBatchResult{ID: id, Success: true}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return AlertBatchResult{ID: id, Error: "告警记录不存在"}
+	}
+	if !errors.Is(err, errAlertNotFiring) {
+		s.logger.Error("批量处理告警失败", zap.Int64("recordId", id), zap.Error(err))
+	}
+	return AlertBatchResult{ID: id, Error: err.Error()}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+const (
+	// defaultCollectorErrorDuration 默认采集异常持续时间（秒）
+	defaultCollectorErrorDuration = 600
+	// collectorHealthStaleAfter 运行状况超过该时间未更新时视为无效，心跳默认 30 秒一次
+	collectorHealthStaleAfter = 5 * time.Minute
+)
+
+// collectorErrorDuration 采集异常告警的持续时间，未配置时使用默认值
+func collectorErrorDuration(rules *models.AlertRules) int {
+	if rules.CollectorErrorDuration <= 0 {
+		return defaultCollectorErrorDuration
+	}
+	return rules.CollectorErrorDuration
+}
+
+// checkCollectorErrorAlerts 按探针心跳上报的运行状况检查采集异常告警
+func (s *AlertService) checkCollectorErrorAlerts(ctx context.Context, config *models.AlertConfig, now int64) error {
+	agents, err := s.agentRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	duration := collectorErrorDuration(&config.Rules)
+	for i := range agents {
+		agent := &agents[i]
+		// 旧版本探针不上报运行状况
+		if agent.Health.Data().ReportedAt == 0 {
+			continue
+		}
+		// 与资源类告警一致，匹配模板绑定的探针按绑定的条件确定作用范围
+		agentConfig, _, _ := s.resolveAgentAlertConfig(ctx, config, agent)
+		if !agent.MatchAttributes(agentConfig.Rules.AgentAttributes) {
+			continue
+		}
+
+		failing, breached := collectorErrorBreached(agent, now)
+		var level string
+		if breached {
+			level = models.AlertLevelWarning
+		}
+		s.evaluateAlert(ctx, agentConfig, agent, "collector_error", failing, 1, duration, breached, level, now)
+	}
+	return nil
+}
+
+// collectorErrorBreached 返回最近一轮采集失败的指标数，以及探针当前是否处于采集异常
+// 离线或运行状况过期的探针不视为采集异常，离线由探针离线告警处理
+func collectorErrorBreached(agent *models.Agent, now int64) (float64, bool) {
+	health := agent.Health.Data()
+	if agent.Status != 1 || now-health.ReportedAt > collectorHealthStaleAfter.Milliseconds() {
+		return 0, false
+	}
+	failing := float64(len(health.FailingCollectors))
+	return failing, failing > 0
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"gorm.io/datatypes"
+)
+
+func TestCollectorErrorBreached(t *testing.T) {
+	now := int64(10 * 60 * 1000)
+	agent := &models.Agent{
+		Status: 1,
+		Health: datatypes.NewJSONType(models.AgentHealthData{
+			AgentHealth: protocol.AgentHealth{FailingCollectors: []string{"disk", "disk_io"}},
+			ReportedAt:  now - 30*1000,
+		}),
+	}
+
+	if failing, breached := collectorErrorBreached(agent, now); !breached || failing != 2 {
+		t.Fatalf("最近一轮有 2 项采集失败，得到 failing=%v breached=%v", failing, breached)
+	}
+
+	// 运行状况过期后不再视为采集异常
+	if _, breached := collectorErrorBreached(agent, now+collectorHealthStaleAfter.Milliseconds()); breached {
+		t.Fatal("运行状况过期时不应视为采集异常")
+	}
+
+	agent.Status = 0
+	if _, breached := collectorErrorBreached(agent, now); breached {
+		t.Fatal("离线探针不应视为采集异常")
+	}
+
+	agent.Status = 1
+	agent.Health = datatypes.NewJSONType(models.AgentHealthData{ReportedAt: now})
+	if _, breached := collectorErrorBreached(agent, now); breached {
+		t.Fatal("最近一轮全部成功时不应视为采集异常")
+	}
+}
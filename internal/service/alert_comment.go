@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+// maxAlertCommentLength 单条告警备注的最大长度（字符）
+const maxAlertCommentLength = 2000
+
+var (
+	ErrAlertCommentEmpty   = errors.New("备注内容不能为空")
+	ErrAlertCommentTooLong = errors.New("备注内容不能超过2000个字符")
+)
+
+// AddComment 为告警记录添加备注，告警记录不存在时返回 gorm.ErrRecordNotFound
+func (s *AlertService) AddComment(ctx context.Context, alertRecordID int64, username, text string) (*models.AlertComment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrAlertCommentEmpty
+	}
+	if utf8.RuneCountInString(text) > maxAlertCommentLength {
+		return nil, ErrAlertCommentTooLong
+	}
+	if _, err := s.AlertRecordRepo.GetAlertRecordByID(ctx, alertRecordID); err != nil {
+		return nil, err
+	}
+
+	comment := &models.AlertComment{
+		AlertRecordID: alertRecordID,
+		Username:      username,
+		Text:          text,
+		CreatedAt:     time.Now().UnixMilli(),
+	}
+	if err := s.AlertCommentRepo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// ListComments 按时间顺序列出告警记录的备注，告警记录不存在时返回 gorm.ErrRecordNotFound
+func (s *AlertService) ListComments(ctx context.Context, alertRecordID int64) ([]models.AlertComment, error) {
+	if _, err := s.AlertRecordRepo.GetAlertRecordByID(ctx, alertRecordID); err != nil {
+		return nil, err
+	}
+	return s.AlertCommentRepo.ListByAlertRecordID(ctx, alertRecordID)
+}
+
+// FillCommentCounts 填充告警记录的备注数量
+func (s *AlertService) FillCommentCounts(ctx context.Context, records []models.AlertRecord) error {
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	counts, err := s.AlertCommentRepo.CountByAlertRecordIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].CommentCount = counts[records[i].ID]
+	}
+	return nil
+}
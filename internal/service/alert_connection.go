@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// connectionAlertTypePrefix 连接状态告警类型前缀，完整类型如 connection_closeWait
+const connectionAlertTypePrefix = "connection_"
+
+// connectionStateLabels 连接状态及其展示名称
+var connectionStateLabels = map[string]string{
+	"established": "ESTABLISHED",
+	"synSent":     "SYN_SENT",
+	"synRecv":     "SYN_RECV",
+	"finWait1":    "FIN_WAIT1",
+	"finWait2":    "FIN_WAIT2",
+	"timeWait":    "TIME_WAIT",
+	"close":       "CLOSE",
+	"closeWait":   "CLOSE_WAIT",
+	"lastAck":     "LAST_ACK",
+	"listen":      "LISTEN",
+	"closing":     "CLOSING",
+}
+
+// isConnectionAlertType 是否为连接数告警（总连接数或某一连接状态）
+func isConnectionAlertType(alertType string) bool {
+	return alertType == "connection" || strings.HasPrefix(alertType, connectionAlertTypePrefix)
+}
+
+// connectionStateCount 获取指定状态的连接数
+func connectionStateCount(data *protocol.NetworkConnectionData, state string) (uint32, bool) {
+	switch state {
+	case "established":
+		return data.Established, true
+	case "synSent":
+		return data.SynSent, true
+	case "synRecv":
+		return data.SynRecv, true
+	case "finWait1":
+		return data.FinWait1, true
+	case "finWait2":
+		return data.FinWait2, true
+	case "timeWait":
+		return data.TimeWait, true
+	case "close":
+		return data.Close, true
+	case "closeWait":
+		return data.CloseWait, true
+	case "lastAck":
+		return data.LastAck, true
+	case "listen":
+		return data.Listen, true
+	case "closing":
+		return data.Closing, true
+	default:
+		return 0, false
+	}
+}
+
+// checkConnectionAlerts 检查总连接数及各状态连接数告警
+func (s *AlertService) checkConnectionAlerts(ctx context.Context, config *models.AlertConfig, agent *models.Agent, data *protocol.NetworkConnectionData, now int64) {
+	rules := config.Rules
+	if rules.ConnectionThreshold > 0 {
+		s.checkAlert(ctx, config, agent, "connection", float64(data.Total), rules.ConnectionThreshold, rules.ConnectionDuration, connectionTiers(rules.ConnectionThreshold), now)
+	}
+
+	for _, rule := range rules.ConnectionStates {
+		count, ok := connectionStateCount(data, rule.State)
+		if !ok || rule.Threshold <= 0 {
+			continue
+		}
+		s.checkAlert(ctx, config, agent, connectionAlertTypePrefix+rule.State, float64(count), rule.Threshold, rules.ConnectionDuration, connectionTiers(rule.Threshold), now)
+	}
+}
+
+// connectionTiers 连接数没有百分比含义，不按超出幅度分级，超过阈值即为警告
+func connectionTiers(threshold float64) []models.SeverityTier {
+	return []models.SeverityTier{{Level: models.AlertLevelWarning, Threshold: threshold}}
+}
+
+// buildConnectionAlertMessage 构建连接数告警消息，消息中标明触发的连接状态
+func buildConnectionAlertMessage(state *models.AlertState) string {
+	stateName := "总"
+	if key, ok := strings.CutPrefix(state.AlertType, connectionAlertTypePrefix); ok {
+		stateName = connectionStateLabels[key]
+		if stateName == "" {
+			stateName = key
+		}
+		stateName += " 状态"
+	}
+	return fmt.Sprintf("%s连接数持续%d秒超过%.0f，当前值%.0f",
+		stateName,
+		state.Duration,
+		state.Threshold,
+		state.Value,
+	)
+}
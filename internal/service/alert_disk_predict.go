@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// diskPredictCheckInterval 磁盘将满预测的重新计算间隔
+	diskPredictCheckInterval = 5 * time.Minute
+	// defaultDiskPredictHorizon 默认预测告警窗口（小时）
+	defaultDiskPredictHorizon = 48
+)
+
+// checkDiskPredictAlerts 检查磁盘将满预测告警
+func (s *AlertService) checkDiskPredictAlerts(ctx context.Context, config *models.AlertConfig, now int64) error {
+	// 拟合使用 5 分钟步长，无需每轮检查都重新计算
+	if now-s.lastDiskPredictAt.Load() < diskPredictCheckInterval.Milliseconds() {
+		return nil
+	}
+	s.lastDiskPredictAt.Store(now)
+
+	horizon := config.Rules.DiskPredictHorizon
+	if horizon <= 0 {
+		horizon = defaultDiskPredictHorizon
+	}
+
+	agents, err := s.agentRepo.FindOnlineAgents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, agent := range agents {
+		forecasts, err := s.metricService.ForecastDiskUsage(ctx, agent.ID)
+		if err != nil {
+			continue
+		}
+
+		for _, forecast := range forecasts {
+			s.checkDiskPredictAlert(ctx, &agent, &forecast, horizon, now)
+		}
+	}
+
+	return nil
+}
+
+// checkDiskPredictAlert 根据单个挂载点的预测结果触发或恢复告警
+func (s *AlertService) checkDiskPredictAlert(ctx context.Context, agent *models.Agent, forecast *models.DiskForecast, horizon int, now int64) {
+	stateKey := fmt.Sprintf("%s:global:disk_predict:%s", agent.ID, forecast.MountPoint)
+
+	state, err := s.AlertStateRepo.GetAlertState(ctx, stateKey)
+	if err != nil {
+		state = &models.AlertState{
+			ID:        stateKey,
+			AgentID:   agent.ID,
+			AlertType: "disk_predict",
+		}
+	}
+
+	var hoursLeft float64
+	predicted := forecast.EstimatedFullAt > 0
+	if predicted {
+		hoursLeft = float64(forecast.EstimatedFullAt-now) / float64(time.Hour.Milliseconds())
+	}
+
+	state.AgentID = agent.ID
+	state.AlertType = "disk_predict"
+	state.Threshold = float64(horizon)
+	state.Value = hoursLeft
+	state.LastCheckTime = now
+
+	shouldFire := predicted && hoursLeft <= float64(horizon) && !state.IsFiring
+	shouldResolve := state.IsFiring && (!predicted || hoursLeft > float64(horizon))
+
+	if shouldFire {
+		state.IsFiring = true
+	}
+	if err := s.AlertStateRepo.SaveAlertState(ctx, state); err != nil {
+		s.logger.Error("保存告警状态失败", zap.Error(err))
+	}
+
+	if shouldFire {
+		s.fireDiskPredictAlert(ctx, agent, forecast, state, now)
+	}
+	if shouldResolve {
+		s.resolveAlert(ctx, nil, agent, state)
+	}
+}
+
+// fireDiskPredictAlert 触发磁盘将满预测告警
+func (s *AlertService) fireDiskPredictAlert(ctx context.Context, agent *models.Agent, forecast *models.DiskForecast, state *models.AlertState, now int64) {
+	s.logger.Info("触发磁盘将满预测告警",
+		zap.String("agentId", agent.ID),
+		zap.String("mountPoint", forecast.MountPoint),
+		zap.Float64("usagePercent", forecast.UsagePercent),
+		zap.Float64("slope", forecast.Slope),
+		zap.Float64("hoursLeft", state.Value),
+	)
+
+	level := models.AlertLevelWarning
+	if state.Value <= state.Threshold/4 {
+		level = models.AlertLevelCritical
+	}
+
+	record := &models.AlertRecord{
+		AgentID:   agent.ID,
+		AgentName: agent.Name,
+		AlertType: "disk_predict",
+		Message: fmt.Sprintf("挂载点 %s 当前使用率%.2f%%，按每小时增长%.2f%%估算，预计于 %s 写满（约%.1f小时后）",
+			forecast.MountPoint,
+			forecast.UsagePercent,
+			forecast.Slope,
+			utils.FormatTimestamp(forecast.EstimatedFullAt),
+			state.Value,
+		),
+		Threshold:   state.Threshold,
+		ActualValue: state.Value,
+		Level:       level,
+		Status:      "firing",
+		FiredAt:     now,
+		TraceID:     utils.TraceIDFromContext(ctx),
+		CreatedAt:   now,
+	}
+	s.applyAlertDependency(ctx, record)
+
+	if err := s.AlertRecordRepo.CreateAlertRecord(ctx, record); err != nil {
+		s.logger.Error("创建磁盘将满预测告警记录失败", zap.Error(err))
+		return
+	}
+
+	state.LastRecordID = record.ID
+	if err := s.AlertStateRepo.SaveAlertState(ctx, state); err != nil {
+		s.logger.Error("保存告警状态失败", zap.Error(err))
+	}
+
+	go s.sendAlertNotification(record, agent)
+}
@@ -0,0 +1,65 @@
+package service
+
+import "github.com/dushixiang/pika/internal/models"
+
+// defaultWindowRatio windowed 模式下默认的超阈值采样占比
+const defaultWindowRatio = 80
+
+// resolveDurationMode 返回生效的持续时间语义和窗口占比，未知的模式按 continuous 处理
+func resolveDurationMode(rules models.AlertRules) (string, float64) {
+	if rules.DurationMode != models.DurationModeWindowed {
+		return models.DurationModeContinuous, 0
+	}
+	ratio := rules.WindowRatio
+	if ratio <= 0 || ratio > 100 {
+		ratio = defaultWindowRatio
+	}
+	return models.DurationModeWindowed, ratio
+}
+
+// advanceAlertTimer 记录本次检查结果并返回告警条件是否成立
+//
+// continuous：超过阈值时从首次超过开始计时，持续时间达到 duration 时成立，任一采样未超过阈值即清零重新计时；
+// 短暂回落会让持续高负载的告警一直无法触发。
+//
+// windowed：保留最近 duration 秒内的采样，统计窗口已覆盖 duration 且超过阈值的采样占比达到 ratio 时成立，
+// 占比低于 ratio 时不成立（已触发的告警随之恢复）。短暂回落只降低占比，不会清零。
+// 采样中断超过一个窗口时重新开始统计，避免重连后的单个采样立即触发。
+func advanceAlertTimer(state *models.AlertState, mode string, ratio float64, breached bool, duration int, now int64) bool {
+	if mode != models.DurationModeWindowed || duration <= 0 {
+		state.Samples = nil
+		if !breached {
+			state.StartTime = 0
+			return false
+		}
+		if state.StartTime == 0 {
+			state.StartTime = now
+		}
+		return (now-state.StartTime)/1000 >= int64(duration)
+	}
+
+	window := int64(duration) * 1000
+	samples := state.Samples[:0]
+	for _, sample := range state.Samples {
+		if sample.Time > now-window && sample.Time <= now {
+			samples = append(samples, sample)
+		}
+	}
+	// StartTime 在 windowed 模式下表示开始统计的时间
+	if len(samples) == 0 || state.StartTime == 0 || state.StartTime > now {
+		state.StartTime = now
+	}
+	samples = append(samples, models.AlertSample{Time: now, Breached: breached})
+	state.Samples = samples
+
+	if now-state.StartTime < window {
+		return false
+	}
+	breachedCount := 0
+	for _, sample := range samples {
+		if sample.Breached {
+			breachedCount++
+		}
+	}
+	return float64(breachedCount)*100 >= ratio*float64(len(samples))
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+// flappingSeries 每 10 秒一个采样，每 6 个采样中有 1 个短暂回落到阈值以下
+func flappingSeries(n int) []bool {
+	series := make([]bool, n)
+	for i := range series {
+		series[i] = i%6 != 5
+	}
+	return series
+}
+
+// runAlertTimer 依次输入采样，返回首次满足告警条件的采样下标，未满足时返回 -1
+func runAlertTimer(mode string, ratio float64, duration int, series []bool) int {
+	state := &models.AlertState{}
+	for i, breached := range series {
+		if advanceAlertTimer(state, mode, ratio, breached, duration, 1_000_000+int64(i)*10_000) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestAlertTimerContinuousResetsOnDip(t *testing.T) {
+	// 持续 60 秒需要连续 7 个超过阈值的采样，每 6 个采样回落一次，永远无法触发
+	if got := runAlertTimer(models.DurationModeContinuous, 0, 60, flappingSeries(100)); got != -1 {
+		t.Fatalf("continuous mode fired at sample %d on a flapping series", got)
+	}
+
+	// 一直超过阈值时在持续时间达到后触发
+	steady := make([]bool, 20)
+	for i := range steady {
+		steady[i] = true
+	}
+	if got := runAlertTimer(models.DurationModeContinuous, 0, 60, steady); got != 6 {
+		t.Fatalf("continuous mode fired at sample %d, want 6", got)
+	}
+}
+
+func TestAlertTimerWindowedToleratesDips(t *testing.T) {
+	// 约 83% 的采样超过阈值，窗口覆盖 60 秒后触发
+	got := runAlertTimer(models.DurationModeWindowed, 80, 60, flappingSeries(100))
+	if got != 6 {
+		t.Fatalf("windowed mode fired at sample %d, want 6", got)
+	}
+
+	// 占比要求高于实际占比时不触发
+	if got := runAlertTimer(models.DurationModeWindowed, 90, 60, flappingSeries(100)); got != -1 {
+		t.Fatalf("windowed mode with 90%% ratio fired at sample %d", got)
+	}
+}
+
+func TestAlertTimerWindowedResolvesWhenRatioDrops(t *testing.T) {
+	state := &models.AlertState{}
+	now := int64(1_000_000)
+	step := func(breached bool) bool {
+		active := advanceAlertTimer(state, models.DurationModeWindowed, 80, breached, 60, now)
+		now += 10_000
+		return active
+	}
+
+	for i := 0; i < 7; i++ {
+		step(true)
+	}
+	// 一次回落不影响已成立的告警条件
+	if !step(false) {
+		t.Fatal("a single dip should not clear the windowed condition")
+	}
+	// 持续恢复后占比低于 80%，条件不再成立
+	active := true
+	for i := 0; i < 3; i++ {
+		active = step(false)
+	}
+	if active {
+		t.Fatal("windowed condition should clear after the ratio drops below the threshold")
+	}
+}
+
+func TestAlertTimerWindowedRestartsAfterGap(t *testing.T) {
+	state := &models.AlertState{}
+	for i := 0; i < 7; i++ {
+		advanceAlertTimer(state, models.DurationModeWindowed, 80, true, 60, 1_000_000+int64(i)*10_000)
+	}
+	// 采样中断超过一个窗口后，重连后的首个采样不应立即满足条件
+	if advanceAlertTimer(state, models.DurationModeWindowed, 80, true, 60, 1_600_000) {
+		t.Fatal("windowed mode should restart after a gap longer than the window")
+	}
+	if len(state.Samples) != 1 || state.StartTime != 1_600_000 {
+		t.Fatalf("unexpected state after gap: samples=%d start=%d", len(state.Samples), state.StartTime)
+	}
+}
+
+func TestResolveDurationMode(t *testing.T) {
+	if mode, _ := resolveDurationMode(models.AlertRules{}); mode != models.DurationModeContinuous {
+		t.Fatalf("default mode = %s", mode)
+	}
+	if mode, ratio := resolveDurationMode(models.AlertRules{DurationMode: models.DurationModeWindowed, WindowRatio: 150}); mode != models.DurationModeWindowed || ratio != defaultWindowRatio {
+		t.Fatalf("mode = %s, ratio = %v", mode, ratio)
+	}
+}
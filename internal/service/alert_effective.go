@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+// 生效配置的来源
+const (
+	ConfigSourceGlobal   = "global"   // 全局告警配置
+	ConfigSourceDefault  = "default"  // 未配置时使用的内置默认值
+	ConfigSourceAgent    = "agent"    // 运维在探针上设置的属性
+	ConfigSourceReported = "reported" // 探针上报的属性
+	ConfigSourceTemplate = "template" // 探针匹配的告警规则模板
+)
+
+// EffectiveValue 生效的配置值及其来源
+type EffectiveValue struct {
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// EffectiveScopeCondition 告警作用范围中单个属性条件的匹配结果
+type EffectiveScopeCondition struct {
+	Key      string `json:"key"`              // 属性名
+	Expected string `json:"expected"`         // 作用范围要求的值
+	Actual   string `json:"actual,omitempty"` // 探针当前生效的值
+	Source   string `json:"source,omitempty"` // 当前值来源: agent, reported，属性不存在时为空
+	Matched  bool   `json:"matched"`          // 是否匹配
+}
+
+// EffectiveAlertRule 单条告警规则对探针的生效结果
+type EffectiveAlertRule struct {
+	AlertType string                `json:"alertType"`           // 告警类型
+	Enabled   bool                  `json:"enabled"`             // 规则是否启用
+	Scoped    bool                  `json:"scoped"`              // 是否受作用范围限制（资源类告警）
+	Active    bool                  `json:"active"`              // 对该探针是否实际生效
+	Threshold *EffectiveValue       `json:"threshold,omitempty"` // 阈值
+	Duration  *EffectiveValue       `json:"duration,omitempty"`  // 持续时间（秒）
+	Tiers     []models.SeverityTier `json:"tiers,omitempty"`     // 分级阈值
+}
+
+// EffectiveThrottle 生效的通知限流配置
+type EffectiveThrottle struct {
+	MaxNotifications EffectiveValue `json:"maxNotifications"`
+	WindowMinutes    EffectiveValue `json:"windowMinutes"`
+}
+
+// EffectiveAlertConfig 探针合并后的生效告警配置
+type EffectiveAlertConfig struct {
+	AgentID      string                    `json:"agentId"`
+	Enabled      EffectiveValue            `json:"enabled"`               // 全局告警开关
+	InScope      bool                      `json:"inScope"`               // 是否在资源类告警的作用范围内
+	Scope        []EffectiveScopeCondition `json:"scope"`                 // 作用范围各条件的匹配结果，为空表示对所有探针生效
+	DurationMode EffectiveValue            `json:"durationMode"`          // 持续时间语义
+	WindowRatio  *EffectiveValue           `json:"windowRatio,omitempty"` // windowed 模式下的超阈值采样占比
+	Throttle     EffectiveThrottle         `json:"throttle"`              // 通知限流
+	Rules        []EffectiveAlertRule      `json:"rules"`                 // 各告警规则
+	Suppressed   bool                      `json:"suppressed"`            // 探针离线告警触发中，其余告警只记录不通知
+	States       []models.AlertState       `json:"states"`                // 当前告警状态
+	Template     *EffectiveAlertTemplate   `json:"template,omitempty"`    // 探针匹配的告警规则模板，未匹配时为空
+}
+
+// EffectiveAlertTemplate 探针匹配的告警规则模板
+type EffectiveAlertTemplate struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetEffectiveAlertConfig 解析探针实际生效的告警配置，并标注每个值的来源
+func (s *AlertService) GetEffectiveAlertConfig(ctx context.Context, agentID string) (*EffectiveAlertConfig, error) {
+	agent, err := s.agentRepo.FindById(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.propertyService.GetAlertConfig(ctx)
+	if err != nil {
+		s.logger.Error("获取全局告警配置失败", zap.Error(err))
+		return nil, err
+	}
+	states, err := s.AlertStateRepo.FindByAgentID(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	config, binding, template := s.resolveAgentAlertConfig(ctx, config, &agent)
+	result := buildEffectiveAlertConfig(config, &agent, states)
+	if template != nil {
+		applyEffectiveTemplate(result, &agent, binding, template)
+	}
+	return result, nil
+}
+
+// applyEffectiveTemplate 标注来自模板的规则，作用范围改为模板绑定的属性条件
+func applyEffectiveTemplate(result *EffectiveAlertConfig, agent *models.Agent, binding *models.AlertTemplateBinding, template *models.AlertRuleTemplate) {
+	result.Template = &EffectiveAlertTemplate{ID: template.ID, Name: template.Name}
+	result.Scope, _ = resolveAlertScope(agent, binding.AgentAttributes)
+	if result.DurationMode.Source == ConfigSourceGlobal {
+		result.DurationMode.Source = ConfigSourceTemplate
+	}
+	if result.WindowRatio != nil && result.WindowRatio.Source == ConfigSourceGlobal {
+		result.WindowRatio.Source = ConfigSourceTemplate
+	}
+	for i := range result.Rules {
+		rule := &result.Rules[i]
+		// 采集异常告警只有作用范围跟随模板绑定，持续时间仍使用全局配置
+		if !rule.Scoped || rule.AlertType == "collector_error" {
+			continue
+		}
+		for _, value := range []*EffectiveValue{rule.Threshold, rule.Duration} {
+			if value != nil && value.Source == ConfigSourceGlobal {
+				value.Source = ConfigSourceTemplate
+			}
+		}
+	}
+}
+
+// buildEffectiveAlertConfig 按告警检查时的规则合并全局配置、默认值和探针属性
+func buildEffectiveAlertConfig(config *models.AlertConfig, agent *models.Agent, states []models.AlertState) *EffectiveAlertConfig {
+	rules := config.Rules
+	scope, inScope := resolveAlertScope(agent, rules.AgentAttributes)
+
+	result := &EffectiveAlertConfig{
+		AgentID: agent.ID,
+		Enabled: EffectiveValue{Value: config.Enabled, Source: ConfigSourceGlobal},
+		InScope: inScope,
+		Scope:   scope,
+		States:  states,
+	}
+	if result.States == nil {
+		result.States = []models.AlertState{}
+	}
+
+	mode, ratio := resolveDurationMode(rules)
+	result.DurationMode = EffectiveValue{Value: mode, Source: sourceOf(rules.DurationMode == mode)}
+	if mode == models.DurationModeWindowed {
+		result.WindowRatio = &EffectiveValue{Value: ratio, Source: sourceOf(rules.WindowRatio == ratio)}
+	}
+
+	windowMinutes := config.Throttle.WindowMinutes
+	if windowMinutes <= 0 {
+		windowMinutes = defaultThrottleWindowMinutes
+	}
+	result.Throttle = EffectiveThrottle{
+		MaxNotifications: EffectiveValue{Value: max(config.Throttle.MaxNotifications, 0), Source: ConfigSourceGlobal},
+		WindowMinutes:    EffectiveValue{Value: windowMinutes, Source: sourceOf(config.Throttle.WindowMinutes == windowMinutes)},
+	}
+
+	for _, state := range states {
+		if state.AlertType == "agent_offline" && state.IsFiring {
+			result.Suppressed = true
+		}
+	}
+
+	add := func(rule EffectiveAlertRule) {
+		rule.Active = config.Enabled && rule.Enabled && (!rule.Scoped || inScope)
+		result.Rules = append(result.Rules, rule)
+	}
+	global := func(v any) *EffectiveValue {
+		return &EffectiveValue{Value: v, Source: ConfigSourceGlobal}
+	}
+
+	add(EffectiveAlertRule{AlertType: "cpu", Enabled: rules.CPUEnabled, Scoped: true,
+		Threshold: global(rules.CPUThreshold), Duration: global(rules.CPUDuration), Tiers: rules.CPUTiers})
+	if rules.MemoryThresholdMode == models.ThresholdModeFree {
+		add(EffectiveAlertRule{AlertType: "memory_free", Enabled: rules.MemoryEnabled, Scoped: true,
+			Threshold: global(rules.MemoryFreeThreshold), Duration: global(rules.MemoryDuration)})
+	} else {
+		add(EffectiveAlertRule{AlertType: "memory", Enabled: rules.MemoryEnabled, Scoped: true,
+			Threshold: global(rules.MemoryThreshold), Duration: global(rules.MemoryDuration), Tiers: rules.MemoryTiers})
+	}
+	if rules.DiskThresholdMode == models.ThresholdModeFree {
+		add(EffectiveAlertRule{AlertType: "disk_free", Enabled: rules.DiskEnabled, Scoped: true,
+			Threshold: global(rules.DiskFreeThreshold), Duration: global(rules.DiskDuration)})
+	} else {
+		add(EffectiveAlertRule{AlertType: "disk", Enabled: rules.DiskEnabled, Scoped: true,
+			Threshold: global(rules.DiskThreshold), Duration: global(rules.DiskDuration), Tiers: rules.DiskTiers})
+	}
+
+	horizon := rules.DiskPredictHorizon
+	if horizon <= 0 {
+		horizon = defaultDiskPredictHorizon
+	}
+	add(EffectiveAlertRule{AlertType: "disk_predict", Enabled: rules.DiskPredictEnabled,
+		Threshold: &EffectiveValue{Value: horizon, Source: sourceOf(rules.DiskPredictHorizon == horizon)}})
+
+	add(EffectiveAlertRule{AlertType: "network", Enabled: rules.NetworkEnabled, Scoped: true,
+		Threshold: global(rules.NetworkThreshold), Duration: global(rules.NetworkDuration), Tiers: rules.NetworkTiers})
+	add(EffectiveAlertRule{AlertType: "load", Enabled: rules.LoadEnabled, Scoped: true,
+		Threshold: global(rules.LoadThreshold), Duration: global(rules.LoadDuration)})
+
+	// 总连接数阈值为 0 时不检查总连接数，只检查各状态连接数
+	add(EffectiveAlertRule{AlertType: "connection", Enabled: rules.ConnectionEnabled && rules.ConnectionThreshold > 0, Scoped: true,
+		Threshold: global(rules.ConnectionThreshold), Duration: global(rules.ConnectionDuration), Tiers: connectionTiers(rules.ConnectionThreshold)})
+	for _, rule := range rules.ConnectionStates {
+		add(EffectiveAlertRule{AlertType: connectionAlertTypePrefix + rule.State, Enabled: rules.ConnectionEnabled && rule.Threshold > 0, Scoped: true,
+			Threshold: global(rule.Threshold), Duration: global(rules.ConnectionDuration), Tiers: connectionTiers(rule.Threshold)})
+	}
+
+	add(EffectiveAlertRule{AlertType: "cert", Enabled: rules.CertEnabled, Threshold: global(rules.CertThreshold)})
+	add(EffectiveAlertRule{AlertType: "service", Enabled: rules.ServiceEnabled, Duration: global(rules.ServiceDuration)})
+	add(EffectiveAlertRule{AlertType: "agent_offline", Enabled: rules.AgentOfflineEnabled, Duration: global(rules.AgentOfflineDuration)})
+	collectorDuration := collectorErrorDuration(&rules)
+	add(EffectiveAlertRule{AlertType: "collector_error", Enabled: rules.CollectorErrorEnabled, Scoped: true,
+		Duration: &EffectiveValue{Value: collectorDuration, Source: sourceOf(rules.CollectorErrorDuration == collectorDuration)}})
+
+	return result
+}
+
+// resolveAlertScope 逐个检查作用范围的属性条件，返回各条件的匹配结果和探针是否在范围内
+func resolveAlertScope(agent *models.Agent, selector map[string]string) ([]EffectiveScopeCondition, bool) {
+	conditions := make([]EffectiveScopeCondition, 0, len(selector))
+	if len(selector) == 0 {
+		return conditions, true
+	}
+
+	attributes := agent.EffectiveAttributes()
+	overrides := agent.Attributes.Data()
+	inScope := true
+	for key, expected := range selector {
+		cond := EffectiveScopeCondition{Key: key, Expected: expected}
+		if actual, ok := attributes[key]; ok {
+			cond.Actual = actual
+			cond.Source = ConfigSourceReported
+			if overrides[key] != "" {
+				cond.Source = ConfigSourceAgent
+			}
+		}
+		cond.Matched = cond.Actual == expected
+		if !cond.Matched {
+			inScope = false
+		}
+		conditions = append(conditions, cond)
+	}
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].Key < conditions[j].Key
+	})
+	return conditions, inScope
+}
+
+// sourceOf 配置值与生效值一致时来源为全局配置，否则为内置默认值
+func sourceOf(configured bool) string {
+	if configured {
+		return ConfigSourceGlobal
+	}
+	return ConfigSourceDefault
+}
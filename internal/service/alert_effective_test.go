@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+	"gorm.io/datatypes"
+)
+
+func findEffectiveRule(config *EffectiveAlertConfig, alertType string) *EffectiveAlertRule {
+	for i := range config.Rules {
+		if config.Rules[i].AlertType == alertType {
+			return &config.Rules[i]
+		}
+	}
+	return nil
+}
+
+func TestEffectiveAlertConfigScopeProvenance(t *testing.T) {
+	agent := &models.Agent{
+		ID:                 "a1",
+		ReportedAttributes: datatypes.NewJSONType(map[string]string{"env": "prod", "role": "db"}),
+		Attributes:         datatypes.NewJSONType(map[string]string{"role": "web"}),
+	}
+	config := &models.AlertConfig{
+		Enabled: true,
+		Rules: models.AlertRules{
+			CPUEnabled:      true,
+			CPUThreshold:    90,
+			CertEnabled:     true,
+			AgentAttributes: map[string]string{"env": "prod", "role": "db"},
+		},
+	}
+
+	effective := buildEffectiveAlertConfig(config, agent, nil)
+	if effective.InScope {
+		t.Fatal("agent with overridden role should be out of scope")
+	}
+	if len(effective.Scope) != 2 {
+		t.Fatalf("scope conditions = %d, want 2", len(effective.Scope))
+	}
+	env, role := effective.Scope[0], effective.Scope[1]
+	if !env.Matched || env.Source != ConfigSourceReported {
+		t.Fatalf("env condition = %+v, want matched from reported attributes", env)
+	}
+	if role.Matched || role.Actual != "web" || role.Source != ConfigSourceAgent {
+		t.Fatalf("role condition = %+v, want unmatched agent override", role)
+	}
+
+	// 资源类告警受作用范围限制，证书告警不受限制
+	if cpu := findEffectiveRule(effective, "cpu"); cpu == nil || cpu.Active {
+		t.Fatalf("cpu rule = %+v, want inactive", cpu)
+	}
+	if cert := findEffectiveRule(effective, "cert"); cert == nil || !cert.Active {
+		t.Fatalf("cert rule = %+v, want active", cert)
+	}
+}
+
+func TestEffectiveAlertConfigDefaults(t *testing.T) {
+	agent := &models.Agent{ID: "a1"}
+	config := &models.AlertConfig{
+		Enabled: true,
+		Rules: models.AlertRules{
+			MemoryEnabled:       true,
+			MemoryThresholdMode: models.ThresholdModeFree,
+			MemoryFreeThreshold: 1,
+			DurationMode:        models.DurationModeWindowed,
+		},
+		Throttle: models.NotificationThrottle{MaxNotifications: 5},
+	}
+	states := []models.AlertState{{ID: "a1:global:agent_offline:a1", AgentID: "a1", AlertType: "agent_offline", IsFiring: true}}
+
+	effective := buildEffectiveAlertConfig(config, agent, states)
+	if !effective.InScope || len(effective.Scope) != 0 {
+		t.Fatalf("empty selector should match all agents, got %+v", effective.Scope)
+	}
+	if effective.DurationMode.Value != models.DurationModeWindowed || effective.DurationMode.Source != ConfigSourceGlobal {
+		t.Fatalf("duration mode = %+v", effective.DurationMode)
+	}
+	if effective.WindowRatio == nil || effective.WindowRatio.Value != float64(defaultWindowRatio) || effective.WindowRatio.Source != ConfigSourceDefault {
+		t.Fatalf("window ratio = %+v, want default", effective.WindowRatio)
+	}
+	if effective.Throttle.WindowMinutes.Value != defaultThrottleWindowMinutes || effective.Throttle.WindowMinutes.Source != ConfigSourceDefault {
+		t.Fatalf("throttle window = %+v, want default", effective.Throttle.WindowMinutes)
+	}
+	if findEffectiveRule(effective, "memory") != nil || findEffectiveRule(effective, "memory_free") == nil {
+		t.Fatal("free threshold mode should resolve to the memory_free rule")
+	}
+	if !effective.Suppressed {
+		t.Fatal("firing agent_offline state should suppress notifications")
+	}
+}
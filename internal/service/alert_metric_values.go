@@ -0,0 +1,127 @@
+package service
+
+import (
+	"math"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// AlertMetricValues 从探针最新指标中提取的告警判断数值
+type AlertMetricValues struct {
+	CPU          float64 // CPU 使用率
+	Memory       float64 // 内存使用率
+	Disk         float64 // 磁盘使用率
+	NetworkSpeed float64 // 网速(MB/s)
+	MemoryFree   *uint64 // 剩余内存(字节)，未上报内存指标时为 nil
+	DiskFree     *uint64 // 磁盘剩余空间(字节)，未上报磁盘指标时为 nil
+	Cores        int     // 逻辑核心数
+	Connections  *protocol.NetworkConnectionData
+	Load         *protocol.LoadData
+}
+
+// NewAlertMetricValues 提取告警判断使用的数值
+// 运维指定了主磁盘、主网卡时只使用该设备，否则使用全部设备的汇总
+func NewAlertMetricValues(latest *metric.LatestMetrics) AlertMetricValues {
+	v := AlertMetricValues{
+		Connections: latest.NetworkConnection,
+		Load:        latest.Load,
+	}
+
+	if latest.CPU != nil {
+		v.CPU = latest.CPU.UsagePercent
+		v.Cores = latest.CPU.LogicalCores
+	}
+
+	if latest.Memory != nil {
+		v.Memory = latest.Memory.UsagePercent
+		// 优先使用可用内存（包含可回收的缓存），探针未上报时使用空闲内存
+		memoryFree := latest.Memory.Available
+		if memoryFree == 0 {
+			memoryFree = latest.Memory.Free
+		}
+		v.MemoryFree = &memoryFree
+	}
+
+	if latest.Disk != nil {
+		v.Disk = latest.Disk.UsagePercent
+		diskFree := latest.Disk.Free
+		if latest.Disk.PrimarySource == PrimarySourceConfigured {
+			v.Disk = latest.Disk.PrimaryUsagePercent
+			diskFree = latest.Disk.PrimaryFree
+		}
+		v.DiskFree = &diskFree
+	}
+
+	if latest.Network != nil {
+		// 网速 = (发送速率 + 接收速率) / 1024 / 1024 (转换为 MB/s)
+		v.NetworkSpeed = float64(latest.Network.TotalBytesSentRate+latest.Network.TotalBytesRecvRate) / 1024 / 1024
+		if latest.Network.PrimarySource == PrimarySourceConfigured {
+			v.NetworkSpeed = float64(latest.Network.PrimaryBytesSentRate+latest.Network.PrimaryBytesRecvRate) / 1024 / 1024
+		}
+	}
+	return v
+}
+
+// enabledThresholds 返回已启用的资源类告警规则及其阈值（连接数告警按状态分别判断，不在其中）
+func enabledThresholds(rules *models.AlertRules) map[string]float64 {
+	thresholds := make(map[string]float64)
+	if rules.CPUEnabled {
+		thresholds["cpu"] = rules.CPUThreshold
+	}
+	if rules.MemoryEnabled {
+		if rules.MemoryThresholdMode == models.ThresholdModeFree {
+			thresholds["memory_free"] = rules.MemoryFreeThreshold
+		} else {
+			thresholds["memory"] = rules.MemoryThreshold
+		}
+	}
+	if rules.DiskEnabled {
+		if rules.DiskThresholdMode == models.ThresholdModeFree {
+			thresholds["disk_free"] = rules.DiskFreeThreshold
+		} else {
+			thresholds["disk"] = rules.DiskThreshold
+		}
+	}
+	if rules.NetworkEnabled {
+		thresholds["network"] = rules.NetworkThreshold
+	}
+	if rules.LoadEnabled && rules.LoadThreshold > 0 {
+		thresholds["load"] = rules.LoadThreshold
+	}
+	return thresholds
+}
+
+// thresholdBreached 当前数值是否超过告警阈值，只比较瞬时值，不考虑持续时间
+func thresholdBreached(alertType string, threshold float64, v AlertMetricValues) bool {
+	switch alertType {
+	case "cpu":
+		return v.CPU >= threshold
+	case "memory":
+		return v.Memory >= threshold
+	case "memory_free":
+		// 未上报内存指标时剩余量未知，不能按 0 判断
+		if v.MemoryFree == nil {
+			return false
+		}
+		return bytesToGB(*v.MemoryFree) < threshold
+	case "disk":
+		return v.Disk >= threshold
+	case "disk_free":
+		if v.DiskFree == nil {
+			return false
+		}
+		return bytesToGB(*v.DiskFree) < threshold
+	case "network":
+		return v.NetworkSpeed >= threshold
+	case "load":
+		// 核心数未知时无法换算每核负载
+		if v.Load == nil || v.Cores <= 0 {
+			return false
+		}
+		return math.Round(v.Load.Load1/float64(v.Cores)*100)/100 >= threshold
+	default:
+		return false
+	}
+}
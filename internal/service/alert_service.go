@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"fmt"
+	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/dushixiang/pika/internal/models"
 	"github.com/dushixiang/pika/internal/protocol"
 	"github.com/dushixiang/pika/internal/repo"
+	"github.com/dushixiang/pika/internal/utils"
 	"github.com/go-orz/orz"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -15,26 +18,34 @@ import (
 
 // AlertService 告警服务
 type AlertService struct {
-	Service         *orz.Service
-	AlertRecordRepo *repo.AlertRecordRepo
-	AlertStateRepo  *repo.AlertStateRepo
-	agentRepo       *repo.AgentRepo
-	monitorService  *MonitorService
-	propertyService *PropertyService
-	notifier        *Notifier
-	logger          *zap.Logger
+	Service          *orz.Service
+	AlertRecordRepo  *repo.AlertRecordRepo
+	AlertStateRepo   *repo.AlertStateRepo
+	AlertCommentRepo *repo.AlertCommentRepo
+	agentRepo        *repo.AgentRepo
+	monitorService   *MonitorService
+	metricService    *MetricService
+	propertyService  *PropertyService
+	notifier         *Notifier
+	notificationSvc  *NotificationService
+	logger           *zap.Logger
+
+	lastDiskPredictAt atomic.Int64 // 上次计算磁盘将满预测的时间（毫秒），重置告警状态时清零
 }
 
-func NewAlertService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, monitorService *MonitorService, notifier *Notifier) *AlertService {
+func NewAlertService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, monitorService *MonitorService, metricService *MetricService, notifier *Notifier, notificationSvc *NotificationService) *AlertService {
 	return &AlertService{
-		Service:         orz.NewService(db),
-		AlertRecordRepo: repo.NewAlertRecordRepo(db),
-		AlertStateRepo:  repo.NewAlertStateRepo(db),
-		agentRepo:       repo.NewAgentRepo(db),
-		monitorService:  monitorService,
-		propertyService: propertyService,
-		notifier:        notifier,
-		logger:          logger,
+		Service:          orz.NewService(db),
+		AlertRecordRepo:  repo.NewAlertRecordRepo(db),
+		AlertStateRepo:   repo.NewAlertStateRepo(db),
+		AlertCommentRepo: repo.NewAlertCommentRepo(db),
+		agentRepo:        repo.NewAgentRepo(db),
+		monitorService:   monitorService,
+		metricService:    metricService,
+		propertyService:  propertyService,
+		notifier:         notifier,
+		notificationSvc:  notificationSvc,
+		logger:           logger,
 	}
 }
 
@@ -53,12 +64,55 @@ func (s *AlertService) Clear(ctx context.Context) error {
 			return err
 		}
 
+		// 清空告警备注
+		if err := s.AlertCommentRepo.Clear(ctx); err != nil {
+			s.logger.Error("清空告警备注失败", zap.Error(err))
+			return err
+		}
+
 		return nil
 	})
 }
 
+// ResetAlertStates 清除告警状态，下一轮检查时重新评估；agentID、alertType 为空时不按该条件过滤
+// 状态关联的告警中记录同时标记为由操作人手动恢复，否则这些记录失去状态后永远无法恢复，且持续超过阈值时会重复触发
+func (s *AlertService) ResetAlertStates(ctx context.Context, agentID, alertType, operator string) (int64, error) {
+	var count, resolved int64
+	err := s.Service.Transaction(ctx, func(ctx context.Context) error {
+		recordIDs, err := s.AlertStateRepo.FindLinkedRecordIDs(ctx, agentID, alertType)
+		if err != nil {
+			return err
+		}
+		if resolved, err = s.AlertRecordRepo.ResolveFiring(ctx, recordIDs, time.Now().UnixMilli(), operator); err != nil {
+			return err
+		}
+		count, err = s.AlertStateRepo.DeleteStates(ctx, agentID, alertType)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("重置告警状态失败", zap.Error(err))
+		return 0, err
+	}
+
+	// 磁盘将满预测有计算间隔，清零后下一轮立即重新计算
+	if alertType == "" || alertType == "disk_predict" {
+		s.lastDiskPredictAt.Store(0)
+	}
+
+	s.logger.Info("重置告警状态",
+		zap.String("operator", operator),
+		zap.String("agentId", agentID),
+		zap.String("alertType", alertType),
+		zap.Int64("count", count),
+		zap.Int64("resolvedRecords", resolved),
+	)
+	return count, nil
+}
+
 // CheckMetrics 检查指标并触发告警
-func (s *AlertService) CheckMetrics(ctx context.Context, agentID string, cpu, memory, disk, networkSpeed float64) error {
+// memoryFree、diskFree 为剩余内存和磁盘剩余空间（字节），用于剩余量模式的告警
+// load 为系统负载，cores 为逻辑核心数，用于按每核负载判断负载告警
+func (s *AlertService) CheckMetrics(ctx context.Context, agentID string, cpu, memory, disk, networkSpeed float64, memoryFree, diskFree *uint64, connections *protocol.NetworkConnectionData, load *protocol.LoadData, cores int) error {
 	// 获取全局告警配置
 	alertConfig, err := s.propertyService.GetAlertConfig(ctx)
 	if err != nil {
@@ -78,36 +132,97 @@ func (s *AlertService) CheckMetrics(ctx context.Context, agentID string, cpu, me
 		return err
 	}
 
+	// 匹配模板绑定的探针使用模板的资源类告警规则
+	alertConfig, _, _ = s.resolveAgentAlertConfig(ctx, alertConfig, &agent)
+
+	// 不在告警作用范围内的探针不检查资源类告警
+	if !agent.MatchAttributes(alertConfig.Rules.AgentAttributes) {
+		return nil
+	}
+
 	now := time.Now().UnixMilli()
 
 	// 检查 CPU 告警
 	if alertConfig.Rules.CPUEnabled {
-		s.checkAlert(ctx, alertConfig, &agent, "cpu", cpu, alertConfig.Rules.CPUThreshold, alertConfig.Rules.CPUDuration, now)
+		s.checkAlert(ctx, alertConfig, &agent, "cpu", cpu, alertConfig.Rules.CPUThreshold, alertConfig.Rules.CPUDuration, alertConfig.Rules.CPUTiers, now)
 	}
 
-	// 检查内存告警
+	// 检查内存告警，剩余量模式下按剩余内存判断，未上报内存指标时剩余量未知，不检查
 	if alertConfig.Rules.MemoryEnabled {
-		s.checkAlert(ctx, alertConfig, &agent, "memory", memory, alertConfig.Rules.MemoryThreshold, alertConfig.Rules.MemoryDuration, now)
+		if alertConfig.Rules.MemoryThresholdMode == models.ThresholdModeFree {
+			if memoryFree != nil {
+				s.checkFreeAlert(ctx, alertConfig, &agent, "memory_free", bytesToGB(*memoryFree), alertConfig.Rules.MemoryFreeThreshold, alertConfig.Rules.MemoryDuration, now)
+			}
+		} else {
+			s.checkAlert(ctx, alertConfig, &agent, "memory", memory, alertConfig.Rules.MemoryThreshold, alertConfig.Rules.MemoryDuration, alertConfig.Rules.MemoryTiers, now)
+		}
 	}
 
-	// 检查磁盘告警
+	// 检查磁盘告警，剩余量模式下按磁盘剩余空间判断，未上报磁盘指标时不检查
 	if alertConfig.Rules.DiskEnabled {
-		s.checkAlert(ctx, alertConfig, &agent, "disk", disk, alertConfig.Rules.DiskThreshold, alertConfig.Rules.DiskDuration, now)
+		if alertConfig.Rules.DiskThresholdMode == models.ThresholdModeFree {
+			if diskFree != nil {
+				s.checkFreeAlert(ctx, alertConfig, &agent, "disk_free", bytesToGB(*diskFree), alertConfig.Rules.DiskFreeThreshold, alertConfig.Rules.DiskDuration, now)
+			}
+		} else {
+			s.checkAlert(ctx, alertConfig, &agent, "disk", disk, alertConfig.Rules.DiskThreshold, alertConfig.Rules.DiskDuration, alertConfig.Rules.DiskTiers, now)
+		}
 	}
 
 	// 检查网速告警
 	if alertConfig.Rules.NetworkEnabled {
-		s.checkAlert(ctx, alertConfig, &agent, "network", networkSpeed, alertConfig.Rules.NetworkThreshold, alertConfig.Rules.NetworkDuration, now)
+		s.checkAlert(ctx, alertConfig, &agent, "network", networkSpeed, alertConfig.Rules.NetworkThreshold, alertConfig.Rules.NetworkDuration, alertConfig.Rules.NetworkTiers, now)
+	}
+
+	// 检查负载告警，核心数未知时无法换算每核负载
+	if alertConfig.Rules.LoadEnabled && load != nil && cores > 0 {
+		s.checkLoadAlert(ctx, alertConfig, &agent, load.Load1/float64(cores), alertConfig.Rules.LoadThreshold, alertConfig.Rules.LoadDuration, now)
+	}
+
+	// 检查连接数告警
+	if alertConfig.Rules.ConnectionEnabled && connections != nil {
+		s.checkConnectionAlerts(ctx, alertConfig, &agent, connections, now)
 	}
 
 	return nil
 }
 
 // checkAlert 检查单个告警规则
-func (s *AlertService) checkAlert(ctx context.Context, config *models.AlertConfig, agent *models.Agent, alertType string, currentValue, threshold float64, duration int, now int64) {
+func (s *AlertService) checkAlert(ctx context.Context, config *models.AlertConfig, agent *models.Agent, alertType string, currentValue, threshold float64, duration int, tiers []models.SeverityTier, now int64) {
+	breached := currentValue >= threshold
+	var level string
+	if breached {
+		level = s.resolveLevel(currentValue, threshold, tiers)
+	}
+	s.evaluateAlert(ctx, config, agent, alertType, currentValue, threshold, duration, breached, level, now)
+}
+
+// checkFreeAlert 检查剩余量告警，剩余量低于阈值并持续指定时间时触发
+func (s *AlertService) checkFreeAlert(ctx context.Context, config *models.AlertConfig, agent *models.Agent, alertType string, free, threshold float64, duration int, now int64) {
+	breached := free < threshold
+	var level string
+	if breached {
+		level = calculateFreeLevel(free, threshold)
+	}
+	s.evaluateAlert(ctx, config, agent, alertType, free, threshold, duration, breached, level, now)
+}
+
+// checkLoadAlert 检查负载告警，每核负载达到阈值并持续指定时间时触发
+func (s *AlertService) checkLoadAlert(ctx context.Context, config *models.AlertConfig, agent *models.Agent, perCore, threshold float64, duration int, now int64) {
+	perCore = math.Round(perCore*100) / 100
+	breached := threshold > 0 && perCore >= threshold
+	var level string
+	if breached {
+		level = calculateLoadLevel(perCore, threshold)
+	}
+	s.evaluateAlert(ctx, config, agent, "load", perCore, threshold, duration, breached, level, now)
+}
+
+// evaluateAlert 按是否超过阈值更新告警状态，持续时间满足时触发告警，恢复时发送恢复通知
+func (s *AlertService) evaluateAlert(ctx context.Context, config *models.AlertConfig, agent *models.Agent, alertType string, currentValue, threshold float64, duration int, breached bool, level string, now int64) {
 	stateKey := fmt.Sprintf("%s:global:%s", agent.ID, alertType)
 
-	var shouldFire, shouldResolve bool
+	var shouldFire, shouldResolve, shouldEscalate bool
 
 	// 从数据库加载状态
 	state, err := s.AlertStateRepo.GetAlertState(ctx, stateKey)
@@ -128,21 +243,32 @@ func (s *AlertService) checkAlert(ctx context.Context, config *models.AlertConfi
 	state.Value = currentValue
 	state.LastCheckTime = now
 
-	if currentValue >= threshold {
-		if state.StartTime == 0 {
-			state.StartTime = now
-		}
+	mode, ratio := resolveDurationMode(config.Rules)
+	active := advanceAlertTimer(state, mode, ratio, breached, duration, now)
 
-		elapsedSeconds := (now - state.StartTime) / 1000
-		if elapsedSeconds >= int64(duration) && !state.IsFiring {
+	if active {
+		if !state.IsFiring {
 			shouldFire = true
 			state.IsFiring = true
+			// windowed 模式下触发时的采样可能未超过阈值，级别为空时由 fireAlert 按当前值计算
+			state.Level = level
+		} else if state.Level == "" {
+			// 升级前已在告警中的状态没有记录级别，以告警记录中的级别为准，无法获取时以当前级别为准，不视为升级
+			state.Level = s.firingRecordLevel(ctx, state)
+			if state.Level == "" {
+				state.Level = level
+			}
+			if breached && levelRank(level) > levelRank(state.Level) {
+				shouldEscalate = true
+				state.Level = level
+			}
+		} else if breached && levelRank(level) > levelRank(state.Level) {
+			// 同一告警事件内超过更高级别阈值，升级告警级别
+			shouldEscalate = true
+			state.Level = level
 		}
-	} else {
-		if state.IsFiring {
-			shouldResolve = true
-		}
-		state.StartTime = 0
+	} else if state.IsFiring {
+		shouldResolve = true
 	}
 
 	// 保存状态到数据库
@@ -154,6 +280,10 @@ func (s *AlertService) checkAlert(ctx context.Context, config *models.AlertConfi
 		s.fireAlert(ctx, config, agent, state)
 	}
 
+	if shouldEscalate {
+		s.escalateAlert(ctx, agent, state)
+	}
+
 	if shouldResolve {
 		s.resolveAlert(ctx, config, agent, state)
 	}
@@ -167,9 +297,13 @@ func (s *AlertService) fireAlert(ctx context.Context, config *models.AlertConfig
 		zap.String("alertType", state.AlertType),
 		zap.Float64("value", state.Value),
 		zap.Float64("threshold", state.Threshold),
+		utils.TraceField(ctx),
 	)
 
 	now := time.Now().UnixMilli()
+	if state.Level == "" {
+		state.Level = s.calculateLevel(state.Value, state.Threshold)
+	}
 
 	// 创建告警记录
 	record := &models.AlertRecord{
@@ -179,11 +313,13 @@ func (s *AlertService) fireAlert(ctx context.Context, config *models.AlertConfig
 		Message:     s.buildAlertMessage(state),
 		Threshold:   state.Threshold,
 		ActualValue: state.Value,
-		Level:       s.calculateLevel(state.Value, state.Threshold),
+		Level:       state.Level,
 		Status:      "firing",
 		FiredAt:     now,
+		TraceID:     utils.TraceIDFromContext(ctx),
 		CreatedAt:   now,
 	}
+	s.applyAlertDependency(ctx, record)
 
 	err := s.AlertRecordRepo.CreateAlertRecord(ctx, record)
 	if err != nil {
@@ -203,6 +339,55 @@ func (s *AlertService) fireAlert(ctx context.Context, config *models.AlertConfig
 	go s.sendAlertNotification(record, agent)
 }
 
+// firingRecordLevel 返回告警状态关联的告警中记录的级别，记录不存在或已恢复时返回空
+func (s *AlertService) firingRecordLevel(ctx context.Context, state *models.AlertState) string {
+	if state.LastRecordID == 0 {
+		return ""
+	}
+	record, err := s.AlertRecordRepo.GetAlertRecordByID(ctx, state.LastRecordID)
+	if err != nil || record == nil || record.Status != "firing" {
+		return ""
+	}
+	return record.Level
+}
+
+// escalateAlert 告警级别升级，更新原告警记录并重新发送通知
+func (s *AlertService) escalateAlert(ctx context.Context, agent *models.Agent, state *models.AlertState) {
+	if state.LastRecordID == 0 {
+		return
+	}
+
+	record, err := s.AlertRecordRepo.GetAlertRecordByID(ctx, state.LastRecordID)
+	if err != nil {
+		s.logger.Error("获取告警记录失败", zap.Error(err))
+		return
+	}
+	if record == nil || record.Status != "firing" {
+		return
+	}
+
+	s.logger.Info("告警级别升级",
+		zap.String("agentId", agent.ID),
+		zap.String("alertType", state.AlertType),
+		zap.String("from", record.Level),
+		zap.String("to", state.Level),
+		zap.Float64("value", state.Value),
+		utils.TraceField(ctx),
+	)
+
+	record.Level = state.Level
+	record.TraceID = utils.TraceIDFromContext(ctx)
+	record.ActualValue = state.Value
+	record.Message = s.buildAlertMessage(state)
+	record.UpdatedAt = time.Now().UnixMilli()
+	if err := s.AlertRecordRepo.UpdateAlertRecord(ctx, record); err != nil {
+		s.logger.Error("更新告警记录失败", zap.Error(err))
+		return
+	}
+
+	go s.sendAlertNotification(record, agent)
+}
+
 // resolveAlert 恢复告警
 func (s *AlertService) resolveAlert(ctx context.Context, config *models.AlertConfig, agent *models.Agent, state *models.AlertState) {
 	s.logger.Info("告警恢复",
@@ -210,6 +395,7 @@ func (s *AlertService) resolveAlert(ctx context.Context, config *models.AlertCon
 		zap.String("agentName", agent.Name),
 		zap.String("alertType", state.AlertType),
 		zap.Float64("value", state.Value),
+		utils.TraceField(ctx),
 	)
 
 	if state.LastRecordID > 0 {
@@ -227,6 +413,7 @@ func (s *AlertService) resolveAlert(ctx context.Context, config *models.AlertCon
 				now := time.Now().UnixMilli()
 				existingRecord.Status = "resolved"
 				existingRecord.ResolvedAt = now
+				existingRecord.TraceID = utils.TraceIDFromContext(ctx)
 				existingRecord.UpdatedAt = now
 
 				err = s.AlertRecordRepo.UpdateAlertRecord(ctx, existingRecord)
@@ -243,6 +430,7 @@ func (s *AlertService) resolveAlert(ctx context.Context, config *models.AlertCon
 	// 更新状态
 	state.IsFiring = false
 	state.LastRecordID = 0
+	state.Level = ""
 	if err := s.AlertStateRepo.SaveAlertState(ctx, state); err != nil {
 		s.logger.Error("保存告警状态失败", zap.Error(err))
 	}
@@ -250,6 +438,10 @@ func (s *AlertService) resolveAlert(ctx context.Context, config *models.AlertCon
 
 // buildAlertMessage 构建告警消息
 func (s *AlertService) buildAlertMessage(state *models.AlertState) string {
+	if isConnectionAlertType(state.AlertType) {
+		return buildConnectionAlertMessage(state)
+	}
+
 	var alertTypeName string
 	switch state.AlertType {
 	case "cpu":
@@ -258,6 +450,12 @@ func (s *AlertService) buildAlertMessage(state *models.AlertState) string {
 		alertTypeName = "内存使用率"
 	case "disk":
 		alertTypeName = "磁盘使用率"
+	case "memory_free":
+		return fmt.Sprintf("剩余内存持续%d秒低于%.2fGB，当前剩余%.2fGB", state.Duration, state.Threshold, state.Value)
+	case "disk_free":
+		return fmt.Sprintf("磁盘剩余空间持续%d秒低于%.2fGB，当前剩余%.2fGB", state.Duration, state.Threshold, state.Value)
+	case "load":
+		return fmt.Sprintf("每核负载持续%d秒超过%.2f，当前值%.2f", state.Duration, state.Threshold, state.Value)
 	case "network":
 		return fmt.Sprintf("网速持续%d秒超过%.2fMB/s，当前值%.2fMB/s",
 			state.Duration,
@@ -268,6 +466,8 @@ func (s *AlertService) buildAlertMessage(state *models.AlertState) string {
 		return fmt.Sprintf("HTTPS证书剩余天数%.0f天，低于阈值%.0f天", state.Value, state.Threshold)
 	case "service":
 		return fmt.Sprintf("服务持续离线%d秒", state.Duration)
+	case "collector_error":
+		return fmt.Sprintf("探针指标采集持续%d秒失败，最近一轮有%.0f项采集失败", state.Duration, state.Value)
 	default:
 		alertTypeName = state.AlertType
 	}
@@ -293,8 +493,101 @@ func (s *AlertService) calculateLevel(value, threshold float64) string {
 	}
 }
 
+// resolveLevel 根据分级阈值计算告警级别，未配置分级时按超出阈值的幅度计算
+func (s *AlertService) resolveLevel(value, threshold float64, tiers []models.SeverityTier) string {
+	if len(tiers) == 0 {
+		return s.calculateLevel(value, threshold)
+	}
+
+	level := models.AlertLevelInfo
+	for _, tier := range tiers {
+		if value >= tier.Threshold && levelRank(tier.Level) > levelRank(level) {
+			level = tier.Level
+		}
+	}
+	return level
+}
+
+// bytesToGB 字节转换为 GB，保留两位小数
+func bytesToGB(bytes uint64) float64 {
+	return math.Round(float64(bytes)/(1<<30)*100) / 100
+}
+
+// calculateFreeLevel 按剩余量占阈值的比例计算剩余量告警的级别
+func calculateFreeLevel(free, threshold float64) string {
+	if threshold <= 0 {
+		return models.AlertLevelInfo
+	}
+	ratio := free / threshold
+	switch {
+	case ratio <= 0.25:
+		return models.AlertLevelCritical
+	case ratio <= 0.5:
+		return models.AlertLevelWarning
+	default:
+		return models.AlertLevelInfo
+	}
+}
+
+// calculateLoadLevel 按每核负载超出阈值的倍数计算负载告警的级别
+func calculateLoadLevel(perCore, threshold float64) string {
+	if threshold <= 0 {
+		return models.AlertLevelInfo
+	}
+	ratio := perCore / threshold
+	switch {
+	case ratio >= 2:
+		return models.AlertLevelCritical
+	case ratio >= 1.5:
+		return models.AlertLevelWarning
+	default:
+		return models.AlertLevelInfo
+	}
+}
+
+// levelRank 告警级别排序，数值越大越严重
+func levelRank(level string) int {
+	switch level {
+	case models.AlertLevelCritical:
+		return 3
+	case models.AlertLevelWarning:
+		return 2
+	case models.AlertLevelInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// applyAlertDependency 探针离线告警触发期间，该探针的其他告警只记录不通知，并标记依赖的父告警
+func (s *AlertService) applyAlertDependency(ctx context.Context, record *models.AlertRecord) {
+	if record.AlertType == "agent_offline" {
+		return
+	}
+
+	stateKey := fmt.Sprintf("%s:global:agent_offline:%s", record.AgentID, record.AgentID)
+	parent, err := s.AlertStateRepo.GetAlertState(ctx, stateKey)
+	if err != nil || !parent.IsFiring {
+		return
+	}
+
+	record.Suppressed = true
+	record.DependsOn = parent.LastRecordID
+
+	s.logger.Info("探针离线中，抑制告警通知",
+		zap.String("agentId", record.AgentID),
+		zap.String("alertType", record.AlertType),
+		zap.Int64("dependsOn", parent.LastRecordID),
+	)
+}
+
 // sendAlertNotification 发送告警通知(带panic恢复)
 func (s *AlertService) sendAlertNotification(record *models.AlertRecord, agent *models.Agent) {
+	// 被抑制的告警（包括其恢复）不发送通知
+	if record.Suppressed {
+		return
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			s.logger.Error("发送告警通知时发生panic",
@@ -306,6 +599,8 @@ func (s *AlertService) sendAlertNotification(record *models.AlertRecord, agent *
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	// 延续告警记录的追踪ID，便于从指标上报一路关联到通知投递
+	ctx = utils.WithTraceID(ctx, record.TraceID)
 
 	// 获取告警配置（包含 MaskIP 设置）
 	alertConfig, err := s.propertyService.GetAlertConfig(ctx)
@@ -320,19 +615,22 @@ func (s *AlertService) sendAlertNotification(record *models.AlertRecord, agent *
 		return
 	}
 
-	var enabledChannels []models.NotificationChannelConfig
-	for _, channel := range channelConfigs {
-		if channel.Enabled {
-			enabledChannels = append(enabledChannels, channel)
-		}
-	}
+	enabledChannels := filterChannelsByLevel(channelConfigs, record.Level)
+	enabledChannels = s.routeMonitorChannels(ctx, record, enabledChannels)
+	// 关闭恢复通知时告警恢复仍已记录，只是不发送通知
+	enabledChannels = filterChannelsByStatus(alertConfig, enabledChannels, record.Status)
 
 	if len(enabledChannels) == 0 {
 		return
 	}
 
-	if err := s.notifier.SendNotificationByConfigs(ctx, enabledChannels, record, agent, alertConfig.MaskIP); err != nil {
-		s.logger.Error("发送告警通知失败", zap.Error(err))
+	// 单个探针的通知超出预算时只保留告警记录
+	if !s.notificationSvc.AllowAgentNotification(ctx, alertConfig, record, agent, enabledChannels) {
+		return
+	}
+
+	if err := s.notifier.SendNotificationByConfigs(ctx, enabledChannels, record, agent, alertConfig.ResolveMaskIPMode()); err != nil {
+		s.logger.Error("发送告警通知失败", zap.Error(err), utils.TraceField(ctx))
 	}
 }
 
@@ -366,6 +664,13 @@ func (s *AlertService) CheckMonitorAlerts(ctx context.Context) error {
 		}
 	}
 
+	// 检查磁盘将满预测告警
+	if alertConfig.Rules.DiskPredictEnabled {
+		if err := s.checkDiskPredictAlerts(ctx, alertConfig, now); err != nil {
+			s.logger.Error("检查磁盘将满预测告警失败", zap.Error(err))
+		}
+	}
+
 	// 检查探针离线告警
 	if alertConfig.Rules.AgentOfflineEnabled {
 		if err := s.checkAgentOfflineAlerts(ctx, alertConfig, now); err != nil {
@@ -373,6 +678,13 @@ func (s *AlertService) CheckMonitorAlerts(ctx context.Context) error {
 		}
 	}
 
+	// 检查探针采集异常告警
+	if alertConfig.Rules.CollectorErrorEnabled {
+		if err := s.checkCollectorErrorAlerts(ctx, alertConfig, now); err != nil {
+			s.logger.Error("检查探针采集异常告警失败", zap.Error(err))
+		}
+	}
+
 	return nil
 }
 
@@ -476,8 +788,11 @@ func (s *AlertService) checkCertAlert(ctx context.Context, config *models.AlertC
 		Level:       s.calculateCertLevel(certDaysLeft),
 		Status:      "firing",
 		FiredAt:     now,
+		TraceID:     utils.TraceIDFromContext(ctx),
 		CreatedAt:   now,
 	}
+	record.Monitor = newMonitorAlertContext(monitor, 0)
+	s.applyAlertDependency(ctx, record)
 
 	err = s.AlertRecordRepo.CreateAlertRecord(ctx, record)
 	if err != nil {
@@ -519,12 +834,14 @@ func (s *AlertService) resolveCertAlert(ctx context.Context, config *models.Aler
 			existingRecord.Status = "resolved"
 			existingRecord.ActualValue = certDaysLeft
 			existingRecord.ResolvedAt = now
+			existingRecord.TraceID = utils.TraceIDFromContext(ctx)
 			existingRecord.UpdatedAt = now
 
 			err = s.AlertRecordRepo.UpdateAlertRecord(ctx, existingRecord)
 			if err != nil {
 				s.logger.Error("更新证书告警记录失败", zap.Error(err))
 			} else {
+				existingRecord.Monitor = newMonitorAlertContext(monitor, 0)
 				// 发送恢复通知
 				go s.sendAlertNotification(existingRecord, agent)
 			}
@@ -647,8 +964,11 @@ func (s *AlertService) fireServiceDownAlert(ctx context.Context, config *models.
 		Level:       "critical",
 		Status:      "firing",
 		FiredAt:     now,
+		TraceID:     utils.TraceIDFromContext(ctx),
 		CreatedAt:   now,
 	}
+	record.Monitor = newMonitorAlertContext(monitor, (now-state.StartTime)/1000)
+	s.applyAlertDependency(ctx, record)
 
 	err := s.AlertRecordRepo.CreateAlertRecord(ctx, record)
 	if err != nil {
@@ -681,12 +1001,15 @@ func (s *AlertService) resolveServiceDownAlert(ctx context.Context, config *mode
 			now := time.Now().UnixMilli()
 			existingRecord.Status = "resolved"
 			existingRecord.ResolvedAt = now
+			existingRecord.TraceID = utils.TraceIDFromContext(ctx)
 			existingRecord.UpdatedAt = now
 
 			err = s.AlertRecordRepo.UpdateAlertRecord(ctx, existingRecord)
 			if err != nil {
 				s.logger.Error("更新服务下线告警记录失败", zap.Error(err))
 			} else {
+				// 离线时长 = 触发前的持续时间 + 触发后到恢复的时间
+				existingRecord.Monitor = newMonitorAlertContext(monitor, int64(state.Duration)+(now-existingRecord.FiredAt)/1000)
 				// 发送恢复通知
 				go s.sendAlertNotification(existingRecord, agent)
 			}
@@ -700,6 +1023,22 @@ func (s *AlertService) resolveServiceDownAlert(ctx context.Context, config *mode
 	}
 }
 
+// newMonitorAlertContext 根据最近一次监控数据构建通知模板使用的监控项上下文
+func newMonitorAlertContext(monitor *protocol.MonitorData, downtime int64) *models.MonitorAlertContext {
+	return &models.MonitorAlertContext{
+		ID:           monitor.MonitorId,
+		Name:         monitor.MonitorName,
+		Type:         monitor.Type,
+		Target:       monitor.Target,
+		Status:       monitor.Status,
+		StatusCode:   monitor.StatusCode,
+		ResponseTime: monitor.ResponseTime,
+		Error:        monitor.Error,
+		Downtime:     max(downtime, 0),
+		CertDaysLeft: monitor.CertDaysLeft,
+	}
+}
+
 // checkAgentOfflineAlerts 检查探针离线告警
 func (s *AlertService) checkAgentOfflineAlerts(ctx context.Context, config *models.AlertConfig, now int64) error {
 	// 获取所有探针
@@ -785,6 +1124,7 @@ func (s *AlertService) fireAgentOfflineAlert(ctx context.Context, config *models
 		Level:       "critical",
 		Status:      "firing",
 		FiredAt:     now,
+		TraceID:     utils.TraceIDFromContext(ctx),
 		CreatedAt:   now,
 	}
 
@@ -819,6 +1159,7 @@ func (s *AlertService) resolveAgentOfflineAlert(ctx context.Context, config *mod
 			now := time.Now().UnixMilli()
 			existingRecord.Status = "resolved"
 			existingRecord.ResolvedAt = now
+			existingRecord.TraceID = utils.TraceIDFromContext(ctx)
 			existingRecord.UpdatedAt = now
 
 			err = s.AlertRecordRepo.UpdateAlertRecord(ctx, existingRecord)
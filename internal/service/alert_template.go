@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrAlertTemplateNotFound 告警规则模板不存在
+var ErrAlertTemplateNotFound = errors.New("告警规则模板不存在")
+
+// GetAlertRuleTemplates 获取告警规则模板列表
+func (s *PropertyService) GetAlertRuleTemplates(ctx context.Context) ([]models.AlertRuleTemplate, error) {
+	var templates []models.AlertRuleTemplate
+	if err := s.GetValue(ctx, PropertyIDAlertRuleTemplates, &templates); err != nil {
+		return nil, fmt.Errorf("获取告警规则模板失败: %w", err)
+	}
+	return templates, nil
+}
+
+// SetAlertRuleTemplates 设置告警规则模板列表
+func (s *PropertyService) SetAlertRuleTemplates(ctx context.Context, templates []models.AlertRuleTemplate) error {
+	return s.Set(ctx, PropertyIDAlertRuleTemplates, "告警规则模板", templates)
+}
+
+// CloneAlertRuleTemplate 复制告警规则模板，name 为空时使用原名称加“副本”
+func (s *PropertyService) CloneAlertRuleTemplate(ctx context.Context, id, name string) (*models.AlertRuleTemplate, error) {
+	templates, err := s.GetAlertRuleTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source := findAlertRuleTemplate(templates, id)
+	if source == nil {
+		return nil, ErrAlertTemplateNotFound
+	}
+	if name == "" {
+		name = source.Name + " 副本"
+	}
+	return s.addAlertRuleTemplate(ctx, templates, name, source.Description, source.Rules)
+}
+
+// CreateAlertRuleTemplateFromConfig 以当前全局告警规则创建模板
+func (s *PropertyService) CreateAlertRuleTemplateFromConfig(ctx context.Context, name, description string) (*models.AlertRuleTemplate, error) {
+	if name == "" {
+		return nil, errors.New("模板名称不能为空")
+	}
+	templates, err := s.GetAlertRuleTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.GetAlertConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.addAlertRuleTemplate(ctx, templates, name, description, config.Rules)
+}
+
+// addAlertRuleTemplate 追加新模板并保存，作用范围由绑定决定，不保留规则中的属性条件
+func (s *PropertyService) addAlertRuleTemplate(ctx context.Context, templates []models.AlertRuleTemplate, name, description string, rules models.AlertRules) (*models.AlertRuleTemplate, error) {
+	rules.AgentAttributes = nil
+	template := models.AlertRuleTemplate{
+		ID:          uuid.NewString(),
+		Name:        name,
+		Description: description,
+		Rules:       rules,
+		CreatedAt:   time.Now().UnixMilli(),
+	}
+	if err := s.SetAlertRuleTemplates(ctx, append(templates, template)); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// InstantiateAlertRuleTemplate 将模板应用到标签或属性匹配的一组探针，追加到告警配置的模板绑定中
+func (s *PropertyService) InstantiateAlertRuleTemplate(ctx context.Context, id string, binding models.AlertTemplateBinding) ([]models.AlertTemplateBinding, error) {
+	templates, err := s.GetAlertRuleTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if findAlertRuleTemplate(templates, id) == nil {
+		return nil, ErrAlertTemplateNotFound
+	}
+	config, err := s.GetAlertConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	binding.TemplateID = id
+	bindings := append(config.TemplateBindings, binding)
+	if err := ValidateAlertTemplateBindings(bindings, templates); err != nil {
+		return nil, err
+	}
+	config.TemplateBindings = bindings
+	if err := s.SetAlertConfig(ctx, *config); err != nil {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// ValidateAlertRuleTemplates 校验告警规则模板，被告警配置引用的模板不能删除
+func ValidateAlertRuleTemplates(templates []models.AlertRuleTemplate, bindings []models.AlertTemplateBinding) error {
+	seen := make(map[string]struct{}, len(templates))
+	for i, template := range templates {
+		if strings.TrimSpace(template.ID) == "" {
+			return fmt.Errorf("第 %d 个模板缺少ID", i+1)
+		}
+		if strings.TrimSpace(template.Name) == "" {
+			return fmt.Errorf("第 %d 个模板名称不能为空", i+1)
+		}
+		if _, ok := seen[template.ID]; ok {
+			return fmt.Errorf("模板ID重复: %s", template.ID)
+		}
+		seen[template.ID] = struct{}{}
+	}
+	for _, binding := range bindings {
+		if _, ok := seen[binding.TemplateID]; !ok {
+			return fmt.Errorf("模板 %s 正在被告警配置引用，不能删除", binding.TemplateID)
+		}
+	}
+	return nil
+}
+
+// ValidateAlertTemplateBindings 校验模板绑定：引用的模板必须存在，且必须指定标签或属性条件
+func ValidateAlertTemplateBindings(bindings []models.AlertTemplateBinding, templates []models.AlertRuleTemplate) error {
+	for i, binding := range bindings {
+		if findAlertRuleTemplate(templates, binding.TemplateID) == nil {
+			return fmt.Errorf("第 %d 个模板绑定引用的模板不存在: %s", i+1, binding.TemplateID)
+		}
+		if len(binding.Tags) == 0 && len(binding.AgentAttributes) == 0 {
+			return fmt.Errorf("第 %d 个模板绑定必须指定标签或属性", i+1)
+		}
+	}
+	return nil
+}
+
+func findAlertRuleTemplate(templates []models.AlertRuleTemplate, id string) *models.AlertRuleTemplate {
+	for i := range templates {
+		if templates[i].ID == id {
+			return &templates[i]
+		}
+	}
+	return nil
+}
+
+// matchAlertTemplate 返回探针匹配的第一个模板绑定及其模板，没有匹配时返回 nil
+func matchAlertTemplate(bindings []models.AlertTemplateBinding, templates []models.AlertRuleTemplate, agent *models.Agent) (*models.AlertTemplateBinding, *models.AlertRuleTemplate) {
+	for i := range bindings {
+		if !bindings[i].Matches(agent) {
+			continue
+		}
+		if template := findAlertRuleTemplate(templates, bindings[i].TemplateID); template != nil {
+			return &bindings[i], template
+		}
+	}
+	return nil, nil
+}
+
+// applyTemplateRules 用模板的资源类告警规则和持续时间语义替换全局规则
+// 证书、服务下线、探针离线等不针对单个探针资源的规则仍使用全局配置；作用范围已由绑定决定，不再按属性过滤
+func applyTemplateRules(global, template models.AlertRules) models.AlertRules {
+	rules := global
+
+	rules.CPUEnabled = template.CPUEnabled
+	rules.CPUThreshold = template.CPUThreshold
+	rules.CPUDuration = template.CPUDuration
+	rules.CPUTiers = template.CPUTiers
+
+	rules.MemoryEnabled = template.MemoryEnabled
+	rules.MemoryThreshold = template.MemoryThreshold
+	rules.MemoryDuration = template.MemoryDuration
+	rules.MemoryTiers = template.MemoryTiers
+	rules.MemoryThresholdMode = template.MemoryThresholdMode
+	rules.MemoryFreeThreshold = template.MemoryFreeThreshold
+
+	rules.DiskEnabled = template.DiskEnabled
+	rules.DiskThreshold = template.DiskThreshold
+	rules.DiskDuration = template.DiskDuration
+	rules.DiskTiers = template.DiskTiers
+	rules.DiskThresholdMode = template.DiskThresholdMode
+	rules.DiskFreeThreshold = template.DiskFreeThreshold
+
+	rules.NetworkEnabled = template.NetworkEnabled
+	rules.NetworkThreshold = template.NetworkThreshold
+	rules.NetworkDuration = template.NetworkDuration
+	rules.NetworkTiers = template.NetworkTiers
+
+	rules.LoadEnabled = template.LoadEnabled
+	rules.LoadThreshold = template.LoadThreshold
+	rules.LoadDuration = template.LoadDuration
+
+	rules.ConnectionEnabled = template.ConnectionEnabled
+	rules.ConnectionThreshold = template.ConnectionThreshold
+	rules.ConnectionDuration = template.ConnectionDuration
+	rules.ConnectionStates = template.ConnectionStates
+
+	rules.DurationMode = template.DurationMode
+	rules.WindowRatio = template.WindowRatio
+
+	rules.AgentAttributes = nil
+	return rules
+}
+
+// resolveAgentAlertConfig 返回探针实际使用的告警配置，匹配模板绑定时使用模板的资源类告警规则
+// 读取模板失败时记录日志并使用全局配置
+func (s *AlertService) resolveAgentAlertConfig(ctx context.Context, config *models.AlertConfig, agent *models.Agent) (*models.AlertConfig, *models.AlertTemplateBinding, *models.AlertRuleTemplate) {
+	if len(config.TemplateBindings) == 0 {
+		return config, nil, nil
+	}
+	templates, err := s.propertyService.GetAlertRuleTemplates(ctx)
+	if err != nil {
+		s.logger.Error("获取告警规则模板失败", zap.Error(err))
+		return config, nil, nil
+	}
+	binding, template := matchAlertTemplate(config.TemplateBindings, templates, agent)
+	if template == nil {
+		return config, nil, nil
+	}
+
+	resolved := *config
+	resolved.Rules = applyTemplateRules(config.Rules, template.Rules)
+	return &resolved, binding, template
+}
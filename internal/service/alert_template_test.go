@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+	"gorm.io/datatypes"
+)
+
+func TestMatchAlertTemplate(t *testing.T) {
+	templates := []models.AlertRuleTemplate{
+		{ID: "db", Name: "数据库", Rules: models.AlertRules{CPUEnabled: true, CPUThreshold: 95, CPUDuration: 600}},
+		{ID: "web", Name: "Web", Rules: models.AlertRules{CPUEnabled: true, CPUThreshold: 70}},
+	}
+	bindings := []models.AlertTemplateBinding{
+		{TemplateID: "db", Tags: []string{"db"}, AgentAttributes: map[string]string{"env": "prod"}},
+		{TemplateID: "web", Tags: []string{"web", "db"}},
+	}
+
+	prodDB := &models.Agent{
+		Tags:               []string{"db"},
+		ReportedAttributes: datatypes.NewJSONType(map[string]string{"env": "prod"}),
+	}
+	if _, template := matchAlertTemplate(bindings, templates, prodDB); template == nil || template.ID != "db" {
+		t.Fatalf("生产数据库应匹配 db 模板，得到 %+v", template)
+	}
+
+	// 标签匹配但属性不匹配时继续匹配下一个绑定
+	testDB := &models.Agent{
+		Tags:               []string{"db"},
+		ReportedAttributes: datatypes.NewJSONType(map[string]string{"env": "test"}),
+	}
+	if _, template := matchAlertTemplate(bindings, templates, testDB); template == nil || template.ID != "web" {
+		t.Fatalf("测试数据库应匹配 web 模板，得到 %+v", template)
+	}
+
+	if _, template := matchAlertTemplate(bindings, templates, &models.Agent{Tags: []string{"cache"}}); template != nil {
+		t.Fatalf("未匹配任何绑定时应返回 nil，得到 %+v", template)
+	}
+}
+
+func TestApplyTemplateRules(t *testing.T) {
+	global := models.AlertRules{
+		CPUEnabled:          true,
+		CPUThreshold:        80,
+		CertEnabled:         true,
+		CertThreshold:       30,
+		AgentOfflineEnabled: true,
+		AgentAttributes:     map[string]string{"env": "prod"},
+	}
+	template := models.AlertRules{CPUEnabled: true, CPUThreshold: 95, CertEnabled: false}
+
+	rules := applyTemplateRules(global, template)
+	if rules.CPUThreshold != 95 {
+		t.Fatalf("CPU 阈值应使用模板的值，得到 %v", rules.CPUThreshold)
+	}
+	if !rules.CertEnabled || rules.CertThreshold != 30 || !rules.AgentOfflineEnabled {
+		t.Fatalf("证书、探针离线告警应保留全局配置，得到 %+v", rules)
+	}
+	if rules.AgentAttributes != nil {
+		t.Fatalf("作用范围由绑定决定，不应再按属性过滤，得到 %v", rules.AgentAttributes)
+	}
+}
+
+func TestValidateAlertRuleTemplates(t *testing.T) {
+	templates := []models.AlertRuleTemplate{{ID: "db", Name: "数据库"}}
+	bindings := []models.AlertTemplateBinding{{TemplateID: "db", Tags: []string{"db"}}}
+
+	if err := ValidateAlertRuleTemplates(templates, bindings); err != nil {
+		t.Fatalf("合法模板不应报错: %v", err)
+	}
+	if err := ValidateAlertRuleTemplates(nil, bindings); err == nil {
+		t.Fatal("删除被引用的模板应报错")
+	}
+	if err := ValidateAlertRuleTemplates(append(templates, models.AlertRuleTemplate{ID: "db", Name: "重复"}), nil); err == nil {
+		t.Fatal("模板ID重复应报错")
+	}
+	if err := ValidateAlertTemplateBindings([]models.AlertTemplateBinding{{TemplateID: "db"}}, templates); err == nil {
+		t.Fatal("未指定标签或属性的绑定应报错")
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestCalculateFreeLevel(t *testing.T) {
+	cases := []struct {
+		free, threshold float64
+		want            string
+	}{
+		{free: 90, threshold: 100, want: models.AlertLevelInfo},
+		{free: 50, threshold: 100, want: models.AlertLevelWarning},
+		{free: 10, threshold: 100, want: models.AlertLevelCritical},
+		{free: 0, threshold: 0, want: models.AlertLevelInfo},
+	}
+	for _, c := range cases {
+		if got := calculateFreeLevel(c.free, c.threshold); got != c.want {
+			t.Errorf("calculateFreeLevel(%v, %v) = %s, want %s", c.free, c.threshold, got, c.want)
+		}
+	}
+}
+
+func TestBytesToGB(t *testing.T) {
+	if got := bytesToGB(3 << 40); got != 3072 {
+		t.Fatalf("unexpected GB: %v", got)
+	}
+	if got := bytesToGB(1 << 29); got != 0.5 {
+		t.Fatalf("unexpected GB: %v", got)
+	}
+}
+
+func TestCalculateLoadLevel(t *testing.T) {
+	cases := []struct {
+		perCore, threshold float64
+		want               string
+	}{
+		{perCore: 1.6, threshold: 1.5, want: models.AlertLevelInfo},
+		{perCore: 2.4, threshold: 1.5, want: models.AlertLevelWarning},
+		{perCore: 3, threshold: 1.5, want: models.AlertLevelCritical},
+		{perCore: 1, threshold: 0, want: models.AlertLevelInfo},
+	}
+	for _, c := range cases {
+		if got := calculateLoadLevel(c.perCore, c.threshold); got != c.want {
+			t.Errorf("calculateLoadLevel(%v, %v) = %s, want %s", c.perCore, c.threshold, got, c.want)
+		}
+	}
+}
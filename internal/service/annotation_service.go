@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/repo"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAnnotationTextLength 注释内容的最大长度（字符）
+	maxAnnotationTextLength = 1000
+	// maxAnnotationTags 单条注释最多的标签数
+	maxAnnotationTags = 16
+)
+
+// ErrInvalidAnnotation 注释参数无效
+var ErrInvalidAnnotation = errors.New("无效的注释")
+
+// AnnotationInput 创建或修改注释的参数
+type AnnotationInput struct {
+	AgentID string   `json:"agentId"` // 探针ID，为空表示全局注释
+	Time    int64    `json:"time"`    // 事件时间（时间戳毫秒），为 0 时使用当前时间
+	EndTime int64    `json:"endTime"` // 结束时间（时间戳毫秒），0 表示时间点事件
+	Text    string   `json:"text"`    // 注释内容
+	Tags    []string `json:"tags"`    // 标签
+}
+
+// AnnotationService 时间序列注释服务，与告警无关，仅用于在图表上标记事件
+type AnnotationService struct {
+	logger         *zap.Logger
+	AnnotationRepo *repo.AnnotationRepo
+	agentRepo      *repo.AgentRepo
+}
+
+func NewAnnotationService(logger *zap.Logger, db *gorm.DB) *AnnotationService {
+	return &AnnotationService{
+		logger:         logger,
+		AnnotationRepo: repo.NewAnnotationRepo(db),
+		agentRepo:      repo.NewAgentRepo(db),
+	}
+}
+
+// Create 创建注释
+func (s *AnnotationService) Create(ctx context.Context, input AnnotationInput, source, createdBy string) (*models.Annotation, error) {
+	annotation := &models.Annotation{
+		ID:        uuid.NewString(),
+		Source:    source,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if err := s.apply(ctx, annotation, input); err != nil {
+		return nil, err
+	}
+	if err := s.AnnotationRepo.Create(ctx, annotation); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("创建注释",
+		zap.String("id", annotation.ID),
+		zap.String("agentId", annotation.AgentID),
+		zap.String("source", source),
+		zap.String("createdBy", createdBy),
+	)
+	return annotation, nil
+}
+
+// Update 修改注释，注释不存在时返回 gorm.ErrRecordNotFound
+func (s *AnnotationService) Update(ctx context.Context, id string, input AnnotationInput) (*models.Annotation, error) {
+	annotation, err := s.AnnotationRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(ctx, &annotation, input); err != nil {
+		return nil, err
+	}
+	if err := s.AnnotationRepo.UpdateById(ctx, &annotation); err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+// Delete 删除注释
+func (s *AnnotationService) Delete(ctx context.Context, id string) error {
+	return s.AnnotationRepo.DeleteById(ctx, id)
+}
+
+// ListInRange 查询与时间窗口有重叠的注释，agentID 不为空时包含全局注释；tag 不为空时只返回带该标签的注释
+func (s *AnnotationService) ListInRange(ctx context.Context, agentID string, start, end int64, tag string) ([]models.Annotation, error) {
+	annotations, err := s.AnnotationRepo.FindOverlapping(ctx, agentID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		return annotations, nil
+	}
+	filtered := annotations[:0]
+	for _, annotation := range annotations {
+		for _, t := range annotation.Tags {
+			if t == tag {
+				filtered = append(filtered, annotation)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// apply 校验参数并写入注释
+func (s *AnnotationService) apply(ctx context.Context, annotation *models.Annotation, input AnnotationInput) error {
+	text := strings.TrimSpace(input.Text)
+	if text == "" {
+		return fmt.Errorf("%w: 内容不能为空", ErrInvalidAnnotation)
+	}
+	if utf8.RuneCountInString(text) > maxAnnotationTextLength {
+		return fmt.Errorf("%w: 内容不能超过%d个字符", ErrInvalidAnnotation, maxAnnotationTextLength)
+	}
+
+	at := input.Time
+	if at < 0 {
+		return fmt.Errorf("%w: 无效的时间", ErrInvalidAnnotation)
+	}
+	if at == 0 {
+		at = time.Now().UnixMilli()
+	}
+	if input.EndTime != 0 && input.EndTime < at {
+		return fmt.Errorf("%w: 结束时间不能早于开始时间", ErrInvalidAnnotation)
+	}
+
+	tags, err := normalizeAnnotationTags(input.Tags)
+	if err != nil {
+		return err
+	}
+
+	agentID := strings.TrimSpace(input.AgentID)
+	if agentID != "" {
+		if _, err := s.agentRepo.FindById(ctx, agentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: 探针不存在", ErrInvalidAnnotation)
+			}
+			return err
+		}
+	}
+
+	annotation.AgentID = agentID
+	annotation.Time = at
+	annotation.EndTime = input.EndTime
+	annotation.Text = text
+	annotation.Tags = tags
+	return nil
+}
+
+// normalizeAnnotationTags 去除空白和重复的标签
+func normalizeAnnotationTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxAnnotationTags {
+		return nil, fmt.Errorf("%w: 标签不能超过%d个", ErrInvalidAnnotation, maxAnnotationTags)
+	}
+	return normalized, nil
+}
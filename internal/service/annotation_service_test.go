@@ -0,0 +1,26 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAnnotationTags(t *testing.T) {
+	tags, err := normalizeAnnotationTags([]string{" deploy ", "", "deploy", "ci", "  "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"deploy", "ci"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("tags = %v, want %v", tags, want)
+	}
+
+	tooMany := make([]string, maxAnnotationTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+	if _, err := normalizeAnnotationTags(tooMany); !errors.Is(err, ErrInvalidAnnotation) {
+		t.Fatalf("expected ErrInvalidAnnotation, got %v", err)
+	}
+}
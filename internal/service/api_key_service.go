@@ -28,6 +28,11 @@ func NewApiKeyService(logger *zap.Logger, db *gorm.DB) *ApiKeyService {
 
 // GenerateApiKey 生成API密钥
 func (s *ApiKeyService) GenerateApiKey(ctx context.Context, name, userID string) (*models.ApiKey, error) {
+	return s.GenerateScopedApiKey(ctx, name, userID, nil)
+}
+
+// GenerateScopedApiKey 生成API密钥，使用该密钥注册的新探针会自动分配 tags
+func (s *ApiKeyService) GenerateScopedApiKey(ctx context.Context, name, userID string, tags []string) (*models.ApiKey, error) {
 	// 生成32字节随机密钥
 	key, err := s.generateSecureKey(32)
 	if err != nil {
@@ -40,6 +45,7 @@ func (s *ApiKeyService) GenerateApiKey(ctx context.Context, name, userID string)
 		Name:      name,
 		Key:       key,
 		Enabled:   true,
+		Tags:      tags,
 		CreatedBy: userID,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultArchiveMetricsInterval 指标归档默认的聚合步长（秒）
+	defaultArchiveMetricsInterval = 3600
+	// archiveMetricsMaxDaysPerRun 单次归档最多处理的天数，积压时分多次完成
+	archiveMetricsMaxDaysPerRun = 7
+)
+
+// archiveMetricTypes 归档的指标类型，服务监控按监控项统计，不在探针指标中归档
+var archiveMetricTypes = []string{"cpu", "memory", "disk", "network", "network_connection", "disk_io", "gpu", "temperature", "load"}
+
+// ArchiveMetricsProgress 指标归档进度，archivedUntil 之前（不含）的整天已写入对象存储
+type ArchiveMetricsProgress struct {
+	ArchivedUntil int64 `json:"archivedUntil"` // UTC 零点时间戳（毫秒）
+}
+
+// archivedMetricRow 归档的一行聚合指标
+type archivedMetricRow struct {
+	AgentID   string  `json:"agentId"`
+	Type      string  `json:"type"`
+	Timestamp int64   `json:"timestamp"`
+	Series    string  `json:"series"`
+	Labels    string  `json:"labels,omitempty"`
+	Value     float64 `json:"value"`
+}
+
+// ArchiveMetrics 按 UTC 自然日将探针指标按 MetricsInterval 聚合后写入对象存储，返回归档的天数
+// 只归档已结束的整天；对象键由日期决定，写入进度失败后重试会覆盖同一对象而不会重复
+// 首次启用时从数据保留期内最早的完整一天开始，早于 VictoriaMetrics 保留期的数据已无法查询
+func (s *ArchiveService) ArchiveMetrics(ctx context.Context, config *models.ArchiveConfig) (int, error) {
+	client, err := newS3Client(s.httpClient, config.Endpoint, config.Region, config.Bucket, config.AccessKeyID, config.SecretAccessKey, config.UsePathStyle)
+	if err != nil {
+		return 0, err
+	}
+
+	progress, err := s.getArchiveMetricsProgress(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := time.UnixMilli(progress.ArchivedUntil).UTC()
+	if earliest := today.AddDate(0, 0, -config.ArchiveAfterDays+1); day.Before(earliest) {
+		day = earliest
+	}
+
+	agents, err := s.agentRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	step := time.Duration(config.MetricsInterval) * time.Second
+	archived := 0
+	for ; day.Before(today) && archived < archiveMetricsMaxDaysPerRun; day = day.AddDate(0, 0, 1) {
+		start, end := day.UnixMilli(), day.AddDate(0, 0, 1).UnixMilli()-1
+		page := ExportPage{Step: step, After: start - 1, End: end}
+
+		var rows []archivedMetricRow
+		for _, agent := range agents {
+			for _, metricType := range archiveMetricTypes {
+				err := s.metricService.ExportMetrics(ctx, agent.ID, metricType, "all", nil, page, func(exported []ExportRow) error {
+					for _, row := range exported {
+						rows = append(rows, archivedMetricRow{
+							AgentID:   agent.ID,
+							Type:      metricType,
+							Timestamp: row.Timestamp,
+							Series:    row.Series,
+							Labels:    row.Labels,
+							Value:     row.Value,
+						})
+					}
+					return nil
+				})
+				if err != nil {
+					return archived, fmt.Errorf("查询 %s 的 %s 指标失败: %w", agent.ID, metricType, err)
+				}
+			}
+		}
+
+		body, err := encodeMetricsArchive(rows, config.Format)
+		if err != nil {
+			return archived, err
+		}
+		key := archiveMetricsObjectKey(config.Prefix, day, config.Format)
+		if err := client.PutObject(ctx, key, body, archiveContentType(config.Format), "gzip"); err != nil {
+			return archived, err
+		}
+
+		progress.ArchivedUntil = day.AddDate(0, 0, 1).UnixMilli()
+		if err := s.propertyService.Set(ctx, PropertyIDArchiveMetricsProgress, "指标归档进度", progress); err != nil {
+			return archived, fmt.Errorf("保存指标归档进度失败: %w", err)
+		}
+
+		archived++
+		s.logger.Info("指标已归档",
+			zap.String("key", key),
+			zap.Int("agents", len(agents)),
+			zap.Int("rows", len(rows)))
+	}
+	return archived, nil
+}
+
+func (s *ArchiveService) getArchiveMetricsProgress(ctx context.Context) (*ArchiveMetricsProgress, error) {
+	var progress ArchiveMetricsProgress
+	if err := s.propertyService.GetValue(ctx, PropertyIDArchiveMetricsProgress, &progress); err != nil {
+		return nil, fmt.Errorf("获取指标归档进度失败: %w", err)
+	}
+	return &progress, nil
+}
+
+// archiveMetricsObjectKey 生成指标归档对象键，如 pika/metrics/2025/01/02/metrics-20250102.json.gz
+func archiveMetricsObjectKey(prefix string, day time.Time, format models.ArchiveFormat) string {
+	name := fmt.Sprintf("metrics-%s.%s.gz", day.Format("20060102"), format)
+	return path.Join(strings.Trim(prefix, "/"), "metrics", day.Format("2006/01/02"), name)
+}
+
+// encodeMetricsArchive 按归档格式编码聚合指标并 gzip 压缩
+func encodeMetricsArchive(rows []archivedMetricRow, format models.ArchiveFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+
+	var err error
+	if format == models.ArchiveFormatNDJSON {
+		for _, row := range rows {
+			if err = encoder.Encode(row); err != nil {
+				break
+			}
+		}
+	} else {
+		if rows == nil {
+			rows = []archivedMetricRow{}
+		}
+		err = encoder.Encode(rows)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码指标归档数据失败: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("压缩指标归档数据失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
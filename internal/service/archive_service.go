@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/repo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// archiveBatchSize 每个归档对象包含的最大记录数
+	archiveBatchSize = 1000
+	// archiveMaxBatchesPerRun 单次归档最多处理的批次数，避免积压时长时间占用数据库
+	archiveMaxBatchesPerRun = 50
+)
+
+// archivedAlertRecord 归档的告警记录，附带该记录的备注
+type archivedAlertRecord struct {
+	models.AlertRecord
+	Comments []models.AlertComment `json:"comments,omitempty"`
+}
+
+// ArchiveService 告警记录归档服务，将已恢复的历史告警写入 S3 兼容对象存储
+type ArchiveService struct {
+	logger          *zap.Logger
+	propertyService *PropertyService
+	metricService   *MetricService
+	agentRepo       *repo.AgentRepo
+	httpClient      *http.Client
+
+	AlertRecordRepo  *repo.AlertRecordRepo
+	AlertCommentRepo *repo.AlertCommentRepo
+}
+
+func NewArchiveService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, metricService *MetricService) *ArchiveService {
+	return &ArchiveService{
+		logger:           logger,
+		propertyService:  propertyService,
+		metricService:    metricService,
+		agentRepo:        repo.NewAgentRepo(db),
+		httpClient:       &http.Client{Timeout: 60 * time.Second},
+		AlertRecordRepo:  repo.NewAlertRecordRepo(db),
+		AlertCommentRepo: repo.NewAlertCommentRepo(db),
+	}
+}
+
+// Run 启动归档调度
+func (s *ArchiveService) Run(ctx context.Context) {
+	s.logger.Info("告警记录归档定时任务已启动")
+
+	for {
+		interval := 10 * time.Minute
+
+		config, err := s.propertyService.GetArchiveConfig(ctx)
+		if err != nil {
+			s.logger.Error("获取归档配置失败", zap.Error(err))
+		} else if config.Enabled {
+			interval = time.Duration(config.IntervalMinutes) * time.Minute
+			if _, err := s.ArchiveAlertRecords(ctx, config); err != nil {
+				s.logger.Error("归档告警记录失败", zap.Error(err))
+			}
+			if config.ArchiveMetrics {
+				if _, err := s.ArchiveMetrics(ctx, config); err != nil {
+					s.logger.Error("归档指标数据失败", zap.Error(err))
+				}
+			}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.logger.Info("告警记录归档定时任务已停止")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// ArchiveAlertRecords 归档恢复时间超过 ArchiveAfterDays 的告警记录，返回归档的记录数
+// 已归档的记录通过 archived_at 标记跳过；对象键由记录ID范围决定，标记失败后重试会覆盖同一对象而不会重复
+func (s *ArchiveService) ArchiveAlertRecords(ctx context.Context, config *models.ArchiveConfig) (int, error) {
+	client, err := newS3Client(s.httpClient, config.Endpoint, config.Region, config.Bucket, config.AccessKeyID, config.SecretAccessKey, config.UsePathStyle)
+	if err != nil {
+		return 0, err
+	}
+
+	before := time.Now().AddDate(0, 0, -config.ArchiveAfterDays).UnixMilli()
+	total := 0
+	for i := 0; i < archiveMaxBatchesPerRun; i++ {
+		records, err := s.AlertRecordRepo.FindArchivable(ctx, before, archiveBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		ids := make([]int64, len(records))
+		for j, record := range records {
+			ids[j] = record.ID
+		}
+		// 备注随记录一起归档，开启归档后删除时备注不会丢失
+		comments, err := s.AlertCommentRepo.ListByAlertRecordIDs(ctx, ids)
+		if err != nil {
+			return total, fmt.Errorf("查询告警备注失败: %w", err)
+		}
+
+		body, err := encodeArchive(withComments(records, comments), config.Format)
+		if err != nil {
+			return total, err
+		}
+		key := archiveObjectKey(config.Prefix, records, config.Format)
+		if err := client.PutObject(ctx, key, body, archiveContentType(config.Format), "gzip"); err != nil {
+			return total, err
+		}
+
+		if err := s.AlertRecordRepo.MarkArchived(ctx, ids, time.Now().UnixMilli()); err != nil {
+			return total, fmt.Errorf("标记归档状态失败: %w", err)
+		}
+		if config.DeleteAfterArchive {
+			if err := s.AlertRecordRepo.DeleteArchived(ctx, ids); err != nil {
+				return total, fmt.Errorf("删除已归档记录失败: %w", err)
+			}
+			if err := s.AlertCommentRepo.DeleteByAlertRecordIDs(ctx, ids); err != nil {
+				return total, fmt.Errorf("删除已归档记录的备注失败: %w", err)
+			}
+		}
+
+		total += len(records)
+		s.logger.Info("告警记录已归档",
+			zap.String("key", key),
+			zap.Int("count", len(records)),
+			zap.Int("comments", len(comments)),
+			zap.Bool("deleted", config.DeleteAfterArchive))
+
+		if len(records) < archiveBatchSize {
+			break
+		}
+	}
+	return total, nil
+}
+
+// archiveObjectKey 生成归档对象键，如 pika/alert-records/2025/01/02/alert-records-100-1099.json.gz
+func archiveObjectKey(prefix string, records []models.AlertRecord, format models.ArchiveFormat) string {
+	first, last := records[0], records[len(records)-1]
+	day := time.UnixMilli(first.ResolvedAt).UTC().Format("2006/01/02")
+	name := fmt.Sprintf("alert-records-%d-%d.%s.gz", first.ID, last.ID, format)
+	return path.Join(strings.Trim(prefix, "/"), "alert-records", day, name)
+}
+
+// withComments 将备注按告警记录ID附加到对应的记录
+func withComments(records []models.AlertRecord, comments []models.AlertComment) []archivedAlertRecord {
+	byRecord := make(map[int64][]models.AlertComment)
+	for _, comment := range comments {
+		byRecord[comment.AlertRecordID] = append(byRecord[comment.AlertRecordID], comment)
+	}
+	archived := make([]archivedAlertRecord, len(records))
+	for i, record := range records {
+		archived[i] = archivedAlertRecord{AlertRecord: record, Comments: byRecord[record.ID]}
+	}
+	return archived
+}
+
+// encodeArchive 按归档格式编码并 gzip 压缩
+func encodeArchive(records []archivedAlertRecord, format models.ArchiveFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+
+	var err error
+	if format == models.ArchiveFormatNDJSON {
+		for _, record := range records {
+			if err = encoder.Encode(record); err != nil {
+				break
+			}
+		}
+	} else {
+		err = encoder.Encode(records)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("编码归档数据失败: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("压缩归档数据失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func archiveContentType(format models.ArchiveFormat) string {
+	if format == models.ArchiveFormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
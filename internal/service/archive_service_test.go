@@ -0,0 +1,42 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestEncodeArchiveWithComments(t *testing.T) {
+	records := []models.AlertRecord{{ID: 1, AgentID: "a1"}, {ID: 2, AgentID: "a1"}}
+	comments := []models.AlertComment{
+		{ID: 10, AlertRecordID: 2, Text: "first"},
+		{ID: 11, AlertRecordID: 2, Text: "second"},
+	}
+
+	body, err := encodeArchive(withComments(records, comments), models.ArchiveFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.NewDecoder(gz).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0]["id"] != float64(1) || got[0]["agentId"] != "a1" {
+		t.Fatalf("records should keep their fields: %+v", got)
+	}
+	if _, ok := got[0]["comments"]; ok {
+		t.Fatalf("record without comments should omit the field: %+v", got[0])
+	}
+	list, _ := got[1]["comments"].([]any)
+	if len(list) != 2 || list[0].(map[string]any)["text"] != "first" {
+		t.Fatalf("comments not archived with record: %+v", got[1])
+	}
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// 安全检查状态
+const (
+	auditStatusPass = "pass"
+	auditStatusWarn = "warn"
+	auditStatusFail = "fail"
+	auditStatusSkip = "skip"
+)
+
+// 风险评分规则：每个 fail 子项按严重程度扣分，warn 子项扣一半，累计封顶 100
+// 安全评分 = 100 - 风险评分，便于界面展示评分趋势
+var auditSeverityPenalty = map[string]int{
+	"high":   20,
+	"medium": 10,
+	"low":    4,
+}
+
+// sensitivePorts 不应直接暴露到公网的服务端口
+var sensitivePorts = map[uint32]string{
+	2375:  "Docker API",
+	3306:  "MySQL",
+	5432:  "PostgreSQL",
+	6379:  "Redis",
+	9200:  "Elasticsearch",
+	11211: "Memcached",
+	27017: "MongoDB",
+}
+
+// commonPublicPorts 通常需要对外开放的端口
+var commonPublicPorts = map[uint32]bool{
+	22:  true,
+	80:  true,
+	443: true,
+}
+
+// analyzeVPSAudit 在服务端分析探针采集的资产清单，生成安全检查结果、风险评分和修复建议
+func analyzeVPSAudit(result *protocol.VPSAuditResult) *protocol.VPSAuditAnalysis {
+	checks := []protocol.SecurityCheck{
+		checkPublicPorts(result.AssetInventory.NetworkAssets),
+		checkSSHConfig(result.AssetInventory.UserAssets),
+		checkFirewall(result.AssetInventory.NetworkAssets),
+		checkPackages(),
+		checkAccounts(result.AssetInventory.UserAssets),
+		checkProcesses(result.AssetInventory.ProcessAssets, result.AssetInventory.FileAssets),
+	}
+
+	riskScore := 0
+	var recommendations []string
+	for _, check := range checks {
+		for _, detail := range check.Details {
+			penalty := auditSeverityPenalty[detail.Severity]
+			switch detail.Status {
+			case auditStatusFail:
+				riskScore += penalty
+			case auditStatusWarn:
+				riskScore += penalty / 2
+			default:
+				continue
+			}
+			if recommendation := auditRecommendation(check.Category, detail.Name); recommendation != "" {
+				recommendations = appendUnique(recommendations, recommendation)
+			}
+		}
+	}
+	if riskScore > 100 {
+		riskScore = 100
+	}
+
+	return &protocol.VPSAuditAnalysis{
+		SecurityChecks:  checks,
+		RiskScore:       riskScore,
+		ThreatLevel:     auditThreatLevel(riskScore),
+		Recommendations: recommendations,
+		AnalyzedAt:      time.Now().UnixMilli(),
+	}
+}
+
+// auditThreatLevel 根据风险评分计算威胁等级
+func auditThreatLevel(riskScore int) string {
+	switch {
+	case riskScore >= 60:
+		return "critical"
+	case riskScore >= 40:
+		return "high"
+	case riskScore >= 20:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// countAuditChecks 统计各状态的检查项数量（按检查类别）
+func countAuditChecks(analysis *protocol.VPSAuditAnalysis) (pass, fail, warn, total int) {
+	for _, check := range analysis.SecurityChecks {
+		switch check.Status {
+		case auditStatusPass:
+			pass++
+		case auditStatusFail:
+			fail++
+		case auditStatusWarn:
+			warn++
+		}
+		total++
+	}
+	return pass, fail, warn, total
+}
+
+// newSecurityCheck 根据子项汇总检查类别状态：任一 fail 为 fail，任一 warn 为 warn，全部 skip 为 skip
+func newSecurityCheck(category, passMessage string, details []protocol.SecurityCheckSub) protocol.SecurityCheck {
+	status := auditStatusSkip
+	failCount, warnCount := 0, 0
+	for _, detail := range details {
+		switch detail.Status {
+		case auditStatusFail:
+			failCount++
+		case auditStatusWarn:
+			warnCount++
+		case auditStatusPass:
+			if status == auditStatusSkip {
+				status = auditStatusPass
+			}
+		}
+	}
+
+	message := passMessage
+	switch {
+	case failCount > 0:
+		status = auditStatusFail
+		message = fmt.Sprintf("发现 %d 个高风险项，%d 个警告项", failCount, warnCount)
+	case warnCount > 0:
+		status = auditStatusWarn
+		message = fmt.Sprintf("发现 %d 个警告项", warnCount)
+	case status == auditStatusSkip && len(details) > 0:
+		message = details[0].Message
+	}
+
+	return protocol.SecurityCheck{
+		Category: category,
+		Status:   status,
+		Message:  message,
+		Details:  details,
+	}
+}
+
+func skipDetail(name, message string) protocol.SecurityCheckSub {
+	return protocol.SecurityCheckSub{Name: name, Status: auditStatusSkip, Message: message}
+}
+
+// checkPublicPorts 检查公网监听端口
+func checkPublicPorts(assets *protocol.NetworkAssets) protocol.SecurityCheck {
+	const category = "public_ports"
+	if assets == nil {
+		return newSecurityCheck(category, "", []protocol.SecurityCheckSub{skipDetail("listening_ports", "未采集到网络资产")})
+	}
+
+	var details []protocol.SecurityCheckSub
+	seen := make(map[string]bool)
+	for _, port := range assets.ListeningPorts {
+		if !port.IsPublic {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d", port.Protocol, port.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		evidence := fmt.Sprintf("%s:%d (%s)", port.Address, port.Port, port.ProcessName)
+		if service, ok := sensitivePorts[port.Port]; ok {
+			details = append(details, protocol.SecurityCheckSub{
+				Name:     "sensitive_port",
+				Status:   auditStatusFail,
+				Severity: "high",
+				Message:  fmt.Sprintf("%s 端口 %d 对公网开放", service, port.Port),
+				Evidence: evidence,
+			})
+		} else if !commonPublicPorts[port.Port] {
+			details = append(details, protocol.SecurityCheckSub{
+				Name:     "public_port",
+				Status:   auditStatusWarn,
+				Severity: "low",
+				Message:  fmt.Sprintf("端口 %s 对公网开放", key),
+				Evidence: evidence,
+			})
+		}
+	}
+
+	if len(details) == 0 {
+		details = append(details, protocol.SecurityCheckSub{
+			Name:    "listening_ports",
+			Status:  auditStatusPass,
+			Message: "未发现非常用端口对公网开放",
+		})
+	}
+	return newSecurityCheck(category, "公网监听端口正常", details)
+}
+
+// checkSSHConfig 检查 SSH 配置
+func checkSSHConfig(assets *protocol.UserAssets) protocol.SecurityCheck {
+	const category = "ssh_config"
+	if assets == nil || assets.SSHConfig == nil {
+		return newSecurityCheck(category, "", []protocol.SecurityCheckSub{skipDetail("sshd_config", "未采集到 SSH 配置")})
+	}
+	cfg := assets.SSHConfig
+
+	check := func(name string, ok bool, status, severity, failMessage, passMessage string) protocol.SecurityCheckSub {
+		if ok {
+			return protocol.SecurityCheckSub{Name: name, Status: auditStatusPass, Message: passMessage}
+		}
+		return protocol.SecurityCheckSub{Name: name, Status: status, Severity: severity, Message: failMessage}
+	}
+
+	details := []protocol.SecurityCheckSub{
+		check("permit_root_login", !strings.EqualFold(cfg.PermitRootLogin, "yes"), auditStatusFail, "high",
+			"允许 root 使用密码登录", "已限制 root 登录"),
+		check("permit_empty_passwords", !cfg.PermitEmptyPasswords, auditStatusFail, "high",
+			"允许空密码登录", "禁止空密码登录"),
+		check("password_authentication", !cfg.PasswordAuthentication, auditStatusWarn, "medium",
+			"允许密码认证", "已禁用密码认证"),
+		check("max_auth_tries", cfg.MaxAuthTries == 0 || cfg.MaxAuthTries <= 6, auditStatusWarn, "low",
+			fmt.Sprintf("最大认证尝试次数过高: %d", cfg.MaxAuthTries), "认证尝试次数限制合理"),
+	}
+	return newSecurityCheck(category, "SSH 配置安全", details)
+}
+
+// checkFirewall 检查防火墙状态
+func checkFirewall(assets *protocol.NetworkAssets) protocol.SecurityCheck {
+	const category = "firewall"
+	if assets == nil || assets.FirewallRules == nil {
+		return newSecurityCheck(category, "", []protocol.SecurityCheckSub{skipDetail("firewall_status", "未采集到防火墙信息")})
+	}
+
+	firewall := assets.FirewallRules
+	detail := protocol.SecurityCheckSub{
+		Name:     "firewall_status",
+		Status:   auditStatusPass,
+		Message:  fmt.Sprintf("%s 防火墙已启用", firewall.Type),
+		Evidence: fmt.Sprintf("%d 条规则", len(firewall.Rules)),
+	}
+	if !strings.EqualFold(firewall.Status, "active") {
+		detail.Status = auditStatusFail
+		detail.Severity = "medium"
+		detail.Message = "防火墙未启用"
+	}
+	return newSecurityCheck(category, "防火墙已启用", []protocol.SecurityCheckSub{detail})
+}
+
+// checkPackages 检查过期软件包，探针目前未采集软件包清单
+func checkPackages() protocol.SecurityCheck {
+	return newSecurityCheck("packages", "", []protocol.SecurityCheckSub{skipDetail("outdated_packages", "探针未采集软件包信息")})
+}
+
+// checkAccounts 检查特权账户
+func checkAccounts(assets *protocol.UserAssets) protocol.SecurityCheck {
+	const category = "accounts"
+	if assets == nil {
+		return newSecurityCheck(category, "", []protocol.SecurityCheckSub{skipDetail("system_users", "未采集到用户资产")})
+	}
+
+	var details []protocol.SecurityCheckSub
+	for _, user := range assets.SystemUsers {
+		if user.IsRootEquiv && user.Username != "root" {
+			details = append(details, protocol.SecurityCheckSub{
+				Name:     "root_equivalent_user",
+				Status:   auditStatusFail,
+				Severity: "high",
+				Message:  fmt.Sprintf("用户 %s 的 UID 为 0", user.Username),
+				Evidence: user.Username,
+			})
+		}
+	}
+	for _, sudo := range assets.SudoUsers {
+		if sudo.NoPasswd {
+			details = append(details, protocol.SecurityCheckSub{
+				Name:     "sudo_nopasswd",
+				Status:   auditStatusWarn,
+				Severity: "medium",
+				Message:  fmt.Sprintf("用户 %s 可免密使用 sudo", sudo.Username),
+				Evidence: sudo.Rules,
+			})
+		}
+	}
+
+	if len(details) == 0 {
+		details = append(details, protocol.SecurityCheckSub{
+			Name:    "system_users",
+			Status:  auditStatusPass,
+			Message: "未发现异常特权账户",
+		})
+	}
+	return newSecurityCheck(category, "账户权限正常", details)
+}
+
+// checkProcesses 检查可疑进程和临时目录可执行文件
+func checkProcesses(processAssets *protocol.ProcessAssets, fileAssets *protocol.FileAssets) protocol.SecurityCheck {
+	const category = "processes"
+	if processAssets == nil && fileAssets == nil {
+		return newSecurityCheck(category, "", []protocol.SecurityCheckSub{skipDetail("suspicious_processes", "未采集到进程和文件资产")})
+	}
+
+	var details []protocol.SecurityCheckSub
+	if processAssets != nil {
+		for _, process := range processAssets.SuspiciousProcesses {
+			details = append(details, protocol.SecurityCheckSub{
+				Name:     "suspicious_process",
+				Status:   auditStatusFail,
+				Severity: "high",
+				Message:  fmt.Sprintf("可疑进程 %s (PID %d)", process.Name, process.PID),
+				Evidence: process.Exe,
+			})
+		}
+	}
+	if fileAssets != nil {
+		for _, file := range fileAssets.TmpExecutables {
+			details = append(details, protocol.SecurityCheckSub{
+				Name:     "tmp_executable",
+				Status:   auditStatusWarn,
+				Severity: "medium",
+				Message:  "临时目录存在可执行文件",
+				Evidence: file.Path,
+			})
+		}
+	}
+
+	if len(details) == 0 {
+		details = append(details, protocol.SecurityCheckSub{
+			Name:    "suspicious_processes",
+			Status:  auditStatusPass,
+			Message: "未发现可疑进程",
+		})
+	}
+	return newSecurityCheck(category, "进程状态正常", details)
+}
+
+// auditRecommendation 获取检查项对应的修复建议
+func auditRecommendation(category, name string) string {
+	switch category + "." + name {
+	case "public_ports.sensitive_port":
+		return "数据库、缓存等服务应只监听内网地址，或通过防火墙限制来源 IP"
+	case "public_ports.public_port":
+		return "关闭不需要对外提供服务的端口"
+	case "ssh_config.permit_root_login":
+		return "将 PermitRootLogin 设置为 no 或 prohibit-password"
+	case "ssh_config.permit_empty_passwords":
+		return "将 PermitEmptyPasswords 设置为 no"
+	case "ssh_config.password_authentication":
+		return "使用密钥登录并将 PasswordAuthentication 设置为 no"
+	case "ssh_config.max_auth_tries":
+		return "将 MaxAuthTries 设置为 6 以下"
+	case "firewall.firewall_status":
+		return "启用防火墙并仅放行必要端口"
+	case "accounts.root_equivalent_user":
+		return "检查 UID 为 0 的非 root 用户，确认是否为后门账户"
+	case "accounts.sudo_nopasswd":
+		return "移除 sudo 规则中的 NOPASSWD"
+	case "processes.suspicious_process":
+		return "排查可执行文件已删除的进程，确认是否为恶意程序"
+	case "processes.tmp_executable":
+		return "清理临时目录中的可执行文件，并为 /tmp 设置 noexec"
+	default:
+		return ""
+	}
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
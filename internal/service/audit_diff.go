@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/go-orz/orz"
+	"gorm.io/gorm"
+)
+
+// auditDiffSections 参与对比的审计结果部分
+var auditDiffSections = []string{"systemInfo", "statistics"}
+
+// DiffAuditResults 对比同一探针的两次审计结果，返回系统信息和统计摘要的新增、移除和变更项
+// 按原始 JSON 逐字段对比，两次审计的结构版本不同时，仅存在于一方的字段记为新增或移除
+func (s *AgentService) DiffAuditResults(ctx context.Context, agentID string, auditIDA, auditIDB int64) (*models.AuditDiff, error) {
+	before, err := s.loadAuditSections(ctx, agentID, auditIDA)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.loadAuditSections(ctx, agentID, auditIDB)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.AuditDiff{
+		AgentID:  agentID,
+		AuditIDA: auditIDA,
+		AuditIDB: auditIDB,
+		Changes:  []models.AuditDiffEntry{},
+	}
+	for _, section := range auditDiffSections {
+		beforeFields := make(map[string]interface{})
+		afterFields := make(map[string]interface{})
+		flattenAuditFields("", before[section], beforeFields)
+		flattenAuditFields("", after[section], afterFields)
+		diff.Changes = append(diff.Changes, diffAuditFields(section, beforeFields, afterFields)...)
+	}
+	return diff, nil
+}
+
+// loadAuditSections 以通用结构读取审计结果，避免结构版本不一致导致解析失败
+func (s *AgentService) loadAuditSections(ctx context.Context, agentID string, auditID int64) (map[string]interface{}, error) {
+	record, err := s.AgentRepo.FindAuditResult(ctx, agentID, auditID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, orz.NewError(404, "审计结果不存在")
+		}
+		return nil, err
+	}
+
+	var sections map[string]interface{}
+	if err := json.Unmarshal([]byte(record.Result), &sections); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// flattenAuditFields 将嵌套对象展开为 路径 -> 值，数组作为整体比较
+func flattenAuditFields(prefix string, value interface{}, fields map[string]interface{}) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		if prefix != "" && value != nil {
+			fields[prefix] = value
+		}
+		return
+	}
+	for key, child := range object {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		flattenAuditFields(path, child, fields)
+	}
+}
+
+// diffAuditFields 对比展开后的字段，按路径排序返回差异项
+func diffAuditFields(section string, before, after map[string]interface{}) []models.AuditDiffEntry {
+	var entries []models.AuditDiffEntry
+	for path, beforeValue := range before {
+		afterValue, ok := after[path]
+		switch {
+		case !ok:
+			entries = append(entries, models.AuditDiffEntry{Section: section, Path: path, Kind: "removed", Before: beforeValue})
+		case !reflect.DeepEqual(beforeValue, afterValue):
+			entries = append(entries, models.AuditDiffEntry{Section: section, Path: path, Kind: "changed", Before: beforeValue, After: afterValue})
+		}
+	}
+	for path, afterValue := range after {
+		if _, ok := before[path]; !ok {
+			entries = append(entries, models.AuditDiffEntry{Section: section, Path: path, Kind: "added", After: afterValue})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type operatorContextKey struct{}
+
+// WithOperator 将当前操作用户写入 context，用于配置变更审计
+func WithOperator(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, operatorContextKey{}, username)
+}
+
+// OperatorFromContext 从 context 中获取当前操作用户
+func OperatorFromContext(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(operatorContextKey{}).(string)
+	return username, ok && username != ""
+}
+
+// recordConfigAudit 记录配置变更审计日志（仅记录由已认证用户发起的变更）
+func (s *PropertyService) recordConfigAudit(ctx context.Context, id, name, before, after string) {
+	username, ok := OperatorFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	changes := diffConfigValues(id, before, after)
+	if len(changes) == 0 {
+		return
+	}
+
+	log := &models.ConfigAuditLog{
+		ID:           uuid.NewString(),
+		PropertyID:   id,
+		PropertyName: name,
+		Username:     username,
+		Changes:      changes,
+		CreatedAt:    time.Now().UnixMilli(),
+	}
+	if err := s.ConfigAuditLogRepo.Create(ctx, log); err != nil {
+		s.logger.Error("记录配置审计日志失败", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// configAuditRedactedFields 整体脱敏的配置对象：配置ID -> 字段路径中的对象名，为空表示整个配置
+// 通知渠道和 DNS 服务商的 config、归档配置中的 URL、请求头、存储地址都可能携带凭据
+var configAuditRedactedFields = map[string]string{
+	PropertyIDNotificationChannels: "config",
+	PropertyIDDNSProviders:         "config",
+	PropertyIDArchiveConfig:        "",
+}
+
+// sensitiveFieldKeywords 字段路径中任一段包含这些关键字时脱敏
+var sensitiveFieldKeywords = []string{"secret", "key", "token", "password", "url", "webhook", "authorization", "header", "endpoint"}
+
+// diffConfigValues 比较两个 JSON 值，返回变更的字段列表，敏感字段的值以 **** 代替
+func diffConfigValues(propertyID, before, after string) []models.ConfigChange {
+	beforeFields := make(map[string]interface{})
+	afterFields := make(map[string]interface{})
+	flattenConfigValue("", parseConfigValue(before), beforeFields)
+	flattenConfigValue("", parseConfigValue(after), afterFields)
+
+	fields := make(map[string]struct{}, len(beforeFields)+len(afterFields))
+	for field := range beforeFields {
+		fields[field] = struct{}{}
+	}
+	for field := range afterFields {
+		fields[field] = struct{}{}
+	}
+
+	var changes []models.ConfigChange
+	for field := range fields {
+		b, a := beforeFields[field], afterFields[field]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		if isSensitiveField(propertyID, field) {
+			b, a = maskConfigValue(b), maskConfigValue(a)
+		}
+		changes = append(changes, models.ConfigChange{Field: field, Before: b, After: a})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+func parseConfigValue(raw string) interface{} {
+	if raw == "" {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return raw
+	}
+	return value
+}
+
+// flattenConfigValue 将嵌套的 JSON 值展开为 字段路径 -> 值
+func flattenConfigValue(prefix string, value interface{}, out map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flattenConfigValue(joinConfigField(prefix, key), item, out)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenConfigValue(fmt.Sprintf("%s[%d]", prefix, i), item, out)
+		}
+	default:
+		if prefix == "" {
+			prefix = "value"
+		}
+		out[prefix] = v
+	}
+}
+
+func joinConfigField(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// isSensitiveField 判断字段是否包含敏感信息
+func isSensitiveField(propertyID, field string) bool {
+	redacted, ok := configAuditRedactedFields[propertyID]
+	if ok && redacted == "" {
+		return true
+	}
+	for _, segment := range strings.Split(strings.ToLower(field), ".") {
+		if ok && segment == redacted {
+			return true
+		}
+		for _, keyword := range sensitiveFieldKeywords {
+			if strings.Contains(segment, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func maskConfigValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return "****"
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func findConfigChange(changes []models.ConfigChange, field string) *models.ConfigChange {
+	for i := range changes {
+		if changes[i].Field == field {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+func TestDiffConfigValuesMasksCredentials(t *testing.T) {
+	before := `[{"type":"discord","enabled":true,"config":{"webhookUrl":"https://discord.com/api/webhooks/1/old","headers":{"Authorization":"Bearer old"}}}]`
+	after := `[{"type":"discord","enabled":false,"config":{"webhookUrl":"https://discord.com/api/webhooks/1/new","headers":{"Authorization":"Bearer new"}}}]`
+
+	changes := diffConfigValues(PropertyIDNotificationChannels, before, after)
+	for _, field := range []string{"[0].config.webhookUrl", "[0].config.headers.Authorization"} {
+		change := findConfigChange(changes, field)
+		if change == nil {
+			t.Fatalf("missing change for %s: %+v", field, changes)
+		}
+		if change.Before != "****" || change.After != "****" {
+			t.Errorf("%s should be masked, got %v -> %v", field, change.Before, change.After)
+		}
+	}
+	// 非敏感字段保留原值
+	if change := findConfigChange(changes, "[0].enabled"); change == nil || change.After != false {
+		t.Errorf("enabled should not be masked: %+v", change)
+	}
+
+	// 其他配置中的 URL 和请求头同样脱敏
+	changes = diffConfigValues(PropertyIDAlertConfig, `{"hook":{"url":"https://a/x","headers":{"Authorization":"a"}}}`, `{"hook":{"url":"https://a/y","headers":{"Authorization":"b"}}}`)
+	for _, change := range changes {
+		if change.Before != "****" || change.After != "****" {
+			t.Errorf("%s should be masked, got %v -> %v", change.Field, change.Before, change.After)
+		}
+	}
+
+	// 归档配置整体脱敏
+	changes = diffConfigValues(PropertyIDArchiveConfig, `{"endpoint":"http://u:p@minio:9000","bucket":"a"}`, `{"endpoint":"http://u:q@minio:9000","bucket":"b"}`)
+	if len(changes) != 2 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	for _, change := range changes {
+		if change.Before != "****" || change.After != "****" {
+			t.Errorf("%s should be masked, got %v -> %v", change.Field, change.Before, change.After)
+		}
+	}
+}
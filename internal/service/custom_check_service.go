@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/repo"
+	"github.com/dushixiang/pika/internal/websocket"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CustomCheckService 自定义检查服务，负责检查定义的保存与下发
+type CustomCheckService struct {
+	logger          *zap.Logger
+	propertyService *PropertyService
+	agentRepo       *repo.AgentRepo
+	wsManager       *websocket.Manager
+}
+
+func NewCustomCheckService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, wsManager *websocket.Manager) *CustomCheckService {
+	return &CustomCheckService{
+		logger:          logger,
+		propertyService: propertyService,
+		agentRepo:       repo.NewAgentRepo(db),
+		wsManager:       wsManager,
+	}
+}
+
+// ListChecks 获取所有自定义检查
+func (s *CustomCheckService) ListChecks(ctx context.Context) ([]models.CustomCheck, error) {
+	return s.propertyService.GetCustomChecks(ctx)
+}
+
+// SaveChecks 校验并保存自定义检查列表，保存后重新下发给在线探针
+func (s *CustomCheckService) SaveChecks(ctx context.Context, checks []models.CustomCheck) ([]models.CustomCheck, error) {
+	names := make(map[string]struct{}, len(checks))
+	for i := range checks {
+		check := &checks[i]
+		check.Normalize()
+		if err := check.Validate(); err != nil {
+			return nil, fmt.Errorf("检查 %q 配置无效: %w", check.Name, err)
+		}
+		if _, exists := names[check.Name]; exists {
+			return nil, fmt.Errorf("检查名称重复: %s", check.Name)
+		}
+		names[check.Name] = struct{}{}
+		if check.ID == "" {
+			check.ID = uuid.NewString()
+		}
+		if check.AgentIDs == nil {
+			check.AgentIDs = []string{}
+		}
+		if check.Tags == nil {
+			check.Tags = []string{}
+		}
+	}
+
+	if err := s.propertyService.SetCustomChecks(ctx, checks); err != nil {
+		return nil, err
+	}
+
+	s.PushToOnlineAgents(ctx)
+	return checks, nil
+}
+
+// BuildConfig 构建下发给指定探针的检查配置，只包含已启用且目标匹配的检查
+func (s *CustomCheckService) BuildConfig(ctx context.Context, agent *models.Agent) (*protocol.CustomCheckConfigData, error) {
+	checks, err := s.propertyService.GetCustomChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &protocol.CustomCheckConfigData{Checks: []protocol.CustomCheckItem{}}
+	for i := range checks {
+		if checks[i].IsTarget(agent) {
+			config.Checks = append(config.Checks, checks[i].ToItem())
+		}
+	}
+	return config, nil
+}
+
+// PushToOnlineAgents 向所有在线探针下发检查配置，未匹配任何检查的探针会收到空列表以停止旧检查
+func (s *CustomCheckService) PushToOnlineAgents(ctx context.Context) {
+	for _, agentID := range s.wsManager.GetAllClients() {
+		agent, err := s.agentRepo.FindById(ctx, agentID)
+		if err != nil {
+			continue
+		}
+
+		config, err := s.BuildConfig(ctx, &agent)
+		if err != nil {
+			s.logger.Error("构建自定义检查配置失败", zap.Error(err))
+			return
+		}
+
+		msgData, err := json.Marshal(protocol.OutboundMessage{
+			Type: protocol.MessageTypeCustomCheckConfig,
+			Data: config,
+		})
+		if err != nil {
+			s.logger.Error("构建自定义检查配置消息失败", zap.Error(err))
+			return
+		}
+
+		if err := s.wsManager.SendToClient(agentID, msgData); err != nil {
+			s.logger.Debug("发送自定义检查配置失败", zap.String("agentID", agentID), zap.Error(err))
+		}
+	}
+}
+
+// mergeCustomMetrics 合并自定义检查的最新结果，各检查独立上报，按检查ID覆盖
+func mergeCustomMetrics(existing, updates []protocol.CustomMetricData) []protocol.CustomMetricData {
+	merged := make([]protocol.CustomMetricData, 0, len(existing)+len(updates))
+	index := make(map[string]int, len(existing)+len(updates))
+	for _, list := range [][]protocol.CustomMetricData{existing, updates} {
+		for _, item := range list {
+			if i, ok := index[item.CheckID]; ok {
+				merged[i] = item
+				continue
+			}
+			index[item.CheckID] = len(merged)
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
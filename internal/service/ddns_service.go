@@ -29,6 +29,7 @@ type DDNSService struct {
 	logger          *zap.Logger
 	ConfigRepo      *repo.DDNSConfigRepo // 导出用于 handler 的 PageBuilder
 	recordRepo      *repo.DDNSRecordRepo
+	agentRepo       *repo.AgentRepo
 	propertyService *PropertyService
 	wsManager       *websocket.Manager
 	ipCache         *syncx.SafeMap[string, *ipCacheData] // 使用内存缓存存储 IP
@@ -43,6 +44,7 @@ func NewDDNSService(
 		logger:          logger,
 		ConfigRepo:      repo.NewDDNSConfigRepo(db),
 		recordRepo:      repo.NewDDNSRecordRepo(db),
+		agentRepo:       repo.NewAgentRepo(db),
 		propertyService: propertyService,
 		wsManager:       wsManager,
 		ipCache:         syncx.NewSafeMap[string, *ipCacheData](),
@@ -152,6 +154,13 @@ func (s *DDNSService) HandleIPReport(ctx context.Context, agentID string, ipData
 		return fmt.Errorf("获取 DDNS 配置失败: %w", err)
 	}
 
+	// 按实际地址族更新 A/AAAA 记录，探针未上报的地址族使用公网 IP 采集记录的地址
+	var agent *models.Agent
+	if found, err := s.agentRepo.FindById(ctx, agentID); err == nil {
+		agent = &found
+	}
+	ipData = resolveDDNSIPs(ipData, agent)
+
 	// 获取缓存的 IP
 	cachedIP, _ := s.ipCache.Get(agentID)
 
@@ -226,6 +235,32 @@ func (s *DDNSService) HandleIPReport(ctx context.Context, agentID string, ipData
 	return nil
 }
 
+// resolveDDNSIPs 将上报的地址按实际地址族归类，忽略无效地址；某一地址族未上报时使用探针记录的公网 IPv4/IPv6
+func resolveDDNSIPs(report *protocol.DDNSIPReportData, agent *models.Agent) *protocol.DDNSIPReportData {
+	resolved := &protocol.DDNSIPReportData{}
+	for _, raw := range []string{report.IPv4, report.IPv6} {
+		switch family, ip := classifyIP(raw); family {
+		case models.IPFamilyV4:
+			resolved.IPv4 = ip
+		case models.IPFamilyV6:
+			resolved.IPv6 = ip
+		}
+	}
+	if agent != nil {
+		if resolved.IPv4 == "" {
+			if family, ip := classifyIP(agent.IPv4); family == models.IPFamilyV4 {
+				resolved.IPv4 = ip
+			}
+		}
+		if resolved.IPv6 == "" {
+			if family, ip := classifyIP(agent.IPv6); family == models.IPFamilyV6 {
+				resolved.IPv6 = ip
+			}
+		}
+	}
+	return resolved
+}
+
 // updateRecord 更新单条 DNS 记录
 func (s *DDNSService) updateRecord(
 	ctx context.Context,
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+const (
+	// diskForecastWindow 拟合使用的历史窗口
+	diskForecastWindow = 6 * time.Hour
+	// diskForecastStep 拟合使用的采样步长
+	diskForecastStep = 5 * time.Minute
+	// diskForecastMinSamples 拟合所需的最少样本数
+	diskForecastMinSamples = 12
+	// diskForecastMinSpan 拟合所需的最短历史跨度
+	diskForecastMinSpan = time.Hour
+)
+
+// ForecastDiskUsage 对探针各挂载点最近的使用率做线性拟合，估算写满时间并保存
+func (s *MetricService) ForecastDiskUsage(ctx context.Context, agentID string) ([]models.DiskForecast, error) {
+	end := time.Now()
+	start := end.Add(-diskForecastWindow)
+	query := fmt.Sprintf(`pika_disk_usage_percent{agent_id="%s",mount_point!=""}`, agentID)
+
+	result, err := s.vmClient.QueryRange(ctx, query, start, end, diskForecastStep)
+	if err != nil {
+		s.logger.Error("查询磁盘使用率历史失败", zap.String("agentId", agentID), zap.Error(err))
+		return nil, err
+	}
+
+	byMount := make(map[string][]vmclient.DataPoint)
+	for _, point := range vmclient.ConvertToDataPoints(result) {
+		mountPoint := point.Labels["mount_point"]
+		byMount[mountPoint] = append(byMount[mountPoint], point)
+	}
+
+	forecasts := make([]models.DiskForecast, 0, len(byMount))
+	for mountPoint, points := range byMount {
+		sort.Slice(points, func(i, j int) bool { return points[i].Timestamp < points[j].Timestamp })
+
+		forecast := models.DiskForecast{
+			ID:           fmt.Sprintf("%s:%s", agentID, mountPoint),
+			AgentID:      agentID,
+			MountPoint:   mountPoint,
+			UsagePercent: points[len(points)-1].Value,
+			Samples:      len(points),
+		}
+
+		span := time.Duration(points[len(points)-1].Timestamp-points[0].Timestamp) * time.Millisecond
+		if len(points) >= diskForecastMinSamples && span >= diskForecastMinSpan {
+			forecast.Slope = linearSlopePerHour(points)
+			// 只有使用率持续增长时才预测
+			if forecast.Slope > 0 && forecast.UsagePercent < 100 {
+				hoursToFull := (100 - forecast.UsagePercent) / forecast.Slope
+				forecast.EstimatedFullAt = end.Add(time.Duration(hoursToFull * float64(time.Hour))).UnixMilli()
+			}
+		}
+
+		if err := s.DiskForecastRepo.SaveForecast(ctx, &forecast); err != nil {
+			s.logger.Error("保存磁盘预测结果失败", zap.String("id", forecast.ID), zap.Error(err))
+		}
+		forecasts = append(forecasts, forecast)
+	}
+
+	sort.Slice(forecasts, func(i, j int) bool { return forecasts[i].MountPoint < forecasts[j].MountPoint })
+	return forecasts, nil
+}
+
+// GetDiskForecasts 获取探针已保存的磁盘预测结果
+func (s *MetricService) GetDiskForecasts(ctx context.Context, agentID string) ([]models.DiskForecast, error) {
+	return s.DiskForecastRepo.FindByAgentID(ctx, agentID)
+}
+
+// linearSlopePerHour 最小二乘法拟合，返回每小时的变化量
+func linearSlopePerHour(points []vmclient.DataPoint) float64 {
+	origin := points[0].Timestamp
+	n := float64(len(points))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, point := range points {
+		x := float64(point.Timestamp-origin) / float64(time.Hour/time.Millisecond)
+		y := point.Value
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
@@ -130,6 +130,35 @@ func (s *GeoIPService) LookupIP(ip string) string {
 	return location
 }
 
+// Available GeoIP 数据库是否可用
+func (s *GeoIPService) Available() bool {
+	return s.config != nil && s.config.Enabled && s.db != nil
+}
+
+// LookupCountry 查询 IP 所属国家代码（ISO 3166-1），查询失败返回空字符串
+func (s *GeoIPService) LookupCountry(ip string) string {
+	if !s.Available() {
+		return ""
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return ""
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, err := s.db.Country(parsedIP)
+	if err != nil {
+		s.logger.Debug("failed to lookup IP country",
+			zap.String("ip", ip),
+			zap.Error(err))
+		return ""
+	}
+	return record.Country.IsoCode
+}
+
 // Close 关闭数据库连接
 func (s *GeoIPService) Close() error {
 	s.mu.Lock()
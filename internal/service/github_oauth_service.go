@@ -80,7 +80,7 @@ func (s *GitHubOAuthService) IsEnabled() bool {
 // GenerateAuthURL 生成 GitHub 认证 URL
 func (s *GitHubOAuthService) GenerateAuthURL() (string, string, error) {
 	if !s.IsEnabled() {
-		return "", "", errors.New("GitHub OAuth 未启用")
+		return "", "", ErrGitHubDisabled
 	}
 
 	// 生成随机 state
@@ -108,7 +108,7 @@ func (s *GitHubOAuthService) GenerateAuthURL() (string, string, error) {
 // ExchangeCode 交换授权码获取 access token 和用户信息
 func (s *GitHubOAuthService) ExchangeCode(ctx context.Context, code, state string) (string, string, error) {
 	if !s.IsEnabled() {
-		return "", "", errors.New("GitHub OAuth 未启用")
+		return "", "", ErrGitHubDisabled
 	}
 
 	// 验证 state
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/websocket"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultLogTailDuration 未指定时长时的默认跟踪时长
+	defaultLogTailDuration = 5 * time.Minute
+	// maxLogTailDuration 单次跟踪的最长时长
+	maxLogTailDuration = 30 * time.Minute
+	// defaultLogTailLinesPerSecond 默认每秒最多回传的行数
+	defaultLogTailLinesPerSecond = 50
+	// maxLogTailLinesPerSecond 每秒最多回传行数的上限
+	maxLogTailLinesPerSecond = 500
+	// maxLogTailSessionsPerAgent 每个探针同时进行的跟踪数
+	maxLogTailSessionsPerAgent = 3
+	// logTailBufferSize 会话缓冲的消息数，客户端读取过慢时丢弃新消息
+	logTailBufferSize = 64
+)
+
+var (
+	// ErrLogTailAgentOffline 探针未连接
+	ErrLogTailAgentOffline = errors.New("探针未连接")
+	// ErrInvalidLogTail 日志跟踪参数无效
+	ErrInvalidLogTail = errors.New("无效的日志跟踪参数")
+	// ErrLogTailTooMany 探针同时进行的日志跟踪过多
+	ErrLogTailTooMany = errors.New("该探针同时进行的日志跟踪过多")
+)
+
+// LogTailSession 一次日志跟踪会话，Data 在会话结束后关闭
+type LogTailSession struct {
+	ID        string
+	AgentID   string
+	Path      string
+	Operator  string
+	Duration  time.Duration
+	StartedAt time.Time
+	Data      chan protocol.LogTailData
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int // 因客户端读取过慢丢弃的消息数
+}
+
+// deliver 非阻塞地投递数据，缓冲已满时丢弃
+func (s *LogTailSession) deliver(data protocol.LogTailData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.Data <- data:
+	default:
+		s.dropped++
+	}
+}
+
+// close 关闭会话，返回是否为首次关闭
+func (s *LogTailSession) close() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.closed = true
+	close(s.Data)
+	return true
+}
+
+// LogTailService 日志跟踪服务，将 Agent 回传的日志行转发给发起请求的客户端
+type LogTailService struct {
+	logger    *zap.Logger
+	wsManager *websocket.Manager
+
+	mu       sync.Mutex
+	sessions map[string]*LogTailSession
+}
+
+func NewLogTailService(logger *zap.Logger, wsManager *websocket.Manager) *LogTailService {
+	return &LogTailService{
+		logger:    logger,
+		wsManager: wsManager,
+		sessions:  make(map[string]*LogTailSession),
+	}
+}
+
+// Start 请求探针开始跟踪日志文件，路径是否允许由探针按本地白名单判断
+// duration、linesPerSecond 为 0 时使用默认值，超过上限时截断
+func (s *LogTailService) Start(agentID, filePath string, duration time.Duration, linesPerSecond int, operator string) (*LogTailSession, error) {
+	if filePath == "" || !path.IsAbs(filePath) && !isWindowsAbsPath(filePath) {
+		return nil, fmt.Errorf("%w: 路径必须为绝对路径", ErrInvalidLogTail)
+	}
+	if duration < 0 || linesPerSecond < 0 {
+		return nil, fmt.Errorf("%w: 时长和速率不能为负数", ErrInvalidLogTail)
+	}
+	if duration == 0 {
+		duration = defaultLogTailDuration
+	}
+	duration = min(duration, maxLogTailDuration)
+	if linesPerSecond == 0 {
+		linesPerSecond = defaultLogTailLinesPerSecond
+	}
+	linesPerSecond = min(linesPerSecond, maxLogTailLinesPerSecond)
+
+	if _, ok := s.wsManager.GetClient(agentID); !ok {
+		return nil, ErrLogTailAgentOffline
+	}
+
+	session := &LogTailSession{
+		ID:        uuid.NewString(),
+		AgentID:   agentID,
+		Path:      filePath,
+		Operator:  operator,
+		Duration:  duration,
+		StartedAt: time.Now(),
+		Data:      make(chan protocol.LogTailData, logTailBufferSize),
+	}
+
+	s.mu.Lock()
+	count := 0
+	for _, existing := range s.sessions {
+		if existing.AgentID == agentID {
+			count++
+		}
+	}
+	if count >= maxLogTailSessionsPerAgent {
+		s.mu.Unlock()
+		return nil, ErrLogTailTooMany
+	}
+	s.sessions[session.ID] = session
+	s.mu.Unlock()
+
+	if err := s.send(agentID, protocol.LogTailRequest{
+		SessionID:          session.ID,
+		Action:             protocol.LogTailActionStart,
+		Path:               filePath,
+		MaxDurationSeconds: int(duration.Seconds()),
+		MaxLinesPerSecond:  linesPerSecond,
+	}); err != nil {
+		s.remove(session.ID)
+		return nil, err
+	}
+
+	s.logger.Info("开始跟踪探针日志",
+		zap.String("agentId", agentID),
+		zap.String("sessionId", session.ID),
+		zap.String("path", filePath),
+		zap.String("operator", operator),
+		zap.Duration("duration", duration),
+		zap.Int("linesPerSecond", linesPerSecond))
+	return session, nil
+}
+
+// Stop 结束会话并通知探针停止跟踪，会话不存在时忽略
+func (s *LogTailService) Stop(sessionID string) {
+	session := s.remove(sessionID)
+	if session == nil {
+		return
+	}
+	if err := s.send(session.AgentID, protocol.LogTailRequest{
+		SessionID: sessionID,
+		Action:    protocol.LogTailActionStop,
+	}); err != nil && !errors.Is(err, ErrLogTailAgentOffline) {
+		s.logger.Warn("通知探针停止跟踪日志失败", zap.String("sessionId", sessionID), zap.Error(err))
+	}
+
+	session.mu.Lock()
+	dropped := session.dropped
+	session.mu.Unlock()
+	s.logger.Info("结束跟踪探针日志",
+		zap.String("agentId", session.AgentID),
+		zap.String("sessionId", sessionID),
+		zap.Duration("elapsed", time.Since(session.StartedAt)),
+		zap.Int("dropped", dropped))
+}
+
+// Get 获取会话
+func (s *LogTailService) Get(sessionID string) (*LogTailSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionID]
+	return session, ok
+}
+
+// HandleData 处理探针回传的日志行，只转发给该探针自己的会话
+func (s *LogTailService) HandleData(agentID string, data protocol.LogTailData) {
+	session, ok := s.Get(data.SessionID)
+	if !ok || session.AgentID != agentID {
+		if !data.Done {
+			// 会话已结束（如客户端断开），让探针停止跟踪
+			_ = s.send(agentID, protocol.LogTailRequest{SessionID: data.SessionID, Action: protocol.LogTailActionStop})
+		}
+		return
+	}
+	session.deliver(data)
+	if data.Done {
+		// 由探针结束的会话，投递结束消息后即可移除，不需要再通知探针
+		s.remove(data.SessionID)
+	}
+}
+
+// remove 移除并关闭会话
+func (s *LogTailService) remove(sessionID string) *LogTailSession {
+	s.mu.Lock()
+	session, ok := s.sessions[sessionID]
+	delete(s.sessions, sessionID)
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	session.close()
+	return session
+}
+
+func (s *LogTailService) send(agentID string, req protocol.LogTailRequest) error {
+	if _, ok := s.wsManager.GetClient(agentID); !ok {
+		return ErrLogTailAgentOffline
+	}
+	msgData, err := json.Marshal(protocol.OutboundMessage{
+		Type: protocol.MessageTypeLogTail,
+		Data: req,
+	})
+	if err != nil {
+		return err
+	}
+	return s.wsManager.SendToClient(agentID, msgData)
+}
+
+// isWindowsAbsPath 判断是否为 Windows 绝对路径，如 C:\Windows\Logs\x.log
+func isWindowsAbsPath(p string) bool {
+	return len(p) >= 3 && (p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z') && p[1] == ':' && (p[2] == '\\' || p[2] == '/')
+}
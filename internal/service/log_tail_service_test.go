@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/websocket"
+	"go.uber.org/zap"
+)
+
+func TestLogTailServiceStartValidation(t *testing.T) {
+	s := NewLogTailService(zap.NewNop(), websocket.NewManager(zap.NewNop(), websocket.Options{}))
+
+	if _, err := s.Start("agent-1", "var/log/syslog", 0, 0, "admin"); !errors.Is(err, ErrInvalidLogTail) {
+		t.Fatalf("expected ErrInvalidLogTail for relative path, got %v", err)
+	}
+	if _, err := s.Start("agent-1", "/var/log/syslog", -time.Second, 0, "admin"); !errors.Is(err, ErrInvalidLogTail) {
+		t.Fatalf("expected ErrInvalidLogTail for negative duration, got %v", err)
+	}
+	if _, err := s.Start("agent-1", "/var/log/syslog", 0, 0, "admin"); !errors.Is(err, ErrLogTailAgentOffline) {
+		t.Fatalf("expected ErrLogTailAgentOffline, got %v", err)
+	}
+}
+
+func TestLogTailServiceHandleData(t *testing.T) {
+	s := NewLogTailService(zap.NewNop(), websocket.NewManager(zap.NewNop(), websocket.Options{}))
+	session := &LogTailSession{ID: "s1", AgentID: "agent-1", Data: make(chan protocol.LogTailData, 1)}
+	s.sessions[session.ID] = session
+
+	// 其他探针不能向该会话写入数据
+	s.HandleData("agent-2", protocol.LogTailData{SessionID: "s1", Lines: []string{"forged"}})
+	if len(session.Data) != 0 {
+		t.Fatal("data from another agent should be ignored")
+	}
+
+	s.HandleData("agent-1", protocol.LogTailData{SessionID: "s1", Lines: []string{"a"}})
+	// 缓冲已满时丢弃而不是阻塞
+	s.HandleData("agent-1", protocol.LogTailData{SessionID: "s1", Lines: []string{"b"}})
+	if session.dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", session.dropped)
+	}
+	if data := <-session.Data; data.Lines[0] != "a" {
+		t.Fatalf("unexpected data %v", data)
+	}
+
+	s.HandleData("agent-1", protocol.LogTailData{SessionID: "s1", Done: true, Error: "boom"})
+	if _, ok := s.Get("s1"); ok {
+		t.Fatal("session should be removed after done")
+	}
+	if data, ok := <-session.Data; !ok || !data.Done {
+		t.Fatalf("expected done message before close, got %v %v", data, ok)
+	}
+	if _, ok := <-session.Data; ok {
+		t.Fatal("session channel should be closed")
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/config"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 登录方式
+const (
+	LoginMethodPassword = "password"
+	LoginMethodOIDC     = "oidc"
+	LoginMethodGitHub   = "github"
+)
+
+var ErrLoginRegionDenied = errors.New("login region denied")
+
+// loginRegionPolicy 登录地区限制策略
+type loginRegionPolicy struct {
+	allow  map[string]struct{}
+	deny   map[string]struct{}
+	bypass []*net.IPNet
+}
+
+// newLoginRegionPolicy 解析配置，未启用时返回 nil
+func newLoginRegionPolicy(logger *zap.Logger, cfg *config.LoginRegionConfig, geoIPService *GeoIPService) *loginRegionPolicy {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	if geoIPService == nil || !geoIPService.Available() {
+		logger.Warn("登录地区限制已启用，但 GeoIP 数据库不可用，限制不会生效")
+		return nil
+	}
+
+	policy := &loginRegionPolicy{
+		allow: toCountrySet(cfg.AllowCountries),
+		deny:  toCountrySet(cfg.DenyCountries),
+	}
+	for _, cidr := range cfg.BypassCIDRs {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			logger.Warn("登录地区限制白名单网段格式错误", zap.String("cidr", cidr), zap.Error(err))
+			continue
+		}
+		policy.bypass = append(policy.bypass, subnet)
+	}
+
+	logger.Info("登录地区限制已启用",
+		zap.Strings("allow", cfg.AllowCountries),
+		zap.Strings("deny", cfg.DenyCountries),
+		zap.Int("bypassCIDRs", len(policy.bypass)))
+	return policy
+}
+
+func toCountrySet(countries []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(countries))
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if country != "" {
+			set[country] = struct{}{}
+		}
+	}
+	return set
+}
+
+// bypassed 判断 IP 是否在白名单网段内
+func (p *loginRegionPolicy) bypassed(ip net.IP) bool {
+	for _, subnet := range p.bypass {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// check 检查国家代码是否允许登录，返回拒绝原因
+func (p *loginRegionPolicy) check(country string) (string, bool) {
+	if _, ok := p.deny[country]; ok && country != "" {
+		return fmt.Sprintf("国家 %s 在禁止登录列表中", country), false
+	}
+	if len(p.allow) == 0 {
+		return "", true
+	}
+	if country == "" {
+		return "无法识别登录地区", false
+	}
+	if _, ok := p.allow[country]; !ok {
+		return fmt.Sprintf("国家 %s 不在允许登录列表中", country), false
+	}
+	return "", true
+}
+
+// checkLoginRegion 检查登录来源地区，被拒绝时记录审计日志
+func (s *AccountService) checkLoginRegion(ctx context.Context, username, method, clientIP string) error {
+	if s.loginRegion == nil {
+		return nil
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip != nil && s.loginRegion.bypassed(ip) {
+		return nil
+	}
+
+	country := s.geoIPService.LookupCountry(clientIP)
+	reason, ok := s.loginRegion.check(country)
+	if ok {
+		return nil
+	}
+
+	s.logger.Warn("登录地区受限，拒绝登录",
+		zap.String("username", username),
+		zap.String("method", method),
+		zap.String("ip", clientIP),
+		zap.String("country", country),
+		zap.String("reason", reason))
+
+	log := &models.LoginAuditLog{
+		ID:        uuid.NewString(),
+		Username:  username,
+		Method:    method,
+		IP:        clientIP,
+		Country:   country,
+		Reason:    reason,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if err := s.LoginAuditLogRepo.Create(ctx, log); err != nil {
+		s.logger.Error("记录登录审计日志失败", zap.String("username", username), zap.Error(err))
+	}
+
+	return ErrLoginRegionDenied
+}
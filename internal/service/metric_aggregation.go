@@ -0,0 +1,60 @@
+package service
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// isValidAggregation 判断聚合函数是否受支持
+func isValidAggregation(aggregation string) bool {
+	switch aggregation {
+	case "avg", "max", "last":
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeAggregationConfig 规范化聚合配置，忽略不支持的聚合函数
+func normalizeAggregationConfig(logger *zap.Logger, raw map[string]map[string]string) map[string]map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	aggregations := make(map[string]map[string]string, len(raw))
+	for metricType, fields := range raw {
+		metricType = strings.ToLower(strings.TrimSpace(metricType))
+		for field, aggregation := range fields {
+			aggregation = strings.ToLower(strings.TrimSpace(aggregation))
+			if !isValidAggregation(aggregation) {
+				logger.Warn("忽略不支持的聚合函数",
+					zap.String("metricType", metricType),
+					zap.String("field", field),
+					zap.String("aggregation", aggregation),
+				)
+				continue
+			}
+			if aggregations[metricType] == nil {
+				aggregations[metricType] = make(map[string]string)
+			}
+			aggregations[metricType][strings.TrimSpace(field)] = aggregation
+		}
+	}
+	return aggregations
+}
+
+// resolveAggregation 确定系列使用的聚合函数，请求参数优先，其次为配置中的系列、指标类型通配（*）
+func (s *MetricService) resolveAggregation(metricType, seriesName, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	fields, ok := s.aggregations[metricType]
+	if !ok {
+		return ""
+	}
+	if aggregation, ok := fields[seriesName]; ok {
+		return aggregation
+	}
+	return fields["*"]
+}
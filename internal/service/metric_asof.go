@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	// asOfRawWindow 时间点距今在该范围内时查询原始样本，更早的时间点查询降采样数据
+	asOfRawWindow = 6 * time.Hour
+	// asOfRawLookback 查询原始样本时向前查找的最长时间
+	asOfRawLookback = 5 * time.Minute
+	// asOfAggregateSteps 查询降采样数据时向前查找的步数
+	asOfAggregateSteps = 3
+)
+
+// ErrNoMetricBefore 指定时间点之前没有数据
+var ErrNoMetricBefore = errors.New("指定时间点之前没有数据")
+
+// GetMetricAsOf 获取指定时间点（含）之前的最新指标值，每个系列只返回一个数据点
+// 时间点较近时查询原始样本并返回样本的实际时间戳；较早时按距今时长选择降采样步长，返回所在时间桶的聚合值
+func (s *MetricService) GetMetricAsOf(ctx context.Context, agentID, metricType string, ts int64) (*metric.AsOfResponse, error) {
+	now := time.Now().UnixMilli()
+	if ts <= 0 || ts > now {
+		ts = now
+	}
+
+	response := &metric.AsOfResponse{
+		AgentID: agentID,
+		Type:    metricType,
+		At:      ts,
+	}
+
+	var series []metric.Series
+	if time.Duration(now-ts)*time.Millisecond <= asOfRawWindow {
+		queries := s.buildPromQLQueries(agentID, metricType, "", "", 0)
+		if len(queries) == 0 {
+			return nil, fmt.Errorf("unsupported metric type: %s", metricType)
+		}
+		response.Source = metric.AsOfSourceRaw
+		for _, q := range queries {
+			query := rawRangeQuery(q.Query, asOfRawLookback)
+			result, err := s.vmClient.QueryAt(ctx, query, time.UnixMilli(ts))
+			if err != nil {
+				s.logger.Error("查询时间点原始数据失败", zap.String("query", query), zap.Error(err))
+				return nil, err
+			}
+			series = append(series, s.convertQueryResultToSeries(result, q.Name, q.Labels)...)
+		}
+	} else {
+		// 按时间点距今的时长选择步长，越早的数据使用越大的聚合步长
+		step := s.DetermineInterval(ts, now, 0)
+		queries := s.buildPromQLQueries(agentID, metricType, "", "", step)
+		if len(queries) == 0 {
+			return nil, fmt.Errorf("unsupported metric type: %s", metricType)
+		}
+		response.Source = metric.AsOfSourceAggregate
+		response.Interval = int64(step.Seconds())
+		start := time.UnixMilli(ts).Add(-asOfAggregateSteps * step)
+		for _, q := range queries {
+			result, err := s.vmClient.QueryRange(ctx, q.Query, start, time.UnixMilli(ts), step)
+			if err != nil {
+				s.logger.Error("查询时间点聚合数据失败", zap.String("query", q.Query), zap.Error(err))
+				return nil, err
+			}
+			converted := s.convertQueryResultToSeries(result, q.Name, q.Labels)
+			for i := range converted {
+				converted[i].Aggregation = q.Aggregation
+			}
+			series = append(series, converted...)
+		}
+	}
+
+	response.Series = latestPointAsOf(series, ts)
+	if len(response.Series) == 0 {
+		return nil, ErrNoMetricBefore
+	}
+	return response, nil
+}
+
+// latestPointAsOf 每个系列只保留不晚于 ts 的最后一个数据点，没有数据的系列被丢弃
+func latestPointAsOf(series []metric.Series, ts int64) []metric.Series {
+	result := make([]metric.Series, 0, len(series))
+	for _, item := range series {
+		var latest *metric.DataPoint
+		for i := range item.Data {
+			if item.Data[i].Timestamp > ts {
+				break
+			}
+			latest = &item.Data[i]
+		}
+		if latest == nil {
+			continue
+		}
+		item.Data = []metric.DataPoint{*latest}
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+func TestLatestPointAsOf(t *testing.T) {
+	series := []metric.Series{
+		{Name: "usage", Data: []metric.DataPoint{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2}, {Timestamp: 3000, Value: 3}}},
+		{Name: "late", Data: []metric.DataPoint{{Timestamp: 4000, Value: 4}}},
+		{Name: "empty"},
+	}
+
+	result := latestPointAsOf(series, 2500)
+	if len(result) != 1 {
+		t.Fatalf("expected only series with data before ts, got %+v", result)
+	}
+	if p := result[0].Data; len(p) != 1 || p[0].Timestamp != 2000 || p[0].Value != 2 {
+		t.Fatalf("unexpected point: %+v", p)
+	}
+
+	if result := latestPointAsOf(series, 3000); result[0].Data[0].Value != 3 {
+		t.Fatalf("point at ts should be included: %+v", result)
+	}
+	if result := latestPointAsOf(series, 500); len(result) != 0 {
+		t.Fatalf("expected no data before ts, got %+v", result)
+	}
+}
@@ -103,6 +103,28 @@ func (s *MetricService) convertToMetrics(agentID string, metricType string, data
 			}
 			metrics = append(metrics, createMetric("pika_monitor_response_time_ms", agentID, labels, float64(monitorData.ResponseTime), timestamp))
 		}
+
+	case protocol.MetricTypeLoad:
+		loadData := data.(*protocol.LoadData)
+		metrics = append(metrics, createMetric("pika_load_1", agentID, nil, loadData.Load1, timestamp))
+		metrics = append(metrics, createMetric("pika_load_5", agentID, nil, loadData.Load5, timestamp))
+		metrics = append(metrics, createMetric("pika_load_15", agentID, nil, loadData.Load15, timestamp))
+
+	case protocol.MetricTypeCustom:
+		customDataList := data.([]protocol.CustomMetricData)
+		for _, customData := range customDataList {
+			labels := map[string]string{
+				"check_id":   customData.CheckID,
+				"check_name": customData.Name,
+			}
+			success := 0.0
+			if customData.Success {
+				success = 1
+				// 执行失败时没有有效数值，只记录成功状态
+				metrics = append(metrics, createMetric("pika_custom_check_value", agentID, labels, customData.Value, timestamp))
+			}
+			metrics = append(metrics, createMetric("pika_custom_check_success", agentID, labels, success, timestamp))
+		}
 	}
 
 	return metrics
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultCollectorInterval 探针默认采集间隔
+	defaultCollectorInterval = 5 * time.Second
+	// coverageStaleFactor 超过期望间隔的倍数视为停止上报
+	coverageStaleFactor = 3
+	// coverageLookback 覆盖情况查询的回溯窗口
+	coverageLookback = 24 * time.Hour
+)
+
+// coverageMetrics 各指标类型用于判断是否上报的代表性指标
+var coverageMetrics = []struct {
+	Type   protocol.MetricType
+	Metric string
+}{
+	{protocol.MetricTypeCPU, "pika_cpu_usage_percent"},
+	{protocol.MetricTypeMemory, "pika_memory_usage_percent"},
+	{protocol.MetricTypeDisk, "pika_disk_usage_percent"},
+	{protocol.MetricTypeDiskIO, "pika_disk_read_bytes_rate"},
+	{protocol.MetricTypeNetwork, "pika_network_sent_bytes_rate"},
+	{protocol.MetricTypeNetworkConnection, "pika_network_conn_total"},
+	{protocol.MetricTypeGPU, "pika_gpu_utilization_percent"},
+	{protocol.MetricTypeTemperature, "pika_temperature_celsius"},
+	{protocol.MetricTypeLoad, "pika_load_1"},
+}
+
+// GetMetricCoverage 获取探针各指标类型的最后上报时间及是否停止上报
+func (s *MetricService) GetMetricCoverage(ctx context.Context, agentID string) ([]metric.MetricCoverage, error) {
+	now := time.Now()
+	expected := int64(defaultCollectorInterval / time.Second)
+	staleAfter := defaultCollectorInterval * coverageStaleFactor
+
+	coverages := make([]metric.MetricCoverage, 0, len(coverageMetrics))
+	for _, item := range coverageMetrics {
+		coverage := metric.MetricCoverage{
+			Type:             string(item.Type),
+			ExpectedInterval: expected,
+		}
+
+		// tlast_over_time 返回窗口内最后一个样本的时间戳（秒）
+		query := fmt.Sprintf(`max(tlast_over_time(%s{agent_id="%s"}[%s]))`,
+			item.Metric, agentID, coverageLookback.String())
+		result, err := s.vmClient.Query(ctx, query)
+		if err != nil {
+			s.logger.Error("查询指标覆盖情况失败",
+				zap.String("agentId", agentID),
+				zap.String("query", query),
+				zap.Error(err))
+			return nil, err
+		}
+
+		points := vmclient.ConvertToDataPoints(result)
+		if len(points) > 0 && points[0].Value > 0 {
+			lastSeen := time.UnixMilli(int64(points[0].Value * 1000))
+			coverage.Reported = true
+			coverage.LastSeenAt = lastSeen.UnixMilli()
+			coverage.Stale = now.Sub(lastSeen) > staleAfter
+		}
+
+		coverages = append(coverages, coverage)
+	}
+
+	return coverages, nil
+}
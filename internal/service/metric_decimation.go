@@ -0,0 +1,102 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+// DecimationLTTB 使用 Largest-Triangle-Three-Buckets 算法从原始精度的数据中挑选数据点，保留峰值和谷值
+const DecimationLTTB = "lttb"
+
+// decimationPoints 返回 LTTB 的目标点数：未指定时与按步长聚合得到的点数相同，且不超过单个系列的最大点数
+func decimationPoints(requested int, start, end int64, step time.Duration) int {
+	points := requested
+	if points <= 0 && step > 0 {
+		points = int((end-start)/step.Milliseconds()) + 1
+	}
+	return min(max(points, 2), maxIntervalPoints)
+}
+
+// decimateSeries 按 LTTB 将每个系列缩减到 points 个点
+// 数据在相邻点间隔超过 rawStep 处断开，各段按点数比例分配目标点数，markGaps 为 true 时在断开处插入空数据点
+func decimateSeries(series []metric.Series, points int, rawStep time.Duration, markGaps bool) {
+	stepMs := rawStep.Milliseconds()
+	for i := range series {
+		data := series[i].Data
+		if len(data) == 0 {
+			continue
+		}
+
+		var segments [][]metric.DataPoint
+		from := 0
+		for j := 1; j < len(data); j++ {
+			if stepMs > 0 && data[j].Timestamp-data[j-1].Timestamp > stepMs {
+				segments = append(segments, data[from:j])
+				from = j
+			}
+		}
+		segments = append(segments, data[from:])
+
+		decimated := make([]metric.DataPoint, 0, min(len(data), points+len(segments)))
+		for j, segment := range segments {
+			if j > 0 && markGaps {
+				decimated = append(decimated, metric.DataPoint{Timestamp: segments[j-1][len(segments[j-1])-1].Timestamp + stepMs, Gap: true})
+			}
+			threshold := int(math.Round(float64(points) * float64(len(segment)) / float64(len(data))))
+			decimated = append(decimated, lttb(segment, threshold)...)
+		}
+		series[i].Data = decimated
+	}
+}
+
+// lttb 使用 Largest-Triangle-Three-Buckets 算法将数据点缩减到 threshold 个，始终保留首尾两点
+// 中间的点等分为 threshold-2 个桶，每个桶选出与上一个选中点、下一个桶平均点构成三角形面积最大的点
+func lttb(points []metric.DataPoint, threshold int) []metric.DataPoint {
+	if threshold >= len(points) || len(points) <= 2 {
+		return points
+	}
+	if threshold < 2 {
+		threshold = 2
+	}
+
+	sampled := make([]metric.DataPoint, 0, threshold)
+	sampled = append(sampled, points[0])
+
+	every := float64(len(points)-2) / float64(threshold-2)
+	selected := 0
+	for i := 0; i < threshold-2; i++ {
+		// 下一个桶的平均点，最后一个桶使用末尾点
+		avgFrom := int(math.Floor(float64(i+1)*every)) + 1
+		avgTo := min(int(math.Floor(float64(i+2)*every))+1, len(points))
+		var avgX, avgY float64
+		for j := avgFrom; j < avgTo; j++ {
+			avgX += float64(points[j].Timestamp)
+			avgY += points[j].Value
+		}
+		count := float64(avgTo - avgFrom)
+		avgX /= count
+		avgY /= count
+
+		// 当前桶中与上一个选中点、下一个桶平均点构成三角形面积最大的点
+		from := int(math.Floor(float64(i)*every)) + 1
+		to := int(math.Floor(float64(i+1)*every)) + 1
+		ax := float64(points[selected].Timestamp)
+		ay := points[selected].Value
+		maxArea := -1.0
+		next := from
+		for j := from; j < to; j++ {
+			area := math.Abs((ax-avgX)*(points[j].Value-ay) - (ax-float64(points[j].Timestamp))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+
+		sampled = append(sampled, points[next])
+		selected = next
+	}
+
+	return append(sampled, points[len(points)-1])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+func TestLTTBKeepsPeaks(t *testing.T) {
+	points := make([]metric.DataPoint, 100)
+	for i := range points {
+		points[i] = metric.DataPoint{Timestamp: int64(i) * 10000, Value: 10}
+	}
+	points[37].Value = 500
+	points[71].Value = 0
+
+	sampled := lttb(points, 10)
+	if len(sampled) != 10 {
+		t.Fatalf("应缩减到 10 个点，得到 %d", len(sampled))
+	}
+	if sampled[0] != points[0] || sampled[len(sampled)-1] != points[99] {
+		t.Fatal("应保留首尾两点")
+	}
+
+	var peak, valley bool
+	for _, p := range sampled {
+		peak = peak || p.Value == 500
+		valley = valley || p.Value == 0
+	}
+	if !peak || !valley {
+		t.Fatalf("应保留峰值和谷值，得到 %+v", sampled)
+	}
+
+	if got := lttb(points[:5], 10); len(got) != 5 {
+		t.Fatalf("点数不足时应原样返回，得到 %d", len(got))
+	}
+}
+
+func TestDecimateSeriesMarksGaps(t *testing.T) {
+	step := 10 * time.Second
+	var data []metric.DataPoint
+	for i := 0; i < 60; i++ {
+		data = append(data, metric.DataPoint{Timestamp: int64(i) * step.Milliseconds(), Value: float64(i % 7)})
+	}
+	// 中间断开 10 分钟
+	for i := 120; i < 180; i++ {
+		data = append(data, metric.DataPoint{Timestamp: int64(i) * step.Milliseconds(), Value: float64(i % 5)})
+	}
+
+	series := []metric.Series{{Name: "cpu", Data: data}}
+	decimateSeries(series, 20, step, true)
+
+	got := series[0].Data
+	if len(got) != 21 {
+		t.Fatalf("两段各 10 个点加 1 个断开标记，得到 %d", len(got))
+	}
+	if !got[10].Gap || got[10].Timestamp != 60*step.Milliseconds() {
+		t.Fatalf("断开处应插入空数据点，得到 %+v", got[10])
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Timestamp <= got[i-1].Timestamp {
+			t.Fatalf("数据点应按时间递增，第 %d 个点 %d 不大于前一个 %d", i, got[i].Timestamp, got[i-1].Timestamp)
+		}
+	}
+}
+
+func TestDecimationPoints(t *testing.T) {
+	if got := decimationPoints(0, 0, 3600*1000, time.Minute); got != 61 {
+		t.Fatalf("未指定点数时应与按步长聚合的点数相同，得到 %d", got)
+	}
+	if got := decimationPoints(maxIntervalPoints*2, 0, 3600*1000, time.Minute); got != maxIntervalPoints {
+		t.Fatalf("点数不应超过上限，得到 %d", got)
+	}
+}
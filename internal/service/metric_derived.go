@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxDerivedExpressionLength 派生指标表达式的最大长度
+	maxDerivedExpressionLength = 256
+	// maxDerivedExpressionDepth 派生指标表达式的最大嵌套层数
+	maxDerivedExpressionDepth = 16
+	// maxDerivedMetrics 自定义派生指标的最大数量
+	maxDerivedMetrics = 32
+)
+
+// ErrDerivedMetricNotFound 派生指标不存在
+var ErrDerivedMetricNotFound = errors.New("派生指标不存在")
+
+var derivedMetricNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// derivedField 派生指标表达式可引用的字段
+type derivedField struct {
+	metricType string // 所属指标类型，用于选择步长
+	query      string // PromQL 查询模板，%s 为探针ID
+}
+
+// derivedFields 派生指标表达式可引用的字段白名单，均为每个探针一条序列
+var derivedFields = map[string]derivedField{
+	"cpu.usage":                     {"cpu", `pika_cpu_usage_percent{agent_id="%s"}`},
+	"cpu.cores":                     {"cpu", `pika_cpu_cores_logical{agent_id="%s"}`},
+	"memory.usage":                  {"memory", `pika_memory_usage_percent{agent_id="%s"}`},
+	"memory.total":                  {"memory", `pika_memory_total_bytes{agent_id="%s"}`},
+	"memory.used":                   {"memory", `pika_memory_used_bytes{agent_id="%s"}`},
+	"memory.available":              {"memory", `pika_memory_available_bytes{agent_id="%s"}`},
+	"memory.swapTotal":              {"memory", `pika_memory_swap_total_bytes{agent_id="%s"}`},
+	"memory.swapUsed":               {"memory", `pika_memory_swap_used_bytes{agent_id="%s"}`},
+	"disk.usage":                    {"disk", `pika_disk_usage_percent{agent_id="%s",mount_point=""}`},
+	"disk.total":                    {"disk", `pika_disk_total_bytes{agent_id="%s",mount_point=""}`},
+	"disk.used":                     {"disk", `pika_disk_used_bytes{agent_id="%s",mount_point=""}`},
+	"disk.free":                     {"disk", `pika_disk_free_bytes{agent_id="%s",mount_point=""}`},
+	"network.upload":                {"network", `sum(pika_network_sent_bytes_rate{agent_id="%s"}) by (agent_id)`},
+	"network.download":              {"network", `sum(pika_network_recv_bytes_rate{agent_id="%s"}) by (agent_id)`},
+	"disk_io.read":                  {"disk_io", `pika_disk_read_bytes_rate{agent_id="%s"}`},
+	"disk_io.write":                 {"disk_io", `pika_disk_write_bytes_rate{agent_id="%s"}`},
+	"network_connection.total":      {"network_connection", `pika_network_conn_total{agent_id="%s"}`},
+	"network_connection.time_wait":  {"network_connection", `pika_network_conn_time_wait{agent_id="%s"}`},
+	"network_connection.close_wait": {"network_connection", `pika_network_conn_close_wait{agent_id="%s"}`},
+	"load.load1":                    {"load", `pika_load_1{agent_id="%s"}`},
+	"load.load5":                    {"load", `pika_load_5{agent_id="%s"}`},
+	"load.load15":                   {"load", `pika_load_15{agent_id="%s"}`},
+}
+
+// builtinDerivedMetrics 内置派生指标，自定义派生指标不能与其重名
+var builtinDerivedMetrics = []models.DerivedMetric{
+	{
+		Name:        "memory_pressure",
+		Expression:  "(memory.used+memory.swapUsed)/(memory.total+memory.swapTotal)*100",
+		Unit:        "%",
+		Description: "内存与交换空间的综合使用率",
+	},
+	{
+		Name:        "network_total",
+		Expression:  "network.upload+network.download",
+		Unit:        "B/s",
+		Description: "上行与下行速率之和",
+	},
+	{
+		Name:        "disk_io_total",
+		Expression:  "disk_io.read+disk_io.write",
+		Unit:        "B/s",
+		Description: "磁盘读写速率之和",
+	},
+	{
+		Name:        "load_per_core",
+		Expression:  "load.load1/cpu.cores",
+		Description: "每个逻辑核心的 1 分钟平均负载",
+	},
+}
+
+// derivedExpr 派生指标表达式节点
+type derivedExpr interface {
+	// eval 计算表达式的值，引用的字段缺失或除数为 0 时返回 false
+	eval(values map[string]float64) (float64, bool)
+}
+
+type derivedNumber float64
+
+func (n derivedNumber) eval(map[string]float64) (float64, bool) {
+	return float64(n), true
+}
+
+type derivedFieldRef string
+
+func (f derivedFieldRef) eval(values map[string]float64) (float64, bool) {
+	v, ok := values[string(f)]
+	return v, ok
+}
+
+type derivedNegate struct {
+	operand derivedExpr
+}
+
+func (n derivedNegate) eval(values map[string]float64) (float64, bool) {
+	v, ok := n.operand.eval(values)
+	return -v, ok
+}
+
+type derivedBinary struct {
+	op          byte
+	left, right derivedExpr
+}
+
+func (b derivedBinary) eval(values map[string]float64) (float64, bool) {
+	l, ok := b.left.eval(values)
+	if !ok {
+		return 0, false
+	}
+	r, ok := b.right.eval(values)
+	if !ok {
+		return 0, false
+	}
+	switch b.op {
+	case '+':
+		return l + r, true
+	case '-':
+		return l - r, true
+	case '*':
+		return l * r, true
+	case '/':
+		if r == 0 {
+			return 0, false
+		}
+		return l / r, true
+	}
+	return 0, false
+}
+
+// derivedParser 派生指标表达式解析器，只支持数字、白名单字段、括号和四则运算
+type derivedParser struct {
+	input  string
+	pos    int
+	depth  int
+	fields map[string]struct{}
+}
+
+// parseDerivedExpression 解析表达式，返回表达式树及引用的字段（已排序）
+func parseDerivedExpression(expression string) (derivedExpr, []string, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil, fmt.Errorf("表达式不能为空")
+	}
+	if len(expression) > maxDerivedExpressionLength {
+		return nil, nil, fmt.Errorf("表达式不能超过%d个字符", maxDerivedExpressionLength)
+	}
+
+	p := &derivedParser{input: expression, fields: make(map[string]struct{})}
+	expr, err := p.parseSum()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, nil, fmt.Errorf("表达式第 %d 个字符附近无法解析", p.pos+1)
+	}
+	if len(p.fields) == 0 {
+		return nil, nil, fmt.Errorf("表达式至少需要引用一个字段")
+	}
+
+	fields := make([]string, 0, len(p.fields))
+	for field := range p.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return expr, fields, nil
+}
+
+func (p *derivedParser) skipSpaces() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// parseSum 解析加减运算
+func (p *derivedParser) parseSum() (derivedExpr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) || (p.input[p.pos] != '+' && p.input[p.pos] != '-') {
+			return left, nil
+		}
+		op := p.input[p.pos]
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = derivedBinary{op: op, left: left, right: right}
+	}
+}
+
+// parseProduct 解析乘除运算
+func (p *derivedParser) parseProduct() (derivedExpr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) || (p.input[p.pos] != '*' && p.input[p.pos] != '/') {
+			return left, nil
+		}
+		op := p.input[p.pos]
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = derivedBinary{op: op, left: left, right: right}
+	}
+}
+
+// parseFactor 解析数字、字段、括号和负号
+func (p *derivedParser) parseFactor() (derivedExpr, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("表达式不完整")
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '(' || c == '-':
+		p.depth++
+		if p.depth > maxDerivedExpressionDepth {
+			return nil, fmt.Errorf("表达式嵌套不能超过%d层", maxDerivedExpressionDepth)
+		}
+		defer func() { p.depth-- }()
+		p.pos++
+		if c == '-' {
+			operand, err := p.parseFactor()
+			if err != nil {
+				return nil, err
+			}
+			return derivedNegate{operand: operand}, nil
+		}
+		expr, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return nil, fmt.Errorf("表达式缺少右括号")
+		}
+		p.pos++
+		return expr, nil
+
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数字: %s", p.input[start:p.pos])
+		}
+		return derivedNumber(v), nil
+
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := p.pos
+		for p.pos < len(p.input) && isDerivedIdentChar(p.input[p.pos]) {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		if _, ok := derivedFields[name]; !ok {
+			return nil, fmt.Errorf("不支持的字段: %s", name)
+		}
+		p.fields[name] = struct{}{}
+		return derivedFieldRef(name), nil
+	}
+	return nil, fmt.Errorf("表达式第 %d 个字符 %q 无效", p.pos+1, c)
+}
+
+func isDerivedIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+// ValidateDerivedMetrics 校验自定义派生指标：名称合法且不重复，表达式只引用白名单字段
+func ValidateDerivedMetrics(metrics []models.DerivedMetric) error {
+	if len(metrics) > maxDerivedMetrics {
+		return fmt.Errorf("派生指标不能超过%d个", maxDerivedMetrics)
+	}
+	names := make(map[string]struct{}, len(metrics)+len(builtinDerivedMetrics))
+	for _, m := range builtinDerivedMetrics {
+		names[m.Name] = struct{}{}
+	}
+	for _, m := range metrics {
+		if !derivedMetricNamePattern.MatchString(m.Name) {
+			return fmt.Errorf("派生指标名称 %q 无效，只能包含小写字母、数字和下划线，且以字母开头", m.Name)
+		}
+		if _, ok := names[m.Name]; ok {
+			return fmt.Errorf("派生指标名称 %s 重复或与内置派生指标冲突", m.Name)
+		}
+		names[m.Name] = struct{}{}
+		if _, _, err := parseDerivedExpression(m.Expression); err != nil {
+			return fmt.Errorf("派生指标 %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// ListDerivedMetrics 返回所有可查询的派生指标，内置在前
+func (s *MetricService) ListDerivedMetrics(ctx context.Context) ([]models.DerivedMetric, error) {
+	custom, err := s.propertyService.GetDerivedMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics := make([]models.DerivedMetric, 0, len(builtinDerivedMetrics)+len(custom))
+	metrics = append(metrics, builtinDerivedMetrics...)
+	return append(metrics, custom...), nil
+}
+
+// findDerivedMetric 按名称查找派生指标
+func (s *MetricService) findDerivedMetric(ctx context.Context, name string) (*models.DerivedMetric, error) {
+	metrics, err := s.ListDerivedMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range metrics {
+		if metrics[i].Name == name {
+			return &metrics[i], nil
+		}
+	}
+	return nil, ErrDerivedMetricNotFound
+}
+
+// GetDerivedMetrics 查询派生指标：分别查询表达式引用的字段（按相同步长聚合），再按时间戳逐点计算
+// 只在所有字段都有数据且计算结果有效的时间点输出数据
+func (s *MetricService) GetDerivedMetrics(ctx context.Context, agentID, name string, start, end int64, aggregation string, smooth bool, interval time.Duration, fill bool) (*metric.GetMetricsResponse, error) {
+	derived, err := s.findDerivedMetric(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	expr, fields, err := parseDerivedExpression(derived.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("派生指标 %s 的表达式无效: %w", name, err)
+	}
+
+	step := s.determineDataInterval(ctx, agentID, derivedFields[fields[0]].metricType, start, end, interval)
+	resolved := s.resolveAggregation("derived", name, aggregation)
+
+	// 时间戳 -> 字段值
+	values := make(map[int64]map[string]float64)
+	for _, field := range fields {
+		def := derivedFields[field]
+		query := wrapAggregationQuery(fmt.Sprintf(def.query, agentID), resolved, step)
+		result, err := s.vmClient.QueryRange(ctx, query, time.UnixMilli(start), time.UnixMilli(end), step)
+		if err != nil {
+			s.logger.Error("查询派生指标字段失败",
+				zap.String("derived", name),
+				zap.String("query", query),
+				zap.Error(err))
+			return nil, err
+		}
+		for _, series := range s.convertQueryResultToSeries(result, field, nil) {
+			for _, p := range series.Data {
+				point, ok := values[p.Timestamp]
+				if !ok {
+					point = make(map[string]float64, len(fields))
+					values[p.Timestamp] = point
+				}
+				point[field] = p.Value
+			}
+		}
+	}
+
+	timestamps := make([]int64, 0, len(values))
+	for ts := range values {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	data := make([]metric.DataPoint, 0, len(timestamps))
+	for _, ts := range timestamps {
+		v, ok := expr.eval(values[ts])
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		data = append(data, metric.DataPoint{Timestamp: ts, Value: v})
+	}
+
+	series := []metric.Series{{
+		Name:        name,
+		Aggregation: resolved,
+		Data:        data,
+	}}
+	if smooth {
+		smoothSeries(series, smoothingWindow(step))
+	}
+	roundSeries(series, s.precision)
+	if fill {
+		fillGaps(series, start, end, step)
+	}
+
+	return &metric.GetMetricsResponse{
+		AgentID:  agentID,
+		Type:     "derived",
+		Range:    fmt.Sprintf("%d-%d", start, end),
+		Interval: int64(step.Seconds()),
+		Series:   series,
+	}, nil
+}
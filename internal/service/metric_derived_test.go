@@ -0,0 +1,86 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestParseDerivedExpression(t *testing.T) {
+	expr, fields, err := parseDerivedExpression("(memory.used + memory.swapUsed) / (memory.total + memory.swapTotal) * 100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"memory.swapTotal", "memory.swapUsed", "memory.total", "memory.used"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("fields = %v, want %v", fields, want)
+	}
+
+	v, ok := expr.eval(map[string]float64{
+		"memory.used": 6, "memory.swapUsed": 2, "memory.total": 12, "memory.swapTotal": 4,
+	})
+	if !ok || math.Abs(v-50) > 1e-9 {
+		t.Fatalf("eval = %v, %v, want 50", v, ok)
+	}
+
+	// 缺少字段或除数为 0 时不输出
+	if _, ok := expr.eval(map[string]float64{"memory.used": 1}); ok {
+		t.Fatal("expected missing field to skip the point")
+	}
+	if _, ok := expr.eval(map[string]float64{
+		"memory.used": 1, "memory.swapUsed": 0, "memory.total": 0, "memory.swapTotal": 0,
+	}); ok {
+		t.Fatal("expected division by zero to skip the point")
+	}
+
+	// 运算优先级与负号
+	expr, _, err = parseDerivedExpression("-load.load1 + 2 * 3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, _ := expr.eval(map[string]float64{"load.load1": 1}); v != 5 {
+		t.Fatalf("eval = %v, want 5", v)
+	}
+}
+
+func TestParseDerivedExpressionRejectsUnsafeInput(t *testing.T) {
+	cases := []string{
+		"",
+		"100",
+		"memory.used + secret",
+		"rate(memory.used)",
+		"memory.used +",
+		"(memory.used",
+		"memory.used ^ 2",
+		`memory.used{agent_id="x"}`,
+	}
+	for _, expression := range cases {
+		if _, _, err := parseDerivedExpression(expression); err == nil {
+			t.Errorf("expected error for %q", expression)
+		}
+	}
+}
+
+func TestValidateDerivedMetrics(t *testing.T) {
+	if err := ValidateDerivedMetrics([]models.DerivedMetric{
+		{Name: "swap_ratio", Expression: "memory.swapUsed/memory.swapTotal*100"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateDerivedMetrics([]models.DerivedMetric{
+		{Name: "memory_pressure", Expression: "memory.usage"},
+	}); err == nil {
+		t.Fatal("expected conflict with builtin derived metric")
+	}
+	if err := ValidateDerivedMetrics([]models.DerivedMetric{
+		{Name: "Bad-Name", Expression: "memory.usage"},
+	}); err == nil {
+		t.Fatal("expected invalid name error")
+	}
+	for _, m := range builtinDerivedMetrics {
+		if _, _, err := parseDerivedExpression(m.Expression); err != nil {
+			t.Errorf("builtin %s: %v", m.Name, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/config"
+	"go.uber.org/zap"
+)
+
+// downsamplingTier 规范化后的降采样档位
+type downsamplingTier struct {
+	after    time.Duration // 数据超过该时长后降采样
+	interval time.Duration // 降采样后的间隔
+}
+
+// normalizeDownsamplingTiers 校验并规范化降采样档位，按时长升序排列
+// 忽略非正数的配置；较早档位的间隔必须大于较新的档位，否则忽略
+func normalizeDownsamplingTiers(logger *zap.Logger, raw []config.DownsamplingTier) []downsamplingTier {
+	var tiers []downsamplingTier
+	for _, item := range raw {
+		if item.AfterDays <= 0 || item.Interval <= 0 {
+			logger.Warn("忽略无效的降采样档位", zap.Int("afterDays", item.AfterDays), zap.Int("interval", item.Interval))
+			continue
+		}
+		tiers = append(tiers, downsamplingTier{
+			after:    time.Duration(item.AfterDays) * 24 * time.Hour,
+			interval: time.Duration(item.Interval) * time.Second,
+		})
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].after < tiers[j].after })
+
+	normalized := tiers[:0]
+	for _, tier := range tiers {
+		if n := len(normalized); n > 0 && tier.interval <= normalized[n-1].interval {
+			logger.Warn("忽略降采样档位：间隔必须大于较新的档位",
+				zap.Duration("after", tier.after),
+				zap.Duration("interval", tier.interval))
+			continue
+		}
+		normalized = append(normalized, tier)
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	logger.Info("已启用降采样查询档位，请确认 VictoriaMetrics 使用相同的降采样配置",
+		zap.String("flag", downsamplingPeriodFlag(normalized)))
+	return normalized
+}
+
+// downsamplingPeriodFlag 生成与档位对应的 VictoriaMetrics 启动参数
+func downsamplingPeriodFlag(tiers []downsamplingTier) string {
+	periods := make([]string, 0, len(tiers))
+	for _, tier := range tiers {
+		periods = append(periods, fmt.Sprintf("%dd:%ds", int(tier.after.Hours()/24), int(tier.interval.Seconds())))
+	}
+	return "-downsampling.period=" + strings.Join(periods, ",")
+}
+
+// CheckDownsampling 启动时核对 VictoriaMetrics 的降采样参数
+// Pika 不会重新聚合或删除已存储的数据，档位只放大查询步长；VictoriaMetrics 未启用相同的降采样时，较早的数据仍按原始精度保存
+func (s *MetricService) CheckDownsampling(ctx context.Context) {
+	if len(s.downsampling) == 0 || s.vmClient == nil {
+		return
+	}
+	flags, err := s.vmClient.Flags(ctx)
+	if err != nil {
+		s.logger.Warn("获取 VictoriaMetrics 启动参数失败，无法核对降采样配置", zap.Error(err))
+		return
+	}
+	expected := downsamplingPeriodFlag(s.downsampling)
+	period, ok := flags["downsampling.period"]
+	if !ok || period == "" {
+		s.logger.Warn("VictoriaMetrics 未启用降采样（-downsampling.period 为企业版功能），降采样档位只放大查询步长，不会减少存储空间",
+			zap.String("expected", expected))
+		return
+	}
+	actual, err := parseDownsamplingPeriod(period)
+	if err != nil || !sameDownsamplingTiers(actual, s.downsampling) {
+		s.logger.Warn("VictoriaMetrics 的降采样参数与配置的档位不一致，较早时间范围的查询可能出现空洞或精度不符",
+			zap.String("actual", period),
+			zap.String("expected", expected),
+			zap.Error(err))
+	}
+}
+
+// parseDownsamplingPeriod 解析 VictoriaMetrics 的 -downsampling.period 取值，如 7d:1h,30d:1d，带序列过滤条件的档位忽略
+func parseDownsamplingPeriod(value string) ([]downsamplingTier, error) {
+	var tiers []downsamplingTier
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.Contains(item, "{") {
+			continue
+		}
+		offset, interval, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("无效的降采样档位: %s", item)
+		}
+		after, err := parseVMDuration(offset)
+		if err != nil {
+			return nil, err
+		}
+		step, err := parseVMDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, downsamplingTier{after: after, interval: step})
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].after < tiers[j].after })
+	return tiers, nil
+}
+
+// parseVMDuration 解析 VictoriaMetrics 的时长，在 Go 时长的基础上支持 d/w/y 单位
+func parseVMDuration(value string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			f, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("无效的时长: %s", value)
+			}
+			return time.Duration(f * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时长: %s", value)
+	}
+	return d, nil
+}
+
+// sameDownsamplingTiers 两组档位是否一致
+func sameDownsamplingTiers(a, b []downsamplingTier) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// downsamplingInterval 返回查询起始时间所在档位的降采样间隔，起始时间未超过任何档位时返回 0
+// 查询范围跨越多个档位时按最早（最粗）的档位，避免较早的部分因步长小于数据间隔而出现空洞
+func downsamplingInterval(tiers []downsamplingTier, start int64, now time.Time) time.Duration {
+	age := now.Sub(time.UnixMilli(start))
+	var interval time.Duration
+	for _, tier := range tiers {
+		if age >= tier.after {
+			interval = tier.interval
+		}
+	}
+	return interval
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestNormalizeDownsamplingTiers(t *testing.T) {
+	tiers := normalizeDownsamplingTiers(zap.NewNop(), []config.DownsamplingTier{
+		{AfterDays: 30, Interval: 86400},
+		{AfterDays: 7, Interval: 3600},
+		{AfterDays: 14, Interval: 600}, // 间隔小于较新的档位，忽略
+		{AfterDays: 0, Interval: 60},   // 无效
+	})
+	if len(tiers) != 2 || tiers[0].after != 7*24*time.Hour || tiers[1].interval != 24*time.Hour {
+		t.Fatalf("unexpected tiers: %+v", tiers)
+	}
+	if got := downsamplingPeriodFlag(tiers); got != "-downsampling.period=7d:3600s,30d:86400s" {
+		t.Fatalf("unexpected flag: %s", got)
+	}
+	if normalizeDownsamplingTiers(zap.NewNop(), nil) != nil {
+		t.Fatal("empty config should disable downsampling")
+	}
+}
+
+func TestLimitIntervalWithDownsampling(t *testing.T) {
+	s := &MetricService{
+		allowedIntervals: defaultAllowedIntervals,
+		downsampling: []downsamplingTier{
+			{after: 7 * 24 * time.Hour, interval: time.Hour},
+			{after: 30 * 24 * time.Hour, interval: 24 * time.Hour},
+		},
+	}
+	now := time.Now()
+	day := 24 * time.Hour
+
+	// 最近的数据不受影响
+	start := now.Add(-time.Hour).UnixMilli()
+	if got := s.limitInterval(start, now.UnixMilli(), 10*time.Second); got != 10*time.Second {
+		t.Fatalf("recent range: got %v", got)
+	}
+	// 起始时间超过 7 天，步长不小于 1 小时
+	start = now.Add(-8 * day).UnixMilli()
+	if got := s.limitInterval(start, now.Add(-7*day).UnixMilli(), time.Minute); got != time.Hour {
+		t.Fatalf("7d tier: got %v", got)
+	}
+	// 起始时间超过 30 天，按天返回（超过允许的最大步长）
+	start = now.Add(-60 * day).UnixMilli()
+	if got := s.limitInterval(start, now.UnixMilli(), time.Hour); got != 24*time.Hour {
+		t.Fatalf("30d tier: got %v", got)
+	}
+}
+
+func TestParseDownsamplingPeriod(t *testing.T) {
+	tiers, err := parseDownsamplingPeriod(`30d:1d,7d:1h,{__name__=~"node_.*"}:1d:5m`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []downsamplingTier{
+		{after: 7 * 24 * time.Hour, interval: time.Hour},
+		{after: 30 * 24 * time.Hour, interval: 24 * time.Hour},
+	}
+	if !sameDownsamplingTiers(tiers, expected) {
+		t.Fatalf("unexpected tiers: %+v", tiers)
+	}
+	if _, err := parseDownsamplingPeriod("7d"); err == nil {
+		t.Fatal("missing interval should fail")
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// exportChunkPoints 导出时每次查询的步数，限制单次查询占用的内存
+	exportChunkPoints = 1000
+	// exportPagePoints 单次导出请求最多包含的步数，超出部分通过续传令牌继续导出
+	exportPagePoints = 50000
+)
+
+// ExportRow 导出的一行指标数据
+type ExportRow struct {
+	Timestamp int64
+	Series    string
+	Labels    string // 按名称排序的 key=value 列表，以分号分隔
+	Value     float64
+}
+
+// ExportPage 单次导出的时间窗口
+type ExportPage struct {
+	Step  time.Duration // 导出步长
+	After int64         // 只导出时间戳大于该值的数据
+	End   int64         // 本次导出的结束时间（含）
+	Next  int64         // 续传令牌对应的时间戳，0 表示已导出完毕
+}
+
+// PlanExportPage 计算单次导出的时间窗口
+// cursor 为上一次导出的续传令牌或客户端最后收到的完整时间戳，0 表示从 start 开始
+// 步长只由请求的 interval 决定，保证续传前后一致
+func (s *MetricService) PlanExportPage(start, end, cursor int64, requested time.Duration) ExportPage {
+	step := alignInterval(max(requested, s.allowedIntervals[0]), s.allowedIntervals)
+
+	after := start - 1
+	if cursor > after {
+		after = cursor
+	}
+	page := ExportPage{
+		Step:  step,
+		After: after,
+		End:   end,
+	}
+	if pageEnd := after + exportPagePoints*step.Milliseconds(); pageEnd < end {
+		page.End = pageEnd
+		page.Next = pageEnd
+	}
+	return page
+}
+
+// ExportMetrics 按时间顺序分块查询指标并逐行回调，不会一次性加载整个时间范围
+func (s *MetricService) ExportMetrics(ctx context.Context, agentID, metricType, interfaceName string, fields []string, page ExportPage, emit func(rows []ExportRow) error) error {
+	queries := filterQueriesByFields(s.buildPromQLQueries(agentID, metricType, interfaceName, "", page.Step), fields)
+	if len(queries) == 0 {
+		return fmt.Errorf("unsupported metric type: %s", metricType)
+	}
+
+	chunk := exportChunkPoints * page.Step.Milliseconds()
+	after := page.After
+	for after < page.End {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunkEnd := min(after+chunk, page.End)
+
+		var rows []ExportRow
+		for _, q := range queries {
+			result, err := s.vmClient.QueryRange(ctx, q.Query, time.UnixMilli(after+1), time.UnixMilli(chunkEnd), page.Step)
+			if err != nil {
+				s.logger.Error("导出指标时查询 VictoriaMetrics 失败", zap.String("query", q.Query), zap.Error(err))
+				return err
+			}
+			for _, series := range s.convertQueryResultToSeries(result, q.Name, q.Labels) {
+				delete(series.Labels, "agent_id")
+				labels := formatExportLabels(series.Labels)
+				for _, p := range series.Data {
+					// 查询起点会按步长对齐，可能包含上一块已导出的时间戳
+					if p.Timestamp <= after || p.Timestamp > chunkEnd {
+						continue
+					}
+					rows = append(rows, ExportRow{
+						Timestamp: p.Timestamp,
+						Series:    series.Name,
+						Labels:    labels,
+						Value:     p.Value,
+					})
+				}
+			}
+		}
+
+		sort.Slice(rows, func(i, j int) bool {
+			if rows[i].Timestamp != rows[j].Timestamp {
+				return rows[i].Timestamp < rows[j].Timestamp
+			}
+			if rows[i].Series != rows[j].Series {
+				return rows[i].Series < rows[j].Series
+			}
+			return rows[i].Labels < rows[j].Labels
+		})
+		if len(rows) > 0 {
+			if err := emit(rows); err != nil {
+				return err
+			}
+		}
+		after = chunkEnd
+	}
+	return nil
+}
+
+// formatExportLabels 将标签格式化为按名称排序的 key=value 列表
+func formatExportLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanExportPage(t *testing.T) {
+	s := &MetricService{allowedIntervals: defaultAllowedIntervals}
+	start := int64(1_000_000)
+	step := time.Minute.Milliseconds()
+
+	page := s.PlanExportPage(start, start+step*100, 0, time.Minute)
+	if page.After != start-1 || page.End != start+step*100 || page.Next != 0 {
+		t.Fatalf("small range should fit in one page: %+v", page)
+	}
+
+	end := start + step*exportPagePoints*3
+	page = s.PlanExportPage(start, end, 0, time.Minute)
+	if page.Next == 0 || page.End != page.Next || page.Next >= end {
+		t.Fatalf("large range should return continue token: %+v", page)
+	}
+
+	next := s.PlanExportPage(start, end, page.Next, time.Minute)
+	if next.After != page.Next || next.Step != page.Step {
+		t.Fatalf("resumed page should start after token with same step: %+v", next)
+	}
+
+	// 未指定步长时使用最小允许步长，且不随续传变化
+	page = s.PlanExportPage(start, end, 0, 0)
+	if page.Step != defaultAllowedIntervals[0] {
+		t.Fatalf("unexpected default step: %v", page.Step)
+	}
+}
+
+func TestFormatExportLabels(t *testing.T) {
+	if got := formatExportLabels(map[string]string{"mount": "/", "device": "sda"}); got != "device=sda;mount=/" {
+		t.Fatalf("unexpected labels: %s", got)
+	}
+	if got := formatExportLabels(nil); got != "" {
+		t.Fatalf("unexpected labels: %s", got)
+	}
+}
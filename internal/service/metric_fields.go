@@ -0,0 +1,37 @@
+package service
+
+import "github.com/dushixiang/pika/internal/metric"
+
+// filterQueriesByFields 只保留 fields 中指定的系列，未知的字段会被忽略
+// 未指定字段或指定的字段全部未知时返回全部系列
+func filterQueriesByFields(queries []metric.QueryDefinition, fields []string) []metric.QueryDefinition {
+	if len(fields) == 0 {
+		return queries
+	}
+	selected := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		selected[field] = struct{}{}
+	}
+
+	filtered := make([]metric.QueryDefinition, 0, len(queries))
+	for _, q := range queries {
+		if _, ok := selected[q.Name]; ok {
+			filtered = append(filtered, q)
+		}
+	}
+	if len(filtered) == 0 {
+		return queries
+	}
+	return filtered
+}
+
+// SeriesNames 返回指标类型固定包含的系列名称（即 fields 参数可选的值）
+// 多网卡、多传感器等按标签拆分的系列不在此列出
+func (s *MetricService) SeriesNames(metricType string) []string {
+	queries := s.buildPromQLQueries("", metricType, "", "", 0)
+	names := make([]string, 0, len(queries))
+	for _, q := range queries {
+		names = append(names, q.Name)
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/models"
+)
+
+const (
+	// fleetSummaryCacheKey 汇总统计只有一份，使用固定的缓存键
+	fleetSummaryCacheKey = "fleet"
+	// fleetSummaryCacheTTL 汇总统计缓存时间，需要遍历所有探针的最新指标，避免大盘频繁刷新时重复计算
+	fleetSummaryCacheTTL = 30 * time.Second
+)
+
+// GetFleetSummary 获取所有在线探针的汇总统计，结果缓存 30 秒
+func (s *MetricService) GetFleetSummary(ctx context.Context) (*metric.FleetSummary, error) {
+	if summary, ok := s.fleetSummaryCache.Get(fleetSummaryCacheKey); ok {
+		return summary, nil
+	}
+
+	agents, err := s.agentRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	alertConfig, err := s.propertyService.GetAlertConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := s.buildFleetSummary(agents, alertConfig, time.Now().UnixMilli())
+	s.fleetSummaryCache.Set(fleetSummaryCacheKey, summary, fleetSummaryCacheTTL)
+	return summary, nil
+}
+
+// buildFleetSummary 汇总在线探针的最新指标，并按告警规则统计超过阈值的探针数
+func (s *MetricService) buildFleetSummary(agents []models.Agent, alertConfig *models.AlertConfig, now int64) *metric.FleetSummary {
+	summary := &metric.FleetSummary{
+		TotalAgents: len(agents),
+		Breaches:    make([]metric.FleetThresholdBreach, 0),
+		GeneratedAt: now,
+	}
+	// 全局告警关闭时不统计超过阈值的探针
+	var thresholds map[string]float64
+	if alertConfig.Enabled {
+		thresholds = enabledThresholds(&alertConfig.Rules)
+	}
+	breaches := make(map[string]*metric.FleetThresholdBreach, len(thresholds))
+	for alertType, threshold := range thresholds {
+		breaches[alertType] = &metric.FleetThresholdBreach{AlertType: alertType, Threshold: threshold}
+	}
+
+	for i := range agents {
+		agent := &agents[i]
+		if agent.Status != 1 {
+			continue
+		}
+		summary.OnlineAgents++
+
+		latest, ok := s.GetLatestMetrics(agent.ID)
+		if !ok || latest == nil {
+			continue
+		}
+		summary.ReportingAgents++
+
+		if latest.CPU != nil {
+			summary.TotalCores += latest.CPU.LogicalCores
+			summary.CoresInUse += float64(latest.CPU.LogicalCores) * latest.CPU.UsagePercent / 100
+		}
+		if latest.Memory != nil {
+			summary.MemoryTotal += latest.Memory.Total
+			summary.MemoryUsed += latest.Memory.Used
+		}
+		if latest.Disk != nil {
+			summary.DiskTotal += latest.Disk.Total
+			summary.DiskUsed += latest.Disk.Used
+			summary.DiskFree += latest.Disk.Free
+		}
+		if latest.Network != nil {
+			summary.NetworkSentRate += latest.Network.TotalBytesSentRate
+			summary.NetworkRecvRate += latest.Network.TotalBytesRecvRate
+		}
+
+		// 与告警检查一致：不在告警作用范围内的探针不统计
+		if len(breaches) == 0 || !agent.MatchAttributes(alertConfig.Rules.AgentAttributes) {
+			continue
+		}
+		values := NewAlertMetricValues(latest)
+		for alertType, breach := range breaches {
+			if thresholdBreached(alertType, breach.Threshold, values) {
+				breach.Agents++
+			}
+		}
+	}
+
+	summary.CoresInUse = math.Round(summary.CoresInUse*100) / 100
+	if summary.TotalCores > 0 {
+		summary.CPUUsagePercent = math.Round(summary.CoresInUse/float64(summary.TotalCores)*10000) / 100
+	}
+	for _, breach := range breaches {
+		summary.Breaches = append(summary.Breaches, *breach)
+	}
+	sort.Slice(summary.Breaches, func(i, j int) bool {
+		return summary.Breaches[i].AlertType < summary.Breaches[j].AlertType
+	})
+	return summary
+}
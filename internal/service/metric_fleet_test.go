@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/go-orz/cache"
+)
+
+func TestBuildFleetSummary(t *testing.T) {
+	s := &MetricService{latestCache: cache.New[string, *metric.LatestMetrics](time.Minute)}
+	s.latestCache.Set("a1", &metric.LatestMetrics{
+		CPU:     &protocol.CPUData{LogicalCores: 4, UsagePercent: 50},
+		Memory:  &protocol.MemoryData{Total: 8, Used: 6, UsagePercent: 75},
+		Disk:    &metric.DiskSummary{Total: 100, Used: 95, Free: 5, UsagePercent: 95},
+		Network: &metric.NetworkSummary{TotalBytesSentRate: 10, TotalBytesRecvRate: 20},
+	}, time.Minute)
+	s.latestCache.Set("a2", &metric.LatestMetrics{
+		CPU:  &protocol.CPUData{LogicalCores: 4, UsagePercent: 100},
+		Disk: &metric.DiskSummary{Total: 100, Used: 10, Free: 90, UsagePercent: 10},
+	}, time.Minute)
+	// 离线探针的缓存不参与统计
+	s.latestCache.Set("a3", &metric.LatestMetrics{CPU: &protocol.CPUData{LogicalCores: 64, UsagePercent: 100}}, time.Minute)
+
+	agents := []models.Agent{
+		{ID: "a1", Status: 1},
+		{ID: "a2", Status: 1},
+		{ID: "a3", Status: 0},
+		{ID: "a4", Status: 1},
+	}
+	config := &models.AlertConfig{
+		Enabled: true,
+		Rules: models.AlertRules{
+			CPUEnabled:    true,
+			CPUThreshold:  90,
+			DiskEnabled:   true,
+			DiskThreshold: 90,
+			LoadEnabled:   true,
+		},
+	}
+
+	summary := s.buildFleetSummary(agents, config, 0)
+	if summary.TotalAgents != 4 || summary.OnlineAgents != 3 || summary.ReportingAgents != 2 {
+		t.Fatalf("agents: total=%d online=%d reporting=%d, want 4/3/2", summary.TotalAgents, summary.OnlineAgents, summary.ReportingAgents)
+	}
+	if summary.TotalCores != 8 || summary.CoresInUse != 6 || summary.CPUUsagePercent != 75 {
+		t.Fatalf("cpu: cores=%d inUse=%v usage=%v, want 8/6/75", summary.TotalCores, summary.CoresInUse, summary.CPUUsagePercent)
+	}
+	if summary.DiskTotal != 200 || summary.DiskUsed != 105 || summary.DiskFree != 95 || summary.NetworkRecvRate != 20 {
+		t.Fatalf("unexpected totals: %+v", summary)
+	}
+
+	// 负载阈值为 0 时不参与统计
+	want := []metric.FleetThresholdBreach{
+		{AlertType: "cpu", Threshold: 90, Agents: 1},
+		{AlertType: "disk", Threshold: 90, Agents: 1},
+	}
+	if len(summary.Breaches) != len(want) {
+		t.Fatalf("breaches: got %+v, want %+v", summary.Breaches, want)
+	}
+	for i := range want {
+		if summary.Breaches[i] != want[i] {
+			t.Fatalf("breaches[%d]: got %+v, want %+v", i, summary.Breaches[i], want[i])
+		}
+	}
+
+	config.Enabled = false
+	if summary := s.buildFleetSummary(agents, config, 0); len(summary.Breaches) != 0 {
+		t.Fatalf("alerting disabled: got %+v, want no breaches", summary.Breaches)
+	}
+}
+
+func TestBuildFleetSummaryFreeModeSkipsMissingMetrics(t *testing.T) {
+	s := &MetricService{latestCache: cache.New[string, *metric.LatestMetrics](time.Minute)}
+	s.latestCache.Set("a1", &metric.LatestMetrics{
+		Memory: &protocol.MemoryData{Total: 8 << 30, Available: 512 << 20},
+		Disk:   &metric.DiskSummary{Total: 100 << 30, Free: 50 << 30},
+	}, time.Minute)
+	// 只上报了 CPU，剩余内存和磁盘空间未知，不能按 0 计入
+	s.latestCache.Set("a2", &metric.LatestMetrics{CPU: &protocol.CPUData{LogicalCores: 4}}, time.Minute)
+
+	agents := []models.Agent{{ID: "a1", Status: 1}, {ID: "a2", Status: 1}}
+	config := &models.AlertConfig{
+		Enabled: true,
+		Rules: models.AlertRules{
+			MemoryEnabled:       true,
+			MemoryThresholdMode: models.ThresholdModeFree,
+			MemoryFreeThreshold: 1,
+			DiskEnabled:         true,
+			DiskThresholdMode:   models.ThresholdModeFree,
+			DiskFreeThreshold:   10,
+		},
+	}
+
+	summary := s.buildFleetSummary(agents, config, 0)
+	want := []metric.FleetThresholdBreach{
+		{AlertType: "disk_free", Threshold: 10, Agents: 0},
+		{AlertType: "memory_free", Threshold: 1, Agents: 1},
+	}
+	if len(summary.Breaches) != len(want) {
+		t.Fatalf("breaches: got %+v, want %+v", summary.Breaches, want)
+	}
+	for i := range want {
+		if summary.Breaches[i] != want[i] {
+			t.Fatalf("breaches[%d]: got %+v, want %+v", i, summary.Breaches[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+// fillGaps 按步长生成 [start, end] 的时间桶网格，并与已有数据点左连接，
+// 没有数据的桶补一个空数据点（序列化为 null），前端据此断开连线而不是直线连接离线区间
+// 网格以系列的第一个数据点为锚点，与 VictoriaMetrics 返回的时间戳对齐
+func fillGaps(series []metric.Series, start, end int64, step time.Duration) {
+	stepMs := step.Milliseconds()
+	if stepMs <= 0 || end < start {
+		return
+	}
+	for i := range series {
+		points := series[i].Data
+		if len(points) == 0 {
+			continue
+		}
+
+		anchor := points[0].Timestamp
+		first := anchor
+		if anchor > start {
+			first = anchor - (anchor-start)/stepMs*stepMs
+		}
+
+		filled := make([]metric.DataPoint, 0, (end-first)/stepMs+1)
+		idx := 0
+		for bucket := first; bucket <= end; bucket += stepMs {
+			if idx < len(points) && points[idx].Timestamp < bucket+stepMs {
+				// 同一个桶内可能有多个点，全部保留
+				for idx < len(points) && points[idx].Timestamp < bucket+stepMs {
+					filled = append(filled, points[idx])
+					idx++
+				}
+				continue
+			}
+			filled = append(filled, metric.DataPoint{Timestamp: bucket, Gap: true})
+		}
+		filled = append(filled, points[idx:]...)
+		series[i].Data = filled
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+func TestFillGaps(t *testing.T) {
+	series := []metric.Series{{
+		Name: "usage",
+		Data: []metric.DataPoint{
+			{Timestamp: 20_000, Value: 1},
+			{Timestamp: 30_000, Value: 2},
+			{Timestamp: 60_000, Value: 3},
+		},
+	}}
+
+	fillGaps(series, 0, 70_000, 10*time.Second)
+
+	data := series[0].Data
+	if len(data) != 8 {
+		t.Fatalf("expected 8 points, got %d", len(data))
+	}
+	gaps := map[int64]bool{0: true, 10_000: true, 40_000: true, 50_000: true, 70_000: true}
+	for i, p := range data {
+		if p.Timestamp != int64(i)*10_000 {
+			t.Fatalf("point %d: unexpected timestamp %d", i, p.Timestamp)
+		}
+		if p.Gap != gaps[p.Timestamp] {
+			t.Fatalf("point %d: expected gap=%v", i, gaps[p.Timestamp])
+		}
+	}
+
+	raw, err := json.Marshal(data[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"timestamp":0,"value":null},{"timestamp":10000,"value":null},{"timestamp":20000,"value":1}]`
+	if string(raw) != expected {
+		t.Fatalf("unexpected json: %s", raw)
+	}
+}
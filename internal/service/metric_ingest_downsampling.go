@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+const (
+	// ingestRuleCacheTTL 探针匹配的入库降采样规则缓存时间，修改配置或探针标签后最多延迟该时间生效
+	ingestRuleCacheTTL = time.Minute
+	// ingestFlushInterval 检查并写入已结束的平均值窗口的间隔
+	ingestFlushInterval = 10 * time.Second
+	// maxIngestDownsamplingInterval 入库降采样窗口的上限
+	maxIngestDownsamplingInterval = time.Hour
+)
+
+// ingestRule 探针匹配的入库降采样规则，interval 为 0 表示不降采样
+type ingestRule struct {
+	interval time.Duration
+	mode     string
+}
+
+// ingestWindow 探针某个指标类型当前所在的降采样窗口
+type ingestWindow struct {
+	start    int64                       // 窗口起点（毫秒）
+	interval int64                       // 窗口长度（毫秒）
+	sums     map[string]*ingestSeriesSum // avg 模式下各序列的累计值，first 模式为空
+}
+
+// ingestSeriesSum 平均值窗口内单个序列的累计值
+type ingestSeriesSum struct {
+	labels map[string]string
+	sum    float64
+	count  int
+}
+
+// ValidateIngestDownsamplingConfig 校验入库降采样配置，每条规则必须指定探针或标签
+func ValidateIngestDownsamplingConfig(config *models.IngestDownsamplingConfig) error {
+	for i, rule := range config.Rules {
+		if len(rule.AgentIDs) == 0 && len(rule.Tags) == 0 {
+			return fmt.Errorf("第 %d 条规则必须指定探针或标签", i+1)
+		}
+		interval := time.Duration(rule.Interval) * time.Second
+		if interval < time.Second || interval > maxIngestDownsamplingInterval {
+			return fmt.Errorf("第 %d 条规则的时间窗口必须在 1 到 %d 秒之间", i+1, int(maxIngestDownsamplingInterval.Seconds()))
+		}
+		switch rule.Mode {
+		case "", models.IngestDownsamplingModeFirst, models.IngestDownsamplingModeAvg:
+		default:
+			return errors.New("降采样方式只支持 first 和 avg")
+		}
+	}
+	return nil
+}
+
+// matchIngestDownsamplingRule 返回探针匹配的第一条规则，未启用或没有匹配时返回 nil
+func matchIngestDownsamplingRule(config *models.IngestDownsamplingConfig, agent *models.Agent) *models.IngestDownsamplingRule {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if slices.Contains(rule.AgentIDs, agent.ID) {
+			return rule
+		}
+		for _, tag := range rule.Tags {
+			if slices.Contains(agent.Tags, tag) {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// getIngestRule 获取探针匹配的入库降采样规则，避免每次上报都查询配置和探针标签
+func (s *MetricService) getIngestRule(ctx context.Context, agentID string) ingestRule {
+	if rule, ok := s.ingestRuleCache.Get(agentID); ok {
+		return rule
+	}
+	var rule ingestRule
+	config, err := s.propertyService.GetIngestDownsamplingConfig(ctx)
+	if err == nil && config.Enabled {
+		if agent, err := s.agentRepo.FindById(ctx, agentID); err == nil {
+			if matched := matchIngestDownsamplingRule(config, &agent); matched != nil {
+				rule.interval = time.Duration(matched.Interval) * time.Second
+				rule.mode = matched.Mode
+				if rule.mode == "" {
+					rule.mode = models.IngestDownsamplingModeFirst
+				}
+			}
+		}
+	}
+	s.ingestRuleCache.Set(agentID, rule, ingestRuleCacheTTL)
+	return rule
+}
+
+// writeMetrics 写入探针上报的指标，匹配入库降采样规则时每个时间窗口只写入一个样本
+// 服务监控数据由服务端按任务调度下发，自定义检查每次上报只包含一个检查项，窗口按类型划分会丢弃其他检查项，均不做降采样
+func (s *MetricService) writeMetrics(ctx context.Context, agentID, metricType string, metrics []vmclient.Metric, timestamp int64) error {
+	if len(metrics) == 0 || metricType == string(protocol.MetricTypeMonitor) || metricType == string(protocol.MetricTypeCustom) {
+		return s.metricStore.Write(ctx, metrics)
+	}
+	rule := s.getIngestRule(ctx, agentID)
+	if rule.interval <= 0 {
+		return s.metricStore.Write(ctx, metrics)
+	}
+
+	toWrite, kept := s.downsampleOnIngest(agentID+":"+metricType, rule, metrics, timestamp)
+	if !kept {
+		s.ingestionMu.Lock()
+		stats, ok := s.ingestionStats[agentID]
+		if !ok {
+			stats = &metric.IngestionStats{AgentID: agentID}
+			s.ingestionStats[agentID] = stats
+		}
+		stats.DownsampledSamples++
+		s.ingestionMu.Unlock()
+	}
+	if len(toWrite) == 0 {
+		return nil
+	}
+	return s.metricStore.Write(ctx, toWrite)
+}
+
+// downsampleOnIngest 按窗口处理一次上报，返回需要写入的指标，kept 表示本次上报的样本是否被单独写入
+// first 模式写入窗口内的第一次上报；avg 模式累计窗口内的样本，进入下一个窗口时写入上一个窗口的平均值
+func (s *MetricService) downsampleOnIngest(key string, rule ingestRule, metrics []vmclient.Metric, timestamp int64) ([]vmclient.Metric, bool) {
+	interval := rule.interval.Milliseconds()
+	start := timestamp - timestamp%interval
+
+	s.ingestWindowMu.Lock()
+	defer s.ingestWindowMu.Unlock()
+
+	window, ok := s.ingestWindows[key]
+	if ok && start < window.start && window.interval == interval {
+		// 迟到的样本所在窗口已经写入过，直接丢弃
+		return nil, false
+	}
+	if ok && window.start == start && window.interval == interval {
+		if window.sums != nil {
+			window.add(metrics)
+		}
+		return nil, false
+	}
+
+	var flushed []vmclient.Metric
+	if ok && window.sums != nil {
+		flushed = window.average()
+	}
+	window = &ingestWindow{start: start, interval: interval}
+	s.ingestWindows[key] = window
+	if rule.mode != models.IngestDownsamplingModeAvg {
+		return append(flushed, metrics...), true
+	}
+	window.sums = make(map[string]*ingestSeriesSum)
+	window.add(metrics)
+	return flushed, false
+}
+
+// add 将一次上报累计到平均值窗口
+func (w *ingestWindow) add(metrics []vmclient.Metric) {
+	for _, m := range metrics {
+		if len(m.Values) == 0 {
+			continue
+		}
+		key := seriesLabelsKey(m.Metric)
+		sum, ok := w.sums[key]
+		if !ok {
+			sum = &ingestSeriesSum{labels: m.Metric}
+			w.sums[key] = sum
+		}
+		sum.sum += m.Values[0]
+		sum.count++
+	}
+}
+
+// average 返回窗口内各序列的平均值，时间戳为窗口起点
+func (w *ingestWindow) average() []vmclient.Metric {
+	keys := make([]string, 0, len(w.sums))
+	for key := range w.sums {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]vmclient.Metric, 0, len(keys))
+	for _, key := range keys {
+		sum := w.sums[key]
+		metrics = append(metrics, vmclient.Metric{
+			Metric:     sum.labels,
+			Values:     []float64{sum.sum / float64(sum.count)},
+			Timestamps: []int64{w.start},
+		})
+	}
+	return metrics
+}
+
+// seriesLabelsKey 按标签生成序列标识
+func seriesLabelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(labels[name])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// RunIngestDownsampling 定期写入已结束但没有新上报触发写入的平均值窗口（如探针离线）
+func (s *MetricService) RunIngestDownsampling(ctx context.Context) {
+	ticker := time.NewTicker(ingestFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.flushIngestWindows(ctx, time.Now().UnixMilli())
+		}
+	}
+}
+
+// flushIngestWindows 写入已结束的平均值窗口，窗口结束后再等待一个窗口长度，留给迟到的样本
+func (s *MetricService) flushIngestWindows(ctx context.Context, now int64) {
+	var metrics []vmclient.Metric
+	s.ingestWindowMu.Lock()
+	for key, window := range s.ingestWindows {
+		if now < window.start+2*window.interval {
+			continue
+		}
+		if window.sums != nil {
+			metrics = append(metrics, window.average()...)
+		}
+		delete(s.ingestWindows, key)
+	}
+	s.ingestWindowMu.Unlock()
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := s.metricStore.Write(ctx, metrics); err != nil {
+		s.logger.Error("写入入库降采样平均值失败", zap.Int("count", len(metrics)), zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/vmclient"
+)
+
+func ingestSample(value float64, timestamp int64) []vmclient.Metric {
+	return []vmclient.Metric{createMetric("pika_cpu_usage_percent", "a1", nil, value, timestamp)}
+}
+
+func TestDownsampleOnIngestFirst(t *testing.T) {
+	s := &MetricService{ingestWindows: make(map[string]*ingestWindow)}
+	rule := ingestRule{interval: 10 * time.Second, mode: models.IngestDownsamplingModeFirst}
+
+	var written []float64
+	for i, ts := range []int64{100_000, 101_000, 109_999, 110_000, 105_000, 121_000} {
+		metrics, kept := s.downsampleOnIngest("a1:cpu", rule, ingestSample(float64(i), ts), ts)
+		if kept != (len(metrics) > 0) {
+			t.Errorf("sample %d: kept = %v with %d metrics", i, kept, len(metrics))
+		}
+		for _, m := range metrics {
+			written = append(written, m.Values[0])
+		}
+	}
+	// 每个 10 秒窗口只写入第一个样本，迟到的样本被丢弃
+	want := []float64{0, 3, 5}
+	if len(written) != len(want) {
+		t.Fatalf("written = %v, want %v", written, want)
+	}
+	for i := range want {
+		if written[i] != want[i] {
+			t.Fatalf("written = %v, want %v", written, want)
+		}
+	}
+}
+
+func TestDownsampleOnIngestAvg(t *testing.T) {
+	s := &MetricService{ingestWindows: make(map[string]*ingestWindow)}
+	rule := ingestRule{interval: 10 * time.Second, mode: models.IngestDownsamplingModeAvg}
+
+	for _, sample := range []struct {
+		value float64
+		ts    int64
+	}{{10, 100_000}, {20, 104_000}, {60, 109_000}} {
+		if metrics, _ := s.downsampleOnIngest("a1:cpu", rule, ingestSample(sample.value, sample.ts), sample.ts); len(metrics) != 0 {
+			t.Fatalf("window should not be written before it ends, got %v", metrics)
+		}
+	}
+
+	metrics, kept := s.downsampleOnIngest("a1:cpu", rule, ingestSample(5, 111_000), 111_000)
+	if kept {
+		t.Error("avg mode should not write samples individually")
+	}
+	if len(metrics) != 1 || metrics[0].Values[0] != 30 || metrics[0].Timestamps[0] != 100_000 {
+		t.Fatalf("flushed = %+v, want average 30 at window start", metrics)
+	}
+
+	window := s.ingestWindows["a1:cpu"]
+	if window == nil || window.start != 110_000 {
+		t.Fatalf("current window = %+v, want start 110000", window)
+	}
+	if got := window.average(); len(got) != 1 || got[0].Values[0] != 5 {
+		t.Errorf("pending average = %+v, want 5", got)
+	}
+}
+
+func TestMatchIngestDownsamplingRule(t *testing.T) {
+	config := &models.IngestDownsamplingConfig{
+		Enabled: true,
+		Rules: []models.IngestDownsamplingRule{
+			{AgentIDs: []string{"a1"}, Interval: 10},
+			{Tags: []string{"edge"}, Interval: 30, Mode: models.IngestDownsamplingModeAvg},
+		},
+	}
+	if rule := matchIngestDownsamplingRule(config, &models.Agent{ID: "a1", Tags: []string{"edge"}}); rule == nil || rule.Interval != 10 {
+		t.Errorf("agent rule should match first, got %+v", rule)
+	}
+	if rule := matchIngestDownsamplingRule(config, &models.Agent{ID: "a2", Tags: []string{"edge"}}); rule == nil || rule.Interval != 30 {
+		t.Errorf("tag rule should match, got %+v", rule)
+	}
+	if rule := matchIngestDownsamplingRule(config, &models.Agent{ID: "a3"}); rule != nil {
+		t.Errorf("unmatched agent got %+v", rule)
+	}
+	config.Enabled = false
+	if rule := matchIngestDownsamplingRule(config, &models.Agent{ID: "a1"}); rule != nil {
+		t.Errorf("disabled config should not match, got %+v", rule)
+	}
+
+	if err := ValidateIngestDownsamplingConfig(&models.IngestDownsamplingConfig{Rules: []models.IngestDownsamplingRule{{Interval: 10}}}); err == nil {
+		t.Error("rule without agents or tags should be rejected")
+	}
+}
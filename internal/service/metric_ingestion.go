@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+// defaultClockSkewTolerance 默认允许的探针时间偏差
+const defaultClockSkewTolerance = 30 * time.Second
+
+// clockSkewPersistStep 时钟偏差变化超过该值（毫秒）时才更新数据库，避免每次上报都写库
+const clockSkewPersistStep = 1000
+
+// ingestionLatencyAlpha 接收延迟指数移动平均的平滑系数
+const ingestionLatencyAlpha = 0.2
+
+// ingestionSampleInterval 接收延迟和采集耗时写入 VictoriaMetrics 的间隔，期间的上报聚合为一个样本
+const ingestionSampleInterval = time.Minute
+
+// ingestionSample 一个写入间隔内的接收延迟和采集耗时汇总
+type ingestionSample struct {
+	latencySum      int64
+	latencyCount    int64
+	collectDuration map[string]int64 // 指标类型 -> 最大采集耗时
+}
+
+// RecordIngestion 记录一次指标上报的采集耗时和接收延迟
+// timestamp 为探针上报的采集时间，receivedAt 为服务端接收时间，均为毫秒
+// 历史趋势不逐帧写入，按 ingestionSampleInterval 汇总后由 flushIngestionSamples 写入 VictoriaMetrics
+func (s *MetricService) RecordIngestion(ctx context.Context, agentID, metricType string, timestamp, collectDuration, receivedAt int64) {
+	if timestamp == 0 {
+		return
+	}
+	latency := receivedAt - timestamp
+
+	s.ingestionMu.Lock()
+	stats, ok := s.ingestionStats[agentID]
+	if !ok {
+		stats = &metric.IngestionStats{AgentID: agentID, AvgLatency: latency}
+		s.ingestionStats[agentID] = stats
+	}
+	stats.Samples++
+	stats.UpdatedAt = receivedAt
+	stats.LastLatency = latency
+	stats.AvgLatency = int64(ingestionLatencyAlpha*float64(latency) + (1-ingestionLatencyAlpha)*float64(stats.AvgLatency))
+	if collectDuration > 0 {
+		stats.LastCollectDuration = collectDuration
+		if collectDuration > stats.MaxCollectDuration {
+			stats.MaxCollectDuration = collectDuration
+			stats.MaxCollectType = metricType
+		}
+	}
+	wasSkewed := stats.ClockSkewed
+	stats.ClockSkewed = abs(stats.AvgLatency) > s.clockSkewTolerance.Milliseconds()
+	skewed, avgLatency := stats.ClockSkewed, stats.AvgLatency
+	// 首次统计、偏差状态变化或偏差变化较大时持久化
+	persist := stats.Samples == 1 || skewed != wasSkewed || abs(avgLatency-stats.PersistedSkew) >= clockSkewPersistStep
+	if persist {
+		stats.PersistedSkew = avgLatency
+	}
+
+	sample, ok := s.ingestionSamples[agentID]
+	if !ok {
+		sample = &ingestionSample{collectDuration: make(map[string]int64)}
+		s.ingestionSamples[agentID] = sample
+	}
+	sample.latencySum += latency
+	sample.latencyCount++
+	if collectDuration > sample.collectDuration[metricType] {
+		sample.collectDuration[metricType] = collectDuration
+	}
+	s.ingestionMu.Unlock()
+
+	if skewed && !wasSkewed {
+		s.logger.Warn("探针上报时间与服务端时间偏差过大，请检查探针时钟同步",
+			zap.String("agentId", agentID),
+			zap.Int64("avgLatencyMs", avgLatency),
+			zap.Duration("tolerance", s.clockSkewTolerance),
+			zap.Bool("correct", s.correctClockSkew))
+	} else if !skewed && wasSkewed {
+		s.logger.Info("探针时钟偏差已恢复正常",
+			zap.String("agentId", agentID),
+			zap.Int64("avgLatencyMs", avgLatency))
+	}
+	if persist {
+		if err := s.agentRepo.UpdateClockSkew(ctx, agentID, avgLatency, skewed); err != nil {
+			s.logger.Error("保存探针时钟偏差失败", zap.String("agentId", agentID), zap.Error(err))
+		}
+	}
+}
+
+// flushIngestionSamples 写入上一个间隔内各探针的平均接收延迟和各指标类型的最大采集耗时
+func (s *MetricService) flushIngestionSamples(ctx context.Context, now int64) {
+	s.ingestionMu.Lock()
+	samples := s.ingestionSamples
+	s.ingestionSamples = make(map[string]*ingestionSample, len(samples))
+	s.ingestionMu.Unlock()
+
+	var metrics []vmclient.Metric
+	for agentID, sample := range samples {
+		if sample.latencyCount > 0 {
+			metrics = append(metrics, createMetric("pika_agent_ingest_latency_ms", agentID, nil,
+				float64(sample.latencySum)/float64(sample.latencyCount), now))
+		}
+		for metricType, duration := range sample.collectDuration {
+			if duration <= 0 {
+				continue
+			}
+			metrics = append(metrics, createMetric("pika_agent_collect_duration_ms", agentID,
+				map[string]string{"type": metricType}, float64(duration), now))
+		}
+	}
+	if len(metrics) == 0 {
+		return
+	}
+	if err := s.metricStore.Write(ctx, metrics); err != nil {
+		s.logger.Warn("写入指标上报延迟失败", zap.Int("count", len(metrics)), zap.Error(err))
+	}
+}
+
+// RunIngestionSamples 定时写入汇总后的接收延迟和采集耗时
+func (s *MetricService) RunIngestionSamples(ctx context.Context) {
+	ticker := time.NewTicker(ingestionSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.flushIngestionSamples(ctx, time.Now().UnixMilli())
+		}
+	}
+}
+
+// ForgetAgent 探针删除后清理内存中按探针记录的上报统计、限流记录和未写入的汇总数据
+func (s *MetricService) ForgetAgent(agentID string) {
+	prefix := agentID + ":"
+
+	s.ingestionMu.Lock()
+	delete(s.ingestionStats, agentID)
+	delete(s.ingestionSamples, agentID)
+	for key := range s.lastSampleAt {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.lastSampleAt, key)
+		}
+	}
+	s.ingestionMu.Unlock()
+
+	s.ingestWindowMu.Lock()
+	for key := range s.ingestWindows {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.ingestWindows, key)
+		}
+	}
+	s.ingestWindowMu.Unlock()
+
+	s.latestCache.Delete(agentID)
+	s.primaryCache.Delete(agentID)
+	s.ingestRuleCache.Delete(agentID)
+}
+
+// clockOffset 返回校正探针时间戳需要加上的偏移量（毫秒），未启用校正或偏差未超限时为 0
+func (s *MetricService) clockOffset(agentID string) int64 {
+	if !s.correctClockSkew {
+		return 0
+	}
+	s.ingestionMu.Lock()
+	defer s.ingestionMu.Unlock()
+	stats, ok := s.ingestionStats[agentID]
+	if !ok || !stats.ClockSkewed {
+		return 0
+	}
+	return stats.AvgLatency
+}
+
+// GetIngestionStats 获取各探针的采集耗时与接收延迟统计，时钟偏差的探针排在前面
+func (s *MetricService) GetIngestionStats() []metric.IngestionStats {
+	s.ingestionMu.Lock()
+	result := make([]metric.IngestionStats, 0, len(s.ingestionStats))
+	for _, stats := range s.ingestionStats {
+		result = append(result, *stats)
+	}
+	s.ingestionMu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ClockSkewed != result[j].ClockSkewed {
+			return result[i].ClockSkewed
+		}
+		return abs(result[i].AvgLatency) > abs(result[j].AvgLatency)
+	})
+	return result
+}
+
+// abs 返回整数的绝对值
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+// defaultAllowedIntervals 默认允许的查询步长，与 vmclient.AutoStep 的各档位一致
+var defaultAllowedIntervals = []time.Duration{
+	10 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+}
+
+// maxIntervalPoints 单个系列允许返回的最大点数，请求的步长过小时会自动放大
+const maxIntervalPoints = 10000
+
+// normalizeAllowedIntervals 校验并规范化配置的步长（秒），去除非法值并去重排序，未配置时使用默认值
+func normalizeAllowedIntervals(logger *zap.Logger, seconds []int) []time.Duration {
+	seen := make(map[int]struct{}, len(seconds))
+	var intervals []time.Duration
+	for _, sec := range seconds {
+		if sec <= 0 {
+			logger.Warn("忽略无效的查询步长", zap.Int("seconds", sec))
+			continue
+		}
+		if _, ok := seen[sec]; ok {
+			continue
+		}
+		seen[sec] = struct{}{}
+		intervals = append(intervals, time.Duration(sec)*time.Second)
+	}
+	if len(intervals) == 0 {
+		return defaultAllowedIntervals
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals
+}
+
+// alignInterval 将步长对齐到允许的步长：取不小于 d 的最小值，超出范围时取最大值
+func alignInterval(d time.Duration, allowed []time.Duration) time.Duration {
+	for _, interval := range allowed {
+		if interval >= d {
+			return interval
+		}
+	}
+	return allowed[len(allowed)-1]
+}
+
+// DetermineInterval 确定查询步长，requested 为 0 时按时间范围自动选择，结果始终在允许的步长内
+func (s *MetricService) DetermineInterval(start, end int64, requested time.Duration) time.Duration {
+	target := requested
+	if target <= 0 {
+		target = vmclient.AutoStep(time.UnixMilli(start), time.UnixMilli(end))
+	}
+	return s.limitInterval(start, end, target)
+}
+
+// limitInterval 按整个查询范围限制最小步长并对齐到允许的步长
+// 起始时间超过降采样档位时，步长不小于该档位的间隔（可能大于允许的最大步长）
+func (s *MetricService) limitInterval(start, end int64, target time.Duration) time.Duration {
+	// 避免请求过小的步长导致点数过多
+	if minInterval := time.Duration(end-start) * time.Millisecond / maxIntervalPoints; target < minInterval {
+		target = minInterval
+	}
+	return max(alignInterval(target, s.allowedIntervals), downsamplingInterval(s.downsampling, start, time.Now()))
+}
+
+// representativeMetric 返回指标类型对应的代表性指标，用于探测数据的实际时间范围
+func representativeMetric(metricType string) (string, bool) {
+	for _, item := range coverageMetrics {
+		if string(item.Type) == metricType {
+			return item.Metric, true
+		}
+	}
+	return "", false
+}
+
+// availableDataRange 查询时间范围内实际存在数据的首尾时间戳（毫秒），无数据或查询失败时 ok 为 false
+func (s *MetricService) availableDataRange(ctx context.Context, agentID, metricType string, start, end int64) (first, last int64, ok bool) {
+	metricName, found := representativeMetric(metricType)
+	if !found || end <= start {
+		return 0, 0, false
+	}
+
+	// 即时查询以当前时间为评估点，通过 offset 将窗口对齐到 [start, end]
+	window := fmt.Sprintf("[%ds]", (end-start+999)/1000)
+	if offset := (time.Now().UnixMilli() - end) / 1000; offset > 0 {
+		window += fmt.Sprintf(" offset %ds", offset)
+	}
+	selector := fmt.Sprintf(`%s{agent_id="%s"}%s`, metricName, agentID, window)
+
+	// tfirst_over_time / tlast_over_time 返回窗口内首个/最后一个样本的时间戳（秒）
+	queryTimestamp := func(query string) (int64, bool) {
+		result, err := s.vmClient.Query(ctx, query)
+		if err != nil {
+			s.logger.Warn("查询数据时间范围失败",
+				zap.String("agentId", agentID),
+				zap.String("query", query),
+				zap.Error(err))
+			return 0, false
+		}
+		points := vmclient.ConvertToDataPoints(result)
+		if len(points) == 0 || points[0].Value <= 0 {
+			return 0, false
+		}
+		return int64(points[0].Value * 1000), true
+	}
+
+	first, ok = queryTimestamp(fmt.Sprintf("min(tfirst_over_time(%s))", selector))
+	if !ok {
+		return 0, 0, false
+	}
+	last, ok = queryTimestamp(fmt.Sprintf("max(tlast_over_time(%s))", selector))
+	if !ok {
+		return 0, 0, false
+	}
+	return max(first, start), min(last, end), true
+}
+
+// determineDataInterval 自动选择步长时参考实际数据范围，数据稀疏（如新接入的探针）时返回更细的粒度
+func (s *MetricService) determineDataInterval(ctx context.Context, agentID, metricType string, start, end int64, requested time.Duration) time.Duration {
+	if requested > 0 {
+		return s.DetermineInterval(start, end, requested)
+	}
+	first, last, ok := s.availableDataRange(ctx, agentID, metricType, start, end)
+	if !ok || last <= first {
+		return s.DetermineInterval(start, end, 0)
+	}
+	// 步长按实际数据范围选择，点数上限仍按整个查询范围计算
+	target := vmclient.AutoStep(time.UnixMilli(first), time.UnixMilli(last))
+	return s.limitInterval(start, end, target)
+}
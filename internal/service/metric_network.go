@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"go.uber.org/zap"
+)
+
+// networkInterfaceQueries 按网卡查询的字段和 PromQL 模板（参数为探针ID和时间窗口）
+// 所有查询都按 interface 分组，长时间范围降采样后仍保留网卡维度；interface 为空的汇总数据被排除
+// 平均速率由累计字节数在时间桶内重新计算，比对瞬时速率采样取平均更准确，也能正确处理计数器归零
+var networkInterfaceQueries = []struct {
+	field string
+	query string
+}{
+	{"avgSent", `sum by (interface) (rate(pika_network_sent_bytes_total{agent_id="%s",interface!=""}[%s]))`},
+	{"minSent", `min by (interface) (min_over_time(pika_network_sent_bytes_rate{agent_id="%s",interface!=""}[%s]))`},
+	{"maxSent", `max by (interface) (max_over_time(pika_network_sent_bytes_rate{agent_id="%s",interface!=""}[%s]))`},
+	{"avgRecv", `sum by (interface) (rate(pika_network_recv_bytes_total{agent_id="%s",interface!=""}[%s]))`},
+	{"minRecv", `min by (interface) (min_over_time(pika_network_recv_bytes_rate{agent_id="%s",interface!=""}[%s]))`},
+	{"maxRecv", `max by (interface) (max_over_time(pika_network_recv_bytes_rate{agent_id="%s",interface!=""}[%s]))`},
+}
+
+// GetNetworkMetricsByInterface 按网卡查询网络速率历史，每个时间桶返回各网卡的平均、最小、最大速率
+// 网卡以名称区分，改名（如 eth0 -> ens3）后作为不同的网卡返回，可通过 firstSeen / lastSeen 判断前后关系
+func (s *MetricService) GetNetworkMetricsByInterface(ctx context.Context, agentID string, start, end int64, interval time.Duration) (*metric.NetworkByInterfaceResponse, error) {
+	step := s.determineDataInterval(ctx, agentID, "network", start, end, interval)
+	window := fmt.Sprintf("%ds", int(step.Seconds()))
+
+	type bucketKey struct {
+		iface string
+		ts    int64
+	}
+	buckets := make(map[bucketKey]*metric.NetworkInterfaceMetric)
+	for _, q := range networkInterfaceQueries {
+		query := fmt.Sprintf(q.query, agentID, window)
+		result, err := s.vmClient.QueryRange(ctx, query, time.UnixMilli(start), time.UnixMilli(end), step)
+		if err != nil {
+			s.logger.Error("查询网卡速率失败",
+				zap.String("agentId", agentID),
+				zap.String("query", query),
+				zap.Error(err))
+			return nil, err
+		}
+		for _, series := range s.convertQueryResultToSeries(result, q.field, nil) {
+			iface := series.Labels["interface"]
+			if iface == "" {
+				continue
+			}
+			for _, p := range series.Data {
+				key := bucketKey{iface: iface, ts: p.Timestamp}
+				row, ok := buckets[key]
+				if !ok {
+					row = &metric.NetworkInterfaceMetric{Timestamp: p.Timestamp, Interface: iface}
+					buckets[key] = row
+				}
+				setNetworkInterfaceField(row, q.field, p.Value)
+			}
+		}
+	}
+
+	metrics := make([]metric.NetworkInterfaceMetric, 0, len(buckets))
+	for _, row := range buckets {
+		metrics = append(metrics, *row)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Timestamp != metrics[j].Timestamp {
+			return metrics[i].Timestamp < metrics[j].Timestamp
+		}
+		return metrics[i].Interface < metrics[j].Interface
+	})
+
+	macs := make(map[string]string)
+	if latest, ok := s.GetLatestMetrics(agentID); ok {
+		for _, netData := range latest.NetworkInterfaces {
+			macs[netData.Interface] = netData.MacAddress
+		}
+	}
+
+	return &metric.NetworkByInterfaceResponse{
+		AgentID:    agentID,
+		Type:       "network",
+		Range:      fmt.Sprintf("%d-%d", start, end),
+		Start:      start,
+		End:        end,
+		Interval:   int64(step.Seconds()),
+		Metrics:    metrics,
+		Interfaces: summarizeNetworkInterfaces(metrics, macs, s.precision),
+	}, nil
+}
+
+// setNetworkInterfaceField 将查询结果写入时间桶对应的字段
+func setNetworkInterfaceField(row *metric.NetworkInterfaceMetric, field string, value float64) {
+	switch field {
+	case "avgSent":
+		row.AvgSentRate = value
+	case "minSent":
+		row.MinSentRate = value
+	case "maxSent":
+		row.MaxSentRate = value
+	case "avgRecv":
+		row.AvgRecvRate = value
+	case "minRecv":
+		row.MinRecvRate = value
+	case "maxRecv":
+		row.MaxRecvRate = value
+	}
+}
+
+// summarizeNetworkInterfaces 汇总每个网卡在整个范围内的速率，metrics 需按时间排序
+// 平均速率为各时间桶平均速率的平均值，最小、最大速率取各时间桶的极值
+func summarizeNetworkInterfaces(metrics []metric.NetworkInterfaceMetric, macs map[string]string, precision int) []metric.NetworkInterfaceSummary {
+	index := make(map[string]int)
+	summaries := make([]metric.NetworkInterfaceSummary, 0)
+	var counts []int
+	for _, row := range metrics {
+		i, ok := index[row.Interface]
+		if !ok {
+			i = len(summaries)
+			index[row.Interface] = i
+			summaries = append(summaries, metric.NetworkInterfaceSummary{
+				Interface:   row.Interface,
+				MacAddress:  macs[row.Interface],
+				FirstSeen:   row.Timestamp,
+				MinSentRate: row.MinSentRate,
+				MinRecvRate: row.MinRecvRate,
+			})
+			counts = append(counts, 0)
+		}
+		summary := &summaries[i]
+		summary.LastSeen = row.Timestamp
+		summary.AvgSentRate += row.AvgSentRate
+		summary.AvgRecvRate += row.AvgRecvRate
+		summary.MinSentRate = min(summary.MinSentRate, row.MinSentRate)
+		summary.MinRecvRate = min(summary.MinRecvRate, row.MinRecvRate)
+		summary.MaxSentRate = max(summary.MaxSentRate, row.MaxSentRate)
+		summary.MaxRecvRate = max(summary.MaxRecvRate, row.MaxRecvRate)
+		counts[i]++
+	}
+
+	for i := range summaries {
+		summaries[i].AvgSentRate = roundValue(summaries[i].AvgSentRate/float64(counts[i]), precision)
+		summaries[i].AvgRecvRate = roundValue(summaries[i].AvgRecvRate/float64(counts[i]), precision)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Interface < summaries[j].Interface })
+	return summaries
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+func TestSummarizeNetworkInterfaces(t *testing.T) {
+	// eth0 改名为 ens3 后应作为两个网卡分别统计
+	metrics := []metric.NetworkInterfaceMetric{
+		{Timestamp: 1000, Interface: "eth0", AvgSentRate: 100, MinSentRate: 50, MaxSentRate: 200, AvgRecvRate: 10, MinRecvRate: 5, MaxRecvRate: 20},
+		{Timestamp: 2000, Interface: "eth0", AvgSentRate: 300, MinSentRate: 20, MaxSentRate: 400, AvgRecvRate: 30, MinRecvRate: 1, MaxRecvRate: 60},
+		{Timestamp: 3000, Interface: "ens3", AvgSentRate: 500, MinSentRate: 500, MaxSentRate: 500, AvgRecvRate: 50, MinRecvRate: 50, MaxRecvRate: 50},
+	}
+
+	summaries := summarizeNetworkInterfaces(metrics, map[string]string{"ens3": "aa:bb:cc:dd:ee:ff"}, 2)
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 interfaces, got %d", len(summaries))
+	}
+
+	ens3, eth0 := summaries[0], summaries[1]
+	if ens3.Interface != "ens3" || ens3.MacAddress != "aa:bb:cc:dd:ee:ff" || ens3.FirstSeen != 3000 || ens3.LastSeen != 3000 {
+		t.Fatalf("unexpected ens3 summary: %+v", ens3)
+	}
+	if eth0.Interface != "eth0" || eth0.MacAddress != "" || eth0.FirstSeen != 1000 || eth0.LastSeen != 2000 {
+		t.Fatalf("unexpected eth0 summary: %+v", eth0)
+	}
+	if eth0.AvgSentRate != 200 || eth0.MinSentRate != 20 || eth0.MaxSentRate != 400 {
+		t.Fatalf("unexpected eth0 sent rates: %+v", eth0)
+	}
+	if eth0.AvgRecvRate != 20 || eth0.MinRecvRate != 1 || eth0.MaxRecvRate != 60 {
+		t.Fatalf("unexpected eth0 recv rates: %+v", eth0)
+	}
+
+	if empty := summarizeNetworkInterfaces(nil, nil, 2); empty == nil || len(empty) != 0 {
+		t.Fatalf("expected empty non-nil summaries, got %v", empty)
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/utils"
+	"go.uber.org/zap"
+)
+
+// MetricPartialError 数组类指标（磁盘、网卡、GPU 等）部分条目处理失败
+type MetricPartialError struct {
+	MetricType string
+	Timestamp  int64
+	Succeeded  int
+	Failed     int
+	Items      []protocol.MetricItemError
+	Resend     bool // 写入存储失败，建议探针重新上报
+}
+
+func (e *MetricPartialError) Error() string {
+	return fmt.Sprintf("%s 指标处理部分失败: 成功 %d 条，失败 %d 条", e.MetricType, e.Succeeded, e.Failed)
+}
+
+// Result 转换为回传给探针的处理结果
+func (e *MetricPartialError) Result() protocol.MetricsResult {
+	return protocol.MetricsResult{
+		Type:        protocol.MetricType(e.MetricType),
+		Timestamp:   e.Timestamp,
+		Succeeded:   e.Succeeded,
+		Failed:      e.Failed,
+		FailedItems: e.Items,
+		Resend:      e.Resend,
+	}
+}
+
+// decodeMetricItems 逐条解析数组类指标，单个条目格式错误不影响其他条目
+func decodeMetricItems[T any](data json.RawMessage) ([]T, []protocol.MetricItemError, error) {
+	var rawItems []json.RawMessage
+	if err := json.Unmarshal(data, &rawItems); err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]T, 0, len(rawItems))
+	var itemErrs []protocol.MetricItemError
+	for i, raw := range rawItems {
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			itemErrs = append(itemErrs, protocol.MetricItemError{Index: i, Error: err.Error()})
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, itemErrs, nil
+}
+
+// writeArrayMetrics 写入数组类指标，有条目失败时返回 MetricPartialError 并记录成功/失败数
+func (s *MetricService) writeArrayMetrics(ctx context.Context, agentID, metricType string, items interface{}, succeeded int, itemErrs []protocol.MetricItemError, timestamp int64) error {
+	metrics := s.convertToMetrics(agentID, metricType, items, timestamp)
+	if err := s.writeMetrics(ctx, agentID, metricType, metrics, timestamp); err != nil {
+		s.logger.Error("写入指标失败",
+			zap.String("agentId", agentID),
+			zap.String("type", metricType),
+			zap.Int("succeeded", 0),
+			zap.Int("failed", succeeded+len(itemErrs)),
+			zap.Error(err),
+			utils.TraceField(ctx))
+		return &MetricPartialError{
+			MetricType: metricType,
+			Timestamp:  timestamp,
+			Failed:     succeeded + len(itemErrs),
+			Items:      append(itemErrs, protocol.MetricItemError{Index: -1, Error: err.Error()}),
+			Resend:     true,
+		}
+	}
+
+	if len(itemErrs) == 0 {
+		return nil
+	}
+	s.logger.Warn("指标部分条目解析失败",
+		zap.String("agentId", agentID),
+		zap.String("type", metricType),
+		zap.Int("succeeded", succeeded),
+		zap.Int("failed", len(itemErrs)),
+		utils.TraceField(ctx))
+	return &MetricPartialError{
+		MetricType: metricType,
+		Timestamp:  timestamp,
+		Succeeded:  succeeded,
+		Failed:     len(itemErrs),
+		Items:      itemErrs,
+	}
+}
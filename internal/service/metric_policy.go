@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// metricPolicyLogInterval 同一探针同一指标类型被策略丢弃时的日志间隔
+const metricPolicyLogInterval = 10 * time.Minute
+
+// GetMetricPolicy 获取探针的指标采集策略，未配置时返回空策略（不限制）
+func (s *MetricService) GetMetricPolicy(ctx context.Context, agentID string) (*protocol.MetricPolicyData, error) {
+	policies, err := s.propertyService.GetMetricPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy := policies[agentID]
+	return &policy, nil
+}
+
+// SetMetricPolicy 设置探针的指标采集策略，Allow 和 Deny 均为空时删除该探针的策略
+func (s *MetricService) SetMetricPolicy(ctx context.Context, agentID string, policy *protocol.MetricPolicyData) error {
+	for _, metricType := range slices.Concat(policy.Allow, policy.Deny) {
+		if !protocol.IsKnownMetricType(metricType) {
+			return fmt.Errorf("未知的指标类型: %s", metricType)
+		}
+	}
+
+	policies, err := s.propertyService.GetMetricPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	if len(policy.Allow) == 0 && len(policy.Deny) == 0 {
+		delete(policies, agentID)
+	} else {
+		policies[agentID] = *policy
+	}
+	return s.propertyService.SetMetricPolicies(ctx, policies)
+}
+
+// DeleteMetricPolicy 删除探针的指标采集策略
+func (s *MetricService) DeleteMetricPolicy(ctx context.Context, agentID string) error {
+	policies, err := s.propertyService.GetMetricPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := policies[agentID]; !ok {
+		return nil
+	}
+	delete(policies, agentID)
+	return s.propertyService.SetMetricPolicies(ctx, policies)
+}
+
+// metricAllowed 判断探针上报的指标类型是否被策略允许，被丢弃时按间隔记录日志
+func (s *MetricService) metricAllowed(ctx context.Context, agentID, metricType string) bool {
+	policy, err := s.GetMetricPolicy(ctx, agentID)
+	if err != nil {
+		// 策略读取失败时不影响正常入库
+		return true
+	}
+	if policy.Allowed(protocol.MetricType(metricType)) {
+		return true
+	}
+
+	key := agentID + ":" + metricType
+	if _, logged := s.policyDropLog.Get(key); !logged {
+		s.policyDropLog.Set(key, struct{}{}, metricPolicyLogInterval)
+		s.logger.Info("指标类型被探针采集策略禁止，已丢弃",
+			zap.String("agentId", agentID),
+			zap.String("type", metricType),
+			zap.Strings("allow", metricTypesToStrings(policy.Allow)),
+			zap.Strings("deny", metricTypesToStrings(policy.Deny)),
+		)
+	}
+	return false
+}
+
+func metricTypesToStrings(types []protocol.MetricType) []string {
+	values := make([]string, len(types))
+	for i, metricType := range types {
+		values[i] = string(metricType)
+	}
+	return values
+}
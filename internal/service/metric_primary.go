@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// 主磁盘、主网卡的来源
+const (
+	PrimarySourceConfigured = "configured" // 运维指定
+	PrimarySourceAuto       = "auto"       // 自动识别
+)
+
+// primaryDevicesCacheTTL 探针主磁盘、主网卡设置的缓存时间，修改探针信息时主动失效
+const primaryDevicesCacheTTL = time.Minute
+
+// primaryDevices 探针的主磁盘、主网卡设置和连接 IP（用于自动识别主网卡）
+type primaryDevices struct {
+	MountPoint string
+	Interface  string
+	IP         string
+}
+
+// getPrimaryDevices 获取探针的主磁盘、主网卡设置，避免每次上报都查询数据库
+func (s *MetricService) getPrimaryDevices(ctx context.Context, agentID string) primaryDevices {
+	if devices, ok := s.primaryCache.Get(agentID); ok {
+		return devices
+	}
+	var devices primaryDevices
+	if agent, err := s.agentRepo.FindById(ctx, agentID); err == nil {
+		devices = primaryDevices{
+			MountPoint: agent.PrimaryMountPoint,
+			Interface:  agent.PrimaryInterface,
+			IP:         agent.IP,
+		}
+	}
+	s.primaryCache.Set(agentID, devices, primaryDevicesCacheTTL)
+	return devices
+}
+
+// InvalidatePrimaryDevices 探针的主磁盘、主网卡设置变更后使缓存失效，下一次上报时生效
+func (s *MetricService) InvalidatePrimaryDevices(agentID string) {
+	s.primaryCache.Delete(agentID)
+}
+
+// selectPrimaryDisk 选择主磁盘：运维指定的挂载点存在时使用，否则依次选择根目录、Windows 系统盘和容量最大的磁盘
+func selectPrimaryDisk(disks []protocol.DiskData, configured string) (*protocol.DiskData, string) {
+	if len(disks) == 0 {
+		return nil, ""
+	}
+	if configured != "" {
+		for i := range disks {
+			if disks[i].MountPoint == configured {
+				return &disks[i], PrimarySourceConfigured
+			}
+		}
+	}
+
+	largest := 0
+	for i := range disks {
+		mountPoint := strings.TrimRight(disks[i].MountPoint, `\`)
+		if mountPoint == "/" || strings.EqualFold(mountPoint, "C:") {
+			return &disks[i], PrimarySourceAuto
+		}
+		if disks[i].Total > disks[largest].Total {
+			largest = i
+		}
+	}
+	return &disks[largest], PrimarySourceAuto
+}
+
+// selectPrimaryInterface 选择主网卡：运维指定的网卡存在时使用，否则选择绑定了探针连接 IP 的网卡，都没有时选择累计流量最大的网卡
+func selectPrimaryInterface(interfaces []protocol.NetworkData, configured, ip string) (*protocol.NetworkData, string) {
+	if len(interfaces) == 0 {
+		return nil, ""
+	}
+	if configured != "" {
+		for i := range interfaces {
+			if interfaces[i].Interface == configured {
+				return &interfaces[i], PrimarySourceConfigured
+			}
+		}
+	}
+
+	if connIP := net.ParseIP(ip); connIP != nil {
+		for i := range interfaces {
+			for _, addr := range interfaces[i].Addrs {
+				addrIP := net.ParseIP(addr)
+				if prefix, _, err := net.ParseCIDR(addr); err == nil {
+					addrIP = prefix
+				}
+				if addrIP != nil && addrIP.Equal(connIP) {
+					return &interfaces[i], PrimarySourceAuto
+				}
+			}
+		}
+	}
+
+	busiest := 0
+	for i := range interfaces {
+		if interfaces[i].BytesSentTotal+interfaces[i].BytesRecvTotal > interfaces[busiest].BytesSentTotal+interfaces[busiest].BytesRecvTotal {
+			busiest = i
+		}
+	}
+	return &interfaces[busiest], PrimarySourceAuto
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+func TestSelectPrimaryDisk(t *testing.T) {
+	disks := []protocol.DiskData{
+		{MountPoint: "/data", Total: 4000},
+		{MountPoint: "/", Total: 100},
+	}
+	if primary, source := selectPrimaryDisk(disks, ""); primary.MountPoint != "/" || source != PrimarySourceAuto {
+		t.Fatalf("expected root mount auto-detected, got %s (%s)", primary.MountPoint, source)
+	}
+	if primary, source := selectPrimaryDisk(disks, "/data"); primary.MountPoint != "/data" || source != PrimarySourceConfigured {
+		t.Fatalf("expected configured mount, got %s (%s)", primary.MountPoint, source)
+	}
+	// 指定的挂载点不存在时回退到自动识别
+	if primary, source := selectPrimaryDisk(disks, "/missing"); primary.MountPoint != "/" || source != PrimarySourceAuto {
+		t.Fatalf("expected fallback to auto, got %s (%s)", primary.MountPoint, source)
+	}
+	if primary, _ := selectPrimaryDisk([]protocol.DiskData{{MountPoint: "D:", Total: 10}, {MountPoint: `C:\`, Total: 5}}, ""); primary.MountPoint != `C:\` {
+		t.Fatalf("expected windows system drive, got %s", primary.MountPoint)
+	}
+	if primary, _ := selectPrimaryDisk([]protocol.DiskData{{MountPoint: "/a", Total: 10}, {MountPoint: "/b", Total: 50}}, ""); primary.MountPoint != "/b" {
+		t.Fatalf("expected largest disk, got %s", primary.MountPoint)
+	}
+	if primary, _ := selectPrimaryDisk(nil, "/"); primary != nil {
+		t.Fatalf("expected nil for empty disks")
+	}
+}
+
+func TestSelectPrimaryInterface(t *testing.T) {
+	interfaces := []protocol.NetworkData{
+		{Interface: "eth0", Addrs: []string{"10.0.0.2/24"}, BytesSentTotal: 10},
+		{Interface: "eth1", Addrs: []string{"192.168.1.5/24", "fe80::1/64"}, BytesSentTotal: 1000},
+	}
+	if primary, source := selectPrimaryInterface(interfaces, "", "10.0.0.2"); primary.Interface != "eth0" || source != PrimarySourceAuto {
+		t.Fatalf("expected interface with connection ip, got %s (%s)", primary.Interface, source)
+	}
+	if primary, _ := selectPrimaryInterface(interfaces, "", "203.0.113.1"); primary.Interface != "eth1" {
+		t.Fatalf("expected busiest interface, got %s", primary.Interface)
+	}
+	if primary, source := selectPrimaryInterface(interfaces, "eth0", "203.0.113.1"); primary.Interface != "eth0" || source != PrimarySourceConfigured {
+		t.Fatalf("expected configured interface, got %s (%s)", primary.Interface, source)
+	}
+}
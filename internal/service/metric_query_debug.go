@@ -0,0 +1,48 @@
+package service
+
+import (
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/vmclient"
+)
+
+// newQueryDebug 创建查询调试信息，起始时间命中降采样档位时数据来源为降采样数据
+func (s *MetricService) newQueryDebug(start int64, step time.Duration, aggregation string) *metric.QueryDebug {
+	debug := &metric.QueryDebug{
+		Source:      metric.AsOfSourceRaw,
+		Step:        int64(step.Seconds()),
+		Aggregation: aggregation,
+		Queries:     make([]metric.QueryDebugEntry, 0),
+	}
+	if interval := downsamplingInterval(s.downsampling, start, time.Now()); interval > 0 {
+		debug.Source = metric.AsOfSourceAggregate
+		debug.DownsamplingInterval = int64(interval.Seconds())
+	}
+	return debug
+}
+
+// recordQueryDebug 记录单个 PromQL 查询的扫描量、返回量和耗时，并累加到汇总
+func recordQueryDebug(debug *metric.QueryDebug, q metric.QueryDefinition, result *vmclient.QueryResult, series []metric.Series, elapsed time.Duration, err error) {
+	entry := metric.QueryDebugEntry{
+		Name:       q.Name,
+		Query:      q.Query,
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if result != nil && result.Stats != nil {
+		entry.SeriesFetched, _ = result.Stats.SeriesFetched.Int64()
+		entry.ExecutionTimeMs = result.Stats.ExecutionTimeMsec
+	}
+	entry.SeriesReturned = len(series)
+	for _, item := range series {
+		entry.PointsReturned += len(item.Data)
+	}
+
+	debug.Queries = append(debug.Queries, entry)
+	debug.SeriesFetched += entry.SeriesFetched
+	debug.SeriesReturned += entry.SeriesReturned
+	debug.PointsReturned += entry.PointsReturned
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/vmclient"
+)
+
+func TestRecordQueryDebug(t *testing.T) {
+	var result vmclient.QueryResult
+	body := `{"status":"success","data":{"resultType":"matrix","result":[]},"stats":{"seriesFetched":"3","executionTimeMsec":12}}`
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatalf("decode query result: %v", err)
+	}
+
+	debug := &metric.QueryDebug{}
+	series := []metric.Series{
+		{Name: "usage", Data: []metric.DataPoint{{Timestamp: 1}, {Timestamp: 2}}},
+		{Name: "usage", Data: []metric.DataPoint{{Timestamp: 1}}},
+	}
+	recordQueryDebug(debug, metric.QueryDefinition{Name: "usage", Query: "q1"}, &result, series, 5*time.Millisecond, nil)
+	recordQueryDebug(debug, metric.QueryDefinition{Name: "free", Query: "q2"}, nil, nil, time.Millisecond, errors.New("timeout"))
+
+	if len(debug.Queries) != 2 {
+		t.Fatalf("queries = %d, want 2", len(debug.Queries))
+	}
+	first := debug.Queries[0]
+	if first.SeriesFetched != 3 || first.ExecutionTimeMs != 12 || first.SeriesReturned != 2 || first.PointsReturned != 3 || first.DurationMs != 5 {
+		t.Errorf("first entry = %+v", first)
+	}
+	if debug.Queries[1].Error != "timeout" {
+		t.Errorf("failed query error = %q, want timeout", debug.Queries[1].Error)
+	}
+	if debug.SeriesFetched != 3 || debug.SeriesReturned != 2 || debug.PointsReturned != 3 {
+		t.Errorf("totals = fetched %d, series %d, points %d", debug.SeriesFetched, debug.SeriesReturned, debug.PointsReturned)
+	}
+}
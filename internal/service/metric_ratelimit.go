@@ -0,0 +1,66 @@
+package service
+
+import (
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// sampleDropLogInterval 丢弃过快上报的日志节流间隔
+const sampleDropLogInterval = time.Minute
+
+// allowSample 按探针和指标类型限制上报频率，采集时间与上一个接受的样本相差小于最小间隔的数据被丢弃并计数
+// 按探针的采集时间而非接收时间比较，断线重连后补发的缓存数据采集时间间隔正常，不会被丢弃
+// 服务监控数据由服务端按任务调度下发、自定义检查每个检查单独上报，各结果分别上报，不做限制
+func (s *MetricService) allowSample(agentID, metricType string, sampledAt int64) bool {
+	if s.minSampleInterval <= 0 || metricType == string(protocol.MetricTypeMonitor) || metricType == string(protocol.MetricTypeCustom) {
+		return true
+	}
+
+	key := agentID + ":" + metricType
+	s.ingestionMu.Lock()
+	last, ok := s.lastSampleAt[key]
+	if diff := sampledAt - last; !ok || diff >= s.minSampleInterval.Milliseconds() || -diff >= s.minSampleInterval.Milliseconds() {
+		s.lastSampleAt[key] = sampledAt
+		s.ingestionMu.Unlock()
+		return true
+	}
+
+	stats, ok := s.ingestionStats[agentID]
+	if !ok {
+		stats = &metric.IngestionStats{AgentID: agentID}
+		s.ingestionStats[agentID] = stats
+	}
+	stats.DroppedSamples++
+	if stats.DroppedByType == nil {
+		stats.DroppedByType = make(map[string]int64)
+	}
+	stats.DroppedByType[metricType]++
+	stats.LastDroppedAt = time.Now().UnixMilli()
+	dropped := stats.DroppedSamples
+	s.ingestionMu.Unlock()
+
+	logKey := "rate:" + key
+	if _, logged := s.policyDropLog.Get(logKey); !logged {
+		s.policyDropLog.Set(logKey, struct{}{}, sampleDropLogInterval)
+		s.logger.Warn("探针上报过于频繁，已丢弃",
+			zap.String("agentId", agentID),
+			zap.String("type", metricType),
+			zap.Duration("minInterval", s.minSampleInterval),
+			zap.Int64("dropped", dropped),
+		)
+	}
+	return false
+}
+
+// forgetSample 样本处理失败时撤销限流记录，探针重新上报同一采集时间的数据时不会被当作过快上报丢弃
+func (s *MetricService) forgetSample(agentID, metricType string, sampledAt int64) {
+	key := agentID + ":" + metricType
+	s.ingestionMu.Lock()
+	defer s.ingestionMu.Unlock()
+	if last, ok := s.lastSampleAt[key]; ok && last == sampledAt {
+		delete(s.lastSampleAt, key)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"github.com/go-orz/cache"
+	"go.uber.org/zap"
+)
+
+func TestAllowSample(t *testing.T) {
+	s := &MetricService{
+		logger:            zap.NewNop(),
+		policyDropLog:     cache.New[string, struct{}](time.Minute),
+		ingestionStats:    make(map[string]*metric.IngestionStats),
+		minSampleInterval: time.Second,
+		lastSampleAt:      make(map[string]int64),
+	}
+
+	if !s.allowSample("agent-1", "cpu", 1000) {
+		t.Fatal("first sample should be allowed")
+	}
+	if s.allowSample("agent-1", "cpu", 1100) {
+		t.Fatal("sample within min interval should be dropped")
+	}
+	if !s.allowSample("agent-1", "memory", 1100) {
+		t.Fatal("different metric type should be limited separately")
+	}
+	if !s.allowSample("agent-1", "monitor", 1100) || !s.allowSample("agent-1", "monitor", 1150) {
+		t.Fatal("monitor data should not be limited")
+	}
+	if !s.allowSample("agent-1", "cpu", 2000) {
+		t.Fatal("sample after min interval should be allowed")
+	}
+	if !s.allowSample("agent-1", "custom", 2000) || !s.allowSample("agent-1", "custom", 2010) {
+		t.Fatal("custom check results should not be limited")
+	}
+	// 重连后补发的缓存数据采集时间早于最新样本，间隔正常，不应丢弃
+	for _, ts := range []int64{-8000, -3000} {
+		if !s.allowSample("agent-1", "cpu", ts) {
+			t.Fatalf("replayed sample at %d should be allowed", ts)
+		}
+	}
+	// 写入失败后重新上报同一采集时间的数据
+	s.forgetSample("agent-1", "cpu", -3000)
+	if !s.allowSample("agent-1", "cpu", -3000) {
+		t.Fatal("resent sample should be allowed after forgetSample")
+	}
+
+	stats := s.ingestionStats["agent-1"]
+	if stats == nil || stats.DroppedSamples != 1 || stats.DroppedByType["cpu"] != 1 {
+		t.Fatalf("unexpected dropped stats: %+v", stats)
+	}
+}
+
+type recordingMetricStore struct {
+	metrics []vmclient.Metric
+}
+
+func (r *recordingMetricStore) Name() string { return "recording" }
+
+func (r *recordingMetricStore) Write(_ context.Context, metrics []vmclient.Metric) error {
+	r.metrics = append(r.metrics, metrics...)
+	return nil
+}
+
+func TestFlushIngestionSamplesAndForgetAgent(t *testing.T) {
+	store := &recordingMetricStore{}
+	s := &MetricService{
+		logger:          zap.NewNop(),
+		metricStore:     store,
+		ingestionStats:  map[string]*metric.IngestionStats{"agent-1": {AgentID: "agent-1"}},
+		lastSampleAt:    map[string]int64{"agent-1:cpu": 1000, "agent-10:cpu": 1000},
+		ingestWindows:   map[string]*ingestWindow{"agent-1:cpu": {}},
+		latestCache:     cache.New[string, *metric.LatestMetrics](time.Minute),
+		primaryCache:    cache.New[string, primaryDevices](time.Minute),
+		ingestRuleCache: cache.New[string, ingestRule](time.Minute),
+		ingestionSamples: map[string]*ingestionSample{
+			"agent-1": {latencySum: 300, latencyCount: 3, collectDuration: map[string]int64{"cpu": 20, "disk": 0}},
+		},
+	}
+
+	s.flushIngestionSamples(context.Background(), 5000)
+	// 一个间隔内的多次上报只写入一个平均延迟样本和各类型的最大采集耗时
+	if len(store.metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(store.metrics))
+	}
+	for _, m := range store.metrics {
+		if m.Metric["__name__"] == "pika_agent_ingest_latency_ms" && m.Values[0] != 100 {
+			t.Fatalf("unexpected average latency: %v", m.Values)
+		}
+	}
+	if len(s.ingestionSamples) != 0 {
+		t.Fatal("samples should be reset after flush")
+	}
+
+	s.ingestionSamples["agent-1"] = &ingestionSample{}
+	s.ForgetAgent("agent-1")
+	if len(s.ingestionStats) != 0 || len(s.ingestionSamples) != 0 || len(s.ingestWindows) != 0 {
+		t.Fatal("agent state should be removed")
+	}
+	if _, ok := s.lastSampleAt["agent-10:cpu"]; !ok || len(s.lastSampleAt) != 1 {
+		t.Fatalf("only the deleted agent should be forgotten: %v", s.lastSampleAt)
+	}
+}
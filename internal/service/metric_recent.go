@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRecentRawLimit 默认返回的最近原始数据点数
+	defaultRecentRawLimit = 60
+	// maxRecentRawLimit 最多返回的最近原始数据点数
+	maxRecentRawLimit = 1000
+	// maxRecentRawLookback 最近原始数据的最大回溯窗口
+	maxRecentRawLookback = time.Hour
+)
+
+// GetRecentRawMetrics 获取最近 limit 个原始数据点（不做聚合和时间范围归一化），用于实时小图
+func (s *MetricService) GetRecentRawMetrics(ctx context.Context, agentID, metricType, interfaceName string, limit int) (*metric.GetMetricsResponse, error) {
+	if limit <= 0 {
+		limit = defaultRecentRawLimit
+	}
+	if limit > maxRecentRawLimit {
+		limit = maxRecentRawLimit
+	}
+
+	queries := s.buildPromQLQueries(agentID, metricType, interfaceName, "", 0)
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("unsupported metric type: %s", metricType)
+	}
+
+	// 按采集间隔估算回溯窗口，多留一倍余量应对上报抖动
+	lookback := defaultCollectorInterval * time.Duration(limit) * 2
+	lookback = min(max(lookback, time.Minute), maxRecentRawLookback)
+
+	var series []metric.Series
+	for _, q := range queries {
+		query := rawRangeQuery(q.Query, lookback)
+		result, err := s.vmClient.Query(ctx, query)
+		if err != nil {
+			s.logger.Error("查询最近原始数据失败",
+				zap.String("query", query),
+				zap.Error(err))
+			continue
+		}
+
+		for _, item := range s.convertQueryResultToSeries(result, q.Name, q.Labels) {
+			if len(item.Data) > limit {
+				item.Data = item.Data[len(item.Data)-limit:]
+			}
+			series = append(series, item)
+		}
+	}
+
+	end := time.Now().UnixMilli()
+	return &metric.GetMetricsResponse{
+		AgentID: agentID,
+		Type:    metricType,
+		Range:   fmt.Sprintf("%d-%d", end-lookback.Milliseconds(), end),
+		Series:  series,
+	}, nil
+}
+
+// rawRangeQuery 构造返回原始样本的范围查询
+// 纯选择器直接使用范围向量；聚合表达式使用按采集间隔的子查询
+func rawRangeQuery(query string, lookback time.Duration) string {
+	window := fmt.Sprintf("%ds", int(lookback.Seconds()))
+	if strings.HasPrefix(query, "pika_") && strings.HasSuffix(query, "}") {
+		return fmt.Sprintf("%s[%s]", query, window)
+	}
+	return fmt.Sprintf("(%s)[%s:%ds]", query, window, int(defaultCollectorInterval.Seconds()))
+}
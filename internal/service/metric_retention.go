@@ -0,0 +1,26 @@
+package service
+
+import (
+	"time"
+)
+
+// ClampTimeRange 将查询起始时间限制在数据保留范围内
+// extended 为 true 时使用 ExtendedRetentionDays（仅限已登录的管理员），否则使用 RetentionDays
+func (s *MetricService) ClampTimeRange(start, end int64, extended bool) (int64, int64) {
+	retention := s.retention
+	if extended {
+		retention = s.extendedRetention
+	}
+	if retention <= 0 {
+		return start, end
+	}
+
+	earliest := time.Now().Add(-retention).UnixMilli()
+	if start < earliest {
+		start = earliest
+	}
+	if start >= end {
+		start = end - 1
+	}
+	return start, end
+}
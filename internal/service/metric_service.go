@@ -4,14 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"slices"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/dushixiang/pika/internal/config"
 	"github.com/dushixiang/pika/internal/metric"
+	"github.com/dushixiang/pika/internal/metricstore"
+	"github.com/dushixiang/pika/internal/models"
 	"github.com/dushixiang/pika/internal/protocol"
 	"github.com/dushixiang/pika/internal/repo"
+	"github.com/dushixiang/pika/internal/utils"
 	"github.com/dushixiang/pika/internal/vmclient"
 	"github.com/go-orz/toolkit/syncx"
 
@@ -22,36 +26,132 @@ import (
 
 // MetricService 指标服务
 type MetricService struct {
-	logger          *zap.Logger
-	agentRepo       *repo.AgentRepo
-	monitorRepo     *repo.MonitorRepo
-	propertyService *PropertyService
-	trafficService  *TrafficService // 流量统计服务
-	vmClient        *vmclient.VMClient
-
-	latestCache cache.Cache[string, *metric.LatestMetrics] // Agent 最新指标缓存
+	logger            *zap.Logger
+	agentRepo         *repo.AgentRepo
+	monitorRepo       *repo.MonitorRepo
+	DiskForecastRepo  *repo.DiskForecastRepo
+	storageStatsRepo  *repo.StorageStatsRepo
+	propertyService   *PropertyService
+	trafficService    *TrafficService              // 流量统计服务
+	vmClient          *vmclient.VMClient           // 用于查询
+	metricStore       metricstore.MetricStore      // 指标写入后端
+	precision         int                          // 查询结果保留的小数位数，负数表示不取整
+	retention         time.Duration                // 普通查询允许的最长回溯时间，0 表示不限制
+	extendedRetention time.Duration                // 管理员扩展查询允许的最长回溯时间，0 表示不限制
+	aggregations      map[string]map[string]string // 指标类型 -> 系列名称 -> 聚合函数
+	allowedIntervals  []time.Duration              // 允许的查询步长（升序）
+	downsampling      []downsamplingTier           // 多级保留档位（按时长升序），查询较早的数据时放大步长
+
+	latestCache   cache.Cache[string, *metric.LatestMetrics] // Agent 最新指标缓存
+	policyDropLog cache.Cache[string, struct{}]              // 被采集策略丢弃的指标日志节流
+	staticCache   cache.Cache[string, any]                   // 探针ID/设备 -> 最后一次上报的静态描述信息
+	primaryCache  cache.Cache[string, primaryDevices]        // 探针ID -> 主磁盘、主网卡设置
 
 	monitorLatestCache cache.Cache[string, *metric.LatestMonitorMetrics] // 监控最新指标缓存
+	monitorHTTPCache   cache.Cache[string, *protocol.HTTPMonitorConfig]  // 监控任务ID -> HTTP 断言配置，任务不存在时为 nil
+	fleetSummaryCache  cache.Cache[string, *metric.FleetSummary]         // 所有探针的汇总统计缓存
+
+	clockSkewTolerance time.Duration                     // 探针上报时间允许的最大偏差
+	correctClockSkew   bool                              // 是否将偏差超限的探针时间戳校正为服务端时间
+	ingestionMu        sync.Mutex                        // 保护 ingestionStats、ingestionSamples
+	ingestionStats     map[string]*metric.IngestionStats // 探针ID -> 指标上报延迟统计
+	ingestionSamples   map[string]*ingestionSample       // 探针ID -> 未写入的接收延迟和采集耗时汇总
+	minSampleInterval  time.Duration                     // 同一探针同一指标类型两次上报的最小间隔，0 表示不限制
+	lastSampleAt       map[string]int64                  // 探针ID:指标类型 -> 最近一次接受上报的时间（毫秒），由 ingestionMu 保护
+
+	ingestRuleCache cache.Cache[string, ingestRule] // 探针ID -> 匹配的入库降采样规则
+	ingestWindowMu  sync.Mutex                      // 保护 ingestWindows
+	ingestWindows   map[string]*ingestWindow        // 探针ID:指标类型 -> 当前的入库降采样窗口
 }
 
 // NewMetricService 创建指标服务
-func NewMetricService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, trafficService *TrafficService, vmClient *vmclient.VMClient) *MetricService {
+func NewMetricService(logger *zap.Logger, db *gorm.DB, propertyService *PropertyService, trafficService *TrafficService, vmClient *vmclient.VMClient, metricStore metricstore.MetricStore, appConfig *config.AppConfig) *MetricService {
+	precision := defaultValuePrecision
+	if appConfig.VictoriaMetrics != nil && appConfig.VictoriaMetrics.Precision != nil {
+		precision = *appConfig.VictoriaMetrics.Precision
+	}
+	var retention, extendedRetention time.Duration
+	var aggregations map[string]map[string]string
+	allowedIntervals := defaultAllowedIntervals
+	var downsampling []downsamplingTier
+	if appConfig.VictoriaMetrics != nil {
+		retention = time.Duration(appConfig.VictoriaMetrics.RetentionDays) * 24 * time.Hour
+		extendedRetention = time.Duration(appConfig.VictoriaMetrics.ExtendedRetentionDays) * 24 * time.Hour
+		aggregations = normalizeAggregationConfig(logger, appConfig.VictoriaMetrics.Aggregations)
+		allowedIntervals = normalizeAllowedIntervals(logger, appConfig.VictoriaMetrics.AllowedIntervals)
+		downsampling = normalizeDownsamplingTiers(logger, appConfig.VictoriaMetrics.Downsampling)
+	}
+
+	clockSkewTolerance := defaultClockSkewTolerance
+	var minSampleInterval time.Duration
+	var correctClockSkew bool
+	if appConfig.Agent != nil {
+		if appConfig.Agent.ClockSkewTolerance > 0 {
+			clockSkewTolerance = time.Duration(appConfig.Agent.ClockSkewTolerance) * time.Second
+		}
+		correctClockSkew = appConfig.Agent.CorrectClockSkew
+		if appConfig.Agent.MinSampleInterval > 0 {
+			minSampleInterval = time.Duration(appConfig.Agent.MinSampleInterval) * time.Millisecond
+		}
+	}
+
 	return &MetricService{
 		logger:             logger,
 		agentRepo:          repo.NewAgentRepo(db),
+		DiskForecastRepo:   repo.NewDiskForecastRepo(db),
 		monitorRepo:        repo.NewMonitorRepo(db),
+		storageStatsRepo:   repo.NewStorageStatsRepo(db),
 		propertyService:    propertyService,
 		trafficService:     trafficService,
 		vmClient:           vmClient,
+		metricStore:        metricStore,
+		precision:          precision,
+		retention:          retention,
+		extendedRetention:  extendedRetention,
+		aggregations:       aggregations,
+		allowedIntervals:   allowedIntervals,
+		downsampling:       downsampling,
 		latestCache:        cache.New[string, *metric.LatestMetrics](time.Minute),
+		policyDropLog:      cache.New[string, struct{}](time.Minute),
+		staticCache:        cache.New[string, any](time.Minute),
+		primaryCache:       cache.New[string, primaryDevices](time.Minute),
 		monitorLatestCache: cache.New[string, *metric.LatestMonitorMetrics](5 * time.Minute), // 监控数据缓存 5 分钟
+		monitorHTTPCache:   cache.New[string, *protocol.HTTPMonitorConfig](monitorHTTPConfigCacheTTL),
+		fleetSummaryCache:  cache.New[string, *metric.FleetSummary](time.Minute),
+		clockSkewTolerance: clockSkewTolerance,
+		correctClockSkew:   correctClockSkew,
+		ingestionStats:     make(map[string]*metric.IngestionStats),
+		ingestionSamples:   make(map[string]*ingestionSample),
+		minSampleInterval:  minSampleInterval,
+		lastSampleAt:       make(map[string]int64),
+		ingestRuleCache:    cache.New[string, ingestRule](time.Minute),
+		ingestWindows:      make(map[string]*ingestWindow),
 	}
 }
 
 // HandleMetricData 处理指标数据
-func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, metricType string, data json.RawMessage, timestamp int64) error {
+func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, metricType string, data json.RawMessage, timestamp int64) (err error) {
+	// 时钟偏差超限的探针按测得的偏差校正到服务端时间
+	clockOffset := s.clockOffset(agentID)
 	if timestamp == 0 {
 		timestamp = time.Now().UnixMilli()
+	} else {
+		timestamp += clockOffset
+	}
+
+	// 丢弃上报过于频繁的数据，避免异常探针压垮存储
+	if !s.allowSample(agentID, metricType, timestamp) {
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			s.forgetSample(agentID, metricType, timestamp)
+		}
+	}()
+
+	// 按探针的采集策略丢弃被禁止的指标类型
+	if !s.metricAllowed(ctx, agentID, metricType) {
+		return nil
 	}
 
 	// 更新内存缓存
@@ -60,6 +160,18 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		latestMetrics = &metric.LatestMetrics{}
 		s.latestCache.Set(agentID, latestMetrics, time.Hour)
 	}
+	if traceID := utils.TraceIDFromContext(ctx); traceID != "" {
+		latestMetrics.TraceID = traceID
+	}
+	if timestamp > latestMetrics.UpdatedAt {
+		latestMetrics.UpdatedAt = timestamp
+	}
+	latestMetrics.Revision++
+	s.logger.Debug("接收指标数据",
+		zap.String("agentId", agentID),
+		zap.String("type", metricType),
+		utils.TraceField(ctx),
+	)
 
 	// 解析数据并写入 VictoriaMetrics
 	switch protocol.MetricType(metricType) {
@@ -68,9 +180,10 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		if err := json.Unmarshal(data, &cpuData); err != nil {
 			return err
 		}
+		s.mergeCPUStatic(agentID, &cpuData)
 		latestMetrics.CPU = &cpuData
 		metrics := s.convertToMetrics(agentID, metricType, &cpuData, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeMetrics(ctx, agentID, metricType, metrics, timestamp)
 
 	case protocol.MetricTypeMemory:
 		var memData protocol.MemoryData
@@ -79,11 +192,11 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		}
 		latestMetrics.Memory = &memData
 		metrics := s.convertToMetrics(agentID, metricType, &memData, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeMetrics(ctx, agentID, metricType, metrics, timestamp)
 
 	case protocol.MetricTypeDisk:
-		var diskDataList []protocol.DiskData
-		if err := json.Unmarshal(data, &diskDataList); err != nil {
+		diskDataList, itemErrs, err := decodeMetricItems[protocol.DiskData](data)
+		if err != nil {
 			return err
 		}
 		// 计算汇总数据用于缓存
@@ -97,19 +210,28 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		if totalTotal > 0 {
 			usagePercent = float64(totalUsed) / float64(totalTotal) * 100
 		}
-		latestMetrics.Disk = &metric.DiskSummary{
+		diskSummary := &metric.DiskSummary{
 			UsagePercent: usagePercent,
 			TotalDisks:   len(diskDataList),
 			Total:        totalTotal,
 			Used:         totalUsed,
 			Free:         totalFree,
 		}
-		metrics := s.convertToMetrics(agentID, metricType, diskDataList, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		devices := s.getPrimaryDevices(ctx, agentID)
+		if primary, source := selectPrimaryDisk(diskDataList, devices.MountPoint); primary != nil {
+			diskSummary.PrimaryMountPoint = primary.MountPoint
+			diskSummary.PrimarySource = source
+			diskSummary.PrimaryUsagePercent = primary.UsagePercent
+			diskSummary.PrimaryTotal = primary.Total
+			diskSummary.PrimaryUsed = primary.Used
+			diskSummary.PrimaryFree = primary.Free
+		}
+		latestMetrics.Disk = diskSummary
+		return s.writeArrayMetrics(ctx, agentID, metricType, diskDataList, len(diskDataList), itemErrs, timestamp)
 
 	case protocol.MetricTypeNetwork:
-		var networkDataList []protocol.NetworkData
-		if err := json.Unmarshal(data, &networkDataList); err != nil {
+		networkDataList, itemErrs, err := decodeMetricItems[protocol.NetworkData](data)
+		if err != nil {
 			return err
 		}
 		// 计算汇总数据用于缓存
@@ -121,13 +243,21 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 			totalSentTotal += netData.BytesSentTotal
 			totalRecvTotal += netData.BytesRecvTotal
 		}
-		latestMetrics.Network = &metric.NetworkSummary{
+		networkSummary := &metric.NetworkSummary{
 			TotalBytesSentRate:  totalSentRate,
 			TotalBytesRecvRate:  totalRecvRate,
 			TotalBytesSentTotal: totalSentTotal,
 			TotalBytesRecvTotal: totalRecvTotal,
 			TotalInterfaces:     len(networkDataList),
 		}
+		devices := s.getPrimaryDevices(ctx, agentID)
+		if primary, source := selectPrimaryInterface(networkDataList, devices.Interface, devices.IP); primary != nil {
+			networkSummary.PrimaryInterface = primary.Interface
+			networkSummary.PrimarySource = source
+			networkSummary.PrimaryBytesSentRate = primary.BytesSentRate
+			networkSummary.PrimaryBytesRecvRate = primary.BytesRecvRate
+		}
+		latestMetrics.Network = networkSummary
 		latestMetrics.NetworkInterfaces = networkDataList
 		// 更新流量统计
 		if err := s.trafficService.UpdateAgentTraffic(ctx, agentID, totalRecvTotal, totalSentTotal); err != nil {
@@ -135,8 +265,7 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 				zap.String("agentId", agentID),
 				zap.Error(err))
 		}
-		metrics := s.convertToMetrics(agentID, metricType, networkDataList, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeArrayMetrics(ctx, agentID, metricType, networkDataList, len(networkDataList), itemErrs, timestamp)
 
 	case protocol.MetricTypeNetworkConnection:
 		var connData protocol.NetworkConnectionData
@@ -145,43 +274,60 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		}
 		latestMetrics.NetworkConnection = &connData
 		metrics := s.convertToMetrics(agentID, metricType, &connData, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeMetrics(ctx, agentID, metricType, metrics, timestamp)
 
 	case protocol.MetricTypeDiskIO:
-		var diskIODataList []*protocol.DiskIOData
-		if err := json.Unmarshal(data, &diskIODataList); err != nil {
+		diskIODataList, itemErrs, err := decodeMetricItems[*protocol.DiskIOData](data)
+		if err != nil {
 			return err
 		}
-		metrics := s.convertToMetrics(agentID, metricType, diskIODataList, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeArrayMetrics(ctx, agentID, metricType, diskIODataList, len(diskIODataList), itemErrs, timestamp)
 
 	case protocol.MetricTypeHost:
 		var hostData protocol.HostInfoData
 		if err := json.Unmarshal(data, &hostData); err != nil {
 			return err
 		}
+		s.mergeHostStatic(agentID, &hostData)
 		latestMetrics.Host = &hostData
+		// 旧版探针不单独上报负载，从主机信息中提取
+		if latestMetrics.Load == nil || latestMetrics.LoadFromHost {
+			loadData := &protocol.LoadData{Load1: hostData.Load1, Load5: hostData.Load5, Load15: hostData.Load15}
+			latestMetrics.Load = loadData
+			latestMetrics.LoadFromHost = true
+			metrics := s.convertToMetrics(agentID, string(protocol.MetricTypeLoad), loadData, timestamp)
+			return s.writeMetrics(ctx, agentID, string(protocol.MetricTypeLoad), metrics, timestamp)
+		}
 		return nil
 
+	case protocol.MetricTypeLoad:
+		var loadData protocol.LoadData
+		if err := json.Unmarshal(data, &loadData); err != nil {
+			return err
+		}
+		latestMetrics.Load = &loadData
+		latestMetrics.LoadFromHost = false
+		metrics := s.convertToMetrics(agentID, metricType, &loadData, timestamp)
+		return s.writeMetrics(ctx, agentID, metricType, metrics, timestamp)
+
 	case protocol.MetricTypeGPU:
-		var gpuDataList []protocol.GPUData
-		if err := json.Unmarshal(data, &gpuDataList); err != nil {
+		gpuDataList, itemErrs, err := decodeMetricItems[protocol.GPUData](data)
+		if err != nil {
 			return err
 		}
+		s.mergeGPUStatic(agentID, gpuDataList)
 		// 更新缓存
 		latestMetrics.GPU = gpuDataList
-		metrics := s.convertToMetrics(agentID, metricType, gpuDataList, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeArrayMetrics(ctx, agentID, metricType, gpuDataList, len(gpuDataList), itemErrs, timestamp)
 
 	case protocol.MetricTypeTemperature:
-		var tempDataList []protocol.TemperatureData
-		if err := json.Unmarshal(data, &tempDataList); err != nil {
+		tempDataList, itemErrs, err := decodeMetricItems[protocol.TemperatureData](data)
+		if err != nil {
 			return err
 		}
 		// 更新缓存
 		latestMetrics.Temp = tempDataList
-		metrics := s.convertToMetrics(agentID, metricType, tempDataList, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeArrayMetrics(ctx, agentID, metricType, tempDataList, len(tempDataList), itemErrs, timestamp)
 
 	case protocol.MetricTypeMonitor:
 		var monitorDataList []protocol.MonitorData
@@ -190,7 +336,12 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		}
 		for i := range monitorDataList {
 			monitorDataList[i].AgentId = agentID // 关联探针ID
+			if clockOffset != 0 && monitorDataList[i].CheckedAt > 0 {
+				monitorDataList[i].CheckedAt += clockOffset
+			}
 		}
+		// 服务端校验 HTTP 断言
+		s.applyMonitorAssertions(ctx, monitorDataList)
 		// 更新缓存
 		latestMetrics.Monitors = monitorDataList
 		for _, monitorData := range monitorDataList {
@@ -198,7 +349,26 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 		}
 
 		metrics := s.convertToMetrics(agentID, metricType, monitorDataList, timestamp)
-		return s.vmClient.Write(ctx, metrics)
+		return s.writeMetrics(ctx, agentID, metricType, metrics, timestamp)
+
+	case protocol.MetricTypeCustom:
+		var customDataList []protocol.CustomMetricData
+		if err := json.Unmarshal(data, &customDataList); err != nil {
+			return err
+		}
+		for _, customData := range customDataList {
+			if !customData.Success {
+				s.logger.Debug("自定义检查执行失败",
+					zap.String("agentId", agentID),
+					zap.String("check", customData.Name),
+					zap.String("error", customData.Error),
+				)
+			}
+		}
+		// 更新缓存
+		latestMetrics.Custom = mergeCustomMetrics(latestMetrics.Custom, customDataList)
+		metrics := s.convertToMetrics(agentID, metricType, customDataList, timestamp)
+		return s.writeMetrics(ctx, agentID, metricType, metrics, timestamp)
 
 	default:
 		s.logger.Warn("unknown cpiMetric type", zap.String("type", metricType))
@@ -207,26 +377,40 @@ func (s *MetricService) HandleMetricData(ctx context.Context, agentID string, me
 }
 
 // GetMetrics 获取聚合指标数据（从 VictoriaMetrics 查询）
-// 返回统一的 GetMetricsResponse 格式
-func (s *MetricService) GetMetrics(ctx context.Context, agentID, metricType string, start, end int64, interfaceName string, aggregation string) (*metric.GetMetricsResponse, error) {
-	step := vmclient.AutoStep(time.UnixMilli(start), time.UnixMilli(end))
+// 返回统一的 GetMetricsResponse 格式，smooth 为 true 时对结果做移动平均平滑
+// interval 为请求的步长，0 表示自动选择，最终会对齐到允许的步长
+// fill 为 true 时按步长网格补齐没有数据的时间桶（value 为 null），否则只返回有数据的时间桶
+// decimation 为 lttb 时按最小步长查询，再用 LTTB 缩减到 points 个点（为 0 时与按步长聚合的点数相同）
+func (s *MetricService) GetMetrics(ctx context.Context, agentID, metricType string, start, end int64, interfaceName string, aggregation string, smooth bool, fields []string, interval time.Duration, fill bool, decimation string, points int) (*metric.GetMetricsResponse, error) {
+	began := time.Now()
+	step := s.determineDataInterval(ctx, agentID, metricType, start, end, interval)
+	queryStep := step
+	if decimation == DecimationLTTB {
+		points = decimationPoints(points, start, end, step)
+		queryStep = s.DetermineInterval(start, end, s.allowedIntervals[0])
+	}
 
 	// 构造 PromQL 查询（返回多个查询以支持多系列）
-	queries := s.buildPromQLQueries(agentID, metricType, interfaceName, aggregation, step)
+	queries := s.buildPromQLQueries(agentID, metricType, interfaceName, aggregation, queryStep)
 	if len(queries) == 0 {
 		return nil, fmt.Errorf("unsupported metric type: %s", metricType)
 	}
+	// 按 fields 只查询需要的系列，减少查询次数和响应体积
+	queries = filterQueriesByFields(queries, fields)
 
 	// 执行查询并转换结果
 	// step 设为 0，让 VictoriaMetrics 自动选择合适的步长
 	var series []metric.Series
+	debug := s.newQueryDebug(start, queryStep, aggregation)
 
 	for _, q := range queries {
+		queryBegan := time.Now()
 		result, err := s.vmClient.QueryRange(ctx, q.Query,
 			time.UnixMilli(start),
 			time.UnixMilli(end),
-			step)
+			queryStep)
 		if err != nil {
+			recordQueryDebug(debug, q, nil, nil, time.Since(queryBegan), err)
 			s.logger.Error("查询 VictoriaMetrics 失败",
 				zap.String("query", q.Query),
 				zap.Error(err))
@@ -235,9 +419,29 @@ func (s *MetricService) GetMetrics(ctx context.Context, agentID, metricType stri
 
 		// 转换查询结果为 MetricSeries
 		convertedSeries := s.convertQueryResultToSeries(result, q.Name, q.Labels)
+		for i := range convertedSeries {
+			convertedSeries[i].Aggregation = q.Aggregation
+		}
+		recordQueryDebug(debug, q, result, convertedSeries, time.Since(queryBegan), nil)
 		series = append(series, convertedSeries...)
 	}
 
+	// LTTB 挑选的点间隔不均匀，需要补齐时在数据断开处插入空数据点，不再按步长补齐
+	if decimation == DecimationLTTB {
+		decimateSeries(series, points, queryStep, fill)
+	}
+
+	// 可选的平滑处理，与聚合方式无关
+	if smooth {
+		smoothSeries(series, smoothingWindow(step))
+		roundSeries(series, s.precision)
+	}
+
+	// 补齐空数据点需在平滑之后进行，避免空值参与平均
+	if fill && decimation != DecimationLTTB {
+		fillGaps(series, start, end, step)
+	}
+
 	// 如果是监控类型，添加监控任务名称到标签中
 	if metricType == "monitor" && len(series) > 0 {
 		// 收集所有 monitor_id
@@ -277,11 +481,15 @@ func (s *MetricService) GetMetrics(ctx context.Context, agentID, metricType stri
 		}
 	}
 
+	debug.DurationMs = time.Since(began).Milliseconds()
 	return &metric.GetMetricsResponse{
-		AgentID: agentID,
-		Type:    metricType,
-		Range:   fmt.Sprintf("%d-%d", start, end),
-		Series:  series,
+		AgentID:    agentID,
+		Type:       metricType,
+		Range:      fmt.Sprintf("%d-%d", start, end),
+		Interval:   int64(step.Seconds()),
+		Decimation: decimation,
+		Series:     series,
+		Debug:      debug,
 	}, nil
 }
 
@@ -300,11 +508,15 @@ func (s *MetricService) CleanMonitorCache(ctx context.Context, monitorID string)
 		return err
 	}
 
-	// 只在有过滤条件（指定了 AgentIds）时清理缓存
-	if len(monitorTask.AgentIds) > 0 {
+	// 只在有过滤条件（指定了探针或标签）时清理缓存
+	if hasAgentSelector(&monitorTask) {
+		selection, err := resolveMonitorAgents(ctx, s.agentRepo, &monitorTask)
+		if err != nil {
+			return err
+		}
 		// 遍历缓存中的探针，移除不再关联的探针数据
 		for agentId := range latestMetrics.Agents.Keys() {
-			if !slices.Contains(monitorTask.AgentIds, agentId) {
+			if !selection.Contains(agentId) {
 				// 该探针已不再关联到此监控任务，从缓存中移除
 				latestMetrics.Agents.Delete(agentId)
 				s.logger.Debug("从监控缓存中移除探针",
@@ -317,6 +529,86 @@ func (s *MetricService) CleanMonitorCache(ctx context.Context, monitorID string)
 	return nil
 }
 
+// applyMonitorAssertions 根据监控任务配置校验 HTTP 状态码和内容断言，
+// 请求成功但断言失败时标记为 down，断言结果记录在 Message 中
+func (s *MetricService) applyMonitorAssertions(ctx context.Context, monitorDataList []protocol.MonitorData) {
+	monitorIds := make([]string, 0, len(monitorDataList))
+	for _, monitorData := range monitorDataList {
+		if monitorData.Type == "http" || monitorData.Type == "https" {
+			monitorIds = append(monitorIds, monitorData.MonitorId)
+		}
+	}
+	if len(monitorIds) == 0 {
+		return
+	}
+	configs := s.getMonitorHTTPConfigs(ctx, monitorIds)
+
+	for i := range monitorDataList {
+		monitorData := &monitorDataList[i]
+		httpCfg := configs[monitorData.MonitorId]
+		if httpCfg == nil || monitorData.Status != "up" {
+			continue
+		}
+
+		if !httpCfg.MatchStatusCode(monitorData.StatusCode) {
+			monitorData.Status = "down"
+			monitorData.Message = fmt.Sprintf("断言失败: 期望状态码 %s，实际 %d", httpCfg.ExpectedStatusText(), monitorData.StatusCode)
+			continue
+		}
+		if httpCfg.HasContentAssertion() && !monitorData.ContentMatch {
+			monitorData.Status = "down"
+			monitorData.Message = "断言失败: 响应内容不满足断言"
+			continue
+		}
+		if httpCfg.HasContentAssertion() || httpCfg.ExpectedStatusCodes != "" {
+			if monitorData.Message == "" {
+				monitorData.Message = "断言通过"
+			} else {
+				monitorData.Message += "，断言通过"
+			}
+		}
+	}
+}
+
+// monitorHTTPConfigCacheTTL 监控任务断言配置的缓存时间，新增、修改或删除任务时主动失效
+const monitorHTTPConfigCacheTTL = time.Minute
+
+// getMonitorHTTPConfigs 获取监控任务的 HTTP 断言配置，只查询缓存中没有的任务，避免每次上报都查询数据库
+func (s *MetricService) getMonitorHTTPConfigs(ctx context.Context, monitorIds []string) map[string]*protocol.HTTPMonitorConfig {
+	configs := make(map[string]*protocol.HTTPMonitorConfig, len(monitorIds))
+	var missing []string
+	for _, id := range monitorIds {
+		if httpCfg, ok := s.monitorHTTPCache.Get(id); ok {
+			configs[id] = httpCfg
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return configs
+	}
+
+	monitors, err := s.monitorRepo.FindByIdIn(ctx, missing)
+	if err != nil {
+		s.logger.Error("查询监控任务失败", zap.Error(err))
+		return configs
+	}
+	for _, monitor := range monitors {
+		httpCfg := monitor.HTTPConfig.Data()
+		configs[monitor.ID] = &httpCfg
+	}
+	// 已删除的任务也缓存为 nil，避免残留的上报反复查询
+	for _, id := range missing {
+		s.monitorHTTPCache.Set(id, configs[id], monitorHTTPConfigCacheTTL)
+	}
+	return configs
+}
+
+// InvalidateMonitorConfig 监控任务新增、修改或删除后使断言配置缓存失效，下一次上报时生效
+func (s *MetricService) InvalidateMonitorConfig(monitorID string) {
+	s.monitorHTTPCache.Delete(monitorID)
+}
+
 // updateMonitorCache 更新监控数据缓存
 func (s *MetricService) updateMonitorCache(agentID string, monitorData *protocol.MonitorData, timestamp int64) {
 	monitorID := monitorData.MonitorId
@@ -456,6 +748,14 @@ func (s *MetricService) buildPromQLQueries(agentID, metricType string, interface
 			Query: fmt.Sprintf(`pika_temperature_celsius{agent_id="%s"}`, agentID),
 		}}
 
+	case "load":
+		// 系统负载：1/5/15 分钟平均负载
+		queries = []metric.QueryDefinition{
+			{Name: "load1", Query: fmt.Sprintf(`pika_load_1{agent_id="%s"}`, agentID)},
+			{Name: "load5", Query: fmt.Sprintf(`pika_load_5{agent_id="%s"}`, agentID)},
+			{Name: "load15", Query: fmt.Sprintf(`pika_load_15{agent_id="%s"}`, agentID)},
+		}
+
 	case "monitor":
 		// 监控：响应时间（该探针参与的所有监控任务）
 		queries = []metric.QueryDefinition{{
@@ -464,10 +764,9 @@ func (s *MetricService) buildPromQLQueries(agentID, metricType string, interface
 		}}
 	}
 
-	if aggregation != "" {
-		for i := range queries {
-			queries[i].Query = wrapAggregationQuery(queries[i].Query, aggregation, step)
-		}
+	for i := range queries {
+		queries[i].Aggregation = s.resolveAggregation(metricType, queries[i].Name, aggregation)
+		queries[i].Query = wrapAggregationQuery(queries[i].Query, queries[i].Aggregation, step)
 	}
 
 	return queries
@@ -489,6 +788,8 @@ func wrapAggregationQuery(query, aggregation string, step time.Duration) string
 		return fmt.Sprintf(`avg_over_time((%s)[%s:])`, query, window)
 	case "max":
 		return fmt.Sprintf(`max_over_time((%s)[%s:])`, query, window)
+	case "last":
+		return fmt.Sprintf(`last_over_time((%s)[%s:])`, query, window)
 	default:
 		return query
 	}
@@ -524,7 +825,7 @@ func (s *MetricService) convertQueryResultToSeries(result *vmclient.QueryResult,
 			value, _ := strconv.ParseFloat(valueStr, 64)
 			dataPoints = append(dataPoints, metric.DataPoint{
 				Timestamp: int64(timestamp * 1000), // 转换为毫秒
-				Value:     value,
+				Value:     roundValue(value, s.precision),
 			})
 		}
 
@@ -586,7 +887,7 @@ func (s *MetricService) GetMonitorHistory(ctx context.Context, monitorID string,
 		return nil, err
 	}
 
-	step := vmclient.AutoStep(time.UnixMilli(start), time.UnixMilli(end))
+	step := s.DetermineInterval(start, end, 0)
 	queries := s.buildMonitorPromQLQueries(monitorID, aggregation, step)
 
 	var series []metric.Series
@@ -608,12 +909,16 @@ func (s *MetricService) GetMonitorHistory(ctx context.Context, monitorID string,
 
 	// 过滤掉已取消关联的 agent 数据（仅在有过滤条件时）
 	agentIdSet := make(map[string]struct{})
-	if len(monitorTask.AgentIds) > 0 {
+	if hasAgentSelector(&monitorTask) {
+		selection, err := resolveMonitorAgents(ctx, s.agentRepo, &monitorTask)
+		if err != nil {
+			return nil, err
+		}
 		// 有过滤条件，只保留当前关联的 agent 数据
 		filteredSeries := make([]metric.Series, 0)
 		for _, s := range series {
 			if agentId, ok := s.Labels["agent_id"]; ok {
-				if slices.Contains(monitorTask.AgentIds, agentId) {
+				if selection.Contains(agentId) {
 					filteredSeries = append(filteredSeries, s)
 					agentIdSet[agentId] = struct{}{}
 				}
@@ -641,7 +946,7 @@ func (s *MetricService) GetMonitorHistory(ctx context.Context, monitorID string,
 			s.logger.Error("查询 agent 信息失败", zap.Error(err))
 		} else {
 			// 构建 agentId -> agentName 映射
-			agentNameMap := make(map[string]string)
+			agentNameMap := map[string]string{models.ServerAgentID: models.ServerAgentName}
 			for _, agent := range agents {
 				agentNameMap[agent.ID] = agent.Name
 			}
@@ -682,18 +987,16 @@ func (s *MetricService) GetMonitorAgentStats(monitorID string) []protocol.Monito
 		return []protocol.MonitorData{}
 	}
 
+	selection, err := resolveMonitorAgents(ctx, s.agentRepo, &monitorTask)
+	if err != nil {
+		s.logger.Error("解析监控任务探针失败", zap.String("monitorID", monitorID), zap.Error(err))
+		return []protocol.MonitorData{}
+	}
+
 	// 收集所有当前关联的 agentId（从缓存中过滤）
 	agentIds := make([]string, 0)
-	if len(monitorTask.AgentIds) > 0 {
-		// 有过滤条件，只保留匹配的 agent
-		for agentId := range latestMetrics.Agents.Keys() {
-			if slices.Contains(monitorTask.AgentIds, agentId) {
-				agentIds = append(agentIds, agentId)
-			}
-		}
-	} else {
-		// 无过滤条件，返回所有缓存中的 agent
-		for agentId := range latestMetrics.Agents.Keys() {
+	for agentId := range latestMetrics.Agents.Keys() {
+		if selection.Contains(agentId) {
 			agentIds = append(agentIds, agentId)
 		}
 	}
@@ -705,7 +1008,7 @@ func (s *MetricService) GetMonitorAgentStats(monitorID string) []protocol.Monito
 	}
 
 	// 构建 agentId -> agentName 映射
-	agentNameMap := make(map[string]string)
+	agentNameMap := map[string]string{models.ServerAgentID: models.ServerAgentName}
 	for _, agent := range agents {
 		agentNameMap[agent.ID] = agent.Name
 	}
@@ -713,15 +1016,8 @@ func (s *MetricService) GetMonitorAgentStats(monitorID string) []protocol.Monito
 	// 转换为数组并填充 agent 名称
 	result := make([]protocol.MonitorData, 0, len(agentIds))
 	for stat := range latestMetrics.Agents.Values() {
-		// 根据过滤条件决定是否包含该 agent
-		if len(monitorTask.AgentIds) > 0 {
-			// 有过滤条件，只返回当前关联的 agent 数据
-			if slices.Contains(monitorTask.AgentIds, stat.AgentId) {
-				stat.AgentName = agentNameMap[stat.AgentId] // 填充 agent 名称
-				result = append(result, *stat)
-			}
-		} else {
-			// 无过滤条件，返回所有 agent 数据
+		// 只返回当前关联的 agent 数据（服务端执行的结果始终保留）
+		if selection.Contains(stat.AgentId) || stat.AgentId == models.ServerAgentID {
 			stat.AgentName = agentNameMap[stat.AgentId] // 填充 agent 名称
 			result = append(result, *stat)
 		}
@@ -755,12 +1051,20 @@ func (s *MetricService) GetMonitorStats(monitorID string) *metric.MonitorStatsRe
 		}
 	}
 
+	selection, err := resolveMonitorAgents(ctx, s.agentRepo, &monitorTask)
+	if err != nil {
+		s.logger.Error("解析监控任务探针失败", zap.String("monitorID", monitorID), zap.Error(err))
+		return &metric.MonitorStatsResult{
+			Status: "unknown",
+		}
+	}
+
 	// 聚合各探针数据
-	return s.aggregateMonitorStats(latestMetrics, monitorTask.AgentIds)
+	return s.aggregateMonitorStats(latestMetrics, selection, monitorTask.AgentWeights.Data(), monitorTask.DegradedThreshold)
 }
 
-// aggregateMonitorStats 聚合各探针的监控数据
-func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonitorMetrics, agentIds []string) *metric.MonitorStatsResult {
+// aggregateMonitorStats 聚合各探针的监控数据，平均响应时间和聚合状态按探针权重计算
+func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonitorMetrics, selection monitorAgentSelection, weights map[string]float64, degradedThreshold int) *metric.MonitorStatsResult {
 	result := &metric.MonitorStatsResult{
 		Status: "unknown",
 	}
@@ -769,7 +1073,8 @@ func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonito
 		return result
 	}
 
-	var totalResponseTime int64
+	var weightedResponseTime, totalWeight float64
+	var upWeight, downWeight float64
 	var minResponseTime int64 = 9223372036854775807 // math.MaxInt64
 	var maxResponseTime int64
 	var lastCheckTime int64
@@ -780,16 +1085,15 @@ func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonito
 	var minCertDaysLeft int
 
 	for stat := range latestMetrics.Agents.Values() {
-		// 根据过滤条件决定是否聚合该探针
-		if len(agentIds) > 0 {
-			// 有过滤条件，只聚合当前关联的探针数据
-			if !slices.Contains(agentIds, stat.AgentId) {
-				continue
-			}
+		// 只聚合当前关联的探针数据（服务端执行的结果始终聚合）
+		if !selection.Contains(stat.AgentId) && stat.AgentId != models.ServerAgentID {
+			continue
 		}
 
 		validCount++
-		totalResponseTime += stat.ResponseTime
+		weight := agentWeight(weights, stat.AgentId)
+		weightedResponseTime += float64(stat.ResponseTime) * weight
+		totalWeight += weight
 
 		// 计算响应时间的最小值和最大值
 		if stat.ResponseTime < minResponseTime {
@@ -807,8 +1111,10 @@ func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonito
 		switch stat.Status {
 		case "up":
 			upCount++
+			upWeight += weight
 		case "down":
 			downCount++
+			downWeight += weight
 		default:
 			unknownCount++
 		}
@@ -823,8 +1129,8 @@ func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonito
 	}
 
 	result.AgentCount = validCount
-	if validCount > 0 {
-		result.ResponseTime = totalResponseTime / int64(validCount)
+	if totalWeight > 0 {
+		result.ResponseTime = int64(weightedResponseTime / totalWeight)
 	}
 	result.ResponseTimeMin = minResponseTime
 	result.ResponseTimeMax = maxResponseTime
@@ -835,12 +1141,7 @@ func (s *MetricService) aggregateMonitorStats(latestMetrics *metric.LatestMonito
 	result.AgentStats.Down = downCount
 	result.AgentStats.Unknown = unknownCount
 
-	// 聚合状态：只要有一个探针 up，整体就是 up
-	if upCount > 0 {
-		result.Status = "up"
-	} else if downCount > 0 {
-		result.Status = "down"
-	}
+	result.Status = aggregateMonitorStatus(upWeight, downWeight, degradedThreshold)
 
 	if hasCert {
 		result.CertExpiryTime = minCertExpiryTime
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/dushixiang/pika/internal/metric"
+)
+
+const (
+	// smoothingSpan 平滑窗口覆盖的时间跨度
+	smoothingSpan = time.Minute
+	// minSmoothingWindow 最小平滑窗口（数据点数）
+	minSmoothingWindow = 3
+	// maxSmoothingWindow 最大平滑窗口（数据点数）
+	maxSmoothingWindow = 15
+)
+
+// smoothingWindow 根据查询步长计算平滑窗口大小（奇数，便于居中）
+func smoothingWindow(step time.Duration) int {
+	window := minSmoothingWindow
+	if step > 0 {
+		window = int(smoothingSpan / step)
+	}
+	if window < minSmoothingWindow {
+		window = minSmoothingWindow
+	}
+	if window > maxSmoothingWindow {
+		window = maxSmoothingWindow
+	}
+	if window%2 == 0 {
+		window++
+	}
+	return window
+}
+
+// smoothSeries 对每个系列做居中简单移动平均，消除探针上报相位不同导致的锯齿
+func smoothSeries(series []metric.Series, window int) {
+	half := window / 2
+	for i := range series {
+		points := series[i].Data
+		if len(points) < minSmoothingWindow {
+			continue
+		}
+
+		smoothed := make([]metric.DataPoint, len(points))
+		for j := range points {
+			from := max(0, j-half)
+			to := min(len(points)-1, j+half)
+
+			var sum float64
+			for k := from; k <= to; k++ {
+				sum += points[k].Value
+			}
+			smoothed[j] = metric.DataPoint{
+				Timestamp: points[j].Timestamp,
+				Value:     sum / float64(to-from+1),
+			}
+		}
+		series[i].Data = smoothed
+	}
+}
+
+// defaultValuePrecision 查询结果默认保留的小数位数
+const defaultValuePrecision = 2
+
+// roundValue 按精度四舍五入，precision 为负数时不处理
+func roundValue(value float64, precision int) float64 {
+	if precision < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+	factor := math.Pow(10, float64(precision))
+	return math.Round(value*factor) / factor
+}
+
+// roundSeries 对所有系列的数据点按精度取整
+func roundSeries(series []metric.Series, precision int) {
+	for i := range series {
+		for j := range series[i].Data {
+			series[i].Data[j].Value = roundValue(series[i].Data[j].Value, precision)
+		}
+	}
+}
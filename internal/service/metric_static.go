@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// staticDescriptorTTL 静态描述信息的缓存时间，探针至少每 10 分钟会完整上报一次，过期前会被刷新
+const staticDescriptorTTL = time.Hour
+
+// 探针只在静态描述信息（CPU 型号、主机系统信息、GPU 名称等）首次上报、发生变化或定期刷新时发送，
+// 其余时候只发送动态数值，服务端按 探针ID + 设备 保存最后一次收到的静态信息并合并到最新指标中
+
+func staticDescriptorKey(agentID, device string) string {
+	return agentID + "/" + device
+}
+
+// mergeCPUStatic 合并 CPU 静态信息：携带静态信息时更新缓存，否则使用缓存补齐
+func (s *MetricService) mergeCPUStatic(agentID string, data *protocol.CPUData) {
+	key := staticDescriptorKey(agentID, "cpu")
+	if data.ModelName != "" || data.LogicalCores > 0 || data.PhysicalCores > 0 {
+		s.staticCache.Set(key, protocol.CPUData{
+			LogicalCores:  data.LogicalCores,
+			PhysicalCores: data.PhysicalCores,
+			ModelName:     data.ModelName,
+		}, staticDescriptorTTL)
+		return
+	}
+	if value, ok := s.staticCache.Get(key); ok {
+		if static, ok := value.(protocol.CPUData); ok {
+			data.LogicalCores = static.LogicalCores
+			data.PhysicalCores = static.PhysicalCores
+			data.ModelName = static.ModelName
+		}
+	}
+}
+
+// mergeHostStatic 合并主机静态信息（主机名、系统、内核、虚拟化等）
+func (s *MetricService) mergeHostStatic(agentID string, data *protocol.HostInfoData) {
+	key := staticDescriptorKey(agentID, "host")
+	if data.OS != "" || data.Hostname != "" {
+		static := *data
+		static.Uptime, static.Procs = 0, 0
+		static.Load1, static.Load5, static.Load15 = 0, 0, 0
+		s.staticCache.Set(key, static, staticDescriptorTTL)
+		return
+	}
+	if value, ok := s.staticCache.Get(key); ok {
+		if static, ok := value.(protocol.HostInfoData); ok {
+			data.Hostname = static.Hostname
+			data.BootTime = static.BootTime
+			data.OS = static.OS
+			data.Platform = static.Platform
+			data.PlatformFamily = static.PlatformFamily
+			data.PlatformVersion = static.PlatformVersion
+			data.KernelVersion = static.KernelVersion
+			data.KernelArch = static.KernelArch
+			data.VirtualizationSystem = static.VirtualizationSystem
+			data.VirtualizationRole = static.VirtualizationRole
+		}
+	}
+}
+
+// mergeGPUStatic 按 GPU 序号合并静态信息（名称、UUID、显存总量）
+func (s *MetricService) mergeGPUStatic(agentID string, gpus []protocol.GPUData) {
+	for i := range gpus {
+		key := staticDescriptorKey(agentID, fmt.Sprintf("gpu/%d", gpus[i].Index))
+		if gpus[i].Name != "" {
+			s.staticCache.Set(key, protocol.GPUData{
+				Index:       gpus[i].Index,
+				Name:        gpus[i].Name,
+				UUID:        gpus[i].UUID,
+				MemoryTotal: gpus[i].MemoryTotal,
+			}, staticDescriptorTTL)
+			continue
+		}
+		if value, ok := s.staticCache.Get(key); ok {
+			if static, ok := value.(protocol.GPUData); ok {
+				gpus[i].Name = static.Name
+				gpus[i].UUID = static.UUID
+				if gpus[i].MemoryTotal == 0 {
+					gpus[i].MemoryTotal = static.MemoryTotal
+				}
+			}
+		}
+	}
+}
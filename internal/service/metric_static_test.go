@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/go-orz/cache"
+)
+
+func TestMergeCPUStatic(t *testing.T) {
+	s := &MetricService{staticCache: cache.New[string, any](time.Minute)}
+
+	full := protocol.CPUData{LogicalCores: 8, PhysicalCores: 4, ModelName: "Intel Xeon", UsagePercent: 10}
+	s.mergeCPUStatic("agent-1", &full)
+
+	partial := protocol.CPUData{UsagePercent: 20}
+	s.mergeCPUStatic("agent-1", &partial)
+	if partial.ModelName != "Intel Xeon" || partial.LogicalCores != 8 || partial.PhysicalCores != 4 {
+		t.Fatalf("static fields not merged: %+v", partial)
+	}
+	if partial.UsagePercent != 20 {
+		t.Fatalf("dynamic field overwritten: %+v", partial)
+	}
+
+	other := protocol.CPUData{UsagePercent: 30}
+	s.mergeCPUStatic("agent-2", &other)
+	if other.ModelName != "" {
+		t.Fatalf("static fields leaked across agents: %+v", other)
+	}
+}
+
+func TestMergeGPUStatic(t *testing.T) {
+	s := &MetricService{staticCache: cache.New[string, any](time.Minute)}
+
+	s.mergeGPUStatic("agent-1", []protocol.GPUData{
+		{Index: 0, Name: "RTX 4090", MemoryTotal: 24 << 30},
+		{Index: 1, Name: "RTX 3090", MemoryTotal: 24 << 30},
+	})
+
+	gpus := []protocol.GPUData{{Index: 1, Utilization: 50}, {Index: 0, Utilization: 10}}
+	s.mergeGPUStatic("agent-1", gpus)
+	if gpus[0].Name != "RTX 3090" || gpus[1].Name != "RTX 4090" {
+		t.Fatalf("gpu static fields not merged by index: %+v", gpus)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/repo"
+	"github.com/go-orz/orz"
+	"gorm.io/datatypes"
+)
+
+// monitorAgentSelection 监控任务选中的探针，all 为 true 时表示未限定探针，所有探针都参与检测
+type monitorAgentSelection struct {
+	all bool
+	ids map[string]struct{}
+}
+
+// Contains 探针是否被监控任务选中
+func (m monitorAgentSelection) Contains(agentID string) bool {
+	if m.all {
+		return true
+	}
+	_, ok := m.ids[agentID]
+	return ok
+}
+
+// hasAgentSelector 监控任务是否指定了探针或标签
+func hasAgentSelector(task *models.MonitorTask) bool {
+	return len(task.AgentIds) > 0 || len(task.Tags) > 0
+}
+
+// monitorSelectsAgent 探针是否匹配监控任务的选择条件：探针 ID 在指定列表中，或带有任一指定标签
+func monitorSelectsAgent(task *models.MonitorTask, agent *models.Agent) bool {
+	if !hasAgentSelector(task) {
+		return true
+	}
+	if slices.Contains(task.AgentIds, agent.ID) {
+		return true
+	}
+	for _, tag := range task.Tags {
+		if slices.Contains(agent.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMonitorAgents 解析监控任务选中的探针
+// 只指定了探针 ID 时直接使用，指定了标签时需要查询探针的标签
+func resolveMonitorAgents(ctx context.Context, agentRepo *repo.AgentRepo, task *models.MonitorTask) (monitorAgentSelection, error) {
+	if !hasAgentSelector(task) {
+		return monitorAgentSelection{all: true}, nil
+	}
+	ids := make(map[string]struct{}, len(task.AgentIds))
+	if len(task.Tags) == 0 {
+		for _, id := range task.AgentIds {
+			ids[id] = struct{}{}
+		}
+		return monitorAgentSelection{ids: ids}, nil
+	}
+
+	agents, err := agentRepo.FindAll(ctx)
+	if err != nil {
+		return monitorAgentSelection{}, err
+	}
+	for i := range agents {
+		if monitorSelectsAgent(task, &agents[i]) {
+			ids[agents[i].ID] = struct{}{}
+		}
+	}
+	return monitorAgentSelection{ids: ids}, nil
+}
+
+// validateMonitorAgentSelector 指定了探针或标签时，至少要匹配一个已存在的探针
+func validateMonitorAgentSelector(ctx context.Context, agentRepo *repo.AgentRepo, task *models.MonitorTask) error {
+	if task.RunOnServer || !hasAgentSelector(task) {
+		return nil
+	}
+	agents, err := agentRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range agents {
+		if monitorSelectsAgent(task, &agents[i]) {
+			return nil
+		}
+	}
+	return orz.NewError(400, "没有探针匹配指定的探针或标签")
+}
+
+// normalizeAgentTags 去除空白和重复的标签
+func normalizeAgentTags(tags []string) datatypes.JSONSlice[string] {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
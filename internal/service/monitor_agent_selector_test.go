@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestMonitorSelectsAgent(t *testing.T) {
+	web := &models.Agent{ID: "a1", Tags: []string{"web", "cn"}}
+	db := &models.Agent{ID: "a2", Tags: []string{"db"}}
+	bare := &models.Agent{ID: "a3"}
+
+	tests := []struct {
+		name string
+		task models.MonitorTask
+		want map[string]bool
+	}{
+		{"no selector", models.MonitorTask{}, map[string]bool{"a1": true, "a2": true, "a3": true}},
+		{"agent ids", models.MonitorTask{AgentIds: []string{"a3"}}, map[string]bool{"a1": false, "a2": false, "a3": true}},
+		{"tags", models.MonitorTask{Tags: []string{"web"}}, map[string]bool{"a1": true, "a2": false, "a3": false}},
+		{"ids and tags", models.MonitorTask{AgentIds: []string{"a3"}, Tags: []string{"db"}}, map[string]bool{"a1": false, "a2": true, "a3": true}},
+	}
+	for _, tt := range tests {
+		for _, agent := range []*models.Agent{web, db, bare} {
+			if got := monitorSelectsAgent(&tt.task, agent); got != tt.want[agent.ID] {
+				t.Errorf("%s: agent %s selected = %v, want %v", tt.name, agent.ID, got, tt.want[agent.ID])
+			}
+		}
+	}
+}
+
+func TestMonitorAgentSelectionContains(t *testing.T) {
+	all := monitorAgentSelection{all: true}
+	if !all.Contains("any") {
+		t.Error("unrestricted selection should contain every agent")
+	}
+	empty := monitorAgentSelection{ids: map[string]struct{}{}}
+	if empty.Contains("a1") {
+		t.Error("selector matching no agents should not fall back to all agents")
+	}
+}
+
+func TestNormalizeAgentTags(t *testing.T) {
+	got := normalizeAgentTags([]string{" web ", "", "web", "db"})
+	if len(got) != 2 || got[0] != "web" || got[1] != "db" {
+		t.Errorf("normalizeAgentTags = %v, want [web db]", got)
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+func TestPrepareHTTPConfig(t *testing.T) {
+	req := &MonitorTaskRequest{
+		Type: "https",
+		HTTPConfig: protocol.HTTPMonitorConfig{
+			Method:  " post ",
+			Headers: map[string]string{"Authorization": "Bearer token", " ": "ignored"},
+			Body:    `{"ping":true}`,
+		},
+	}
+	if err := prepareHTTPConfig(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := req.HTTPConfig
+	if cfg.Method != "POST" || cfg.Timeout != protocol.DefaultHTTPMonitorTimeout {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+	if cfg.FollowRedirects == nil || !*cfg.FollowRedirects || cfg.VerifyTLS {
+		t.Fatalf("unexpected redirect/tls defaults: %+v", cfg)
+	}
+	if len(cfg.Headers) != 1 || cfg.Headers["Authorization"] != "Bearer token" {
+		t.Fatalf("unexpected headers: %v", cfg.Headers)
+	}
+
+	follow := false
+	req = &MonitorTaskRequest{Type: "http", HTTPConfig: protocol.HTTPMonitorConfig{FollowRedirects: &follow}}
+	if err := prepareHTTPConfig(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.HTTPConfig.Method != "GET" || req.HTTPConfig.ShouldFollowRedirects() {
+		t.Fatalf("explicit followRedirects=false should be kept: %+v", req.HTTPConfig)
+	}
+
+	invalid := []protocol.HTTPMonitorConfig{
+		{Method: "CONNECT"},
+		{Headers: map[string]string{"X Bad": "v"}},
+		{Headers: map[string]string{"X-Inject": "a\r\nb: c"}},
+	}
+	for _, cfg := range invalid {
+		if err := prepareHTTPConfig(&MonitorTaskRequest{Type: "http", HTTPConfig: cfg}); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+
+	// 非 HTTP 监控不填充 HTTP 默认值
+	tcp := &MonitorTaskRequest{Type: "tcp"}
+	if err := prepareHTTPConfig(tcp); err != nil || tcp.HTTPConfig.Method != "" {
+		t.Fatalf("tcp monitor should keep empty http config: %+v, %v", tcp.HTTPConfig, err)
+	}
+}
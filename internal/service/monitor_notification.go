@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+// normalizeNotificationChannels 规范化监控项的通知渠道 ID：去除空白和重复
+func normalizeNotificationChannels(channels []string) datatypes.JSONSlice[string] {
+	normalized := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channel = strings.TrimSpace(channel)
+		if channel == "" || slices.Contains(normalized, channel) {
+			continue
+		}
+		normalized = append(normalized, channel)
+	}
+	return normalized
+}
+
+// filterChannelsByIDs 只保留指定 ID 的通知渠道，ids 为空时不过滤
+func filterChannelsByIDs(channels []models.NotificationChannelConfig, ids []string) []models.NotificationChannelConfig {
+	if len(ids) == 0 {
+		return channels
+	}
+	var matched []models.NotificationChannelConfig
+	for _, channel := range channels {
+		if slices.Contains(ids, channel.ChannelID()) {
+			matched = append(matched, channel)
+		}
+	}
+	return matched
+}
+
+// routeMonitorChannels 监控项告警按监控项配置的通知渠道发送，未配置时使用全部渠道
+// 配置的渠道均已被删除或停用时记录警告并发送到全部渠道，避免告警静默丢失
+func (s *AlertService) routeMonitorChannels(ctx context.Context, record *models.AlertRecord, channels []models.NotificationChannelConfig) []models.NotificationChannelConfig {
+	if record.Monitor == nil || record.Monitor.ID == "" {
+		return channels
+	}
+	monitor, err := s.monitorService.MonitorRepo.FindById(ctx, record.Monitor.ID)
+	if err != nil {
+		s.logger.Warn("获取监控项通知渠道失败，发送到全部渠道", zap.String("monitorId", record.Monitor.ID), zap.Error(err))
+		return channels
+	}
+	matched := filterChannelsByIDs(channels, monitor.NotificationChannels)
+	if len(matched) == 0 && len(channels) > 0 {
+		s.logger.Warn("监控项配置的通知渠道均不可用，发送到全部渠道",
+			zap.String("monitorId", monitor.ID),
+			zap.Strings("channels", monitor.NotificationChannels))
+		return channels
+	}
+	return matched
+}
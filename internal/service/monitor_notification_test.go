@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestFilterChannelsByIDs(t *testing.T) {
+	channels := []models.NotificationChannelConfig{
+		{ID: "ops-webhook", Type: "webhook"},
+		{ID: "dev-webhook", Type: "webhook"},
+		{Type: "feishu"},
+	}
+	if got := filterChannelsByIDs(channels, nil); len(got) != 3 {
+		t.Fatalf("empty ids should keep all channels, got %d", len(got))
+	}
+	got := filterChannelsByIDs(channels, []string{"dev-webhook", "feishu"})
+	if len(got) != 2 || got[0].ID != "dev-webhook" || got[1].Type != "feishu" {
+		t.Fatalf("unexpected channels: %+v", got)
+	}
+	// 按 ID 路由，不会因类型相同而发送到同类型的其他渠道
+	if got := filterChannelsByIDs(channels, []string{"webhook"}); len(got) != 0 {
+		t.Fatalf("type should not match channels with explicit id: %+v", got)
+	}
+	if err := ValidateNotificationChannels([]models.NotificationChannelConfig{{Type: "feishu"}, {ID: "feishu", Type: "webhook"}}); err == nil {
+		t.Fatal("duplicate channel id should be rejected")
+	}
+	if err := ValidateNotificationChannels([]models.NotificationChannelConfig{{Type: "feishu", MinLevel: "error"}}); err == nil {
+		t.Fatal("unknown min level should be rejected")
+	}
+	if err := ValidateNotificationChannels([]models.NotificationChannelConfig{{Type: "feishu", MinLevel: models.AlertLevelWarning}}); err != nil {
+		t.Fatalf("valid min level rejected: %v", err)
+	}
+}
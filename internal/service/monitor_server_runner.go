@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// buildMonitorItem 根据监控任务构建检测项
+func buildMonitorItem(monitor models.MonitorTask) protocol.MonitorItem {
+	item := protocol.MonitorItem{
+		ID:     monitor.ID,
+		Type:   monitor.Type,
+		Target: monitor.Target,
+	}
+
+	if monitor.Type == "http" || monitor.Type == "https" {
+		httpConfig := monitor.HTTPConfig.Data()
+		item.HTTPConfig = &httpConfig
+	} else if monitor.Type == "tcp" {
+		var tcpConfig = monitor.TCPConfig.Data()
+		item.TCPConfig = &tcpConfig
+	} else if monitor.Type == "icmp" || monitor.Type == "ping" {
+		var icmpConfig = monitor.ICMPConfig.Data()
+		item.ICMPConfig = &icmpConfig
+	}
+	return item
+}
+
+// runServerCheck 在服务端执行监控检测，结果以保留探针ID走与探针上报相同的存储流程
+func (s *MonitorService) runServerCheck(ctx context.Context, monitor models.MonitorTask) {
+	results := s.serverChecker.Collect([]protocol.MonitorItem{buildMonitorItem(monitor)})
+	if len(results) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		s.logger.Error("序列化服务端监控结果失败", zap.String("taskID", monitor.ID), zap.Error(err))
+		return
+	}
+
+	if err := s.metricService.HandleMetricData(ctx, models.ServerAgentID, string(protocol.MetricTypeMonitor), data, time.Now().UnixMilli()); err != nil {
+		s.logger.Error("保存服务端监控结果失败",
+			zap.String("taskID", monitor.ID),
+			zap.String("taskName", monitor.Name),
+			zap.Error(err))
+	}
+}
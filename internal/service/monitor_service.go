@@ -12,6 +12,7 @@ import (
 	"github.com/dushixiang/pika/internal/protocol"
 	"github.com/dushixiang/pika/internal/repo"
 	ws "github.com/dushixiang/pika/internal/websocket"
+	"github.com/dushixiang/pika/pkg/monitorcheck"
 	"github.com/go-orz/orz"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -26,6 +27,7 @@ type MonitorService struct {
 	agentRepo     *repo.AgentRepo
 	metricService *MetricService
 	wsManager     *ws.Manager
+	serverChecker *monitorcheck.Collector // 服务端执行监控检测
 
 	// 调度器引用（用于动态管理任务）
 	scheduler MonitorScheduler
@@ -46,6 +48,7 @@ func NewMonitorService(logger *zap.Logger, db *gorm.DB, metricService *MetricSer
 		agentRepo:     repo.NewAgentRepo(db),
 		metricService: metricService,
 		wsManager:     wsManager,
+		serverChecker: monitorcheck.NewCollector(),
 	}
 }
 
@@ -55,21 +58,47 @@ func (s *MonitorService) SetScheduler(scheduler MonitorScheduler) {
 }
 
 type MonitorTaskRequest struct {
-	Name             string                     `json:"name"`
-	Type             string                     `json:"type"`
-	Target           string                     `json:"target"`
-	Description      string                     `json:"description"`
-	Enabled          bool                       `json:"enabled,omitempty"`
-	ShowTargetPublic bool                       `json:"showTargetPublic,omitempty"` // 在公开页面是否显示目标地址
-	Visibility       string                     `json:"visibility,omitempty"`       // 可见性: public-匿名可见, private-登录可见
-	Interval         int                        `json:"interval"`                   // 检测频率（秒）
-	HTTPConfig       protocol.HTTPMonitorConfig `json:"httpConfig,omitempty"`
-	TCPConfig        protocol.TCPMonitorConfig  `json:"tcpConfig,omitempty"`
-	ICMPConfig       protocol.ICMPMonitorConfig `json:"icmpConfig,omitempty"`
-	AgentIds         []string                   `json:"agentIds,omitempty"`
+	Name              string                     `json:"name"`
+	Type              string                     `json:"type"`
+	Target            string                     `json:"target"`
+	Description       string                     `json:"description"`
+	Enabled           bool                       `json:"enabled,omitempty"`
+	ShowTargetPublic  bool                       `json:"showTargetPublic,omitempty"`  // 在公开页面是否显示目标地址
+	Visibility        string                     `json:"visibility,omitempty"`        // 可见性: public-匿名可见, private-登录可见
+	Group             string                     `json:"group,omitempty"`             // 分组
+	Interval          int                        `json:"interval"`                    // 检测频率（秒）
+	DegradedThreshold int                        `json:"degradedThreshold,omitempty"` // 判定为 degraded 的异常探针比例上限（%）
+	RunOnServer       bool                       `json:"runOnServer,omitempty"`       // 是否由服务端执行检测
+	HTTPConfig        protocol.HTTPMonitorConfig `json:"httpConfig,omitempty"`
+	TCPConfig         protocol.TCPMonitorConfig  `json:"tcpConfig,omitempty"`
+	ICMPConfig        protocol.ICMPMonitorConfig `json:"icmpConfig,omitempty"`
+	AgentIds          []string                   `json:"agentIds,omitempty"`
+	Tags              []string                   `json:"tags,omitempty"`         // 探针标签，带有任一标签的探针参与检测
+	AgentWeights      map[string]float64         `json:"agentWeights,omitempty"` // 探针权重，未配置的探针权重为 1
+	// 接收状态变化通知的渠道类型，为空时发送到所有已启用的渠道
+	NotificationChannels []string `json:"notificationChannels,omitempty"`
+}
+
+// prepareHTTPConfig 校验 HTTP 监控配置，并将默认值写入配置，保存的即为实际发出的请求
+func prepareHTTPConfig(req *MonitorTaskRequest) error {
+	if err := req.HTTPConfig.ValidateAssertions(); err != nil {
+		return orz.NewError(400, err.Error())
+	}
+	if req.Type != "http" && req.Type != "https" {
+		return nil
+	}
+	if err := req.HTTPConfig.ValidateRequest(); err != nil {
+		return orz.NewError(400, err.Error())
+	}
+	req.HTTPConfig.Normalize()
+	return nil
 }
 
 func (s *MonitorService) CreateMonitor(ctx context.Context, req *MonitorTaskRequest) (*models.MonitorTask, error) {
+	if err := prepareHTTPConfig(req); err != nil {
+		return nil, err
+	}
+
 	// 设置默认检测频率
 	interval := req.Interval
 	if interval <= 0 {
@@ -83,26 +112,38 @@ func (s *MonitorService) CreateMonitor(ctx context.Context, req *MonitorTaskRequ
 	}
 
 	task := &models.MonitorTask{
-		ID:               uuid.NewString(),
-		Name:             strings.TrimSpace(req.Name),
-		Type:             req.Type,
-		Target:           strings.TrimSpace(req.Target),
-		Description:      req.Description,
-		Enabled:          req.Enabled,
-		ShowTargetPublic: req.ShowTargetPublic,
-		Visibility:       visibility,
-		Interval:         interval,
-		AgentIds:         datatypes.JSONSlice[string](req.AgentIds),
-		HTTPConfig:       datatypes.NewJSONType(req.HTTPConfig),
-		TCPConfig:        datatypes.NewJSONType(req.TCPConfig),
-		ICMPConfig:       datatypes.NewJSONType(req.ICMPConfig),
-		CreatedAt:        0,
-		UpdatedAt:        0,
+		ID:                   uuid.NewString(),
+		Name:                 strings.TrimSpace(req.Name),
+		Type:                 req.Type,
+		Target:               strings.TrimSpace(req.Target),
+		Description:          req.Description,
+		Enabled:              req.Enabled,
+		ShowTargetPublic:     req.ShowTargetPublic,
+		Visibility:           visibility,
+		Group:                strings.TrimSpace(req.Group),
+		Interval:             interval,
+		DegradedThreshold:    normalizeDegradedThreshold(req.DegradedThreshold),
+		RunOnServer:          req.RunOnServer,
+		AgentIds:             datatypes.JSONSlice[string](req.AgentIds),
+		Tags:                 normalizeAgentTags(req.Tags),
+		AgentWeights:         datatypes.NewJSONType(normalizeAgentWeights(req.AgentWeights)),
+		NotificationChannels: normalizeNotificationChannels(req.NotificationChannels),
+		HTTPConfig:           datatypes.NewJSONType(req.HTTPConfig),
+		TCPConfig:            datatypes.NewJSONType(req.TCPConfig),
+		ICMPConfig:           datatypes.NewJSONType(req.ICMPConfig),
+		CreatedAt:            0,
+		UpdatedAt:            0,
+	}
+	if err := validateMonitorAgentSelector(ctx, s.agentRepo, task); err != nil {
+		return nil, err
 	}
 
 	if err := s.MonitorRepo.Create(ctx, task); err != nil {
 		return nil, err
 	}
+	if s.metricService != nil {
+		s.metricService.InvalidateMonitorConfig(task.ID)
+	}
 
 	// 如果任务启用，添加到调度器
 	if task.Enabled && s.scheduler != nil {
@@ -117,6 +158,10 @@ func (s *MonitorService) CreateMonitor(ctx context.Context, req *MonitorTaskRequ
 }
 
 func (s *MonitorService) UpdateMonitor(ctx context.Context, id string, req *MonitorTaskRequest) (*models.MonitorTask, error) {
+	if err := prepareHTTPConfig(req); err != nil {
+		return nil, err
+	}
+
 	task, err := s.MonitorRepo.FindById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -133,6 +178,7 @@ func (s *MonitorService) UpdateMonitor(ctx context.Context, id string, req *Moni
 	task.Description = req.Description
 	task.ShowTargetPublic = req.ShowTargetPublic
 	task.Visibility = req.Visibility
+	task.Group = strings.TrimSpace(req.Group)
 
 	// 更新检测频率
 	interval := req.Interval
@@ -140,11 +186,19 @@ func (s *MonitorService) UpdateMonitor(ctx context.Context, id string, req *Moni
 		interval = 60 // 默认 60 秒
 	}
 	task.Interval = interval
+	task.DegradedThreshold = normalizeDegradedThreshold(req.DegradedThreshold)
+	task.RunOnServer = req.RunOnServer
 
 	task.AgentIds = req.AgentIds
+	task.Tags = normalizeAgentTags(req.Tags)
+	task.AgentWeights = datatypes.NewJSONType(normalizeAgentWeights(req.AgentWeights))
+	task.NotificationChannels = normalizeNotificationChannels(req.NotificationChannels)
 	task.HTTPConfig = datatypes.NewJSONType(req.HTTPConfig)
 	task.TCPConfig = datatypes.NewJSONType(req.TCPConfig)
 	task.ICMPConfig = datatypes.NewJSONType(req.ICMPConfig)
+	if err := validateMonitorAgentSelector(ctx, s.agentRepo, &task); err != nil {
+		return nil, err
+	}
 
 	if err := s.MonitorRepo.Save(ctx, &task); err != nil {
 		return nil, err
@@ -152,6 +206,7 @@ func (s *MonitorService) UpdateMonitor(ctx context.Context, id string, req *Moni
 
 	// 清理监控缓存中不再关联的探针数据
 	if s.metricService != nil {
+		s.metricService.InvalidateMonitorConfig(id)
 		if err := s.metricService.CleanMonitorCache(ctx, id); err != nil {
 			s.logger.Warn("清理监控缓存失败",
 				zap.String("monitorID", id),
@@ -198,6 +253,9 @@ func (s *MonitorService) DeleteMonitor(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+	if s.metricService != nil {
+		s.metricService.InvalidateMonitorConfig(id)
+	}
 
 	// 从调度器中移除
 	if s.scheduler != nil {
@@ -207,8 +265,8 @@ func (s *MonitorService) DeleteMonitor(ctx context.Context, id string) error {
 	return nil
 }
 
-// ListByAuth 返回公开展示所需的监控配置和汇总统计
-func (s *MonitorService) ListByAuth(ctx context.Context, isAuthenticated bool) ([]metric.PublicMonitorOverview, error) {
+// ListByAuth 返回公开展示所需的监控配置和汇总统计，group 不为空时只返回该分组
+func (s *MonitorService) ListByAuth(ctx context.Context, isAuthenticated bool, group string) ([]metric.PublicMonitorOverview, error) {
 	// 获取符合权限的监控任务列表
 	monitors, err := s.FindByAuth(ctx, isAuthenticated)
 	if err != nil {
@@ -218,6 +276,9 @@ func (s *MonitorService) ListByAuth(ctx context.Context, isAuthenticated bool) (
 	// 构建监控概览列表
 	items := make([]metric.PublicMonitorOverview, 0, len(monitors))
 	for _, monitor := range monitors {
+		if group != "" && monitor.Group != group {
+			continue
+		}
 		// 查询统计数据
 		stats := s.metricService.GetMonitorStats(monitor.ID)
 		// 构建监控概览对象
@@ -243,6 +304,7 @@ func (s *MonitorService) buildMonitorOverview(monitor models.MonitorTask, stats
 		Target:           target,
 		ShowTargetPublic: monitor.ShowTargetPublic,
 		Description:      monitor.Description,
+		Group:            monitor.Group,
 		Enabled:          monitor.Enabled,
 		Interval:         monitor.Interval,
 		AgentCount:       stats.AgentCount,
@@ -276,14 +338,21 @@ func (s *MonitorService) sendMonitorConfigToAgent(agentID string, payload protoc
 
 // SendMonitorTaskToAgents 向指定探针发送单个监控任务（公开方法）
 func (s *MonitorService) SendMonitorTaskToAgents(ctx context.Context, monitor models.MonitorTask) error {
-	// 确定目标探针 ID 列表
+	if monitor.RunOnServer {
+		go s.runServerCheck(ctx, monitor)
+		return nil
+	}
+
+	// 确定目标探针 ID 列表，只向在线且匹配选择条件的探针发送
+	selection, err := resolveMonitorAgents(ctx, s.agentRepo, &monitor)
+	if err != nil {
+		return err
+	}
 	var targetAgentIDs []string
-	if len(monitor.AgentIds) == 0 {
-		// 没有指定探针，向所有在线探针发送
-		targetAgentIDs = s.wsManager.GetAllClients()
-	} else {
-		// 指定了探针
-		targetAgentIDs = monitor.AgentIds
+	for _, agentID := range s.wsManager.GetAllClients() {
+		if selection.Contains(agentID) {
+			targetAgentIDs = append(targetAgentIDs, agentID)
+		}
 	}
 
 	if len(targetAgentIDs) == 0 {
@@ -291,22 +360,7 @@ func (s *MonitorService) SendMonitorTaskToAgents(ctx context.Context, monitor mo
 	}
 
 	// 构建监控项
-	item := protocol.MonitorItem{
-		ID:     monitor.ID,
-		Type:   monitor.Type,
-		Target: monitor.Target,
-	}
-
-	if monitor.Type == "http" || monitor.Type == "https" {
-		httpConfig := monitor.HTTPConfig.Data()
-		item.HTTPConfig = &httpConfig
-	} else if monitor.Type == "tcp" {
-		var tcpConfig = monitor.TCPConfig.Data()
-		item.TCPConfig = &tcpConfig
-	} else if monitor.Type == "icmp" || monitor.Type == "ping" {
-		var icmpConfig = monitor.ICMPConfig.Data()
-		item.ICMPConfig = &icmpConfig
-	}
+	item := buildMonitorItem(monitor)
 
 	// 构建 payload
 	payload := protocol.MonitorConfigPayload{
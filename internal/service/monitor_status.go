@@ -0,0 +1,55 @@
+package service
+
+// defaultDegradedThreshold 默认的 degraded 判定比例（%）：异常探针占比低于该值为 degraded，否则为 down
+const defaultDegradedThreshold = 50
+
+// normalizeDegradedThreshold 规范化 degraded 判定比例，超出 1-100 时使用默认值
+func normalizeDegradedThreshold(threshold int) int {
+	if threshold <= 0 || threshold > 100 {
+		return defaultDegradedThreshold
+	}
+	return threshold
+}
+
+// defaultAgentWeight 未配置权重的探针默认权重
+const defaultAgentWeight = 1.0
+
+// normalizeAgentWeights 规范化探针权重，丢弃非正数和默认值的配置
+func normalizeAgentWeights(weights map[string]float64) map[string]float64 {
+	normalized := make(map[string]float64, len(weights))
+	for agentID, weight := range weights {
+		if agentID == "" || weight <= 0 || weight == defaultAgentWeight {
+			continue
+		}
+		normalized[agentID] = weight
+	}
+	return normalized
+}
+
+// agentWeight 获取探针权重，未配置时为 1
+func agentWeight(weights map[string]float64, agentID string) float64 {
+	if weight, ok := weights[agentID]; ok && weight > 0 {
+		return weight
+	}
+	return defaultAgentWeight
+}
+
+// aggregateMonitorStatus 根据探针状态的权重分布计算监控聚合状态
+// 全部正常为 up，全部异常为 down；部分异常时异常权重占比低于阈值为 degraded，否则为 down
+// 所有探针权重均为 1 时，异常权重占比即异常探针数量占比
+func aggregateMonitorStatus(upWeight, downWeight float64, degradedThreshold int) string {
+	switch {
+	case upWeight == 0 && downWeight == 0:
+		return "unknown"
+	case downWeight == 0:
+		return "up"
+	case upWeight == 0:
+		return "down"
+	}
+
+	downPercent := downWeight * 100 / (upWeight + downWeight)
+	if downPercent < float64(normalizeDegradedThreshold(degradedThreshold)) {
+		return "degraded"
+	}
+	return "down"
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// promSample Prometheus 文本格式中的一个采样
+type promSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// parsePromText 解析 Prometheus 文本格式（node_exporter 抓取结果或 textfile 文件）
+// 只保留 accept 返回 true 的指标，忽略注释、时间戳和非有限值
+func parsePromText(r io.Reader, accept func(name string) bool) ([]promSample, error) {
+	var samples []promSample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		name, rest := splitPromName(line)
+		if name == "" {
+			return nil, fmt.Errorf("第 %d 行: 缺少指标名", lineNo)
+		}
+		if !accept(name) {
+			continue
+		}
+
+		labels := map[string]string{}
+		if strings.HasPrefix(rest, "{") {
+			var err error
+			labels, rest, err = parsePromLabels(rest[1:])
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", lineNo, err)
+			}
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("第 %d 行: 缺少指标值", lineNo)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: 无效的指标值 %q", lineNo, fields[0])
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		samples = append(samples, promSample{Name: name, Labels: labels, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// splitPromName 拆分出行首的指标名
+func splitPromName(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '{' || c == ' ' || c == '\t' {
+			return line[:i], strings.TrimLeft(line[i:], " \t")
+		}
+	}
+	return line, ""
+}
+
+// parsePromLabels 解析 {} 内的标签，返回标签和右花括号之后的内容
+func parsePromLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return nil, "", fmt.Errorf("标签未闭合")
+		}
+		if s[0] == '}' {
+			return labels, strings.TrimLeft(s[1:], " \t"), nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, "", fmt.Errorf("无效的标签")
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		if s == "" || s[0] != '"' {
+			return nil, "", fmt.Errorf("标签 %s 的值缺少引号", key)
+		}
+
+		var value strings.Builder
+		i := 1
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '"' {
+				break
+			}
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(c)
+		}
+		if i >= len(s) {
+			return nil, "", fmt.Errorf("标签 %s 的值未闭合", key)
+		}
+		labels[key] = value.String()
+		s = s[i+1:]
+	}
+}
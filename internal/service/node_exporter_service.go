@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/config"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/repo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// NodeExporterAgentVersion 通过 node_exporter 导入指标的探针的版本标识
+	NodeExporterAgentVersion = "node_exporter"
+	// defaultImportIdentityHeader 未配置时携带探针ID的请求头
+	defaultImportIdentityHeader = "X-Pika-Agent-ID"
+	// nodeExporterStaleTimeout 超过该时间未导入数据的探针标记为离线
+	nodeExporterStaleTimeout = 3 * time.Minute
+)
+
+var (
+	ErrInvalidImportAgentID = errors.New("探针ID只能包含字母、数字、点、下划线和连字符，长度不超过 64")
+	ErrNoImportableMetrics  = errors.New("未找到可导入的 node_exporter 指标")
+	ErrInvalidPromText      = errors.New("解析 node_exporter 数据失败")
+	ErrImportAgentIDInUse   = errors.New("探针ID已被通过 WebSocket 连接的探针使用，不能用于导入")
+
+	importAgentIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+)
+
+// nodeExporterIgnoredFstypes 不计入磁盘使用的伪文件系统
+var nodeExporterIgnoredFstypes = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "overlay": true, "squashfs": true, "proc": true,
+	"sysfs": true, "cgroup": true, "cgroup2": true, "devpts": true, "autofs": true,
+	"nsfs": true, "fuse.lxcfs": true, "ramfs": true, "rpc_pipefs": true, "tracefs": true,
+}
+
+// cpuTimes 单个 CPU 累计的总时间和空闲时间（秒）
+type cpuTimes struct {
+	total float64
+	idle  float64
+}
+
+// cpuBaseline 探针上一次导入时各 CPU 的累计时间
+type cpuBaseline struct {
+	at   int64               // 导入时间（毫秒）
+	cpus map[string]cpuTimes // CPU 编号 -> 累计时间
+}
+
+// NodeExporterImportResult 单次导入的结果
+type NodeExporterImportResult struct {
+	AgentID  string   `json:"agentId"`
+	Created  bool     `json:"created"`  // 是否新建了探针
+	Samples  int      `json:"samples"`  // 识别到的采样数
+	Imported []string `json:"imported"` // 写入的指标类型
+}
+
+// NodeExporterService 将 node_exporter 抓取结果或 textfile 文件映射为探针指标
+type NodeExporterService struct {
+	logger         *zap.Logger
+	agentRepo      *repo.AgentRepo
+	metricService  *MetricService
+	identityHeader string
+
+	mu      sync.Mutex
+	cpuPrev map[string]cpuBaseline // 探针ID -> 上一次导入时的 CPU 累计时间，用于计算使用率
+}
+
+func NewNodeExporterService(logger *zap.Logger, db *gorm.DB, metricService *MetricService, appConfig *config.AppConfig) *NodeExporterService {
+	identityHeader := defaultImportIdentityHeader
+	if appConfig.Agent != nil && appConfig.Agent.ImportIdentityHeader != "" {
+		identityHeader = appConfig.Agent.ImportIdentityHeader
+	}
+	return &NodeExporterService{
+		logger:         logger,
+		agentRepo:      repo.NewAgentRepo(db),
+		metricService:  metricService,
+		identityHeader: identityHeader,
+		cpuPrev:        make(map[string]cpuBaseline),
+	}
+}
+
+// IdentityHeader 返回携带探针ID的请求头名称
+func (s *NodeExporterService) IdentityHeader() string {
+	return s.identityHeader
+}
+
+// Import 解析 Prometheus 文本格式的数据并写入探针指标，探针不存在时自动创建
+// 探针ID已属于通过 WebSocket 连接的探针时返回 ErrImportAgentIDInUse，避免导入的数据覆盖真实探针
+// CPU 使用率由两次导入之间的累计时间差计算，首次导入时不写入 CPU 指标
+func (s *NodeExporterService) Import(ctx context.Context, agentID, ip string, key *models.ApiKey, body io.Reader) (*NodeExporterImportResult, error) {
+	if !importAgentIDPattern.MatchString(agentID) {
+		return nil, ErrInvalidImportAgentID
+	}
+
+	samples, err := parsePromText(body, func(name string) bool {
+		return strings.HasPrefix(name, "node_cpu_seconds_total") ||
+			strings.HasPrefix(name, "node_memory_") ||
+			strings.HasPrefix(name, "node_filesystem_") ||
+			strings.HasPrefix(name, "node_load")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPromText, err)
+	}
+	if len(samples) == 0 {
+		return nil, ErrNoImportableMetrics
+	}
+
+	created, err := s.touchAgent(ctx, agentID, ip, key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NodeExporterImportResult{AgentID: agentID, Created: created, Samples: len(samples), Imported: []string{}}
+	now := time.Now().UnixMilli()
+
+	write := func(metricType protocol.MetricType, data any) {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		if err := s.metricService.HandleMetricData(ctx, agentID, string(metricType), raw, now); err != nil {
+			s.logger.Warn("写入导入的指标失败", zap.String("agentId", agentID), zap.String("type", string(metricType)), zap.Error(err))
+			return
+		}
+		result.Imported = append(result.Imported, string(metricType))
+	}
+
+	if cpu := s.cpuUsage(agentID, samples); cpu != nil {
+		write(protocol.MetricTypeCPU, cpu)
+	}
+	if mem := nodeExporterMemory(samples); mem != nil {
+		write(protocol.MetricTypeMemory, mem)
+	}
+	if disks := nodeExporterDisks(samples); len(disks) > 0 {
+		write(protocol.MetricTypeDisk, disks)
+	}
+	if load := nodeExporterLoad(samples); load != nil {
+		write(protocol.MetricTypeLoad, load)
+	}
+	return result, nil
+}
+
+// touchAgent 更新探针在线状态，探针不存在时创建，返回是否新建
+func (s *NodeExporterService) touchAgent(ctx context.Context, agentID, ip string, key *models.ApiKey) (bool, error) {
+	now := time.Now().UnixMilli()
+	if agent, err := s.agentRepo.FindById(ctx, agentID); err == nil {
+		if agent.Version != NodeExporterAgentVersion {
+			return false, ErrImportAgentIDInUse
+		}
+		return false, s.agentRepo.UpdateStatus(ctx, agentID, 1, now)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	agent := &models.Agent{
+		ID:         agentID,
+		Name:       agentID,
+		Hostname:   agentID,
+		IP:         ip,
+		OS:         "linux",
+		Version:    NodeExporterAgentVersion,
+		Tags:       key.Tags,
+		Status:     1,
+		LastSeenAt: now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.agentRepo.Create(ctx, agent); err != nil {
+		return false, err
+	}
+	s.logger.Info("通过 node_exporter 导入创建探针",
+		zap.String("agentId", agentID),
+		zap.String("ip", ip),
+		zap.String("apiKey", key.Name))
+	return true, nil
+}
+
+// MarkStaleAgentsOffline 将长时间未导入数据的探针标记为离线，同时清理这些探针的 CPU 累计时间
+// 导入的探针没有长连接，无法通过断开连接判断离线
+func (s *NodeExporterService) MarkStaleAgentsOffline(ctx context.Context) {
+	before := time.Now().Add(-nodeExporterStaleTimeout).UnixMilli()
+	s.pruneCPUBaselines(before)
+
+	count, err := s.agentRepo.MarkStaleOffline(ctx, NodeExporterAgentVersion, before)
+	if err != nil {
+		s.logger.Error("标记导入探针离线失败", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		s.logger.Info("导入探针超时未上报，已标记为离线", zap.Int64("count", count))
+	}
+}
+
+// pruneCPUBaselines 删除 before 之前导入的 CPU 累计时间，已删除或停止导入的探针不再占用内存
+// 间隔过长的两次导入只能得到长时间的平均使用率，清理后重新从下一次导入开始计算
+func (s *NodeExporterService) pruneCPUBaselines(before int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for agentID, baseline := range s.cpuPrev {
+		if baseline.at < before {
+			delete(s.cpuPrev, agentID)
+		}
+	}
+}
+
+// cpuUsage 根据与上一次导入之间的累计时间差计算 CPU 使用率，iowait 计为空闲
+func (s *NodeExporterService) cpuUsage(agentID string, samples []promSample) *protocol.CPUData {
+	current := make(map[string]cpuTimes)
+	for _, sample := range samples {
+		if sample.Name != "node_cpu_seconds_total" {
+			continue
+		}
+		cpu := sample.Labels["cpu"]
+		times := current[cpu]
+		times.total += sample.Value
+		if mode := sample.Labels["mode"]; mode == "idle" || mode == "iowait" {
+			times.idle += sample.Value
+		}
+		current[cpu] = times
+	}
+	if len(current) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	prev := s.cpuPrev[agentID]
+	s.cpuPrev[agentID] = cpuBaseline{at: time.Now().UnixMilli(), cpus: current}
+	s.mu.Unlock()
+	return cpuUsageBetween(prev.cpus, current)
+}
+
+// cpuUsageBetween 计算两次累计时间之间的总使用率和每核使用率，计数器重置或缺少上一次数据时返回 nil
+func cpuUsageBetween(prev, current map[string]cpuTimes) *protocol.CPUData {
+	if len(prev) == 0 {
+		return nil
+	}
+
+	cpus := make([]string, 0, len(current))
+	for cpu := range current {
+		cpus = append(cpus, cpu)
+	}
+	sort.Slice(cpus, func(i, j int) bool {
+		if len(cpus[i]) != len(cpus[j]) {
+			return len(cpus[i]) < len(cpus[j])
+		}
+		return cpus[i] < cpus[j]
+	})
+
+	data := &protocol.CPUData{LogicalCores: len(current)}
+	var sumTotal, sumIdle float64
+	for _, cpu := range cpus {
+		before, ok := prev[cpu]
+		if !ok {
+			return nil
+		}
+		deltaTotal := current[cpu].total - before.total
+		deltaIdle := current[cpu].idle - before.idle
+		if deltaTotal <= 0 || deltaIdle < 0 {
+			return nil
+		}
+		sumTotal += deltaTotal
+		sumIdle += deltaIdle
+		data.PerCore = append(data.PerCore, (1-deltaIdle/deltaTotal)*100)
+	}
+	data.UsagePercent = (1 - sumIdle/sumTotal) * 100
+	return data
+}
+
+// nodeExporterMemory 将 node_memory_* 映射为内存数据，缺少总内存时返回 nil
+func nodeExporterMemory(samples []promSample) *protocol.MemoryData {
+	values := make(map[string]uint64)
+	for _, sample := range samples {
+		if strings.HasPrefix(sample.Name, "node_memory_") && sample.Value >= 0 {
+			values[strings.TrimPrefix(sample.Name, "node_memory_")] = uint64(sample.Value)
+		}
+	}
+	total := values["MemTotal_bytes"]
+	if total == 0 {
+		return nil
+	}
+
+	data := &protocol.MemoryData{
+		Total:     total,
+		Free:      values["MemFree_bytes"],
+		Available: values["MemAvailable_bytes"],
+		Cached:    values["Cached_bytes"],
+		Buffers:   values["Buffers_bytes"],
+		SwapTotal: values["SwapTotal_bytes"],
+		SwapFree:  values["SwapFree_bytes"],
+	}
+	// 旧内核没有 MemAvailable，按空闲、缓存和缓冲区估算
+	if _, ok := values["MemAvailable_bytes"]; !ok {
+		data.Available = min(data.Free+data.Cached+data.Buffers, total)
+	}
+	data.Used = total - min(data.Available, total)
+	data.UsagePercent = float64(data.Used) / float64(total) * 100
+	if data.SwapTotal > data.SwapFree {
+		data.SwapUsed = data.SwapTotal - data.SwapFree
+	}
+	return data
+}
+
+// nodeExporterDisks 将 node_filesystem_* 映射为磁盘数据，忽略伪文件系统和重复挂载的设备
+func nodeExporterDisks(samples []promSample) []protocol.DiskData {
+	type fsValues struct {
+		device, fstype    string
+		size, free, avail float64
+	}
+	filesystems := make(map[string]*fsValues)
+	for _, sample := range samples {
+		var field *float64
+		mountPoint := sample.Labels["mountpoint"]
+		if mountPoint == "" || nodeExporterIgnoredFstypes[sample.Labels["fstype"]] {
+			continue
+		}
+		fs, ok := filesystems[mountPoint]
+		if !ok {
+			fs = &fsValues{device: sample.Labels["device"], fstype: sample.Labels["fstype"]}
+		}
+		switch sample.Name {
+		case "node_filesystem_size_bytes":
+			field = &fs.size
+		case "node_filesystem_free_bytes":
+			field = &fs.free
+		case "node_filesystem_avail_bytes":
+			field = &fs.avail
+		default:
+			continue
+		}
+		*field = sample.Value
+		filesystems[mountPoint] = fs
+	}
+
+	mountPoints := make([]string, 0, len(filesystems))
+	for mountPoint := range filesystems {
+		mountPoints = append(mountPoints, mountPoint)
+	}
+	sort.Strings(mountPoints)
+
+	seenDevices := make(map[string]bool)
+	var disks []protocol.DiskData
+	for _, mountPoint := range mountPoints {
+		fs := filesystems[mountPoint]
+		if fs.size <= 0 || seenDevices[fs.device] {
+			continue
+		}
+		seenDevices[fs.device] = true
+
+		used := max(fs.size-fs.free, 0)
+		disk := protocol.DiskData{
+			MountPoint: mountPoint,
+			Device:     fs.device,
+			Fstype:     fs.fstype,
+			Total:      uint64(fs.size),
+			Used:       uint64(used),
+			Free:       uint64(fs.avail),
+		}
+		// 与 df 一致，使用率按普通用户可用空间计算，不含保留块
+		if used+fs.avail > 0 {
+			disk.UsagePercent = used / (used + fs.avail) * 100
+		}
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+// nodeExporterLoad 将 node_load1/5/15 映射为系统负载，缺少 1 分钟负载时返回 nil
+func nodeExporterLoad(samples []promSample) *protocol.LoadData {
+	var data protocol.LoadData
+	var found bool
+	for _, sample := range samples {
+		switch sample.Name {
+		case "node_load1":
+			data.Load1 = sample.Value
+			found = true
+		case "node_load5":
+			data.Load5 = sample.Value
+		case "node_load15":
+			data.Load15 = sample.Value
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &data
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const nodeExporterSample = `# HELP node_cpu_seconds_total Seconds the CPUs spent in each mode.
+# TYPE node_cpu_seconds_total counter
+node_cpu_seconds_total{cpu="0",mode="idle"} 100
+node_cpu_seconds_total{cpu="0",mode="iowait"} 10
+node_cpu_seconds_total{cpu="0",mode="user"} 40
+node_cpu_seconds_total{cpu="1",mode="idle"} 120
+node_cpu_seconds_total{cpu="1",mode="user"} 30
+node_memory_MemTotal_bytes 8e+09
+node_memory_MemAvailable_bytes 2e+09
+node_memory_MemFree_bytes 1e+09
+node_memory_SwapTotal_bytes 1000
+node_memory_SwapFree_bytes 400
+node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/"} 1000
+node_filesystem_free_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/"} 400
+node_filesystem_avail_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/"} 300
+node_filesystem_size_bytes{device="tmpfs",fstype="tmpfs",mountpoint="/run"} 500
+node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/var/lib/docker"} 1000
+node_load1 0.5
+node_load5 0.25
+node_network_receive_bytes_total{device="eth0"} 12345
+`
+
+func TestParsePromText(t *testing.T) {
+	samples, err := parsePromText(strings.NewReader(`metric{a="x\"y",b="1"} 2 1700000000000
+metric_nan NaN
+`), func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Labels["a"] != `x"y` || samples[0].Labels["b"] != "1" || samples[0].Value != 2 {
+		t.Fatalf("unexpected samples: %+v", samples)
+	}
+
+	if _, err := parsePromText(strings.NewReader(`metric{a="x" 1`), func(string) bool { return true }); err == nil {
+		t.Fatal("expected error for unterminated labels")
+	}
+}
+
+func TestNodeExporterMapping(t *testing.T) {
+	samples, err := parsePromText(strings.NewReader(nodeExporterSample), func(name string) bool {
+		return !strings.HasPrefix(name, "node_network_")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem := nodeExporterMemory(samples)
+	if mem == nil || mem.Used != 6e9 || mem.UsagePercent != 75 || mem.SwapUsed != 600 {
+		t.Fatalf("memory = %+v", mem)
+	}
+
+	// tmpfs 被忽略，同一设备重复挂载只计一次
+	disks := nodeExporterDisks(samples)
+	if len(disks) != 1 || disks[0].MountPoint != "/" || disks[0].Used != 600 || disks[0].Free != 300 {
+		t.Fatalf("disks = %+v", disks)
+	}
+	if want := 600.0 / 900 * 100; math.Abs(disks[0].UsagePercent-want) > 1e-9 {
+		t.Fatalf("disk usage = %v, want %v", disks[0].UsagePercent, want)
+	}
+
+	if load := nodeExporterLoad(samples); load == nil || load.Load1 != 0.5 || load.Load5 != 0.25 {
+		t.Fatalf("load = %+v", load)
+	}
+}
+
+func TestNodeExporterCPUUsage(t *testing.T) {
+	s := &NodeExporterService{cpuPrev: make(map[string]cpuBaseline)}
+	first, _ := parsePromText(strings.NewReader(nodeExporterSample), func(name string) bool { return name == "node_cpu_seconds_total" })
+	if cpu := s.cpuUsage("a1", first); cpu != nil {
+		t.Fatalf("first import should not produce cpu usage, got %+v", cpu)
+	}
+
+	// cpu0 空闲增加 5、用户态增加 15；cpu1 空闲增加 10、用户态增加 10
+	second, _ := parsePromText(strings.NewReader(`node_cpu_seconds_total{cpu="0",mode="idle"} 105
+node_cpu_seconds_total{cpu="0",mode="iowait"} 10
+node_cpu_seconds_total{cpu="0",mode="user"} 55
+node_cpu_seconds_total{cpu="1",mode="idle"} 130
+node_cpu_seconds_total{cpu="1",mode="user"} 40
+`), func(string) bool { return true })
+	cpu := s.cpuUsage("a1", second)
+	if cpu == nil || cpu.LogicalCores != 2 || len(cpu.PerCore) != 2 {
+		t.Fatalf("cpu = %+v", cpu)
+	}
+	if cpu.PerCore[0] != 75 || cpu.PerCore[1] != 50 || cpu.UsagePercent != 62.5 {
+		t.Fatalf("cpu usage = %v per core %v", cpu.UsagePercent, cpu.PerCore)
+	}
+
+	// 超时未导入的探针清理累计时间
+	s.pruneCPUBaselines(time.Now().Add(-time.Minute).UnixMilli())
+	if len(s.cpuPrev) != 1 {
+		t.Fatalf("recent baseline should be kept, got %d", len(s.cpuPrev))
+	}
+	s.pruneCPUBaselines(time.Now().Add(time.Minute).UnixMilli())
+	if len(s.cpuPrev) != 0 {
+		t.Fatalf("stale baseline should be pruned, got %d", len(s.cpuPrev))
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sync"
 
 	"github.com/dushixiang/pika/internal/models"
 	"go.uber.org/zap"
@@ -18,6 +19,9 @@ type NotificationService struct {
 	logger          *zap.Logger
 	propertyService *PropertyService
 	notifier        *Notifier
+
+	budgetMu sync.Mutex
+	budgets  map[string]*notificationBudget // 按探针统计的通知预算
 }
 
 func NewNotificationService(logger *zap.Logger, propertyService *PropertyService, notifier *Notifier) *NotificationService {
@@ -25,6 +29,7 @@ func NewNotificationService(logger *zap.Logger, propertyService *PropertyService
 		logger:          logger,
 		propertyService: propertyService,
 		notifier:        notifier,
+		budgets:         make(map[string]*notificationBudget),
 	}
 }
 
@@ -48,18 +53,18 @@ func (s *NotificationService) SendAlertNotification(ctx context.Context, notific
 		return err
 	}
 
-	var enabledChannels []models.NotificationChannelConfig
-	for _, channel := range channelConfigs {
-		if channel.Enabled {
-			enabledChannels = append(enabledChannels, channel)
-		}
-	}
+	enabledChannels := filterChannelsByLevel(channelConfigs, record.Level)
+	enabledChannels = filterChannelsByStatus(alertConfig, enabledChannels, record.Status)
 
 	if len(enabledChannels) == 0 {
 		return nil
 	}
 
-	if err := s.notifier.SendNotificationByConfigs(ctx, enabledChannels, record, agent, alertConfig.MaskIP); err != nil {
+	if !s.AllowAgentNotification(ctx, alertConfig, record, agent, enabledChannels) {
+		return nil
+	}
+
+	if err := s.notifier.SendNotificationByConfigs(ctx, enabledChannels, record, agent, alertConfig.ResolveMaskIPMode()); err != nil {
 		s.logger.Error("发送通知失败", zap.Error(err))
 		return err
 	}
@@ -67,12 +72,46 @@ func (s *NotificationService) SendAlertNotification(ctx context.Context, notific
 	return nil
 }
 
-func (s *NotificationService) IsMaskIPEnabled(ctx context.Context) (bool, error) {
+// GetMaskIPMode 获取通知中 IP 地址的打码粒度
+func (s *NotificationService) GetMaskIPMode(ctx context.Context) (models.IPMaskMode, error) {
 	alertConfig, err := s.propertyService.GetAlertConfig(ctx)
 	if err != nil {
-		return false, err
+		return models.IPMaskNone, err
+	}
+	return alertConfig.ResolveMaskIPMode(), nil
+}
+
+// filterChannelsByLevel 筛选已启用且最低告警级别满足的通知渠道，未设置级别的记录按 info 处理
+func filterChannelsByLevel(channels []models.NotificationChannelConfig, level string) []models.NotificationChannelConfig {
+	rank := levelRank(level)
+	if rank == 0 {
+		rank = levelRank(models.AlertLevelInfo)
+	}
+	var matched []models.NotificationChannelConfig
+	for _, channel := range channels {
+		if !channel.Enabled {
+			continue
+		}
+		if channel.MinLevel != "" && rank < levelRank(channel.MinLevel) {
+			continue
+		}
+		matched = append(matched, channel)
+	}
+	return matched
+}
+
+// filterChannelsByStatus 告警恢复时去掉不发送恢复通知的渠道，其他状态不做筛选
+func filterChannelsByStatus(config *models.AlertConfig, channels []models.NotificationChannelConfig, status string) []models.NotificationChannelConfig {
+	if status != "resolved" {
+		return channels
+	}
+	var matched []models.NotificationChannelConfig
+	for _, channel := range channels {
+		if config.ShouldNotifyOnResolve(channel) {
+			matched = append(matched, channel)
+		}
 	}
-	return alertConfig.MaskIP, nil
+	return matched
 }
 
 func isNotificationEnabled(config *models.AlertConfig, notificationType string) bool {
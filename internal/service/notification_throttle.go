@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// NotificationTypeThrottled 探针通知被限流时发送的汇总通知类型
+	NotificationTypeThrottled = "notification_throttled"
+	// defaultThrottleWindowMinutes 未配置时间窗口时使用的默认值
+	defaultThrottleWindowMinutes = 60
+)
+
+// notificationBudget 单个探针在当前时间窗口内的通知计数
+type notificationBudget struct {
+	windowStart int64 // 窗口开始时间（毫秒）
+	sent        int   // 已发送的通知数
+	dropped     int   // 超出预算被丢弃的通知数
+}
+
+// consumeNotificationBudget 消耗探针的通知预算
+// allowed 表示本次通知可以发送，firstDrop 表示本窗口内第一次超出预算（需要发送限流汇总）
+func (s *NotificationService) consumeNotificationBudget(agentID string, throttle models.NotificationThrottle, now int64) (allowed, firstDrop bool) {
+	if throttle.MaxNotifications <= 0 || agentID == "" {
+		return true, false
+	}
+	windowMinutes := throttle.WindowMinutes
+	if windowMinutes <= 0 {
+		windowMinutes = defaultThrottleWindowMinutes
+	}
+	window := (time.Duration(windowMinutes) * time.Minute).Milliseconds()
+
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+
+	budget, ok := s.budgets[agentID]
+	if !ok || now-budget.windowStart >= window {
+		if ok && budget.dropped > 0 {
+			s.logger.Info("探针通知限流窗口结束",
+				zap.String("agentId", agentID),
+				zap.Int("sent", budget.sent),
+				zap.Int("dropped", budget.dropped),
+			)
+		}
+		budget = &notificationBudget{windowStart: now}
+		s.budgets[agentID] = budget
+	}
+
+	if budget.sent < throttle.MaxNotifications {
+		budget.sent++
+		return true, false
+	}
+	budget.dropped++
+	return false, budget.dropped == 1
+}
+
+// ForgetAgent 删除探针的通知预算，探针删除后调用
+func (s *NotificationService) ForgetAgent(agentID string) {
+	s.budgetMu.Lock()
+	delete(s.budgets, agentID)
+	s.budgetMu.Unlock()
+}
+
+// AllowAgentNotification 检查探针的通知预算，超出预算时返回 false，告警记录照常保存但不发送通知；
+// 每个时间窗口内第一次超出预算时向 channels 发送一条限流汇总通知
+func (s *NotificationService) AllowAgentNotification(ctx context.Context, config *models.AlertConfig, record *models.AlertRecord, agent *models.Agent, channels []models.NotificationChannelConfig) bool {
+	now := time.Now().UnixMilli()
+	allowed, firstDrop := s.consumeNotificationBudget(record.AgentID, config.Throttle, now)
+	if allowed {
+		return true
+	}
+
+	s.logger.Debug("探针通知超出预算，已丢弃",
+		zap.String("agentId", record.AgentID),
+		zap.String("alertType", record.AlertType),
+		zap.Int64("recordId", record.ID),
+	)
+	if !firstDrop || len(channels) == 0 {
+		return false
+	}
+
+	windowMinutes := config.Throttle.WindowMinutes
+	if windowMinutes <= 0 {
+		windowMinutes = defaultThrottleWindowMinutes
+	}
+	agentName := record.AgentName
+	if agentName == "" && agent != nil {
+		agentName = agent.Name
+	}
+	summary := &models.AlertRecord{
+		AgentID:   record.AgentID,
+		AgentName: agentName,
+		AlertType: NotificationTypeThrottled,
+		Message: fmt.Sprintf("探针 %s 在 %d 分钟内的通知已达到上限 %d 条，本窗口内后续通知将不再发送（告警仍会记录）",
+			agentName, windowMinutes, config.Throttle.MaxNotifications),
+		Level:   models.AlertLevelWarning,
+		Status:  "firing",
+		FiredAt: now,
+		TraceID: record.TraceID,
+	}
+	s.logger.Warn("探针通知已被限流",
+		zap.String("agentId", record.AgentID),
+		zap.Int("maxNotifications", config.Throttle.MaxNotifications),
+		zap.Int("windowMinutes", windowMinutes),
+	)
+	if err := s.notifier.SendNotificationByConfigs(ctx, channels, summary, agent, config.ResolveMaskIPMode()); err != nil {
+		s.logger.Error("发送限流汇总通知失败", zap.Error(err))
+	}
+	return false
+}
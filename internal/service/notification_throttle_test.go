@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestConsumeNotificationBudget(t *testing.T) {
+	s := NewNotificationService(zap.NewNop(), nil, nil)
+	throttle := models.NotificationThrottle{MaxNotifications: 2, WindowMinutes: 1}
+	now := int64(1_000_000)
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := s.consumeNotificationBudget("a1", throttle, now); !allowed {
+			t.Fatalf("notification %d should be allowed", i)
+		}
+	}
+	allowed, firstDrop := s.consumeNotificationBudget("a1", throttle, now+1000)
+	if allowed || !firstDrop {
+		t.Fatalf("third notification: allowed=%v firstDrop=%v, want false/true", allowed, firstDrop)
+	}
+	allowed, firstDrop = s.consumeNotificationBudget("a1", throttle, now+2000)
+	if allowed || firstDrop {
+		t.Fatalf("fourth notification: allowed=%v firstDrop=%v, want false/false", allowed, firstDrop)
+	}
+
+	// 其他探针不受影响
+	if allowed, _ := s.consumeNotificationBudget("a2", throttle, now+2000); !allowed {
+		t.Fatal("other agent should not be throttled")
+	}
+
+	// 窗口结束后预算恢复
+	if allowed, _ := s.consumeNotificationBudget("a1", throttle, now+60_000); !allowed {
+		t.Fatal("budget should reset after the window")
+	}
+
+	// 未配置上限时不限制
+	for i := 0; i < 10; i++ {
+		if allowed, _ := s.consumeNotificationBudget("a3", models.NotificationThrottle{}, now); !allowed {
+			t.Fatal("unlimited budget should always allow")
+		}
+	}
+
+	// 删除探针后清理预算
+	s.ForgetAgent("a1")
+	s.ForgetAgent("a2")
+	if len(s.budgets) != 0 {
+		t.Fatalf("budgets should be empty after ForgetAgent, got %d", len(s.budgets))
+	}
+}
+
+func TestFilterChannelsByStatus(t *testing.T) {
+	off, on := false, true
+	channels := []models.NotificationChannelConfig{
+		{Type: "dingtalk"},
+		{Type: "telegram", NotifyOnResolve: &on},
+		{Type: "webhook", NotifyOnResolve: &off},
+	}
+	types := func(channels []models.NotificationChannelConfig) []string {
+		var result []string
+		for _, channel := range channels {
+			result = append(result, channel.Type)
+		}
+		return result
+	}
+
+	// 默认发送恢复通知，渠道配置优先于全局配置
+	config := &models.AlertConfig{}
+	if got := types(filterChannelsByStatus(config, channels, "resolved")); len(got) != 2 || got[0] != "dingtalk" || got[1] != "telegram" {
+		t.Fatalf("default: got %v, want [dingtalk telegram]", got)
+	}
+
+	config.NotifyOnResolve = &off
+	if got := types(filterChannelsByStatus(config, channels, "resolved")); len(got) != 1 || got[0] != "telegram" {
+		t.Fatalf("global off: got %v, want [telegram]", got)
+	}
+
+	// 告警触发通知不受影响
+	if got := filterChannelsByStatus(config, channels, "firing"); len(got) != 3 {
+		t.Fatalf("firing: got %d channels, want 3", len(got))
+	}
+}
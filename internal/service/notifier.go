@@ -10,9 +10,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dushixiang/pika/internal/models"
@@ -55,6 +57,34 @@ var alertTypeMetadataMap = map[string]AlertTypeMetadata{
 		ShowThreshold: true,
 		ShowActual:    true,
 	},
+	"memory_free": {
+		Name:          "剩余内存告警",
+		ThresholdUnit: "GB",
+		ValueUnit:     "GB",
+		ShowThreshold: true,
+		ShowActual:    true,
+	},
+	"disk_free": {
+		Name:          "磁盘剩余空间告警",
+		ThresholdUnit: "GB",
+		ValueUnit:     "GB",
+		ShowThreshold: true,
+		ShowActual:    true,
+	},
+	"disk_predict": {
+		Name:          "磁盘将满预测告警",
+		ThresholdUnit: "小时",
+		ValueUnit:     "小时",
+		ShowThreshold: true,
+		ShowActual:    true,
+	},
+	"load": {
+		Name:          "负载告警",
+		ThresholdUnit: "/核",
+		ValueUnit:     "/核",
+		ShowThreshold: true,
+		ShowActual:    true,
+	},
 	"network": {
 		Name:          "网络告警",
 		ThresholdUnit: "MB/s",
@@ -62,6 +92,13 @@ var alertTypeMetadataMap = map[string]AlertTypeMetadata{
 		ShowThreshold: true,
 		ShowActual:    true,
 	},
+	"connection": {
+		Name:          "连接数告警",
+		ThresholdUnit: "个",
+		ValueUnit:     "个",
+		ShowThreshold: true,
+		ShowActual:    true,
+	},
 	"traffic": {
 		Name:          "流量告警",
 		ThresholdUnit: "%",
@@ -90,6 +127,13 @@ var alertTypeMetadataMap = map[string]AlertTypeMetadata{
 		ShowThreshold: true,
 		ShowActual:    true,
 	},
+	"collector_error": {
+		Name:          "探针采集异常告警",
+		ThresholdUnit: "项",
+		ValueUnit:     "项",
+		ShowThreshold: false,
+		ShowActual:    true,
+	},
 	"ssh_login": {
 		Name:          "SSH登录成功",
 		ThresholdUnit: "",
@@ -104,6 +148,13 @@ var alertTypeMetadataMap = map[string]AlertTypeMetadata{
 		ShowThreshold: false,
 		ShowActual:    false,
 	},
+	NotificationTypeThrottled: {
+		Name:          "通知限流",
+		ThresholdUnit: "",
+		ValueUnit:     "",
+		ShowThreshold: false,
+		ShowActual:    false,
+	},
 }
 
 // 告警级别图标映射
@@ -116,6 +167,11 @@ var levelIconMap = map[string]string{
 // Notifier 告警通知服务
 type Notifier struct {
 	logger *zap.Logger
+
+	deliveriesMu sync.Mutex
+	deliveries   []DeliveryResult // 最近的投递结果
+
+	transports notifyTransports // 共享的 HTTP 连接池
 }
 
 func NewNotifier(logger *zap.Logger) *Notifier {
@@ -124,55 +180,54 @@ func NewNotifier(logger *zap.Logger) *Notifier {
 	}
 }
 
-// maskIPAddress 打码 IP 地址 (例如: 192.168.1.100 -> 192.168.*.*）
-func maskIPAddress(ip string) string {
-	parts := strings.Split(ip, ".")
-	if len(parts) == 4 {
-		// IPv4: 保留前两段，后两段打码
-		return parts[0] + "." + parts[1] + ".*.*"
+// maskIPAddress 按打码粒度处理 IP 地址
+// partial: 192.168.1.100 -> 192.168.1.*，2001:db8:1:2:3:4:5:6 -> 2001:db8:1:2:*:*:*:*
+// full: 192.168.1.100 -> *.*.*.*，IPv6 -> *:*:*:*:*:*:*:*
+func maskIPAddress(ip string, mode models.IPMaskMode) string {
+	if ip == "" || (mode != models.IPMaskPartial && mode != models.IPMaskFull) {
+		return ip
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "****"
+	}
+	if v4 := parsed.To4(); v4 != nil && !strings.Contains(ip, ":") {
+		if mode == models.IPMaskFull {
+			return "*.*.*.*"
+		}
+		return fmt.Sprintf("%d.%d.%d.*", v4[0], v4[1], v4[2])
 	}
-	// IPv6 或其他格式: 保留前半部分，后半部分打码
-	if len(ip) > 8 {
-		return ip[:len(ip)/2] + "****"
+	if mode == models.IPMaskFull {
+		return "*:*:*:*:*:*:*:*"
 	}
-	return "****"
+	// IPv6: 保留前 64 位网络前缀，隐藏接口标识
+	v6 := parsed.To16()
+	groups := make([]string, 0, 8)
+	for i := 0; i < 8; i += 2 {
+		groups = append(groups, strconv.FormatUint(uint64(v6[i])<<8|uint64(v6[i+1]), 16))
+	}
+	return strings.Join(groups, ":") + ":*:*:*:*"
 }
 
+// joinAgentIPs 拼接连接 IP 和公网 IPv4/IPv6，公网地址带地址族前缀，连接 IP 与公网地址相同时不重复显示
 func joinAgentIPs(ip string, ipv4 string, ipv6 string) string {
 	parts := make([]string, 0, 3)
-	seen := make(map[string]bool)
-
-	// 按优先级添加，避免重复
-	if ip != "" && !seen[ip] {
+	if ip != "" && ip != ipv4 && ip != ipv6 {
 		parts = append(parts, ip)
-		seen[ip] = true
 	}
-	if ipv4 != "" && !seen[ipv4] {
-		parts = append(parts, ipv4)
-		seen[ipv4] = true
+	if ipv4 != "" {
+		parts = append(parts, "IPv4 "+ipv4)
 	}
-	if ipv6 != "" && !seen[ipv6] {
-		parts = append(parts, ipv6)
-		seen[ipv6] = true
+	if ipv6 != "" {
+		parts = append(parts, "IPv6 "+ipv6)
 	}
 	return strings.Join(parts, " / ")
 }
 
-func formatAgentIP(agent *models.Agent, mask bool) string {
-	ip := strings.TrimSpace(agent.IP)
-	ipv4 := strings.TrimSpace(agent.IPv4)
-	ipv6 := strings.TrimSpace(agent.IPv6)
-	if mask {
-		if ip != "" {
-			ip = maskIPAddress(ip)
-		}
-		if ipv4 != "" {
-			ipv4 = maskIPAddress(ipv4)
-		}
-		if ipv6 != "" {
-			ipv6 = maskIPAddress(ipv6)
-		}
-	}
+func formatAgentIP(agent *models.Agent, mode models.IPMaskMode) string {
+	ip := maskIPAddress(strings.TrimSpace(agent.IP), mode)
+	ipv4 := maskIPAddress(strings.TrimSpace(agent.IPv4), mode)
+	ipv6 := maskIPAddress(strings.TrimSpace(agent.IPv6), mode)
 	combined := joinAgentIPs(ip, ipv4, ipv6)
 	if combined == "" {
 		return "-"
@@ -185,6 +240,10 @@ func getAlertTypeMetadata(alertType string) AlertTypeMetadata {
 	if metadata, ok := alertTypeMetadataMap[alertType]; ok {
 		return metadata
 	}
+	// 各连接状态的告警共用连接数告警的元数据
+	if isConnectionAlertType(alertType) {
+		return alertTypeMetadataMap["connection"]
+	}
 	// 返回默认值
 	return AlertTypeMetadata{
 		Name:          "未知告警",
@@ -204,7 +263,7 @@ func getLevelIcon(level string) string {
 }
 
 // buildMessage 构建告警消息文本
-func (n *Notifier) buildMessage(agent *models.Agent, record *models.AlertRecord, maskIP bool) string {
+func (n *Notifier) buildMessage(agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) string {
 	// 获取告警级别图标
 	levelIcon := getLevelIcon(record.Level)
 
@@ -212,7 +271,7 @@ func (n *Notifier) buildMessage(agent *models.Agent, record *models.AlertRecord,
 	metadata := getAlertTypeMetadata(record.AlertType)
 
 	// 处理 IP 地址显示
-	displayIP := formatAgentIP(agent, maskIP)
+	displayIP := formatAgentIP(agent, maskMode)
 
 	// 根据状态构建消息
 	switch record.Status {
@@ -396,7 +455,7 @@ func (n *Notifier) getWecomAppToken(ctx context.Context, origin, corpId, corpSec
 	accessTokenURL := fmt.Sprintf("%s/cgi-bin/gettoken?corpid=%s&corpsecret=%s", origin, corpId, corpSecret)
 
 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, accessTokenURL, nil)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := n.httpClient(ctx).Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -425,28 +484,79 @@ func (n *Notifier) getWecomAppToken(ctx context.Context, origin, corpId, corpSec
 
 }
 
+// 企业微信 access_token 失效相关错误码
+const (
+	wecomErrInvalidCredential = 40001
+	wecomErrInvalidToken      = 40014
+	wecomErrTokenExpired      = 42001
+)
+
+// wecomAppConfig 企业微信应用消息配置
+type wecomAppConfig struct {
+	Origin     string
+	CorpID     string
+	CorpSecret string
+	AgentID    int
+	ToUser     string // 成员ID列表，多个用 | 分隔，@all 表示全部成员
+	ToParty    string // 部门ID列表，多个用 | 分隔
+	ToTag      string // 标签ID列表，多个用 | 分隔
+	MsgType    string // 消息类型: text, textcard
+	CardURL    string // 文本卡片点击跳转地址（textcard 必填）
+}
+
 // sendWeComApp 发送企业应用微信通知
-func (n *Notifier) sendWeComApp(ctx context.Context, origin, corpId, corpSecret string, agentId int, toUser string, message string) error {
-	token, err := n.getWecomAppToken(ctx, origin, corpId, corpSecret)
+func (n *Notifier) sendWeComApp(ctx context.Context, cfg wecomAppConfig, message string) error {
+	errCode, err := n.doSendWeComApp(ctx, cfg, message)
+	if err != nil && (errCode == wecomErrInvalidCredential || errCode == wecomErrInvalidToken || errCode == wecomErrTokenExpired) {
+		// access_token 提前失效，清除缓存后重试一次
+		wecomAppAccessTokenCache.Delete(fmt.Sprintf("%s#%s", cfg.CorpID, cfg.CorpSecret))
+		_, err = n.doSendWeComApp(ctx, cfg, message)
+	}
+	return err
+}
+
+func (n *Notifier) doSendWeComApp(ctx context.Context, cfg wecomAppConfig, message string) (int, error) {
+	token, err := n.getWecomAppToken(ctx, cfg.Origin, cfg.CorpID, cfg.CorpSecret)
 	if err != nil {
-		return fmt.Errorf("获取企业微信应用ACCESS_TOKEN失败：%s", err)
+		return 0, fmt.Errorf("获取企业微信应用ACCESS_TOKEN失败：%s", err)
 	}
 
-	webhook := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", origin, token)
+	webhook := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", cfg.Origin, token)
 
 	body := map[string]interface{}{
-		"touser":  toUser,
-		"msgtype": "text",
-		"agentid": agentId,
-		"text": map[string]string{
+		"agentid": cfg.AgentID,
+		"safe":    0,
+	}
+	if cfg.ToUser != "" {
+		body["touser"] = cfg.ToUser
+	}
+	if cfg.ToParty != "" {
+		body["toparty"] = cfg.ToParty
+	}
+	if cfg.ToTag != "" {
+		body["totag"] = cfg.ToTag
+	}
+
+	if cfg.MsgType == "textcard" && cfg.CardURL != "" {
+		// 第一行作为标题，其余内容作为描述
+		title, description, _ := strings.Cut(message, "\n")
+		body["msgtype"] = "textcard"
+		body["textcard"] = map[string]string{
+			"title":       title,
+			"description": strings.TrimSpace(description),
+			"url":         cfg.CardURL,
+			"btntxt":      "详情",
+		}
+	} else {
+		body["msgtype"] = "text"
+		body["text"] = map[string]string{
 			"content": message,
-		},
-		"safe": 0,
+		}
 	}
 
 	result, err := n.sendJSONRequest(ctx, webhook, body)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	var sendRespBody struct {
@@ -455,14 +565,14 @@ func (n *Notifier) sendWeComApp(ctx context.Context, origin, corpId, corpSecret
 	}
 
 	if err := json.Unmarshal(result, &sendRespBody); err != nil {
-		return err
+		return 0, err
 	}
 
 	if sendRespBody.ErrCode != 0 {
-		return fmt.Errorf("%s", sendRespBody.ErrMsg)
+		return sendRespBody.ErrCode, fmt.Errorf("%s", sendRespBody.ErrMsg)
 	}
 
-	return nil
+	return 0, nil
 }
 
 // sendFeishu 发送飞书通知
@@ -550,6 +660,8 @@ type webhookConfig struct {
 	Method     string
 	Headers    map[string]string
 	CustomBody string
+	// SchemaVersion 请求体结构版本，渠道可固定为旧版本以兼容尚未升级的接收端
+	SchemaVersion int
 }
 
 // parseWebhookConfig 解析 Webhook 配置
@@ -579,16 +691,22 @@ func parseWebhookConfig(config map[string]interface{}) (*webhookConfig, error) {
 	// 获取自定义请求体
 	customBody, _ := config["customBody"].(string)
 
+	schemaVersion, err := parseWebhookSchemaVersion(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &webhookConfig{
-		URL:        webhookURL,
-		Method:     method,
-		Headers:    headers,
-		CustomBody: customBody,
+		URL:           webhookURL,
+		Method:        method,
+		Headers:       headers,
+		CustomBody:    customBody,
+		SchemaVersion: schemaVersion,
 	}, nil
 }
 
 // buildCustomBody 构建自定义模板格式的请求体
-func (n *Notifier) buildCustomBody(agent *models.Agent, record *models.AlertRecord, message, customBody string, maskIP bool) (io.Reader, error) {
+func (n *Notifier) buildCustomBody(agent *models.Agent, record *models.AlertRecord, message, customBody string, schemaVersion int, maskMode models.IPMaskMode) (io.Reader, error) {
 	if customBody == "" {
 		return nil, fmt.Errorf("必须提供自定义请求体模板")
 	}
@@ -608,6 +726,8 @@ func (n *Notifier) buildCustomBody(agent *models.Agent, record *models.AlertReco
 		switch tag {
 		case "message":
 			v = message
+		case "schemaVersion":
+			v = strconv.Itoa(schemaVersion)
 		case "agent.id":
 			v = agent.ID
 		case "agent.name":
@@ -638,8 +758,15 @@ func (n *Notifier) buildCustomBody(agent *models.Agent, record *models.AlertReco
 		case "alert.resolvedAt":
 			// 格式化的恢复时间 (使用系统时区，Docker 中设置为 Asia/Shanghai)
 			v = utils.FormatTimestamp(record.ResolvedAt)
+		case "event.category":
+			v = alertEventCategory(record)
 		default:
-			return w.Write([]byte("{{" + tag + "}}"))
+			if strings.HasPrefix(tag, "monitor.") {
+				v = monitorTemplateValue(record.Monitor, strings.TrimPrefix(tag, "monitor."))
+				break
+			}
+			// 未定义的变量渲染为空，避免单个变量拼写错误导致整个通知失败
+			n.logger.Debug("自定义Webhook模板包含未定义的变量", zap.String("tag", tag))
 		}
 
 		// 写入 JSON 安全转义后的值
@@ -650,10 +777,57 @@ func (n *Notifier) buildCustomBody(agent *models.Agent, record *models.AlertReco
 	return strings.NewReader(bodyStr), nil
 }
 
+// alertEventCategory 返回告警事件类别：monitor（监控项状态变化）或 agent（探针阈值告警）
+func alertEventCategory(record *models.AlertRecord) string {
+	if record.Monitor != nil {
+		return "monitor"
+	}
+	return "agent"
+}
+
+// monitorTemplateValue 返回监控项相关的模板变量值，非监控项事件时为空
+func monitorTemplateValue(monitor *models.MonitorAlertContext, field string) string {
+	if monitor == nil {
+		return ""
+	}
+	switch field {
+	case "id":
+		return monitor.ID
+	case "name":
+		return monitor.Name
+	case "type":
+		return monitor.Type
+	case "target":
+		return monitor.Target
+	case "status":
+		return monitor.Status
+	case "statusCode":
+		if monitor.StatusCode == 0 {
+			return ""
+		}
+		return strconv.Itoa(monitor.StatusCode)
+	case "responseTime":
+		return strconv.FormatInt(monitor.ResponseTime, 10)
+	case "error":
+		return monitor.Error
+	case "downtime":
+		return strconv.FormatInt(monitor.Downtime, 10)
+	case "certDaysLeft":
+		return strconv.Itoa(monitor.CertDaysLeft)
+	default:
+		return ""
+	}
+}
+
 // sendHTTPRequest 发送 HTTP 请求
 func (n *Notifier) sendHTTPRequest(ctx context.Context, method, webhookURL string, body io.Reader, headers map[string]string, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("读取请求体失败: %w", err)
+	}
+
 	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, method, webhookURL, body)
+	req, err := http.NewRequestWithContext(ctx, method, webhookURL, bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("创建请求失败: %w", err)
 	}
@@ -667,11 +841,7 @@ func (n *Notifier) sendHTTPRequest(ctx context.Context, method, webhookURL strin
 	}
 
 	// 发送请求
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
+	resp, err := n.httpClient(ctx).Do(req)
 	if err != nil {
 		return fmt.Errorf("发送请求失败: %w", err)
 	}
@@ -679,6 +849,7 @@ func (n *Notifier) sendHTTPRequest(ctx context.Context, method, webhookURL strin
 
 	// 读取响应
 	respBody, _ := io.ReadAll(resp.Body)
+	n.recordTransfer(ctx, webhookURL, data, resp, len(respBody))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return fmt.Errorf("请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
@@ -694,7 +865,7 @@ func (n *Notifier) sendHTTPRequest(ctx context.Context, method, webhookURL strin
 }
 
 // sendCustomWebhook 发送自定义Webhook
-func (n *Notifier) sendCustomWebhook(ctx context.Context, config map[string]interface{}, agent *models.Agent, record *models.AlertRecord, maskIP bool) error {
+func (n *Notifier) sendCustomWebhook(ctx context.Context, config map[string]interface{}, agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) error {
 	// 解析配置
 	cfg, err := parseWebhookConfig(config)
 	if err != nil {
@@ -702,17 +873,35 @@ func (n *Notifier) sendCustomWebhook(ctx context.Context, config map[string]inte
 	}
 
 	// 构建消息内容
-	message := n.buildMessage(agent, record, maskIP)
+	message := n.buildMessage(agent, record, maskMode)
 
-	// 构建自定义请求体
-	reqBody, err := n.buildCustomBody(agent, record, message, cfg.CustomBody, maskIP)
-	if err != nil {
-		return err
+	// 未配置自定义请求体模板时发送对应结构版本的默认请求体
+	var reqBody io.Reader
+	if cfg.CustomBody == "" {
+		payload, err := buildWebhookPayload(cfg.SchemaVersion, agent, record, message, maskMode)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("序列化请求体失败: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	} else {
+		reqBody, err = n.buildCustomBody(agent, record, message, cfg.CustomBody, cfg.SchemaVersion, maskMode)
+		if err != nil {
+			return err
+		}
+	}
+
+	headers := map[string]string{webhookSchemaVersionHeader: strconv.Itoa(cfg.SchemaVersion)}
+	for k, v := range cfg.Headers {
+		headers[k] = v
 	}
 
 	// 发送 HTTP 请求，使用 application/json 作为默认 Content-Type
 	contentType := "application/json"
-	return n.sendHTTPRequest(ctx, cfg.Method, cfg.URL, reqBody, cfg.Headers, contentType)
+	return n.sendHTTPRequest(ctx, cfg.Method, cfg.URL, reqBody, headers, contentType)
 }
 
 // sendJSONRequest 发送JSON请求
@@ -729,11 +918,7 @@ func (n *Notifier) sendJSONRequest(ctx context.Context, url string, body interfa
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
+	resp, err := n.httpClient(ctx).Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
@@ -741,6 +926,7 @@ func (n *Notifier) sendJSONRequest(ctx context.Context, url string, body interfa
 
 	// 读取响应
 	respBody, _ := io.ReadAll(resp.Body)
+	n.recordTransfer(ctx, url, data, resp, len(respBody))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
@@ -767,7 +953,12 @@ func (n *Notifier) sendDingTalkByConfig(ctx context.Context, config map[string]i
 }
 
 // sendWeComByConfig 根据配置发送企业微信通知
+// 配置了 corpId 时使用企业应用消息（默认文本卡片），否则使用群机器人
 func (n *Notifier) sendWeComByConfig(ctx context.Context, config map[string]interface{}, message string) error {
+	if corpId, ok := config["corpId"].(string); ok && corpId != "" {
+		return n.sendWeComAppMessageByConfig(ctx, config, message, "textcard")
+	}
+
 	secretKey, ok := config["secretKey"].(string)
 	if !ok || secretKey == "" {
 		return fmt.Errorf("企业微信配置缺少 secretKey")
@@ -781,23 +972,33 @@ func (n *Notifier) sendWeComByConfig(ctx context.Context, config map[string]inte
 
 // sendWeComAppByConfig 根据配置发送企业微信应用通知
 func (n *Notifier) sendWeComAppByConfig(ctx context.Context, config map[string]interface{}, message string) error {
-	origin := "https://qyapi.weixin.qq.com"
+	return n.sendWeComAppMessageByConfig(ctx, config, message, "text")
+}
+
+// sendWeComAppMessageByConfig 解析企业微信应用配置并发送，defaultMsgType 为未配置 msgType 时的消息类型
+func (n *Notifier) sendWeComAppMessageByConfig(ctx context.Context, config map[string]interface{}, message, defaultMsgType string) error {
+	cfg := wecomAppConfig{
+		Origin:  "https://qyapi.weixin.qq.com",
+		MsgType: defaultMsgType,
+	}
 	if v, ok := config["origin"].(string); ok && v != "" {
-		origin = v
+		cfg.Origin = v
 	}
-
-	toUser := "@all"
-	if v, ok := config["toUser"].(string); ok && v != "" {
-		toUser = v
+	if v, ok := config["msgType"].(string); ok && v != "" {
+		cfg.MsgType = v
+	}
+	if v, ok := config["url"].(string); ok {
+		cfg.CardURL = v
 	}
 
-	corpId, ok := config["corpId"].(string)
-	if !ok || corpId == "" {
+	var ok bool
+	cfg.CorpID, ok = config["corpId"].(string)
+	if !ok || cfg.CorpID == "" {
 		return fmt.Errorf("企业微信应用配置缺少 corpid")
 	}
 
-	corpSecret, ok := config["corpSecret"].(string)
-	if !ok || corpSecret == "" {
+	cfg.CorpSecret, ok = config["corpSecret"].(string)
+	if !ok || cfg.CorpSecret == "" {
 		return fmt.Errorf("企业微信应用配置缺少 corpsecret")
 	}
 
@@ -805,8 +1006,41 @@ func (n *Notifier) sendWeComAppByConfig(ctx context.Context, config map[string]i
 	if !ok || agentIdf <= 0 {
 		return fmt.Errorf("企业微信应用配置缺少 agentid")
 	}
+	cfg.AgentID = int(agentIdf)
 
-	return n.sendWeComApp(ctx, origin, corpId, corpSecret, int(agentIdf), toUser, message)
+	// 接收人：兼容旧的 toUser 字段和新的 userIds 列表
+	cfg.ToUser = joinWeComIDs(config["userIds"])
+	if cfg.ToUser == "" {
+		cfg.ToUser = joinWeComIDs(config["toUser"])
+	}
+	cfg.ToParty = joinWeComIDs(config["departmentIds"])
+	cfg.ToTag = joinWeComIDs(config["tagIds"])
+	if cfg.ToUser == "" && cfg.ToParty == "" && cfg.ToTag == "" {
+		cfg.ToUser = "@all"
+	}
+
+	return n.sendWeComApp(ctx, cfg, message)
+}
+
+// joinWeComIDs 将配置中的 ID 列表（数组或 | 分隔的字符串）转换为企业微信接口格式
+func joinWeComIDs(value interface{}) string {
+	var ids []string
+	switch v := value.(type) {
+	case string:
+		for _, id := range strings.Split(v, "|") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			id := strings.TrimSpace(fmt.Sprint(item))
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return strings.Join(ids, "|")
 }
 
 // sendFeishuByConfig 根据配置发送飞书通知
@@ -887,12 +1121,12 @@ func (n *Notifier) sendEmailByConfig(ctx context.Context, config map[string]inte
 }
 
 // sendWebhookByConfig 根据配置发送自定义Webhook
-func (n *Notifier) sendWebhookByConfig(ctx context.Context, config map[string]interface{}, agent *models.Agent, record *models.AlertRecord, maskIP bool) error {
-	return n.sendCustomWebhook(ctx, config, agent, record, maskIP)
+func (n *Notifier) sendWebhookByConfig(ctx context.Context, config map[string]interface{}, agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) error {
+	return n.sendCustomWebhook(ctx, config, agent, record, maskMode)
 }
 
 // SendNotificationByConfig 根据新的配置结构发送通知
-func (n *Notifier) SendNotificationByConfig(ctx context.Context, channelConfig *models.NotificationChannelConfig, record *models.AlertRecord, agent *models.Agent, maskIP bool) error {
+func (n *Notifier) SendNotificationByConfig(ctx context.Context, channelConfig *models.NotificationChannelConfig, record *models.AlertRecord, agent *models.Agent, maskMode models.IPMaskMode) error {
 	if !channelConfig.Enabled {
 		return fmt.Errorf("通知渠道已禁用")
 	}
@@ -902,8 +1136,15 @@ func (n *Notifier) SendNotificationByConfig(ctx context.Context, channelConfig *
 	)
 
 	// 构造通知消息内容
-	message := n.buildMessage(agent, record, maskIP)
+	message := n.buildMessage(agent, record, maskMode)
 
+	return n.trackDelivery(ctx, channelConfig.Type, channelConfig.Config, func(ctx context.Context) error {
+		return n.dispatchNotification(ctx, channelConfig, record, agent, message, maskMode)
+	})
+}
+
+// dispatchNotification 按渠道类型发送通知
+func (n *Notifier) dispatchNotification(ctx context.Context, channelConfig *models.NotificationChannelConfig, record *models.AlertRecord, agent *models.Agent, message string, maskMode models.IPMaskMode) error {
 	switch channelConfig.Type {
 	case "dingtalk":
 		return n.sendDingTalkByConfig(ctx, channelConfig.Config, message)
@@ -917,19 +1158,23 @@ func (n *Notifier) SendNotificationByConfig(ctx context.Context, channelConfig *
 		return n.sendTelegramByConfig(ctx, channelConfig.Config, message)
 	case "email":
 		return n.sendEmailByConfig(ctx, channelConfig.Config, message)
+	case "discord":
+		return n.sendDiscordByConfig(ctx, channelConfig.Config, agent, record, maskMode)
+	case "mattermost":
+		return n.sendMattermostByConfig(ctx, channelConfig.Config, agent, record, maskMode)
 	case "webhook":
-		return n.sendWebhookByConfig(ctx, channelConfig.Config, agent, record, maskIP)
+		return n.sendWebhookByConfig(ctx, channelConfig.Config, agent, record, maskMode)
 	default:
 		return fmt.Errorf("不支持的通知渠道类型: %s", channelConfig.Type)
 	}
 }
 
 // SendNotificationByConfigs 根据新的配置结构向多个渠道发送通知
-func (n *Notifier) SendNotificationByConfigs(ctx context.Context, channelConfigs []models.NotificationChannelConfig, record *models.AlertRecord, agent *models.Agent, maskIP bool) error {
+func (n *Notifier) SendNotificationByConfigs(ctx context.Context, channelConfigs []models.NotificationChannelConfig, record *models.AlertRecord, agent *models.Agent, maskMode models.IPMaskMode) error {
 	var errs []error
 
 	for _, channelConfig := range channelConfigs {
-		if err := n.SendNotificationByConfig(ctx, &channelConfig, record, agent, maskIP); err != nil {
+		if err := n.SendNotificationByConfig(ctx, &channelConfig, record, agent, maskMode); err != nil {
 			n.logger.Error("发送通知失败",
 				zap.String("channelType", channelConfig.Type),
 				zap.Error(err),
@@ -993,11 +1238,18 @@ func (n *Notifier) SendWebhookByConfig(ctx context.Context, config map[string]in
 		ActualValue: 0,
 		FiredAt:     time.Now().UnixMilli(),
 	}
-	return n.sendWebhookByConfig(ctx, config, agent, record, false)
+	return n.sendWebhookByConfig(ctx, config, agent, record, models.IPMaskNone)
 }
 
 // SendTestNotification 发送测试通知（动态匹配通知渠道类型）
 func (n *Notifier) SendTestNotification(ctx context.Context, channelType string, config map[string]interface{}, message string) error {
+	return n.trackDelivery(ctx, channelType, config, func(ctx context.Context) error {
+		return n.dispatchTestNotification(ctx, channelType, config, message)
+	})
+}
+
+// dispatchTestNotification 按渠道类型发送测试通知
+func (n *Notifier) dispatchTestNotification(ctx context.Context, channelType string, config map[string]interface{}, message string) error {
 	switch channelType {
 	case "dingtalk":
 		return n.sendDingTalkByConfig(ctx, config, message)
@@ -1011,6 +1263,10 @@ func (n *Notifier) SendTestNotification(ctx context.Context, channelType string,
 		return n.sendTelegramByConfig(ctx, config, message)
 	case "email":
 		return n.sendEmailByConfig(ctx, config, message)
+	case "discord":
+		return n.sendDiscordMessageByConfig(ctx, config, message)
+	case "mattermost":
+		return n.sendMattermostMessageByConfig(ctx, config, message)
 	case "webhook":
 		// Webhook 需要 agent 和 record，创建测试数据
 		agent := &models.Agent{
@@ -1028,7 +1284,7 @@ func (n *Notifier) SendTestNotification(ctx context.Context, channelType string,
 			ActualValue: 0,
 			FiredAt:     time.Now().UnixMilli(),
 		}
-		return n.sendWebhookByConfig(ctx, config, agent, record, false)
+		return n.sendWebhookByConfig(ctx, config, agent, record, models.IPMaskNone)
 	default:
 		return fmt.Errorf("不支持的通知渠道类型: %s", channelType)
 	}
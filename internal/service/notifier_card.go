@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/utils"
+)
+
+// 卡片颜色（Discord、Mattermost 等支持彩色附件的渠道共用）
+var alertLevelColors = map[string]int{
+	"info":     0x3498DB,
+	"warning":  0xF1C40F,
+	"critical": 0xE74C3C,
+}
+
+const alertResolvedColor = 0x2ECC71
+
+// alertCardField 卡片字段
+type alertCardField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// alertCard 渠道无关的告警卡片，由各渠道转换为自己的消息格式
+type alertCard struct {
+	Title     string
+	Text      string
+	Color     int
+	Fields    []alertCardField
+	EventTime time.Time
+}
+
+// buildAlertCard 构建告警卡片，包含告警状态、探针信息（按 maskMode 脱敏）和阈值等字段
+func buildAlertCard(agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) alertCard {
+	metadata := getAlertTypeMetadata(record.AlertType)
+
+	color, ok := alertLevelColors[record.Level]
+	if !ok {
+		color = alertLevelColors["info"]
+	}
+
+	var title string
+	eventTime := record.FiredAt
+	switch record.Status {
+	case "resolved":
+		title = fmt.Sprintf("✅ [已恢复] %s", metadata.Name)
+		color = alertResolvedColor
+		eventTime = record.ResolvedAt
+	case "notice":
+		title = fmt.Sprintf("%s [通知] %s", getLevelIcon(record.Level), metadata.Name)
+	default:
+		title = fmt.Sprintf("%s [告警中] %s", getLevelIcon(record.Level), metadata.Name)
+	}
+
+	fields := []alertCardField{
+		{Name: "探针", Value: agent.Name, Inline: true},
+		{Name: "主机", Value: agent.Hostname, Inline: true},
+		{Name: "IP", Value: formatAgentIP(agent, maskMode), Inline: true},
+		{Name: "告警类型", Value: record.AlertType, Inline: true},
+		{Name: "级别", Value: record.Level, Inline: true},
+	}
+	if metadata.ShowThreshold && record.Status != "resolved" {
+		fields = append(fields, alertCardField{Name: "阈值", Value: fmt.Sprintf("%.2f%s", record.Threshold, metadata.ThresholdUnit), Inline: true})
+	}
+	if metadata.ShowActual {
+		fields = append(fields, alertCardField{Name: "当前值", Value: fmt.Sprintf("%.2f%s", record.ActualValue, metadata.ValueUnit), Inline: true})
+	}
+	fields = append(fields, alertCardField{Name: "触发时间", Value: utils.FormatTimestamp(record.FiredAt), Inline: true})
+	if record.Status == "resolved" {
+		fields = append(fields, alertCardField{Name: "恢复时间", Value: utils.FormatTimestamp(record.ResolvedAt), Inline: true})
+		if record.FiredAt > 0 && record.ResolvedAt > record.FiredAt {
+			fields = append(fields, alertCardField{Name: "持续时间", Value: utils.FormatDuration(record.ResolvedAt - record.FiredAt), Inline: true})
+		}
+	}
+
+	// 部分渠道不接受空的字段值
+	for i := range fields {
+		if fields[i].Value == "" {
+			fields[i].Value = "-"
+		}
+	}
+
+	if eventTime <= 0 {
+		eventTime = time.Now().UnixMilli()
+	}
+
+	return alertCard{
+		Title:     title,
+		Text:      record.Message,
+		Color:     color,
+		Fields:    fields,
+		EventTime: time.UnixMilli(eventTime),
+	}
+}
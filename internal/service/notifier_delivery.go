@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/utils"
+	"go.uber.org/zap"
+)
+
+// maxRecentDeliveries 保留的最近投递记录数
+const maxRecentDeliveries = 100
+
+// DeliveryResult 单次通知投递结果
+type DeliveryResult struct {
+	ChannelType   string `json:"channelType"`        // 通知渠道类型
+	URL           string `json:"url,omitempty"`      // 请求地址（已脱敏）
+	FinalURL      string `json:"finalUrl,omitempty"` // 跟随重定向后的最终地址（已脱敏）
+	StatusCode    int    `json:"statusCode"`         // 最后一次请求的响应状态码
+	Requests      int    `json:"requests"`           // HTTP 请求次数（包含获取令牌、重试等）
+	RequestBytes  int64  `json:"requestBytes"`       // 发送的请求体字节数
+	ResponseBytes int64  `json:"responseBytes"`      // 接收的响应体字节数
+	DurationMs    int64  `json:"durationMs"`         // 投递耗时（毫秒）
+	Success       bool   `json:"success"`            // 是否成功
+	Error         string `json:"error,omitempty"`    // 失败原因
+	Timestamp     int64  `json:"timestamp"`          // 投递时间（毫秒）
+}
+
+type deliveryContextKey struct{}
+
+// deliveryTracker 记录一次投递中所有 HTTP 请求的传输情况
+type deliveryTracker struct {
+	mu        sync.Mutex
+	result    *DeliveryResult
+	debugBody bool          // 是否以 debug 级别记录完整请求体
+	timeout   time.Duration // 单次 HTTP 请求超时
+	proxy     *url.URL      // HTTP 代理，为空时直连（或使用环境变量中的代理）
+}
+
+func withDeliveryTracker(ctx context.Context, tracker *deliveryTracker) context.Context {
+	return context.WithValue(ctx, deliveryContextKey{}, tracker)
+}
+
+func deliveryTrackerFrom(ctx context.Context) *deliveryTracker {
+	tracker, _ := ctx.Value(deliveryContextKey{}).(*deliveryTracker)
+	return tracker
+}
+
+// recordTransfer 记录一次 HTTP 请求的传输大小及最终地址
+func (n *Notifier) recordTransfer(ctx context.Context, requestURL string, requestBody []byte, resp *http.Response, responseBytes int) {
+	tracker := deliveryTrackerFrom(ctx)
+	if tracker == nil {
+		return
+	}
+
+	if tracker.debugBody {
+		n.logger.Debug("通知请求体",
+			zap.String("channelType", tracker.result.ChannelType),
+			zap.String("url", redactURL(requestURL)),
+			zap.ByteString("body", requestBody),
+		)
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	result := tracker.result
+	result.Requests++
+	result.RequestBytes += int64(len(requestBody))
+	result.ResponseBytes += int64(responseBytes)
+	result.URL = redactURL(requestURL)
+	result.FinalURL = result.URL
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+		if resp.Request != nil && resp.Request.URL != nil {
+			result.FinalURL = redactURL(resp.Request.URL.String())
+		}
+	}
+}
+
+// trackDelivery 执行投递并记录结果
+func (n *Notifier) trackDelivery(ctx context.Context, channelType string, config map[string]interface{}, send func(ctx context.Context) error) error {
+	debugBody, _ := config["debugBody"].(bool)
+	timeout, proxy, err := parseNotifyHTTPOptions(config)
+	tracker := &deliveryTracker{
+		result: &DeliveryResult{
+			ChannelType: channelType,
+			Timestamp:   time.Now().UnixMilli(),
+		},
+		debugBody: debugBody,
+		timeout:   timeout,
+		proxy:     proxy,
+	}
+
+	start := time.Now()
+	if err == nil {
+		err = send(withDeliveryTracker(ctx, tracker))
+	}
+
+	tracker.mu.Lock()
+	result := *tracker.result
+	tracker.mu.Unlock()
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	n.logger.Info("通知投递结果",
+		zap.String("channelType", result.ChannelType),
+		zap.String("url", result.URL),
+		zap.String("finalUrl", result.FinalURL),
+		zap.Int("statusCode", result.StatusCode),
+		zap.Int("requests", result.Requests),
+		zap.Int64("requestBytes", result.RequestBytes),
+		zap.Int64("responseBytes", result.ResponseBytes),
+		zap.Int64("durationMs", result.DurationMs),
+		zap.Bool("success", result.Success),
+		utils.TraceField(ctx),
+	)
+
+	n.deliveriesMu.Lock()
+	n.deliveries = append(n.deliveries, result)
+	if len(n.deliveries) > maxRecentDeliveries {
+		n.deliveries = n.deliveries[len(n.deliveries)-maxRecentDeliveries:]
+	}
+	n.deliveriesMu.Unlock()
+
+	return err
+}
+
+// RecentDeliveries 获取最近的通知投递结果（按时间倒序）
+func (n *Notifier) RecentDeliveries(channelType string) []DeliveryResult {
+	n.deliveriesMu.Lock()
+	defer n.deliveriesMu.Unlock()
+
+	results := make([]DeliveryResult, 0, len(n.deliveries))
+	for i := len(n.deliveries) - 1; i >= 0; i-- {
+		if channelType != "" && n.deliveries[i].ChannelType != channelType {
+			continue
+		}
+		results = append(results, n.deliveries[i])
+	}
+	return results
+}
+
+// redactURL 去除地址中的查询参数、用户信息以及路径中疑似令牌的片段
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		// Telegram、Discord 等渠道的令牌位于路径中
+		if len(segment) >= 20 {
+			segments[i] = "***"
+		}
+	}
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+	return u.String()
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// discordContentLimit Discord 消息 content 最大字符数
+	discordContentLimit = 2000
+	// discordDescriptionLimit Discord embed description 最大字符数
+	discordDescriptionLimit = 4096
+	// discordFieldValueLimit Discord embed field value 最大字符数
+	discordFieldValueLimit = 1024
+	// discordMaxRetries 被限流时的最大重试次数
+	discordMaxRetries = 3
+)
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Footer      *discordEmbedFooter `json:"footer,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+type discordPayload struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// buildDiscordEmbed 构建告警 embed
+func (n *Notifier) buildDiscordEmbed(agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) discordEmbed {
+	card := buildAlertCard(agent, record, maskMode)
+
+	fields := make([]discordEmbedField, 0, len(card.Fields))
+	for _, field := range card.Fields {
+		fields = append(fields, discordEmbedField{
+			Name:   field.Name,
+			Value:  truncateRunes(field.Value, discordFieldValueLimit),
+			Inline: field.Inline,
+		})
+	}
+
+	return discordEmbed{
+		Title:       card.Title,
+		Description: truncateRunes(card.Text, discordDescriptionLimit),
+		Color:       card.Color,
+		Fields:      fields,
+		Footer:      &discordEmbedFooter{Text: "Pika"},
+		Timestamp:   card.EventTime.UTC().Format(time.RFC3339),
+	}
+}
+
+// sendDiscordByConfig 根据配置发送 Discord 告警通知
+func (n *Notifier) sendDiscordByConfig(ctx context.Context, config map[string]interface{}, agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) error {
+	webhookURL, ok := config["webhookUrl"].(string)
+	if !ok || webhookURL == "" {
+		return fmt.Errorf("Discord 配置缺少 webhookUrl")
+	}
+
+	payload := discordPayload{
+		Embeds: []discordEmbed{n.buildDiscordEmbed(agent, record, maskMode)},
+	}
+	return n.sendDiscord(ctx, webhookURL, payload)
+}
+
+// sendDiscordMessageByConfig 根据配置发送 Discord 纯文本消息
+func (n *Notifier) sendDiscordMessageByConfig(ctx context.Context, config map[string]interface{}, message string) error {
+	webhookURL, ok := config["webhookUrl"].(string)
+	if !ok || webhookURL == "" {
+		return fmt.Errorf("Discord 配置缺少 webhookUrl")
+	}
+
+	payload := discordPayload{
+		Content: truncateRunes(message, discordContentLimit),
+	}
+	return n.sendDiscord(ctx, webhookURL, payload)
+}
+
+// sendDiscord 发送 Discord Webhook 消息，被限流时按 retry_after 等待后重试
+func (n *Notifier) sendDiscord(ctx context.Context, webhookURL string, payload discordPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化请求体失败: %w", err)
+	}
+
+	client := n.httpClient(ctx)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("创建请求失败: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("发送请求失败: %w", err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		n.recordTransfer(ctx, webhookURL, data, resp, len(respBody))
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < discordMaxRetries {
+			wait := discordRetryAfter(resp.Header, respBody)
+			n.logger.Warn("Discord 通知被限流，等待后重试",
+				zap.Duration("retryAfter", wait),
+				zap.Int("attempt", attempt+1),
+			)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+		}
+
+		n.logger.Info("Discord 通知发送成功")
+		return nil
+	}
+}
+
+// discordRetryAfter 解析限流等待时间，优先使用响应体中的 retry_after（秒）
+func discordRetryAfter(header http.Header, body []byte) time.Duration {
+	var rateLimit struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.Unmarshal(body, &rateLimit); err == nil && rateLimit.RetryAfter > 0 {
+		return time.Duration(rateLimit.RetryAfter * float64(time.Second))
+	}
+	if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
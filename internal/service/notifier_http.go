@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+const (
+	// defaultNotifyTimeout 通知请求的默认超时时间
+	defaultNotifyTimeout = 10 * time.Second
+	// maxNotifyTimeout 渠道可配置的最长超时时间
+	maxNotifyTimeout = 120 * time.Second
+)
+
+// notifyTransports 通知使用的共享连接池，按代理地址区分
+type notifyTransports struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport // 代理地址 -> 连接池，空字符串表示直连
+}
+
+// newNotifyTransport 创建通知请求使用的连接池
+func newNotifyTransport(proxy *url.URL) *http.Transport {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		MaxConnsPerHost:       20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return transport
+}
+
+// get 获取指定代理的连接池，不存在时创建
+func (t *notifyTransports) get(proxy *url.URL) *http.Transport {
+	key := ""
+	if proxy != nil {
+		key = proxy.String()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.transports == nil {
+		t.transports = make(map[string]*http.Transport)
+	}
+	transport, ok := t.transports[key]
+	if !ok {
+		transport = newNotifyTransport(proxy)
+		t.transports[key] = transport
+	}
+	return transport
+}
+
+// parseNotifyHTTPOptions 解析渠道配置中的超时时间（timeoutSeconds）和代理地址（proxy）
+func parseNotifyHTTPOptions(config map[string]interface{}) (time.Duration, *url.URL, error) {
+	timeout := defaultNotifyTimeout
+	switch v := config["timeoutSeconds"].(type) {
+	case float64:
+		if v <= 0 {
+			return 0, nil, fmt.Errorf("timeoutSeconds 必须为正数")
+		}
+		timeout = min(time.Duration(v*float64(time.Second)), maxNotifyTimeout)
+	case int:
+		if v <= 0 {
+			return 0, nil, fmt.Errorf("timeoutSeconds 必须为正数")
+		}
+		timeout = min(time.Duration(v)*time.Second, maxNotifyTimeout)
+	}
+
+	rawProxy, _ := config["proxy"].(string)
+	rawProxy = strings.TrimSpace(rawProxy)
+	if rawProxy == "" {
+		return timeout, nil, nil
+	}
+	proxy, err := url.Parse(rawProxy)
+	if err != nil || proxy.Host == "" {
+		return 0, nil, fmt.Errorf("代理地址无效: %s", rawProxy)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return 0, nil, fmt.Errorf("不支持的代理协议: %s", proxy.Scheme)
+	}
+	return timeout, proxy, nil
+}
+
+// ValidateNotificationChannels 校验通知渠道 ID 是否重复、最低告警级别、超时和代理配置，以及 Webhook 固定的结构版本
+func ValidateNotificationChannels(channels []models.NotificationChannelConfig) error {
+	ids := make(map[string]struct{}, len(channels))
+	for _, channel := range channels {
+		if _, ok := ids[channel.ChannelID()]; ok {
+			return fmt.Errorf("通知渠道 ID 重复: %s", channel.ChannelID())
+		}
+		ids[channel.ChannelID()] = struct{}{}
+		if channel.MinLevel != "" && levelRank(channel.MinLevel) == 0 {
+			return fmt.Errorf("通知渠道 %s 的最低告警级别无效: %s，支持: info, warning, critical", channel.ChannelID(), channel.MinLevel)
+		}
+		if _, _, err := parseNotifyHTTPOptions(channel.Config); err != nil {
+			return fmt.Errorf("通知渠道 %s 配置错误: %w", channel.Type, err)
+		}
+		if channel.Type == "webhook" {
+			if _, err := parseWebhookSchemaVersion(channel.Config); err != nil {
+				return fmt.Errorf("通知渠道 %s 配置错误: %w", channel.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+// httpClient 返回当前投递使用的 HTTP 客户端，共享连接池，超时和代理取自渠道配置
+func (n *Notifier) httpClient(ctx context.Context) *http.Client {
+	timeout := defaultNotifyTimeout
+	var proxy *url.URL
+	if tracker := deliveryTrackerFrom(ctx); tracker != nil {
+		if tracker.timeout > 0 {
+			timeout = tracker.timeout
+		}
+		proxy = tracker.proxy
+	}
+	return &http.Client{
+		Transport: n.transports.get(proxy),
+		Timeout:   timeout,
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNotifyHTTPOptions(t *testing.T) {
+	timeout, proxy, err := parseNotifyHTTPOptions(map[string]interface{}{})
+	if err != nil || timeout != defaultNotifyTimeout || proxy != nil {
+		t.Fatalf("defaults: timeout=%v proxy=%v err=%v", timeout, proxy, err)
+	}
+
+	timeout, proxy, err = parseNotifyHTTPOptions(map[string]interface{}{
+		"timeoutSeconds": float64(3),
+		"proxy":          "socks5://127.0.0.1:1080",
+	})
+	if err != nil || timeout != 3*time.Second || proxy == nil || proxy.Host != "127.0.0.1:1080" {
+		t.Fatalf("configured: timeout=%v proxy=%v err=%v", timeout, proxy, err)
+	}
+
+	if timeout, _, _ := parseNotifyHTTPOptions(map[string]interface{}{"timeoutSeconds": float64(3600)}); timeout != maxNotifyTimeout {
+		t.Fatalf("timeout should be capped, got %v", timeout)
+	}
+
+	for _, config := range []map[string]interface{}{
+		{"timeoutSeconds": float64(0)},
+		{"timeoutSeconds": float64(-5)},
+		{"proxy": "ftp://proxy.example"},
+		{"proxy": "not a url"},
+	} {
+		if _, _, err := parseNotifyHTTPOptions(config); err == nil {
+			t.Fatalf("expected error for %v", config)
+		}
+	}
+}
+
+func TestNotifyTransportsShared(t *testing.T) {
+	var transports notifyTransports
+	if transports.get(nil) != transports.get(nil) {
+		t.Fatal("direct transport should be reused")
+	}
+	_, proxy, _ := parseNotifyHTTPOptions(map[string]interface{}{"proxy": "http://127.0.0.1:3128"})
+	if transports.get(proxy) == transports.get(nil) {
+		t.Fatal("proxied transport should differ from the direct one")
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+func TestMaskIPAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		mode models.IPMaskMode
+		want string
+	}{
+		{"192.168.1.100", models.IPMaskNone, "192.168.1.100"},
+		{"192.168.1.100", models.IPMaskPartial, "192.168.1.*"},
+		{"192.168.1.100", models.IPMaskFull, "*.*.*.*"},
+		{"2001:db8:1:2:3:4:5:6", models.IPMaskNone, "2001:db8:1:2:3:4:5:6"},
+		{"2001:db8:1:2:3:4:5:6", models.IPMaskPartial, "2001:db8:1:2:*:*:*:*"},
+		{"2001:db8::1", models.IPMaskPartial, "2001:db8:0:0:*:*:*:*"},
+		{"2001:db8:1:2:3:4:5:6", models.IPMaskFull, "*:*:*:*:*:*:*:*"},
+		{"not-an-ip", models.IPMaskPartial, "****"},
+		{"", models.IPMaskFull, ""},
+	}
+
+	for _, tt := range tests {
+		if got := maskIPAddress(tt.ip, tt.mode); got != tt.want {
+			t.Errorf("maskIPAddress(%q, %q) = %q，期望 %q", tt.ip, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMaskIPMode(t *testing.T) {
+	if got := (models.AlertConfig{MaskIP: true}).ResolveMaskIPMode(); got != models.IPMaskPartial {
+		t.Errorf("旧配置 MaskIP=true 应为 partial，实际为 %q", got)
+	}
+	if got := (models.AlertConfig{}).ResolveMaskIPMode(); got != models.IPMaskNone {
+		t.Errorf("未配置时应为 none，实际为 %q", got)
+	}
+	if got := (models.AlertConfig{MaskIP: true, MaskIPMode: models.IPMaskPartial}).ResolveMaskIPMode(); got != models.IPMaskPartial {
+		t.Errorf("MaskIPMode 应优先于 MaskIP，实际为 %q", got)
+	}
+}
+
+func TestJoinAgentIPs(t *testing.T) {
+	tests := []struct {
+		ip, ipv4, ipv6 string
+		want           string
+	}{
+		{"1.2.3.4", "1.2.3.4", "2001:db8::1", "IPv4 1.2.3.4 / IPv6 2001:db8::1"},
+		{"10.0.0.1", "1.2.3.4", "", "10.0.0.1 / IPv4 1.2.3.4"},
+		{"2001:db8::1", "", "2001:db8::1", "IPv6 2001:db8::1"},
+		{"1.2.3.4", "", "", "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		if got := joinAgentIPs(tt.ip, tt.ipv4, tt.ipv6); got != tt.want {
+			t.Errorf("joinAgentIPs(%q, %q, %q) = %q，期望 %q", tt.ip, tt.ipv4, tt.ipv6, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDDNSIPs(t *testing.T) {
+	agent := &models.Agent{IPv4: "1.2.3.4", IPv6: "2001:db8::1"}
+
+	// 放错字段的地址按实际地址族归类
+	got := resolveDDNSIPs(&protocol.DDNSIPReportData{IPv4: "2001:db8::2", IPv6: "5.6.7.8"}, nil)
+	if got.IPv4 != "5.6.7.8" || got.IPv6 != "2001:db8::2" {
+		t.Errorf("地址族归类错误: %+v", got)
+	}
+
+	// 未上报的地址族使用探针记录的公网地址
+	got = resolveDDNSIPs(&protocol.DDNSIPReportData{IPv4: "5.6.7.8"}, agent)
+	if got.IPv4 != "5.6.7.8" || got.IPv6 != "2001:db8::1" {
+		t.Errorf("未使用探针记录的 IPv6: %+v", got)
+	}
+
+	// 无效地址被忽略
+	got = resolveDDNSIPs(&protocol.DDNSIPReportData{IPv4: "invalid"}, nil)
+	if got.IPv4 != "" || got.IPv6 != "" {
+		t.Errorf("无效地址应被忽略: %+v", got)
+	}
+}
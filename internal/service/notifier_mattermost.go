@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+type mattermostAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type mattermostAttachment struct {
+	Fallback string                      `json:"fallback"`
+	Color    string                      `json:"color"`
+	Title    string                      `json:"title"`
+	Text     string                      `json:"text,omitempty"`
+	Fields   []mattermostAttachmentField `json:"fields,omitempty"`
+	Footer   string                      `json:"footer,omitempty"`
+	Ts       int64                       `json:"ts,omitempty"`
+}
+
+type mattermostPayload struct {
+	Channel     string                 `json:"channel,omitempty"`
+	Username    string                 `json:"username,omitempty"`
+	Text        string                 `json:"text,omitempty"`
+	Attachments []mattermostAttachment `json:"attachments,omitempty"`
+}
+
+// buildMattermostAttachment 构建告警附件
+func (n *Notifier) buildMattermostAttachment(agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) mattermostAttachment {
+	card := buildAlertCard(agent, record, maskMode)
+
+	fields := make([]mattermostAttachmentField, 0, len(card.Fields))
+	for _, field := range card.Fields {
+		fields = append(fields, mattermostAttachmentField{
+			Title: field.Name,
+			Value: field.Value,
+			Short: field.Inline,
+		})
+	}
+
+	return mattermostAttachment{
+		Fallback: fmt.Sprintf("%s: %s", card.Title, card.Text),
+		Color:    fmt.Sprintf("#%06X", card.Color),
+		Title:    card.Title,
+		Text:     card.Text,
+		Fields:   fields,
+		Footer:   "Pika",
+		Ts:       card.EventTime.Unix(),
+	}
+}
+
+// mattermostWebhookConfig 解析 Mattermost 配置
+func mattermostWebhookConfig(config map[string]interface{}) (webhookURL, channel string, err error) {
+	webhookURL, ok := config["webhookUrl"].(string)
+	if !ok || webhookURL == "" {
+		return "", "", fmt.Errorf("Mattermost 配置缺少 webhookUrl")
+	}
+	channel, _ = config["channel"].(string)
+	return webhookURL, channel, nil
+}
+
+// sendMattermostByConfig 根据配置发送 Mattermost 告警通知
+func (n *Notifier) sendMattermostByConfig(ctx context.Context, config map[string]interface{}, agent *models.Agent, record *models.AlertRecord, maskMode models.IPMaskMode) error {
+	webhookURL, channel, err := mattermostWebhookConfig(config)
+	if err != nil {
+		return err
+	}
+
+	payload := mattermostPayload{
+		Channel:     channel,
+		Username:    "Pika",
+		Attachments: []mattermostAttachment{n.buildMattermostAttachment(agent, record, maskMode)},
+	}
+	_, err = n.sendJSONRequest(ctx, webhookURL, payload)
+	return err
+}
+
+// sendMattermostMessageByConfig 根据配置发送 Mattermost 纯文本消息
+func (n *Notifier) sendMattermostMessageByConfig(ctx context.Context, config map[string]interface{}, message string) error {
+	webhookURL, channel, err := mattermostWebhookConfig(config)
+	if err != nil {
+		return err
+	}
+
+	payload := mattermostPayload{
+		Channel:  channel,
+		Username: "Pika",
+		Text:     message,
+	}
+	_, err = n.sendJSONRequest(ctx, webhookURL, payload)
+	return err
+}
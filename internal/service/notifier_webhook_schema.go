@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+const (
+	// WebhookSchemaVersion 当前的 Webhook 请求体结构版本，只在不兼容变更（删除、重命名字段或改变字段类型）时递增
+	WebhookSchemaVersion = 1
+	// webhookSchemaVersionHeader 请求头中的结构版本，自定义请求体模板也会携带
+	webhookSchemaVersionHeader = "X-Pika-Schema-Version"
+)
+
+// webhookPayloadV1 结构版本 1 的默认请求体，同一版本内只会追加字段
+type webhookPayloadV1 struct {
+	SchemaVersion int                      `json:"schemaVersion"`
+	Category      string                   `json:"category"` // agent 探针告警 / monitor 监控项告警
+	Message       string                   `json:"message"`  // 渲染后的通知文本
+	Alert         webhookAlertV1           `json:"alert"`
+	Agent         webhookAgentV1           `json:"agent"`
+	Monitor       *webhookMonitorContextV1 `json:"monitor,omitempty"` // 仅服务下线、证书告警
+}
+
+type webhookAlertV1 struct {
+	ID          int64   `json:"id"`
+	Type        string  `json:"type"`
+	Level       string  `json:"level"`
+	Status      string  `json:"status"`
+	Message     string  `json:"message"`
+	Threshold   float64 `json:"threshold"`
+	ActualValue float64 `json:"actualValue"`
+	FiredAt     int64   `json:"firedAt"`    // 时间戳毫秒
+	ResolvedAt  int64   `json:"resolvedAt"` // 时间戳毫秒，未恢复时为 0
+}
+
+type webhookAgentV1 struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	IPv4     string `json:"ipv4"`
+	IPv6     string `json:"ipv6"`
+}
+
+type webhookMonitorContextV1 struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Target       string `json:"target"`
+	Status       string `json:"status"`
+	StatusCode   int    `json:"statusCode"`
+	ResponseTime int64  `json:"responseTime"` // 毫秒
+	Error        string `json:"error"`
+	Downtime     int64  `json:"downtime"` // 秒
+	CertDaysLeft int    `json:"certDaysLeft"`
+}
+
+// parseWebhookSchemaVersion 解析渠道固定的结构版本（schemaVersion），未配置时使用当前版本
+func parseWebhookSchemaVersion(config map[string]interface{}) (int, error) {
+	version := WebhookSchemaVersion
+	switch v := config["schemaVersion"].(type) {
+	case nil:
+	case float64:
+		version = int(v)
+		if float64(version) != v {
+			return 0, fmt.Errorf("schemaVersion 必须为整数")
+		}
+	case int:
+		version = v
+	default:
+		return 0, fmt.Errorf("schemaVersion 必须为整数")
+	}
+	if version < 1 || version > WebhookSchemaVersion {
+		return 0, fmt.Errorf("不支持的 schemaVersion: %d，当前支持 1-%d", version, WebhookSchemaVersion)
+	}
+	return version, nil
+}
+
+// buildWebhookPayload 按结构版本构建默认请求体，IP 按通知脱敏设置处理
+func buildWebhookPayload(version int, agent *models.Agent, record *models.AlertRecord, message string, maskMode models.IPMaskMode) (any, error) {
+	switch version {
+	case 1:
+		payload := webhookPayloadV1{
+			SchemaVersion: 1,
+			Category:      alertEventCategory(record),
+			Message:       message,
+			Alert: webhookAlertV1{
+				ID:          record.ID,
+				Type:        record.AlertType,
+				Level:       record.Level,
+				Status:      record.Status,
+				Message:     record.Message,
+				Threshold:   record.Threshold,
+				ActualValue: record.ActualValue,
+				FiredAt:     record.FiredAt,
+				ResolvedAt:  record.ResolvedAt,
+			},
+			Agent: webhookAgentV1{
+				ID:       agent.ID,
+				Name:     agent.Name,
+				Hostname: agent.Hostname,
+				IP:       maskIPAddress(agent.IP, maskMode),
+				IPv4:     maskIPAddress(agent.IPv4, maskMode),
+				IPv6:     maskIPAddress(agent.IPv6, maskMode),
+			},
+		}
+		if m := record.Monitor; m != nil {
+			payload.Monitor = &webhookMonitorContextV1{
+				ID:           m.ID,
+				Name:         m.Name,
+				Type:         m.Type,
+				Target:       m.Target,
+				Status:       m.Status,
+				StatusCode:   m.StatusCode,
+				ResponseTime: m.ResponseTime,
+				Error:        m.Error,
+				Downtime:     m.Downtime,
+				CertDaysLeft: m.CertDaysLeft,
+			}
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("不支持的 schemaVersion: %d", version)
+	}
+}
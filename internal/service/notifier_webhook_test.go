@@ -0,0 +1,81 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestBuildCustomBodyMonitorVariables(t *testing.T) {
+	n := NewNotifier(zap.NewNop())
+	agent := &models.Agent{ID: "a1", Name: "node-1"}
+	template := `{"category":"{{event.category}}","monitor":"{{monitor.name}}","target":"{{monitor.target}}","rt":"{{monitor.responseTime}}","downtime":"{{monitor.downtime}}","unknown":"{{foo.bar}}"}`
+
+	tests := []struct {
+		name   string
+		record *models.AlertRecord
+		want   string
+	}{
+		{
+			name:   "探针告警",
+			record: &models.AlertRecord{AlertType: "cpu"},
+			want:   `{"category":"agent","monitor":"","target":"","rt":"","downtime":"","unknown":""}`,
+		},
+		{
+			name: "监控项告警",
+			record: &models.AlertRecord{AlertType: "service", Monitor: &models.MonitorAlertContext{
+				Name:         "官网",
+				Target:       "https://example.com",
+				ResponseTime: 120,
+				Downtime:     300,
+			}},
+			want: `{"category":"monitor","monitor":"官网","target":"https://example.com","rt":"120","downtime":"300","unknown":""}`,
+		},
+	}
+
+	for _, tt := range tests {
+		body, err := n.buildCustomBody(agent, tt.record, "", template, WebhookSchemaVersion, models.IPMaskNone)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, _ := io.ReadAll(body)
+		if string(got) != tt.want {
+			t.Errorf("%s: 请求体 = %s，期望 %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 结构版本 1 的请求体在同一版本内只能追加字段，修改已有字段需要递增版本
+func TestBuildWebhookPayloadV1(t *testing.T) {
+	agent := &models.Agent{ID: "a1", Name: "node-1", Hostname: "host", IPv4: "10.0.0.8"}
+	record := &models.AlertRecord{ID: 7, AlertType: "cpu", Level: "warning", Status: "firing", Message: "CPU 过高", Threshold: 80, ActualValue: 91.5, FiredAt: 1000}
+
+	payload, err := buildWebhookPayload(1, agent, record, "msg", models.IPMaskPartial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(payload)
+	want := `{"schemaVersion":1,"category":"agent","message":"msg",` +
+		`"alert":{"id":7,"type":"cpu","level":"warning","status":"firing","message":"CPU 过高","threshold":80,"actualValue":91.5,"firedAt":1000,"resolvedAt":0},` +
+		`"agent":{"id":"a1","name":"node-1","hostname":"host","ip":"","ipv4":"10.0.0.*","ipv6":""}}`
+	if string(got) != want {
+		t.Fatalf("请求体 = %s，期望 %s", got, want)
+	}
+}
+
+func TestParseWebhookSchemaVersion(t *testing.T) {
+	if v, err := parseWebhookSchemaVersion(map[string]interface{}{}); err != nil || v != WebhookSchemaVersion {
+		t.Fatalf("未配置时应使用当前版本，得到 %d, %v", v, err)
+	}
+	if v, err := parseWebhookSchemaVersion(map[string]interface{}{"schemaVersion": float64(1)}); err != nil || v != 1 {
+		t.Fatalf("应使用固定的版本，得到 %d, %v", v, err)
+	}
+	for _, invalid := range []interface{}{float64(0), float64(WebhookSchemaVersion + 1), 1.5, "1"} {
+		if _, err := parseWebhookSchemaVersion(map[string]interface{}{"schemaVersion": invalid}); err == nil {
+			t.Errorf("schemaVersion %v 应报错", invalid)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -24,6 +26,17 @@ type OIDCService struct {
 	stateStore   map[string]time.Time // 简单的 state 存储（生产环境应使用 Redis 等）
 }
 
+// defaultOIDCGroupsClaim 默认的用户组 claim 名称
+const defaultOIDCGroupsClaim = "groups"
+
+// OIDCIdentity OIDC 认证后的用户身份
+type OIDCIdentity struct {
+	Username string
+	Nickname string
+	Groups   []string
+	Role     string
+}
+
 // NewOIDCService 创建 OIDC 服务
 func NewOIDCService(logger *zap.Logger, appConfig *config.AppConfig) *OIDCService {
 	if appConfig.OIDC == nil || !appConfig.OIDC.Enabled {
@@ -89,7 +102,7 @@ func (s *OIDCService) IsEnabled() bool {
 // GenerateAuthURL 生成认证 URL
 func (s *OIDCService) GenerateAuthURL() (string, string, error) {
 	if !s.IsEnabled() {
-		return "", "", errors.New("OIDC 未启用")
+		return "", "", ErrOIDCDisabled
 	}
 
 	// 生成随机 state
@@ -109,14 +122,14 @@ func (s *OIDCService) GenerateAuthURL() (string, string, error) {
 }
 
 // ExchangeCode 交换授权码获取 token 和用户信息
-func (s *OIDCService) ExchangeCode(ctx context.Context, code, state string) (string, string, error) {
+func (s *OIDCService) ExchangeCode(ctx context.Context, code, state string) (*OIDCIdentity, error) {
 	if !s.IsEnabled() {
-		return "", "", errors.New("OIDC 未启用")
+		return nil, ErrOIDCDisabled
 	}
 
 	// 验证 state
 	if !s.validateState(state) {
-		return "", "", errors.New("无效的 state")
+		return nil, errors.New("无效的 state")
 	}
 
 	// 删除已使用的 state
@@ -125,19 +138,19 @@ func (s *OIDCService) ExchangeCode(ctx context.Context, code, state string) (str
 	// 交换授权码
 	oauth2Token, err := s.oauth2Config.Exchange(ctx, code)
 	if err != nil {
-		return "", "", fmt.Errorf("交换授权码失败: %w", err)
+		return nil, fmt.Errorf("交换授权码失败: %w", err)
 	}
 
 	// 提取 ID Token
 	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
 	if !ok {
-		return "", "", errors.New("未获取到 ID Token")
+		return nil, errors.New("未获取到 ID Token")
 	}
 
 	// 验证 ID Token
 	idToken, err := s.verifier.Verify(ctx, rawIDToken)
 	if err != nil {
-		return "", "", fmt.Errorf("验证 ID Token 失败: %w", err)
+		return nil, fmt.Errorf("验证 ID Token 失败: %w", err)
 	}
 
 	// 提取用户信息
@@ -149,8 +162,15 @@ func (s *OIDCService) ExchangeCode(ctx context.Context, code, state string) (str
 	}
 
 	if err := idToken.Claims(&claims); err != nil {
-		return "", "", fmt.Errorf("解析 claims 失败: %w", err)
+		return nil, fmt.Errorf("解析 claims 失败: %w", err)
+	}
+
+	// 用户组 claim 名称因 IdP 而异，单独按原始 JSON 解析
+	var rawClaims map[string]interface{}
+	if err := idToken.Claims(&rawClaims); err != nil {
+		return nil, fmt.Errorf("解析 claims 失败: %w", err)
 	}
+	groups := extractGroupsClaim(rawClaims, s.groupsClaim())
 
 	// 确定用户标识（优先使用 email，其次 preferred_username，最后使用 subject）
 	username := claims.Email
@@ -166,12 +186,105 @@ func (s *OIDCService) ExchangeCode(ctx context.Context, code, state string) (str
 		nickname = username
 	}
 
+	// 检查用户组白名单
+	if !s.isGroupAllowed(groups) {
+		s.logger.Warn("OIDC 用户不在允许的用户组中",
+			zap.String("username", username),
+			zap.Strings("groups", groups))
+		return nil, errors.New("用户不在允许登录的用户组中")
+	}
+
+	role := s.resolveRole(groups)
+
 	s.logger.Info("OIDC 认证成功",
 		zap.String("username", username),
 		zap.String("nickname", nickname),
-		zap.String("subject", idToken.Subject))
+		zap.String("subject", idToken.Subject),
+		zap.Strings("groups", groups),
+		zap.String("role", role))
+
+	return &OIDCIdentity{
+		Username: username,
+		Nickname: nickname,
+		Groups:   groups,
+		Role:     role,
+	}, nil
+}
+
+// groupsClaim 获取用户组 claim 名称
+func (s *OIDCService) groupsClaim() string {
+	if s.config.GroupsClaim == "" {
+		return defaultOIDCGroupsClaim
+	}
+	return s.config.GroupsClaim
+}
 
-	return username, nickname, nil
+// isGroupAllowed 检查用户组是否在白名单中
+func (s *OIDCService) isGroupAllowed(groups []string) bool {
+	// 如果未配置白名单，则允许所有用户
+	if len(s.config.AllowedGroups) == 0 {
+		return true
+	}
+	return containsAnyGroup(groups, s.config.AllowedGroups)
+}
+
+// resolveRole 根据用户组映射角色
+// 未配置 AdminGroups 时保持原有行为，除 ViewerGroups 成员外均为管理员；
+// 配置了 AdminGroups 后，未命中的用户按最小权限处理为只读
+func (s *OIDCService) resolveRole(groups []string) string {
+	if containsAnyGroup(groups, s.config.AdminGroups) {
+		return RoleAdmin
+	}
+	if containsAnyGroup(groups, s.config.ViewerGroups) {
+		return RoleViewer
+	}
+	if len(s.config.AdminGroups) == 0 {
+		return RoleAdmin
+	}
+	return RoleViewer
+}
+
+// containsAnyGroup 判断 groups 中是否有任一用户组在 targets 中
+func containsAnyGroup(groups, targets []string) bool {
+	for _, group := range groups {
+		if slices.Contains(targets, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractGroupsClaim 从 claims 中提取用户组
+// 支持 a.b 形式的嵌套路径（如 Keycloak 的 realm_access.roles），claim 值可以是字符串数组或单个字符串
+func extractGroupsClaim(claims map[string]interface{}, path string) []string {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = m[key]; !ok {
+			return nil
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		groups := make([]string, 0, len(v))
+		for _, item := range v {
+			if group, ok := item.(string); ok && group != "" {
+				groups = append(groups, group)
+			}
+		}
+		return groups
+	default:
+		return nil
+	}
 }
 
 // generateState 生成随机 state
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希算法
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// argon2id 默认参数
+const (
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 1
+	defaultArgon2Parallelism = 2
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+var (
+	ErrPasswordMismatch          = errors.New("密码不匹配")
+	ErrUnknownPasswordHash       = errors.New("无法识别的密码哈希算法")
+	errInvalidArgon2PasswordHash = errors.New("无效的 argon2id 密码哈希")
+)
+
+// PasswordHasher 密码哈希算法
+type PasswordHasher interface {
+	// Hash 计算密码哈希
+	Hash(password string) (string, error)
+	// Compare 校验密码与哈希是否匹配
+	Compare(hash, password string) error
+}
+
+// passwordAlgorithm 根据哈希前缀识别算法，无法识别时返回空
+func passwordAlgorithm(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return PasswordAlgorithmArgon2id
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return PasswordAlgorithmBcrypt
+	default:
+		return ""
+	}
+}
+
+// bcryptHasher bcrypt 密码哈希
+type bcryptHasher struct {
+	cost int
+}
+
+func (h bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h bcryptHasher) Compare(hash, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// argon2idHasher argon2id 密码哈希，使用 PHC 格式：$argon2id$v=19$m=65536,t=1,p=2$<salt>$<hash>
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func (h argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Compare 使用哈希中记录的参数重新计算，参数调整后旧哈希仍可校验
+func (h argon2idHasher) Compare(hash, password string) error {
+	params, salt, key, err := parseArgon2Hash(hash)
+	if err != nil {
+		return err
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// parseArgon2Hash 解析 PHC 格式的 argon2id 哈希
+func parseArgon2Hash(hash string) (argon2idHasher, []byte, []byte, error) {
+	var params argon2idHasher
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordAlgorithmArgon2id {
+		return params, nil, nil, errInvalidArgon2PasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidArgon2PasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, errInvalidArgon2PasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2PasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidArgon2PasswordHash
+	}
+	return params, salt, key, nil
+}
+
+// passwordHashers 按默认算法计算新哈希，校验时根据哈希前缀选择算法，兼容旧的 bcrypt 哈希
+type passwordHashers struct {
+	algorithm string
+	argon2id  argon2idHasher
+	bcrypt    bcryptHasher
+}
+
+func (h *passwordHashers) Hash(password string) (string, error) {
+	if h.algorithm == PasswordAlgorithmBcrypt {
+		return h.bcrypt.Hash(password)
+	}
+	return h.argon2id.Hash(password)
+}
+
+func (h *passwordHashers) Compare(hash, password string) error {
+	switch passwordAlgorithm(hash) {
+	case PasswordAlgorithmArgon2id:
+		return h.argon2id.Compare(hash, password)
+	case PasswordAlgorithmBcrypt:
+		return h.bcrypt.Compare(hash, password)
+	default:
+		return ErrUnknownPasswordHash
+	}
+}
+
+// NeedsRehash 哈希不是默认算法，或 argon2id 参数与当前配置不同时需要重新计算
+func (h *passwordHashers) NeedsRehash(hash string) bool {
+	algorithm := passwordAlgorithm(hash)
+	if algorithm != h.algorithm {
+		return true
+	}
+	if algorithm == PasswordAlgorithmArgon2id {
+		params, _, _, err := parseArgon2Hash(hash)
+		return err != nil || params != h.argon2id
+	}
+	return false
+}
+
+// newPasswordHasher 根据配置创建密码哈希算法，默认使用 argon2id
+func newPasswordHasher(logger *zap.Logger, cfg *config.PasswordHashConfig) *passwordHashers {
+	hashers := &passwordHashers{
+		algorithm: PasswordAlgorithmArgon2id,
+		argon2id: argon2idHasher{
+			memory:      defaultArgon2Memory,
+			iterations:  defaultArgon2Iterations,
+			parallelism: defaultArgon2Parallelism,
+		},
+		bcrypt: bcryptHasher{cost: bcrypt.DefaultCost},
+	}
+	if cfg == nil {
+		return hashers
+	}
+
+	switch algorithm := strings.ToLower(cfg.Algorithm); algorithm {
+	case "":
+	case PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt:
+		hashers.algorithm = algorithm
+	default:
+		logger.Warn("不支持的密码哈希算法，使用 argon2id",
+			zap.String("algorithm", cfg.Algorithm))
+	}
+
+	if cfg.BcryptCost != 0 {
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			logger.Warn("bcrypt 计算成本超出范围，使用默认值",
+				zap.Int("cost", cfg.BcryptCost),
+				zap.Int("default", bcrypt.DefaultCost))
+		} else {
+			hashers.bcrypt.cost = cfg.BcryptCost
+		}
+	}
+
+	if cfg.Argon2Memory > 0 {
+		hashers.argon2id.memory = uint32(cfg.Argon2Memory)
+	}
+	if cfg.Argon2Iterations > 0 {
+		hashers.argon2id.iterations = uint32(cfg.Argon2Iterations)
+	}
+	if cfg.Argon2Parallelism > 0 && cfg.Argon2Parallelism <= 255 {
+		hashers.argon2id.parallelism = uint8(cfg.Argon2Parallelism)
+	}
+	return hashers
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dushixiang/pika/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashersVerifyBothAlgorithms(t *testing.T) {
+	hashers := newPasswordHasher(zap.NewNop(), &config.PasswordHashConfig{Argon2Memory: 1024})
+
+	hash, err := hashers.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=2$") {
+		t.Fatalf("default hash = %q, want argon2id", hash)
+	}
+	if err := hashers.Compare(hash, "secret-password"); err != nil {
+		t.Errorf("argon2id compare: %v", err)
+	}
+	if err := hashers.Compare(hash, "wrong-password"); err == nil {
+		t.Error("argon2id compare with wrong password should fail")
+	}
+	if hashers.NeedsRehash(hash) {
+		t.Error("hash with current parameters should not need rehash")
+	}
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	if err := hashers.Compare(string(legacy), "secret-password"); err != nil {
+		t.Errorf("legacy bcrypt compare: %v", err)
+	}
+	if !hashers.NeedsRehash(string(legacy)) {
+		t.Error("legacy bcrypt hash should need rehash to argon2id")
+	}
+
+	if err := hashers.Compare("plain-text", "plain-text"); err == nil {
+		t.Error("unknown hash format should not verify")
+	}
+}
+
+func TestPasswordHashersArgon2ParameterChange(t *testing.T) {
+	old := newPasswordHasher(zap.NewNop(), &config.PasswordHashConfig{Argon2Memory: 1024})
+	hash, err := old.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	current := newPasswordHasher(zap.NewNop(), &config.PasswordHashConfig{Argon2Memory: 2048})
+	if err := current.Compare(hash, "secret-password"); err != nil {
+		t.Errorf("hash with old parameters should still verify: %v", err)
+	}
+	if !current.NeedsRehash(hash) {
+		t.Error("hash with old parameters should need rehash")
+	}
+}
+
+func TestPasswordHashersBcryptDefault(t *testing.T) {
+	hashers := newPasswordHasher(zap.NewNop(), &config.PasswordHashConfig{Algorithm: "bcrypt", BcryptCost: bcrypt.MinCost})
+	hash, err := hashers.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if passwordAlgorithm(hash) != PasswordAlgorithmBcrypt {
+		t.Fatalf("hash = %q, want bcrypt", hash)
+	}
+	if hashers.NeedsRehash(hash) {
+		t.Error("bcrypt hash should not need rehash when bcrypt is the default")
+	}
+}
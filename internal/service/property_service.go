@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
 	"github.com/dushixiang/pika/internal/repo"
 	"github.com/dushixiang/pika/pkg/version"
 	"github.com/dushixiang/pika/web"
@@ -30,6 +34,24 @@ const (
 	PropertyIDDNSProviders = "dns_providers"
 	// PropertyIDAgentInstallConfig 探针安装配置的固定 ID
 	PropertyIDAgentInstallConfig = "agent_install_config"
+	// PropertyIDGroupBranding 分组品牌配置的固定 ID
+	PropertyIDGroupBranding = "group_branding"
+	// PropertyIDCustomChecks 自定义检查配置的固定 ID
+	PropertyIDCustomChecks = "custom_checks"
+	// PropertyIDMetricPolicies 探针指标采集策略的固定 ID（按探针ID索引）
+	PropertyIDMetricPolicies = "metric_policies"
+	// PropertyIDArchiveConfig 告警记录归档配置的固定 ID
+	PropertyIDArchiveConfig = "archive_config"
+	// PropertyIDArchiveMetricsProgress 指标归档进度的固定 ID
+	PropertyIDArchiveMetricsProgress = "archive_metrics_progress"
+	// PropertyIDDerivedMetrics 自定义派生指标
+	PropertyIDDerivedMetrics = "derived_metrics"
+	// PropertyIDAgentCleanupConfig 长期离线探针自动清理配置的固定 ID
+	PropertyIDAgentCleanupConfig = "agent_cleanup_config"
+	// PropertyIDIngestDownsampling 入库降采样配置的固定 ID
+	PropertyIDIngestDownsampling = "ingest_downsampling"
+	// PropertyIDAlertRuleTemplates 告警规则模板的固定 ID
+	PropertyIDAlertRuleTemplates = "alert_rule_templates"
 )
 
 var defaultPublicIPv4APIs = []string{
@@ -48,17 +70,19 @@ var defaultPublicIPv6APIs = []string{
 }
 
 type PropertyService struct {
-	repo   *repo.PropertyRepo
-	logger *zap.Logger
+	repo               *repo.PropertyRepo
+	ConfigAuditLogRepo *repo.ConfigAuditLogRepo
+	logger             *zap.Logger
 	// 内存缓存，使用 go-orz/cache，永不过期
 	cache cache.Cache[string, *models.Property]
 }
 
 func NewPropertyService(logger *zap.Logger, db *gorm.DB) *PropertyService {
 	return &PropertyService{
-		repo:   repo.NewPropertyRepo(db),
-		logger: logger,
-		cache:  cache.New[string, *models.Property](time.Minute), // 0 表示永不过期
+		repo:               repo.NewPropertyRepo(db),
+		ConfigAuditLogRepo: repo.NewConfigAuditLogRepo(db),
+		logger:             logger,
+		cache:              cache.New[string, *models.Property](time.Minute), // 0 表示永不过期
 	}
 }
 
@@ -103,6 +127,12 @@ func (s *PropertyService) Set(ctx context.Context, id string, name string, value
 		return err
 	}
 
+	// 记录变更前的值，用于审计
+	var before string
+	if old, err := s.repo.FindById(ctx, id); err == nil {
+		before = old.Value
+	}
+
 	property := &models.Property{
 		ID:        id,
 		Name:      name,
@@ -119,6 +149,8 @@ func (s *PropertyService) Set(ctx context.Context, id string, name string, value
 	// 清空缓存中的该项，下次读取时会重新从数据库加载
 	s.cache.Delete(id)
 
+	s.recordConfigAudit(ctx, id, name, before, property.Value)
+
 	return nil
 }
 
@@ -139,9 +171,56 @@ func (s *PropertyService) GetSystemConfig(ctx context.Context) (*models.SystemCo
 	}
 	// 设置系统版本
 	systemConfig.Version = version.Version
+	if len(systemConfig.MetricCards) == 0 {
+		systemConfig.MetricCards = models.DefaultMetricCards
+	}
 	return &systemConfig, nil
 }
 
+// ValidateMetricCards 校验指标卡片配置：只允许已知的指标类型且不能重复
+func ValidateMetricCards(cards []string) error {
+	seen := make(map[string]struct{}, len(cards))
+	for _, card := range cards {
+		if !slices.Contains(models.DefaultMetricCards, card) {
+			return fmt.Errorf("未知的指标卡片: %s，可选值: %s", card, strings.Join(models.DefaultMetricCards, ", "))
+		}
+		if _, ok := seen[card]; ok {
+			return fmt.Errorf("指标卡片重复: %s", card)
+		}
+		seen[card] = struct{}{}
+	}
+	return nil
+}
+
+// GetGroupBranding 获取分组品牌配置（分组 -> 覆盖配置）
+func (s *PropertyService) GetGroupBranding(ctx context.Context) (map[string]models.BrandingOverride, error) {
+	branding := make(map[string]models.BrandingOverride)
+	if err := s.GetValue(ctx, PropertyIDGroupBranding, &branding); err != nil {
+		return nil, fmt.Errorf("获取分组品牌配置失败: %w", err)
+	}
+	return branding, nil
+}
+
+// ResolveSystemConfig 获取指定分组生效的系统配置，分组未配置覆盖时使用全局配置
+func (s *PropertyService) ResolveSystemConfig(ctx context.Context, group string) (*models.SystemConfig, error) {
+	systemConfig, err := s.GetSystemConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if group == "" {
+		return systemConfig, nil
+	}
+
+	branding, err := s.GetGroupBranding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if override, ok := branding[group]; ok {
+		override.Apply(systemConfig)
+	}
+	return systemConfig, nil
+}
+
 // GetPublicIPConfig 获取公网 IP 采集配置
 func (s *PropertyService) GetPublicIPConfig(ctx context.Context) (*models.PublicIPConfig, error) {
 	var config models.PublicIPConfig
@@ -152,6 +231,62 @@ func (s *PropertyService) GetPublicIPConfig(ctx context.Context) (*models.Public
 	return &config, nil
 }
 
+// GetAgentCleanupConfig 获取长期离线探针自动清理配置
+func (s *PropertyService) GetAgentCleanupConfig(ctx context.Context) (*models.AgentCleanupConfig, error) {
+	var config models.AgentCleanupConfig
+	if err := s.GetValue(ctx, PropertyIDAgentCleanupConfig, &config); err != nil {
+		return nil, fmt.Errorf("获取探针自动清理配置失败: %w", err)
+	}
+	applyAgentCleanupConfigDefaults(&config)
+	return &config, nil
+}
+
+func applyAgentCleanupConfigDefaults(config *models.AgentCleanupConfig) {
+	if config.OfflineDays <= 0 {
+		config.OfflineDays = 30
+	}
+	if config.GraceHours <= 0 {
+		config.GraceHours = 24
+	}
+}
+
+// GetIngestDownsamplingConfig 获取入库降采样配置
+func (s *PropertyService) GetIngestDownsamplingConfig(ctx context.Context) (*models.IngestDownsamplingConfig, error) {
+	var config models.IngestDownsamplingConfig
+	if err := s.GetValue(ctx, PropertyIDIngestDownsampling, &config); err != nil {
+		return nil, fmt.Errorf("获取入库降采样配置失败: %w", err)
+	}
+	return &config, nil
+}
+
+// GetArchiveConfig 获取告警记录归档配置
+func (s *PropertyService) GetArchiveConfig(ctx context.Context) (*models.ArchiveConfig, error) {
+	var config models.ArchiveConfig
+	if err := s.GetValue(ctx, PropertyIDArchiveConfig, &config); err != nil {
+		return nil, fmt.Errorf("获取归档配置失败: %w", err)
+	}
+	applyArchiveConfigDefaults(&config)
+	return &config, nil
+}
+
+func applyArchiveConfigDefaults(config *models.ArchiveConfig) {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Format != models.ArchiveFormatNDJSON {
+		config.Format = models.ArchiveFormatJSON
+	}
+	if config.ArchiveAfterDays <= 0 {
+		config.ArchiveAfterDays = 7
+	}
+	if config.IntervalMinutes <= 0 {
+		config.IntervalMinutes = 60
+	}
+	if config.MetricsInterval <= 0 {
+		config.MetricsInterval = defaultArchiveMetricsInterval
+	}
+}
+
 // GetAlertConfig 获取告警配置
 func (s *PropertyService) GetAlertConfig(ctx context.Context) (*models.AlertConfig, error) {
 	property, err := s.Get(ctx, PropertyIDAlertConfig)
@@ -167,6 +302,7 @@ func (s *PropertyService) GetAlertConfig(ctx context.Context) (*models.AlertConf
 	}
 
 	applyAlertNotificationDefaults(&config, property.Value)
+	applyAlertEscalationDefaults(&config.Escalation)
 
 	return &config, nil
 }
@@ -330,6 +466,49 @@ func (s *PropertyService) SetAgentInstallConfig(ctx context.Context, config mode
 	return s.Set(ctx, PropertyIDAgentInstallConfig, "探针安装配置", config)
 }
 
+// GetCustomChecks 获取自定义检查列表
+func (s *PropertyService) GetCustomChecks(ctx context.Context) ([]models.CustomCheck, error) {
+	var checks []models.CustomCheck
+	if err := s.GetValue(ctx, PropertyIDCustomChecks, &checks); err != nil {
+		return nil, fmt.Errorf("获取自定义检查配置失败: %w", err)
+	}
+	for i := range checks {
+		checks[i].Normalize()
+	}
+	return checks, nil
+}
+
+// SetCustomChecks 设置自定义检查列表
+func (s *PropertyService) SetCustomChecks(ctx context.Context, checks []models.CustomCheck) error {
+	return s.Set(ctx, PropertyIDCustomChecks, "自定义检查配置", checks)
+}
+
+// GetMetricPolicies 获取所有探针的指标采集策略
+func (s *PropertyService) GetMetricPolicies(ctx context.Context) (map[string]protocol.MetricPolicyData, error) {
+	policies := make(map[string]protocol.MetricPolicyData)
+	if err := s.GetValue(ctx, PropertyIDMetricPolicies, &policies); err != nil {
+		return nil, fmt.Errorf("获取指标采集策略失败: %w", err)
+	}
+	return policies, nil
+}
+
+// SetMetricPolicies 设置所有探针的指标采集策略
+func (s *PropertyService) SetMetricPolicies(ctx context.Context, policies map[string]protocol.MetricPolicyData) error {
+	return s.Set(ctx, PropertyIDMetricPolicies, "指标采集策略", policies)
+}
+
+// GetDerivedMetrics 获取自定义派生指标
+func (s *PropertyService) GetDerivedMetrics(ctx context.Context) ([]models.DerivedMetric, error) {
+	var metrics []models.DerivedMetric
+	if err := s.GetValue(ctx, PropertyIDDerivedMetrics, &metrics); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取派生指标失败: %w", err)
+	}
+	return metrics, nil
+}
+
 // defaultPropertyConfig 默认配置项定义
 type defaultPropertyConfig struct {
 	ID    string
@@ -350,6 +529,7 @@ func (s *PropertyService) InitializeDefaultConfigs(ctx context.Context) error {
 				LogoBase64:   web.DefaultLogoBase64(),
 				ICPCode:      "",
 				DefaultView:  "grid",
+				MetricCards:  models.DefaultMetricCards,
 			},
 		},
 		{
@@ -384,24 +564,37 @@ func (s *PropertyService) InitializeDefaultConfigs(ctx context.Context) error {
 					TamperEventEnabled:     true,
 				},
 				Rules: models.AlertRules{
-					CPUEnabled:           true,
-					CPUThreshold:         80,
-					CPUDuration:          300, // 5分钟
-					MemoryEnabled:        true,
-					MemoryThreshold:      80,
-					MemoryDuration:       300, // 5分钟
-					DiskEnabled:          true,
-					DiskThreshold:        85,
-					DiskDuration:         300, // 5分钟
-					NetworkEnabled:       false,
-					NetworkThreshold:     100,
-					NetworkDuration:      300, // 5分钟
-					CertEnabled:          true,
-					CertThreshold:        30, // 30天
-					ServiceEnabled:       true,
-					ServiceDuration:      300, // 5分钟
-					AgentOfflineEnabled:  true,
-					AgentOfflineDuration: 300, // 5分钟
+					CPUEnabled:          true,
+					CPUThreshold:        80,
+					CPUDuration:         300, // 5分钟
+					MemoryEnabled:       true,
+					MemoryThreshold:     80,
+					MemoryDuration:      300, // 5分钟
+					DiskEnabled:         true,
+					DiskThreshold:       85,
+					DiskDuration:        300, // 5分钟
+					DiskPredictEnabled:  false,
+					DiskPredictHorizon:  48, // 48小时
+					NetworkEnabled:      false,
+					NetworkThreshold:    100,
+					NetworkDuration:     300, // 5分钟
+					LoadEnabled:         false,
+					LoadThreshold:       1.5,
+					LoadDuration:        300, // 5分钟
+					ConnectionEnabled:   false,
+					ConnectionThreshold: 10000,
+					ConnectionDuration:  300, // 5分钟
+					ConnectionStates: []models.ConnectionStateRule{
+						{State: "closeWait", Threshold: 500},
+					},
+					CertEnabled:            true,
+					CertThreshold:          30, // 30天
+					ServiceEnabled:         true,
+					ServiceDuration:        300, // 5分钟
+					AgentOfflineEnabled:    true,
+					AgentOfflineDuration:   300, // 5分钟
+					CollectorErrorEnabled:  true,
+					CollectorErrorDuration: 600, // 10分钟
 				},
 			},
 		},
@@ -410,11 +603,70 @@ func (s *PropertyService) InitializeDefaultConfigs(ctx context.Context) error {
 			Name:  "DNS 服务商配置",
 			Value: []models.DNSProviderConfig{}, // 默认为空数组
 		},
+		{
+			ID:    PropertyIDCustomChecks,
+			Name:  "自定义检查配置",
+			Value: []models.CustomCheck{}, // 默认为空数组
+		},
+		{
+			ID:    PropertyIDMetricPolicies,
+			Name:  "指标采集策略",
+			Value: map[string]protocol.MetricPolicyData{}, // 默认不限制
+		},
+		{
+			ID:    PropertyIDDerivedMetrics,
+			Name:  "派生指标",
+			Value: []models.DerivedMetric{}, // 默认只使用内置派生指标
+		},
 		{
 			ID:    PropertyIDAgentInstallConfig,
 			Name:  "探针安装配置",
 			Value: models.AgentInstallConfig{ServerURL: ""}, // 默认空字符串，使用自动检测
 		},
+		{
+			ID:    PropertyIDGroupBranding,
+			Name:  "分组品牌配置",
+			Value: map[string]models.BrandingOverride{}, // 默认无分组覆盖
+		},
+		{
+			ID:    PropertyIDAlertRuleTemplates,
+			Name:  "告警规则模板",
+			Value: []models.AlertRuleTemplate{}, // 默认无模板
+		},
+		{
+			ID:    PropertyIDIngestDownsampling,
+			Name:  "入库降采样配置",
+			Value: models.IngestDownsamplingConfig{Enabled: false, Rules: []models.IngestDownsamplingRule{}},
+		},
+		{
+			ID:   PropertyIDAgentCleanupConfig,
+			Name: "探针自动清理配置",
+			Value: models.AgentCleanupConfig{
+				Enabled:         false,
+				OfflineDays:     30,
+				GraceHours:      24,
+				ExcludeAgentIDs: []string{},
+				ExcludeTags:     []string{},
+			},
+		},
+		{
+			ID:   PropertyIDArchiveConfig,
+			Name: "告警记录归档配置",
+			Value: models.ArchiveConfig{
+				Enabled:          false,
+				Region:           "us-east-1",
+				Prefix:           "pika",
+				Format:           models.ArchiveFormatJSON,
+				ArchiveAfterDays: 7,
+				IntervalMinutes:  60,
+				MetricsInterval:  defaultArchiveMetricsInterval,
+			},
+		},
+		{
+			ID:    PropertyIDArchiveMetricsProgress,
+			Name:  "指标归档进度",
+			Value: ArchiveMetricsProgress{},
+		},
 	}
 
 	// 遍历并初始化每个配置
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// defaultPublicIPAPITimeoutSeconds 未配置超时的 API（旧版字符串列表）使用的超时
+const defaultPublicIPAPITimeoutSeconds = 10
+
+// ValidatePublicIPConfig 校验公网 IP 采集配置中的 API 列表
+func ValidatePublicIPConfig(config *models.PublicIPConfig) error {
+	if err := validatePublicIPAPIs("IPv4", config.IPv4APIEntries); err != nil {
+		return err
+	}
+	return validatePublicIPAPIs("IPv6", config.IPv6APIEntries)
+}
+
+func validatePublicIPAPIs(family string, entries []models.PublicIPAPI) error {
+	for _, entry := range entries {
+		url := strings.TrimSpace(entry.URL)
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("%s API 地址无效: %q", family, entry.URL)
+		}
+		if entry.TimeoutSeconds <= 0 {
+			return fmt.Errorf("%s API %s 的超时时间必须为正数", family, url)
+		}
+	}
+	return nil
+}
+
+// orderPublicIPAPIs 按优先级排列 API，优先级相同时保持配置顺序；
+// 未配置带优先级的列表时按旧版字符串列表的顺序使用默认超时
+func orderPublicIPAPIs(entries []models.PublicIPAPI, legacy []string) []protocol.PublicIPAPIEntry {
+	if len(entries) == 0 {
+		result := make([]protocol.PublicIPAPIEntry, 0, len(legacy))
+		for _, url := range legacy {
+			result = append(result, protocol.PublicIPAPIEntry{
+				URL:            url,
+				TimeoutSeconds: defaultPublicIPAPITimeoutSeconds,
+			})
+		}
+		return result
+	}
+
+	sorted := append([]models.PublicIPAPI(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	result := make([]protocol.PublicIPAPIEntry, 0, len(sorted))
+	for _, entry := range sorted {
+		result = append(result, protocol.PublicIPAPIEntry{
+			URL:            strings.TrimSpace(entry.URL),
+			TimeoutSeconds: entry.TimeoutSeconds,
+		})
+	}
+	return result
+}
+
+// BuildPublicIPConfigData 构建下发给探针的公网 IP 采集配置
+func BuildPublicIPConfigData(config *models.PublicIPConfig, ipv4Enabled, ipv6Enabled bool) protocol.PublicIPConfigData {
+	ipv4 := orderPublicIPAPIs(config.IPv4APIEntries, config.IPv4APIs)
+	ipv6 := orderPublicIPAPIs(config.IPv6APIEntries, config.IPv6APIs)
+	return protocol.PublicIPConfigData{
+		Enabled:         config.Enabled,
+		IntervalSeconds: config.IntervalSeconds,
+		IPv4Enabled:     ipv4Enabled,
+		IPv6Enabled:     ipv6Enabled,
+		IPv4APIs:        publicIPAPIURLs(ipv4),
+		IPv6APIs:        publicIPAPIURLs(ipv6),
+		IPv4APIEntries:  ipv4,
+		IPv6APIEntries:  ipv6,
+	}
+}
+
+// publicIPAPIURLs 提取有序的 API 地址，供不支持超时配置的旧版客户端使用
+func publicIPAPIURLs(entries []protocol.PublicIPAPIEntry) []string {
+	urls := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		urls = append(urls, entry.URL)
+	}
+	return urls
+}
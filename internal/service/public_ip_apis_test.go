@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/models"
+)
+
+func TestOrderPublicIPAPIs(t *testing.T) {
+	entries := []models.PublicIPAPI{
+		{URL: "https://a.example", Priority: 10, TimeoutSeconds: 5},
+		{URL: "https://internal.example", Priority: 0, TimeoutSeconds: 2},
+		{URL: "https://b.example", Priority: 10, TimeoutSeconds: 8},
+	}
+	got := orderPublicIPAPIs(entries, []string{"https://legacy.example"})
+	want := []string{"https://internal.example", "https://a.example", "https://b.example"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i, url := range want {
+		if got[i].URL != url {
+			t.Fatalf("entry %d = %s, want %s", i, got[i].URL, url)
+		}
+	}
+	if got[0].TimeoutSeconds != 2 {
+		t.Fatalf("timeout = %d, want 2", got[0].TimeoutSeconds)
+	}
+
+	legacy := orderPublicIPAPIs(nil, []string{"https://legacy.example"})
+	if len(legacy) != 1 || legacy[0].TimeoutSeconds != defaultPublicIPAPITimeoutSeconds {
+		t.Fatalf("legacy entries = %+v", legacy)
+	}
+}
+
+func TestValidatePublicIPConfig(t *testing.T) {
+	valid := &models.PublicIPConfig{
+		IPv4APIEntries: []models.PublicIPAPI{{URL: "https://internal.example", TimeoutSeconds: 3}},
+	}
+	if err := ValidatePublicIPConfig(valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, entry := range []models.PublicIPAPI{
+		{URL: "https://internal.example", TimeoutSeconds: 0},
+		{URL: "https://internal.example", TimeoutSeconds: -1},
+		{URL: "internal.example", TimeoutSeconds: 3},
+	} {
+		config := &models.PublicIPConfig{IPv6APIEntries: []models.PublicIPAPI{entry}}
+		if err := ValidatePublicIPConfig(config); err == nil {
+			t.Fatalf("expected error for %+v", entry)
+		}
+	}
+}
@@ -87,14 +87,7 @@ func (s *PublicIPService) sendConfigToOnlineAgents(config *models.PublicIPConfig
 
 		msgData, err := json.Marshal(protocol.OutboundMessage{
 			Type: protocol.MessageTypePublicIPConfig,
-			Data: protocol.PublicIPConfigData{
-				Enabled:         config.Enabled,
-				IntervalSeconds: config.IntervalSeconds,
-				IPv4Enabled:     ipv4Enabled,
-				IPv6Enabled:     ipv6Enabled,
-				IPv4APIs:        config.IPv4APIs,
-				IPv6APIs:        config.IPv6APIs,
-			},
+			Data: BuildPublicIPConfigData(config, ipv4Enabled, ipv6Enabled),
 		})
 		if err != nil {
 			s.logger.Error("构建公网 IP 配置消息失败", zap.Error(err))
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/config"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/repo"
+	"github.com/go-orz/orz"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// shareTokenAudience 分享令牌的 audience，用于和登录令牌区分
+const shareTokenAudience = "pika-share"
+
+// maxShareTokenTTL 分享令牌最长有效期
+const maxShareTokenTTL = 365 * 24 * time.Hour
+
+// ShareTokenClaims 分享令牌声明，携带允许访问的探针范围，验证时无需查询数据库
+type ShareTokenClaims struct {
+	AgentIDs []string `json:"agentIds,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ShareScope 分享令牌授予的只读访问范围
+type ShareScope struct {
+	TokenID  string
+	AgentIDs []string
+	Tags     []string
+}
+
+// Allows 判断探针是否在分享范围内（指定的探针或包含任一指定标签）
+func (s *ShareScope) Allows(agent *models.Agent) bool {
+	if slices.Contains(s.AgentIDs, agent.ID) {
+		return true
+	}
+	for _, tag := range agent.Tags {
+		if slices.Contains(s.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateShareTokenResult 创建分享令牌的结果，令牌字符串只在创建时返回
+type CreateShareTokenResult struct {
+	models.ShareToken
+	Token string `json:"token"`
+}
+
+type ShareTokenService struct {
+	logger         *zap.Logger
+	ShareTokenRepo *repo.ShareTokenRepo
+	signingKey     []byte
+
+	// 已吊销令牌ID -> 过期时间（时间戳毫秒）
+	mu      sync.RWMutex
+	revoked map[string]int64
+}
+
+func NewShareTokenService(logger *zap.Logger, db *gorm.DB, appConfig *config.AppConfig) *ShareTokenService {
+	// 从 JWT 密钥派生独立的签名密钥，避免分享令牌被当作登录令牌使用
+	key := sha256.Sum256([]byte("share-token:" + appConfig.JWT.Secret))
+	return &ShareTokenService{
+		logger:         logger,
+		ShareTokenRepo: repo.NewShareTokenRepo(db),
+		signingKey:     key[:],
+		revoked:        make(map[string]int64),
+	}
+}
+
+// LoadRevoked 从数据库加载尚未过期的已吊销令牌
+func (s *ShareTokenService) LoadRevoked(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	tokens, err := s.ShareTokenRepo.FindRevokedUnexpired(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range tokens {
+		s.revoked[token.ID] = token.ExpiresAt
+	}
+	return nil
+}
+
+// Create 创建分享令牌
+func (s *ShareTokenService) Create(ctx context.Context, name, createdBy string, agentIDs, tags []string, ttl time.Duration) (*CreateShareTokenResult, error) {
+	if len(agentIDs) == 0 && len(tags) == 0 {
+		return nil, orz.NewError(400, "请至少指定一个探针或标签")
+	}
+	if ttl <= 0 || ttl > maxShareTokenTTL {
+		return nil, orz.NewError(400, "有效期必须在 1 小时到 365 天之间")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	record := models.ShareToken{
+		ID:        uuid.NewString(),
+		Name:      name,
+		AgentIDs:  agentIDs,
+		Tags:      tags,
+		ExpiresAt: expiresAt.UnixMilli(),
+		CreatedBy: createdBy,
+		CreatedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+	}
+
+	claims := ShareTokenClaims{
+		AgentIDs: agentIDs,
+		Tags:     tags,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        record.ID,
+			Audience:  jwt.ClaimStrings{shareTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ShareTokenRepo.Create(ctx, &record); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("创建分享令牌",
+		zap.String("tokenId", record.ID),
+		zap.String("name", name),
+		zap.Strings("agentIds", agentIDs),
+		zap.Strings("tags", tags),
+		zap.Time("expiresAt", expiresAt))
+
+	return &CreateShareTokenResult{ShareToken: record, Token: token}, nil
+}
+
+// List 列出所有分享令牌
+func (s *ShareTokenService) List(ctx context.Context) ([]models.ShareToken, error) {
+	return s.ShareTokenRepo.ListAll(ctx)
+}
+
+// Revoke 吊销分享令牌，立即加入吊销列表
+func (s *ShareTokenService) Revoke(ctx context.Context, id string) error {
+	token, err := s.ShareTokenRepo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if token.RevokedAt > 0 {
+		return nil
+	}
+
+	if err := s.ShareTokenRepo.UpdateRevokedAt(ctx, id, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.revoked[id] = token.ExpiresAt
+	s.mu.Unlock()
+
+	s.logger.Info("吊销分享令牌", zap.String("tokenId", id), zap.String("name", token.Name))
+	return nil
+}
+
+// Validate 验证分享令牌并返回访问范围，仅校验签名、有效期和吊销列表
+func (s *ShareTokenService) Validate(tokenString string) (*ShareScope, error) {
+	claims := &ShareTokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("无效的签名方法")
+		}
+		return s.signingKey, nil
+	}, jwt.WithAudience(shareTokenAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.isRevoked(claims.ID) {
+		return nil, errors.New("分享令牌已吊销")
+	}
+
+	return &ShareScope{
+		TokenID:  claims.ID,
+		AgentIDs: claims.AgentIDs,
+		Tags:     claims.Tags,
+	}, nil
+}
+
+// isRevoked 判断令牌是否已吊销，顺便清理已过期的吊销记录
+func (s *ShareTokenService) isRevoked(id string) bool {
+	s.mu.RLock()
+	expiresAt, ok := s.revoked[id]
+	s.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	if expiresAt <= time.Now().UnixMilli() {
+		s.mu.Lock()
+		delete(s.revoked, id)
+		s.mu.Unlock()
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dushixiang/pika/internal/config"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func newTestShareTokenService() *ShareTokenService {
+	cfg := &config.AppConfig{}
+	cfg.JWT.Secret = "0123456789abcdef0123456789abcdef"
+	return NewShareTokenService(zap.NewNop(), nil, cfg)
+}
+
+func signShareToken(t *testing.T, key []byte, claims ShareTokenClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	return token
+}
+
+func TestShareTokenValidate(t *testing.T) {
+	s := newTestShareTokenService()
+	claims := ShareTokenClaims{
+		AgentIDs: []string{"a1"},
+		Tags:     []string{"vendor"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "t1",
+			Audience:  jwt.ClaimStrings{shareTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	scope, err := s.Validate(signShareToken(t, s.signingKey, claims))
+	if err != nil {
+		t.Fatalf("有效令牌验证失败: %v", err)
+	}
+	if !scope.Allows(&models.Agent{ID: "a1"}) || !scope.Allows(&models.Agent{ID: "a2", Tags: []string{"vendor"}}) {
+		t.Error("范围内的探针应允许访问")
+	}
+	if scope.Allows(&models.Agent{ID: "a3", Tags: []string{"internal"}}) {
+		t.Error("范围外的探针不应允许访问")
+	}
+
+	// 使用登录令牌的密钥签名的令牌不能作为分享令牌
+	if _, err := s.Validate(signShareToken(t, []byte("0123456789abcdef0123456789abcdef"), claims)); err == nil {
+		t.Error("使用其他密钥签名的令牌应验证失败")
+	}
+
+	expired := claims
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	if _, err := s.Validate(signShareToken(t, s.signingKey, expired)); err == nil {
+		t.Error("过期令牌应验证失败")
+	}
+
+	s.revoked["t1"] = time.Now().Add(time.Hour).UnixMilli()
+	if _, err := s.Validate(signShareToken(t, s.signingKey, claims)); err == nil {
+		t.Error("已吊销令牌应验证失败")
+	}
+}
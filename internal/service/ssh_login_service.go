@@ -195,8 +195,8 @@ func (s *SSHLoginService) sendLoginSuccessNotification(agentID string, eventData
 
 	sourceIP := eventData.IP
 	if s.notificationSvc != nil {
-		if maskIP, err := s.notificationSvc.IsMaskIPEnabled(context.Background()); err == nil && maskIP {
-			sourceIP = maskIPAddress(sourceIP)
+		if maskMode, err := s.notificationSvc.GetMaskIPMode(context.Background()); err == nil {
+			sourceIP = maskIPAddress(sourceIP, maskMode)
 		}
 	}
 
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/vmclient"
+	"go.uber.org/zap"
+)
+
+// storageStatsDefaultLookback 未配置保留时间时，指标统计的回溯窗口
+const storageStatsDefaultLookback = 31 * 24 * time.Hour
+
+// storageStatsTimeColumns 数据表用于统计最早/最新时间的字段，未列出的表存在 created_at 时使用该字段
+var storageStatsTimeColumns = map[string]string{
+	"agent_connection_events": "timestamp",
+	"agent_ip_histories":      "changed_at",
+	"alert_records":           "fired_at",
+	"alert_states":            "updated_at",
+	"tamper_events":           "timestamp",
+	"disk_forecasts":          "updated_at",
+	"ssh_login_events":        "timestamp",
+	"properties":              "",
+	"user_credentials":        "",
+}
+
+// GetStorageStats 获取全部数据表的行数、占用空间以及各指标类型的序列数和时间范围
+// 数据表从数据库中读取，新增的表无需维护列表；单个表或指标类型统计失败时在该项的 error 中返回，不影响其他项
+func (s *MetricService) GetStorageStats(ctx context.Context) (*models.StorageStats, error) {
+	stats := &models.StorageStats{
+		Dialect: s.storageStatsRepo.Dialect(),
+	}
+
+	databaseBytes, err := s.storageStatsRepo.DatabaseSize(ctx)
+	if err != nil {
+		s.logger.Warn("获取数据库占用空间失败", zap.Error(err))
+	}
+	stats.DatabaseBytes = databaseBytes
+
+	tables, err := s.storageStatsRepo.Tables(ctx)
+	if err != nil {
+		s.logger.Error("获取数据表列表失败", zap.Error(err))
+		return nil, err
+	}
+	for _, table := range tables {
+		stats.Tables = append(stats.Tables, s.getTableStats(ctx, table))
+	}
+
+	stats.Metrics = s.getMetricTypeStats(ctx)
+	return stats, nil
+}
+
+// getTableStats 统计单个数据表，失败时记录原因
+func (s *MetricService) getTableStats(ctx context.Context, name string) models.TableStats {
+	table := models.TableStats{Table: name}
+	timeColumn, ok := storageStatsTimeColumns[name]
+	if !ok && s.storageStatsRepo.HasColumn(ctx, name, "created_at") {
+		timeColumn = "created_at"
+	}
+	table.TimeColumn = timeColumn
+
+	rows, err := s.storageStatsRepo.CountRows(ctx, name)
+	if err != nil {
+		s.logger.Warn("统计数据表行数失败", zap.String("table", name), zap.Error(err))
+		table.Error = err.Error()
+		return table
+	}
+	table.Rows = rows
+
+	size, err := s.storageStatsRepo.TableSize(ctx, name)
+	if err != nil {
+		s.logger.Warn("获取数据表占用空间失败", zap.String("table", name), zap.Error(err))
+	}
+	table.SizeBytes = size
+
+	if timeColumn != "" && rows > 0 {
+		oldest, newest, err := s.storageStatsRepo.TimeRange(ctx, name, timeColumn)
+		if err != nil {
+			s.logger.Warn("统计数据表时间范围失败", zap.String("table", name), zap.Error(err))
+			table.Error = err.Error()
+			return table
+		}
+		table.OldestAt = oldest
+		table.NewestAt = newest
+	}
+	return table
+}
+
+// getMetricTypeStats 统计各指标类型在 VictoriaMetrics 中的序列数和最早/最新样本时间
+func (s *MetricService) getMetricTypeStats(ctx context.Context) []models.MetricTypeStats {
+	lookback := storageStatsDefaultLookback
+	if s.extendedRetention > lookback {
+		lookback = s.extendedRetention
+	}
+	window := fmt.Sprintf("%dh", int64(lookback/time.Hour))
+
+	queryValue := func(query string) (float64, error) {
+		result, err := s.vmClient.Query(ctx, query)
+		if err != nil {
+			s.logger.Error("查询指标存储统计失败", zap.String("query", query), zap.Error(err))
+			return 0, err
+		}
+		points := vmclient.ConvertToDataPoints(result)
+		if len(points) == 0 {
+			return 0, nil
+		}
+		return points[0].Value, nil
+	}
+
+	stats := make([]models.MetricTypeStats, 0, len(coverageMetrics))
+	for _, item := range coverageMetrics {
+		stat := models.MetricTypeStats{
+			Type:   string(item.Type),
+			Metric: item.Metric,
+		}
+
+		if err := fillMetricTypeStats(&stat, item.Metric, window, queryValue); err != nil {
+			stat.Error = err.Error()
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// fillMetricTypeStats 查询单个指标类型的序列数和最早/最新样本时间
+func fillMetricTypeStats(stat *models.MetricTypeStats, metricName, window string, queryValue func(string) (float64, error)) error {
+	series, err := queryValue(fmt.Sprintf(`count(last_over_time(%s[%s]))`, metricName, window))
+	if err != nil {
+		return err
+	}
+	stat.Series = int64(series)
+	if stat.Series == 0 {
+		return nil
+	}
+
+	// tfirst_over_time/tlast_over_time 返回窗口内首个/最后一个样本的时间戳（秒）
+	oldest, err := queryValue(fmt.Sprintf(`min(tfirst_over_time(%s[%s]))`, metricName, window))
+	if err != nil {
+		return err
+	}
+	newest, err := queryValue(fmt.Sprintf(`max(tlast_over_time(%s[%s]))`, metricName, window))
+	if err != nil {
+		return err
+	}
+	stat.OldestAt = int64(oldest * 1000)
+	stat.NewestAt = int64(newest * 1000)
+	return nil
+}
@@ -3,45 +3,137 @@ package service
 import (
 	"context"
 	"errors"
+	"unicode/utf8"
 
 	"github.com/dushixiang/pika/internal/config"
+	"github.com/dushixiang/pika/internal/models"
+	"github.com/dushixiang/pika/internal/repo"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// minPasswordLength 修改密码时新密码的最小长度
+const minPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrPasswordTooShort   = errors.New("新密码长度不能少于8位")
 )
 
 // UserService User 认证服务
 type UserService struct {
-	logger *zap.Logger
-	users  map[string]string // 用户名 -> bcrypt加密的密码
+	logger         *zap.Logger
+	users          map[string]string // 用户名 -> 密码哈希（bcrypt 或 argon2id）
+	hasher         *passwordHashers
+	credentialRepo *repo.UserCredentialRepo
 }
 
 // NewUserService 创建 User 服务
-func NewUserService(logger *zap.Logger, appConfig *config.AppConfig) *UserService {
+func NewUserService(logger *zap.Logger, db *gorm.DB, appConfig *config.AppConfig) *UserService {
 	return &UserService{
-		logger: logger,
-		users:  appConfig.Users,
+		logger:         logger,
+		users:          appConfig.Users,
+		hasher:         newPasswordHasher(logger, appConfig.PasswordHash),
+		credentialRepo: repo.NewUserCredentialRepo(db),
 	}
 }
 
+// passwordHash 获取用户的密码哈希，数据库中修改过的密码优先于配置文件
+// rehashedFrom 为该密码对应的配置文件哈希，通过接口修改的密码为空；登录时升级算法得到的哈希在配置文件中的密码变更后失效
+func (s *UserService) passwordHash(ctx context.Context, username string) (hash, rehashedFrom string, exists bool) {
+	configuredHash, exists := s.users[username]
+	if !exists {
+		return "", "", false
+	}
+
+	credential, err := s.credentialRepo.FindByUsername(ctx, username)
+	if err != nil {
+		s.logger.Error("查询用户密码失败", zap.String("username", username), zap.Error(err))
+	}
+	if credential != nil && credential.PasswordHash != "" {
+		if credential.RehashedFrom == "" || credential.RehashedFrom == configuredHash {
+			return credential.PasswordHash, credential.RehashedFrom, true
+		}
+	}
+	return configuredHash, configuredHash, true
+}
+
 // ValidateCredentials 验证用户名和密码
 func (s *UserService) ValidateCredentials(ctx context.Context, username, password string) error {
-	// 从配置中获取用户的bcrypt密码哈希
-	hashedPassword, exists := s.users[username]
+	hashedPassword, rehashedFrom, exists := s.passwordHash(ctx, username)
 	if !exists {
 		s.logger.Debug("用户不存在", zap.String("username", username))
-		return errors.New("用户名或密码错误")
+		return ErrInvalidCredentials
 	}
 
 	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+	if err := s.hasher.Compare(hashedPassword, password); err != nil {
 		s.logger.Debug("密码验证失败", zap.String("username", username), zap.Error(err))
-		return errors.New("用户名或密码错误")
+		return ErrInvalidCredentials
+	}
+
+	if s.hasher.NeedsRehash(hashedPassword) {
+		s.rehashPassword(ctx, username, password, hashedPassword, rehashedFrom)
 	}
 
 	s.logger.Info("User 认证成功", zap.String("username", username))
 	return nil
 }
 
+// rehashPassword 登录成功后使用默认算法重新计算密码哈希，失败时不影响登录
+// 配置文件中的密码升级后记录原哈希，配置文件中的密码修改后重新使用配置文件
+func (s *UserService) rehashPassword(ctx context.Context, username, password, oldHash, rehashedFrom string) {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logger.Error("重新计算密码哈希失败", zap.String("username", username), zap.Error(err))
+		return
+	}
+
+	credential := &models.UserCredential{
+		Username:     username,
+		PasswordHash: hash,
+		Algorithm:    passwordAlgorithm(hash),
+		RehashedFrom: rehashedFrom,
+	}
+	if err := s.credentialRepo.SaveCredential(ctx, credential); err != nil {
+		s.logger.Error("保存升级后的密码哈希失败", zap.String("username", username), zap.Error(err))
+		return
+	}
+
+	s.logger.Info("用户密码哈希已升级",
+		zap.String("username", username),
+		zap.String("from", passwordAlgorithm(oldHash)),
+		zap.String("to", credential.Algorithm))
+}
+
+// ChangePassword 校验旧密码后修改密码，新密码保存到数据库，无需修改配置文件和重启
+func (s *UserService) ChangePassword(ctx context.Context, username, oldPassword, newPassword string) error {
+	if err := s.ValidateCredentials(ctx, username, oldPassword); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(newPassword) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	credential := &models.UserCredential{
+		Username:     username,
+		PasswordHash: hash,
+		Algorithm:    passwordAlgorithm(hash),
+	}
+	if err := s.credentialRepo.SaveCredential(ctx, credential); err != nil {
+		s.logger.Error("保存用户密码失败", zap.String("username", username), zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("用户密码已修改", zap.String("username", username))
+	return nil
+}
+
 // GetUsername 获取用户名（如果认证成功）
 func (s *UserService) GetUsername(ctx context.Context, username string) (string, error) {
 	if _, exists := s.users[username]; !exists {
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type traceContextKey struct{}
+
+// NewTraceID 生成新的追踪ID
+func NewTraceID() string {
+	return uuid.NewString()
+}
+
+// WithTraceID 将追踪ID写入上下文，id 为空时返回原上下文
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceContextKey{}, traceID)
+}
+
+// TraceIDFromContext 从上下文中获取追踪ID
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceContextKey{}).(string)
+	return traceID
+}
+
+// TraceField 返回上下文中追踪ID对应的日志字段
+func TraceField(ctx context.Context) zap.Field {
+	return zap.String("traceId", TraceIDFromContext(ctx))
+}
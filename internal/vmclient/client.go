@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,8 +23,15 @@ type VMClient struct {
 
 // QueryResult 查询结果
 type QueryResult struct {
-	Status string     `json:"status"`
-	Data   ResultData `json:"data"`
+	Status string      `json:"status"`
+	Data   ResultData  `json:"data"`
+	Stats  *QueryStats `json:"stats,omitempty"` // 查询统计，旧版本 VictoriaMetrics 不返回
+}
+
+// QueryStats VictoriaMetrics 返回的查询统计
+type QueryStats struct {
+	SeriesFetched     json.Number `json:"seriesFetched"`     // 扫描的时间序列数（以字符串返回）
+	ExecutionTimeMsec int64       `json:"executionTimeMsec"` // 服务端执行耗时（毫秒）
 }
 
 // ResultData 查询结果数据
@@ -34,7 +43,8 @@ type ResultData struct {
 // Result 单个时间序列结果
 type Result struct {
 	Metric map[string]string `json:"metric"`
-	Values [][]interface{}   `json:"values"` // [[timestamp, value], ...]
+	Values [][]interface{}   `json:"values"`          // [[timestamp, value], ...]
+	Value  []interface{}     `json:"value,omitempty"` // 即时查询结果 [timestamp, value]
 }
 
 // DataPoint 数据点
@@ -184,11 +194,19 @@ func (c *VMClient) QueryRange(ctx context.Context, query string, start, end time
 
 // Query 即时查询
 func (c *VMClient) Query(ctx context.Context, query string) (*QueryResult, error) {
+	return c.QueryAt(ctx, query, time.Time{})
+}
+
+// QueryAt 在指定时间点执行即时查询，at 为零值时使用当前时间
+func (c *VMClient) QueryAt(ctx context.Context, query string, at time.Time) (*QueryResult, error) {
 	reqCtx, cancel := context.WithTimeout(ctx, c.queryTimeout)
 	defer cancel()
 
 	params := url.Values{}
 	params.Set("query", query)
+	if !at.IsZero() {
+		params.Set("time", strconv.FormatFloat(float64(at.UnixMilli())/1000, 'f', 3, 64))
+	}
 
 	reqURL := fmt.Sprintf("%s/api/v1/query?%s", c.baseURL, params.Encode())
 
@@ -228,7 +246,11 @@ func ConvertToDataPoints(result *QueryResult) []DataPoint {
 
 	var points []DataPoint
 	for _, r := range result.Data.Result {
-		for _, v := range r.Values {
+		values := r.Values
+		if len(r.Value) > 0 {
+			values = append(values, r.Value)
+		}
+		for _, v := range values {
 			if len(v) < 2 {
 				continue
 			}
@@ -304,3 +326,90 @@ func (c *VMClient) GetLabelValues(ctx context.Context, labelName string, match [
 
 	return result.Data, nil
 }
+
+// Export 流式导出匹配序列的原始样本（VictoriaMetrics JSON Line Format），每解析一个序列回调一次
+// 导出可能持续较长时间，不使用客户端的整体超时，由 ctx 控制取消
+func (c *VMClient) Export(ctx context.Context, match string, start, end time.Time, fn func(metric Metric) error) error {
+	params := url.Values{}
+	params.Set("match[]", match)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+
+	reqURL := fmt.Sprintf("%s/api/v1/export?%s", c.baseURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+
+	client := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("export failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var metric Metric
+		if err := decoder.Decode(&metric); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("decode export failed: %w", err)
+		}
+		if err := fn(metric); err != nil {
+			return err
+		}
+	}
+}
+
+// Flags 获取 VictoriaMetrics 的启动参数（/flags），返回参数名（不含前缀 -）到取值的映射
+func (c *VMClient) Flags(ctx context.Context) (map[string]string, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, c.queryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", c.baseURL+"/flags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get flags failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get flags failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read flags failed: %w", err)
+	}
+	return parseFlags(string(body)), nil
+}
+
+// parseFlags 解析 /flags 的输出，每行格式为 -name="value"
+func parseFlags(text string) map[string]string {
+	flags := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-") {
+			continue
+		}
+		name, value, _ := strings.Cut(strings.TrimPrefix(line, "-"), "=")
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		flags[name] = value
+	}
+	return flags
+}
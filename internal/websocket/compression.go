@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 支持的压缩算法
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// maxDecompressedSize 解压后消息的最大字节数，防止压缩炸弹
+const maxDecompressedSize = 16 << 20
+
+var ErrMessageTooLarge = errors.New("decompressed message too large")
+
+// zstdDecoder 共享的 zstd 解码器，DecodeAll 可并发调用；限制解码内存防止压缩炸弹
+var zstdDecoder, _ = zstd.NewReader(nil,
+	zstd.WithDecoderConcurrency(0),
+	zstd.WithDecoderMaxMemory(maxDecompressedSize),
+)
+
+// NegotiateCompression 按探针的偏好顺序选择服务端支持的第一个压缩算法，都不支持时不压缩
+func NegotiateCompression(requested ...string) string {
+	for _, compression := range requested {
+		switch compression {
+		case CompressionZstd, CompressionGzip:
+			return compression
+		}
+	}
+	return CompressionNone
+}
+
+// decompress 按协商的算法解压消息
+func decompress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		out, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > maxDecompressedSize {
+			return nil, ErrMessageTooLarge
+		}
+		return out, nil
+	case CompressionZstd:
+		out, err := zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+				return nil, ErrMessageTooLarge
+			}
+			return nil, err
+		}
+		if len(out) > maxDecompressedSize {
+			return nil, ErrMessageTooLarge
+		}
+		return out, nil
+	default:
+		return data, nil
+	}
+}
+
+// compressionStats 压缩统计
+type compressionStats struct {
+	messages        atomic.Int64
+	compressedBytes atomic.Int64
+	rawBytes        atomic.Int64
+}
+
+func (s *compressionStats) record(compressed, raw int) {
+	s.messages.Add(1)
+	s.compressedBytes.Add(int64(compressed))
+	s.rawBytes.Add(int64(raw))
+}
+
+// CompressionStats 压缩统计快照
+type CompressionStats struct {
+	Messages        int64   `json:"messages"`        // 压缩消息数
+	CompressedBytes int64   `json:"compressedBytes"` // 压缩后字节数
+	RawBytes        int64   `json:"rawBytes"`        // 解压后字节数
+	AverageRatio    float64 `json:"averageRatio"`    // 平均压缩率（解压后/压缩后）
+}
@@ -3,48 +3,243 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/dushixiang/pika/internal/utils"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
 // Client WebSocket客户端
 type Client struct {
-	ID         string          // 探针ID
-	Conn       *websocket.Conn // WebSocket连接
-	Send       chan []byte     // 发送消息通道
-	Manager    *Manager        // 管理器引用
-	LastActive time.Time       // 最后活跃时间
-	closed     bool            // 标记channel是否已关闭
-	closeMu    sync.Mutex      // 保护closed字段
+	ID          string          // 探针ID
+	Conn        *websocket.Conn // WebSocket连接
+	Send        chan []byte     // 发送消息通道
+	Manager     *Manager        // 管理器引用
+	LastActive  time.Time       // 最后活跃时间
+	RemoteAddr  string          // 连接来源地址
+	Compression string          // 注册时协商的压缩算法，为空表示不压缩
+	closed      bool            // 标记channel是否已关闭
+	closeMu     sync.Mutex      // 保护closed字段
+
+	pingRTT    atomic.Int64 // 最近一次 Ping 往返时间（纳秒）
+	lastPongAt atomic.Int64 // 最近一次收到 Pong 的时间（毫秒）
 }
 
 // Manager WebSocket连接管理器
 type Manager struct {
 	clients    map[string]*Client // 客户端映射 probeID -> Client
-	register   chan *Client       // 注册通道
+	reserved   map[string]int     // 已通过连接数检查、尚未完成注册的探针 probeID -> 预留数
 	unregister chan *Client       // 注销通道
 	broadcast  chan []byte        // 广播通道
 	mu         sync.RWMutex       // 读写锁
 	logger     *zap.Logger        // 日志
 	onMessage  MessageHandler     // 消息处理器
+	onConnEvt  ConnectionHandler  // 连接事件处理器
+	compStats  compressionStats   // 压缩统计
+	options    Options            // 连接限制与背压配置
+
+	droppedFrames       atomic.Int64 // 因发送缓冲区满而丢弃的消息数
+	overflowDisconnects atomic.Int64 // 因发送缓冲区满而断开的连接数
+	rejectedConnections atomic.Int64 // 因超过最大连接数而拒绝的连接数
+}
+
+// 发送缓冲区溢出策略
+const (
+	OverflowDropOldest = "drop-oldest" // 丢弃最旧的消息
+	OverflowDisconnect = "disconnect"  // 断开客户端连接
+)
+
+// 心跳默认配置
+const (
+	defaultPingInterval = 30 * time.Second
+	defaultPongWait     = 60 * time.Second
+	defaultIdleTimeout  = 2 * time.Minute
+	writeWait           = 10 * time.Second
+)
+
+// Options 连接限制、背压与心跳配置
+type Options struct {
+	MaxConnections int           // 最大连接数，0 表示不限制
+	SendBufferSize int           // 每个客户端的发送缓冲区大小
+	OverflowPolicy string        // 发送缓冲区溢出策略: drop-oldest, disconnect
+	PingInterval   time.Duration // 心跳 Ping 间隔
+	PongWait       time.Duration // 等待 Pong 的超时时间，必须大于 Ping 间隔
+	IdleTimeout    time.Duration // 未收到任何消息的空闲超时
 }
 
 // MessageHandler 消息处理器接口
 type MessageHandler func(ctx context.Context, probeID string, messageType string, data json.RawMessage) error
 
+// 连接事件类型
+const (
+	EventConnect    = "connect"
+	EventDisconnect = "disconnect"
+)
+
+// ConnectionEvent 探针连接事件
+type ConnectionEvent struct {
+	AgentID    string
+	Event      string // connect, disconnect
+	RemoteAddr string
+	Timestamp  int64 // 毫秒
+}
+
+// ConnectionHandler 连接事件处理器
+type ConnectionHandler func(event ConnectionEvent)
+
 // NewManager 创建新的WebSocket管理器
-func NewManager(logger *zap.Logger) *Manager {
+func NewManager(logger *zap.Logger, options Options) *Manager {
+	if options.SendBufferSize <= 0 {
+		options.SendBufferSize = 256
+	}
+	if options.OverflowPolicy != OverflowDisconnect {
+		options.OverflowPolicy = OverflowDropOldest
+	}
+	if options.PingInterval <= 0 {
+		options.PingInterval = defaultPingInterval
+	}
+	if options.PongWait <= 0 {
+		options.PongWait = defaultPongWait
+	}
+	if options.PongWait <= options.PingInterval {
+		// Pong 超时不大于 Ping 间隔时，正常连接也会在两次 Ping 之间超时
+		logger.Warn("pong wait must be greater than ping interval, using twice the ping interval",
+			zap.Duration("pingInterval", options.PingInterval),
+			zap.Duration("pongWait", options.PongWait))
+		options.PongWait = 2 * options.PingInterval
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = defaultIdleTimeout
+	}
 	return &Manager{
 		clients:    make(map[string]*Client),
-		register:   make(chan *Client, 10),
+		reserved:   make(map[string]int),
 		unregister: make(chan *Client, 10),
 		broadcast:  make(chan []byte, 256),
 		logger:     logger,
+		options:    options,
+	}
+}
+
+// NewSendChannel 按配置创建客户端发送缓冲区
+func (m *Manager) NewSendChannel() chan []byte {
+	return make(chan []byte, m.options.SendBufferSize)
+}
+
+// Reserve 检查是否可以接受新连接并预留名额（已连接或正在注册的探针重连不受限制）
+// 预留的名额计入连接数，直到 Register 或 Release，避免并发注册时同时通过检查而超过最大连接数
+func (m *Manager) Reserve(probeID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.options.MaxConnections > 0 && !m.hasSlot(probeID) {
+		m.rejectedConnections.Add(1)
+		return false
+	}
+	m.reserved[probeID]++
+	return true
+}
+
+// hasSlot 调用方需持有写锁
+func (m *Manager) hasSlot(probeID string) bool {
+	if _, exists := m.clients[probeID]; exists {
+		return true
+	}
+	if _, exists := m.reserved[probeID]; exists {
+		return true
+	}
+	used := len(m.clients)
+	for id := range m.reserved {
+		if _, exists := m.clients[id]; !exists {
+			used++
+		}
+	}
+	return used < m.options.MaxConnections
+}
+
+// Release 释放 Reserve 预留的名额，注册失败时调用
+func (m *Manager) Release(probeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.releaseReservation(probeID)
+}
+
+// releaseReservation 调用方需持有写锁
+func (m *Manager) releaseReservation(probeID string) {
+	if m.reserved[probeID] <= 1 {
+		delete(m.reserved, probeID)
+		return
+	}
+	m.reserved[probeID]--
+}
+
+// ConnectionStats 连接统计
+type ConnectionStats struct {
+	Connected           int    `json:"connected"`           // 当前连接数
+	MaxConnections      int    `json:"maxConnections"`      // 最大连接数，0 表示不限制
+	SendBufferSize      int    `json:"sendBufferSize"`      // 每个客户端的发送缓冲区大小
+	OverflowPolicy      string `json:"overflowPolicy"`      // 发送缓冲区溢出策略
+	DroppedFrames       int64  `json:"droppedFrames"`       // 丢弃的消息数
+	OverflowDisconnects int64  `json:"overflowDisconnects"` // 因缓冲区溢出断开的连接数
+	RejectedConnections int64  `json:"rejectedConnections"` // 拒绝的连接数
+	PingInterval        int64  `json:"pingInterval"`        // 心跳 Ping 间隔（秒）
+	PongTimeout         int64  `json:"pongTimeout"`         // 等待 Pong 的超时时间（秒）
+	IdleTimeout         int64  `json:"idleTimeout"`         // 空闲超时（秒）
+}
+
+// ConnectionStats 获取连接统计
+func (m *Manager) ConnectionStats() ConnectionStats {
+	return ConnectionStats{
+		Connected:           m.ClientCount(),
+		MaxConnections:      m.options.MaxConnections,
+		SendBufferSize:      m.options.SendBufferSize,
+		OverflowPolicy:      m.options.OverflowPolicy,
+		DroppedFrames:       m.droppedFrames.Load(),
+		OverflowDisconnects: m.overflowDisconnects.Load(),
+		RejectedConnections: m.rejectedConnections.Load(),
+		PingInterval:        int64(m.options.PingInterval / time.Second),
+		PongTimeout:         int64(m.options.PongWait / time.Second),
+		IdleTimeout:         int64(m.options.IdleTimeout / time.Second),
+	}
+}
+
+// PingStat 探针心跳统计
+type PingStat struct {
+	RTT        float64 `json:"rtt"`        // 最近一次 Ping 往返时间（毫秒）
+	LastPongAt int64   `json:"lastPongAt"` // 最近一次收到 Pong 的时间（毫秒）
+}
+
+// PingStats 获取所有在线探针的心跳统计，尚未收到 Pong 的探针不包含在内
+func (m *Manager) PingStats() map[string]PingStat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[string]PingStat, len(m.clients))
+	for id, client := range m.clients {
+		if stat, ok := client.PingStat(); ok {
+			stats[id] = stat
+		}
 	}
+	return stats
+}
+
+// PingStat 获取客户端心跳统计
+func (c *Client) PingStat() (PingStat, bool) {
+	lastPongAt := c.lastPongAt.Load()
+	if lastPongAt == 0 {
+		return PingStat{}, false
+	}
+	return PingStat{
+		RTT:        float64(c.pingRTT.Load()) / float64(time.Millisecond),
+		LastPongAt: lastPongAt,
+	}, true
 }
 
 // SetMessageHandler 设置消息处理器
@@ -52,6 +247,25 @@ func (m *Manager) SetMessageHandler(handler MessageHandler) {
 	m.onMessage = handler
 }
 
+// SetConnectionHandler 设置连接事件处理器
+func (m *Manager) SetConnectionHandler(handler ConnectionHandler) {
+	m.onConnEvt = handler
+}
+
+// emitConnectionEvent 异步通知连接事件，避免阻塞管理器主循环
+func (m *Manager) emitConnectionEvent(client *Client, event string) {
+	if m.onConnEvt == nil {
+		return
+	}
+	evt := ConnectionEvent{
+		AgentID:    client.ID,
+		Event:      event,
+		RemoteAddr: client.RemoteAddr,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+	go m.onConnEvt(evt)
+}
+
 // Run 启动管理器
 func (m *Manager) Run(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
@@ -62,8 +276,6 @@ func (m *Manager) Run(ctx context.Context) {
 		case <-ctx.Done():
 			m.logger.Info("websocket manager stopped")
 			return
-		case client := <-m.register:
-			m.registerClient(client)
 		case client := <-m.unregister:
 			m.unregisterClient(client)
 		case message := <-m.broadcast:
@@ -74,24 +286,24 @@ func (m *Manager) Run(ctx context.Context) {
 	}
 }
 
-// Register 注册客户端（公开方法）
-func (m *Manager) Register(client *Client) {
-	m.register <- client
-}
-
-// registerClient 注册客户端
-func (m *Manager) registerClient(client *Client) {
+// Register 注册客户端，并释放注册前为 reservedID 预留的名额
+// 登记连接和释放预留在同一把锁内完成，期间其他连接的检查不会少算名额
+func (m *Manager) Register(client *Client, reservedID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.releaseReservation(reservedID)
+
 	// 如果已存在该探针的连接，先关闭旧连接
 	if oldClient, exists := m.clients[client.ID]; exists {
 		m.logger.Info("agent reconnected, closing old connection", zap.String("agentID", client.ID))
 		oldClient.closeChannel()
 		oldClient.Conn.Close()
+		m.emitConnectionEvent(oldClient, EventDisconnect)
 	}
 
 	m.clients[client.ID] = client
+	m.emitConnectionEvent(client, EventConnect)
 	m.logger.Info("agent connected", zap.String("agentID", client.ID), zap.Int("totalClients", len(m.clients)))
 }
 
@@ -100,9 +312,11 @@ func (m *Manager) unregisterClient(client *Client) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.clients[client.ID]; exists {
+	// 仅注销当前登记的连接，避免旧连接退出时误删重连后的新连接
+	if current, exists := m.clients[client.ID]; exists && current == client {
 		delete(m.clients, client.ID)
 		client.closeChannel()
+		m.emitConnectionEvent(client, EventDisconnect)
 		m.logger.Info("agent disconnected", zap.String("agentID", client.ID), zap.Int("totalClients", len(m.clients)))
 	}
 }
@@ -113,9 +327,7 @@ func (m *Manager) broadcastMessage(message []byte) {
 	defer m.mu.RUnlock()
 
 	for _, client := range m.clients {
-		select {
-		case client.Send <- message:
-		default:
+		if !client.enqueue(message) {
 			// 发送失败，客户端可能已断开
 			m.logger.Warn("failed to send message, client may be disconnected", zap.String("agentID", client.ID))
 		}
@@ -126,7 +338,7 @@ func (m *Manager) broadcastMessage(message []byte) {
 func (m *Manager) checkInactiveClients() {
 	m.mu.RLock()
 	inactiveClients := make([]*Client, 0)
-	timeout := 2 * time.Minute
+	timeout := m.options.IdleTimeout
 
 	for _, client := range m.clients {
 		if time.Since(client.LastActive) > timeout {
@@ -160,12 +372,10 @@ func (m *Manager) SendToClient(probeID string, message []byte) error {
 		return ErrClientNotFound
 	}
 
-	select {
-	case client.Send <- message:
-		return nil
-	default:
+	if !client.enqueue(message) {
 		return ErrSendTimeout
 	}
+	return nil
 }
 
 // GetClient 获取客户端
@@ -195,6 +405,19 @@ func (m *Manager) ClientCount() int {
 	return len(m.clients)
 }
 
+// CompressionStats 获取压缩统计
+func (m *Manager) CompressionStats() CompressionStats {
+	stats := CompressionStats{
+		Messages:        m.compStats.messages.Load(),
+		CompressedBytes: m.compStats.compressedBytes.Load(),
+		RawBytes:        m.compStats.rawBytes.Load(),
+	}
+	if stats.CompressedBytes > 0 {
+		stats.AverageRatio = float64(stats.RawBytes) / float64(stats.CompressedBytes)
+	}
+	return stats
+}
+
 // ReadPump 读取客户端消息
 func (c *Client) ReadPump(ctx context.Context) {
 	defer func() {
@@ -202,17 +425,27 @@ func (c *Client) ReadPump(ctx context.Context) {
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		c.LastActive = time.Now()
+	pongWait := c.Manager.options.PongWait
+	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetPongHandler(func(appData string) error {
+		now := time.Now()
+		c.Conn.SetReadDeadline(now.Add(pongWait))
+		c.LastActive = now
+		// Ping 携带发送时间，探针原样返回，据此计算往返时间
+		if sentAt, err := strconv.ParseInt(appData, 10, 64); err == nil {
+			c.pingRTT.Store(now.UnixNano() - sentAt)
+		}
+		c.lastPongAt.Store(now.UnixMilli())
 		return nil
 	})
 
 	for {
-		_, message, err := c.Conn.ReadMessage()
+		frameType, message, err := c.Conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				c.Manager.logger.Warn("agent pong timeout, disconnecting", zap.String("agentID", c.ID), zap.Duration("pongWait", pongWait))
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.Manager.logger.Error("websocket read error", zap.Error(err), zap.String("agentID", c.ID))
 			}
 			break
@@ -220,6 +453,17 @@ func (c *Client) ReadPump(ctx context.Context) {
 
 		c.LastActive = time.Now()
 
+		// 压缩消息使用二进制帧发送，未协商压缩的探针继续发送明文 JSON
+		if frameType == websocket.BinaryMessage && c.Compression != CompressionNone {
+			raw, err := decompress(c.Compression, message)
+			if err != nil {
+				c.Manager.logger.Error("failed to decompress message", zap.Error(err), zap.String("agentID", c.ID), zap.String("compression", c.Compression))
+				continue
+			}
+			c.Manager.compStats.record(len(message), len(raw))
+			message = raw
+		}
+
 		// 解析消息
 		var msg protocol.InputMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
@@ -227,10 +471,11 @@ func (c *Client) ReadPump(ctx context.Context) {
 			continue
 		}
 
-		// 处理消息
+		// 处理消息，每一帧生成独立的追踪ID，贯穿指标写入、告警判定与通知发送
 		if c.Manager.onMessage != nil {
-			if err := c.Manager.onMessage(ctx, c.ID, string(msg.Type), msg.Data); err != nil {
-				c.Manager.logger.Error("failed to handle message", zap.Error(err), zap.String("agentID", c.ID), zap.String("type", string(msg.Type)))
+			msgCtx := utils.WithTraceID(ctx, utils.NewTraceID())
+			if err := c.Manager.onMessage(msgCtx, c.ID, string(msg.Type), msg.Data); err != nil {
+				c.Manager.logger.Error("failed to handle message", zap.Error(err), zap.String("agentID", c.ID), zap.String("type", string(msg.Type)), utils.TraceField(msgCtx))
 			}
 		}
 	}
@@ -238,7 +483,7 @@ func (c *Client) ReadPump(ctx context.Context) {
 
 // WritePump 向客户端写入消息
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(c.Manager.options.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -247,7 +492,7 @@ func (c *Client) WritePump() {
 	for {
 		select {
 		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// 通道已关闭
 				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
@@ -260,14 +505,53 @@ func (c *Client) WritePump() {
 			}
 
 		case <-ticker.C:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			now := time.Now()
+			c.Conn.SetWriteDeadline(now.Add(writeWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, []byte(strconv.FormatInt(now.UnixNano(), 10))); err != nil {
 				return
 			}
 		}
 	}
 }
 
+// enqueue 将消息放入发送缓冲区，缓冲区满时按溢出策略处理
+func (c *Client) enqueue(message []byte) bool {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	select {
+	case c.Send <- message:
+		return true
+	default:
+	}
+
+	m := c.Manager
+	m.droppedFrames.Add(1)
+
+	if m.options.OverflowPolicy == OverflowDisconnect {
+		m.overflowDisconnects.Add(1)
+		m.logger.Warn("send buffer overflow, disconnecting", zap.String("agentID", c.ID))
+		c.Conn.Close()
+		return false
+	}
+
+	// 丢弃最旧的消息，为新消息腾出空间
+	select {
+	case <-c.Send:
+	default:
+	}
+	select {
+	case c.Send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
 // closeChannel 安全地关闭发送通道
 func (c *Client) closeChannel() {
 	c.closeMu.Lock()
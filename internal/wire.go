@@ -8,6 +8,7 @@ import (
 
 	"github.com/dushixiang/pika/internal/config"
 	"github.com/dushixiang/pika/internal/handler"
+	"github.com/dushixiang/pika/internal/metricstore"
 	"github.com/dushixiang/pika/internal/service"
 	"github.com/dushixiang/pika/internal/vmclient"
 	"github.com/dushixiang/pika/internal/websocket"
@@ -21,6 +22,7 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 	wire.Build(
 		// VictoriaMetrics Client
 		provideVMClient,
+		provideMetricStore,
 
 		service.NewAccountService,
 		service.NewAgentService,
@@ -39,10 +41,18 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 		service.NewDDNSService,
 		service.NewSSHLoginService,
 		service.NewPublicIPService,
+		service.NewCustomCheckService,
+		service.NewArchiveService,
+		service.NewShareTokenService,
+		service.NewAnnotationService,
+		service.NewNodeExporterService,
+		service.NewAgentCleanupService,
+		service.NewLogTailService,
+		service.NewAgentConfigService,
 
 		service.NewNotifier,
 		// WebSocket Manager
-		websocket.NewManager,
+		provideWSManager,
 
 		// Handlers
 		handler.NewAgentHandler,
@@ -55,6 +65,11 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 		handler.NewDNSProviderHandler,
 		handler.NewDDNSHandler,
 		handler.NewSSHLoginHandler,
+		handler.NewCustomCheckHandler,
+		handler.NewShareTokenHandler,
+		handler.NewAnnotationHandler,
+		handler.NewNodeExporterHandler,
+		handler.NewAgentCleanupHandler,
 
 		// App Components
 		wire.Struct(new(AppComponents), "*"),
@@ -64,28 +79,39 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 
 // AppComponents 应用组件
 type AppComponents struct {
-	AccountHandler     *handler.AccountHandler
-	AgentHandler       *handler.AgentHandler
-	ApiKeyHandler      *handler.ApiKeyHandler
-	AlertHandler       *handler.AlertHandler
-	PropertyHandler    *handler.PropertyHandler
-	MonitorHandler     *handler.MonitorHandler
-	TamperHandler      *handler.TamperHandler
-	DNSProviderHandler *handler.DNSProviderHandler
-	DDNSHandler        *handler.DDNSHandler
-	SSHLoginHandler    *handler.SSHLoginHandler
-
-	AgentService    *service.AgentService
-	TrafficService  *service.TrafficService
-	MetricService   *service.MetricService
-	AlertService    *service.AlertService
-	PropertyService *service.PropertyService
-	MonitorService  *service.MonitorService
-	ApiKeyService   *service.ApiKeyService
-	TamperService   *service.TamperService
-	DDNSService     *service.DDNSService
-	SSHLoginService *service.SSHLoginService
-	PublicIPService *service.PublicIPService
+	AccountHandler      *handler.AccountHandler
+	AgentHandler        *handler.AgentHandler
+	ApiKeyHandler       *handler.ApiKeyHandler
+	AlertHandler        *handler.AlertHandler
+	PropertyHandler     *handler.PropertyHandler
+	MonitorHandler      *handler.MonitorHandler
+	TamperHandler       *handler.TamperHandler
+	DNSProviderHandler  *handler.DNSProviderHandler
+	DDNSHandler         *handler.DDNSHandler
+	SSHLoginHandler     *handler.SSHLoginHandler
+	CustomCheckHandler  *handler.CustomCheckHandler
+	ShareTokenHandler   *handler.ShareTokenHandler
+	AnnotationHandler   *handler.AnnotationHandler
+	NodeExporterHandler *handler.NodeExporterHandler
+	AgentCleanupHandler *handler.AgentCleanupHandler
+
+	AgentService        *service.AgentService
+	TrafficService      *service.TrafficService
+	MetricService       *service.MetricService
+	AlertService        *service.AlertService
+	PropertyService     *service.PropertyService
+	MonitorService      *service.MonitorService
+	ApiKeyService       *service.ApiKeyService
+	TamperService       *service.TamperService
+	DDNSService         *service.DDNSService
+	SSHLoginService     *service.SSHLoginService
+	PublicIPService     *service.PublicIPService
+	CustomCheckService  *service.CustomCheckService
+	ArchiveService      *service.ArchiveService
+	ShareTokenService   *service.ShareTokenService
+	AnnotationService   *service.AnnotationService
+	NodeExporterService *service.NodeExporterService
+	AgentCleanupService *service.AgentCleanupService
 
 	WSManager *websocket.Manager
 	VMClient  *vmclient.VMClient
@@ -118,3 +144,47 @@ func provideVMClient(cfg *config.AppConfig, logger *zap.Logger) *vmclient.VMClie
 
 	return vmclient.NewVMClient(cfg.VictoriaMetrics.URL, writeTimeout, queryTimeout)
 }
+
+// provideWSManager 提供 WebSocket 连接管理器
+func provideWSManager(cfg *config.AppConfig, logger *zap.Logger) *websocket.Manager {
+	var options websocket.Options
+	if cfg.WebSocket != nil {
+		options = websocket.Options{
+			MaxConnections: cfg.WebSocket.MaxConnections,
+			SendBufferSize: cfg.WebSocket.SendBufferSize,
+			OverflowPolicy: cfg.WebSocket.OverflowPolicy,
+			PingInterval:   time.Duration(cfg.WebSocket.PingInterval) * time.Second,
+			PongWait:       time.Duration(cfg.WebSocket.PongTimeout) * time.Second,
+			IdleTimeout:    time.Duration(cfg.WebSocket.IdleTimeout) * time.Second,
+		}
+	}
+	return websocket.NewManager(logger, options)
+}
+
+// provideMetricStore 提供指标写入后端（默认 VictoriaMetrics，可转发或双写到外部 TSDB）
+func provideMetricStore(cfg *config.AppConfig, logger *zap.Logger, vmClient *vmclient.VMClient) (metricstore.MetricStore, error) {
+	vmStore := metricstore.NewVMStore(vmClient)
+	if cfg.MetricForward == nil || !cfg.MetricForward.Enabled {
+		return vmStore, nil
+	}
+
+	forwardStore, err := metricstore.NewLineProtocolStore(metricstore.LineProtocolOptions{
+		URL:     cfg.MetricForward.URL,
+		Token:   cfg.MetricForward.Token,
+		Org:     cfg.MetricForward.Org,
+		Bucket:  cfg.MetricForward.Bucket,
+		Timeout: time.Duration(cfg.MetricForward.Timeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("metric forwarding enabled", zap.String("url", cfg.MetricForward.URL), zap.Bool("dualWrite", cfg.MetricForward.DualWrite))
+
+	if cfg.MetricForward.DualWrite {
+		return metricstore.NewMultiStore(logger, vmStore, forwardStore), nil
+	}
+	// 仅转发模式不写入 VictoriaMetrics，图表、历史查询、导出、归档和基于历史数据的告警均无数据
+	logger.Warn("metric forwarding without dual write: VictoriaMetrics receives no samples, history queries will be empty")
+	return forwardStore, nil
+}
@@ -9,6 +9,7 @@ package internal
 import (
 	"github.com/dushixiang/pika/internal/config"
 	"github.com/dushixiang/pika/internal/handler"
+	"github.com/dushixiang/pika/internal/metricstore"
 	"github.com/dushixiang/pika/internal/service"
 	"github.com/dushixiang/pika/internal/vmclient"
 	"github.com/dushixiang/pika/internal/websocket"
@@ -21,10 +22,14 @@ import (
 
 // InitializeApp 初始化应用
 func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*AppComponents, error) {
-	userService := service.NewUserService(logger, cfg)
+	userService := service.NewUserService(logger, db, cfg)
 	oidcService := service.NewOIDCService(logger, cfg)
 	gitHubOAuthService := service.NewGitHubOAuthService(logger, cfg)
-	accountService := service.NewAccountService(logger, userService, oidcService, gitHubOAuthService, cfg)
+	geoIPService, err := service.NewGeoIPService(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	accountService := service.NewAccountService(logger, db, userService, oidcService, gitHubOAuthService, geoIPService, cfg)
 	accountHandler := handler.NewAccountHandler(accountService)
 	apiKeyService := service.NewApiKeyService(logger, db)
 	propertyService := service.NewPropertyService(logger, db)
@@ -32,21 +37,23 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 	notificationService := service.NewNotificationService(logger, propertyService, notifier)
 	trafficService := service.NewTrafficService(logger, db, notificationService)
 	vmClient := provideVMClient(cfg, logger)
-	metricService := service.NewMetricService(logger, db, propertyService, trafficService, vmClient)
-	geoIPService, err := service.NewGeoIPService(logger, cfg)
+	metricStore, err := provideMetricStore(cfg, logger, vmClient)
 	if err != nil {
 		return nil, err
 	}
-	agentService := service.NewAgentService(logger, db, apiKeyService, metricService, geoIPService)
-	manager := websocket.NewManager(logger)
+	metricService := service.NewMetricService(logger, db, propertyService, trafficService, vmClient, metricStore, cfg)
+	agentService := service.NewAgentService(logger, db, apiKeyService, metricService, notificationService, geoIPService, cfg)
+	manager := provideWSManager(cfg, logger)
 	monitorService := service.NewMonitorService(logger, db, metricService, manager)
 	tamperService := service.NewTamperService(logger, db, manager, notificationService)
 	ddnsService := service.NewDDNSService(logger, db, propertyService, manager)
 	sshLoginService := service.NewSSHLoginService(logger, db, manager, geoIPService, notificationService)
-	publicIPService := service.NewPublicIPService(logger, propertyService, manager)
-	agentHandler := handler.NewAgentHandler(logger, agentService, trafficService, metricService, monitorService, tamperService, ddnsService, sshLoginService, apiKeyService, propertyService, manager)
+	customCheckService := service.NewCustomCheckService(logger, db, propertyService, manager)
+	logTailService := service.NewLogTailService(logger, manager)
+	agentConfigService := service.NewAgentConfigService(logger, db, propertyService, metricService, sshLoginService, customCheckService, tamperService, manager)
+	agentHandler := handler.NewAgentHandler(logger, agentService, trafficService, metricService, monitorService, tamperService, ddnsService, sshLoginService, apiKeyService, propertyService, customCheckService, logTailService, agentConfigService, manager)
 	apiKeyHandler := handler.NewApiKeyHandler(logger, apiKeyService)
-	alertService := service.NewAlertService(logger, db, propertyService, monitorService, notifier)
+	alertService := service.NewAlertService(logger, db, propertyService, monitorService, metricService, notifier, notificationService)
 	alertHandler := handler.NewAlertHandler(logger, alertService)
 	propertyHandler := handler.NewPropertyHandler(logger, propertyService, notifier)
 	monitorHandler := handler.NewMonitorHandler(logger, monitorService, metricService, agentService)
@@ -54,30 +61,52 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 	dnsProviderHandler := handler.NewDNSProviderHandler(logger, propertyService)
 	ddnsHandler := handler.NewDDNSHandler(logger, ddnsService)
 	sshLoginHandler := handler.NewSSHLoginHandler(logger, sshLoginService)
+	customCheckHandler := handler.NewCustomCheckHandler(logger, customCheckService)
+	shareTokenService := service.NewShareTokenService(logger, db, cfg)
+	shareTokenHandler := handler.NewShareTokenHandler(logger, shareTokenService)
+	annotationService := service.NewAnnotationService(logger, db)
+	annotationHandler := handler.NewAnnotationHandler(logger, annotationService, apiKeyService)
+	nodeExporterService := service.NewNodeExporterService(logger, db, metricService, cfg)
+	nodeExporterHandler := handler.NewNodeExporterHandler(logger, nodeExporterService, apiKeyService)
+	agentCleanupService := service.NewAgentCleanupService(logger, agentService, propertyService, notificationService)
+	agentCleanupHandler := handler.NewAgentCleanupHandler(logger, agentCleanupService)
+	publicIPService := service.NewPublicIPService(logger, propertyService, manager)
+	archiveService := service.NewArchiveService(logger, db, propertyService, metricService)
 	appComponents := &AppComponents{
-		AccountHandler:     accountHandler,
-		AgentHandler:       agentHandler,
-		ApiKeyHandler:      apiKeyHandler,
-		AlertHandler:       alertHandler,
-		PropertyHandler:    propertyHandler,
-		MonitorHandler:     monitorHandler,
-		TamperHandler:      tamperHandler,
-		DNSProviderHandler: dnsProviderHandler,
-		DDNSHandler:        ddnsHandler,
-		SSHLoginHandler:    sshLoginHandler,
-		AgentService:       agentService,
-		TrafficService:     trafficService,
-		MetricService:      metricService,
-		AlertService:       alertService,
-		PropertyService:    propertyService,
-		MonitorService:     monitorService,
-		ApiKeyService:      apiKeyService,
-		TamperService:      tamperService,
-		DDNSService:        ddnsService,
-		SSHLoginService:    sshLoginService,
-		PublicIPService:    publicIPService,
-		WSManager:          manager,
-		VMClient:           vmClient,
+		AccountHandler:      accountHandler,
+		AgentHandler:        agentHandler,
+		ApiKeyHandler:       apiKeyHandler,
+		AlertHandler:        alertHandler,
+		PropertyHandler:     propertyHandler,
+		MonitorHandler:      monitorHandler,
+		TamperHandler:       tamperHandler,
+		DNSProviderHandler:  dnsProviderHandler,
+		DDNSHandler:         ddnsHandler,
+		SSHLoginHandler:     sshLoginHandler,
+		CustomCheckHandler:  customCheckHandler,
+		ShareTokenHandler:   shareTokenHandler,
+		AnnotationHandler:   annotationHandler,
+		NodeExporterHandler: nodeExporterHandler,
+		AgentCleanupHandler: agentCleanupHandler,
+		AgentService:        agentService,
+		TrafficService:      trafficService,
+		MetricService:       metricService,
+		AlertService:        alertService,
+		PropertyService:     propertyService,
+		MonitorService:      monitorService,
+		ApiKeyService:       apiKeyService,
+		TamperService:       tamperService,
+		DDNSService:         ddnsService,
+		SSHLoginService:     sshLoginService,
+		PublicIPService:     publicIPService,
+		CustomCheckService:  customCheckService,
+		ArchiveService:      archiveService,
+		ShareTokenService:   shareTokenService,
+		AnnotationService:   annotationService,
+		NodeExporterService: nodeExporterService,
+		AgentCleanupService: agentCleanupService,
+		WSManager:           manager,
+		VMClient:            vmClient,
 	}
 	return appComponents, nil
 }
@@ -86,28 +115,39 @@ func InitializeApp(logger *zap.Logger, db *gorm.DB, cfg *config.AppConfig) (*App
 
 // AppComponents 应用组件
 type AppComponents struct {
-	AccountHandler     *handler.AccountHandler
-	AgentHandler       *handler.AgentHandler
-	ApiKeyHandler      *handler.ApiKeyHandler
-	AlertHandler       *handler.AlertHandler
-	PropertyHandler    *handler.PropertyHandler
-	MonitorHandler     *handler.MonitorHandler
-	TamperHandler      *handler.TamperHandler
-	DNSProviderHandler *handler.DNSProviderHandler
-	DDNSHandler        *handler.DDNSHandler
-	SSHLoginHandler    *handler.SSHLoginHandler
-
-	AgentService    *service.AgentService
-	TrafficService  *service.TrafficService
-	MetricService   *service.MetricService
-	AlertService    *service.AlertService
-	PropertyService *service.PropertyService
-	MonitorService  *service.MonitorService
-	ApiKeyService   *service.ApiKeyService
-	TamperService   *service.TamperService
-	DDNSService     *service.DDNSService
-	SSHLoginService *service.SSHLoginService
-	PublicIPService *service.PublicIPService
+	AccountHandler      *handler.AccountHandler
+	AgentHandler        *handler.AgentHandler
+	ApiKeyHandler       *handler.ApiKeyHandler
+	AlertHandler        *handler.AlertHandler
+	PropertyHandler     *handler.PropertyHandler
+	MonitorHandler      *handler.MonitorHandler
+	TamperHandler       *handler.TamperHandler
+	DNSProviderHandler  *handler.DNSProviderHandler
+	DDNSHandler         *handler.DDNSHandler
+	SSHLoginHandler     *handler.SSHLoginHandler
+	CustomCheckHandler  *handler.CustomCheckHandler
+	ShareTokenHandler   *handler.ShareTokenHandler
+	AnnotationHandler   *handler.AnnotationHandler
+	NodeExporterHandler *handler.NodeExporterHandler
+	AgentCleanupHandler *handler.AgentCleanupHandler
+
+	AgentService        *service.AgentService
+	TrafficService      *service.TrafficService
+	MetricService       *service.MetricService
+	AlertService        *service.AlertService
+	PropertyService     *service.PropertyService
+	MonitorService      *service.MonitorService
+	ApiKeyService       *service.ApiKeyService
+	TamperService       *service.TamperService
+	DDNSService         *service.DDNSService
+	SSHLoginService     *service.SSHLoginService
+	PublicIPService     *service.PublicIPService
+	CustomCheckService  *service.CustomCheckService
+	ArchiveService      *service.ArchiveService
+	ShareTokenService   *service.ShareTokenService
+	AnnotationService   *service.AnnotationService
+	NodeExporterService *service.NodeExporterService
+	AgentCleanupService *service.AgentCleanupService
 
 	WSManager *websocket.Manager
 	VMClient  *vmclient.VMClient
@@ -136,3 +176,47 @@ func provideVMClient(cfg *config.AppConfig, logger *zap.Logger) *vmclient.VMClie
 
 	return vmclient.NewVMClient(cfg.VictoriaMetrics.URL, writeTimeout, queryTimeout)
 }
+
+// provideWSManager 提供 WebSocket 连接管理器
+func provideWSManager(cfg *config.AppConfig, logger *zap.Logger) *websocket.Manager {
+	var options websocket.Options
+	if cfg.WebSocket != nil {
+		options = websocket.Options{
+			MaxConnections: cfg.WebSocket.MaxConnections,
+			SendBufferSize: cfg.WebSocket.SendBufferSize,
+			OverflowPolicy: cfg.WebSocket.OverflowPolicy,
+			PingInterval:   time.Duration(cfg.WebSocket.PingInterval) * time.Second,
+			PongWait:       time.Duration(cfg.WebSocket.PongTimeout) * time.Second,
+			IdleTimeout:    time.Duration(cfg.WebSocket.IdleTimeout) * time.Second,
+		}
+	}
+	return websocket.NewManager(logger, options)
+}
+
+// provideMetricStore 提供指标写入后端（默认 VictoriaMetrics，可转发或双写到外部 TSDB）
+func provideMetricStore(cfg *config.AppConfig, logger *zap.Logger, vmClient *vmclient.VMClient) (metricstore.MetricStore, error) {
+	vmStore := metricstore.NewVMStore(vmClient)
+	if cfg.MetricForward == nil || !cfg.MetricForward.Enabled {
+		return vmStore, nil
+	}
+
+	forwardStore, err := metricstore.NewLineProtocolStore(metricstore.LineProtocolOptions{
+		URL:     cfg.MetricForward.URL,
+		Token:   cfg.MetricForward.Token,
+		Org:     cfg.MetricForward.Org,
+		Bucket:  cfg.MetricForward.Bucket,
+		Timeout: time.Duration(cfg.MetricForward.Timeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("metric forwarding enabled", zap.String("url", cfg.MetricForward.URL), zap.Bool("dualWrite", cfg.MetricForward.DualWrite))
+
+	if cfg.MetricForward.DualWrite {
+		return metricstore.NewMultiStore(logger, vmStore, forwardStore), nil
+	}
+
+	logger.Warn("metric forwarding without dual write: VictoriaMetrics receives no samples, history queries will be empty")
+	return forwardStore, nil
+}
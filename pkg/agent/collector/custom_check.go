@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// numberPattern 匹配输出中的数字
+var numberPattern = regexp.MustCompile(`[-+]?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?`)
+
+// CustomCheckCollector 自定义检查采集器，执行服务端下发的命令并解析出数值
+type CustomCheckCollector struct{}
+
+// NewCustomCheckCollector 创建自定义检查采集器
+func NewCustomCheckCollector() *CustomCheckCollector {
+	return &CustomCheckCollector{}
+}
+
+// Collect 执行单个检查
+func (c *CustomCheckCollector) Collect(item protocol.CustomCheckItem) protocol.CustomMetricData {
+	result := protocol.CustomMetricData{
+		CheckID: item.ID,
+		Name:    item.Name,
+	}
+
+	timeout := time.Duration(item.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, exitCode, err := runShell(ctx, item.Command)
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Error = fmt.Sprintf("执行超时（%s）", timeout)
+		return result
+	}
+
+	if item.Parser == protocol.CustomCheckParserExitCode {
+		if err != nil && exitCode < 0 {
+			result.Error = err.Error()
+			return result
+		}
+		result.Value = float64(exitCode)
+		result.Success = true
+		return result
+	}
+
+	if err != nil {
+		result.Error = truncateOutput(fmt.Sprintf("%v: %s", err, output))
+		return result
+	}
+
+	value, err := parseCheckOutput(item, output)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Value = value
+	result.Success = true
+	return result
+}
+
+// runShell 通过系统 shell 执行命令，返回合并后的输出和退出码（无法获取时为 -1）
+func runShell(ctx context.Context, command string) (string, int, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	output, err := cmd.CombinedOutput()
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	return strings.TrimSpace(string(output)), exitCode, err
+}
+
+// parseCheckOutput 按解析方式从输出中提取数值
+func parseCheckOutput(item protocol.CustomCheckItem, output string) (float64, error) {
+	var raw string
+	switch item.Parser {
+	case protocol.CustomCheckParserRegex:
+		re, err := regexp.Compile(item.Pattern)
+		if err != nil {
+			return 0, fmt.Errorf("正则表达式无效: %w", err)
+		}
+		matches := re.FindStringSubmatch(output)
+		if len(matches) == 0 {
+			return 0, errors.New("输出未匹配正则表达式")
+		}
+		raw = matches[0]
+		if len(matches) > 1 {
+			raw = matches[1]
+		}
+	default:
+		raw = numberPattern.FindString(output)
+		if raw == "" {
+			return 0, errors.New("输出中未找到数字: " + truncateOutput(output))
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析数值 %q", raw)
+	}
+	return value, nil
+}
+
+// truncateOutput 截断过长的输出，避免上报过大的错误信息
+func truncateOutput(output string) string {
+	const limit = 256
+	if len(output) <= limit {
+		return output
+	}
+	return output[:limit] + "..."
+}
@@ -93,6 +93,11 @@ func (d *DDNSCollector) getIP(method, value string, isIPv6 bool) (string, error)
 
 // GetIPFromAPI 通过 API 获取 IP 地址（支持轮询多个 API）
 func (d *DDNSCollector) GetIPFromAPI(apiURL string, isIPv6 bool) (string, error) {
+	return d.GetIPFromAPIWithTimeout(apiURL, isIPv6, 10*time.Second)
+}
+
+// GetIPFromAPIWithTimeout 通过 API 获取 IP 地址，使用指定的单次请求超时
+func (d *DDNSCollector) GetIPFromAPIWithTimeout(apiURL string, isIPv6 bool, timeout time.Duration) (string, error) {
 	var apiList []string
 
 	if apiURL == "" {
@@ -108,7 +113,7 @@ func (d *DDNSCollector) GetIPFromAPI(apiURL string, isIPv6 bool) (string, error)
 	}
 
 	client := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: timeout,
 	}
 
 	var lastErr error
@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/shirou/gopsutil/v4/load"
+)
+
+// LoadCollector 系统负载采集器
+type LoadCollector struct{}
+
+// NewLoadCollector 创建系统负载采集器
+func NewLoadCollector() *LoadCollector {
+	return &LoadCollector{}
+}
+
+// Collect 采集 1/5/15 分钟平均负载
+func (l *LoadCollector) Collect() (*protocol.LoadData, error) {
+	loadAvg, err := load.Avg()
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.LoadData{
+		Load1:  loadAvg.Load1,
+		Load5:  loadAvg.Load5,
+		Load15: loadAvg.Load15,
+	}, nil
+}
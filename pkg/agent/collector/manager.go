@@ -1,10 +1,12 @@
 package collector
 
 import (
+	"sync"
 	"time"
 
 	"github.com/dushixiang/pika/internal/protocol"
 	"github.com/dushixiang/pika/pkg/agent/config"
+	"github.com/dushixiang/pika/pkg/monitorcheck"
 )
 
 // WebSocketWriter 定义 WebSocket 写入接口
@@ -21,10 +23,17 @@ type Manager struct {
 	networkCollector           *NetworkCollector
 	networkConnectionCollector *NetworkConnectionCollector
 	hostCollector              *HostCollector
+	loadCollector              *LoadCollector
 	temperatureCollector       *TemperatureCollector
 	gpuCollector               *GPUCollector
-	monitorCollector           *MonitorCollector
+	monitorCollector           *monitorcheck.Collector
+	customCheckCollector       *CustomCheckCollector
 	ddnsCollector              *DDNSCollector
+
+	policyMu sync.RWMutex
+	policy   *protocol.MetricPolicyData // 服务端下发的指标采集策略
+
+	static *staticTracker // 已上报的静态描述信息
 }
 
 // NewManager 创建采集器管理器
@@ -37,105 +46,187 @@ func NewManager(cfg *config.Config) *Manager {
 		networkCollector:           NewNetworkCollector(cfg),
 		networkConnectionCollector: NewNetworkConnectionCollector(),
 		hostCollector:              NewHostCollector(),
+		loadCollector:              NewLoadCollector(),
 		temperatureCollector:       NewTemperatureCollector(),
 		gpuCollector:               NewGPUCollector(),
-		monitorCollector:           NewMonitorCollector(),
+		monitorCollector:           monitorcheck.NewCollector(),
+		customCheckCollector:       NewCustomCheckCollector(),
 		ddnsCollector:              nil, // DDNS 采集器需要配置后才能初始化
+		static:                     newStaticTracker(),
 	}
 }
 
 // CollectAndSendCPU 采集并发送 CPU 指标
 func (m *Manager) CollectAndSendCPU(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeCPU) {
+		return nil
+	}
+	start := time.Now()
 	cpuData, err := m.cpuCollector.Collect()
 	if err != nil {
 		return err
 	}
+	m.static.stripCPUStatic(cpuData)
 
-	return m.sendMetrics(conn, protocol.MetricTypeCPU, cpuData)
+	return m.sendMetrics(conn, protocol.MetricTypeCPU, cpuData, start)
 }
 
 // CollectAndSendMemory 采集并发送内存指标
 func (m *Manager) CollectAndSendMemory(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeMemory) {
+		return nil
+	}
+	start := time.Now()
 	memData, err := m.memoryCollector.Collect()
 	if err != nil {
 		return err
 	}
 
-	return m.sendMetrics(conn, protocol.MetricTypeMemory, memData)
+	return m.sendMetrics(conn, protocol.MetricTypeMemory, memData, start)
 }
 
 // CollectAndSendDisk 采集并发送磁盘指标
 func (m *Manager) CollectAndSendDisk(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeDisk) {
+		return nil
+	}
+	start := time.Now()
 	diskDataList, err := m.diskCollector.Collect()
 	if err != nil {
 		return err
 	}
-	return m.sendMetrics(conn, protocol.MetricTypeDisk, diskDataList)
+	return m.sendMetrics(conn, protocol.MetricTypeDisk, diskDataList, start)
 }
 
 // CollectAndSendDiskIO 采集并发送磁盘 IO 指标
 func (m *Manager) CollectAndSendDiskIO(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeDiskIO) {
+		return nil
+	}
+	start := time.Now()
 	diskIODataList, err := m.diskIOCollector.Collect()
 	if err != nil {
 		return err
 	}
-	return m.sendMetrics(conn, protocol.MetricTypeDiskIO, diskIODataList)
+	return m.sendMetrics(conn, protocol.MetricTypeDiskIO, diskIODataList, start)
 }
 
 // CollectAndSendNetwork 采集并发送网络指标
 func (m *Manager) CollectAndSendNetwork(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeNetwork) {
+		return nil
+	}
+	start := time.Now()
 	networkDataList, err := m.networkCollector.Collect()
 	if err != nil {
 		return err
 	}
-	return m.sendMetrics(conn, protocol.MetricTypeNetwork, networkDataList)
+	return m.sendMetrics(conn, protocol.MetricTypeNetwork, networkDataList, start)
 }
 
 // CollectAndSendNetworkConnection 采集并发送网络连接统计
 func (m *Manager) CollectAndSendNetworkConnection(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeNetworkConnection) {
+		return nil
+	}
+	start := time.Now()
 	connectionData, err := m.networkConnectionCollector.Collect()
 	if err != nil {
 		return err
 	}
-	return m.sendMetrics(conn, protocol.MetricTypeNetworkConnection, connectionData)
+	return m.sendMetrics(conn, protocol.MetricTypeNetworkConnection, connectionData, start)
 }
 
 // CollectAndSendHost 采集并发送主机信息
 func (m *Manager) CollectAndSendHost(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeHost) {
+		return nil
+	}
+	start := time.Now()
 	hostData, err := m.hostCollector.Collect()
 	if err != nil {
 		return err
 	}
+	m.static.stripHostStatic(hostData)
+
+	return m.sendMetrics(conn, protocol.MetricTypeHost, hostData, start)
+}
 
-	return m.sendMetrics(conn, protocol.MetricTypeHost, hostData)
+// CollectAndSendLoad 采集并发送系统负载
+func (m *Manager) CollectAndSendLoad(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeLoad) {
+		return nil
+	}
+	start := time.Now()
+	loadData, err := m.loadCollector.Collect()
+	if err != nil {
+		return err
+	}
+	return m.sendMetrics(conn, protocol.MetricTypeLoad, loadData, start)
 }
 
 // CollectAndSendGPU 采集并发送 GPU 指标
 func (m *Manager) CollectAndSendGPU(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeGPU) {
+		return nil
+	}
+	start := time.Now()
 	gpuDataList, err := m.gpuCollector.Collect()
 	if err != nil || len(gpuDataList) == 0 {
 		// GPU 监控不是必须的,失败或无数据时直接返回
 		return nil
 	}
+	m.static.stripGPUStatic(gpuDataList)
 
-	return m.sendMetrics(conn, protocol.MetricTypeGPU, gpuDataList)
+	return m.sendMetrics(conn, protocol.MetricTypeGPU, gpuDataList, start)
 }
 
 // CollectAndSendTemperature 采集并发送温度信息
 func (m *Manager) CollectAndSendTemperature(conn WebSocketWriter) error {
+	if !m.allowed(protocol.MetricTypeTemperature) {
+		return nil
+	}
+	start := time.Now()
 	tempDataList, err := m.temperatureCollector.Collect()
 	if err != nil || len(tempDataList) == 0 {
 		// 温度监控不是必须的,失败或无数据时直接返回
 		return nil
 	}
 
-	return m.sendMetrics(conn, protocol.MetricTypeTemperature, tempDataList)
+	return m.sendMetrics(conn, protocol.MetricTypeTemperature, tempDataList, start)
 }
 
 // CollectAndSendMonitor 采集并发送监控数据
 func (m *Manager) CollectAndSendMonitor(conn WebSocketWriter, items []protocol.MonitorItem) error {
+	start := time.Now()
 	monitorDataList := m.monitorCollector.Collect(items)
-	return m.sendMetrics(conn, protocol.MetricTypeMonitor, monitorDataList)
+	return m.sendMetrics(conn, protocol.MetricTypeMonitor, monitorDataList, start)
+}
+
+// CollectAndSendCustomCheck 执行并发送自定义检查结果
+func (m *Manager) CollectAndSendCustomCheck(conn WebSocketWriter, item protocol.CustomCheckItem) error {
+	start := time.Now()
+	result := m.customCheckCollector.Collect(item)
+	return m.sendMetrics(conn, protocol.MetricTypeCustom, []protocol.CustomMetricData{result}, start)
+}
+
+// ResetStaticDescriptors 重置静态描述信息的上报记录，连接建立后下一次上报会携带完整的静态信息
+func (m *Manager) ResetStaticDescriptors() {
+	m.static.reset()
+}
+
+// SetMetricPolicy 更新指标采集策略，被禁止的指标类型不再采集
+func (m *Manager) SetMetricPolicy(policy *protocol.MetricPolicyData) {
+	m.policyMu.Lock()
+	defer m.policyMu.Unlock()
+	m.policy = policy
+}
+
+// allowed 判断指标类型是否允许采集
+func (m *Manager) allowed(metricType protocol.MetricType) bool {
+	m.policyMu.RLock()
+	defer m.policyMu.RUnlock()
+	return m.policy.Allowed(metricType)
 }
 
 // UpdateDDNSConfig 更新 DDNS 配置
@@ -174,14 +265,16 @@ func (m *Manager) CollectAndSendDDNSIP(conn WebSocketWriter) error {
 	})
 }
 
-// sendMetrics 发送指标数据
-func (m *Manager) sendMetrics(conn WebSocketWriter, metricType protocol.MetricType, data interface{}) error {
+// sendMetrics 发送指标数据，start 为开始采集的时间，用于上报采集耗时
+func (m *Manager) sendMetrics(conn WebSocketWriter, metricType protocol.MetricType, data interface{}, start time.Time) error {
+	now := time.Now()
 	return conn.WriteJSON(protocol.OutboundMessage{
 		Type: protocol.MessageTypeMetrics,
 		Data: protocol.MetricsPayload{
-			Type:      metricType,
-			Data:      data,
-			Timestamp: time.Now().UnixMilli(),
+			Type:            metricType,
+			Data:            data,
+			Timestamp:       now.UnixMilli(),
+			CollectDuration: now.Sub(start).Milliseconds(),
 		},
 	})
 }
@@ -194,6 +287,14 @@ func (m *Manager) GetPublicIP(apiURL string, isIPv6 bool) (string, error) {
 	return collector.GetIPFromAPI(apiURL, isIPv6)
 }
 
+// GetPublicIPWithTimeout 通过 API 获取公网 IP 地址，使用指定的请求超时
+func (m *Manager) GetPublicIPWithTimeout(apiURL string, isIPv6 bool, timeout time.Duration) (string, error) {
+	collector := NewDDNSCollector(&protocol.DDNSConfigData{
+		Enabled: true,
+	})
+	return collector.GetIPFromAPIWithTimeout(apiURL, isIPv6, timeout)
+}
+
 // GetInterfaceIP 从网络接口获取 IP 地址
 func (m *Manager) GetInterfaceIP(interfaceName string, isIPv6 bool) (string, error) {
 	collector := NewDDNSCollector(&protocol.DDNSConfigData{
@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// staticRefreshInterval 静态描述信息未变化时的完整上报间隔，防止服务端缓存过期或重启后丢失
+const staticRefreshInterval = 10 * time.Minute
+
+type staticEntry struct {
+	signature string
+	sentAt    time.Time
+}
+
+// staticTracker 记录已上报的静态描述信息（CPU 型号、主机系统信息、GPU 名称等），
+// 未变化时只发送动态数值以减少上报数据量
+type staticTracker struct {
+	mu      sync.Mutex
+	entries map[string]staticEntry
+}
+
+func newStaticTracker() *staticTracker {
+	return &staticTracker{entries: make(map[string]staticEntry)}
+}
+
+// shouldSend 判断静态信息是否需要发送：首次上报、发生变化或距上次完整上报超过刷新间隔
+func (t *staticTracker) shouldSend(key, signature string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := t.entries[key]
+	if ok && entry.signature == signature && now.Sub(entry.sentAt) < staticRefreshInterval {
+		return false
+	}
+	t.entries[key] = staticEntry{signature: signature, sentAt: now}
+	return true
+}
+
+// reset 清空记录，下次上报时发送完整的静态信息（连接建立时调用）
+func (t *staticTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = make(map[string]staticEntry)
+}
+
+// stripCPUStatic 静态信息未变化时清空 CPU 静态字段
+func (t *staticTracker) stripCPUStatic(data *protocol.CPUData) {
+	signature := fmt.Sprintf("%d|%d|%s", data.LogicalCores, data.PhysicalCores, data.ModelName)
+	if t.shouldSend("cpu", signature) {
+		return
+	}
+	data.LogicalCores = 0
+	data.PhysicalCores = 0
+	data.ModelName = ""
+}
+
+// stripHostStatic 静态信息未变化时清空主机静态字段
+func (t *staticTracker) stripHostStatic(data *protocol.HostInfoData) {
+	signature := fmt.Sprintf("%s|%d|%s|%s|%s|%s|%s|%s|%s|%s", data.Hostname, data.BootTime, data.OS,
+		data.Platform, data.PlatformFamily, data.PlatformVersion, data.KernelVersion, data.KernelArch,
+		data.VirtualizationSystem, data.VirtualizationRole)
+	if t.shouldSend("host", signature) {
+		return
+	}
+	data.Hostname = ""
+	data.BootTime = 0
+	data.OS = ""
+	data.Platform = ""
+	data.PlatformFamily = ""
+	data.PlatformVersion = ""
+	data.KernelVersion = ""
+	data.KernelArch = ""
+	data.VirtualizationSystem = ""
+	data.VirtualizationRole = ""
+}
+
+// stripGPUStatic 按 GPU 序号判断，静态信息未变化时清空名称、UUID 和显存总量
+func (t *staticTracker) stripGPUStatic(gpus []*protocol.GPUData) {
+	for _, gpu := range gpus {
+		signature := fmt.Sprintf("%s|%s|%d", gpu.Name, gpu.UUID, gpu.MemoryTotal)
+		if t.shouldSend(fmt.Sprintf("gpu/%d", gpu.Index), signature) {
+			continue
+		}
+		gpu.Name = ""
+		gpu.UUID = ""
+		gpu.MemoryTotal = 0
+	}
+}
@@ -29,6 +29,12 @@ type Config struct {
 
 	// 自动更新配置
 	AutoUpdate AutoUpdateConfig `yaml:"auto_update"`
+
+	// 日志跟踪配置
+	LogTail LogTailConfig `yaml:"log_tail"`
+
+	// 自定义检查配置
+	CustomCheck CustomCheckConfig `yaml:"custom_check"`
 }
 
 // ServerConfig 服务器配置
@@ -41,6 +47,9 @@ type ServerConfig struct {
 
 	// 是否跳过 TLS 证书验证（仅用于测试环境，生产环境不建议开启）
 	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+
+	// 消息压缩算法：auto（默认，优先 zstd，其次 gzip）、zstd、gzip、none，服务端不支持时不压缩
+	Compression string `yaml:"compression"`
 }
 
 // AgentConfig Agent 配置
@@ -65,6 +74,13 @@ type AgentConfig struct {
 
 	// 是否压缩旧日志文件（默认 true）
 	LogCompress bool `yaml:"log_compress"`
+
+	// 自定义属性（如 datacenter: us-east、role: db），注册时上报给服务端，可用于筛选探针和限定告警范围
+	Attributes map[string]string `yaml:"attributes"`
+
+	// 是否使用密钥对签名注册（默认关闭）。开启后首次注册时生成 Ed25519 密钥（~/.pika/agent.key），
+	// 公钥经管理员审批后绑定到探针ID，泄露的 API 密钥无法再冒充该探针
+	SignedRegistration bool `yaml:"signed_registration"`
 }
 
 // CollectorConfig 采集器配置
@@ -93,6 +109,25 @@ type CollectorConfig struct {
 	DiskInclude []string `yaml:"disk_include"`
 }
 
+// LogTailConfig 日志跟踪配置
+type LogTailConfig struct {
+	// 允许服务端跟踪的日志文件路径（白名单，支持 filepath.Match 通配符）
+	// 为空时不允许跟踪任何文件；符号链接按实际路径匹配
+	// 例如: ["/var/log/syslog", "/var/log/nginx/*.log"]
+	AllowedPaths []string `yaml:"allowed_paths"`
+}
+
+// CustomCheckConfig 自定义检查配置
+// 服务端下发的命令会在本机通过 shell 执行，必须在探针本地显式开启并逐条列出允许的命令
+type CustomCheckConfig struct {
+	// 是否允许执行服务端下发的自定义检查（默认关闭）
+	Enabled bool `yaml:"enabled"`
+
+	// 允许执行的命令（白名单，按去除首尾空白后的完整命令精确匹配，不支持通配符）
+	// 例如: ["systemctl is-active nginx", "cat /sys/class/thermal/thermal_zone0/temp"]
+	AllowedCommands []string `yaml:"allowed_commands"`
+}
+
 // AutoUpdateConfig 自动更新配置
 type AutoUpdateConfig struct {
 	// 是否启用自动更新
@@ -229,6 +264,12 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("无效的日志等级: %s (可选值: debug, info, warn, error)", c.Agent.LogLevel)
 	}
 
+	switch c.Server.Compression {
+	case "", "auto", "zstd", "gzip", "none":
+	default:
+		return fmt.Errorf("无效的压缩算法: %s (可选值: auto, zstd, gzip, none)", c.Server.Compression)
+	}
+
 	return nil
 }
 
@@ -242,6 +283,18 @@ func (c *Config) GetHeartbeatInterval() time.Duration {
 	return time.Duration(c.Collector.HeartbeatInterval) * time.Second
 }
 
+// GetCompressions 获取注册时声明支持的压缩算法，按偏好排序
+func (c *Config) GetCompressions() []string {
+	switch c.Server.Compression {
+	case "none":
+		return nil
+	case "zstd", "gzip":
+		return []string{c.Server.Compression}
+	default:
+		return []string{"zstd", "gzip"}
+	}
+}
+
 // GetUpdateCheckInterval 获取更新检查间隔时长
 func (c *Config) GetUpdateCheckInterval() time.Duration {
 	duration, _ := time.ParseDuration(c.AutoUpdate.CheckInterval)
@@ -98,6 +98,11 @@ func (m *Manager) save(id string) error {
 	return nil
 }
 
+// Save 保存新的探针 ID（服务端重新分配 ID 时使用）
+func (m *Manager) Save(id string) error {
+	return m.save(id)
+}
+
 // GetPath 获取 ID 文件路径
 func (m *Manager) GetPath() string {
 	return m.idFilePath
@@ -0,0 +1,46 @@
+package id
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dushixiang/pika/pkg/agent/utils"
+)
+
+// GetKeyFilePath 获取注册密钥文件路径
+func GetKeyFilePath() string {
+	return filepath.Join(utils.GetSafeHomeDir(), ".pika", "agent.key")
+}
+
+// LoadOrCreateKey 加载注册私钥，不存在时生成新的 Ed25519 密钥并保存（仅所有者可读）
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, bool, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, false, fmt.Errorf("密钥文件 %s 格式错误", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), false, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, false, fmt.Errorf("创建目录失败: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(privateKey.Seed())
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, false, fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+	return privateKey, true, nil
+}
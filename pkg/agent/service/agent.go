@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"net"
 	"os"
 	"runtime"
 	"strings"
@@ -31,19 +35,44 @@ import (
 var (
 	// ErrConnectionEstablished 表示连接已建立后断开（需要立即重连）
 	ErrConnectionEstablished = errors.New("connection was established")
+	// ErrAgentIDReassigned 表示服务端重新分配了探针 ID（需要使用新 ID 重新注册）
+	ErrAgentIDReassigned = errors.New("agent id reassigned")
 )
 
 // safeConn 线程安全的 WebSocket 连接包装器
 type safeConn struct {
-	conn *websocket.Conn
-	mu   sync.Mutex
+	conn        *websocket.Conn
+	mu          sync.Mutex
+	compression string // 注册时协商的压缩算法，为空表示不压缩
 }
 
-// WriteJSON 线程安全地写入 JSON 消息
+// WriteJSON 线程安全地写入 JSON 消息，协商了压缩算法时较大的消息压缩后以二进制帧发送
 func (sc *safeConn) WriteJSON(v interface{}) error {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
-	return sc.conn.WriteJSON(v)
+	if sc.compression == "" {
+		return sc.conn.WriteJSON(v)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) < compressMinSize {
+		return sc.conn.WriteMessage(websocket.TextMessage, data)
+	}
+	compressed, err := compressFrame(sc.compression, data)
+	if err != nil {
+		return err
+	}
+	return sc.conn.WriteMessage(websocket.BinaryMessage, compressed)
+}
+
+// setCompression 设置协商后的压缩算法，注册完成后调用
+func (sc *safeConn) setCompression(compression string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.compression = compression
 }
 
 // WriteMessage 线程安全地写入消息
@@ -73,8 +102,16 @@ type Agent struct {
 	collectorMu      sync.RWMutex
 	collectorManager *collector.Manager
 	outboundBuffer   *outboundBuffer
+	sentFrames       *sentFrames
+	health           *healthTracker
 	tamperProtector  *tamper.Protector
 	sshMonitor       *sshmonitor.Monitor
+
+	customCheckMu     sync.Mutex
+	customCheckCancel context.CancelFunc // 取消当前运行中的自定义检查
+
+	logTailMu      sync.Mutex
+	logTailCancels map[string]context.CancelFunc // 进行中的日志跟踪，key 为会话ID
 }
 
 // New 创建 Agent 实例
@@ -84,8 +121,11 @@ func New(cfg *config.Config) *Agent {
 		idMgr:            id.NewManager(),
 		collectorManager: collector.NewManager(cfg),
 		outboundBuffer:   newOutboundBuffer(),
+		sentFrames:       newSentFrames(),
+		health:           newHealthTracker(),
 		tamperProtector:  tamper.NewProtector(),
 		sshMonitor:       sshmonitor.NewMonitor(),
+		logTailCancels:   make(map[string]context.CancelFunc),
 	}
 }
 
@@ -123,6 +163,10 @@ func (a *Agent) Start(ctx context.Context) error {
 		// 连接建立失败或注册失败（使用 backoff）
 		if err != nil {
 			retryAfter := b.Duration()
+			if isTryAgainLater(err) {
+				// 服务端连接数已满，至少等待 tryAgainLaterDelay 并加入随机抖动，避免大量探针同时重连
+				retryAfter = max(retryAfter, tryAgainLaterDelay+rand.N(tryAgainLaterDelay))
+			}
 			slog.Warn("探针运行出错，将在后重试", "error", err, "retryAfter", retryAfter)
 
 			select {
@@ -139,11 +183,21 @@ func (a *Agent) Start(ctx context.Context) error {
 	}
 }
 
+// tryAgainLaterDelay 服务端以 1013 (Try Again Later) 拒绝连接时的最短重连等待时间
+const tryAgainLaterDelay = 30 * time.Second
+
+// isTryAgainLater 服务端是否因连接数已满以 1013 关闭连接
+func isTryAgainLater(err error) bool {
+	var closeErr *websocket.CloseError
+	return errors.As(err, &closeErr) && closeErr.Code == websocket.CloseTryAgainLater
+}
+
 // Stop 停止探针服务
 func (a *Agent) Stop() {
 	if a.cancel != nil {
 		a.cancel()
 	}
+	a.stopCustomChecks()
 }
 
 // runOnce 运行一次探针连接
@@ -168,8 +222,6 @@ func (a *Agent) runOnce(ctx context.Context, onConnected func()) error {
 	}
 	defer rawConn.Close()
 
-	onConnected()
-
 	// 创建线程安全的连接包装器
 	conn := &safeConn{conn: rawConn}
 
@@ -189,10 +241,18 @@ func (a *Agent) runOnce(ctx context.Context, onConnected func()) error {
 	}
 
 	slog.Info("探针注册成功，开始监控...")
+	// 注册成功后才重置退避时间，连接后被服务端拒绝（如 1013）时继续退避
+	onConnected()
 
 	a.setActiveConn(conn)
+	// 新连接的服务端可能没有缓存静态描述信息（如服务端重启），下一次上报发送完整数据
+	if manager := a.getCollectorManager(); manager != nil {
+		manager.ResetStaticDescriptors()
+	}
 	defer func() {
 		a.setActiveConn(nil)
+		// 日志跟踪会话只存在于当前连接，断开后全部停止
+		a.stopAllLogTails()
 	}()
 
 	// 创建完成通道和错误通道
@@ -295,14 +355,74 @@ func (a *Agent) readLoop(conn *websocket.Conn, done chan struct{}) error {
 			go a.handlePublicIPConfig(msg.Data)
 		case protocol.MessageTypeSSHLoginConfig:
 			go a.handleSSHLoginConfig(msg.Data)
+		case protocol.MessageTypeCustomCheckConfig:
+			go a.handleCustomCheckConfig(msg.Data)
+		case protocol.MessageTypeMetricPolicy:
+			a.handleMetricPolicy(msg.Data)
+		case protocol.MessageTypeReloadConfig:
+			a.handleReloadConfig(msg.Data)
+		case protocol.MessageTypeMetricsResult:
+			go a.handleMetricsResult(msg.Data)
+		case protocol.MessageTypeLogTail:
+			a.handleLogTail(msg.Data)
 		case protocol.MessageTypeUninstall:
 			go a.handleUninstall()
+		case protocol.MessageTypeReassignID:
+			// 保存新 ID 后断开连接，使用新 ID 重新注册
+			if err := a.handleReassignID(msg.Data); err != nil {
+				slog.Warn("处理探针ID重新分配失败", "error", err)
+				continue
+			}
+			return ErrAgentIDReassigned
 		default:
 			// 忽略其他类型
 		}
 	}
 }
 
+// signRegistration 使用本地密钥对注册请求签名，密钥不存在时生成，服务端审批公钥后才会绑定
+// nonce 为服务端下发的挑战随机数，签名只对本次连接有效
+func signRegistration(agentID, nonce string) (*protocol.RegisterSignature, error) {
+	keyPath := id.GetKeyFilePath()
+	privateKey, created, err := id.LoadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载注册密钥失败: %w", err)
+	}
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	if created {
+		slog.Info("已生成注册密钥，请在服务端审批该公钥", "path", keyPath, "fingerprint", protocol.RegisterKeyFingerprint(publicKey))
+	}
+
+	timestamp := time.Now().UnixMilli()
+	signature := ed25519.Sign(privateKey, protocol.RegisterSigningPayload(agentID, timestamp, nonce))
+
+	return &protocol.RegisterSignature{
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// requestRegisterChallenge 向服务端请求签名注册的挑战随机数
+func requestRegisterChallenge(conn *safeConn) (string, error) {
+	if err := conn.WriteJSON(protocol.OutboundMessage{Type: protocol.MessageTypeRegisterChallenge}); err != nil {
+		return "", fmt.Errorf("请求注册挑战失败: %w", err)
+	}
+	var response protocol.InputMessage
+	if err := conn.ReadJSON(&response); err != nil {
+		return "", fmt.Errorf("读取注册挑战失败: %w", err)
+	}
+	if response.Type != protocol.MessageTypeRegisterChallenge {
+		return "", fmt.Errorf("服务端不支持签名注册挑战，收到响应类型 %s，请升级服务端", response.Type)
+	}
+	var challenge protocol.RegisterChallenge
+	if err := json.Unmarshal(response.Data, &challenge); err != nil || challenge.Nonce == "" {
+		return "", fmt.Errorf("解析注册挑战失败")
+	}
+	return challenge.Nonce, nil
+}
+
 // registerAgent 注册探针
 func (a *Agent) registerAgent(conn *safeConn) error {
 	// 加载或生成探针 ID
@@ -327,14 +447,28 @@ func (a *Agent) registerAgent(conn *safeConn) error {
 	// 构建注册请求
 	registerReq := protocol.RegisterRequest{
 		AgentInfo: protocol.AgentInfo{
-			ID:       agentID,
-			Name:     agentName,
-			Hostname: hostname,
-			OS:       runtime.GOOS,
-			Arch:     runtime.GOARCH,
-			Version:  GetVersion(),
+			ID:         agentID,
+			Name:       agentName,
+			Hostname:   hostname,
+			OS:         runtime.GOOS,
+			Arch:       runtime.GOARCH,
+			Version:    GetVersion(),
+			MAC:        primaryMAC(),
+			Attributes: a.cfg.Agent.Attributes,
 		},
-		ApiKey: a.cfg.Server.APIKey,
+		ApiKey:       a.cfg.Server.APIKey,
+		Compressions: a.cfg.GetCompressions(),
+	}
+	if a.cfg.Agent.SignedRegistration {
+		nonce, err := requestRegisterChallenge(conn)
+		if err != nil {
+			return err
+		}
+		signature, err := signRegistration(agentID, nonce)
+		if err != nil {
+			return err
+		}
+		registerReq.Signature = signature
 	}
 
 	if err := conn.WriteJSON(protocol.OutboundMessage{
@@ -368,7 +502,14 @@ func (a *Agent) registerAgent(conn *safeConn) error {
 		return fmt.Errorf("解析注册响应失败: %w", err)
 	}
 
-	slog.Info("注册成功", "agentId", registerResp.AgentID, "status", registerResp.Status)
+	switch registerResp.Compression {
+	case "", "zstd", "gzip":
+		conn.setCompression(registerResp.Compression)
+	default:
+		slog.Warn("服务端协商了不支持的压缩算法，不压缩消息", "compression", registerResp.Compression)
+	}
+
+	slog.Info("注册成功", "agentId", registerResp.AgentID, "status", registerResp.Status, "compression", registerResp.Compression)
 	return nil
 }
 
@@ -393,7 +534,7 @@ func (a *Agent) handleMonitorConfig(data json.RawMessage) {
 	slog.Info("收到服务监控配置，立即执行检测", "count", len(payload.Items))
 
 	// 立即执行一次监控检测
-	writer := newOutboundWriter(a.getActiveConn(), a.outboundBuffer)
+	writer := newOutboundWriter(a.getActiveConn(), a.outboundBuffer, a.sentFrames)
 	if err := manager.CollectAndSendMonitor(writer, payload.Items); err != nil {
 		slog.Warn("监控检测失败", "error", err)
 	} else {
@@ -411,7 +552,7 @@ func (a *Agent) heartbeatLoop(ctx context.Context, conn *safeConn, done chan str
 		case <-ticker.C:
 			if err := conn.WriteJSON(protocol.OutboundMessage{
 				Type: protocol.MessageTypeHeartbeat,
-				Data: struct{}{},
+				Data: protocol.HeartbeatData{Health: a.health.snapshot(a.outboundBuffer.Dropped())},
 			}); err != nil {
 				return fmt.Errorf("发送心跳失败: %w", err)
 			}
@@ -512,49 +653,61 @@ func (a *Agent) collectAndSendAllMetrics(manager *collector.Manager) error {
 		}
 	}
 
-	writer := newOutboundWriter(conn, a.outboundBuffer)
-	var hasError bool
+	writer := newOutboundWriter(conn, a.outboundBuffer, a.sentFrames)
+	var (
+		failing []string
+		lastErr error
+	)
+	failed := func(metricType protocol.MetricType, err error) {
+		failing = append(failing, string(metricType))
+		lastErr = err
+	}
 
 	// CPU 动态指标
 	if err := manager.CollectAndSendCPU(writer); err != nil {
 		slog.Warn("发送CPU指标失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeCPU, err)
 	}
 
 	// 内存动态指标
 	if err := manager.CollectAndSendMemory(writer); err != nil {
 		slog.Warn("发送内存指标失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeMemory, err)
 	}
 
 	// 磁盘指标
 	if err := manager.CollectAndSendDisk(writer); err != nil {
 		slog.Warn("发送磁盘指标失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeDisk, err)
 	}
 
 	// 磁盘 IO 指标
 	if err := manager.CollectAndSendDiskIO(writer); err != nil {
 		slog.Warn("发送磁盘IO指标失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeDiskIO, err)
 	}
 
 	// 网络指标
 	if err := manager.CollectAndSendNetwork(writer); err != nil {
 		slog.Warn("发送网络指标失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeNetwork, err)
 	}
 
 	// 网络连接统计
 	if err := manager.CollectAndSendNetworkConnection(writer); err != nil {
 		slog.Warn("发送网络连接统计失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeNetworkConnection, err)
+	}
+
+	// 系统负载（部分平台不支持），先于主机信息上报，服务端据此不再从主机信息中提取负载
+	if err := manager.CollectAndSendLoad(writer); err != nil {
+		slog.Info("发送系统负载失败", "error", err)
 	}
 
 	// 主机信息（包含 Load）
 	if err := manager.CollectAndSendHost(writer); err != nil {
 		slog.Warn("发送主机信息失败", "error", err)
-		hasError = true
+		failed(protocol.MetricTypeHost, err)
 	}
 
 	// GPU 信息（可选）
@@ -575,7 +728,8 @@ func (a *Agent) collectAndSendAllMetrics(manager *collector.Manager) error {
 		}
 	}
 
-	if hasError {
+	a.health.recordCollect(failing, lastErr)
+	if len(failing) > 0 {
 		return fmt.Errorf("部分指标采集失败")
 	}
 
@@ -700,6 +854,32 @@ func (a *Agent) handleTamperProtect(data json.RawMessage) {
 	a.sendTamperProtectResponse(true, message, result.Current, result.Added, result.Removed)
 }
 
+// replaceTamperPaths 按重新下发的完整目录列表替换当前保护的目录，并回传结果
+func (a *Agent) replaceTamperPaths(paths []string) {
+	slog.Info("收到重新下发的防篡改保护目录", "paths", paths)
+
+	// 未启用防篡改且当前没有保护的目录时无需处理（非 Linux 系统不支持防篡改）
+	if len(paths) == 0 && len(a.tamperProtector.GetProtectedPaths()) == 0 {
+		return
+	}
+
+	result, err := a.tamperProtector.UpdatePaths(context.Background(), paths)
+	if err != nil {
+		slog.Warn("替换防篡改保护目录失败", "error", err)
+		if result != nil {
+			a.sendTamperProtectResponse(false, fmt.Sprintf("替换防篡改保护目录失败: %v", err), result.Current, result.Added, result.Removed)
+		} else {
+			a.sendTamperProtectResponse(false, fmt.Sprintf("替换防篡改保护目录失败: %v", err), nil, nil, nil)
+		}
+		return
+	}
+
+	message := fmt.Sprintf("防篡改保护已更新: 新增 %d 个, 移除 %d 个, 当前保护 %d 个目录",
+		len(result.Added), len(result.Removed), len(result.Current))
+	slog.Info(message)
+	a.sendTamperProtectResponse(true, message, result.Current, result.Added, result.Removed)
+}
+
 // sendTamperProtectResponse 发送防篡改保护响应
 func (a *Agent) sendTamperProtectResponse(success bool, message string, paths []string, added []string, removed []string) {
 	resp := protocol.TamperProtectResponse{
@@ -811,6 +991,82 @@ func (a *Agent) handleDDNSConfig(data json.RawMessage) {
 	}
 }
 
+// handleMetricPolicy 处理指标采集策略
+func (a *Agent) handleMetricPolicy(data json.RawMessage) {
+	var policy protocol.MetricPolicyData
+	if err := json.Unmarshal(data, &policy); err != nil {
+		slog.Warn("解析指标采集策略失败", "error", err)
+		return
+	}
+
+	manager := a.getCollectorManager()
+	if manager == nil {
+		manager = collector.NewManager(a.cfg)
+		a.setCollectorManager(manager)
+	}
+	manager.SetMetricPolicy(&policy)
+
+	if len(policy.Allow) > 0 || len(policy.Deny) > 0 {
+		slog.Info("收到指标采集策略", "allow", policy.Allow, "deny", policy.Deny)
+	}
+}
+
+// handleReloadConfig 处理服务端重新下发的完整配置，逐项交给对应的处理函数，未下发的项保持不变
+// 指标采集策略与单独下发时一样同步应用，其余各项异步应用
+func (a *Agent) handleReloadConfig(data json.RawMessage) {
+	var config protocol.ReloadConfigData
+	if err := json.Unmarshal(data, &config); err != nil {
+		slog.Warn("解析重新下发的配置失败", "error", err)
+		return
+	}
+	slog.Info("收到服务端重新下发的配置")
+
+	if config.MetricPolicy != nil {
+		if raw, err := json.Marshal(config.MetricPolicy); err == nil {
+			a.handleMetricPolicy(raw)
+		}
+	}
+	if config.SSHLogin != nil {
+		if raw, err := json.Marshal(config.SSHLogin); err == nil {
+			go a.handleSSHLoginConfig(raw)
+		}
+	}
+	if config.CustomChecks != nil {
+		if raw, err := json.Marshal(config.CustomChecks); err == nil {
+			go a.handleCustomCheckConfig(raw)
+		}
+	}
+	if config.PublicIP != nil {
+		if raw, err := json.Marshal(config.PublicIP); err == nil {
+			go a.handlePublicIPConfig(raw)
+		}
+	}
+	if config.Tamper != nil {
+		go a.replaceTamperPaths(config.Tamper.Paths)
+	}
+}
+
+// handleMetricsResult 处理服务端回传的指标处理结果，写入失败时按退避时间重发原始指标
+func (a *Agent) handleMetricsResult(data json.RawMessage) {
+	var result protocol.MetricsResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		slog.Warn("解析指标处理结果失败", "error", err)
+		return
+	}
+
+	slog.Warn("服务端指标处理部分失败",
+		"type", result.Type,
+		"succeeded", result.Succeeded,
+		"failed", result.Failed,
+		"failedItems", result.FailedItems,
+		"resend", result.Resend)
+
+	if !result.Resend {
+		return
+	}
+	a.scheduleResend(result)
+}
+
 // handlePublicIPConfig 处理公网 IP 采集配置
 func (a *Agent) handlePublicIPConfig(data json.RawMessage) {
 	var config protocol.PublicIPConfigData
@@ -833,7 +1089,7 @@ func (a *Agent) handlePublicIPConfig(data json.RawMessage) {
 	var report protocol.PublicIPReportData
 
 	if config.IPv4Enabled {
-		ipv4, err := a.getPublicIPFromAPIs(manager, config.IPv4APIs, false)
+		ipv4, err := a.getPublicIPFromAPIs(manager, publicIPAPIEntries(config.IPv4APIEntries, config.IPv4APIs), false)
 		if err != nil {
 			slog.Warn("获取 IPv4 失败", "error", err)
 		} else {
@@ -843,7 +1099,7 @@ func (a *Agent) handlePublicIPConfig(data json.RawMessage) {
 	}
 
 	if config.IPv6Enabled {
-		ipv6, err := a.getPublicIPFromAPIs(manager, config.IPv6APIs, true)
+		ipv6, err := a.getPublicIPFromAPIs(manager, publicIPAPIEntries(config.IPv6APIEntries, config.IPv6APIs), true)
 		if err != nil {
 			slog.Warn("获取 IPv6 失败", "error", err)
 		} else {
@@ -864,14 +1120,26 @@ func (a *Agent) handlePublicIPConfig(data json.RawMessage) {
 	}
 }
 
-func (a *Agent) getPublicIPFromAPIs(manager *collector.Manager, apis []string, isIPv6 bool) (string, error) {
+// publicIPAPIEntries 返回按尝试顺序排列的 API 列表，旧版服务端只下发地址列表时使用默认超时
+func publicIPAPIEntries(entries []protocol.PublicIPAPIEntry, apis []string) []protocol.PublicIPAPIEntry {
+	if len(entries) > 0 {
+		return entries
+	}
+	result := make([]protocol.PublicIPAPIEntry, 0, len(apis))
+	for _, api := range apis {
+		result = append(result, protocol.PublicIPAPIEntry{URL: api})
+	}
+	return result
+}
+
+func (a *Agent) getPublicIPFromAPIs(manager *collector.Manager, apis []protocol.PublicIPAPIEntry, isIPv6 bool) (string, error) {
 	if len(apis) == 0 {
 		return manager.GetPublicIP("", isIPv6)
 	}
 
 	var lastErr error
-	for _, api := range apis {
-		api = strings.TrimSpace(api)
+	for _, entry := range apis {
+		api := strings.TrimSpace(entry.URL)
 		if api == "" {
 			continue
 		}
@@ -879,7 +1147,11 @@ func (a *Agent) getPublicIPFromAPIs(manager *collector.Manager, apis []string, i
 			lastErr = fmt.Errorf("非法 API 地址: %s", api)
 			continue
 		}
-		ip, err := manager.GetPublicIP(api, isIPv6)
+		timeout := time.Duration(entry.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		ip, err := manager.GetPublicIPWithTimeout(api, isIPv6, timeout)
 		if err == nil && ip != "" {
 			return ip, nil
 		}
@@ -971,6 +1243,37 @@ func (a *Agent) handleUninstall() {
 	}
 }
 
+// handleReassignID 处理服务端重新分配的探针 ID（克隆机器 ID 冲突时）
+func (a *Agent) handleReassignID(data json.RawMessage) error {
+	var reassign protocol.ReassignIDData
+	if err := json.Unmarshal(data, &reassign); err != nil {
+		return err
+	}
+	if reassign.AgentID == "" {
+		return fmt.Errorf("新的探针ID为空")
+	}
+	if err := a.idMgr.Save(reassign.AgentID); err != nil {
+		return err
+	}
+	slog.Info("服务端已重新分配探针ID", "id", reassign.AgentID)
+	return nil
+}
+
+// primaryMAC 获取第一个非回环网卡的 MAC 地址
+func primaryMAC() string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		return iface.HardwareAddr.String()
+	}
+	return ""
+}
+
 // handleSSHLoginConfig 处理 SSH 登录监控配置
 func (a *Agent) handleSSHLoginConfig(data json.RawMessage) {
 	var sshLoginConfig protocol.SSHLoginConfig
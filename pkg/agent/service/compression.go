@@ -0,0 +1,35 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressMinSize 小于该字节数的消息（如心跳）压缩收益很小，直接以文本帧发送
+const compressMinSize = 1024
+
+// zstdEncoder 共享的 zstd 编码器，EncodeAll 可并发调用
+var zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+// compressFrame 按注册时协商的算法压缩消息
+func compressFrame(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "zstd":
+		return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	case "gzip":
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(data); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("不支持的压缩算法: %s", compression)
+	}
+}
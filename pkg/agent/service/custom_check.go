@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+// handleCustomCheckConfig 处理自定义检查配置，停止已有检查后按新配置重新调度
+func (a *Agent) handleCustomCheckConfig(data json.RawMessage) {
+	var config protocol.CustomCheckConfigData
+	if err := json.Unmarshal(data, &config); err != nil {
+		slog.Warn("解析自定义检查配置失败", "error", err)
+		return
+	}
+
+	a.customCheckMu.Lock()
+	defer a.customCheckMu.Unlock()
+
+	if a.customCheckCancel != nil {
+		a.customCheckCancel()
+		a.customCheckCancel = nil
+	}
+
+	if len(config.Checks) == 0 {
+		slog.Info("自定义检查已清空")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.customCheckCancel = cancel
+
+	var accepted int
+	for _, item := range config.Checks {
+		if item.Command == "" {
+			continue
+		}
+		if err := a.customCheckAllowed(item.Command); err != nil {
+			slog.Warn("拒绝执行自定义检查", "check", item.Name, "command", item.Command, "error", err)
+			continue
+		}
+		accepted++
+		go a.runCustomCheck(ctx, item)
+	}
+	slog.Info("收到自定义检查配置", "count", len(config.Checks), "accepted", accepted)
+}
+
+// customCheckAllowed 校验服务端下发的命令是否允许执行：需要本地开启自定义检查，且命令在白名单中
+func (a *Agent) customCheckAllowed(command string) error {
+	cfg := a.cfg.CustomCheck
+	if !cfg.Enabled {
+		return errors.New("本地配置未开启 custom_check.enabled")
+	}
+	command = strings.TrimSpace(command)
+	if !slices.ContainsFunc(cfg.AllowedCommands, func(allowed string) bool {
+		return strings.TrimSpace(allowed) == command
+	}) {
+		return errors.New("命令不在本地配置 custom_check.allowed_commands 白名单中")
+	}
+	return nil
+}
+
+// runCustomCheck 按间隔执行单个自定义检查
+func (a *Agent) runCustomCheck(ctx context.Context, item protocol.CustomCheckItem) {
+	interval := time.Duration(item.IntervalSeconds) * time.Second
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		manager := a.getCollectorManager()
+		if manager != nil {
+			writer := newOutboundWriter(a.getActiveConn(), a.outboundBuffer, a.sentFrames)
+			if err := manager.CollectAndSendCustomCheck(writer, item); err != nil {
+				slog.Warn("发送自定义检查结果失败", "check", item.Name, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopCustomChecks 停止所有自定义检查
+func (a *Agent) stopCustomChecks() {
+	a.customCheckMu.Lock()
+	defer a.customCheckMu.Unlock()
+	if a.customCheckCancel != nil {
+		a.customCheckCancel()
+		a.customCheckCancel = nil
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// healthTracker 记录探针自身运行状况，随心跳上报
+type healthTracker struct {
+	mu                sync.Mutex
+	startedAt         time.Time
+	collectErrors     int64
+	failingCollectors []string
+	lastError         string
+	lastErrorAt       int64
+	proc              *process.Process // 探针进程，获取失败时为空
+}
+
+func newHealthTracker() *healthTracker {
+	h := &healthTracker{startedAt: time.Now()}
+	if proc, err := process.NewProcess(int32(os.Getpid())); err == nil {
+		h.proc = proc
+		// 首次调用只记录基准值，之后每次心跳得到两次心跳之间的使用率
+		_, _ = proc.Percent(0)
+	}
+	return h
+}
+
+// recordCollect 记录一轮指标采集的结果，failing 为采集失败的指标类型
+func (h *healthTracker) recordCollect(failing []string, lastErr error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failingCollectors = failing
+	if len(failing) == 0 {
+		return
+	}
+	h.collectErrors += int64(len(failing))
+	if lastErr != nil {
+		h.lastError = lastErr.Error()
+		h.lastErrorAt = time.Now().UnixMilli()
+	}
+}
+
+// snapshot 生成当前的运行状况，dropped 为断线缓存丢弃的消息数
+func (h *healthTracker) snapshot(dropped int64) *protocol.AgentHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := &protocol.AgentHealth{
+		CollectErrors:     h.collectErrors,
+		FailingCollectors: append([]string(nil), h.failingCollectors...),
+		LastError:         h.lastError,
+		LastErrorAt:       h.lastErrorAt,
+		DroppedSamples:    dropped,
+		Goroutines:        runtime.NumGoroutine(),
+		UptimeSeconds:     int64(time.Since(h.startedAt).Seconds()),
+	}
+	if h.proc != nil {
+		if percent, err := h.proc.Percent(0); err == nil {
+			health.CPUPercent = percent
+		}
+		if mem, err := h.proc.MemoryInfo(); err == nil {
+			health.MemoryRSS = mem.RSS
+		}
+	}
+	return health
+}
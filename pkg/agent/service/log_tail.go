@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+const (
+	// logTailMaxDuration 单次跟踪的最长时长，服务端请求的时长超过该值时截断
+	logTailMaxDuration = 30 * time.Minute
+	// logTailMaxLinesPerSecond 每秒最多回传的行数上限
+	logTailMaxLinesPerSecond = 500
+	// logTailMaxSessions 同时进行的跟踪数
+	logTailMaxSessions = 3
+	// logTailPollInterval 检查文件新增内容的间隔
+	logTailPollInterval = 500 * time.Millisecond
+	// logTailMaxLineLength 单行最大长度，超出部分截断
+	logTailMaxLineLength = 4096
+)
+
+// handleLogTail 处理日志跟踪请求
+func (a *Agent) handleLogTail(data json.RawMessage) {
+	var req protocol.LogTailRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		slog.Warn("解析日志跟踪请求失败", "error", err)
+		return
+	}
+
+	switch req.Action {
+	case protocol.LogTailActionStop:
+		a.stopLogTail(req.SessionID)
+	case protocol.LogTailActionStart:
+		a.startLogTail(req)
+	default:
+		slog.Warn("未知的日志跟踪动作", "action", req.Action)
+	}
+}
+
+// startLogTail 校验路径后开始跟踪
+func (a *Agent) startLogTail(req protocol.LogTailRequest) {
+	realPath, err := resolveLogTailPath(a.cfg.LogTail.AllowedPaths, req.Path)
+	if err != nil {
+		slog.Warn("拒绝日志跟踪请求", "path", req.Path, "error", err)
+		a.sendLogTailData(protocol.LogTailData{SessionID: req.SessionID, Done: true, Error: err.Error()})
+		return
+	}
+
+	duration := time.Duration(req.MaxDurationSeconds) * time.Second
+	if duration <= 0 || duration > logTailMaxDuration {
+		duration = logTailMaxDuration
+	}
+	rate := req.MaxLinesPerSecond
+	if rate <= 0 || rate > logTailMaxLinesPerSecond {
+		rate = logTailMaxLinesPerSecond
+	}
+
+	a.logTailMu.Lock()
+	if len(a.logTailCancels) >= logTailMaxSessions {
+		a.logTailMu.Unlock()
+		a.sendLogTailData(protocol.LogTailData{SessionID: req.SessionID, Done: true, Error: "同时进行的日志跟踪过多"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	a.logTailCancels[req.SessionID] = cancel
+	a.logTailMu.Unlock()
+
+	slog.Info("开始跟踪日志", "sessionId", req.SessionID, "path", realPath, "duration", duration, "linesPerSecond", rate)
+	go func() {
+		defer a.stopLogTail(req.SessionID)
+
+		err := a.tailFile(ctx, req.SessionID, realPath, rate)
+		done := protocol.LogTailData{SessionID: req.SessionID, Done: true}
+		if err != nil {
+			done.Error = err.Error()
+		}
+		// 被服务端停止时不需要再回传结束消息
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			a.sendLogTailData(done)
+		}
+		slog.Info("结束跟踪日志", "sessionId", req.SessionID, "path", realPath)
+	}()
+}
+
+// stopLogTail 停止跟踪，会话不存在时忽略
+func (a *Agent) stopLogTail(sessionID string) {
+	a.logTailMu.Lock()
+	defer a.logTailMu.Unlock()
+	if cancel, ok := a.logTailCancels[sessionID]; ok {
+		cancel()
+		delete(a.logTailCancels, sessionID)
+	}
+}
+
+// stopAllLogTails 连接断开时停止所有跟踪，重连后服务端的会话已不存在
+func (a *Agent) stopAllLogTails() {
+	a.logTailMu.Lock()
+	defer a.logTailMu.Unlock()
+	for sessionID, cancel := range a.logTailCancels {
+		cancel()
+		delete(a.logTailCancels, sessionID)
+	}
+}
+
+// tailFile 从文件末尾开始跟踪新增内容，按速率限制批量回传，文件被截断或轮转时重新打开
+func (a *Agent) tailFile(ctx context.Context, sessionID, path string, linesPerSecond int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer func() { file.Close() }()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("定位文件末尾失败: %w", err)
+	}
+	reader := bufio.NewReaderSize(file, logTailMaxLineLength)
+
+	ticker := time.NewTicker(logTailPollInterval)
+	defer ticker.Stop()
+
+	var (
+		windowStart = time.Now()
+		windowLines int
+		partial     []byte
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// 检查截断和轮转
+		if info, err := os.Stat(path); err == nil {
+			current, _ := file.Stat()
+			if current == nil || !os.SameFile(info, current) {
+				reopened, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("重新打开文件失败: %w", err)
+				}
+				file.Close()
+				file = reopened
+				offset = 0
+				reader.Reset(file)
+				partial = nil
+			} else if info.Size() < offset {
+				if _, err := file.Seek(0, io.SeekStart); err != nil {
+					return fmt.Errorf("定位文件开头失败: %w", err)
+				}
+				offset = 0
+				reader.Reset(file)
+				partial = nil
+			}
+		}
+
+		var (
+			lines   []string
+			dropped int
+		)
+		for {
+			chunk, err := reader.ReadSlice('\n')
+			offset += int64(len(chunk))
+			if len(partial) < logTailMaxLineLength {
+				partial = append(partial, chunk[:min(len(chunk), logTailMaxLineLength-len(partial))]...)
+			}
+			if errors.Is(err, bufio.ErrBufferFull) {
+				continue
+			}
+			if err != nil {
+				// 未读到换行符，保留不完整的行等待下次读取
+				break
+			}
+
+			line := string(trimLineEnding(partial))
+			partial = partial[:0]
+
+			if now := time.Now(); now.Sub(windowStart) >= time.Second {
+				windowStart = now
+				windowLines = 0
+			}
+			if windowLines >= linesPerSecond {
+				dropped++
+				continue
+			}
+			windowLines++
+			lines = append(lines, line)
+		}
+
+		if len(lines) > 0 || dropped > 0 {
+			if !a.sendLogTailData(protocol.LogTailData{SessionID: sessionID, Lines: lines, Dropped: dropped}) {
+				return fmt.Errorf("连接已断开")
+			}
+		}
+	}
+}
+
+// sendLogTailData 直接发送日志跟踪数据，连接断开时不写入离线缓存
+func (a *Agent) sendLogTailData(data protocol.LogTailData) bool {
+	conn := a.getActiveConn()
+	if conn == nil {
+		return false
+	}
+	if err := conn.WriteJSON(protocol.OutboundMessage{
+		Type: protocol.MessageTypeLogTailData,
+		Data: data,
+	}); err != nil {
+		slog.Warn("发送日志跟踪数据失败", "sessionId", data.SessionID, "error", err)
+		return false
+	}
+	return true
+}
+
+// resolveLogTailPath 解析符号链接后按白名单校验路径，返回实际路径
+func resolveLogTailPath(allowed []string, path string) (string, error) {
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("探针未配置允许跟踪的日志文件（log_tail.allowed_paths）")
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("路径必须为绝对路径")
+	}
+	realPath, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("文件不存在或无法访问")
+	}
+	info, err := os.Stat(realPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("只能跟踪普通文件")
+	}
+	for _, pattern := range allowed {
+		if matched, err := filepath.Match(filepath.Clean(pattern), realPath); err == nil && matched {
+			return realPath, nil
+		}
+	}
+	return "", fmt.Errorf("路径不在允许跟踪的白名单中")
+}
+
+// trimLineEnding 去除行尾的换行符
+func trimLineEnding(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dushixiang/pika/internal/protocol"
@@ -24,8 +25,9 @@ const (
 )
 
 type outboundBuffer struct {
-	path string
-	mu   sync.Mutex
+	path    string
+	mu      sync.Mutex
+	dropped atomic.Int64 // 因写入失败、过期或损坏而丢弃的消息数
 }
 
 type bufferedMessage struct {
@@ -39,6 +41,19 @@ func newOutboundBuffer() *outboundBuffer {
 }
 
 func (b *outboundBuffer) Append(v interface{}) error {
+	if err := b.append(v); err != nil {
+		b.dropped.Add(1)
+		return err
+	}
+	return nil
+}
+
+// Dropped 返回启动以来丢弃的消息数
+func (b *outboundBuffer) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+func (b *outboundBuffer) append(v interface{}) error {
 	payload, err := json.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("序列化缓存消息失败: %w", err)
@@ -91,6 +106,7 @@ func (b *outboundBuffer) Append(v interface{}) error {
 			if err := cursor.Delete(); err != nil {
 				return fmt.Errorf("删除过期缓存失败: %w", err)
 			}
+			b.dropped.Add(1)
 		}
 
 		return nil
@@ -138,6 +154,7 @@ func (b *outboundBuffer) Flush(writer collector.WebSocketWriter) (int, error) {
 					if err := cursor.Delete(); err != nil {
 						return fmt.Errorf("删除过期缓存失败: %w", err)
 					}
+					b.dropped.Add(1)
 					continue
 				}
 
@@ -147,6 +164,7 @@ func (b *outboundBuffer) Flush(writer collector.WebSocketWriter) (int, error) {
 					if err := cursor.Delete(); err != nil {
 						return fmt.Errorf("删除损坏缓存失败: %w", err)
 					}
+					b.dropped.Add(1)
 					continue
 				}
 
@@ -168,6 +186,7 @@ func (b *outboundBuffer) Flush(writer collector.WebSocketWriter) (int, error) {
 				if err := cursor.Delete(); err != nil {
 					return fmt.Errorf("删除损坏缓存失败: %w", err)
 				}
+				b.dropped.Add(1)
 				continue
 			}
 
@@ -210,14 +229,16 @@ func (b *outboundBuffer) openDB() (*bolt.DB, error) {
 type outboundWriter struct {
 	conn     *safeConn
 	buffer   *outboundBuffer
+	sent     *sentFrames
 	buffered bool
 	sendErr  error
 }
 
-func newOutboundWriter(conn *safeConn, buffer *outboundBuffer) *outboundWriter {
+func newOutboundWriter(conn *safeConn, buffer *outboundBuffer, sent *sentFrames) *outboundWriter {
 	return &outboundWriter{
 		conn:   conn,
 		buffer: buffer,
+		sent:   sent,
 	}
 }
 
@@ -239,5 +260,8 @@ func (w *outboundWriter) WriteJSON(v interface{}) error {
 		return nil
 	}
 
+	if w.sent != nil {
+		w.sent.remember(v)
+	}
 	return nil
 }
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+const (
+	// sentFrameRetention 已发送指标的保留时间，超过后服务端要求重发时不再重发
+	sentFrameRetention = 10 * time.Minute
+	// maxSentFrames 最多保留的已发送指标数，超出时淘汰最早发送的记录
+	maxSentFrames = 256
+	// resendBaseDelay 首次重发的等待时间，之后每次翻倍
+	resendBaseDelay = 2 * time.Second
+	// resendMaxDelay 重发等待时间的上限
+	resendMaxDelay = time.Minute
+	// maxResendAttempts 同一条指标最多重发的次数
+	maxResendAttempts = 5
+)
+
+type sentFrameKey struct {
+	metricType protocol.MetricType
+	timestamp  int64
+}
+
+type sentFrame struct {
+	msg      protocol.OutboundMessage
+	sentAt   time.Time
+	attempts int
+}
+
+// sentFrames 最近发送的指标，服务端写入失败要求重发时按类型和采集时间找回原始数据
+type sentFrames struct {
+	mu     sync.Mutex
+	frames map[sentFrameKey]*sentFrame
+}
+
+func newSentFrames() *sentFrames {
+	return &sentFrames{frames: make(map[sentFrameKey]*sentFrame)}
+}
+
+// remember 记录发送成功的指标消息，其他类型的消息忽略
+func (f *sentFrames) remember(v interface{}) {
+	msg, ok := v.(protocol.OutboundMessage)
+	if !ok || msg.Type != protocol.MessageTypeMetrics {
+		return
+	}
+	payload, ok := msg.Data.(protocol.MetricsPayload)
+	if !ok || payload.Timestamp == 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	key := sentFrameKey{metricType: payload.Type, timestamp: payload.Timestamp}
+	if _, ok := f.frames[key]; ok {
+		return
+	}
+	f.prune(now)
+	if len(f.frames) >= maxSentFrames {
+		f.evictOldest()
+	}
+	f.frames[key] = &sentFrame{msg: msg, sentAt: now}
+}
+
+// evictOldest 删除最早发送的记录，保证最近发送的指标可以重发
+func (f *sentFrames) evictOldest() {
+	var oldestKey sentFrameKey
+	var oldest *sentFrame
+	for key, frame := range f.frames {
+		if oldest == nil || frame.sentAt.Before(oldest.sentAt) ||
+			(frame.sentAt.Equal(oldest.sentAt) && key.timestamp < oldestKey.timestamp) {
+			oldestKey, oldest = key, frame
+		}
+	}
+	if oldest != nil {
+		delete(f.frames, oldestKey)
+	}
+}
+
+// prune 删除超过保留时间的记录
+func (f *sentFrames) prune(now time.Time) {
+	for key, frame := range f.frames {
+		if now.Sub(frame.sentAt) > sentFrameRetention {
+			delete(f.frames, key)
+		}
+	}
+}
+
+// next 返回需要重发的原始消息及等待时间，找不到或超过重发次数时返回 false
+func (f *sentFrames) next(metricType protocol.MetricType, timestamp int64) (protocol.OutboundMessage, time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := sentFrameKey{metricType: metricType, timestamp: timestamp}
+	frame, ok := f.frames[key]
+	if !ok {
+		return protocol.OutboundMessage{}, 0, false
+	}
+	if frame.attempts >= maxResendAttempts {
+		delete(f.frames, key)
+		return protocol.OutboundMessage{}, 0, false
+	}
+	frame.attempts++
+	return frame.msg, resendDelay(frame.attempts), true
+}
+
+// resendDelay 第 attempt 次重发的等待时间
+func resendDelay(attempt int) time.Duration {
+	delay := resendBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > resendMaxDelay {
+		return resendMaxDelay
+	}
+	return delay
+}
+
+// scheduleResend 等待退避时间后将原始指标写入发送缓存，由下一次采集时随缓存一起发送
+// 重发的是原始数据而不是重新采集，服务端按原采集时间写入，不会与后续样本重复
+func (a *Agent) scheduleResend(result protocol.MetricsResult) {
+	msg, delay, ok := a.sentFrames.next(result.Type, result.Timestamp)
+	if !ok {
+		slog.Warn("未找到需要重发的指标或已超过重发次数，放弃重发", "type", result.Type, "timestamp", result.Timestamp)
+		return
+	}
+	time.AfterFunc(delay, func() {
+		if err := a.outboundBuffer.Append(msg); err != nil {
+			slog.Warn("重发指标写入缓存失败", "type", result.Type, "error", err)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dushixiang/pika/internal/protocol"
+)
+
+func TestSentFramesEvictsOldest(t *testing.T) {
+	frames := newSentFrames()
+	remember := func(timestamp int64) {
+		frames.remember(protocol.OutboundMessage{
+			Type: protocol.MessageTypeMetrics,
+			Data: protocol.MetricsPayload{Type: protocol.MetricTypeCPU, Timestamp: timestamp},
+		})
+	}
+
+	total := int64(maxSentFrames + 10)
+	for ts := int64(1); ts <= total; ts++ {
+		remember(ts)
+	}
+	if len(frames.frames) != maxSentFrames {
+		t.Fatalf("frames = %d, want %d", len(frames.frames), maxSentFrames)
+	}
+
+	// 超出上限后最近发送的指标仍可重发
+	msg, _, ok := frames.next(protocol.MetricTypeCPU, total)
+	if !ok {
+		t.Fatal("recent frame should be resendable")
+	}
+	if payload := msg.Data.(protocol.MetricsPayload); payload.Timestamp != total {
+		t.Fatalf("resent timestamp = %d, want %d", payload.Timestamp, total)
+	}
+	// 最早的记录被淘汰
+	if _, _, ok := frames.next(protocol.MetricTypeCPU, 1); ok {
+		t.Fatal("oldest frame should be evicted")
+	}
+}
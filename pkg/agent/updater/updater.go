@@ -11,12 +11,14 @@ import (
 	"time"
 
 	"github.com/dushixiang/pika/pkg/agent/config"
+	"github.com/dushixiang/pika/pkg/version"
 	"github.com/minio/selfupdate"
 )
 
 // VersionInfo 版本信息
 type VersionInfo struct {
-	Version string `json:"version"`
+	Version       string `json:"version"`
+	ServerVersion string `json:"serverVersion,omitempty"` // 服务端版本（旧版服务端不返回）
 }
 
 // Updater 自动更新器
@@ -92,6 +94,8 @@ func (u *Updater) CheckAndUpdate() {
 		return
 	}
 
+	u.checkServerVersion(versionInfo.ServerVersion)
+
 	// 比较版本
 	if versionInfo.Version == u.currentVer {
 		slog.Debug("当前已是最新版本", "version", u.currentVer)
@@ -109,6 +113,15 @@ func (u *Updater) CheckAndUpdate() {
 	slog.Info("更新成功，将在下次重启时生效")
 }
 
+// checkServerVersion 检查服务端版本是否与构建本探针时的服务端版本一致，不一致时给出提示
+func (u *Updater) checkServerVersion(serverVersion string) {
+	builtWith := version.GetVersion()
+	if version.IsDev(serverVersion) || version.IsDev(builtWith) || serverVersion == builtWith {
+		return
+	}
+	slog.Warn("探针与服务端版本不一致，可能存在兼容性问题", "server_version", serverVersion, "agent_built_for", builtWith)
+}
+
 // fetchLatestVersion 获取最新版本信息
 func (u *Updater) fetchLatestVersion() (*VersionInfo, error) {
 	latestVersionURL := u.cfg.GetLatestVersionURL()
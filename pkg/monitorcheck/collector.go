@@ -1,4 +1,4 @@
-package collector
+package monitorcheck
 
 import (
 	"context"
@@ -15,22 +15,41 @@ import (
 	"github.com/dushixiang/pika/internal/protocol"
 )
 
-// MonitorCollector 监控采集器
-type MonitorCollector struct {
-	httpClient *http.Client
+// Collector 监控采集器
+type Collector struct {
+	insecureTransport *http.Transport // 跳过 TLS 验证，允许自签名证书（默认）
+	verifyTransport   *http.Transport // 校验 TLS 证书
 }
 
-// NewMonitorCollector 创建监控采集器
-func NewMonitorCollector() *MonitorCollector {
-	// 创建自定义的 HTTP 客户端，支持跳过 TLS 验证
-	httpClient := &http.Client{
-		Transport: &http.Transport{
+// NewCollector 创建监控采集器
+func NewCollector() *Collector {
+	return &Collector{
+		insecureTransport: &http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true, // 允许自签名证书
 			},
 			DisableKeepAlives: true,
 		},
+		verifyTransport: &http.Transport{
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+// httpClient 按监控项的 TLS 校验和重定向配置创建 HTTP 客户端
+func (c *Collector) httpClient(cfg *protocol.HTTPMonitorConfig) *http.Client {
+	transport := c.insecureTransport
+	if cfg.VerifyTLS {
+		transport = c.verifyTransport
+	}
+	followRedirects := cfg.ShouldFollowRedirects()
+	return &http.Client{
+		Transport: transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// 不跟随重定向时直接返回重定向响应
+			if !followRedirects {
+				return http.ErrUseLastResponse
+			}
 			// 限制重定向次数为 10
 			if len(via) >= 10 {
 				return fmt.Errorf("stopped after 10 redirects")
@@ -38,14 +57,10 @@ func NewMonitorCollector() *MonitorCollector {
 			return nil
 		},
 	}
-
-	return &MonitorCollector{
-		httpClient: httpClient,
-	}
 }
 
 // Collect 采集所有监控项数据
-func (c *MonitorCollector) Collect(items []protocol.MonitorItem) []protocol.MonitorData {
+func (c *Collector) Collect(items []protocol.MonitorItem) []protocol.MonitorData {
 	if len(items) == 0 {
 		return nil
 	}
@@ -80,7 +95,7 @@ func (c *MonitorCollector) Collect(items []protocol.MonitorItem) []protocol.Moni
 }
 
 // checkHTTP 检查 HTTP/HTTPS 服务
-func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.MonitorData {
+func (c *Collector) checkHTTP(item protocol.MonitorItem) protocol.MonitorData {
 	result := protocol.MonitorData{
 		MonitorId: item.ID,
 		Type:      item.Type,
@@ -99,7 +114,7 @@ func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.Monitor
 	}
 
 	// 设置默认值
-	method := httpCfg.Method
+	method := strings.ToUpper(httpCfg.Method)
 	if method == "" {
 		method = "GET"
 	}
@@ -109,11 +124,6 @@ func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.Monitor
 		timeout = 60
 	}
 
-	expectedStatus := httpCfg.ExpectedStatusCode
-	if expectedStatus == 0 {
-		expectedStatus = 200
-	}
-
 	// 创建请求
 	var bodyReader io.Reader
 	if httpCfg.Body != "" {
@@ -132,16 +142,18 @@ func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.Monitor
 		return result
 	}
 
-	// 设置请求头
-	if httpCfg.Headers != nil {
-		for key, value := range httpCfg.Headers {
-			req.Header.Set(key, value)
+	// 设置请求头，Host 需要通过 req.Host 设置
+	for key, value := range httpCfg.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
 		}
+		req.Header.Set(key, value)
 	}
 
 	// 发送请求并计时
 	startTime := time.Now()
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.httpClient(httpCfg).Do(req)
 	responseTime := time.Since(startTime).Milliseconds()
 	result.ResponseTime = responseTime
 
@@ -155,15 +167,15 @@ func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.Monitor
 	result.StatusCode = resp.StatusCode
 
 	// 检查状态码
-	if resp.StatusCode != expectedStatus {
+	if !httpCfg.MatchStatusCode(resp.StatusCode) {
 		result.Status = "down"
-		result.Error = fmt.Sprintf("status code mismatch: expected %d, got %d", expectedStatus, resp.StatusCode)
+		result.Error = fmt.Sprintf("status code mismatch: expected %s, got %d", httpCfg.ExpectedStatusText(), resp.StatusCode)
 		result.Message = fmt.Sprintf("HTTP %d", resp.StatusCode)
 		return result
 	}
 
 	// 检查响应内容（如果有配置）
-	if httpCfg.ExpectedContent != "" {
+	if httpCfg.HasContentAssertion() {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			result.Status = "down"
@@ -171,10 +183,9 @@ func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.Monitor
 			return result
 		}
 
-		bodyStr := string(body)
-		if !strings.Contains(bodyStr, httpCfg.ExpectedContent) {
+		if err := httpCfg.CheckContent(string(body)); err != nil {
 			result.Status = "down"
-			result.Error = fmt.Sprintf("content does not contain expected string: %s", httpCfg.ExpectedContent)
+			result.Error = err.Error()
 			result.ContentMatch = false
 			return result
 		}
@@ -202,7 +213,7 @@ func (c *MonitorCollector) checkHTTP(item protocol.MonitorItem) protocol.Monitor
 }
 
 // checkTCP 检查 TCP 端口
-func (c *MonitorCollector) checkTCP(item protocol.MonitorItem) protocol.MonitorData {
+func (c *Collector) checkTCP(item protocol.MonitorItem) protocol.MonitorData {
 	result := protocol.MonitorData{
 		MonitorId: item.ID,
 		Type:      item.Type,
@@ -237,7 +248,7 @@ func (c *MonitorCollector) checkTCP(item protocol.MonitorItem) protocol.MonitorD
 }
 
 // checkICMP 检查 ICMP (Ping)
-func (c *MonitorCollector) checkICMP(item protocol.MonitorItem) protocol.MonitorData {
+func (c *Collector) checkICMP(item protocol.MonitorItem) protocol.MonitorData {
 	result := protocol.MonitorData{
 		MonitorId: item.ID,
 		Type:      item.Type,
@@ -1,11 +1,31 @@
 package version
 
+import (
+	"runtime"
+)
+
 // Version 服务端版本号（通过 -ldflags 注入）
 var Version = "dev"
 
 // AgentVersion Agent 版本号（通过 -ldflags 注入）
 var AgentVersion = "dev"
 
+// GitCommit 构建时的 git 提交（通过 -ldflags 注入）
+var GitCommit = ""
+
+// BuildTime 构建时间（通过 -ldflags 注入）
+var BuildTime = ""
+
+// BuildInfo 构建信息
+type BuildInfo struct {
+	Version      string `json:"version"`
+	AgentVersion string `json:"agentVersion"`
+	GitCommit    string `json:"gitCommit"`
+	BuildTime    string `json:"buildTime"`
+	GoVersion    string `json:"goVersion"`
+	Platform     string `json:"platform"`
+}
+
 // GetVersion 获取服务端版本号
 func GetVersion() string {
 	if Version == "" {
@@ -21,3 +41,20 @@ func GetAgentVersion() string {
 	}
 	return AgentVersion
 }
+
+// GetBuildInfo 获取构建信息
+func GetBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:      GetVersion(),
+		AgentVersion: GetAgentVersion(),
+		GitCommit:    GitCommit,
+		BuildTime:    BuildTime,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// IsDev 是否为未注入版本号的开发构建
+func IsDev(v string) bool {
+	return v == "" || v == "dev"
+}
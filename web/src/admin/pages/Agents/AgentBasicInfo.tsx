@@ -241,6 +241,37 @@ const AgentBasicInfo = ({agentId}: AgentBasicInfoProps) => {
                             </Space>
                         ) : '-'}
                     </Descriptions.Item>
+                    <Descriptions.Item label="时钟偏差">
+                        <Space>
+                            <span>{((agent.clockSkew || 0) / 1000).toFixed(1)} 秒</span>
+                            {agent.clockSkewed && <Tag color="warning">超出容忍范围，请检查探针时钟同步</Tag>}
+                        </Space>
+                    </Descriptions.Item>
+                    <Descriptions.Item label="运行状况" span={{xs: 1, sm: 2, lg: 3}}>
+                        {agent.health?.reportedAt ? (
+                            <div className="space-y-1">
+                                <Space wrap>
+                                    {agent.health.failingCollectors?.length ? (
+                                        <Tag color="error">采集失败：{agent.health.failingCollectors.join(', ')}</Tag>
+                                    ) : (
+                                        <Tag color="success">采集正常</Tag>
+                                    )}
+                                    <span>累计采集失败 {agent.health.collectErrors} 次</span>
+                                    <span>丢弃消息 {agent.health.droppedSamples} 条</span>
+                                    <span>CPU {agent.health.cpuPercent.toFixed(1)}%</span>
+                                    <span>内存 {(agent.health.memoryRss / 1024 / 1024).toFixed(1)} MB</span>
+                                    <span>协程 {agent.health.goroutines}</span>
+                                </Space>
+                                {agent.health.lastError && (
+                                    <div className="text-xs text-gray-500">
+                                        最近错误（{dayjs(agent.health.lastErrorAt).format('YYYY-MM-DD HH:mm:ss')}）：{agent.health.lastError}
+                                    </div>
+                                )}
+                            </div>
+                        ) : (
+                            <span className="text-gray-400">探针版本较旧，未上报</span>
+                        )}
+                    </Descriptions.Item>
                 </Descriptions>
             </Card>
 
@@ -214,9 +214,16 @@ const AgentList = () => {
             key: 'status',
             width: 80,
             render: (_, record) => (
-                <Tag color={record.status === 1 ? 'success' : 'default'}>
-                    {record.status === 1 ? '在线' : '离线'}
-                </Tag>
+                <Space size={4} direction="vertical">
+                    <Tag color={record.status === 1 ? 'success' : 'default'}>
+                        {record.status === 1 ? '在线' : '离线'}
+                    </Tag>
+                    {record.clockSkewed && (
+                        <Tag color="warning" title={`时钟偏差 ${((record.clockSkew || 0) / 1000).toFixed(1)} 秒`}>
+                            时钟偏差
+                        </Tag>
+                    )}
+                </Space>
             ),
         },
         {
@@ -0,0 +1,87 @@
+import {useState} from 'react';
+import {App, Button, Drawer, Empty, Input, List, Space} from 'antd';
+import dayjs from 'dayjs';
+import {useQuery, useQueryClient} from '@tanstack/react-query';
+import {addAlertComment, getAlertComments} from '@/api/alert.ts';
+import type {AlertRecord} from '@/types';
+import {getErrorMessage} from '@/lib/utils';
+
+interface CommentsDrawerProps {
+    record: AlertRecord | null;
+    onClose: () => void;
+}
+
+const CommentsDrawer = ({record, onClose}: CommentsDrawerProps) => {
+    const {message: messageApi} = App.useApp();
+    const queryClient = useQueryClient();
+    const [text, setText] = useState('');
+    const [submitting, setSubmitting] = useState(false);
+
+    const {data: comments = [], isLoading} = useQuery({
+        queryKey: ['admin', 'alert-comments', record?.id],
+        queryFn: () => getAlertComments(record!.id),
+        enabled: !!record,
+    });
+
+    const handleSubmit = async () => {
+        if (!record || !text.trim()) {
+            return;
+        }
+        setSubmitting(true);
+        try {
+            await addAlertComment(record.id, text.trim());
+            setText('');
+            queryClient.invalidateQueries({queryKey: ['admin', 'alert-comments', record.id]});
+            queryClient.invalidateQueries({queryKey: ['admin', 'alert-records']});
+        } catch (error: unknown) {
+            messageApi.error(getErrorMessage(error, '添加备注失败'));
+        } finally {
+            setSubmitting(false);
+        }
+    };
+
+    return (
+        <Drawer
+            title={record ? `告警备注 #${record.id}` : '告警备注'}
+            open={!!record}
+            onClose={onClose}
+            width={520}
+            destroyOnHidden
+        >
+            <div className="text-sm text-gray-500 dark:text-gray-400 mb-4">
+                {record?.agentName} · {record?.message}
+            </div>
+            <List
+                loading={isLoading}
+                dataSource={comments}
+                locale={{emptyText: <Empty description="暂无备注"/>}}
+                renderItem={(comment) => (
+                    <List.Item>
+                        <div className="w-full">
+                            <div className="flex justify-between text-xs text-gray-400 dark:text-gray-500">
+                                <span>{comment.username || '-'}</span>
+                                <span>{dayjs(comment.createdAt).format('YYYY-MM-DD HH:mm:ss')}</span>
+                            </div>
+                            <div className="mt-1 whitespace-pre-wrap break-all">{comment.text}</div>
+                        </div>
+                    </List.Item>
+                )}
+            />
+            <Space direction="vertical" className="w-full mt-4">
+                <Input.TextArea
+                    value={text}
+                    onChange={(e) => setText(e.target.value)}
+                    placeholder="记录排查过程或结论，如：确认为误报，已调整阈值"
+                    autoSize={{minRows: 3, maxRows: 8}}
+                    maxLength={2000}
+                    showCount
+                />
+                <Button type="primary" loading={submitting} disabled={!text.trim()} onClick={handleSubmit}>
+                    添加备注
+                </Button>
+            </Space>
+        </Drawer>
+    );
+};
+
+export default CommentsDrawer;
@@ -1,8 +1,8 @@
 import React, {useState} from 'react';
 import {useSearchParams} from 'react-router-dom';
-import {App, Divider, Select, Space, Table, Tag} from 'antd';
+import {App, Button, Divider, Select, Space, Table, Tag} from 'antd';
 import type {ColumnsType, TablePaginationConfig} from 'antd/es/table';
-import {Trash2} from 'lucide-react';
+import {MessageSquare, Trash2} from 'lucide-react';
 import {clearAlertRecords, getAlertRecords} from '@/api/alert.ts';
 import type {AlertRecord} from '@/types';
 import dayjs from 'dayjs';
@@ -10,12 +10,14 @@ import {getErrorMessage} from '@/lib/utils';
 import {PageHeader} from '@admin/components';
 import {useQuery, useQueryClient} from '@tanstack/react-query';
 import {listAgentsByAdmin} from "@/api/agent.ts";
+import CommentsDrawer from './CommentsDrawer';
 
 const AlertRecordList = () => {
     const {message: messageApi, modal} = App.useApp();
     const queryClient = useQueryClient();
     const [searchParams, setSearchParams] = useSearchParams();
     const [selectedAgentId, setSelectedAgentId] = useState<string>('');
+    const [commentRecord, setCommentRecord] = useState<AlertRecord | null>(null);
 
     const pageIndex = Number(searchParams.get('pageIndex')) || 1;
     const pageSize = Number(searchParams.get('pageSize')) || 20;
@@ -35,10 +37,12 @@ const AlertRecordList = () => {
         memory: '内存使用率',
         disk: '磁盘使用率',
         network: '网速',
+        connection: '连接数',
         traffic: '流量',
         cert: 'HTTPS证书',
         service: '服务下线',
         agent_offline: '探针离线',
+        collector_error: '探针采集异常',
     };
 
     // 告警级别映射
@@ -159,7 +163,8 @@ const AlertRecordList = () => {
             title: '告警类型',
             dataIndex: 'alertType',
             width: 120,
-            render: (_, record) => alertTypeMap[record.alertType] || record.alertType,
+            render: (_, record) => alertTypeMap[record.alertType]
+                || (record.alertType.startsWith('connection_') ? '连接状态' : record.alertType),
         },
         {
             title: '告警消息',
@@ -174,6 +179,9 @@ const AlertRecordList = () => {
                 if (record.alertType === 'network') {
                     return `${record.threshold.toFixed(2)} MB/s`;
                 }
+                if (record.alertType.startsWith('connection')) {
+                    return `${record.threshold.toFixed(0)}`;
+                }
                 if (record.alertType === 'cert') {
                     return `${record.threshold.toFixed(0)} 天`;
                 }
@@ -191,6 +199,9 @@ const AlertRecordList = () => {
                 if (record.alertType === 'network') {
                     return `${record.actualValue.toFixed(2)} MB/s`;
                 }
+                if (record.alertType.startsWith('connection')) {
+                    return `${record.actualValue.toFixed(0)}`;
+                }
                 if (record.alertType === 'cert') {
                     return `${record.actualValue.toFixed(0)} 天`;
                 }
@@ -231,6 +242,21 @@ const AlertRecordList = () => {
             width: 130,
             render: (_, record) => formatDuration(record.firedAt, record.resolvedAt, record.status),
         },
+        {
+            title: '备注',
+            dataIndex: 'commentCount',
+            width: 80,
+            render: (_, record) => (
+                <Button
+                    type="link"
+                    size="small"
+                    icon={<MessageSquare className="h-3.5 w-3.5"/>}
+                    onClick={() => setCommentRecord(record)}
+                >
+                    {record.commentCount || 0}
+                </Button>
+            ),
+        },
     ];
 
     return (
@@ -288,6 +314,8 @@ const AlertRecordList = () => {
                 }}
                 onChange={handleTableChange}
             />
+
+            <CommentsDrawer record={commentRecord} onClose={() => setCommentRecord(null)}/>
         </div>
     );
 };
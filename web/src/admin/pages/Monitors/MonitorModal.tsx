@@ -72,6 +72,14 @@ const MonitorModal = ({open, monitorId, onCancel, onSuccess}: MonitorModalProps)
         [agents],
     );
 
+    const tagOptions = useMemo(
+        () =>
+            Array.from(new Set(agents.flatMap((agent: Agent) => agent.tags || [])))
+                .sort()
+                .map((tag) => ({label: tag, value: tag})),
+        [agents],
+    );
+
     useEffect(() => {
         if (!open) {
             return;
@@ -276,6 +284,16 @@ const MonitorModal = ({open, monitorId, onCancel, onSuccess}: MonitorModalProps)
                     />
                 </Form.Item>
 
+                <Form.Item label="探针标签" name="tags" extra="带有任一标签的探针也会执行此监控，与探针范围取并集">
+                    <Select
+                        mode="tags"
+                        placeholder="选择或输入探针标签"
+                        options={tagOptions}
+                        loading={loadingAgents}
+                        allowClear
+                    />
+                </Form.Item>
+
                 <Form.Item
                     label="检测频率 (秒)"
                     name="interval"
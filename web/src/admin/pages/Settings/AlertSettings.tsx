@@ -1,10 +1,25 @@
 import { useEffect } from 'react';
-import { App, Button, Card, Form, InputNumber, Space, Switch } from 'antd';
+import { App, Button, Card, Form, InputNumber, Select, Space, Switch } from 'antd';
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 import type { AlertConfig } from '@/api/property';
 import { getAlertConfig, saveAlertConfig } from '@/api/property';
 import { getErrorMessage } from '@/lib/utils';
 
+// 可配置阈值的连接状态
+const connectionStateOptions = [
+    { label: 'ESTABLISHED', value: 'established' },
+    { label: 'SYN_SENT', value: 'synSent' },
+    { label: 'SYN_RECV', value: 'synRecv' },
+    { label: 'FIN_WAIT1', value: 'finWait1' },
+    { label: 'FIN_WAIT2', value: 'finWait2' },
+    { label: 'TIME_WAIT', value: 'timeWait' },
+    { label: 'CLOSE', value: 'close' },
+    { label: 'CLOSE_WAIT', value: 'closeWait' },
+    { label: 'LAST_ACK', value: 'lastAck' },
+    { label: 'LISTEN', value: 'listen' },
+    { label: 'CLOSING', value: 'closing' },
+];
+
 const AlertSettings = () => {
     const [form] = Form.useForm();
     const { message: messageApi } = App.useApp();
@@ -19,7 +34,13 @@ const AlertSettings = () => {
     // 设置表单默认值
     useEffect(() => {
         if (configData) {
-            form.setFieldsValue(configData);
+            form.setFieldsValue({
+                ...configData,
+                // 兼容旧配置：未设置打码粒度时按 maskIP 开关推断
+                maskIPMode: configData.maskIPMode || (configData.maskIP ? 'partial' : 'none'),
+                // 未配置时默认发送恢复通知
+                notifyOnResolve: configData.notifyOnResolve ?? true,
+            });
         }
     }, [configData, configLoading, form]);
 
@@ -37,7 +58,8 @@ const AlertSettings = () => {
 
     const handleSubmit = async () => {
         const values = await form.validateFields();
-        saveMutation.mutate(values as AlertConfig);
+        // 模板绑定不在表单中编辑，保存时保留原有配置
+        saveMutation.mutate({...values, templateBindings: configData?.templateBindings} as AlertConfig);
     };
 
     return (
@@ -50,15 +72,29 @@ const AlertSettings = () => {
                         </Form.Item>
                         <Form.Item
                             label="IP 打码"
-                            name="maskIP"
-                            valuePropName="checked"
-                            tooltip="开启后，通知消息中的 IP 地址将显示为 192.168.*.* 格式"
+                            name="maskIPMode"
+                            tooltip="部分打码隐藏 IPv4 最后一段（192.168.1.*）和 IPv6 接口标识；完全打码隐藏整个地址"
                         >
-                            <Switch checkedChildren="开启" unCheckedChildren="关闭" />
+                            <Select
+                                className="w-48"
+                                options={[
+                                    { label: '不打码', value: 'none' },
+                                    { label: '部分打码', value: 'partial' },
+                                    { label: '完全打码', value: 'full' },
+                                ]}
+                            />
                         </Form.Item>
                     </Card>
 
                     <Card title="通知开关" type="inner">
+                        <Form.Item
+                            label="告警恢复通知"
+                            name="notifyOnResolve"
+                            valuePropName="checked"
+                            tooltip="关闭后告警恢复仍会记录，只是不再发送恢复通知；渠道单独配置时以渠道配置为准"
+                        >
+                            <Switch checkedChildren="开启" unCheckedChildren="关闭" />
+                        </Form.Item>
                         <Form.Item
                             label="流量告警通知"
                             name={['notifications', 'trafficEnabled']}
@@ -131,6 +167,72 @@ const AlertSettings = () => {
                         </Card>
                     ))}
 
+                    <Card title="连接数告警规则" type="inner">
+                        <Form.Item noStyle shouldUpdate>
+                            {({ getFieldValue }) => {
+                                const enabled = getFieldValue(['rules', 'connectionEnabled']);
+                                return (
+                                    <>
+                                        <div className="flex items-center gap-8">
+                                            <Form.Item
+                                                label="开关"
+                                                name={['rules', 'connectionEnabled']}
+                                                valuePropName="checked"
+                                                className="mb-0"
+                                            >
+                                                <Switch />
+                                            </Form.Item>
+                                            <Form.Item
+                                                label="总连接数阈值"
+                                                name={['rules', 'connectionThreshold']}
+                                                className="mb-0"
+                                                tooltip="为 0 时不检查总连接数"
+                                            >
+                                                <InputNumber min={0} style={{ width: '100%' }} disabled={!enabled} />
+                                            </Form.Item>
+                                            <Form.Item
+                                                label="持续时间（秒）"
+                                                name={['rules', 'connectionDuration']}
+                                                className="mb-0"
+                                            >
+                                                <InputNumber min={1} max={3600} style={{ width: '100%' }}
+                                                    disabled={!enabled} />
+                                            </Form.Item>
+                                        </div>
+                                        <Form.List name={['rules', 'connectionStates']}>
+                                            {(fields, { add, remove }) => (
+                                                <div className="mt-4 space-y-2">
+                                                    {fields.map((field) => (
+                                                        <div key={field.key} className="flex items-center gap-4">
+                                                            <Form.Item name={[field.name, 'state']} className="mb-0">
+                                                                <Select
+                                                                    className="w-40"
+                                                                    disabled={!enabled}
+                                                                    options={connectionStateOptions}
+                                                                />
+                                                            </Form.Item>
+                                                            <Form.Item name={[field.name, 'threshold']} className="mb-0">
+                                                                <InputNumber min={1} placeholder="连接数阈值" disabled={!enabled} />
+                                                            </Form.Item>
+                                                            <Button type="link" danger disabled={!enabled}
+                                                                onClick={() => remove(field.name)}>
+                                                                删除
+                                                            </Button>
+                                                        </div>
+                                                    ))}
+                                                    <Button type="dashed" disabled={!enabled}
+                                                        onClick={() => add({ state: 'closeWait', threshold: 500 })}>
+                                                        添加连接状态阈值
+                                                    </Button>
+                                                </div>
+                                            )}
+                                        </Form.List>
+                                    </>
+                                );
+                            }}
+                        </Form.Item>
+                    </Card>
+
                     <Card title="HTTPS 证书告警规则" type="inner">
                         <Form.Item noStyle shouldUpdate>
                             {({ getFieldValue }) => {
@@ -230,6 +332,39 @@ const AlertSettings = () => {
                         </Form.Item>
                     </Card>
 
+                    <Card title="探针采集异常告警规则" type="inner">
+                        <Form.Item noStyle shouldUpdate>
+                            {({ getFieldValue }) => {
+                                const enabled = getFieldValue(['rules', 'collectorErrorEnabled']);
+                                return (
+                                    <div className="flex items-center gap-8">
+                                        <Form.Item
+                                            label="开关"
+                                            name={['rules', 'collectorErrorEnabled']}
+                                            valuePropName="checked"
+                                            className="mb-0"
+                                        >
+                                            <Switch />
+                                        </Form.Item>
+                                        <Form.Item
+                                            label="持续时间（秒）"
+                                            name={['rules', 'collectorErrorDuration']}
+                                            className="mb-0"
+                                            tooltip="探针心跳上报的指标采集持续失败多久后触发告警，默认 600 秒"
+                                        >
+                                            <InputNumber
+                                                min={1}
+                                                max={86400}
+                                                style={{ width: '100%' }}
+                                                disabled={!enabled}
+                                            />
+                                        </Form.Item>
+                                    </div>
+                                );
+                            }}
+                        </Form.Item>
+                    </Card>
+
                     <Button
                         type="primary"
                         loading={saveMutation.isPending}
@@ -104,6 +104,7 @@ const NotificationChannels = () => {
                     formValues.webhookUrl = channel.config?.url || '';
                     formValues.webhookMethod = channel.config?.method || 'POST';
                     formValues.webhookCustomBody = channel.config?.customBody || '';
+                    formValues.webhookSchemaVersion = channel.config?.schemaVersion;
 
                     // 解析 headers 为数组形式方便编辑
                     const headers = channel.config?.headers || {};
@@ -223,11 +224,26 @@ const NotificationChannels = () => {
                         url: values.webhookUrl || '',
                         method: values.webhookMethod || 'POST',
                         customBody: values.webhookCustomBody || '',
+                        schemaVersion: values.webhookSchemaVersion || undefined,
                         headers: Object.keys(headersObj).length > 0 ? headersObj : undefined,
                     },
                 });
             }
 
+            // 保留已有渠道的 ID、最低告警级别和恢复通知配置
+            newChannels.forEach((channel) => {
+                const existing = channels.find((item) => item.type === channel.type);
+                if (existing?.id) {
+                    channel.id = existing.id;
+                }
+                if (existing?.minLevel) {
+                    channel.minLevel = existing.minLevel;
+                }
+                if (existing?.notifyOnResolve !== undefined) {
+                    channel.notifyOnResolve = existing.notifyOnResolve;
+                }
+            });
+
             saveMutation.mutate(newChannels);
         } catch (error) {
             // 表单验证失败
@@ -747,23 +763,45 @@ const NotificationChannels = () => {
                                             />
                                         </Form.Item>
 
+                                        {/* 请求体结构版本 */}
+                                        <Form.Item
+                                            label="请求体结构版本"
+                                            name="webhookSchemaVersion"
+                                            tooltip="固定版本后，新版本的不兼容变更不会影响该接收端；不固定时使用当前版本"
+                                        >
+                                            <Select
+                                                allowClear
+                                                placeholder="使用当前版本"
+                                                options={[
+                                                    {label: 'v1', value: 1},
+                                                ]}
+                                            />
+                                        </Form.Item>
+
                                         {/* 自定义请求体 */}
                                         <Form.Item
                                             label="自定义请求体"
                                             name="webhookCustomBody"
-                                            rules={[
-                                                {
-                                                    required: true,
-                                                    message: '请输入自定义请求体模板'
-                                                }
-                                            ]}
-                                            tooltip="支持变量替换，可用变量见下方说明"
+                                            tooltip="支持变量替换，可用变量见下方说明；为空时发送对应结构版本的默认 JSON 请求体"
                                         >
                                             <Input.TextArea
                                                 rows={6}
                                                 placeholder='示例: {"alert": "{{alert.message}}", "host": "{{agent.hostname}}"}'
                                             />
                                         </Form.Item>
+                                        <Alert
+                                            className="mb-4"
+                                            type="info"
+                                            showIcon
+                                            title="可用变量（未定义的变量渲染为空）"
+                                            description={
+                                                <div className="text-xs space-y-1">
+                                                    <div>通用：{'{{schemaVersion}}'}、{'{{message}}'}、{'{{event.category}}'}（agent / monitor）、{'{{alert.type}}'}、{'{{alert.level}}'}、{'{{alert.status}}'}、{'{{alert.message}}'}、{'{{alert.threshold}}'}、{'{{alert.actualValue}}'}、{'{{alert.firedAt}}'}、{'{{alert.resolvedAt}}'}</div>
+                                                    <div>探针：{'{{agent.id}}'}、{'{{agent.name}}'}、{'{{agent.hostname}}'}、{'{{agent.ip}}'}、{'{{agent.ipv4}}'}、{'{{agent.ipv6}}'}</div>
+                                                    <div>监控项（服务下线、证书告警）：{'{{monitor.id}}'}、{'{{monitor.name}}'}、{'{{monitor.type}}'}、{'{{monitor.target}}'}、{'{{monitor.status}}'}、{'{{monitor.statusCode}}'}、{'{{monitor.responseTime}}'}、{'{{monitor.error}}'}、{'{{monitor.downtime}}'}、{'{{monitor.certDaysLeft}}'}</div>
+                                                </div>
+                                            }
+                                        />
 
                                         {/* 自定义请求头 */}
                                         <Form.Item label="自定义请求头"
@@ -1,9 +1,9 @@
 import { useEffect, useState } from 'react';
-import { App, Button, Card, Form, Input, Radio, Space, Spin, Upload } from 'antd';
+import { App, Button, Card, Form, Input, Radio, Select, Space, Spin, Upload } from 'antd';
 import { Upload as UploadIcon, Grid3x3, List } from 'lucide-react';
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 import type { SystemConfig } from '@/api/property.ts';
-import { getSystemConfig, saveSystemConfig } from '@/api/property.ts';
+import { DEFAULT_METRIC_CARDS, METRIC_CARD_LABELS, getSystemConfig, saveSystemConfig } from '@/api/property.ts';
 import { getErrorMessage } from '@/lib/utils.ts';
 import type { RcFile } from 'antd/es/upload/interface';
 
@@ -42,6 +42,7 @@ const SystemConfigComponent = () => {
                 systemNameZh: config.systemNameZh,
                 icpCode: config.icpCode,
                 defaultView: config.defaultView ?? true, // 默认为 grid 视图
+                metricCards: config.metricCards?.length ? config.metricCards : DEFAULT_METRIC_CARDS,
                 customCSS: config.customCSS,
                 customJS: config.customJS,
             });
@@ -101,6 +102,7 @@ const SystemConfigComponent = () => {
                 logoBase64: logoPreview,
                 icpCode: values.icpCode || '',
                 defaultView: values.defaultView ?? true,
+                metricCards: values.metricCards || [],
                 customCSS: values.customCSS || '',
                 customJS: values.customJS || '',
             } as SystemConfig);
@@ -117,6 +119,7 @@ const SystemConfigComponent = () => {
                 systemNameZh: config.systemNameZh,
                 icpCode: config.icpCode,
                 defaultView: config.defaultView ?? true,
+                metricCards: config.metricCards?.length ? config.metricCards : DEFAULT_METRIC_CARDS,
                 customCSS: config.customCSS,
                 customJS: config.customJS,
             });
@@ -226,6 +229,21 @@ const SystemConfigComponent = () => {
                             </Radio.Group>
                         </Form.Item>
 
+                        <Form.Item
+                            label="指标卡片"
+                            name="metricCards"
+                            tooltip="探针详情页历史趋势中展示的指标卡片，按选择顺序排列，未选择的卡片将被隐藏；清空时恢复默认"
+                        >
+                            <Select
+                                mode="multiple"
+                                placeholder="选择要展示的指标卡片"
+                                options={DEFAULT_METRIC_CARDS.map((card) => ({
+                                    label: METRIC_CARD_LABELS[card],
+                                    value: card,
+                                }))}
+                            />
+                        </Form.Item>
+
                         <Form.Item
                             label="系统 Logo"
                             tooltip="上传系统 Logo，建议使用正方形图片，尺寸为 256x256 或更大，文件大小不超过 500KB"
@@ -1,6 +1,7 @@
 import {del, get, post, put} from './request';
 import type {
     Agent,
+    PageResponse,
     LatestMetrics,
     SSHLoginConfig,
     SSHLoginEvent,
@@ -12,17 +13,24 @@ import qs from "qs";
 
 export interface GetAgentMetricsRequest {
     agentId: string;
-    type: 'cpu' | 'memory' | 'disk' | 'network' | 'network_connection' | 'disk_io' | 'gpu' | 'temperature' | 'monitor';
+    type: 'cpu' | 'memory' | 'disk' | 'network' | 'network_connection' | 'disk_io' | 'gpu' | 'temperature' | 'monitor' | 'load' | 'derived';
+    name?: string; // 派生指标名称（仅对 derived 类型有效），如 'memory_pressure'
     range?: string; // 时间范围，如 '15m', '1h', '1d' 等，从后端配置获取
     start?: number; // 自定义开始时间（毫秒时间戳）
     end?: number; // 自定义结束时间（毫秒时间戳）
     interface?: string; // 网卡过滤参数（仅对 network 类型有效）
+    fields?: string[]; // 只返回指定名称的系列，如 ['usage']、['upload']，未知名称会被忽略
+    interval?: string; // 查询步长，如 '20s'、'5m'，会对齐到服务端允许的档位
+    fill?: boolean; // 是否补齐没有数据的时间桶（value 为 null），用于图表断开离线区间
+    decimation?: 'lttb'; // 数据点缩减算法，lttb 从原始精度数据中挑选点以保留峰谷，不传时按步长聚合
+    points?: number; // lttb 的目标点数，不传时与按步长聚合的点数相同
+    debug?: boolean; // 返回查询调试信息（仅管理员有效）
 }
 
 // 新的统一数据格式
 export interface MetricDataPoint {
     timestamp: number;
-    value: number;
+    value: number; // 开启 fill 时没有数据的时间桶为 null
 }
 
 export interface MetricSeries {
@@ -35,7 +43,32 @@ export interface GetAgentMetricsResponse {
     agentId: string;
     type: string;
     range: string;
+    interval?: number; // 实际使用的步长（秒）
+    decimation?: 'lttb'; // 使用的数据点缩减算法
     series: MetricSeries[];
+    debug?: MetricQueryDebug; // 查询调试信息，仅管理员指定 debug 时返回
+}
+
+// 指标查询调试信息
+export interface MetricQueryDebug {
+    source: 'raw' | 'aggregate'; // 数据来源：原始样本或降采样数据
+    step: number; // 查询步长（秒）
+    downsamplingInterval?: number; // 命中的降采样档位间隔（秒）
+    aggregation?: string;
+    seriesFetched: number; // VictoriaMetrics 扫描的时间序列数
+    seriesReturned: number;
+    pointsReturned: number;
+    durationMs: number;
+    queries: {
+        name: string;
+        query: string;
+        seriesFetched: number;
+        seriesReturned: number;
+        pointsReturned: number;
+        executionTimeMs: number;
+        durationMs: number;
+        error?: string;
+    }[];
 }
 
 // 管理员接口 - 获取所有探针（需要认证）
@@ -43,10 +76,55 @@ export const listAgentsByAdmin = () => {
     return get<Agent[]>('/admin/agents');
 };
 
+export interface PagingAgentsRequest {
+    pageIndex?: number;
+    pageSize?: number;
+    status?: 'online' | 'offline';
+    tag?: string;        // 多个标签以逗号分隔，需同时包含
+    group?: string;      // 分组
+    keyword?: string;    // 名称、主机名或 IP 的子串
+    sortField?: 'weight' | 'name' | 'lastSeenAt';
+    sortOrder?: 'asc' | 'desc';
+}
+
+export type PagingAgentsResponse = PageResponse<Agent>;
+
+// 管理员接口 - 分页查询探针
+export const pagingAgentsByAdmin = (req: PagingAgentsRequest = {}) => {
+    const {pageIndex = 1, pageSize = 20, status, tag, group, keyword, sortField, sortOrder} = req;
+    const params = new URLSearchParams();
+    params.append('pageIndex', pageIndex.toString());
+    params.append('pageSize', pageSize.toString());
+    if (status) {
+        params.append('status', status);
+    }
+    if (tag) {
+        params.append('tag', tag);
+    }
+    if (group) {
+        params.append('group', group);
+    }
+    if (keyword) {
+        params.append('keyword', keyword);
+    }
+    if (sortField) {
+        params.append('sortField', sortField);
+    }
+    if (sortOrder) {
+        params.append('sortOrder', sortOrder);
+    }
+    return get<PagingAgentsResponse>(`/admin/agents/paged?${params.toString()}`);
+};
+
 export const listAgents = () => {
     return get<Agent[]>('/agents');
 };
 
+// 分页获取探针列表（公开接口）
+export const listAgentsPaged = (page = 1, pageSize = 20) => {
+    return get<PageResponse<Agent>>(`/agents/paged?page=${page}&pageSize=${pageSize}`);
+};
+
 export const getAgent = (id: string) => {
     return get<Agent>(`/agents/${id}`);
 };
@@ -61,9 +139,12 @@ export const getAgentLatestMetricsForAdmin = (agentId: string) => {
 };
 
 export const getAgentMetrics = (params: GetAgentMetricsRequest) => {
-    const {agentId, type, range = '1h', start, end, interface: interfaceName} = params;
+    const {agentId, type, name, range = '1h', start, end, interface: interfaceName, fields, interval, fill, decimation, points, debug} = params;
     const query = new URLSearchParams();
     query.append('type', type);
+    if (name) {
+        query.append('name', name);
+    }
     if (start !== undefined && end !== undefined) {
         query.append('start', start.toString());
         query.append('end', end.toString());
@@ -73,9 +154,117 @@ export const getAgentMetrics = (params: GetAgentMetricsRequest) => {
     if (interfaceName) {
         query.append('interface', interfaceName);
     }
+    if (fields && fields.length > 0) {
+        query.append('fields', fields.join(','));
+    }
+    if (interval) {
+        query.append('interval', interval);
+    }
+    if (fill) {
+        query.append('fill', 'true');
+    }
+    if (decimation) {
+        query.append('decimation', decimation);
+        if (points) {
+            query.append('points', points.toString());
+        }
+    }
+    if (debug) {
+        query.append('debug', 'true');
+    }
     return get<GetAgentMetricsResponse>(`/agents/${agentId}/metrics?${query.toString()}`);
 };
 
+// 日志跟踪请求，跟踪内容以 SSE 流式返回（需使用 fetch 读取响应流）
+export interface LogTailRequest {
+    path: string;
+    durationSeconds?: number;
+    linesPerSecond?: number;
+}
+
+// 日志跟踪 SSE 事件数据
+export interface LogTailLinesEvent {
+    lines: string[];
+    dropped: number;
+}
+
+export interface LogTailEndEvent {
+    reason: 'timeout' | 'finished' | 'error' | 'agent_offline' | 'stopped';
+    error?: string;
+}
+
+// 停止日志跟踪
+export const stopLogTail = (agentId: string, sessionId: string) => {
+    return del(`/admin/agents/${agentId}/log-tail/${sessionId}`);
+};
+
+// 所有在线探针的汇总统计
+export interface FleetThresholdBreach {
+    alertType: string;
+    threshold: number;
+    agents: number; // 最新指标超过阈值的探针数
+}
+
+export interface FleetSummary {
+    totalAgents: number;
+    onlineAgents: number;
+    reportingAgents: number;
+    totalCores: number;
+    coresInUse: number;
+    cpuUsagePercent: number;
+    memoryTotal: number;
+    memoryUsed: number;
+    diskTotal: number;
+    diskUsed: number;
+    diskFree: number;
+    networkSentRate: number;
+    networkRecvRate: number;
+    breaches: FleetThresholdBreach[];
+    generatedAt: number;
+}
+
+// 获取所有在线探针的汇总统计（服务端缓存 30 秒）
+export const getFleetSummary = () => {
+    return get<FleetSummary>('/admin/agents/fleet-summary');
+};
+
+// 向在线探针重新下发完整配置（公网 IP 采集、SSH 登录监控、指标采集策略、自定义检查、防篡改目录），探针无需重连即可应用
+export const reloadAgentConfig = (agentId: string) => {
+    return post<Record<string, unknown>>(`/admin/agents/${agentId}/reload-config`);
+};
+
+// 派生指标：由已存储的字段通过四则运算计算得到
+export interface DerivedMetric {
+    name: string;
+    expression: string;
+    unit?: string;
+    description?: string;
+}
+
+// 获取可查询的派生指标（内置和自定义）
+export const listDerivedMetrics = () => {
+    return get<DerivedMetric[]>('/metrics/derived');
+};
+
+// 指定时间点（含）之前的最新指标值，每个系列只包含一个数据点
+export interface GetAgentMetricAsOfResponse {
+    agentId: string;
+    type: string;
+    at: number;
+    source: 'raw' | 'aggregate';
+    interval?: number;
+    series: MetricSeries[];
+}
+
+export const getAgentMetricAsOf = (agentId: string, type: string, ts?: number) => {
+    const query = new URLSearchParams();
+    query.append('type', type);
+    if (ts) {
+        query.append('ts', ts.toString());
+    }
+    return get<GetAgentMetricAsOfResponse>(`/agents/${agentId}/metrics/as-of?${query.toString()}`);
+};
+
 export const getAgentLatestMetrics = (agentId: string) => {
     return get<LatestMetrics>(`/agents/${agentId}/metrics/latest`);
 };
@@ -92,12 +281,30 @@ export const getAvailableNetworkInterfaces = (agentId: string) => {
 export interface GetNetworkMetricsByInterfaceRequest {
     agentId: string;
     range?: '1m' | '5m' | '15m' | '30m' | '1h' | '3h' | '6h' | '12h' | '1d' | '24h' | '3d' | '7d' | '30d';
+    interval?: string; // 步长，如 1m、5m，为空时自动选择
 }
 
 export interface NetworkMetricByInterface {
     timestamp: number;
     interface: string;
+    avgSentRate: number; // 由累计字节数重新计算的平均速率
+    minSentRate: number;
     maxSentRate: number;
+    avgRecvRate: number;
+    minRecvRate: number;
+    maxRecvRate: number;
+}
+
+export interface NetworkInterfaceSummary {
+    interface: string;
+    macAddress?: string;
+    firstSeen: number;
+    lastSeen: number;
+    avgSentRate: number;
+    minSentRate: number;
+    maxSentRate: number;
+    avgRecvRate: number;
+    minRecvRate: number;
     maxRecvRate: number;
 }
 
@@ -109,12 +316,16 @@ export interface GetNetworkMetricsByInterfaceResponse {
     end: number;
     interval: number;
     metrics: NetworkMetricByInterface[];
+    interfaces: NetworkInterfaceSummary[];
 }
 
 export const getNetworkMetricsByInterface = (params: GetNetworkMetricsByInterfaceRequest) => {
-    const {agentId, range = '1h'} = params;
+    const {agentId, range = '1h', interval} = params;
     const query = new URLSearchParams();
     query.append('range', range);
+    if (interval) {
+        query.append('interval', interval);
+    }
     return get<GetNetworkMetricsByInterfaceResponse>(`/agents/${agentId}/metrics/network-by-interface?${query.toString()}`);
 };
 
@@ -413,6 +624,9 @@ export interface AuditResultSummary {
     failCount: number;
     warnCount: number;
     totalCount: number;
+    riskScore: number; // 风险评分(0-100)
+    securityScore: number; // 安全评分 = 100 - 风险评分
+    threatLevel: 'low' | 'medium' | 'high' | 'critical';
     systemInfo: SystemInfo;
 }
 
@@ -433,7 +647,32 @@ export const getAuditResult = (agentId: string) => {
 
 // 获取审计结果列表（管理员接口）
 export const listAuditResults = (agentId: string) => {
-    return get<{ items: AuditResultSummary[]; total: number }>(`/admin/agents/${agentId}/audit/results`);
+    return get<PageResponse<AuditResultSummary>>(`/admin/agents/${agentId}/audit/results`);
+};
+
+// 获取审计结果的安全分析（管理员接口）
+export const getAuditAnalysis = (agentId: string, auditId: number) => {
+    return get<VPSAuditAnalysis>(`/admin/agents/${agentId}/audit/results/${auditId}/analysis`);
+};
+
+export interface AuditDiffEntry {
+    section: 'systemInfo' | 'statistics';
+    path: string;
+    kind: 'added' | 'removed' | 'changed';
+    before?: unknown;
+    after?: unknown;
+}
+
+export interface AuditDiff {
+    agentId: string;
+    auditIdA: number;
+    auditIdB: number;
+    changes: AuditDiffEntry[];
+}
+
+// 对比两次审计结果（管理员接口）
+export const diffAuditResults = (agentId: string, auditIdA: number, auditIdB: number) => {
+    return get<AuditDiff>(`/admin/agents/${agentId}/audit/diff?a=${auditIdA}&b=${auditIdB}`);
 };
 
 // 更新探针名称
@@ -447,6 +686,9 @@ export interface UpdateAgentInfoRequest {
     tags?: string[];
     expireTime?: number;
     visibility?: string;
+    primaryMountPoint?: string;  // 主磁盘挂载点，为空表示自动识别
+    primaryInterface?: string;   // 主网卡，为空表示自动识别
+    group?: string;              // 分组，未传时保持不变
 }
 
 export const updateAgentInfo = (agentId: string, data: UpdateAgentInfoRequest) => {
@@ -466,6 +708,28 @@ export const deleteAgent = (agentId: string) => {
     return del(`/admin/agents/${agentId}`);
 };
 
+// 探针导出包下载地址，metricDays 为导出最近几天的原始指标（0-30，默认 7）
+export const getAgentExportUrl = (agentId: string, metricDays = 7) => {
+    return `/api/admin/agents/${agentId}/export?metricDays=${metricDays}`;
+};
+
+export interface AgentImportResult {
+    agent: Agent;
+    sourceAgentId: string;
+    metricSeries: number;
+    metricsSkipped: boolean;
+}
+
+// 从导出包导入探针，newId 为空时沿用原探针ID
+export const importAgent = (file: File, newId?: string) => {
+    const form = new FormData();
+    form.append('file', file);
+    if (newId) {
+        form.append('newId', newId);
+    }
+    return post<AgentImportResult>('/admin/agents/import', form);
+};
+
 // 获取所有探针的标签
 export interface GetTagsResponse {
     tags: string[];
@@ -551,7 +815,7 @@ export const updateSSHLoginConfig = async (agentId: string, data: UpdateSSHLogin
 // 获取 SSH 登录事件列表
 export const getSSHLoginEvents = async (agentId: string, params?: any) => {
    const query = qs.stringify(params);
-    const response = await get<{ items: SSHLoginEvent[]; total: number }>(`/admin/agents/${agentId}/ssh-login/events?${query}`);
+    const response = await get<PageResponse<SSHLoginEvent>>(`/admin/agents/${agentId}/ssh-login/events?${query}`);
     return response.data;
 };
 
@@ -559,3 +823,40 @@ export const getSSHLoginEvents = async (agentId: string, params?: any) => {
 export const deleteSSHLoginEvents = async (agentId: string) => {
     await del(`/admin/agents/${agentId}/ssh-login/events`);
 };
+
+// 探针注册公钥相关接口
+
+export interface AgentKey {
+    agentId: string;
+    publicKey: string;
+    fingerprint: string;
+    status: 'pending' | 'approved' | 'rejected';
+    hostname: string;
+    ip: string;
+    reviewedBy?: string;
+    reviewedAt?: number;
+    lastUsedAt?: number;
+    createdAt: number;
+    updatedAt: number;
+}
+
+// 获取探针公钥列表，status 为空时返回全部
+export const listAgentKeys = (status?: string) => {
+    const query = status ? `?status=${status}` : '';
+    return get<AgentKey[]>(`/admin/agent-keys${query}`);
+};
+
+// 审批通过探针公钥
+export const approveAgentKey = (agentId: string) => {
+    return post<AgentKey>(`/admin/agent-keys/${agentId}/approve`, {});
+};
+
+// 拒绝探针公钥
+export const rejectAgentKey = (agentId: string) => {
+    return post<AgentKey>(`/admin/agent-keys/${agentId}/reject`, {});
+};
+
+// 解除探针公钥绑定
+export const deleteAgentKey = (agentId: string) => {
+    return del(`/admin/agent-keys/${agentId}`);
+};
@@ -1,5 +1,5 @@
-import {del, get} from './request';
-import type {AlertRecord} from '@/types';
+import {del, get, post} from './request';
+import type {AlertComment, AlertRecord, EffectiveAlertConfig, PageResponse} from '@/types';
 
 // 注意：告警配置相关 API 已迁移到 property.ts 中
 // 使用 getAlertConfig() 和 saveAlertConfig() 从 '@/api/property' 导入
@@ -22,10 +22,7 @@ export const getAlertRecords = async (
         params.append('agentId', agentId);
     }
 
-    const response = await get<{
-        items: AlertRecord[];
-        total: number;
-    }>(`/admin/alert-records?${params.toString()}`);
+    const response = await get<PageResponse<AlertRecord>>(`/admin/alert-records?${params.toString()}`);
     return response.data;
 };
 
@@ -35,3 +32,52 @@ export const clearAlertRecords = async (agentId?: string): Promise<void> => {
     if (agentId) url += `?agentId=${agentId}`;
     await del(url);
 };
+
+// 批量操作请求：ids 为空时按 agentId + alertType 筛选告警中的记录
+export interface AlertBatchRequest {
+    ids?: number[];
+    agentId?: string;
+    alertType?: string;
+}
+
+export interface AlertBatchResult {
+    id: number;
+    success: boolean;
+    error?: string;
+}
+
+// 批量确认告警
+// 清除告警状态，下一轮检查时重新评估，参数为空时清除全部
+export const resetAlertStates = async (req: { agentId?: string; alertType?: string } = {}): Promise<number> => {
+    const response = await post<{ count: number }>('/admin/alert-states/reset', req);
+    return response.data.count;
+};
+
+export const ackAlertRecords = async (req: AlertBatchRequest): Promise<AlertBatchResult[]> => {
+    const response = await post<{ items: AlertBatchResult[] }>('/admin/alert-records/ack', req);
+    return response.data.items;
+};
+
+// 批量手动恢复告警
+export const resolveAlertRecords = async (req: AlertBatchRequest): Promise<AlertBatchResult[]> => {
+    const response = await post<{ items: AlertBatchResult[] }>('/admin/alert-records/resolve', req);
+    return response.data.items;
+};
+
+// 获取告警备注
+export const getAlertComments = async (alertRecordId: number): Promise<AlertComment[]> => {
+    const response = await get<AlertComment[]>(`/admin/alert-records/${alertRecordId}/comments`);
+    return response.data || [];
+};
+
+// 添加告警备注
+export const addAlertComment = async (alertRecordId: number, text: string): Promise<AlertComment> => {
+    const response = await post<AlertComment>(`/admin/alert-records/${alertRecordId}/comments`, {text});
+    return response.data;
+};
+
+// 预览探针实际生效的告警配置
+export const getEffectiveAlertConfig = async (agentId: string): Promise<EffectiveAlertConfig> => {
+    const response = await get<EffectiveAlertConfig>(`/admin/agents/${agentId}/effective-alert-config`);
+    return response.data;
+};
@@ -0,0 +1,38 @@
+import { get, post, put, del } from './request';
+import type { Annotation, AnnotationRequest } from '../types';
+
+export interface AnnotationQuery {
+    range?: string;
+    start?: number;
+    end?: number;
+    tag?: string;
+}
+
+const buildAnnotationQuery = (query: AnnotationQuery) => {
+    const params = new URLSearchParams();
+    if (query.range) params.append('range', query.range);
+    if (query.start !== undefined) params.append('start', query.start.toString());
+    if (query.end !== undefined) params.append('end', query.end.toString());
+    if (query.tag) params.append('tag', query.tag);
+    return params.toString();
+};
+
+// 获取与时间窗口重叠的注释（含全局注释），用于图表叠加展示
+export const getAgentAnnotations = (agentId: string, query: AnnotationQuery) => {
+    return get<Annotation[]>(`/admin/agents/${agentId}/annotations?${buildAnnotationQuery(query)}`);
+};
+
+// 创建注释
+export const createAnnotation = (data: AnnotationRequest) => {
+    return post<Annotation>('/admin/annotations', data);
+};
+
+// 修改注释
+export const updateAnnotation = (id: string, data: AnnotationRequest) => {
+    return put<Annotation>(`/admin/annotations/${id}`, data);
+};
+
+// 删除注释
+export const deleteAnnotation = (id: string) => {
+    return del(`/admin/annotations/${id}`);
+};
@@ -59,6 +59,7 @@ export const logout = () => {
 export interface CurrentUser {
     userId: string;
     username: string;
+    role: 'admin' | 'viewer';
 }
 
 export const getCurrentUser = () => {
@@ -54,8 +54,11 @@ const PROPERTY_ID_NOTIFICATION_CHANNELS = 'notification_channels';
 
 // 通知渠道配置（通过 type 标识，不再使用独立ID）
 export interface NotificationChannel {
-    type: 'dingtalk' | 'wecom' | 'wecomApp' | 'feishu' | 'email' | 'webhook' | 'telegram'; // 渠道类型，作为唯一标识
+    id?: string; // 渠道ID，监控项按 ID 指定通知渠道，为空时使用类型
+    type: 'dingtalk' | 'wecom' | 'wecomApp' | 'feishu' | 'email' | 'webhook' | 'telegram'; // 渠道类型
     enabled: boolean; // 是否启用
+    minLevel?: 'info' | 'warning' | 'critical'; // 最低告警级别，为空时接收所有级别
+    notifyOnResolve?: boolean; // 是否发送告警恢复通知，为空时使用全局告警配置
     config: Record<string, any>; // JSON配置，根据type不同而不同
 }
 
@@ -87,6 +90,7 @@ export interface SystemConfig {
     logoBase64: string;    // Logo 的 base64 编码
     icpCode: string;       // ICP 备案号
     defaultView: string;   // 默认视图 grid,list
+    metricCards?: string[]; // 探针详情页展示的指标卡片及顺序
     customCSS: string;     // 自定义 CSS
     customJS: string;      // 自定义 JS
 }
@@ -102,8 +106,30 @@ export interface PublicIPConfig {
     ipv6Enabled: boolean;
     ipv4Apis: string[];
     ipv6Apis: string[];
+    ipv4ApiEntries?: PublicIPAPI[]; // 带优先级和超时的 API 列表，非空时优先于 ipv4Apis
+    ipv6ApiEntries?: PublicIPAPI[];
 }
 
+export interface PublicIPAPI {
+    url: string;
+    priority: number;       // 数值越小越先尝试
+    timeoutSeconds: number; // 单次请求超时（秒），必须为正数
+}
+
+// 默认展示的指标卡片及顺序，与后端 models.DefaultMetricCards 保持一致
+export const DEFAULT_METRIC_CARDS = ['cpu', 'memory', 'network', 'disk_io', 'network_connection', 'gpu', 'temperature', 'monitor'];
+
+export const METRIC_CARD_LABELS: Record<string, string> = {
+    cpu: 'CPU',
+    memory: '内存',
+    network: '网络',
+    disk_io: '磁盘 IO',
+    network_connection: '网络连接',
+    gpu: 'GPU',
+    temperature: '温度',
+    monitor: '服务监控',
+};
+
 // 获取系统配置（管理后台使用）
 export const getSystemConfig = async (): Promise<SystemConfig> => {
     return getProperty<SystemConfig>(PROPERTY_ID_SYSTEM_CONFIG);
@@ -124,6 +150,61 @@ export const savePublicIPConfig = async (config: PublicIPConfig): Promise<void>
     return saveProperty(PROPERTY_ID_PUBLIC_IP_CONFIG, '公网 IP 采集配置', config);
 };
 
+// ==================== 告警记录归档配置 ====================
+
+const PROPERTY_ID_ARCHIVE_CONFIG = 'archive_config';
+
+// 告警记录归档配置（S3 兼容对象存储）
+export interface ArchiveConfig {
+    enabled: boolean;
+    endpoint: string;            // 对象存储地址，如 https://s3.amazonaws.com
+    region: string;
+    bucket: string;
+    accessKeyId: string;
+    secretAccessKey: string;
+    prefix: string;              // 对象键前缀
+    usePathStyle: boolean;       // 路径风格访问（MinIO 等通常需要开启）
+    format: 'json' | 'ndjson';   // 归档格式，均为 gzip 压缩
+    archiveAfterDays: number;    // 恢复超过该天数的告警记录才归档
+    deleteAfterArchive: boolean; // 归档后从数据库删除
+    intervalMinutes: number;     // 归档检查间隔（分钟）
+    archiveMetrics?: boolean;    // 同时按天归档聚合后的探针指标
+    metricsInterval?: number;    // 指标归档的聚合步长（秒），默认 3600
+}
+
+// 获取告警记录归档配置
+export const getArchiveConfig = async (): Promise<ArchiveConfig> => {
+    return getProperty<ArchiveConfig>(PROPERTY_ID_ARCHIVE_CONFIG);
+};
+
+// 保存告警记录归档配置
+export const saveArchiveConfig = async (config: ArchiveConfig): Promise<void> => {
+    return saveProperty(PROPERTY_ID_ARCHIVE_CONFIG, '告警记录归档配置', config);
+};
+
+// ==================== 探针自动清理配置 ====================
+
+const PROPERTY_ID_AGENT_CLEANUP_CONFIG = 'agent_cleanup_config';
+
+// 长期离线探针自动清理配置
+export interface AgentCleanupConfig {
+    enabled: boolean;
+    offlineDays: number;       // 离线超过该天数进入待清理状态，默认 30
+    graceHours: number;        // 发出清理预告后等待的小时数，默认 24
+    excludeAgentIds: string[]; // 不自动清理的探针ID
+    excludeTags: string[];     // 带有任一标签的探针不自动清理
+}
+
+// 获取探针自动清理配置
+export const getAgentCleanupConfig = async (): Promise<AgentCleanupConfig> => {
+    return getProperty<AgentCleanupConfig>(PROPERTY_ID_AGENT_CLEANUP_CONFIG);
+};
+
+// 保存探针自动清理配置
+export const saveAgentCleanupConfig = async (config: AgentCleanupConfig): Promise<void> => {
+    return saveProperty(PROPERTY_ID_AGENT_CLEANUP_CONFIG, '探针自动清理配置', config);
+};
+
 // ==================== 告警配置 ====================
 
 const PROPERTY_ID_ALERT_CONFIG = 'alert_config';
@@ -142,12 +223,29 @@ export interface AlertRules {
     networkEnabled: boolean;
     networkThreshold: number;  // 网速阈值(MB/s)
     networkDuration: number;
+    loadEnabled?: boolean;     // 负载告警开关
+    loadThreshold?: number;    // 每核 1 分钟平均负载阈值
+    loadDuration?: number;
+    connectionEnabled?: boolean;     // 连接数告警开关
+    connectionThreshold?: number;    // 总连接数阈值（0 表示不检查）
+    connectionDuration?: number;
+    connectionStates?: ConnectionStateRule[]; // 按连接状态的阈值
     certEnabled: boolean;      // HTTPS 证书告警开关
     certThreshold: number;     // 证书剩余天数阈值（天）
     serviceEnabled: boolean;   // 服务下线告警开关
     serviceDuration: number;   // 服务下线持续时间（秒）
     agentOfflineEnabled: boolean;   // 探针离线告警开关
     agentOfflineDuration: number;   // 探针离线持续时间（秒）
+    collectorErrorEnabled?: boolean;  // 探针采集异常告警开关
+    collectorErrorDuration?: number;  // 探针采集异常持续时间（秒）
+    durationMode?: 'continuous' | 'windowed'; // 持续时间语义：连续超过阈值（默认）或按窗口内超阈值占比
+    windowRatio?: number;                     // windowed 模式下触发所需的超阈值采样占比(0-100)，默认 80
+}
+
+// 连接状态告警阈值
+export interface ConnectionStateRule {
+    state: string;     // 连接状态，如 closeWait、timeWait
+    threshold: number;
 }
 
 export interface AlertNotifications {
@@ -159,9 +257,45 @@ export interface AlertNotifications {
 // 全局告警配置
 export interface AlertConfig {
     enabled: boolean;  // 全局告警开关
-    maskIP: boolean;   // 是否在通知中打码 IP 地址
+    maskIP: boolean;   // 是否在通知中打码 IP 地址（旧配置）
+    maskIPMode?: 'none' | 'partial' | 'full'; // IP 打码粒度
     rules: AlertRules;
     notifications: AlertNotifications;
+    throttle?: NotificationThrottle; // 单个探针的通知限流
+    escalation?: AlertEscalation;    // 未确认告警的升级策略
+    notifyOnResolve?: boolean;       // 是否发送告警恢复通知，默认发送
+    templateBindings?: AlertTemplateBinding[]; // 按顺序匹配，匹配的模板替换全局的资源类告警规则
+}
+
+// 告警规则模板
+export interface AlertRuleTemplate {
+    id: string;
+    name: string;
+    description?: string;
+    rules: AlertRules;   // 只使用其中的资源类告警规则和持续时间语义
+    createdAt: number;
+}
+
+// 将告警规则模板应用到一组探针，标签和属性同时指定时需同时满足
+export interface AlertTemplateBinding {
+    templateId: string;
+    tags?: string[];                          // 带有任一标签的探针
+    agentAttributes?: Record<string, string>; // 属性全部匹配的探针
+}
+
+// 单个探针在时间窗口内的通知预算
+export interface NotificationThrottle {
+    maxNotifications: number; // 窗口内最多发送的通知数，0 表示不限制
+    windowMinutes: number;    // 时间窗口（分钟），默认 60
+}
+
+// 未确认告警的升级策略
+export interface AlertEscalation {
+    enabled: boolean;
+    minLevel?: 'info' | 'warning' | 'critical'; // 参与升级的最低告警级别，默认 critical
+    afterMinutes: number;   // 告警触发后未确认的分钟数，默认 15
+    repeatMinutes?: number; // 升级后仍未确认时重复通知的间隔（分钟），0 表示只通知一次
+    channels: string[];     // 升级通知发送的渠道 ID（未设置 id 时为渠道类型）
 }
 
 // 获取告警配置
@@ -174,6 +308,36 @@ export const saveAlertConfig = async (config: AlertConfig): Promise<void> => {
     return saveProperty(PROPERTY_ID_ALERT_CONFIG, '告警配置', config);
 };
 
+const PROPERTY_ID_ALERT_RULE_TEMPLATES = 'alert_rule_templates';
+
+// 获取告警规则模板
+export const getAlertRuleTemplates = async (): Promise<AlertRuleTemplate[]> => {
+    return getProperty<AlertRuleTemplate[]>(PROPERTY_ID_ALERT_RULE_TEMPLATES);
+};
+
+// 保存告警规则模板，被告警配置引用的模板不能删除
+export const saveAlertRuleTemplates = async (templates: AlertRuleTemplate[]): Promise<void> => {
+    return saveProperty(PROPERTY_ID_ALERT_RULE_TEMPLATES, '告警规则模板', templates);
+};
+
+// 以当前全局告警规则创建模板
+export const createAlertTemplateFromConfig = async (name: string, description?: string): Promise<AlertRuleTemplate> => {
+    const response = await post<AlertRuleTemplate>('/admin/alert-templates/from-config', {name, description});
+    return response.data;
+};
+
+// 复制告警规则模板，名称为空时使用原名称加“副本”
+export const cloneAlertTemplate = async (id: string, name?: string): Promise<AlertRuleTemplate> => {
+    const response = await post<AlertRuleTemplate>(`/admin/alert-templates/${id}/clone`, {name});
+    return response.data;
+};
+
+// 将模板应用到带有指定标签或属性的探针，返回更新后的全部模板绑定
+export const instantiateAlertTemplate = async (id: string, binding: Omit<AlertTemplateBinding, 'templateId'>): Promise<AlertTemplateBinding[]> => {
+    const response = await post<AlertTemplateBinding[]>(`/admin/alert-templates/${id}/instantiate`, binding);
+    return response.data;
+};
+
 // ==================== 探针安装配置 ====================
 
 const PROPERTY_ID_AGENT_INSTALL_CONFIG = 'agent_install_config';
@@ -51,6 +51,16 @@ const headersToRecord = (headers: Headers) => {
     return record;
 };
 
+// 分享链接携带的只读令牌（?share_token=xxx），保存在会话中以便页面内跳转后继续使用
+const getShareToken = () => {
+    const fromUrl = new URLSearchParams(window.location.search).get('share_token');
+    if (fromUrl) {
+        sessionStorage.setItem('shareToken', fromUrl);
+        return fromUrl;
+    }
+    return sessionStorage.getItem('shareToken');
+};
+
 const sendRequest = async <T>(url: string, config: RequestConfig = {}): Promise<HttpResponse<T>> => {
     const {timeout = DEFAULT_TIMEOUT, headers, body, ...restConfig} = config;
     const controller = new AbortController();
@@ -61,6 +71,11 @@ const sendRequest = async <T>(url: string, config: RequestConfig = {}): Promise<
         const finalHeaders = new Headers(headers || {});
         if (token) {
             finalHeaders.set('Authorization', `Bearer ${token}`);
+        } else {
+            const shareToken = getShareToken();
+            if (shareToken) {
+                finalHeaders.set('X-Share-Token', shareToken);
+            }
         }
 
         const hasBody = body !== undefined && body !== null;
@@ -138,6 +153,7 @@ const normalizeBody = (data?: any) => {
 
 export interface ApiResponse<T = any> {
     code?: number;
+    errorCode?: string; // 机器可读的错误码，如 ERR_INVALID_CREDENTIALS
     message?: string;
     data?: T;
 }
@@ -0,0 +1,17 @@
+import { get, post, del } from './request';
+import type { CreateShareTokenRequest, CreateShareTokenResponse, ShareToken } from '../types';
+
+// 获取分享令牌列表
+export const listShareTokens = () => {
+    return get<ShareToken[]>('/admin/share-tokens');
+};
+
+// 创建分享令牌
+export const createShareToken = (data: CreateShareTokenRequest) => {
+    return post<CreateShareTokenResponse>('/admin/share-tokens', data);
+};
+
+// 吊销分享令牌
+export const revokeShareToken = (id: string) => {
+    return del(`/admin/share-tokens/${id}`);
+};
@@ -9,6 +9,7 @@ interface ErrorWithResponse {
     response?: {
         data?: {
             message?: string;
+            errorCode?: string;
         };
     };
 }
@@ -27,3 +28,12 @@ export function getErrorMessage(error: unknown, fallback: string) {
 
     return fallback;
 }
+
+// getErrorCode 获取接口返回的机器可读错误码（如 ERR_INVALID_CREDENTIALS），用于本地化和分支处理
+export function getErrorCode(error: unknown): string | undefined {
+    if (typeof error === 'object' && error !== null) {
+        const {response} = error as ErrorWithResponse;
+        return response?.data?.errorCode;
+    }
+    return undefined;
+}
@@ -21,6 +21,10 @@ import {TemperatureChart} from '@portal/components/server/TemperatureChart.tsx';
 import {useAgentQuery, useLatestMetricsQuery} from '@portal/hooks/server.ts';
 import {SERVER_TIME_RANGE_OPTIONS} from '@portal/constants/time.ts';
 import LittleStatCard from '@portal/components/LittleStatCard.tsx';
+import {DEFAULT_METRIC_CARDS} from '@/api/property.ts';
+
+// 大屏下占半宽的指标卡片，其余卡片占满整行
+const HALF_WIDTH_CARDS = new Set(['cpu', 'memory', 'network', 'disk_io']);
 
 /**
  * 服务器详情页面
@@ -49,6 +53,34 @@ const ServerDetail = () => {
         typeof value === 'number' && Number.isFinite(value) ? value.toFixed(2) : '-'
     );
 
+    const metricCards = window.SystemConfig?.MetricCards?.length
+        ? window.SystemConfig.MetricCards
+        : DEFAULT_METRIC_CARDS;
+
+    const renderMetricCard = (card: string) => {
+        const props = {agentId: id!, timeRange, start: customStart, end: customEnd};
+        switch (card) {
+            case 'cpu':
+                return <CpuChart {...props}/>;
+            case 'memory':
+                return <MemoryChart {...props}/>;
+            case 'network':
+                return <NetworkChart {...props}/>;
+            case 'disk_io':
+                return <DiskIOChart {...props}/>;
+            case 'network_connection':
+                return <NetworkConnectionChart {...props}/>;
+            case 'gpu':
+                return <GpuChart {...props}/>;
+            case 'temperature':
+                return <TemperatureChart {...props}/>;
+            case 'monitor':
+                return <MonitorChart {...props}/>;
+            default:
+                return null;
+        }
+    };
+
     const deviceIpInterfaces = (latestMetrics?.networkInterfaces || [])
         .map((netInterface) => ({
             name: netInterface.interface,
@@ -105,36 +137,19 @@ const ServerDetail = () => {
                             </div>
                         }
                     >
-                        <div className="space-y-4 sm:space-y-5 lg:space-y-6">
-                            {/* 核心指标：大屏 2 列，小屏 1 列 */}
-                            <div className="grid gap-4 sm:gap-5 lg:gap-6 grid-cols-1 md:grid-cols-2">
-                                <CpuChart agentId={id!} timeRange={timeRange} start={customStart} end={customEnd}/>
-                                <MemoryChart agentId={id!} timeRange={timeRange} start={customStart} end={customEnd}/>
-                            </div>
-
-                            {/* 网络相关：大屏 2 列，中屏 1 列 */}
-                            <div className="grid gap-4 sm:gap-5 lg:gap-6 grid-cols-1 lg:grid-cols-2">
-                                <NetworkChart agentId={id!} timeRange={timeRange} start={customStart} end={customEnd}/>
-                                <DiskIOChart agentId={id!} timeRange={timeRange} start={customStart} end={customEnd}/>
-                            </div>
-
-                            {/* 进阶指标：单列全宽 */}
-                            <div className="grid gap-4 sm:gap-5 lg:gap-6 grid-cols-1">
-                                <NetworkConnectionChart agentId={id!} timeRange={timeRange} start={customStart}
-                                                        end={customEnd}/>
-                            </div>
-
-                            {/* 硬件指标：条件渲染，单列全宽 */}
-                            <div className="grid gap-4 sm:gap-5 lg:gap-6 grid-cols-1">
-                                <GpuChart agentId={id!} timeRange={timeRange} start={customStart} end={customEnd}/>
-                                <TemperatureChart agentId={id!} timeRange={timeRange} start={customStart}
-                                                  end={customEnd}/>
-                            </div>
-
-                            {/* 监控指标：单列全宽 */}
-                            <div className="grid gap-4 sm:gap-5 lg:gap-6 grid-cols-1">
-                                <MonitorChart agentId={id!} timeRange={timeRange} start={customStart} end={customEnd}/>
-                            </div>
+                        {/* 按系统配置的指标卡片顺序渲染：核心指标大屏 2 列，其余单列全宽 */}
+                        <div className="grid gap-4 sm:gap-5 lg:gap-6 grid-cols-1 lg:grid-cols-2">
+                            {metricCards.map((card) => {
+                                const chart = renderMetricCard(card);
+                                if (!chart) {
+                                    return null;
+                                }
+                                return (
+                                    <div key={card} className={HALF_WIDTH_CARDS.has(card) ? '' : 'lg:col-span-2'}>
+                                        {chart}
+                                    </div>
+                                );
+                            })}
                         </div>
                     </Card>
 
@@ -1,6 +1,15 @@
+// 统一的分页响应，查询参数为 page、pageSize、sort、order
+export interface PageResponse<T> {
+    items: T[];
+    total: number;
+    page: number;
+    pageSize: number;
+}
+
 // 用户相关（简化版，仅用于登录）
 export interface User {
     username: string;
+    role?: 'admin' | 'viewer';
 }
 
 export interface LoginRequest {
@@ -25,18 +34,39 @@ export interface Agent {
     arch: string;
     version: string;
     tags?: string[];         // 标签
+    group?: string;          // 分组
     expireTime?: number;     // 到期时间（时间戳毫秒）
     status: number;
     visibility?: string;     // 可见性: public-匿名可见, private-登录可见
     weight?: number;         // 权重排序（数字越大越靠前）
     remark?: string;         // 备注信息
+    primaryMountPoint?: string;  // 运维指定的主磁盘挂载点，为空时自动识别
+    primaryInterface?: string;   // 运维指定的主网卡，为空时自动识别
+    attributes?: Record<string, string>;          // 运维设置的属性，覆盖探针上报的同名属性
+    reportedAttributes?: Record<string, string>;  // 探针上报的属性
     lastSeenAt: string | number;  // 支持字符串或时间戳
+    clockSkew?: number;      // 测得的时钟偏差（毫秒）：服务端接收时间 - 探针上报时间
+    clockSkewed?: boolean;   // 时钟偏差是否超过容忍范围
     createdAt?: string;
     updatedAt?: string;
     // 流量统计相关字段
     trafficStats?: TrafficStatsData; // 流量统计配置
     tamperProtectConfig?: TamperProtectConfig; // 防篡改保护配置
     sshLoginConfig?: SSHLoginConfigData; // SSH登录监控配置
+    health?: AgentHealth;    // 探针随心跳上报的运行状况
+}
+
+export interface AgentHealth {
+    collectErrors: number;          // 启动以来指标采集失败次数
+    failingCollectors?: string[];   // 最近一轮采集失败的指标类型
+    lastError?: string;             // 最近一次采集错误
+    lastErrorAt?: number;           // 最近一次采集错误时间（时间戳毫秒）
+    droppedSamples: number;         // 启动以来因缓存失败或过期丢弃的消息数
+    cpuPercent: number;             // 探针进程 CPU 使用率（相对单核）
+    memoryRss: number;              // 探针进程常驻内存（字节）
+    goroutines: number;             // 探针协程数
+    uptimeSeconds: number;          // 探针进程运行时长（秒）
+    reportedAt: number;             // 服务端收到的时间（时间戳毫秒），0 表示未上报过
 }
 
 export interface TrafficStatsData {
@@ -168,6 +198,12 @@ export interface DiskSummary {
     total: number;            // 总容量(字节)
     used: number;             // 已使用(字节)
     free: number;             // 空闲(字节)
+    primaryMountPoint?: string;   // 主磁盘挂载点
+    primarySource?: 'configured' | 'auto';  // 主磁盘来源: 运维指定 / 自动识别
+    primaryUsagePercent: number;
+    primaryTotal: number;
+    primaryUsed: number;
+    primaryFree: number;
 }
 
 // 磁盘详细数据
@@ -203,6 +239,10 @@ export interface NetworkSummary {
     totalBytesSentTotal: number;  // 累计总发送流量
     totalBytesRecvTotal: number;  // 累计总接收流量
     totalInterfaces: number;      // 网卡数量
+    primaryInterface?: string;    // 主网卡
+    primarySource?: 'configured' | 'auto';  // 主网卡来源: 运维指定 / 自动识别
+    primaryBytesSentRate: number; // 主网卡发送速率(字节/秒)
+    primaryBytesRecvRate: number; // 主网卡接收速率(字节/秒)
 }
 
 export interface NetworkInterfaceMetric {
@@ -269,6 +309,8 @@ export interface MonitorHttpConfig {
     timeout?: number;
     headers?: Record<string, string>;
     body?: string;
+    followRedirects?: boolean; // 是否跟随重定向，默认 true
+    verifyTLS?: boolean;       // 是否校验 HTTPS 证书，默认 false
 }
 
 export interface MonitorTcpConfig {
@@ -296,6 +338,7 @@ export interface MonitorTask {
     agentIds?: string[];
     agentNames?: string[];
     tags?: string[];       // 标签列表，拥有这些标签的探针都会执行此监控
+    notificationChannels?: string[]; // 接收状态变化通知的渠道 ID（未设置 ID 的渠道为类型），为空时发送到所有已启用的渠道
     createdAt: number;
     updatedAt: number;
 }
@@ -314,6 +357,7 @@ export interface MonitorTaskRequest {
     icmpConfig?: MonitorIcmpConfig | null;
     agentIds?: string[];
     tags?: string[];       // 标签列表
+    notificationChannels?: string[]; // 接收状态变化通知的渠道 ID
 }
 
 export interface MonitorListResponse {
@@ -429,6 +473,13 @@ export interface LatestMetrics {
     host?: HostInfo;          // 主机信息
     gpu?: GPUMetric[];        // GPU 列表
     temperature?: TemperatureMetric[];  // 温度传感器列表
+    load?: LoadMetric;        // 系统负载
+}
+
+export interface LoadMetric {
+    load1: number;
+    load5: number;
+    load15: number;
 }
 
 // API Key 相关
@@ -450,6 +501,52 @@ export interface UpdateApiKeyNameRequest {
     name: string;
 }
 
+// 只读分享令牌相关
+export interface ShareToken {
+    id: string;
+    name: string;
+    agentIds: string[];
+    tags: string[];
+    expiresAt: number;
+    revokedAt?: number;
+    createdBy: string;
+    createdAt: number;
+    updatedAt: number;
+}
+
+export interface CreateShareTokenRequest {
+    name: string;
+    agentIds?: string[];
+    tags?: string[];
+    expiresHours: number;
+}
+
+export interface CreateShareTokenResponse extends ShareToken {
+    token: string;  // 令牌字符串，仅创建时返回
+}
+
+// 时间序列注释相关
+export interface Annotation {
+    id: string;
+    agentId: string;        // 为空表示全局注释
+    time: number;           // 时间戳（毫秒）
+    endTime?: number;       // 结束时间（毫秒），为空表示时间点事件
+    text: string;
+    tags: string[];
+    source: 'manual' | 'api';
+    createdBy: string;
+    createdAt: number;
+    updatedAt: number;
+}
+
+export interface AnnotationRequest {
+    agentId?: string;
+    time?: number;
+    endTime?: number;
+    text: string;
+    tags?: string[];
+}
+
 // 告警配置相关
 export interface AlertRules {
     cpuEnabled: boolean;
@@ -458,18 +555,39 @@ export interface AlertRules {
     memoryEnabled: boolean;
     memoryThreshold: number;
     memoryDuration: number;
+    memoryThresholdMode?: 'percent' | 'free'; // 阈值模式：按使用率（默认）或按剩余内存
+    memoryFreeThreshold?: number;             // 剩余内存阈值(GB)
     diskEnabled: boolean;
     diskThreshold: number;
     diskDuration: number;
+    diskThresholdMode?: 'percent' | 'free';   // 阈值模式：按使用率（默认）或按剩余空间
+    diskFreeThreshold?: number;               // 磁盘剩余空间阈值(GB)
     networkEnabled: boolean;
     networkThreshold: number;  // 网速阈值(MB/s)
     networkDuration: number;
+    loadEnabled?: boolean;     // 负载告警开关
+    loadThreshold?: number;    // 每核 1 分钟平均负载阈值
+    loadDuration?: number;
+    connectionEnabled?: boolean;     // 连接数告警开关
+    connectionThreshold?: number;    // 总连接数阈值（0 表示不检查）
+    connectionDuration?: number;
+    connectionStates?: ConnectionStateRule[]; // 按连接状态的阈值
     certEnabled: boolean;      // HTTPS 证书告警开关
     certThreshold: number;     // 证书剩余天数阈值（天）
     serviceEnabled: boolean;   // 服务下线告警开关
     serviceDuration: number;   // 服务下线持续时间（秒）
     agentOfflineEnabled: boolean;   // 探针离线告警开关
     agentOfflineDuration: number;   // 探针离线持续时间（秒）
+    collectorErrorEnabled?: boolean;  // 探针采集异常告警开关
+    collectorErrorDuration?: number;  // 探针采集异常持续时间（秒）
+    agentAttributes?: Record<string, string>; // 作用范围：资源类告警仅对属性全部匹配的探针生效
+    durationMode?: 'continuous' | 'windowed'; // 持续时间语义：连续超过阈值（默认）或按窗口内超阈值占比
+    windowRatio?: number;                     // windowed 模式下触发所需的超阈值采样占比(0-100)，默认 80
+}
+
+export interface ConnectionStateRule {
+    state: string;     // 连接状态，如 closeWait、timeWait
+    threshold: number;
 }
 
 export interface AlertNotifications {
@@ -481,9 +599,28 @@ export interface AlertNotifications {
 // 全局告警配置（现在存储在 Property 中）
 export interface AlertConfig {
     enabled: boolean;  // 全局告警开关
-    maskIP: boolean;   // 是否在通知中打码 IP 地址
+    maskIP: boolean;   // 是否在通知中打码 IP 地址（旧配置）
+    maskIPMode?: 'none' | 'partial' | 'full'; // IP 打码粒度
     rules: AlertRules;
     notifications: AlertNotifications;
+    throttle?: NotificationThrottle; // 单个探针的通知限流
+    escalation?: AlertEscalation;    // 未确认告警的升级策略
+    notifyOnResolve?: boolean;       // 是否发送告警恢复通知，默认发送
+}
+
+// 单个探针在时间窗口内的通知预算
+export interface NotificationThrottle {
+    maxNotifications: number; // 窗口内最多发送的通知数，0 表示不限制
+    windowMinutes: number;    // 时间窗口（分钟），默认 60
+}
+
+// 未确认告警的升级策略
+export interface AlertEscalation {
+    enabled: boolean;
+    minLevel?: 'info' | 'warning' | 'critical'; // 参与升级的最低告警级别，默认 critical
+    afterMinutes: number;   // 告警触发后未确认的分钟数，默认 15
+    repeatMinutes?: number; // 升级后仍未确认时重复通知的间隔（分钟），0 表示只通知一次
+    channels: string[];     // 升级通知发送的渠道 ID（未设置 id 时为渠道类型）
 }
 
 export interface AlertRecord {
@@ -500,10 +637,80 @@ export interface AlertRecord {
     status: string;
     firedAt: number;
     resolvedAt?: number;
+    ackedAt?: number; // 确认时间
+    ackedBy?: string; // 确认人
+    escalatedAt?: number;     // 最近一次因未确认而升级通知的时间
+    escalationCount?: number; // 因未确认而升级通知的次数
+    resolvedBy?: string; // 手动恢复操作人
+    commentCount?: number; // 备注数量
     createdAt: number;
     updatedAt: number;
 }
 
+// 告警备注
+export interface AlertComment {
+    id: number;
+    alertRecordId: number;
+    username: string;
+    text: string;
+    createdAt: number;
+}
+
+// 生效配置值及其来源：global 全局配置、default 内置默认值、agent 探针上设置的属性、reported 探针上报的属性
+export interface EffectiveValue<T = unknown> {
+    value: T;
+    source: 'global' | 'default' | 'agent' | 'reported';
+}
+
+export interface EffectiveScopeCondition {
+    key: string;
+    expected: string;
+    actual?: string;
+    source?: 'agent' | 'reported';
+    matched: boolean;
+}
+
+export interface EffectiveAlertRule {
+    alertType: string;
+    enabled: boolean;
+    scoped: boolean;  // 是否受作用范围限制（资源类告警）
+    active: boolean;  // 对该探针是否实际生效
+    threshold?: EffectiveValue<number>;
+    duration?: EffectiveValue<number>;
+    tiers?: { level: string; threshold: number }[];
+}
+
+export interface AlertState {
+    id: string;
+    agentId: string;
+    alertType: string;
+    value: number;
+    threshold: number;
+    startTime: number;
+    duration: number;
+    lastCheckTime: number;
+    isFiring: boolean;
+    lastRecordId: number;
+    level: string;
+}
+
+// 探针合并后的生效告警配置
+export interface EffectiveAlertConfig {
+    agentId: string;
+    enabled: EffectiveValue<boolean>;
+    inScope: boolean;
+    scope: EffectiveScopeCondition[];
+    durationMode: EffectiveValue<'continuous' | 'windowed'>;
+    windowRatio?: EffectiveValue<number>;
+    throttle: {
+        maxNotifications: EffectiveValue<number>;
+        windowMinutes: EffectiveValue<number>;
+    };
+    rules: EffectiveAlertRule[];
+    suppressed: boolean; // 探针离线告警触发中，其余告警只记录不通知
+    states: AlertState[];
+}
+
 // 流量统计相关
 export interface TrafficAlerts {
     sent80: boolean;